@@ -2,18 +2,29 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"runtime/debug"
+	"time"
 
 	"context"
 
+	"github.com/MikeO7/HarborBuddy/internal/audit"
 	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/dashboard"
 	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/history"
+	"github.com/MikeO7/HarborBuddy/internal/metrics"
+	"github.com/MikeO7/HarborBuddy/internal/pause"
+	"github.com/MikeO7/HarborBuddy/internal/registry"
 	"github.com/MikeO7/HarborBuddy/internal/scheduler"
 	"github.com/MikeO7/HarborBuddy/internal/selfupdate"
+	"github.com/MikeO7/HarborBuddy/internal/updater"
 	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
 	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
 )
 
 const version = "0.2.0"
@@ -34,32 +45,61 @@ func main() {
 
 	// Define CLI flags
 	configPath := flag.String("config", "/config/harborbuddy.yml", "Path to config file")
+	profile := flag.String("profile", "", "Profile of the config file to merge over its settings (e.g., production)")
 	interval := flag.Duration("interval", 0, "Override update check interval (e.g., 15m, 1h)")
 	scheduleTime := flag.String("schedule-time", "", "Run at specific time daily (e.g., '03:00')")
 	timezone := flag.String("timezone", "", "Timezone for schedule (e.g., 'America/Los_Angeles', 'UTC')")
 	once := flag.Bool("once", false, "Run a single update cycle and exit")
-	dryRun := flag.Bool("dry-run", false, "Enable dry-run mode (no actual updates)")
+	dryRun := flag.Bool("dry-run", false, "Enable dry-run mode (no actual updates or cleanup)")
+	monitorOnly := flag.Bool("monitor-only", false, "Pull and report available updates without applying them")
 	logLevel := flag.String("log-level", "", "Logging level (debug, info, warn, error)")
 	cleanupOnly := flag.Bool("cleanup-only", false, "Run only cleanup logic and exit")
+	labelFilter := flag.StringArray("label-filter", nil, "Only act on containers with this label (key=value, repeatable; requires --once or --cleanup-only)")
 	showVersion := flag.Bool("version", false, "Show version and exit")
+	explainImage := flag.String("explain-patterns", "", "Show which allow/deny patterns match the given image and exit")
+	rollback := flag.String("rollback", "", "Roll back a container to the image it ran before its last update and exit")
+	historyLimit := flag.Int("limit", 20, "Number of cycles shown by the history subcommand (0 = all)")
+	jsonSchema := flag.Bool("json-schema", false, "Print the configuration file's JSON Schema (with the validate-config subcommand)")
+	force := flag.Bool("force", false, "Let the update subcommand update containers that labels, allow/deny patterns or pins exclude")
+	freezeFor := flag.Duration("for", 0, "How long the freeze subcommand keeps a container from being updated, or pause keeps HarborBuddy paused (e.g., 72h; 0 = until unfrozen or resumed)")
+	freezeReason := flag.String("reason", "", "Why the freeze or pause subcommand was run, shown when something is skipped")
+	apiURL := flag.String("api-url", "", "API the dashboard subcommand talks to (default: from api.listen, e.g. http://localhost:8080)")
+	output := flag.String("output", config.OutputText, "Result format of --once, --cleanup-only and update: text, or json to print the cycle report to stdout")
 
 	// Internal flags for self-update mechanism
 	updaterMode := flag.Bool("updater-mode", false, "Internal: Run in updater helper mode")
 	targetID := flag.String("target-container-id", "", "Internal: ID of the container to update")
 	newImage := flag.String("new-image-id", "", "Internal: ID/Name of the new image")
+	updaterLog := flag.String("updater-log", "", "Internal: File the updater helper also logs to")
 
 	flag.Parse()
 
 	flag.Parse()
 
+	selfupdate.CurrentVersion = version
+
 	if *showVersion {
 		fmt.Printf("HarborBuddy version %s (commit: %s, %s/%s)\n", version, commit, runtime.GOOS, runtime.GOARCH)
 		os.Exit(0)
 	}
 
+	// "harborbuddy validate-config [path]" checks a configuration and prints it as resolved
+	if flag.Arg(0) == "validate-config" {
+		if *jsonSchema {
+			schema, err := config.JSONSchema()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to build JSON Schema: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(schema))
+			os.Exit(0)
+		}
+		os.Exit(runValidateConfig(*configPath, flag.Arg(1), *profile))
+	}
+
 	// If running in updater mode, we skip normal configuration loading
 	if *updaterMode {
-		log.Initialize(log.Config{Level: "info"}) // Basic logging for helper
+		log.Initialize(log.Config{Level: "info", File: *updaterLog}) // Basic logging for helper, kept in a file once it exits
 
 		if *targetID == "" || *newImage == "" {
 			log.Error("Updater mode requires --target-container-id and --new-image-id")
@@ -87,7 +127,7 @@ func main() {
 	}
 
 	// Load configuration
-	cfg, err := loadConfig(*configPath)
+	cfg, err := loadConfig(*configPath, *profile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
@@ -109,12 +149,45 @@ func main() {
 	if *dryRun {
 		cfg.Updates.DryRun = true
 	}
+	if *monitorOnly {
+		cfg.Updates.MonitorOnly = true
+	}
 	if *logLevel != "" {
 		cfg.Log.Level = *logLevel
 	}
 	if *cleanupOnly {
 		cfg.CleanupOnly = true
 	}
+	if *rollback != "" {
+		cfg.Rollback = *rollback
+	}
+	if *force {
+		cfg.Force = true
+	}
+	cfg.Output = *output
+
+	// "harborbuddy update <container|pattern>..." updates just those containers now and exits,
+	// regardless of the schedule. Monitor-only from the config doesn't apply; it was asked for.
+	if flag.Arg(0) == "update" {
+		if flag.NArg() < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: harborbuddy update [--force] [--dry-run] <container|pattern>...")
+			os.Exit(1)
+		}
+		cfg.Targets = flag.Args()[1:]
+		cfg.RunOnce = true
+		cfg.Updates.Enabled = true
+		cfg.Updates.MonitorOnly = *monitorOnly
+		cfg.Cleanup.Enabled = false
+	}
+
+	if len(*labelFilter) > 0 {
+		filter, err := config.ParseLabelFilter(*labelFilter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --label-filter: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.LabelFilter = filter
+	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -122,14 +195,105 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Explain pattern matching for an image without touching Docker
+	if *explainImage != "" {
+		fmt.Print(updater.ExplainPatterns(*explainImage, cfg.Updates))
+		os.Exit(0)
+	}
+
+	// Keep update state, history and pins next to the config so --rollback, "history" and
+	// pinning work out of the box
+	if info, err := os.Stat("/config"); err == nil && info.IsDir() {
+		if cfg.Updates.PinsFile == "" {
+			cfg.Updates.PinsFile = "/config/pins.yml"
+		}
+		if cfg.State.File == "" {
+			cfg.State.File = "/config/harborbuddy-state.json"
+		}
+		if cfg.State.HistoryFile == "" {
+			cfg.State.HistoryFile = "/config/harborbuddy-history.jsonl"
+		}
+		if cfg.State.PauseFile == "" {
+			cfg.State.PauseFile = "/config/PAUSE"
+		}
+		if cfg.State.StatusFile == "" {
+			cfg.State.StatusFile = "/config/harborbuddy-status.json"
+		}
+		selfupdate.LogFile = "/config/selfupdate.log"
+	}
+
+	// "harborbuddy history" prints the update journal and exits
+	if flag.Arg(0) == "history" {
+		if cfg.State.HistoryFile == "" {
+			fmt.Fprintln(os.Stderr, "Update history is disabled; set state.history_file (HARBORBUDDY_HISTORY_FILE)")
+			os.Exit(1)
+		}
+		cycles, err := history.Read(cfg.State.HistoryFile, *historyLimit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read update history: %v\n", err)
+			os.Exit(1)
+		}
+		history.Format(os.Stdout, cycles)
+		os.Exit(0)
+	}
+
+	// "harborbuddy check [container...]" compares running containers with their registries
+	// and exits non-zero if updates are available, for cron and CI gating
+	if flag.Arg(0) == "check" {
+		level := "warn"
+		if *logLevel != "" {
+			level = *logLevel
+		}
+		os.Exit(runCheck(cfg, flag.Args()[1:], level))
+	}
+
+	// "harborbuddy list" explains which containers an update cycle would consider, and why
+	if flag.Arg(0) == "list" {
+		os.Exit(runList(cfg))
+	}
+
+	// "harborbuddy explain <container>" walks through every rule for one container
+	if flag.Arg(0) == "explain" {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Usage: harborbuddy explain <container>")
+			os.Exit(1)
+		}
+		os.Exit(runExplain(cfg, flag.Arg(1)))
+	}
+
+	// "harborbuddy freeze [<container> [--for 72h]]" keeps a container from being updated,
+	// or lists the frozen ones; "harborbuddy unfreeze <container>" lifts the freeze
+	if flag.Arg(0) == "freeze" || flag.Arg(0) == "unfreeze" {
+		os.Exit(runFreeze(cfg, flag.Arg(0), flag.Args()[1:], *freezeFor, *freezeReason))
+	}
+
+	// "harborbuddy pause [--for 4h]" suspends every update and cleanup of a running
+	// HarborBuddy; "harborbuddy resume" lifts the pause
+	if flag.Arg(0) == "pause" || flag.Arg(0) == "resume" {
+		os.Exit(runPause(cfg, flag.Arg(0), *freezeFor, *freezeReason))
+	}
+
+	// "harborbuddy dashboard" shows what a running HarborBuddy is doing, through its API
+	if flag.Arg(0) == "dashboard" {
+		os.Exit(runDashboard(cfg, *apiURL))
+	}
+
+	// With --output json stdout carries only the cycle report, so everything else goes to stderr
+	var console io.Writer = os.Stdout
+	var logOutput io.Writer
+	if cfg.Output == config.OutputJSON {
+		console = os.Stderr
+		logOutput = os.Stderr
+	}
+
 	// Auto-detect log volume if not explicitly configured
 	if cfg.Log.File == "" {
 		if info, err := os.Stat("/logs"); err == nil && info.IsDir() {
 			cfg.Log.File = "/logs/harborbuddy.log"
-			fmt.Printf("Detected /logs volume, enabling file logging to %s\n", cfg.Log.File)
+			fmt.Fprintf(console, "Detected /logs volume, enabling file logging to %s\n", cfg.Log.File)
 		} else if info, err := os.Stat("/config"); err == nil && info.IsDir() {
 			cfg.Log.File = "/config/harborbuddy.log"
-			fmt.Printf("Detected /config volume, enabling file logging to %s\n", cfg.Log.File)
+			fmt.Fprintf(console, "Detected /config volume, enabling file logging to %s\n", cfg.Log.File)
 		}
 	}
 
@@ -140,10 +304,25 @@ func main() {
 		File:       cfg.Log.File,
 		MaxSize:    cfg.Log.MaxSize,
 		MaxBackups: cfg.Log.MaxBackups,
+		MaxAge:     cfg.Log.MaxAgeDays,
+		Compress:   cfg.Log.Compress,
+		Output:     logOutput,
+		Secrets:    cfg.Secrets(),
 	})
 
+	if cfg.Audit.File != "" {
+		if err := audit.Open(cfg.Audit.File, cfg.Audit.MaxSize, cfg.Audit.MaxBackups); err != nil {
+			log.ErrorErr("Failed to open the audit log", err)
+			os.Exit(1)
+		}
+		defer audit.Close()
+	}
+
 	log.Infof("HarborBuddy version %s starting", version)
 	log.Infof("Build: commit=%s, os=%s, arch=%s", commit, runtime.GOOS, runtime.GOARCH)
+	if cfg.Profile != "" {
+		log.Infof("Config profile: %s", cfg.Profile)
+	}
 	log.Infof("Docker host: %s", cfg.Docker.Host)
 
 	if cfg.Updates.ScheduleTime != "" {
@@ -153,6 +332,18 @@ func main() {
 	}
 
 	log.Infof("Dry-run mode: %v", cfg.Updates.DryRun)
+	if cfg.Cleanup.DryRun && !cfg.Updates.DryRun {
+		log.Info("Cleanup dry-run mode: cleanup logs what it would remove without removing it")
+	}
+	if cfg.Updates.MonitorOnly {
+		log.Info("Monitor-only mode: updates are reported but never applied")
+	}
+	if len(cfg.LabelFilter) > 0 {
+		log.Infof("Label filter: %v", cfg.LabelFilter)
+	}
+
+	metrics.Default.Configure(cfg.Metrics.PerContainer, cfg.Metrics.MaxContainerSeries)
+	util.SetFriendlyNameLabels(cfg.Log.NameLabels)
 
 	// Create Docker client
 	dockerClient, err := docker.NewClient(cfg.Docker.Host)
@@ -164,8 +355,21 @@ func main() {
 
 	log.Info("Successfully connected to Docker daemon")
 
+	// Registry credentials: configured hosts first, then the Docker CLI config
+	dockerClient.SetKeychain(registry.KeychainFromConfig(cfg.Registries))
+
+	// Wrap the client with an event-invalidated inspect cache
+	var client docker.Client = dockerClient
+	if cfg.Docker.CacheInspects {
+		cachingClient := docker.NewCachingClient(dockerClient)
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		go cachingClient.Watch(watchCtx)
+		client = cachingClient
+	}
+
 	// Start scheduler
-	if err := scheduler.Run(cfg, dockerClient); err != nil {
+	if err := scheduler.Run(cfg, client); err != nil {
 		log.ErrorErr("Scheduler error", err)
 		os.Exit(1)
 	}
@@ -173,15 +377,198 @@ func main() {
 	log.Info("HarborBuddy stopped")
 }
 
-// loadConfig loads and merges configuration from file and environment
-func loadConfig(path string) (config.Config, error) {
+// runCheck runs the check subcommand and returns its exit code. The table goes to stdout,
+// logs to stderr.
+func runCheck(cfg config.Config, names []string, logLevel string) int {
+	log.Initialize(log.Config{Level: logLevel, Output: os.Stderr, Secrets: cfg.Secrets()})
+
+	dockerClient, err := docker.NewClient(cfg.Docker.Host)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create Docker client: %v\n", err)
+		return updater.CheckFailed
+	}
+	defer dockerClient.Close()
+
+	results, err := updater.Check(context.Background(), cfg, dockerClient, names, log.WithFields(map[string]interface{}{"command": "check"}))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Check failed: %v\n", err)
+		return updater.CheckFailed
+	}
+	updater.FormatCheck(os.Stdout, results)
+	return updater.CheckExitCode(results)
+}
+
+// runList runs the list subcommand and returns its exit code
+func runList(cfg config.Config) int {
+	log.Initialize(log.Config{Level: "warn", Output: os.Stderr, Secrets: cfg.Secrets()})
+
+	dockerClient, err := docker.NewClient(cfg.Docker.Host)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create Docker client: %v\n", err)
+		return 1
+	}
+	defer dockerClient.Close()
+
+	entries, err := updater.List(context.Background(), cfg, dockerClient, log.WithFields(map[string]interface{}{"command": "list"}))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "List failed: %v\n", err)
+		return 1
+	}
+	updater.FormatList(os.Stdout, entries)
+	return 0
+}
+
+// runExplain runs the explain subcommand and returns its exit code
+func runExplain(cfg config.Config, name string) int {
+	log.Initialize(log.Config{Level: "warn", Output: os.Stderr, Secrets: cfg.Secrets()})
+
+	dockerClient, err := docker.NewClient(cfg.Docker.Host)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create Docker client: %v\n", err)
+		return 1
+	}
+	defer dockerClient.Close()
+
+	explanation, err := updater.ExplainContainer(context.Background(), cfg, dockerClient, name, log.WithFields(map[string]interface{}{"command": "explain"}))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Explain failed: %v\n", err)
+		return 1
+	}
+	fmt.Print(explanation)
+	return 0
+}
+
+// runFreeze runs the freeze and unfreeze subcommands and returns their exit code
+func runFreeze(cfg config.Config, command string, names []string, d time.Duration, reason string) int {
+	if command == "freeze" && len(names) == 0 {
+		frozen, err := updater.Frozen(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list frozen containers: %v\n", err)
+			return 1
+		}
+		updater.FormatFrozen(os.Stdout, frozen)
+		return 0
+	}
+	if len(names) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: harborbuddy %s <container>\n", command)
+		return 1
+	}
+
+	if command == "unfreeze" {
+		unfrozen, err := updater.Unfreeze(cfg, names[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to unfreeze %s: %v\n", names[0], err)
+			return 1
+		}
+		if !unfrozen {
+			fmt.Fprintf(os.Stderr, "Container %s is not frozen\n", names[0])
+			return 1
+		}
+		fmt.Printf("Unfroze %s\n", names[0])
+		return 0
+	}
+
+	frozen, err := updater.Freeze(cfg, names[0], d, reason)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to freeze %s: %v\n", names[0], err)
+		return 1
+	}
+	fmt.Printf("Froze %s: %s\n", frozen.Name, frozen)
+	return 0
+}
+
+// runPause runs the pause and resume subcommands and returns their exit code
+func runPause(cfg config.Config, command string, d time.Duration, reason string) int {
+	if cfg.State.PauseFile == "" {
+		fmt.Fprintln(os.Stderr, "Pausing needs a pause file; set state.pause_file (HARBORBUDDY_PAUSE_FILE)")
+		return 1
+	}
+
+	if command == "resume" {
+		resumed, err := pause.Clear(cfg.State.PauseFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to resume: %v\n", err)
+			return 1
+		}
+		if !resumed {
+			fmt.Fprintln(os.Stderr, "HarborBuddy is not paused")
+			return 1
+		}
+		fmt.Println("Resumed")
+		return 0
+	}
+
+	p, err := pause.Set(cfg.State.PauseFile, d, reason)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to pause: %v\n", err)
+		return 1
+	}
+	fmt.Printf("HarborBuddy is %s\n", p)
+	return 0
+}
+
+// runDashboard runs the dashboard subcommand and returns its exit code
+func runDashboard(cfg config.Config, apiURL string) int {
+	if apiURL == "" && cfg.API.Listen == "" {
+		fmt.Fprintln(os.Stderr, "The dashboard talks to HarborBuddy's HTTP API; set api.listen (HARBORBUDDY_API_LISTEN) or pass --api-url")
+		return 1
+	}
+	target := dashboard.TargetFor(cfg.API)
+	if apiURL != "" {
+		target.URL, target.SkipVerify = apiURL, false
+	}
+
+	if err := dashboard.Run(context.Background(), target, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Dashboard failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runValidateConfig runs the validate-config subcommand and returns its exit code. The
+// effective configuration, after environment overrides and with secrets redacted, goes to
+// stdout; problems go to stderr.
+func runValidateConfig(defaultPath, path, profile string) int {
+	var cfg config.Config
+	var err error
+	if path == "" {
+		cfg, err = loadConfig(defaultPath, profile)
+	} else if _, statErr := os.Stat(path); statErr != nil {
+		err = statErr // A path that was asked for must exist, unlike the default one
+	} else if cfg, err = config.LoadProfile(path, selectProfile(profile)); err == nil {
+		cfg.ApplyEnvironmentOverrides()
+		err = cfg.LoadSecretFiles()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", err)
+		return 1
+	}
+	enc.Close()
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "Configuration is valid")
+	return 0
+}
+
+// loadConfig loads and merges configuration from file, profile and environment
+func loadConfig(path, profile string) (config.Config, error) {
 	// Check if config env var is set
 	if envPath := os.Getenv("HARBORBUDDY_CONFIG"); envPath != "" {
 		path = envPath
 	}
 
 	// Load from file (or use defaults if file doesn't exist)
-	cfg, err := config.LoadFromFile(path)
+	cfg, err := config.LoadProfile(path, selectProfile(profile))
 	if err != nil {
 		return config.Config{}, err
 	}
@@ -189,5 +576,18 @@ func loadConfig(path string) (config.Config, error) {
 	// Apply environment variable overrides
 	cfg.ApplyEnvironmentOverrides()
 
+	// Read secrets kept in files, such as Docker secrets
+	if err := cfg.LoadSecretFiles(); err != nil {
+		return config.Config{}, err
+	}
+
 	return cfg, nil
 }
+
+// selectProfile returns the profile given with --profile, or else HARBORBUDDY_PROFILE
+func selectProfile(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv("HARBORBUDDY_PROFILE")
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Auth holds the credentials the API requires. A request is let through if it carries the
+// bearer token, or basic-auth credentials matching Username and Password; with neither set
+// the API is open. Browsers can log in to the web interface with the username and password,
+// or with any username and the token as the password.
+type Auth struct {
+	Token    string
+	Username string
+	Password string
+}
+
+// enabled reports whether the API requires credentials
+func (a Auth) enabled() bool {
+	return a.Token != "" || a.Password != ""
+}
+
+// allows reports whether the request carries valid credentials
+func (a Auth) allows(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.Token != "" && equal(token, a.Token)
+	}
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	if a.Password != "" && equal(user, a.Username) && equal(pass, a.Password) {
+		return true
+	}
+	return a.Token != "" && equal(pass, a.Token)
+}
+
+// equal compares secrets in constant time
+func equal(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// requireAuth rejects requests without valid credentials, except for /healthz, which
+// container health checks probe, and the image-pushed webhook, which has its own secret
+func requireAuth(auth Auth, next http.Handler) http.Handler {
+	if !auth.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/v1/hooks/image-pushed" || auth.allows(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="HarborBuddy", charset="UTF-8"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid credentials"})
+	})
+}
+
+// rejectCrossOrigin refuses state-changing requests a browser makes on behalf of another site,
+// such as a form on a web page posting to /trigger. Browsers send stored basic-auth
+// credentials along with those, so without this any site could update, freeze or pause
+// containers. Requests without browser headers, like curl or a registry's webhook, pass.
+func rejectCrossOrigin(next http.Handler) http.Handler {
+	protection := http.NewCrossOriginProtection()
+	protection.AddInsecureBypassPattern("POST /v1/hooks/image-pushed")
+	protection.SetDenyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cross-origin request refused"})
+	}))
+	return protection.Handler(next)
+}
+
+// readOnly refuses requests that would trigger or change something, for api.read_only: the
+// update and freeze endpoints, pausing, and the image-pushed webhook
+func readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "the API is read-only (api.read_only)"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
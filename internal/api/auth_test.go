@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAuth(t *testing.T) {
+	handler := requireAuth(Auth{Token: "api-token", Username: "admin", Password: "hunter2"},
+		newMux(&fakeController{}, "", "hook-secret"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   func(r *http.Request)
+		want   int
+	}{
+		{"no credentials", http.MethodGet, "/status", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"bearer token", http.MethodGet, "/status", func(r *http.Request) { r.Header.Set("Authorization", "Bearer api-token") }, http.StatusOK},
+		{"wrong bearer token", http.MethodGet, "/status", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"basic auth", http.MethodGet, "/", func(r *http.Request) { r.SetBasicAuth("admin", "hunter2") }, http.StatusOK},
+		{"basic auth with the wrong user", http.MethodGet, "/status", func(r *http.Request) { r.SetBasicAuth("root", "hunter2") }, http.StatusUnauthorized},
+		{"token as the basic-auth password", http.MethodGet, "/status", func(r *http.Request) { r.SetBasicAuth("anyone", "api-token") }, http.StatusOK},
+		{"healthz stays open", http.MethodGet, "/healthz", func(r *http.Request) {}, http.StatusOK},
+		{"webhook uses its own secret", http.MethodPost, "/v1/hooks/image-pushed?token=hook-secret", func(r *http.Request) {}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			tt.auth(req)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate, browsers won't ask for credentials")
+			}
+		})
+	}
+}
+
+func TestRequireAuth_Disabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	requireAuth(Auth{}, newMux(&fakeController{}, "", "")).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /status without auth configured = %d, want 200", rec.Code)
+	}
+}
+
+func TestRejectCrossOrigin(t *testing.T) {
+	handler := rejectCrossOrigin(requireAuth(Auth{Username: "admin", Password: "hunter2"},
+		newMux(&fakeController{}, "", "hook-secret")))
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		refused bool
+	}{
+		{"cross-site form post", http.MethodPost, "/trigger", map[string]string{"Sec-Fetch-Site": "cross-site"}, true},
+		{"cross-origin post without fetch metadata", http.MethodDelete, "/pause", map[string]string{"Origin": "https://evil.example"}, true},
+		{"web interface", http.MethodPost, "/trigger", map[string]string{"Sec-Fetch-Site": "same-origin"}, false},
+		{"curl", http.MethodPost, "/trigger", nil, false},
+		{"cross-site read", http.MethodGet, "/status", map[string]string{"Sec-Fetch-Site": "cross-site"}, false},
+		{"registry webhook", http.MethodPost, "/v1/hooks/image-pushed?token=hook-secret", map[string]string{"Origin": "https://hub.docker.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.SetBasicAuth("admin", "hunter2")
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if refused := rec.Code == http.StatusForbidden; refused != tt.refused {
+				t.Errorf("%s %s = %d, want refused=%v: %s", tt.method, tt.path, rec.Code, tt.refused, rec.Body)
+			}
+		})
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	ctrl := &fakeController{accept: true}
+	handler := NewServer(ctrl, Options{ReadOnly: true, WebhookSecret: "hook-secret"}).handler
+
+	for _, tt := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/status", http.StatusOK},
+		{http.MethodGet, "/containers", http.StatusOK},
+		{http.MethodGet, "/metrics", http.StatusOK},
+		{http.MethodPost, "/trigger", http.StatusForbidden},
+		{http.MethodPost, "/update/web", http.StatusForbidden},
+		{http.MethodPost, "/freeze/web", http.StatusForbidden},
+		{http.MethodDelete, "/pause", http.StatusForbidden},
+		{http.MethodPost, "/v1/hooks/image-pushed?token=hook-secret", http.StatusForbidden},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body)
+		}
+	}
+	if ctrl.triggered != 0 || len(ctrl.updated) != 0 || len(ctrl.frozen) != 0 {
+		t.Errorf("read-only API acted: triggered %d, updated %v, frozen %v", ctrl.triggered, ctrl.updated, ctrl.frozen)
+	}
+}
@@ -0,0 +1,357 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/history"
+	"github.com/MikeO7/HarborBuddy/internal/metrics"
+	"github.com/MikeO7/HarborBuddy/internal/pause"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"gopkg.in/yaml.v3"
+)
+
+// shutdownTimeout bounds how long in-flight requests may take once the server stops
+const shutdownTimeout = 5 * time.Second
+
+// defaultHistoryLimit is how many cycles /history returns without ?limit= (0 = all)
+const defaultHistoryLimit = 50
+
+// CycleResult describes a finished update & cleanup cycle
+type CycleResult struct {
+	ID         string    `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   string    `json:"duration"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status is the scheduler state reported by /status
+type Status struct {
+	Running   bool         `json:"running"`
+	LastCycle *CycleResult `json:"last_cycle,omitempty"`
+	NextRun   *time.Time   `json:"next_run,omitempty"`
+	Paused    *pause.Pause `json:"paused,omitempty"` // Set while the pause switch is on
+}
+
+// ErrNotFound is matched by Controller errors for something that doesn't exist, e.g. a
+// container that isn't running
+var ErrNotFound = errors.New("not found")
+
+// Controller is implemented by the scheduler to expose its state and accept on-demand runs
+type Controller interface {
+	Status() Status
+	// Trigger requests an immediate cycle. It returns false if one is already running or queued.
+	Trigger() bool
+	// Explain walks through the update rules for the named container
+	Explain(ctx context.Context, name string) (interface{}, error)
+	// Freeze keeps the named container from being updated for d, or until unfrozen if d is 0
+	Freeze(name string, d time.Duration, reason string) (interface{}, error)
+	// Unfreeze lifts a freeze; the error matches ErrNotFound if the container isn't frozen
+	Unfreeze(name string) error
+	// Frozen lists the frozen containers
+	Frozen() (interface{}, error)
+	// Pause suspends every update and cleanup for d, or until resumed if d is 0
+	Pause(d time.Duration, reason string) (interface{}, error)
+	// Resume lifts the pause; the error matches ErrNotFound if HarborBuddy isn't paused
+	Resume() error
+	// Containers lists the running containers and whether update cycles consider them
+	Containers(ctx context.Context) (interface{}, error)
+	// Logs returns the log lines of the current or last cycle numbered after seq
+	Logs(after uint64) []log.Line
+	// Update starts checking and updating the named container now; the error matches
+	// ErrNotFound if no container has the name
+	Update(name string) error
+	// RedactedConfig returns the configuration in effect with its secrets hidden
+	RedactedConfig() interface{}
+	// ImagePushed starts checking the containers running the image's repository and returns
+	// their names; the error matches ErrNotFound if no container runs it
+	ImagePushed(image string) ([]string, error)
+}
+
+// Options configures the API server
+type Options struct {
+	// Listen is the address to listen on (e.g., ":8080")
+	Listen string
+	// HistoryFile is the update journal served by /history ("" if disabled)
+	HistoryFile string
+	// WebhookSecret authenticates the image-pushed webhook ("" disables it)
+	WebhookSecret string
+	// Auth holds the credentials every other endpoint but /healthz requires
+	Auth Auth
+	// TLSCert and TLSKey are PEM files to serve HTTPS with ("" serves plain HTTP)
+	TLSCert string
+	TLSKey  string
+	// ReadOnly refuses every request but GET and HEAD, so the API can report but not act
+	ReadOnly bool
+}
+
+// Server is the embedded HTTP API
+type Server struct {
+	listen  string
+	handler http.Handler
+	tlsCert string
+	tlsKey  string
+}
+
+// NewServer creates an API server for ctrl
+func NewServer(ctrl Controller, opts Options) *Server {
+	var handler http.Handler = newMux(ctrl, opts.HistoryFile, opts.WebhookSecret)
+	if opts.ReadOnly {
+		handler = readOnly(handler)
+	}
+	return &Server{
+		listen:  opts.Listen,
+		handler: rejectCrossOrigin(requireAuth(opts.Auth, handler)),
+		tlsCert: opts.TLSCert,
+		tlsKey:  opts.TLSKey,
+	}
+}
+
+// Run serves the API until ctx is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.listen,
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	scheme := "http"
+	if s.tlsCert != "" {
+		scheme = "https"
+	}
+	go func() {
+		if s.tlsCert != "" {
+			errCh <- srv.ListenAndServeTLS(s.tlsCert, s.tlsKey)
+			return
+		}
+		errCh <- srv.ListenAndServe()
+	}()
+
+	log.Infof("API listening on %s (%s)", s.listen, scheme)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("api server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down api server: %w", err)
+	}
+	return nil
+}
+
+func newMux(ctrl Controller, historyFile, webhookSecret string) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", serveUI)
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, ctrl.Status())
+	})
+
+	mux.Handle("GET /metrics", metrics.Default)
+
+	mux.HandleFunc("GET /history", func(w http.ResponseWriter, r *http.Request) {
+		if historyFile == "" {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "update history is disabled"})
+			return
+		}
+
+		limit := defaultHistoryLimit
+		if val := r.URL.Query().Get("limit"); val != "" {
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a non-negative integer"})
+				return
+			}
+			limit = n
+		}
+
+		cycles, err := history.Read(historyFile, limit)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, cycles)
+	})
+
+	mux.HandleFunc("GET /explain/{name}", func(w http.ResponseWriter, r *http.Request) {
+		explanation, err := ctrl.Explain(r.Context(), r.PathValue("name"))
+		if errors.Is(err, ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, explanation)
+	})
+
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		out, err := yaml.Marshal(ctrl.RedactedConfig())
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(out)
+	})
+
+	mux.HandleFunc("GET /containers", func(w http.ResponseWriter, r *http.Request) {
+		containers, err := ctrl.Containers(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, containers)
+	})
+
+	mux.HandleFunc("GET /logs", func(w http.ResponseWriter, r *http.Request) {
+		var after uint64
+		if val := r.URL.Query().Get("after"); val != "" {
+			var err error
+			if after, err = strconv.ParseUint(val, 10, 64); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "after must be a non-negative integer"})
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, ctrl.Logs(after))
+	})
+
+	mux.HandleFunc("GET /freeze", func(w http.ResponseWriter, r *http.Request) {
+		frozen, err := ctrl.Frozen()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, frozen)
+	})
+
+	mux.HandleFunc("POST /freeze/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var d time.Duration
+		if val := r.URL.Query().Get("for"); val != "" {
+			var err error
+			if d, err = time.ParseDuration(val); err != nil || d <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "for must be a positive duration, e.g. 72h"})
+				return
+			}
+		}
+
+		frozen, err := ctrl.Freeze(r.PathValue("name"), d, r.URL.Query().Get("reason"))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, frozen)
+	})
+
+	mux.HandleFunc("DELETE /freeze/{name}", func(w http.ResponseWriter, r *http.Request) {
+		err := ctrl.Unfreeze(r.PathValue("name"))
+		if errors.Is(err, ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "unfrozen"})
+	})
+
+	mux.HandleFunc("POST /pause", func(w http.ResponseWriter, r *http.Request) {
+		var d time.Duration
+		if val := r.URL.Query().Get("for"); val != "" {
+			var err error
+			if d, err = time.ParseDuration(val); err != nil || d <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "for must be a positive duration, e.g. 4h"})
+				return
+			}
+		}
+
+		paused, err := ctrl.Pause(d, r.URL.Query().Get("reason"))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, paused)
+	})
+
+	mux.HandleFunc("DELETE /pause", func(w http.ResponseWriter, r *http.Request) {
+		err := ctrl.Resume()
+		if errors.Is(err, ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "resumed"})
+	})
+
+	mux.HandleFunc("POST /update/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if rejectWhilePaused(w, ctrl) {
+			return
+		}
+		err := ctrl.Update(r.PathValue("name"))
+		if errors.Is(err, ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "updating"})
+	})
+
+	mux.HandleFunc("POST /trigger", func(w http.ResponseWriter, r *http.Request) {
+		if rejectWhilePaused(w, ctrl) {
+			return
+		}
+		if !ctrl.Trigger() {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "a cycle is already running or queued"})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "triggered"})
+	})
+
+	mux.HandleFunc("POST /v1/hooks/image-pushed", handleImagePushed(ctrl, webhookSecret))
+
+	return mux
+}
+
+// rejectWhilePaused answers 409 and returns true if the pause switch is on, so asking for an
+// update while paused fails instead of silently doing nothing
+func rejectWhilePaused(w http.ResponseWriter, ctrl Controller) bool {
+	p := ctrl.Status().Paused
+	if p == nil {
+		return false
+	}
+	writeJSON(w, http.StatusConflict, map[string]string{"error": "HarborBuddy is " + p.String() + "; resume it first"})
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warnf("Failed to write API response: %v", err)
+	}
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/history"
+	"github.com/MikeO7/HarborBuddy/internal/pause"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+)
+
+// fakeController is a Controller with canned responses
+type fakeController struct {
+	status    Status
+	accept    bool
+	triggered int
+	explained []string
+	frozen    map[string]time.Duration
+	pushed    []string
+	updated   []string
+}
+
+func (f *fakeController) Status() Status { return f.status }
+
+func (f *fakeController) Trigger() bool {
+	f.triggered++
+	return f.accept
+}
+
+func (f *fakeController) Explain(ctx context.Context, name string) (interface{}, error) {
+	f.explained = append(f.explained, name)
+	if name != "web" {
+		return nil, fmt.Errorf("%w: no running container named %s", ErrNotFound, name)
+	}
+	return map[string]interface{}{"container": "web", "eligible": true}, nil
+}
+
+func (f *fakeController) Freeze(name string, d time.Duration, reason string) (interface{}, error) {
+	if f.frozen == nil {
+		f.frozen = map[string]time.Duration{}
+	}
+	f.frozen[name] = d
+	return map[string]interface{}{"name": name, "reason": reason}, nil
+}
+
+func (f *fakeController) Unfreeze(name string) error {
+	if _, ok := f.frozen[name]; !ok {
+		return fmt.Errorf("%w: container %s is not frozen", ErrNotFound, name)
+	}
+	delete(f.frozen, name)
+	return nil
+}
+
+func (f *fakeController) Frozen() (interface{}, error) {
+	names := []string{}
+	for name := range f.frozen {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (f *fakeController) Pause(d time.Duration, reason string) (interface{}, error) {
+	p := pause.Pause{Reason: reason, PausedAt: time.Now()}
+	if d > 0 {
+		p.Until = p.PausedAt.Add(d)
+	}
+	f.status.Paused = &p
+	return p, nil
+}
+
+func (f *fakeController) Resume() error {
+	if f.status.Paused == nil {
+		return fmt.Errorf("%w: HarborBuddy is not paused", ErrNotFound)
+	}
+	f.status.Paused = nil
+	return nil
+}
+
+func (f *fakeController) Containers(ctx context.Context) (interface{}, error) {
+	return []map[string]interface{}{{"name": "web", "eligible": true}}, nil
+}
+
+func (f *fakeController) Logs(after uint64) []log.Line {
+	lines := []log.Line{}
+	for seq := after + 1; seq <= 3; seq++ {
+		lines = append(lines, log.Line{Seq: seq, Text: fmt.Sprintf("line %d", seq)})
+	}
+	return lines
+}
+
+func (f *fakeController) Update(name string) error {
+	f.updated = append(f.updated, name)
+	if name != "web" {
+		return fmt.Errorf("%w: no running container named %s", ErrNotFound, name)
+	}
+	return nil
+}
+
+func (f *fakeController) RedactedConfig() interface{} {
+	return map[string]interface{}{"api": map[string]string{"listen": ":8080", "webhook_secret": "REDACTED"}}
+}
+
+func (f *fakeController) ImagePushed(image string) ([]string, error) {
+	f.pushed = append(f.pushed, image)
+	if !strings.HasPrefix(image, "ghcr.io/acme/app") {
+		return nil, fmt.Errorf("%w: no running container uses %s", ErrNotFound, image)
+	}
+	return []string{"app"}, nil
+}
+
+func serve(ctrl Controller, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	newMux(ctrl, "", "").ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestHealthz(t *testing.T) {
+	rec := serve(&fakeController{}, http.MethodGet, "/healthz")
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want 200", rec.Code)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	next := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	ctrl := &fakeController{status: Status{
+		LastCycle: &CycleResult{ID: "abcd1234", Success: false, Error: "docker error"},
+		NextRun:   &next,
+	}}
+
+	rec := serve(ctrl, http.MethodGet, "/status")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var got Status
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if got.LastCycle == nil || got.LastCycle.ID != "abcd1234" || got.LastCycle.Error != "docker error" {
+		t.Errorf("LastCycle = %+v", got.LastCycle)
+	}
+	if got.NextRun == nil || !got.NextRun.Equal(next) {
+		t.Errorf("NextRun = %v, want %v", got.NextRun, next)
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept bool
+		want   int
+	}{
+		{"accepted", true, http.StatusAccepted},
+		{"already running", false, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &fakeController{accept: tt.accept}
+			rec := serve(ctrl, http.MethodPost, "/trigger")
+			if rec.Code != tt.want {
+				t.Errorf("POST /trigger = %d, want %d", rec.Code, tt.want)
+			}
+			if ctrl.triggered != 1 {
+				t.Errorf("Trigger called %d times, want 1", ctrl.triggered)
+			}
+		})
+	}
+}
+
+func TestTrigger_RequiresPost(t *testing.T) {
+	ctrl := &fakeController{accept: true}
+	rec := serve(ctrl, http.MethodGet, "/trigger")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /trigger = %d, want 405", rec.Code)
+	}
+	if ctrl.triggered != 0 {
+		t.Error("GET must not trigger a cycle")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	rec := serve(&fakeController{}, http.MethodGet, "/metrics")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d, want 200", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "# TYPE harborbuddy_cycle_duration_seconds histogram") {
+		t.Errorf("unexpected metrics body:\n%s", rec.Body.String())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	for _, id := range []string{"first", "second", "third"} {
+		if err := history.Append(path, history.Cycle{ID: id}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	get := func(historyFile, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newMux(&fakeController{}, historyFile, "").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get(path, "/history?limit=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /history = %d, want 200", rec.Code)
+	}
+	var cycles []history.Cycle
+	if err := json.NewDecoder(rec.Body).Decode(&cycles); err != nil {
+		t.Fatalf("failed to decode history: %v", err)
+	}
+	if len(cycles) != 2 || cycles[0].ID != "third" {
+		t.Errorf("history = %+v, want the 2 newest cycles", cycles)
+	}
+
+	if rec := get(path, "/history?limit=-1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit = %d, want 400", rec.Code)
+	}
+	if rec := get("", "/history"); rec.Code != http.StatusNotFound {
+		t.Errorf("disabled history = %d, want 404", rec.Code)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	ctrl := &fakeController{}
+
+	rec := serve(ctrl, http.MethodGet, "/explain/web")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"eligible":true`) {
+		t.Errorf("GET /explain/web = %d %s, want the explanation", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(ctrl, http.MethodGet, "/explain/missing")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /explain/missing = %d, want 404", rec.Code)
+	}
+	if len(ctrl.explained) != 2 || ctrl.explained[1] != "missing" {
+		t.Errorf("explained = %v, want the names from the path", ctrl.explained)
+	}
+}
+
+func TestContainers(t *testing.T) {
+	rec := serve(&fakeController{}, http.MethodGet, "/containers")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"web"`) {
+		t.Errorf("GET /containers = %d %s, want the container list", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLogs(t *testing.T) {
+	rec := serve(&fakeController{}, http.MethodGet, "/logs?after=1")
+	var lines []log.Line
+	if err := json.Unmarshal(rec.Body.Bytes(), &lines); err != nil || len(lines) != 2 || lines[0].Seq != 2 {
+		t.Errorf("GET /logs?after=1 = %d %s, want lines 2 and 3", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(&fakeController{}, http.MethodGet, "/logs?after=-1")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /logs?after=-1 = %d, want 400", rec.Code)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctrl := &fakeController{}
+
+	rec := serve(ctrl, http.MethodPost, "/update/web")
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("POST /update/web = %d, want 202", rec.Code)
+	}
+	rec = serve(ctrl, http.MethodPost, "/update/missing")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST /update/missing = %d, want 404", rec.Code)
+	}
+	if len(ctrl.updated) != 2 || ctrl.updated[0] != "web" {
+		t.Errorf("updated = %v, want the names from the path", ctrl.updated)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	rec := serve(&fakeController{}, http.MethodGet, "/config")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/yaml" {
+		t.Fatalf("GET /config = %d %s, want YAML", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "webhook_secret: REDACTED") {
+		t.Errorf("GET /config = %s, want the redacted configuration", rec.Body.String())
+	}
+}
+
+func TestUI(t *testing.T) {
+	rec := serve(&fakeController{}, http.MethodGet, "/")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("GET / = %d %s, want the web interface", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "<title>HarborBuddy</title>") {
+		t.Error("GET / did not serve the page")
+	}
+	if rec := serve(&fakeController{}, http.MethodGet, "/nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /nope = %d, want 404 rather than the page", rec.Code)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	ctrl := &fakeController{}
+
+	rec := serve(ctrl, http.MethodPost, "/freeze/db?for=72h&reason=incident")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"reason":"incident"`) {
+		t.Errorf("POST /freeze/db = %d %s, want the freeze", rec.Code, rec.Body.String())
+	}
+	if ctrl.frozen["db"] != 72*time.Hour {
+		t.Errorf("froze %v, want db for 72h", ctrl.frozen)
+	}
+
+	rec = serve(ctrl, http.MethodPost, "/freeze/db?for=soon")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST /freeze/db?for=soon = %d, want 400", rec.Code)
+	}
+
+	rec = serve(ctrl, http.MethodGet, "/freeze")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"db"`) {
+		t.Errorf("GET /freeze = %d %s, want db listed", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(ctrl, http.MethodDelete, "/freeze/db")
+	if rec.Code != http.StatusOK {
+		t.Errorf("DELETE /freeze/db = %d, want 200", rec.Code)
+	}
+	rec = serve(ctrl, http.MethodDelete, "/freeze/db")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE /freeze/db again = %d, want 404", rec.Code)
+	}
+}
+
+func TestPause(t *testing.T) {
+	ctrl := &fakeController{accept: true}
+
+	rec := serve(ctrl, http.MethodPost, "/pause?for=soon")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST /pause?for=soon = %d, want 400", rec.Code)
+	}
+	rec = serve(ctrl, http.MethodPost, "/pause?for=4h&reason=migration")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"reason":"migration"`) {
+		t.Errorf("POST /pause = %d %s, want the pause", rec.Code, rec.Body.String())
+	}
+
+	if rec := serve(ctrl, http.MethodGet, "/status"); !strings.Contains(rec.Body.String(), `"paused":`) {
+		t.Errorf("GET /status = %s, want the pause reported", rec.Body.String())
+	}
+	for _, path := range []string{"/trigger", "/update/web"} {
+		rec := serve(ctrl, http.MethodPost, path)
+		if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "migration") {
+			t.Errorf("POST %s while paused = %d %s, want 409", path, rec.Code, rec.Body.String())
+		}
+	}
+	if ctrl.triggered != 0 || len(ctrl.updated) != 0 {
+		t.Errorf("triggered %d cycles and updated %v while paused", ctrl.triggered, ctrl.updated)
+	}
+
+	rec = serve(ctrl, http.MethodDelete, "/pause")
+	if rec.Code != http.StatusOK {
+		t.Errorf("DELETE /pause = %d, want 200", rec.Code)
+	}
+	rec = serve(ctrl, http.MethodDelete, "/pause")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE /pause again = %d, want 404", rec.Code)
+	}
+	if rec := serve(ctrl, http.MethodPost, "/trigger"); rec.Code != http.StatusAccepted {
+		t.Errorf("POST /trigger after resuming = %d, want 202", rec.Code)
+	}
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// uiPage is the web interface: one page that talks to the rest of the API
+//
+//go:embed ui/index.html
+var uiPage []byte
+
+// serveUI serves the web interface at /
+func serveUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'self' 'unsafe-inline'; script-src 'self' 'unsafe-inline'")
+	_, _ = w.Write(uiPage)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxWebhookBody bounds the payload of the image-pushed webhook
+const maxWebhookBody = 1 << 20
+
+// pushPayload holds the fields of the payloads the image-pushed webhook understands: its own
+// {"image": "..."} and the push notifications of Docker Hub, Harbor and GitHub (GHCR)
+type pushPayload struct {
+	Image string `json:"image"`
+
+	// Docker Hub
+	PushData *struct {
+		Tag string `json:"tag"`
+	} `json:"push_data"`
+	Repository *struct {
+		RepoName string `json:"repo_name"`
+	} `json:"repository"`
+
+	// Harbor
+	EventData *struct {
+		Resources []struct {
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+	} `json:"event_data"`
+
+	// GitHub package events
+	Package *struct {
+		PackageVersion struct {
+			PackageURL string `json:"package_url"`
+		} `json:"package_version"`
+	} `json:"package"`
+}
+
+// image returns the pushed image reference, or "" if the payload names none
+func (p pushPayload) image() string {
+	switch {
+	case p.Image != "":
+		return p.Image
+	case p.PushData != nil && p.Repository != nil && p.Repository.RepoName != "":
+		if p.PushData.Tag == "" {
+			return p.Repository.RepoName
+		}
+		return p.Repository.RepoName + ":" + p.PushData.Tag
+	case p.EventData != nil && len(p.EventData.Resources) > 0:
+		return p.EventData.Resources[0].ResourceURL
+	case p.Package != nil:
+		return p.Package.PackageVersion.PackageURL
+	}
+	return ""
+}
+
+// handleImagePushed serves POST /v1/hooks/image-pushed: it checks and updates the containers
+// running the pushed image's repository. The image comes from ?image= or the JSON payload.
+func handleImagePushed(ctrl Controller, secret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if secret == "" {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "the webhook is disabled, set api.webhook_secret"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read the payload"})
+			return
+		}
+		if !authorized(r, body, secret) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing webhook secret"})
+			return
+		}
+
+		image := r.URL.Query().Get("image")
+		if image == "" && len(body) > 0 {
+			var payload pushPayload
+			if err := json.Unmarshal(body, &payload); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "payload is not JSON"})
+				return
+			}
+			image = payload.image()
+		}
+		if image == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no image in the request, pass ?image= or {\"image\": \"...\"}"})
+			return
+		}
+
+		containers, err := ctrl.ImagePushed(image)
+		if errors.Is(err, ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"status": "checking", "image": image, "containers": containers})
+	}
+}
+
+// authorized reports whether the request carries the webhook secret: as a bearer token or
+// the whole Authorization header (Harbor's auth header), as ?token= for registries that only
+// take a URL (Docker Hub), or as a GitHub X-Hub-Signature-256 of the body
+func authorized(r *http.Request, body []byte, secret string) bool {
+	if signature, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		want := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(want))
+	}
+
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); header != "" {
+		token, _ = strings.CutPrefix(header, "Bearer ")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
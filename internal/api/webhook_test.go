@@ -0,0 +1,108 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func pushHook(ctrl Controller, secret, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	newMux(ctrl, "", secret).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestImagePushed_Payloads(t *testing.T) {
+	bearer := map[string]string{"Authorization": "Bearer s3cret"}
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		want   string
+	}{
+		{"query", "/v1/hooks/image-pushed?image=ghcr.io/acme/app:v2", "", "ghcr.io/acme/app:v2"},
+		{"plain", "/v1/hooks/image-pushed", `{"image": "ghcr.io/acme/app:v2"}`, "ghcr.io/acme/app:v2"},
+		{"docker hub", "/v1/hooks/image-pushed", `{"push_data": {"tag": "latest"}, "repository": {"repo_name": "acme/app"}}`, "acme/app:latest"},
+		{"harbor", "/v1/hooks/image-pushed", `{"type": "PUSH_ARTIFACT", "event_data": {"resources": [{"resource_url": "harbor.example.com/acme/app:v2"}]}}`, "harbor.example.com/acme/app:v2"},
+		{"github", "/v1/hooks/image-pushed", `{"action": "published", "package": {"package_version": {"package_url": "ghcr.io/acme/app:v2"}}}`, "ghcr.io/acme/app:v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &fakeController{}
+			pushHook(ctrl, "s3cret", tt.target, tt.body, bearer)
+			if len(ctrl.pushed) != 1 || ctrl.pushed[0] != tt.want {
+				t.Errorf("pushed %v, want %s", ctrl.pushed, tt.want)
+			}
+		})
+	}
+}
+
+func TestImagePushed_Responses(t *testing.T) {
+	ctrl := &fakeController{}
+	bearer := map[string]string{"Authorization": "Bearer s3cret"}
+
+	rec := pushHook(ctrl, "s3cret", "/v1/hooks/image-pushed?image=ghcr.io/acme/app:v2", "", bearer)
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"containers":["app"]`) {
+		t.Errorf("push of a deployed image = %d %s, want 202 naming the container", rec.Code, rec.Body.String())
+	}
+
+	rec = pushHook(ctrl, "s3cret", "/v1/hooks/image-pushed?image=ghcr.io/acme/other:v2", "", bearer)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("push of an image nothing runs = %d, want 404", rec.Code)
+	}
+
+	rec = pushHook(ctrl, "s3cret", "/v1/hooks/image-pushed", `{"action": "deleted"}`, bearer)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("payload without an image = %d, want 400", rec.Code)
+	}
+
+	rec = pushHook(ctrl, "", "/v1/hooks/image-pushed?image=ghcr.io/acme/app:v2", "", bearer)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("webhook without a secret configured = %d, want 404", rec.Code)
+	}
+	if len(ctrl.pushed) != 2 {
+		t.Errorf("pushed %v, want only the requests with an image and a configured secret", ctrl.pushed)
+	}
+}
+
+func TestImagePushed_Auth(t *testing.T) {
+	body := `{"image": "ghcr.io/acme/app:v2"}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		target string
+		header map[string]string
+		want   int
+	}{
+		{"bearer", "/v1/hooks/image-pushed", map[string]string{"Authorization": "Bearer s3cret"}, http.StatusAccepted},
+		{"raw header", "/v1/hooks/image-pushed", map[string]string{"Authorization": "s3cret"}, http.StatusAccepted},
+		{"query token", "/v1/hooks/image-pushed?token=s3cret", nil, http.StatusAccepted},
+		{"github signature", "/v1/hooks/image-pushed", map[string]string{"X-Hub-Signature-256": signature}, http.StatusAccepted},
+		{"wrong secret", "/v1/hooks/image-pushed", map[string]string{"Authorization": "Bearer guess"}, http.StatusUnauthorized},
+		{"wrong signature", "/v1/hooks/image-pushed?token=s3cret", map[string]string{"X-Hub-Signature-256": "sha256=00"}, http.StatusUnauthorized},
+		{"no secret", "/v1/hooks/image-pushed", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &fakeController{}
+			rec := pushHook(ctrl, "s3cret", tt.target, body, tt.header)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d %s, want %d", rec.Code, rec.Body.String(), tt.want)
+			}
+			if tt.want != http.StatusAccepted && len(ctrl.pushed) > 0 {
+				t.Errorf("unauthorized request reached the controller: %v", ctrl.pushed)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Package audit keeps the audit log: an append-only record of every change HarborBuddy makes
+// to Docker, one JSON object per line, written to its own file apart from the operational log.
+// Each entry says what was done to which object, who asked for it and whether it worked.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Initiators: what started the work an action is part of
+const (
+	InitiatorCycle = "cycle" // A scheduled cycle, or one started by a container or disk event
+	InitiatorAPI   = "api"   // An HTTP API request or the image-pushed webhook
+	InitiatorMQTT  = "mqtt"  // A command sent over MQTT
+	InitiatorCLI   = "cli"   // A one-shot command: --once, update, rollback, --cleanup-only
+)
+
+// Objects acted on
+const (
+	ObjectContainer  = "container"
+	ObjectImage      = "image"
+	ObjectVolume     = "volume"
+	ObjectNetwork    = "network"
+	ObjectBuildCache = "build_cache"
+)
+
+// Entry is one line of the audit log
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Initiator string    `json:"initiator"`
+	CycleID   string    `json:"cycle_id,omitempty"`
+	Action    string    `json:"action"` // e.g. stop, create, rename, remove, pull, tag, prune
+	Object    string    `json:"object"`
+	ID        string    `json:"id,omitempty"` // The object's ID, or its name if it has no other
+	Name      string    `json:"name,omitempty"`
+	Image     string    `json:"image,omitempty"`  // The image a container is created from
+	Before    string    `json:"before,omitempty"` // The name or reference the action changed, if known
+	After     string    `json:"after,omitempty"`  // What the action produced: a new ID, name or reference
+	Detail    string    `json:"detail,omitempty"`
+	Error     string    `json:"error,omitempty"` // Set when Docker refused the action
+}
+
+var (
+	mu  sync.Mutex
+	out io.WriteCloser // nil while the audit log is off
+)
+
+// Open starts writing the audit log to file, rotated once it grows past maxSizeMB with
+// maxBackups old files kept (0 keeps them all)
+func Open(file string, maxSizeMB, maxBackups int) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	f.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if out != nil {
+		out.Close()
+	}
+	out = &lumberjack.Logger{Filename: file, MaxSize: maxSizeMB, MaxBackups: maxBackups}
+	return nil
+}
+
+// Close stops writing the audit log
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if out == nil {
+		return nil
+	}
+	err := out.Close()
+	out = nil
+	return err
+}
+
+// Record appends an entry for an action, with err the error Docker returned for it, if any.
+// The time, initiator and cycle come from ctx. It does nothing while the audit log is off;
+// failing to write is logged rather than failing the action, which has already happened.
+func Record(ctx context.Context, e Entry, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if out == nil {
+		return
+	}
+
+	e.Time = time.Now().UTC()
+	e.Initiator = Initiator(ctx)
+	e.CycleID = cycleID(ctx)
+	if err != nil {
+		e.Error = err.Error()
+	}
+	line, jsonErr := json.Marshal(e)
+	if jsonErr == nil {
+		_, jsonErr = out.Write(append(line, '\n'))
+	}
+	if jsonErr != nil {
+		log.Warnf("Failed to write the audit log: %v", jsonErr)
+	}
+}
+
+type initiatorKey struct{}
+
+type cycleIDKey struct{}
+
+// WithInitiator returns a context whose Docker actions are attributed to initiator
+func WithInitiator(ctx context.Context, initiator string) context.Context {
+	return context.WithValue(ctx, initiatorKey{}, initiator)
+}
+
+// Initiator returns the initiator stored in ctx, InitiatorCycle if none
+func Initiator(ctx context.Context) string {
+	if initiator, ok := ctx.Value(initiatorKey{}).(string); ok {
+		return initiator
+	}
+	return InitiatorCycle
+}
+
+// WithCycleID returns a context whose Docker actions are recorded as part of the cycle id
+func WithCycleID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, cycleIDKey{}, id)
+}
+
+func cycleID(ctx context.Context) string {
+	id, _ := ctx.Value(cycleIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readEntries returns the entries in the audit log at path
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []Entry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("audit log line %q is not JSON: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	Record(context.Background(), Entry{Action: "stop", Object: ObjectContainer, ID: "before-open"}, nil)
+
+	if err := Open(path, 1, 1); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer Close()
+
+	ctx := WithCycleID(WithInitiator(context.Background(), InitiatorAPI), "a1b2c3d4")
+	Record(ctx, Entry{Action: "rename", Object: ObjectContainer, ID: "abc", Before: "web", After: "web-old-1"}, nil)
+	Record(context.Background(), Entry{Action: "remove", Object: ObjectImage, ID: "sha256:old"}, errors.New("image is in use"))
+
+	entries := readEntries(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("audit log has %d entries, want the 2 recorded while open: %+v", len(entries), entries)
+	}
+	rename := entries[0]
+	if rename.Initiator != InitiatorAPI || rename.CycleID != "a1b2c3d4" || rename.Before != "web" || rename.After != "web-old-1" || rename.Time.IsZero() {
+		t.Errorf("rename entry = %+v, want the API's cycle a1b2c3d4 renaming web to web-old-1", rename)
+	}
+	remove := entries[1]
+	if remove.Initiator != InitiatorCycle || remove.CycleID != "" || remove.Error != "image is in use" {
+		t.Errorf("remove entry = %+v, want a cycle's failed removal", remove)
+	}
+
+	Close()
+	Record(context.Background(), Entry{Action: "stop", Object: ObjectContainer, ID: "after-close"}, nil)
+	if got := len(readEntries(t, path)); got != 2 {
+		t.Errorf("audit log has %d entries after Close(), want 2", got)
+	}
+}
@@ -0,0 +1,42 @@
+package cleanup
+
+import (
+	"context"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// pruneBuildCache trims the build cache to cleanup.build_cache.max_size and returns the
+// space reclaimed
+func pruneBuildCache(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) (int64, error) {
+	opts := cfg.Cleanup.BuildCache
+
+	var keep int64
+	if opts.MaxSize != "" {
+		var err error
+		if keep, err = util.ParseBytes(opts.MaxSize); err != nil {
+			return 0, err
+		}
+	}
+
+	budget := "no unused cache"
+	if keep > 0 {
+		budget = util.FormatBytes(keep)
+	}
+
+	if passDryRun(cfg, opts.DryRun) {
+		logger.Info().Msgf("[DRY-RUN] 🗑️  Would prune build cache, keeping %s", budget)
+		return 0, nil
+	}
+
+	deleted, reclaimed, err := dockerClient.PruneBuildCache(ctx, keep)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Info().Msgf("🗑️  Pruned build cache, keeping %s: %d entries removed | Reclaimed: %s", budget, deleted, util.FormatBytes(reclaimed))
+	return reclaimed, nil
+}
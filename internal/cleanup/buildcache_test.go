@@ -0,0 +1,58 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/rs/zerolog"
+)
+
+func TestRunCleanup_BuildCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize string
+		dryRun  bool
+		want    []int64
+	}{
+		{name: "budget", maxSize: "5g", want: []int64{5 * 1024 * 1024 * 1024}},
+		{name: "no budget", maxSize: "", want: []int64{0}},
+		{name: "dry run", maxSize: "5g", dryRun: true, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := docker.NewMockDockerClient()
+
+			cfg := config.Default()
+			cfg.Cleanup.BuildCache.Enabled = true
+			cfg.Cleanup.BuildCache.MaxSize = tt.maxSize
+			cfg.Cleanup.BuildCache.DryRun = tt.dryRun
+
+			logger := zerolog.Nop()
+			if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+				t.Fatalf("RunCleanup() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(mockClient.BuildCachePrunes, tt.want) {
+				t.Errorf("build cache prunes = %v, want %v", mockClient.BuildCachePrunes, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCleanup_BuildCacheErrorIsNotFatal(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.PruneBuildCacheError = errors.New("buildkit not enabled")
+
+	cfg := config.Default()
+	cfg.Cleanup.BuildCache.Enabled = true
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Errorf("RunCleanup() error = %v, want nil when only the build cache prune fails", err)
+	}
+}
@@ -2,12 +2,20 @@ package cleanup
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/MikeO7/HarborBuddy/internal/config"
 	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/metrics"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/MikeO7/HarborBuddy/internal/watchdog"
 	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/distribution/reference"
 	"github.com/rs/zerolog"
 )
 
@@ -19,19 +27,48 @@ func shortID(id string) string {
 	return id
 }
 
-// RunCleanup performs image cleanup based on configuration
+// RunCleanup performs stopped container, volume, network, image and build cache cleanup based on configuration
 func RunCleanup(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) error {
 	if !cfg.Cleanup.Enabled {
 		logger.Debug().Msg("Cleanup is disabled")
 		return nil
 	}
 
+	startTime := time.Now()
+
+	simulate := wholeDryRun(cfg)
+	if simulate {
+		logger.Info().Msg("[DRY-RUN] Cleanup dry run: nothing will be removed")
+	}
+
+	// Taken before stopped containers are pruned, so the images they ran stay in scope
+	scope, err := labelScope(ctx, cfg, dockerClient)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to list containers for the label filter")
+		metrics.Default.RecordCycleFailure("cleanup")
+		return err
+	}
+
+	containersRemoved, err := runPrune(ctx, cfg.Cleanup.Containers.Enabled, cfg.Cleanup.Containers.DryRun, "stopped container", pruneContainers, cfg, dockerClient, logger)
+	if err != nil {
+		return err
+	}
+	// Volumes and networks go after containers, whose removal is what frees them
+	volumesRemoved, err := runPrune(ctx, cfg.Cleanup.Volumes.Enabled, cfg.Cleanup.Volumes.DryRun, "volume", pruneVolumes, cfg, dockerClient, logger)
+	if err != nil {
+		return err
+	}
+	networksRemoved, err := runPrune(ctx, cfg.Cleanup.Networks.Enabled, cfg.Cleanup.Networks.DryRun, "network", pruneNetworks, cfg, dockerClient, logger)
+	if err != nil {
+		return err
+	}
+
 	logger.Info().Msg("Starting image cleanup")
 
 	// List images
 	listStart := time.Now()
 	var images []docker.ImageInfo
-	var err error
+	endStep := watchdog.Begin(ctx, "listing images")
 
 	if cfg.Cleanup.DanglingOnly {
 		logger.Debug().Msg("Listing only dangling images")
@@ -40,15 +77,18 @@ func RunCleanup(ctx context.Context, cfg config.Config, dockerClient docker.Clie
 		logger.Debug().Msg("Listing all images")
 		images, err = dockerClient.ListImages(ctx)
 	}
+	endStep()
 
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to list images")
+		metrics.Default.RecordCycleFailure("cleanup")
 		return err
 	}
 
 	logger.Info().Int64("duration_ms", time.Since(listStart).Milliseconds()).Msgf("Found %d images (in %v)", len(images), time.Since(listStart))
 
 	minAge := time.Duration(cfg.Cleanup.MinAgeHours) * time.Hour
+	retained := retainedImages(cfg, logger)
 	removedCount := 0
 	skippedCount := 0
 	var totalReclaimed int64
@@ -72,47 +112,258 @@ func RunCleanup(ctx context.Context, cfg config.Config, dockerClient docker.Clie
 			Logger()
 		imageLoggerPtr := &imageLogger
 
+		// --label-filter scopes cleanup-only runs to the images of matching containers
+		if !scope.includes(image) {
+			imageLogger.Debug().Msg("Image is not used by a container matching the label filter")
+			skippedCount++
+			continue
+		}
+
+		// Keep the images --rollback would need
+		if retained[image.ID] {
+			imageLogger.Debug().Msg("Image is retained for rollback")
+			skippedCount++
+			continue
+		}
+
 		// Check if image is eligible for cleanup
 		if !isEligibleForCleanup(image, cfg.Cleanup, minAge, imageLoggerPtr) {
 			skippedCount++
 			continue
 		}
 
-		sizeStr := util.FormatBytes(image.Size)
-		// Log attempt at Debug level to reduce noise
-		imageLogger.Debug().Msgf("Attempting to remove image (tags: %v, size: %s)", image.RepoTags, sizeStr)
-
-		if err := dockerClient.RemoveImage(ctx, image.ID); err != nil {
-			imageLogger.Error().Err(err).Msg("Failed to remove image")
+		// Images of stopped containers are still wanted; if we can't tell, keep the image
+		users, err := dockerClient.GetContainersUsingImage(ctx, image.ID)
+		if err != nil {
+			imageLogger.Warn().Err(err).Msg("Failed to check which containers use the image, keeping it")
 			skippedCount++
 			continue
 		}
+		if len(users) > 0 {
+			imageLogger.Debug().Msgf("Image is used by %d containers", len(users))
+			skippedCount++
+			continue
+		}
+
+		sizeStr := util.FormatBytes(image.Size)
 
 		// Friendly "Removed" message
 		tagDisplay := "Dangling"
 		if len(image.RepoTags) > 0 {
 			tagDisplay = strings.Join(image.RepoTags, ", ")
 		} else {
-			// Try to get a friendly name from labels
-			if name := util.GetImageFriendlyName(image.Labels); name != "" {
+			// Try to get a friendly name from labels; no container uses the image
+			if name := util.GetImageFriendlyName(image.Labels, nil); name != "" {
 				tagDisplay = name
 			}
 		}
-		imageLogger.Info().Msgf("🗑️  Removed image %s (%s) | Reclaimed: %s", shortID(image.ID), tagDisplay, sizeStr)
+
+		if simulate {
+			imageLogger.Info().Msgf("[DRY-RUN] 🗑️  Would remove image %s (%s, created %s) | Would reclaim: %s", shortID(image.ID), tagDisplay, util.FormatRelative(image.CreatedAt, time.Now()), sizeStr)
+			removedCount++
+			totalReclaimed += image.Size
+			continue
+		}
+
+		// Log attempt at Debug level to reduce noise
+		imageLogger.Debug().Msgf("Attempting to remove image (tags: %v, size: %s)", image.RepoTags, sizeStr)
+
+		endStep := watchdog.Begin(ctx, "removing image "+shortID(image.ID))
+		err = dockerClient.RemoveImage(ctx, image.ID)
+		endStep()
+		if err != nil {
+			imageLogger.Error().Err(err).Msg("Failed to remove image")
+			skippedCount++
+			continue
+		}
+
+		imageLogger.Info().Msgf("🗑️  Removed image %s (%s, created %s) | Reclaimed: %s", shortID(image.ID), tagDisplay, util.FormatRelative(image.CreatedAt, time.Now()), sizeStr)
 		removedCount++
 		totalReclaimed += image.Size
+		metrics.Default.RecordImageRemoved(image.Size)
+	}
+
+	if cfg.Cleanup.BuildCache.Enabled {
+		endStep := watchdog.Begin(ctx, "pruning the build cache")
+		reclaimed, err := pruneBuildCache(ctx, cfg, dockerClient, logger)
+		endStep()
+		if err != nil {
+			if ctx.Err() != nil {
+				logger.Warn().Msg("Cleanup interrupted")
+				return err
+			}
+			logger.Error().Err(err).Msg("Failed to prune build cache")
+		}
+		totalReclaimed += reclaimed
+	}
+
+	metrics.Default.ObserveCycle("cleanup", time.Since(startTime))
+	removed := "removed"
+	if simulate {
+		removed = "would be removed"
+	}
+	summary := fmt.Sprintf("%d %s", removedCount, removed)
+	if cfg.Cleanup.Containers.Enabled || cfg.Cleanup.Volumes.Enabled || cfg.Cleanup.Networks.Enabled {
+		summary = fmt.Sprintf("%d images, %d containers, %d volumes and %d networks %s", removedCount, containersRemoved, volumesRemoved, networksRemoved, removed)
 	}
 
-	logger.Info().Msgf("✨ Cleanup complete: %d removed. Space Reclaimed: %s", removedCount, util.FormatBytes(totalReclaimed))
+	cleanupReport := report.Cleanup{
+		ImagesRemoved:     removedCount,
+		ContainersRemoved: containersRemoved,
+		VolumesRemoved:    volumesRemoved,
+		NetworksRemoved:   networksRemoved,
+		BytesReclaimed:    totalReclaimed,
+		DurationMs:        time.Since(startTime).Milliseconds(),
+	}
+
+	// A dry run reports what it found but doesn't notify: nothing was removed
+	if simulate {
+		logger.Info().Msgf("✨ [DRY-RUN] Cleanup dry run complete: %s. Space that would be reclaimed: %s", summary, util.FormatBytes(totalReclaimed))
+		cleanupReport.DryRun = true
+		report.FromContext(ctx).SetCleanup(cleanupReport)
+		return nil
+	}
+	logger.Info().Msgf("✨ Cleanup complete: %s. Space Reclaimed: %s", summary, util.FormatBytes(totalReclaimed))
+
+	notifier := notify.FromContext(ctx)
+	if notifier == nil {
+		notifier = notify.New(cfg.Notifications)
+		defer notify.Flush(ctx, notifier, logger)
+	}
+	notify.Send(ctx, notifier, notify.Event{
+		Type:              notify.EventCleanup,
+		Outcome:           notify.OutcomeSuccess,
+		ImagesRemoved:     removedCount,
+		ContainersRemoved: containersRemoved,
+		VolumesRemoved:    volumesRemoved,
+		NetworksRemoved:   networksRemoved,
+		BytesReclaimed:    totalReclaimed,
+	}, logger)
+	report.FromContext(ctx).SetCleanup(cleanupReport)
 	return nil
 }
 
+// pruneFunc removes what one prune pass covers and returns how many it removed, or in a dry
+// run how many it would have removed
+type pruneFunc func(context.Context, config.Config, docker.Client, *zerolog.Logger) (int, error)
+
+// runPrune runs one of the optional prune passes. Failures are logged and cleanup carries
+// on, unless ctx was cancelled. A pass dry-running on its own (dryRun) counts as removing
+// nothing, so the summary of a real cleanup only counts what is gone.
+func runPrune(ctx context.Context, enabled, dryRun bool, what string, prune pruneFunc, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) (int, error) {
+	if !enabled {
+		return 0, nil
+	}
+
+	logger.Info().Msgf("Starting %s cleanup", what)
+	endStep := watchdog.Begin(ctx, "removing "+what+"s")
+	n, err := prune(ctx, cfg, dockerClient, logger)
+	endStep()
+	if err != nil {
+		if ctx.Err() != nil {
+			logger.Warn().Msg("Cleanup interrupted")
+			return n, err
+		}
+		logger.Error().Err(err).Msgf("Failed to clean up %ss", what)
+	}
+	if dryRun && !wholeDryRun(cfg) {
+		logger.Info().Msgf("[DRY-RUN] %d %ss would be removed", n, what)
+		return 0, nil
+	}
+	return n, nil
+}
+
+// wholeDryRun reports whether every cleanup pass only logs what it would remove:
+// cleanup.dry_run, or updates.dry_run (--dry-run)
+func wholeDryRun(cfg config.Config) bool {
+	return cfg.Cleanup.DryRun || cfg.Updates.DryRun
+}
+
+// passDryRun reports whether a prune pass with its own dry_run setting only logs
+func passDryRun(cfg config.Config, pass bool) bool {
+	return pass || wholeDryRun(cfg)
+}
+
+// imageScope is the images --label-filter lets cleanup remove: the ones containers matching
+// the filter run or ran, and older versions of them, matched by repository. A nil scope
+// includes every image.
+type imageScope struct {
+	ids   map[string]bool
+	repos map[string]bool
+}
+
+// labelScope lists the running and stopped containers matching cfg.LabelFilter and returns
+// the scope of their images, or nil without a filter
+func labelScope(ctx context.Context, cfg config.Config, dockerClient docker.Client) (*imageScope, error) {
+	if len(cfg.LabelFilter) == 0 {
+		return nil, nil
+	}
+	running, err := dockerClient.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stopped, err := dockerClient.ListStoppedContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &imageScope{ids: map[string]bool{}, repos: map[string]bool{}}
+	for _, c := range slices.Concat(running, stopped) {
+		if !util.MatchLabels(c.Labels, cfg.LabelFilter) {
+			continue
+		}
+		scope.ids[c.ImageID] = true
+		if repo := repository(c.Image); repo != "" {
+			scope.repos[repo] = true
+		}
+	}
+	return scope, nil
+}
+
+// includes reports whether image is in scope. Dangling images only are if they were pulled
+// by digest from a repository in scope, as nothing else ties them to a container.
+func (s *imageScope) includes(image docker.ImageInfo) bool {
+	if s == nil || s.ids[image.ID] {
+		return true
+	}
+	for _, ref := range slices.Concat(image.RepoTags, image.RepoDigests) {
+		if s.repos[repository(ref)] {
+			return true
+		}
+	}
+	return false
+}
+
+// repository returns the normalized repository of an image reference, e.g.
+// "docker.io/library/nginx" for "nginx:latest", or "" if it doesn't parse
+func repository(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ""
+	}
+	return named.Name()
+}
+
+// retainedImages returns the pre-update images recorded in the state file. If the history
+// can't be read, cleanup proceeds without it rather than failing.
+func retainedImages(cfg config.Config, logger *zerolog.Logger) map[string]bool {
+	if cfg.State.File == "" {
+		return nil
+	}
+	store, err := state.Open(cfg.State.File)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to read update history, rollback images are not protected")
+		return nil
+	}
+	return store.PreviousImageIDs()
+}
+
 // isEligibleForCleanup determines if an image is eligible for cleanup
 func isEligibleForCleanup(image docker.ImageInfo, cfg config.CleanupConfig, minAge time.Duration, logger *zerolog.Logger) bool {
 	// Check if image is old enough
 	age := time.Since(image.CreatedAt)
 	if age < minAge {
-		logger.Debug().Msgf("Image is too new (age: %v, min: %v)", age, minAge)
+		logger.Debug().Msgf("Image is too new (created %s, min age: %s)", util.FormatRelative(image.CreatedAt, time.Now()), util.HumanizeDuration(minAge))
 		return false
 	}
 
@@ -124,5 +375,14 @@ func isEligibleForCleanup(image docker.ImageInfo, cfg config.CleanupConfig, minA
 		}
 	}
 
+	for _, tag := range image.RepoTags {
+		for _, pattern := range cfg.ExcludeImages {
+			if util.MatchPattern(tag, pattern) {
+				logger.Debug().Msgf("Image matches cleanup exclude pattern: %s", pattern)
+				return false
+			}
+		}
+	}
+
 	return true
 }
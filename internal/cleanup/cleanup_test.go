@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/MikeO7/HarborBuddy/internal/config"
 	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/MikeO7/HarborBuddy/internal/state"
 	"github.com/MikeO7/HarborBuddy/pkg/log"
 	"github.com/MikeO7/HarborBuddy/pkg/util"
 	"github.com/rs/zerolog"
@@ -358,6 +362,20 @@ func TestIsEligibleForCleanup(t *testing.T) {
 			minAge:   24 * time.Hour,
 			expected: true,
 		},
+		{
+			name: "tag matches an exclude pattern",
+			image: docker.ImageInfo{
+				ID:        "sha256:test5",
+				RepoTags:  []string{"backup:latest", "postgres:16"},
+				CreatedAt: now.Add(-25 * time.Hour),
+			},
+			config: config.CleanupConfig{
+				DanglingOnly:  false,
+				ExcludeImages: []string{"postgres:*"},
+			},
+			minAge:   24 * time.Hour,
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -589,7 +607,7 @@ func TestGetImageFriendlyName(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := util.GetImageFriendlyName(tt.labels); got != tt.expected {
+			if got := util.GetImageFriendlyName(tt.labels, nil); got != tt.expected {
 				t.Errorf("GetImageFriendlyName() = %v, want %v", got, tt.expected)
 			}
 		})
@@ -645,3 +663,174 @@ func TestRunCleanup_FriendlyNames(t *testing.T) {
 		t.Errorf("Log missing image ID: %q", expectedID)
 	}
 }
+
+func TestRunCleanup_LabelFilter(t *testing.T) {
+	t.Log("Testing that --label-filter scopes cleanup to the images of matching containers")
+
+	yesterday := time.Now().Add(-25 * time.Hour)
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "plex", Name: "plex", Image: "plexinc/pms:1.40", ImageID: "sha256:plex-new", Labels: map[string]string{"project": "media"}},
+		{ID: "grafana", Name: "grafana", Image: "grafana/grafana:11", ImageID: "sha256:grafana-new", Labels: map[string]string{"project": "monitoring"}},
+	}
+	mockClient.Images = []docker.ImageInfo{
+		// The image labels say nothing about the project, the containers do
+		{ID: "sha256:plex-old", RepoTags: []string{"plexinc/pms:1.39"}, CreatedAt: yesterday, Labels: map[string]string{"project": "monitoring"}},
+		{ID: "sha256:plex-dangling", Dangling: true, RepoDigests: []string{"plexinc/pms@sha256:" + strings.Repeat("a", 64)}, CreatedAt: yesterday},
+		{ID: "sha256:grafana-old", RepoTags: []string{"grafana/grafana:10"}, CreatedAt: yesterday, Labels: map[string]string{"project": "media"}},
+		{ID: "sha256:unknown", Dangling: true, CreatedAt: yesterday},
+	}
+
+	cfg := config.Config{
+		CleanupOnly: true,
+		LabelFilter: map[string]string{"project": "media"},
+		Cleanup: config.CleanupConfig{
+			Enabled:     true,
+			MinAgeHours: 24,
+		},
+	}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunCleanup(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	if want := []string{"sha256:plex-old", "sha256:plex-dangling"}; !slices.Equal(mockClient.RemovedImages, want) {
+		t.Errorf("removed %v, want only the old plex images %v", mockClient.RemovedImages, want)
+	}
+}
+
+func TestRunCleanup_RetainsRollbackImages(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	store, err := state.Open(statePath)
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	if err := store.Put("web", state.Record{PreviousImageID: "sha256:previous"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:previous", Dangling: true, CreatedAt: old},
+		{ID: "sha256:unrelated", Dangling: true, CreatedAt: old},
+	}
+
+	cfg := config.Default()
+	cfg.State.File = statePath
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	if len(mockClient.RemovedImages) != 1 || mockClient.RemovedImages[0] != "sha256:unrelated" {
+		t.Errorf("removed %v, want only sha256:unrelated", mockClient.RemovedImages)
+	}
+}
+
+func TestRunCleanup_KeepsImagesInUse(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:stopped", RepoTags: []string{"backup:latest"}, CreatedAt: old},
+		{ID: "sha256:unused", RepoTags: []string{"nginx:1.25"}, CreatedAt: old},
+	}
+	// Stopped containers count too: the mock lists every container, like All=true
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "nightly-backup", ImageID: "sha256:stopped"},
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.DanglingOnly = false
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+	if len(mockClient.RemovedImages) != 1 || mockClient.RemovedImages[0] != "sha256:unused" {
+		t.Errorf("removed %v, want only sha256:unused", mockClient.RemovedImages)
+	}
+
+	// When the containers can't be listed, nothing is removed
+	mockClient.RemovedImages = nil
+	mockClient.GetContainersUsingImageError = fmt.Errorf("daemon unavailable")
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+	if len(mockClient.RemovedImages) != 0 {
+		t.Errorf("removed %v, want nothing when container usage is unknown", mockClient.RemovedImages)
+	}
+}
+
+func TestRunCleanup_DryRun(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+
+	tests := []struct {
+		name  string
+		setup func(c *config.Config)
+	}{
+		{"cleanup dry run", func(c *config.Config) { c.Cleanup.DryRun = true }},
+		{"updates dry run", func(c *config.Config) { c.Updates.DryRun = true }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := docker.NewMockDockerClient()
+			mockClient.Images = []docker.ImageInfo{
+				{ID: "sha256:dangling1", Dangling: true, CreatedAt: old, Size: 3 * 1024 * 1024},
+				{ID: "sha256:dangling2", Dangling: true, CreatedAt: old, Size: 1024 * 1024},
+			}
+			mockClient.Containers = []docker.ContainerInfo{stoppedContainer("old1", "batch-job", 48*time.Hour, nil)}
+
+			cfg := config.Default()
+			cfg.Cleanup.Containers.Enabled = true
+			tt.setup(&cfg)
+
+			rep := report.New("abcd1234", cfg.Updates.DryRun, false)
+			ctx := report.WithReport(context.Background(), rep)
+			logger := zerolog.Nop()
+			if err := RunCleanup(ctx, cfg, mockClient, &logger); err != nil {
+				t.Fatalf("RunCleanup() error = %v", err)
+			}
+
+			if len(mockClient.RemovedImages) != 0 || len(mockClient.RemovedContainers) != 0 {
+				t.Errorf("dry run removed images %v and containers %v", mockClient.RemovedImages, mockClient.RemovedContainers)
+			}
+			want := report.Cleanup{ImagesRemoved: 2, ContainersRemoved: 1, BytesReclaimed: 4 * 1024 * 1024, DryRun: true}
+			got := *rep.Cleanup
+			got.DurationMs = 0
+			if got != want {
+				t.Errorf("report cleanup = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestRunCleanup_PassDryRunCountsNothing(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{stoppedContainer("old1", "batch-job", 48*time.Hour, nil)}
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:dangling", Dangling: true, CreatedAt: time.Now().Add(-48 * time.Hour)},
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.Containers.Enabled = true
+	cfg.Cleanup.Containers.DryRun = true
+
+	rep := report.New("abcd1234", false, false)
+	ctx := report.WithReport(context.Background(), rep)
+	logger := zerolog.Nop()
+	if err := RunCleanup(ctx, cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	// Only the container pass is a dry run; the image is really removed
+	if len(mockClient.RemovedImages) != 1 || len(mockClient.RemovedContainers) != 0 {
+		t.Errorf("removed images %v and containers %v, want only the image", mockClient.RemovedImages, mockClient.RemovedContainers)
+	}
+	if rep.Cleanup.ContainersRemoved != 0 || rep.Cleanup.ImagesRemoved != 1 || rep.Cleanup.DryRun {
+		t.Errorf("report cleanup = %+v, want 1 image removed and no containers", *rep.Cleanup)
+	}
+}
@@ -0,0 +1,109 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// cleanupLabel opts a stopped container out of container cleanup when set to "false"
+const cleanupLabel = "com.harborbuddy.cleanup"
+
+// pruneContainers removes stopped containers as configured in cleanup.containers and returns
+// how many were removed (or would be, in a dry run). It runs before image cleanup so the images they used can go too.
+func pruneContainers(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) (int, error) {
+	opts := cfg.Cleanup.Containers
+	dryRun := passDryRun(cfg, opts.DryRun)
+
+	containers, err := dockerClient.ListExitedContainers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	minAge := time.Duration(opts.MinAgeHours) * time.Hour
+	removed := 0
+
+	for _, c := range containers {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		containerLogger := logger.With().
+			Str("container_id", shortID(c.ID)).
+			Str("container_name", c.Name).
+			Logger()
+
+		finishedAt, ok := containerEligible(c, cfg, minAge, &containerLogger)
+		if !ok {
+			continue
+		}
+
+		if dryRun {
+			containerLogger.Info().Msgf("[DRY-RUN] 🗑️  Would remove stopped container %s (%s, exited %s)", c.Name, c.Image, util.FormatRelative(finishedAt, time.Now()))
+			removed++
+			continue
+		}
+
+		if err := dockerClient.RemoveContainer(ctx, c.ID); err != nil {
+			containerLogger.Error().Err(err).Msg("Failed to remove stopped container")
+			continue
+		}
+
+		containerLogger.Info().Msgf("🗑️  Removed stopped container %s (%s, exited %s)", c.Name, c.Image, util.FormatRelative(finishedAt, time.Now()))
+		removed++
+	}
+
+	return removed, nil
+}
+
+// containerEligible checks the opt-out label, name patterns, label filter and age of a
+// stopped container. It returns when the container exited.
+func containerEligible(c docker.ContainerInfo, cfg config.Config, minAge time.Duration, logger *zerolog.Logger) (time.Time, bool) {
+	opts := cfg.Cleanup.Containers
+
+	if c.Labels[cleanupLabel] == "false" {
+		logger.Debug().Msg("Skipping container: label " + cleanupLabel + "=false")
+		return time.Time{}, false
+	}
+
+	if !cfg.Updates.InScope(c.Labels) {
+		logger.Debug().Msg("Skipping container: belongs to another scope")
+		return time.Time{}, false
+	}
+
+	if !util.MatchLabels(c.Labels, cfg.LabelFilter) {
+		logger.Debug().Msg("Skipping container: does not match label filter")
+		return time.Time{}, false
+	}
+
+	for _, pattern := range opts.DenyNames {
+		if util.MatchPattern(c.Name, pattern) {
+			logger.Debug().Msgf("Skipping container: matches deny pattern: %s", pattern)
+			return time.Time{}, false
+		}
+	}
+
+	if !util.MatchAllowList(c.Name, opts.AllowNames) {
+		logger.Debug().Msg("Skipping container: not let through by the allow patterns")
+		return time.Time{}, false
+	}
+
+	if c.State == nil {
+		return time.Time{}, false
+	}
+	finishedAt, err := time.Parse(time.RFC3339Nano, c.State.FinishedAt)
+	if err != nil || finishedAt.IsZero() {
+		logger.Debug().Msg("Skipping container: exit time unknown")
+		return time.Time{}, false
+	}
+	if time.Since(finishedAt) < minAge {
+		logger.Debug().Msgf("Skipping container: exited too recently (%s, min age: %s)", util.FormatRelative(finishedAt, time.Now()), util.HumanizeDuration(minAge))
+		return time.Time{}, false
+	}
+
+	return finishedAt, true
+}
@@ -0,0 +1,110 @@
+package cleanup
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types"
+	"github.com/rs/zerolog"
+)
+
+func stoppedContainer(id, name string, exited time.Duration, labels map[string]string) docker.ContainerInfo {
+	return docker.ContainerInfo{
+		ID:     id,
+		Name:   name,
+		Image:  "busybox",
+		Labels: labels,
+		State: &types.ContainerState{
+			Status:     "exited",
+			FinishedAt: time.Now().Add(-exited).Format(time.RFC3339Nano),
+		},
+	}
+}
+
+func TestRunCleanup_StoppedContainers(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		stoppedContainer("old1", "batch-job", 48*time.Hour, nil),
+		stoppedContainer("new1", "migrate", time.Hour, nil),
+		stoppedContainer("keep1", "debug-shell", 48*time.Hour, map[string]string{cleanupLabel: "false"}),
+		stoppedContainer("deny1", "db-backup", 48*time.Hour, nil),
+		{ID: "run1", Name: "web", State: &types.ContainerState{Status: "running", Running: true}},
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.Containers.Enabled = true
+	cfg.Cleanup.Containers.DenyNames = []string{"db-*"}
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	if want := []string{"old1"}; !reflect.DeepEqual(mockClient.RemovedContainers, want) {
+		t.Errorf("removed containers = %v, want %v", mockClient.RemovedContainers, want)
+	}
+}
+
+func TestRunCleanup_StoppedContainersDryRun(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{stoppedContainer("old1", "batch-job", 48*time.Hour, nil)}
+
+	cfg := config.Default()
+	cfg.Cleanup.Containers.Enabled = true
+	cfg.Cleanup.Containers.DryRun = true
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	if len(mockClient.RemovedContainers) != 0 {
+		t.Errorf("dry-run removed containers %v", mockClient.RemovedContainers)
+	}
+}
+
+func TestRunCleanup_StoppedContainersListError(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.ListExitedContainersError = context.DeadlineExceeded
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:dangling", Dangling: true, CreatedAt: time.Now().Add(-48 * time.Hour)},
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.Containers.Enabled = true
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v, want image cleanup to continue", err)
+	}
+
+	if len(mockClient.RemovedImages) != 1 {
+		t.Errorf("removed images = %v, want the dangling image", mockClient.RemovedImages)
+	}
+}
+
+func TestRunCleanup_StoppedContainersScope(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		stoppedContainer("mine1", "batch-job", 48*time.Hour, map[string]string{config.ScopeLabel: "teamA"}),
+		stoppedContainer("other1", "report", 48*time.Hour, map[string]string{config.ScopeLabel: "teamB"}),
+		stoppedContainer("none1", "migrate", 48*time.Hour, nil),
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.Containers.Enabled = true
+	cfg.Updates.Scope = "teamA"
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	if want := []string{"mine1"}; !reflect.DeepEqual(mockClient.RemovedContainers, want) {
+		t.Errorf("removed containers = %v, want %v", mockClient.RemovedContainers, want)
+	}
+}
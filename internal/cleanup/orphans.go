@@ -0,0 +1,139 @@
+package cleanup
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// orphanName matches the names HarborBuddy gives containers it may leave behind:
+// "<name>-old-<unix>" blue-green backups and "<name>-updater-<unix>" self-update helpers
+var orphanName = regexp.MustCompile(`^(.+)-(old|updater)-(\d+)$`)
+
+// RemoveOrphans removes stopped backup and self-update helper containers left behind by
+// interrupted or failed updates, as configured in cleanup.orphans, and returns how many were
+// removed. Only containers HarborBuddy can show it made are touched: a backup has to be the
+// container the one now holding its original name was created to replace (docker.ReplacesLabel),
+// and a helper has to carry docker.HelperLabel. Anything else with a matching name is the
+// user's own.
+func RemoveOrphans(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) (int, error) {
+	opts := cfg.Cleanup.Orphans
+	if !opts.Enabled {
+		return 0, nil
+	}
+	dryRun := passDryRun(cfg, opts.DryRun)
+
+	stopped, err := dockerClient.ListStoppedContainers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	running, err := dockerClient.ListContainers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byName := make(map[string]docker.ContainerInfo, len(stopped)+len(running))
+	for _, c := range append(running, stopped...) {
+		byName[c.Name] = c
+	}
+
+	inflight := inflightContainers(cfg, logger)
+	minAge := time.Duration(opts.MinAgeHours) * time.Hour
+	removed := 0
+
+	for _, c := range stopped {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		m := orphanName.FindStringSubmatch(c.Name)
+		if m == nil {
+			continue
+		}
+		original, kind := m[1], "self-update helper"
+		if m[2] == "old" {
+			kind = "backup"
+		}
+		sec, _ := strconv.ParseInt(m[3], 10, 64)
+		leftAt := time.Unix(sec, 0)
+
+		containerLogger := logger.With().
+			Str("container_id", shortID(c.ID)).
+			Str("container_name", c.Name).
+			Logger()
+
+		if c.Labels[cleanupLabel] == "false" {
+			containerLogger.Debug().Msg("Skipping leftover container: label " + cleanupLabel + "=false")
+			continue
+		}
+		if !cfg.Updates.InScope(c.Labels) {
+			containerLogger.Debug().Msg("Skipping leftover container: belongs to another scope")
+			continue
+		}
+		if inflight[c.ID] {
+			containerLogger.Debug().Msg("Skipping leftover container: its replacement is still recorded as in progress")
+			continue
+		}
+		if time.Since(leftAt) < minAge {
+			containerLogger.Debug().Msgf("Skipping leftover container: too new (%s, min age: %s)", util.FormatRelative(leftAt, time.Now()), util.HumanizeDuration(minAge))
+			continue
+		}
+		if kind == "backup" {
+			current, exists := byName[original]
+			if !exists {
+				containerLogger.Warn().Msgf("Keeping backup container %s: no container named %s exists, so it may be the only copy. Rename it back with `docker rename %s %s` or remove it yourself.", c.Name, original, c.Name, original)
+				continue
+			}
+			if current.Labels[docker.ReplacesLabel] != c.ID {
+				containerLogger.Debug().Msgf("Skipping container: %s was not created to replace it, so it isn't a HarborBuddy backup", original)
+				continue
+			}
+		}
+		if kind == "self-update helper" && c.Labels[docker.HelperLabel] != "true" {
+			containerLogger.Debug().Msg("Skipping container: not a helper HarborBuddy made")
+			continue
+		}
+
+		if dryRun {
+			containerLogger.Info().Msgf("[DRY-RUN] 🗑️  Would remove leftover %s container %s (left %s)", kind, c.Name, util.FormatRelative(leftAt, time.Now()))
+			continue
+		}
+
+		if err := dockerClient.RemoveContainer(ctx, c.ID); err != nil {
+			containerLogger.Error().Err(err).Msgf("Failed to remove leftover %s container", kind)
+			continue
+		}
+
+		containerLogger.Info().Msgf("🗑️  Removed leftover %s container %s (left %s)", kind, c.Name, util.FormatRelative(leftAt, time.Now()))
+		removed++
+	}
+
+	return removed, nil
+}
+
+// inflightContainers returns the IDs of containers whose replacement is still recorded in
+// the state file; Recover owns those until it has put them back in order
+func inflightContainers(cfg config.Config, logger *zerolog.Logger) map[string]bool {
+	if cfg.State.File == "" {
+		return nil
+	}
+	store, err := state.Open(cfg.State.File)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to read update state, interrupted replacements are not protected")
+		return nil
+	}
+	ids := make(map[string]bool)
+	for _, rec := range store.Inflight() {
+		ids[rec.OldID] = true
+		if rec.NewID != "" {
+			ids[rec.NewID] = true
+		}
+	}
+	return ids
+}
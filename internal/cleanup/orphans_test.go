@@ -0,0 +1,91 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/docker/docker/api/types"
+	"github.com/rs/zerolog"
+)
+
+// leftoverName names a backup or helper container the way HarborBuddy does, left age ago
+func leftoverName(name, kind string, age time.Duration) string {
+	return fmt.Sprintf("%s-%s-%d", name, kind, time.Now().Add(-age).Unix())
+}
+
+func TestRemoveOrphans(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	store, _ := state.Open(statePath)
+	if err := store.SetInflight(state.Inflight{Name: "db", OldID: "inflight1", Step: "swapped"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "web1", Name: "web", Labels: map[string]string{docker.ReplacesLabel: "backup1"}, State: &types.ContainerState{Status: "running", Running: true}},
+		{ID: "hb1", Name: "harborbuddy", State: &types.ContainerState{Status: "running", Running: true}},
+		stoppedContainer("backup1", leftoverName("web", "old", 3*time.Hour), 3*time.Hour, nil),
+		stoppedContainer("helper1", leftoverName("harborbuddy", "updater", 3*time.Hour), 3*time.Hour, map[string]string{docker.HelperLabel: "true"}),
+		stoppedContainer("recent1", leftoverName("web", "old", time.Minute), time.Minute, nil),
+		stoppedContainer("lone1", leftoverName("api", "old", 3*time.Hour), 3*time.Hour, nil),
+		stoppedContainer("inflight1", leftoverName("db", "old", 3*time.Hour), 3*time.Hour, nil),
+		stoppedContainer("keep1", leftoverName("web", "old", 4*time.Hour), 4*time.Hour, map[string]string{cleanupLabel: "false"}),
+		stoppedContainer("other1", "batch-job", 48*time.Hour, nil),
+		{ID: "db1", Name: "db", State: &types.ContainerState{Status: "running", Running: true}},
+		// The user's own containers, named like leftovers but not made by HarborBuddy
+		stoppedContainer("mine1", "db-old-2", 3*time.Hour, nil),
+		stoppedContainer("mine2", leftoverName("web", "old", 5*time.Hour), 5*time.Hour, nil),
+		stoppedContainer("mine3", leftoverName("harborbuddy", "updater", 3*time.Hour), 3*time.Hour, nil),
+	}
+	// A helper that was created but never started
+	mockClient.Containers[3].State.Status = "created"
+
+	cfg := config.Default()
+	cfg.State.File = statePath
+	logger := zerolog.Nop()
+
+	removed, err := RemoveOrphans(context.Background(), cfg, mockClient, &logger)
+	if err != nil {
+		t.Fatalf("RemoveOrphans() error = %v", err)
+	}
+	if want := []string{"backup1", "helper1"}; removed != 2 || !reflect.DeepEqual(mockClient.RemovedContainers, want) {
+		t.Errorf("removed %d: %v, want %v", removed, mockClient.RemovedContainers, want)
+	}
+}
+
+func TestRemoveOrphans_DryRunAndDisabled(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		setup func(*config.Config)
+	}{
+		{"dry run", func(c *config.Config) { c.Cleanup.Orphans.DryRun = true }},
+		{"updates dry run", func(c *config.Config) { c.Updates.DryRun = true }},
+		{"disabled", func(c *config.Config) { c.Cleanup.Orphans.Enabled = false }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := docker.NewMockDockerClient()
+			mockClient.Containers = []docker.ContainerInfo{
+				{ID: "web1", Name: "web", Labels: map[string]string{docker.ReplacesLabel: "backup1"}, State: &types.ContainerState{Status: "running", Running: true}},
+				stoppedContainer("backup1", leftoverName("web", "old", 3*time.Hour), 3*time.Hour, nil),
+			}
+
+			cfg := config.Default()
+			tt.setup(&cfg)
+			logger := zerolog.Nop()
+
+			if _, err := RemoveOrphans(context.Background(), cfg, mockClient, &logger); err != nil {
+				t.Fatalf("RemoveOrphans() error = %v", err)
+			}
+			if len(mockClient.RemovedContainers) != 0 {
+				t.Errorf("removed containers %v", mockClient.RemovedContainers)
+			}
+		})
+	}
+}
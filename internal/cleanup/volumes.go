@@ -0,0 +1,150 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// anonymousVolumeLabel is set by Docker 23+ on volumes it created without a name
+const anonymousVolumeLabel = "com.docker.volume.anonymous"
+
+// pruneVolumes removes volumes no container uses, as configured in cleanup.volumes,
+// and returns how many were removed (or would be, in a dry run)
+func pruneVolumes(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) (int, error) {
+	opts := cfg.Cleanup.Volumes
+	dryRun := passDryRun(cfg, opts.DryRun)
+
+	volumes, err := dockerClient.ListDanglingVolumes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	minAge := time.Duration(opts.MinAgeHours) * time.Hour
+	removed := 0
+
+	for _, v := range volumes {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		volumeLogger := logger.With().Str("volume", v.Name).Logger()
+
+		if !opts.Named && !isAnonymousVolume(v) {
+			volumeLogger.Debug().Msg("Skipping named volume (cleanup.volumes.named is off)")
+			continue
+		}
+		if !pruneEligible(v.Labels, v.Name, v.CreatedAt, minAge, opts.DenyNames, cfg.LabelFilter, &volumeLogger) {
+			continue
+		}
+
+		if dryRun {
+			volumeLogger.Info().Msgf("[DRY-RUN] 🗑️  Would remove unused volume %s (created %s)", v.Name, util.FormatRelative(v.CreatedAt, time.Now()))
+			removed++
+			continue
+		}
+
+		if err := dockerClient.RemoveVolume(ctx, v.Name); err != nil {
+			volumeLogger.Error().Err(err).Msg("Failed to remove volume")
+			continue
+		}
+
+		volumeLogger.Info().Msgf("🗑️  Removed unused volume %s (created %s)", v.Name, util.FormatRelative(v.CreatedAt, time.Now()))
+		removed++
+	}
+
+	return removed, nil
+}
+
+// pruneNetworks removes user-defined networks with no containers, as configured in
+// cleanup.networks, and returns how many were removed (or would be, in a dry run)
+func pruneNetworks(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) (int, error) {
+	opts := cfg.Cleanup.Networks
+	dryRun := passDryRun(cfg, opts.DryRun)
+
+	networks, err := dockerClient.ListUnusedNetworks(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	minAge := time.Duration(opts.MinAgeHours) * time.Hour
+	removed := 0
+
+	for _, n := range networks {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		networkLogger := logger.With().Str("network", n.Name).Logger()
+
+		if !pruneEligible(n.Labels, n.Name, n.CreatedAt, minAge, opts.DenyNames, cfg.LabelFilter, &networkLogger) {
+			continue
+		}
+
+		if dryRun {
+			networkLogger.Info().Msgf("[DRY-RUN] 🗑️  Would remove unused network %s (created %s)", n.Name, util.FormatRelative(n.CreatedAt, time.Now()))
+			removed++
+			continue
+		}
+
+		if err := dockerClient.RemoveNetwork(ctx, n.ID); err != nil {
+			networkLogger.Error().Err(err).Msg("Failed to remove network")
+			continue
+		}
+
+		networkLogger.Info().Msgf("🗑️  Removed unused network %s (created %s)", n.Name, util.FormatRelative(n.CreatedAt, time.Now()))
+		removed++
+	}
+
+	return removed, nil
+}
+
+// isAnonymousVolume reports whether Docker generated the volume's name. Older daemons don't
+// set the anonymous label, but their anonymous volumes have a 64-character hex name.
+func isAnonymousVolume(v docker.VolumeInfo) bool {
+	if _, ok := v.Labels[anonymousVolumeLabel]; ok {
+		return true
+	}
+	if len(v.Name) != 64 {
+		return false
+	}
+	for _, r := range v.Name {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneEligible applies the opt-out label, label filter, deny patterns and minimum age
+// shared by volume and network cleanup
+func pruneEligible(labels map[string]string, name string, createdAt time.Time, minAge time.Duration, deny []string, labelFilter map[string]string, logger *zerolog.Logger) bool {
+	if labels[cleanupLabel] == "false" {
+		logger.Debug().Msg("Skipping: label " + cleanupLabel + "=false")
+		return false
+	}
+
+	if !util.MatchLabels(labels, labelFilter) {
+		logger.Debug().Msg("Skipping: does not match label filter")
+		return false
+	}
+
+	for _, pattern := range deny {
+		if util.MatchPattern(name, pattern) {
+			logger.Debug().Msgf("Skipping: matches deny pattern: %s", pattern)
+			return false
+		}
+	}
+
+	// An unknown creation time can't prove the minimum age
+	if createdAt.IsZero() || time.Since(createdAt) < minAge {
+		logger.Debug().Msgf("Skipping: too new (min age: %s)", util.HumanizeDuration(minAge))
+		return false
+	}
+
+	return true
+}
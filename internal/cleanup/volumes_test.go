@@ -0,0 +1,127 @@
+package cleanup
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/rs/zerolog"
+)
+
+func TestRunCleanup_Volumes(t *testing.T) {
+	anonymous := strings.Repeat("ab", 32)
+	old := time.Now().Add(-48 * time.Hour)
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Volumes = []docker.VolumeInfo{
+		{Name: anonymous, CreatedAt: old},
+		{Name: "labelled", CreatedAt: old, Labels: map[string]string{anonymousVolumeLabel: ""}},
+		{Name: "pgdata", CreatedAt: old},
+		{Name: strings.Repeat("cd", 32), CreatedAt: time.Now()},
+		{Name: strings.Repeat("ef", 32), CreatedAt: old, Labels: map[string]string{cleanupLabel: "false"}},
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.Volumes.Enabled = true
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	if want := []string{anonymous, "labelled"}; !reflect.DeepEqual(mockClient.RemovedVolumes, want) {
+		t.Errorf("removed volumes = %v, want %v", mockClient.RemovedVolumes, want)
+	}
+}
+
+func TestRunCleanup_NamedVolumes(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Volumes = []docker.VolumeInfo{
+		{Name: "pgdata", CreatedAt: old},
+		{Name: "backup-cache", CreatedAt: old},
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.Volumes.Enabled = true
+	cfg.Cleanup.Volumes.Named = true
+	cfg.Cleanup.Volumes.DenyNames = []string{"pg*"}
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	if want := []string{"backup-cache"}; !reflect.DeepEqual(mockClient.RemovedVolumes, want) {
+		t.Errorf("removed volumes = %v, want %v", mockClient.RemovedVolumes, want)
+	}
+}
+
+func TestRunCleanup_Networks(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Networks = []docker.NetworkInfo{
+		{ID: "net1", Name: "old_default", CreatedAt: old},
+		{ID: "net2", Name: "fresh_default", CreatedAt: time.Now()},
+		{ID: "net3", Name: "shared", CreatedAt: old, Labels: map[string]string{cleanupLabel: "false"}},
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.Networks.Enabled = true
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	if want := []string{"net1"}; !reflect.DeepEqual(mockClient.RemovedNetworks, want) {
+		t.Errorf("removed networks = %v, want %v", mockClient.RemovedNetworks, want)
+	}
+}
+
+func TestRunCleanup_VolumesAndNetworksDryRun(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Volumes = []docker.VolumeInfo{{Name: strings.Repeat("ab", 32), CreatedAt: old}}
+	mockClient.Networks = []docker.NetworkInfo{{ID: "net1", Name: "old_default", CreatedAt: old}}
+
+	cfg := config.Default()
+	cfg.Cleanup.Volumes.Enabled = true
+	cfg.Cleanup.Networks.Enabled = true
+	cfg.Updates.DryRun = true
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v", err)
+	}
+
+	if len(mockClient.RemovedVolumes) != 0 || len(mockClient.RemovedNetworks) != 0 {
+		t.Errorf("dry-run removed volumes %v and networks %v", mockClient.RemovedVolumes, mockClient.RemovedNetworks)
+	}
+}
+
+func TestRunCleanup_VolumeListErrorContinues(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.ListVolumesError = context.DeadlineExceeded
+	mockClient.Networks = []docker.NetworkInfo{{ID: "net1", Name: "old_default", CreatedAt: time.Now().Add(-48 * time.Hour)}}
+
+	cfg := config.Default()
+	cfg.Cleanup.Volumes.Enabled = true
+	cfg.Cleanup.Networks.Enabled = true
+
+	logger := zerolog.Nop()
+	if err := RunCleanup(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunCleanup() error = %v, want nil when only volume listing fails", err)
+	}
+
+	if len(mockClient.RemovedNetworks) != 1 {
+		t.Errorf("removed networks = %v, want network cleanup to run after the volume error", mockClient.RemovedNetworks)
+	}
+}
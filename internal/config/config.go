@@ -2,11 +2,17 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/MikeO7/HarborBuddy/pkg/util"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,29 +23,260 @@ type Config struct {
 	Cleanup CleanupConfig `yaml:"cleanup"`
 	Log     LogConfig     `yaml:"log"`
 	Logging LoggingConfig `yaml:"logging"`
+	Audit   AuditConfig   `yaml:"audit"`
+	Metrics MetricsConfig `yaml:"metrics"`
+
+	Notifications NotificationsConfig `yaml:"notifications"`
+	API           APIConfig           `yaml:"api"`
+	MQTT          MQTTConfig          `yaml:"mqtt"`
+	Hooks         HooksConfig         `yaml:"hooks"`
+
+	State  StateConfig  `yaml:"state"`
+	Report ReportConfig `yaml:"report"`
+	Verify VerifyConfig `yaml:"verify"`
+	Scan   ScanConfig   `yaml:"scan"`
+
+	SelfUpdate SelfUpdateConfig `yaml:"selfupdate"`
+
+	// Registries maps registry hosts (e.g., "ghcr.io", "docker.io") to pull credentials
+	Registries map[string]RegistryAuth `yaml:"registries"`
+
+	// Profiles are named overrides of the settings above, merged over them when selected
+	// with --profile or HARBORBUDDY_PROFILE. Cleared once loaded.
+	Profiles map[string]yaml.Node `yaml:"profiles,omitempty"`
 
 	// Runtime flags (not in YAML)
-	RunOnce     bool
-	CleanupOnly bool
+	Profile     string            `yaml:"-"` // Profile the configuration was loaded with
+	RunOnce     bool              `yaml:"-"`
+	CleanupOnly bool              `yaml:"-"`
+	LabelFilter map[string]string `yaml:"-"` // Only act on containers with these labels, and on the images they use
+	Rollback    string            `yaml:"-"` // Container to roll back to its previous image, then exit
+	Targets     []string          `yaml:"-"` // Container names or patterns to update now, then exit
+	Force       bool              `yaml:"-"` // Update Targets even if labels, allow/deny patterns or pins exclude them
+	Output      string            `yaml:"-"` // OutputText or OutputJSON: how a one-shot run reports its result
 }
 
+// Output formats of one-shot runs (--output)
+const (
+	OutputText = "text" // Logs only
+	OutputJSON = "json" // The cycle report on stdout, logs on stderr
+)
+
 // DockerConfig holds Docker connection settings
 type DockerConfig struct {
-	Host string `yaml:"host"`
-	TLS  bool   `yaml:"tls"`
+	Host          string `yaml:"host"`
+	TLS           bool   `yaml:"tls"`
+	CacheInspects bool   `yaml:"cache_inspects"` // Cache inspect results, invalidated by Docker events
 }
 
 // UpdatesConfig holds update behavior settings
 type UpdatesConfig struct {
 	Enabled       bool          `yaml:"enabled"`
 	UpdateAll     bool          `yaml:"update_all"`
+	LabelEnable   bool          `yaml:"label_enable"` // Only update containers labelled com.harborbuddy.autoupdate=true
+	Scope         string        `yaml:"scope"`        // Only manage containers whose com.harborbuddy.scope label matches
 	CheckInterval time.Duration `yaml:"check_interval"`
+	InitialDelay  time.Duration `yaml:"initial_delay"` // Wait this long after startup before the first interval cycle
 	ScheduleTime  string        `yaml:"schedule_time"` // Time to run daily (e.g., "03:00", "15:30")
 	Timezone      string        `yaml:"timezone"`      // Timezone for schedule (e.g., "America/Los_Angeles", "UTC")
 	DryRun        bool          `yaml:"dry_run"`
+	MonitorOnly   bool          `yaml:"monitor_only"` // Pull and report available updates, never replace containers
+	CheckMethod   string        `yaml:"check_method"` // "pull" or "digest" (compare registry manifest digest before pulling)
+	Policy        string        `yaml:"policy"`       // Tag policy: digest (follow the pinned tag), patch, minor or major
+	Policies      []PolicyRule  `yaml:"policies"`     // Per-image policy overrides, first matching pattern wins
+	Track         Track         `yaml:"track"`        // Per-repository tag containers follow instead of the one they run
+	Strategy      string        `yaml:"strategy"`     // "blue_green" or "recreate"; containers can override it with a label
 	AllowImages   []string      `yaml:"allow_images"`
 	DenyImages    []string      `yaml:"deny_images"`
 	StopTimeout   time.Duration `yaml:"stop_timeout"`
+
+	// AllowContainers and DenyContainers filter by container name, for containers that share
+	// an image but shouldn't all be updated. Empty AllowContainers allows every name.
+	AllowContainers []string `yaml:"allow_containers"`
+	DenyContainers  []string `yaml:"deny_containers"`
+
+	// OnContainerStart checks a container as soon as it starts, and the containers of an image
+	// pulled by hand, rather than waiting for the next scheduled cycle. It follows the Docker
+	// events stream.
+	OnContainerStart bool `yaml:"on_container_start"`
+
+	// CanaryContainers are updated before any other container, like containers labelled
+	// com.harborbuddy.canary=true. If one fails its update (e.g. its health check), the
+	// cycle's remaining updates are aborted.
+	CanaryContainers []string `yaml:"canary_containers"`
+
+	// HealthTimeout is how long a replaced container has to become healthy before it is
+	// rolled back to the old one. It is off (0) unless set, since a slow-starting container
+	// would otherwise be rolled back. Images without a HEALTHCHECK pass if they are still
+	// running after HealthGracePeriod.
+	HealthTimeout     time.Duration `yaml:"health_timeout"`
+	HealthGracePeriod time.Duration `yaml:"health_grace_period"`
+	// HealthLogLines is how many of its last log lines a container that fails the health
+	// check leaves in the error log and failure notification (0 leaves none)
+	HealthLogLines int `yaml:"health_log_lines"`
+
+	// PullTimeout bounds one pull attempt (0, the default, means no limit). Pulls failing for
+	// reasons that may pass (registry 5xx, rate limits, DNS or connection errors) are retried
+	// up to PullRetries times, waiting 2s, 4s, 8s... in between; the default 0 never retries.
+	PullTimeout time.Duration `yaml:"pull_timeout"`
+	PullRetries int           `yaml:"pull_retries"`
+
+	// MaxConcurrentPulls caps the image pulls running at once (0 leaves them to the check
+	// concurrency). RegistryPullLimits caps them per registry host, e.g. "docker.io": 1.
+	MaxConcurrentPulls int            `yaml:"max_concurrent_pulls"`
+	RegistryPullLimits map[string]int `yaml:"registry_pull_limits"`
+
+	// MinImageAge holds back updates to images built less than this long ago (0 disables), so
+	// a broken release can be pulled upstream before it is adopted
+	MinImageAge time.Duration `yaml:"min_image_age"`
+
+	// MinFreeSpace skips pulls that would leave less than this free on the disk holding
+	// Docker's data root, e.g. "5g" (empty disables). The data root is found as for
+	// cleanup.trigger_at_usage, through cleanup.data_root.
+	MinFreeSpace string `yaml:"min_free_space"`
+
+	// CycleTimeout aborts an update cycle, including its cleanup, that runs longer than this
+	// (0 means no limit), so a wedged Docker or registry call can't stall the scheduler
+	CycleTimeout time.Duration `yaml:"cycle_timeout"`
+
+	// DrainTimeout is how long a container replacement already under way may keep going after
+	// shutdown (SIGTERM) or the cycle timeout, before it is cancelled too. Nothing new starts.
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+
+	// HookTimeout bounds lifecycle hook commands (com.harborbuddy.lifecycle.* labels);
+	// containers can override it with a *-timeout label
+	HookTimeout time.Duration `yaml:"hook_timeout"`
+
+	// MaxParallelUpdates is how many containers may be replaced at once. Containers linked by
+	// a shared network namespace or a compose project are always replaced one at a time.
+	MaxParallelUpdates int `yaml:"max_parallel_updates"`
+
+	// MaxUpdatesPerCycle caps how many containers one cycle updates (0 means no limit). The
+	// rest are deferred to later cycles; com.harborbuddy.priority and then the age of the
+	// running image decide which go first.
+	MaxUpdatesPerCycle int `yaml:"max_updates_per_cycle"`
+
+	// StaggerDelay spaces out container replacements so they don't all restart back to back.
+	// Each wait is extended by a random amount up to StaggerJitter.
+	StaggerDelay  time.Duration `yaml:"stagger_delay"`
+	StaggerJitter time.Duration `yaml:"stagger_jitter"`
+
+	// Dependencies maps a container name to the containers it depends on, like the
+	// com.harborbuddy.depends-on label. Dependencies are updated first, and replacing
+	// one restarts its dependents.
+	Dependencies map[string][]string `yaml:"dependencies"`
+
+	// PinsFile holds containers and image repositories at a tag or digest (see the pins
+	// package). It is re-read whenever it changes; a missing file means nothing is pinned.
+	PinsFile string `yaml:"pins_file"`
+
+	// PinDigests tags each image an update deploys as <repository>:harborbuddy-current and
+	// records its digest in the history journal, so the exact version running can be started
+	// again after the upstream tag moves on
+	PinDigests bool `yaml:"pin_digests"`
+
+	// SuggestDigests checks containers started from an image pinned by digest
+	// (image@sha256:...), which are never updated, and reports a newer digest of the tag they
+	// follow as an available update
+	SuggestDigests bool `yaml:"suggest_digests"`
+}
+
+// ScopeLabel assigns a container to the HarborBuddy instance with the same updates.scope
+const ScopeLabel = "com.harborbuddy.scope"
+
+// InScope reports whether a container with these labels belongs to this instance. A scoped
+// instance only manages containers labelled with its scope; an unscoped one only manages
+// containers without a scope, so instances sharing a daemon never touch each other's.
+func (u UpdatesConfig) InScope(labels map[string]string) bool {
+	return labels[ScopeLabel] == u.Scope
+}
+
+// Update check methods
+const (
+	CheckMethodPull   = "pull"   // Pull the image and compare image IDs
+	CheckMethodDigest = "digest" // HEAD the registry manifest and only pull when the digest changed
+)
+
+// Update strategies control how a container is swapped for its replacement
+const (
+	StrategyBlueGreen = "blue_green" // Create the new container beside the old one, swap names, keep a backup until it is healthy
+	StrategyRecreate  = "recreate"   // Stop and remove the old container, then create the new one under the same name
+)
+
+// ValidStrategy reports whether s is a known update strategy
+func ValidStrategy(s string) bool {
+	return s == StrategyBlueGreen || s == StrategyRecreate
+}
+
+// Update policies control which tags a container may move to
+const (
+	PolicyDigest = "digest" // Stay on the pinned tag, update when its image changes
+	PolicyPatch  = "patch"  // Move to newer x.y.Z tags
+	PolicyMinor  = "minor"  // Move to newer x.Y.z tags
+	PolicyMajor  = "major"  // Move to any newer version tag
+)
+
+// PolicyRule applies an update policy to images matching a pattern
+type PolicyRule struct {
+	Pattern string `yaml:"pattern"`
+	Policy  string `yaml:"policy"`
+}
+
+// Track maps an image repository, as containers reference it (e.g. "nginx", "ghcr.io/org/app"),
+// to the tag its containers follow, e.g. "1.25-alpine" for containers running nginx:latest
+type Track map[string]string
+
+// StateConfig holds where HarborBuddy persists update history
+type StateConfig struct {
+	File        string `yaml:"file"`         // JSON state file; empty disables rollback support
+	HistoryFile string `yaml:"history_file"` // JSONL journal of every update cycle; empty disables it
+	PauseFile   string `yaml:"pause_file"`   // While this file exists no update or cleanup runs; empty disables pausing
+	StatusFile  string `yaml:"status_file"`  // JSON file of what the last check found per container, for other tools; empty disables it
+}
+
+// ReportConfig controls the JSON summary written at the end of every cycle
+type ReportConfig struct {
+	File    string        `yaml:"file"` // Replaced with the latest cycle's report; empty disables it
+	URL     string        `yaml:"url"`  // POST each report here; empty disables it
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// VerifyConfig requires new images to carry a cosign signature made with one of the trusted
+// keys before they replace a container
+type VerifyConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	PublicKeys []string `yaml:"public_keys"` // PEM files of trusted public keys (cosign.pub)
+	Images     []string `yaml:"images"`      // Image patterns that must be signed; empty means every image
+}
+
+// ScanConfig runs Trivy against new images before they replace a container, and skips
+// updates whose image has more critical vulnerabilities than allowed
+type ScanConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Trivy         string        `yaml:"trivy"`          // Path of the trivy binary
+	Server        string        `yaml:"server"`         // Trivy server to scan with (client/server mode); empty scans with a local database
+	MaxCritical   int           `yaml:"max_critical"`   // Critical vulnerabilities an image may have and still be applied
+	IgnoreUnfixed bool          `yaml:"ignore_unfixed"` // Only count vulnerabilities that have a fixed version
+	Images        []string      `yaml:"images"`         // Image patterns to scan; empty means every image
+	Timeout       time.Duration `yaml:"timeout"`
+}
+
+// SelfUpdateConfig controls whether and to what HarborBuddy updates its own container
+type SelfUpdateConfig struct {
+	Channel string `yaml:"channel"` // SelfUpdateStable, SelfUpdateBeta or SelfUpdateNone
+	Image   string `yaml:"image"`   // Repository to follow, without a tag; empty follows the one it runs from
+}
+
+// Self-update channels (selfupdate.channel)
+const (
+	SelfUpdateStable = "stable" // Released versions (the "latest" tag), or the version tag it runs
+	SelfUpdateBeta   = "beta"   // Builds of the main branch (the "beta" tag)
+	SelfUpdateNone   = "none"   // Never update itself
+)
+
+// Enabled reports whether cycle reports go anywhere
+func (r ReportConfig) Enabled() bool {
+	return r.File != "" || r.URL != ""
 }
 
 // CleanupConfig holds image cleanup settings
@@ -47,6 +284,101 @@ type CleanupConfig struct {
 	Enabled      bool `yaml:"enabled"`
 	MinAgeHours  int  `yaml:"min_age_hours"`
 	DanglingOnly bool `yaml:"dangling_only"`
+
+	// DryRun logs what every cleanup pass would remove, and the space it would reclaim,
+	// without removing anything. updates.dry_run (--dry-run) turns it on too.
+	DryRun bool `yaml:"dry_run"`
+
+	// ExcludeImages are image patterns (e.g. "postgres:*", "ghcr.io/acme/*") never removed,
+	// matched against every tag of an image
+	ExcludeImages []string `yaml:"exclude_images"`
+
+	// Cleanup runs after every update cycle unless it has its own schedule.
+	// ScheduleTime (daily, in updates.timezone) takes priority over CheckInterval.
+	CheckInterval time.Duration `yaml:"check_interval"`
+	ScheduleTime  string        `yaml:"schedule_time"`
+
+	// RunBeforeUpdates runs the cycle's cleanup before its updates rather than after, so
+	// space is reclaimed before new images are pulled. Images replaced in a cycle are then
+	// removed by the next one. Ignored when cleanup has its own schedule.
+	RunBeforeUpdates bool `yaml:"run_before_updates"`
+
+	// TriggerAtUsage (e.g. "85%") also runs cleanup whenever the filesystem holding Docker's
+	// data root fills past it, checked every UsageCheckInterval. DataRoot is where that
+	// filesystem is mounted inside HarborBuddy's container; empty uses the daemon's path.
+	TriggerAtUsage     string        `yaml:"trigger_at_usage"`
+	UsageCheckInterval time.Duration `yaml:"usage_check_interval"`
+	DataRoot           string        `yaml:"data_root"`
+
+	Containers ContainerCleanupConfig  `yaml:"containers"`
+	Volumes    VolumeCleanupConfig     `yaml:"volumes"`
+	Networks   NetworkCleanupConfig    `yaml:"networks"`
+	BuildCache BuildCacheCleanupConfig `yaml:"build_cache"`
+	Orphans    OrphanCleanupConfig     `yaml:"orphans"`
+}
+
+// ContainerCleanupConfig controls removal of stopped containers.
+// Containers labelled com.harborbuddy.cleanup=false are never removed.
+type ContainerCleanupConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	MinAgeHours int      `yaml:"min_age_hours"` // Only remove containers that exited at least this long ago
+	AllowNames  []string `yaml:"allow_names"`   // Container name patterns that may be removed
+	DenyNames   []string `yaml:"deny_names"`    // Container name patterns that are never removed
+	DryRun      bool     `yaml:"dry_run"`       // Log what would be removed without removing it
+}
+
+// VolumeCleanupConfig controls removal of volumes no container uses.
+// Volumes labelled com.harborbuddy.cleanup=false are never removed.
+type VolumeCleanupConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	MinAgeHours int      `yaml:"min_age_hours"` // Only remove volumes created at least this long ago
+	Named       bool     `yaml:"named"`         // Also remove named volumes, not just anonymous ones
+	DenyNames   []string `yaml:"deny_names"`    // Volume name patterns that are never removed
+	DryRun      bool     `yaml:"dry_run"`
+}
+
+// NetworkCleanupConfig controls removal of user-defined networks with no containers attached.
+// Networks labelled com.harborbuddy.cleanup=false are never removed.
+type NetworkCleanupConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	MinAgeHours int      `yaml:"min_age_hours"` // Only remove networks created at least this long ago
+	DenyNames   []string `yaml:"deny_names"`    // Network name patterns that are never removed
+	DryRun      bool     `yaml:"dry_run"`
+}
+
+// BuildCacheCleanupConfig controls pruning of the BuildKit build cache
+type BuildCacheCleanupConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	MaxSize string `yaml:"max_size"` // Cache to keep, e.g. "5g"; empty removes all unused cache
+	DryRun  bool   `yaml:"dry_run"`
+}
+
+// OrphanCleanupConfig controls removal of containers HarborBuddy left behind itself:
+// "-old-<timestamp>" backups and "-updater-<timestamp>" self-update helpers, recognized by the
+// labels it puts on the containers it creates. It runs at startup and after every update
+// cycle, even when image cleanup is disabled.
+type OrphanCleanupConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	MinAgeHours int  `yaml:"min_age_hours"` // Only remove containers left behind at least this long ago
+	DryRun      bool `yaml:"dry_run"`
+}
+
+// UsageThreshold returns TriggerAtUsage as a percentage, or 0 if disk usage triggering is off
+func (c CleanupConfig) UsageThreshold() (float64, error) {
+	if c.TriggerAtUsage == "" {
+		return 0, nil
+	}
+	value := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(c.TriggerAtUsage), "%"))
+	percent, err := strconv.ParseFloat(value, 64)
+	if err != nil || percent <= 0 || percent >= 100 {
+		return 0, fmt.Errorf("invalid cleanup.trigger_at_usage: %s (must be a percentage between 0 and 100, e.g., '85%%')", c.TriggerAtUsage)
+	}
+	return percent, nil
+}
+
+// Independent reports whether cleanup runs on its own schedule rather than after each update cycle
+func (c CleanupConfig) Independent() bool {
+	return c.ScheduleTime != "" || c.CheckInterval > 0
 }
 
 // LogConfig holds logging settings
@@ -54,8 +386,21 @@ type LogConfig struct {
 	Level      string `yaml:"level"`
 	JSON       bool   `yaml:"json"`
 	File       string `yaml:"file"`
+	MaxSize    int    `yaml:"max_size"`     // megabytes
+	MaxBackups int    `yaml:"max_backups"`  // number of files
+	MaxAgeDays int    `yaml:"max_age_days"` // Delete rotated files older than this; 0 keeps them regardless of age
+	Compress   bool   `yaml:"compress"`     // gzip rotated files
+
+	// NameLabels is the label priority for friendly image names ("compose" = project/service)
+	NameLabels []string `yaml:"name_labels"`
+}
+
+// AuditConfig holds the audit log: every change made to Docker, as JSON lines, kept apart
+// from the operational log
+type AuditConfig struct {
+	File       string `yaml:"file"`        // Empty disables the audit log
 	MaxSize    int    `yaml:"max_size"`    // megabytes
-	MaxBackups int    `yaml:"max_backups"` // number of files
+	MaxBackups int    `yaml:"max_backups"` // number of files, 0 keeps them all
 }
 
 // LoggingConfig matches Docker's logging configuration structure
@@ -64,12 +409,212 @@ type LoggingConfig struct {
 	Options map[string]string `yaml:"options"`
 }
 
+// MetricsConfig holds Prometheus metrics settings
+type MetricsConfig struct {
+	PerContainer       bool `yaml:"per_container"`        // Expose per-container gauges
+	MaxContainerSeries int  `yaml:"max_container_series"` // Cap on containers with their own series (0 = unlimited)
+}
+
+// NotificationsConfig holds event notification settings
+type NotificationsConfig struct {
+	WebhookURL        string        `yaml:"webhook_url"`         // POST a JSON payload here; empty disables notifications
+	WebhookURLFile    string        `yaml:"webhook_url_file"`    // Read webhook_url from this file (e.g. a Docker secret)
+	WebhookSecret     string        `yaml:"webhook_secret"`      // Sign webhook_url and generic:// payloads with HMAC-SHA256 (X-Signature header)
+	WebhookSecretFile string        `yaml:"webhook_secret_file"` // Read webhook_secret from this file
+	URLs              []string      `yaml:"urls"`                // Service URLs such as "telegram://token@telegram?chats=42"
+	URLsFile          string        `yaml:"urls_file"`           // Add the service URLs in this file, one per line
+	OnUpdate          bool          `yaml:"on_update"`
+	OnUpdateAvailable bool          `yaml:"on_update_available"` // Update found but not applied (monitor-only)
+	OnFailure         bool          `yaml:"on_failure"`
+	OnCleanup         bool          `yaml:"on_cleanup"`
+	Level             string        `yaml:"level"` // NotifyAll, NotifyChanges or NotifyErrors; applies on top of the on_* switches
+	Timeout           time.Duration `yaml:"timeout"`
+
+	// RemindAfter repeats the update_available notification of an update still pending after
+	// this long; 0 sends it once per new image. Remembering what was sent needs state.file.
+	RemindAfter time.Duration `yaml:"remind_after"`
+	// Digest ("HH:MM", in updates.timezone) sends the updates still pending as one summary
+	// every day, instead of an update_available notification for each as it is found
+	Digest string `yaml:"digest"`
+
+	// Templates are the default message templates for Gotify, ntfy and notification URLs
+	Templates MessageTemplates `yaml:"templates"`
+
+	Email  EmailConfig  `yaml:"email"`
+	Gotify GotifyConfig `yaml:"gotify"`
+	Ntfy   NtfyConfig   `yaml:"ntfy"`
+}
+
+// hasChannels reports whether any notification channel is configured
+func (n NotificationsConfig) hasChannels() bool {
+	return n.WebhookURL != "" || len(n.URLs) > 0 || n.Email.Host != "" || n.Gotify.URL != "" || n.Ntfy.Topic != ""
+}
+
+// Notification levels (notifications.level)
+const (
+	NotifyAll     = "all"     // Every enabled event, including cleanups that removed nothing
+	NotifyChanges = "changes" // Only events where something changed, was found or failed
+	NotifyErrors  = "errors"  // Only failures
+)
+
+// MessageTemplates customize push notification text. Title and Message are Go text/template
+// strings over the cycle summary (.Updated, .Failed, ...), the .Event being sent and the
+// built-in .Title and .Message; empty uses the built-in text.
+type MessageTemplates struct {
+	Title   string `yaml:"title"`
+	Message string `yaml:"message"`
+	Summary bool   `yaml:"summary"` // Send one message per cycle instead of one per event
+}
+
+// Or fills in unset templates from fallback
+func (t MessageTemplates) Or(fallback MessageTemplates) MessageTemplates {
+	if t.Title == "" {
+		t.Title = fallback.Title
+	}
+	if t.Message == "" {
+		t.Message = fallback.Message
+	}
+	t.Summary = t.Summary || fallback.Summary
+	return t
+}
+
+// Validate checks that both templates parse
+func (t MessageTemplates) Validate() error {
+	for field, text := range map[string]string{"title": t.Title, "message": t.Message} {
+		if _, err := template.New(field).Parse(text); err != nil {
+			return fmt.Errorf("invalid %s template: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// GotifyConfig holds Gotify push notification settings
+type GotifyConfig struct {
+	URL       string `yaml:"url"`        // Gotify server, e.g. "https://gotify.example.com"; empty disables Gotify
+	Token     string `yaml:"token"`      // Application token; may reference environment variables
+	TokenFile string `yaml:"token_file"` // Read token from this file (e.g. a Docker secret)
+
+	// Priorities maps event types (update, update_available, failure, cleanup) to a
+	// message priority from 0 to 10; unlisted events use the built-in priority
+	Priorities map[string]int `yaml:"priorities"`
+
+	MessageTemplates `yaml:",inline"` // Overrides notifications.templates
+}
+
+// NtfyConfig holds ntfy push notification settings
+type NtfyConfig struct {
+	URL       string `yaml:"url"`        // ntfy server, defaults to https://ntfy.sh
+	Topic     string `yaml:"topic"`      // Empty disables ntfy
+	Token     string `yaml:"token"`      // Access token for protected topics; may reference environment variables
+	TokenFile string `yaml:"token_file"` // Read token from this file (e.g. a Docker secret)
+
+	// Priorities maps event types (update, update_available, failure, cleanup) to a
+	// message priority from 1 (min) to 5 (max); unlisted events use the built-in priority
+	Priorities map[string]int `yaml:"priorities"`
+
+	MessageTemplates `yaml:",inline"` // Overrides notifications.templates
+}
+
+// EmailConfig holds SMTP notification settings. Events are batched into one summary email per cycle.
+type EmailConfig struct {
+	Host         string   `yaml:"host"` // SMTP server; empty disables email
+	Port         int      `yaml:"port"`
+	Username     string   `yaml:"username"`
+	Password     string   `yaml:"password"`      // May reference environment variables (e.g., "${SMTP_PASSWORD}")
+	PasswordFile string   `yaml:"password_file"` // Read password from this file (e.g. a Docker secret)
+	From         string   `yaml:"from"`
+	To           []string `yaml:"to"`
+	TLS          string   `yaml:"tls"` // "starttls", "tls" (implicit, usually port 465) or "none"
+
+	// Subject and Body are Go text/template strings; empty uses the built-in summary
+	Subject string `yaml:"subject"`
+	Body    string `yaml:"body"`
+}
+
+// SMTP connection security modes
+const (
+	EmailTLSStartTLS = "starttls"
+	EmailTLSImplicit = "tls"
+	EmailTLSNone     = "none"
+)
+
+// RegistryAuth holds credentials for a private registry.
+// The password or token may be read from a file (e.g., a Docker secret in /run/secrets).
+type RegistryAuth struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	Token        string `yaml:"token"` // Bearer token sent as-is; use username/password for personal access tokens
+	TokenFile    string `yaml:"token_file"`
+}
+
+// APIConfig holds embedded HTTP API settings
+type APIConfig struct {
+	Listen string `yaml:"listen"` // Address to serve on (e.g., ":8080"); empty disables the API
+
+	// WebhookSecret authenticates calls to the image-pushed webhook, which stays disabled
+	// without it
+	WebhookSecret     string `yaml:"webhook_secret"`
+	WebhookSecretFile string `yaml:"webhook_secret_file"` // Read webhook_secret from this file (e.g. a Docker secret)
+
+	// Token and Username/Password protect every endpoint but /healthz and the webhook: a
+	// request needs the bearer token or the basic-auth credentials. Without either the API
+	// is open.
+	Token        string `yaml:"token"`
+	TokenFile    string `yaml:"token_file"` // Read token from this file (e.g. a Docker secret)
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"` // Read password from this file (e.g. a Docker secret)
+
+	// TLSCert and TLSKey are PEM files to serve HTTPS with instead of HTTP
+	TLSCert string `yaml:"tls_cert"`
+	TLSKey  string `yaml:"tls_key"`
+
+	// ReadOnly serves only the endpoints that report, such as /status, /containers and
+	// /metrics, and refuses every request that would trigger or change something
+	ReadOnly bool `yaml:"read_only"`
+}
+
+// MQTTConfig connects HarborBuddy to an MQTT broker, e.g. for Home Assistant. It publishes
+// cycle reports and notification events under TopicPrefix and runs the commands sent to
+// <TopicPrefix>/command.
+type MQTTConfig struct {
+	Broker       string `yaml:"broker"` // "mqtt://host:1883" or "mqtts://host:8883"; empty disables MQTT
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`      // May reference environment variables
+	PasswordFile string `yaml:"password_file"` // Read password from this file (e.g. a Docker secret)
+	ClientID     string `yaml:"client_id"`
+	TopicPrefix  string `yaml:"topic_prefix"`
+	QoS          int    `yaml:"qos"` // 0 (at most once) or 1 (at least once)
+
+	// CAFile verifies an mqtts broker signed by a private CA; InsecureSkipVerify doesn't
+	// verify it at all
+	CAFile             string `yaml:"ca_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// HooksConfig holds shell commands run on the HarborBuddy host around cycles and updates.
+// Commands run with "sh -c"; HARBORBUDDY_* environment variables describe the container and image.
+type HooksConfig struct {
+	PreCycle   string        `yaml:"pre_cycle"`   // Before the update cycle; a failure skips the cycle
+	PostCycle  string        `yaml:"post_cycle"`  // After the update cycle, with its counts
+	PreUpdate  string        `yaml:"pre_update"`  // Before each container update; a failure skips that container
+	PostUpdate string        `yaml:"post_update"` // After each container update, successful or not
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+// Enabled reports whether any hook is configured
+func (h HooksConfig) Enabled() bool {
+	return h.PreCycle != "" || h.PostCycle != "" || h.PreUpdate != "" || h.PostUpdate != ""
+}
+
 // Default returns a config with sensible defaults
 func Default() Config {
 	return Config{
 		Docker: DockerConfig{
-			Host: "unix:///var/run/docker.sock",
-			TLS:  false,
+			Host:          "unix:///var/run/docker.sock",
+			TLS:           false,
+			CacheInspects: true,
 		},
 		Updates: UpdatesConfig{
 			Enabled:       true,
@@ -78,14 +623,45 @@ func Default() Config {
 			ScheduleTime:  "", // Empty means use CheckInterval
 			Timezone:      "UTC",
 			DryRun:        false,
+			CheckMethod:   CheckMethodPull,
+			Policy:        PolicyDigest,
+			Strategy:      StrategyBlueGreen,
 			AllowImages:   []string{"*"},
 			DenyImages:    []string{},
 			StopTimeout:   10 * time.Second,
+
+			HealthTimeout:      0,
+			HealthGracePeriod:  10 * time.Second,
+			HealthLogLines:     20,
+			PullTimeout:        0,
+			PullRetries:        0,
+			HookTimeout:        60 * time.Second,
+			CycleTimeout:       2 * time.Hour,
+			DrainTimeout:       60 * time.Second,
+			MaxParallelUpdates: 1,
 		},
 		Cleanup: CleanupConfig{
-			Enabled:      true,
-			MinAgeHours:  24,
-			DanglingOnly: true,
+			Enabled:            true,
+			MinAgeHours:        24,
+			DanglingOnly:       true,
+			UsageCheckInterval: 5 * time.Minute,
+			Containers: ContainerCleanupConfig{
+				MinAgeHours: 24,
+				AllowNames:  []string{"*"},
+				DenyNames:   []string{},
+			},
+			Volumes: VolumeCleanupConfig{
+				MinAgeHours: 24,
+				DenyNames:   []string{},
+			},
+			Networks: NetworkCleanupConfig{
+				MinAgeHours: 24,
+				DenyNames:   []string{},
+			},
+			Orphans: OrphanCleanupConfig{
+				Enabled:     true,
+				MinAgeHours: 1,
+			},
 		},
 		Log: LogConfig{
 			Level:      "info",
@@ -93,6 +669,46 @@ func Default() Config {
 			MaxSize:    10,
 			MaxBackups: 1,
 		},
+		Audit: AuditConfig{
+			MaxSize:    10,
+			MaxBackups: 5,
+		},
+		Metrics: MetricsConfig{
+			PerContainer:       true,
+			MaxContainerSeries: 100,
+		},
+		Notifications: NotificationsConfig{
+			OnUpdate:          true,
+			OnUpdateAvailable: true,
+			OnFailure:         true,
+			OnCleanup:         false,
+			Level:             NotifyAll,
+			Timeout:           10 * time.Second,
+			Email: EmailConfig{
+				Port: 587,
+				TLS:  EmailTLSStartTLS,
+			},
+			Ntfy: NtfyConfig{
+				URL: "https://ntfy.sh",
+			},
+		},
+		MQTT: MQTTConfig{
+			ClientID:    "harborbuddy",
+			TopicPrefix: "harborbuddy",
+		},
+		Report: ReportConfig{
+			Timeout: 10 * time.Second,
+		},
+		Hooks: HooksConfig{
+			Timeout: 60 * time.Second,
+		},
+		Scan: ScanConfig{
+			Trivy:   "trivy",
+			Timeout: 5 * time.Minute,
+		},
+		SelfUpdate: SelfUpdateConfig{
+			Channel: SelfUpdateStable,
+		},
 		RunOnce:     false,
 		CleanupOnly: false,
 	}
@@ -100,10 +716,20 @@ func Default() Config {
 
 // LoadFromFile loads configuration from a YAML file
 func LoadFromFile(path string) (Config, error) {
+	return LoadProfile(path, "")
+}
+
+// LoadProfile loads configuration from a YAML file with the named profile merged over it.
+// Settings the profile sets replace the file's, nested sections are merged key by key and
+// lists are replaced whole. An empty profile loads the file as it is.
+func LoadProfile(path, profile string) (Config, error) {
 	cfg := Default()
 
 	// If file doesn't exist, return defaults
 	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if profile != "" {
+			return cfg, fmt.Errorf("profile %q requested but config file %s does not exist", profile, path)
+		}
 		return cfg, nil
 	}
 
@@ -112,9 +738,30 @@ func LoadFromFile(path string) (Config, error) {
 		return cfg, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	// Expand environment variable references in the values before decoding them
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return cfg, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	expandNode(&doc)
+	if doc.Kind != 0 {
+		if err := doc.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
+	if profile != "" {
+		node, ok := cfg.Profiles[profile]
+		if !ok {
+			return cfg, fmt.Errorf("unknown profile %q (defined: %s)", profile, strings.Join(profileNames(cfg.Profiles), ", "))
+		}
+		// Decoding onto the loaded configuration only touches what the profile sets
+		if err := node.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("failed to parse profile %q: %w", profile, err)
+		}
+		cfg.Profile = profile
+	}
+	cfg.Profiles = nil
 
 	// Apply partial updates from 'logging' block if present
 	cfg.ApplyLoggingCompatibility()
@@ -122,6 +769,19 @@ func LoadFromFile(path string) (Config, error) {
 	return cfg, nil
 }
 
+// profileNames lists the defined profiles in order, or "none"
+func profileNames(profiles map[string]yaml.Node) []string {
+	if len(profiles) == 0 {
+		return []string{"none"}
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // ApplyLoggingCompatibility maps Docker-style logging config to HarborBuddy config
 func (c *Config) ApplyLoggingCompatibility() {
 	if c.Logging.Options == nil {
@@ -141,6 +801,13 @@ func (c *Config) ApplyLoggingCompatibility() {
 			c.Log.MaxBackups = backups
 		}
 	}
+
+	// Parse compress
+	if val, ok := c.Logging.Options["compress"]; ok {
+		if compress, err := strconv.ParseBool(val); err == nil {
+			c.Log.Compress = compress
+		}
+	}
 }
 
 // parseBytesString converts strings like "10m", "1g", "100k" to Megabytes (int)
@@ -188,12 +855,24 @@ func (c *Config) ApplyEnvironmentOverrides() {
 		c.Docker.Host = val
 	}
 
+	if val := os.Getenv("HARBORBUDDY_DOCKER_CACHE_INSPECTS"); val != "" {
+		if cache, err := strconv.ParseBool(val); err == nil {
+			c.Docker.CacheInspects = cache
+		}
+	}
+
 	if val := os.Getenv("HARBORBUDDY_INTERVAL"); val != "" {
 		if duration, err := time.ParseDuration(val); err == nil {
 			c.Updates.CheckInterval = duration
 		}
 	}
 
+	if val := os.Getenv("HARBORBUDDY_INITIAL_DELAY"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.InitialDelay = duration
+		}
+	}
+
 	if val := os.Getenv("HARBORBUDDY_SCHEDULE_TIME"); val != "" {
 		c.Updates.ScheduleTime = val
 	}
@@ -212,92 +891,1172 @@ func (c *Config) ApplyEnvironmentOverrides() {
 		}
 	}
 
-	if val := os.Getenv("HARBORBUDDY_STOP_TIMEOUT"); val != "" {
-		if duration, err := time.ParseDuration(val); err == nil {
-			c.Updates.StopTimeout = duration
+	if val := os.Getenv("HARBORBUDDY_LABEL_ENABLE"); val != "" {
+		if labelEnable, err := strconv.ParseBool(val); err == nil {
+			c.Updates.LabelEnable = labelEnable
 		}
 	}
 
-	if val := os.Getenv("HARBORBUDDY_UPDATES_ENABLED"); val != "" {
+	if val := os.Getenv("HARBORBUDDY_SCOPE"); val != "" {
+		c.Updates.Scope = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MONITOR_ONLY"); val != "" {
+		if monitorOnly, err := strconv.ParseBool(val); err == nil {
+			c.Updates.MonitorOnly = monitorOnly
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CHECK_METHOD"); val != "" {
+		c.Updates.CheckMethod = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_STATE_FILE"); val != "" {
+		c.State.File = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_HISTORY_FILE"); val != "" {
+		c.State.HistoryFile = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_PAUSE_FILE"); val != "" {
+		c.State.PauseFile = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_STATUS_FILE"); val != "" {
+		c.State.StatusFile = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_REPORT_FILE"); val != "" {
+		c.Report.File = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_REPORT_URL"); val != "" {
+		c.Report.URL = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_VERIFY_ENABLED"); val != "" {
 		if enabled, err := strconv.ParseBool(val); err == nil {
-			c.Updates.Enabled = enabled
+			c.Verify.Enabled = enabled
 		}
 	}
 
-	if val := os.Getenv("HARBORBUDDY_CLEANUP_ENABLED"); val != "" {
+	if val := os.Getenv("HARBORBUDDY_VERIFY_PUBLIC_KEYS"); val != "" {
+		c.Verify.PublicKeys = splitList(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_VERIFY_IMAGES"); val != "" {
+		c.Verify.Images = splitList(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_SCAN_ENABLED"); val != "" {
 		if enabled, err := strconv.ParseBool(val); err == nil {
-			c.Cleanup.Enabled = enabled
+			c.Scan.Enabled = enabled
 		}
 	}
 
-	if val := os.Getenv("HARBORBUDDY_LOG_LEVEL"); val != "" {
-		c.Log.Level = val
+	if val := os.Getenv("HARBORBUDDY_SCAN_TRIVY"); val != "" {
+		c.Scan.Trivy = val
 	}
 
-	if val := os.Getenv("HARBORBUDDY_LOG_JSON"); val != "" {
-		if jsonLog, err := strconv.ParseBool(val); err == nil {
-			c.Log.JSON = jsonLog
+	if val := os.Getenv("HARBORBUDDY_SCAN_SERVER"); val != "" {
+		c.Scan.Server = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_SCAN_MAX_CRITICAL"); val != "" {
+		if limit, err := strconv.Atoi(val); err == nil {
+			c.Scan.MaxCritical = limit
 		}
 	}
 
-	if val := os.Getenv("HARBORBUDDY_LOG_FILE"); val != "" {
-		c.Log.File = val
+	if val := os.Getenv("HARBORBUDDY_SCAN_IGNORE_UNFIXED"); val != "" {
+		if ignore, err := strconv.ParseBool(val); err == nil {
+			c.Scan.IgnoreUnfixed = ignore
+		}
 	}
 
-	if val := os.Getenv("HARBORBUDDY_LOG_MAX_SIZE"); val != "" {
-		if size, err := strconv.Atoi(val); err == nil {
-			c.Log.MaxSize = size
+	if val := os.Getenv("HARBORBUDDY_SCAN_IMAGES"); val != "" {
+		c.Scan.Images = splitList(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_SCAN_TIMEOUT"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Scan.Timeout = duration
 		}
 	}
 
-	if val := os.Getenv("HARBORBUDDY_LOG_MAX_BACKUPS"); val != "" {
-		if backups, err := strconv.Atoi(val); err == nil {
-			c.Log.MaxBackups = backups
+	if val := os.Getenv("HARBORBUDDY_SELFUPDATE_CHANNEL"); val != "" {
+		c.SelfUpdate.Channel = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_SELFUPDATE_IMAGE"); val != "" {
+		c.SelfUpdate.Image = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_HEALTH_TIMEOUT"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.HealthTimeout = duration
 		}
 	}
-}
 
-// Validate checks if the configuration is valid
-func (c *Config) Validate() error {
-	if c.Docker.Host == "" {
-		return fmt.Errorf("docker.host cannot be empty")
+	if val := os.Getenv("HARBORBUDDY_HEALTH_LOG_LINES"); val != "" {
+		if lines, err := strconv.Atoi(val); err == nil {
+			c.Updates.HealthLogLines = lines
+		}
 	}
 
-	// If schedule_time is not set, check_interval must be positive
-	if c.Updates.ScheduleTime == "" && c.Updates.CheckInterval <= 0 {
-		return fmt.Errorf("updates.check_interval must be positive when schedule_time is not set")
+	if val := os.Getenv("HARBORBUDDY_PULL_TIMEOUT"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.PullTimeout = duration
+		}
 	}
 
-	if c.Updates.StopTimeout <= 0 {
-		return fmt.Errorf("updates.stop_timeout must be positive")
+	if val := os.Getenv("HARBORBUDDY_CYCLE_TIMEOUT"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.CycleTimeout = duration
+		}
 	}
 
-	// If schedule_time is set, validate the format
-	if c.Updates.ScheduleTime != "" {
-		if _, err := time.Parse("15:04", c.Updates.ScheduleTime); err != nil {
-			return fmt.Errorf("invalid schedule_time format: %s (must be HH:MM, e.g., '03:00')", c.Updates.ScheduleTime)
+	if val := os.Getenv("HARBORBUDDY_DRAIN_TIMEOUT"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.DrainTimeout = duration
 		}
+	}
 
-		// Validate timezone
-		if _, err := time.LoadLocation(c.Updates.Timezone); err != nil {
-			return fmt.Errorf("invalid timezone: %s (use IANA timezone names like 'America/Los_Angeles' or 'UTC')", c.Updates.Timezone)
+	if val := os.Getenv("HARBORBUDDY_PULL_RETRIES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			c.Updates.PullRetries = n
 		}
 	}
 
-	if c.Cleanup.MinAgeHours < 0 {
-		return fmt.Errorf("cleanup.min_age_hours cannot be negative")
+	if val := os.Getenv("HARBORBUDDY_MIN_IMAGE_AGE"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.MinImageAge = duration
+		}
 	}
 
-	validLogLevels := map[string]bool{
-		"debug": true,
-		"info":  true,
-		"warn":  true,
-		"error": true,
+	if val := os.Getenv("HARBORBUDDY_MIN_FREE_SPACE"); val != "" {
+		c.Updates.MinFreeSpace = val
 	}
 
-	if !validLogLevels[c.Log.Level] {
-		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.Log.Level)
+	if val := os.Getenv("HARBORBUDDY_MAX_CONCURRENT_PULLS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			c.Updates.MaxConcurrentPulls = n
+		}
+	}
+
+	// Per-registry pull limits as "docker.io=1,ghcr.io=2"
+	if val := os.Getenv("HARBORBUDDY_REGISTRY_PULL_LIMITS"); val != "" {
+		limits := make(map[string]int)
+		for _, item := range splitList(val) {
+			host, limit, _ := strings.Cut(item, "=")
+			if n, err := strconv.Atoi(strings.TrimSpace(limit)); err == nil {
+				limits[strings.TrimSpace(host)] = n
+			}
+		}
+		c.Updates.RegistryPullLimits = limits
 	}
 
+	// Tracked tags as "nginx=1.25-alpine,ghcr.io/org/app=stable"
+	if val := os.Getenv("HARBORBUDDY_TRACK"); val != "" {
+		track := make(Track)
+		for _, item := range splitList(val) {
+			if repo, tag, ok := strings.Cut(item, "="); ok {
+				track[strings.TrimSpace(repo)] = strings.TrimSpace(tag)
+			}
+		}
+		c.Updates.Track = track
+	}
+
+	if val := os.Getenv("HARBORBUDDY_HOOK_TIMEOUT"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.HookTimeout = duration
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_UPDATE_POLICY"); val != "" {
+		c.Updates.Policy = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_ALLOW_CONTAINERS"); val != "" {
+		c.Updates.AllowContainers = splitList(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_DENY_CONTAINERS"); val != "" {
+		c.Updates.DenyContainers = splitList(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CANARY_CONTAINERS"); val != "" {
+		c.Updates.CanaryContainers = splitList(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_UPDATE_STRATEGY"); val != "" {
+		c.Updates.Strategy = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_PINS_FILE"); val != "" {
+		c.Updates.PinsFile = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_ON_CONTAINER_START"); val != "" {
+		if onStart, err := strconv.ParseBool(val); err == nil {
+			c.Updates.OnContainerStart = onStart
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_PIN_DIGESTS"); val != "" {
+		if pinDigests, err := strconv.ParseBool(val); err == nil {
+			c.Updates.PinDigests = pinDigests
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_SUGGEST_DIGESTS"); val != "" {
+		if suggestDigests, err := strconv.ParseBool(val); err == nil {
+			c.Updates.SuggestDigests = suggestDigests
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_STOP_TIMEOUT"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.StopTimeout = duration
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MAX_PARALLEL_UPDATES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			c.Updates.MaxParallelUpdates = n
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MAX_UPDATES_PER_CYCLE"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			c.Updates.MaxUpdatesPerCycle = n
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_STAGGER_DELAY"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.StaggerDelay = duration
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_STAGGER_JITTER"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Updates.StaggerJitter = duration
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_UPDATES_ENABLED"); val != "" {
+		if enabled, err := strconv.ParseBool(val); err == nil {
+			c.Updates.Enabled = enabled
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_ENABLED"); val != "" {
+		if enabled, err := strconv.ParseBool(val); err == nil {
+			c.Cleanup.Enabled = enabled
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_RUN_BEFORE_UPDATES"); val != "" {
+		if before, err := strconv.ParseBool(val); err == nil {
+			c.Cleanup.RunBeforeUpdates = before
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_CHECK_INTERVAL"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			c.Cleanup.CheckInterval = duration
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_SCHEDULE_TIME"); val != "" {
+		c.Cleanup.ScheduleTime = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_TRIGGER_AT_USAGE"); val != "" {
+		c.Cleanup.TriggerAtUsage = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_DATA_ROOT"); val != "" {
+		c.Cleanup.DataRoot = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_DRY_RUN"); val != "" {
+		if dryRun, err := strconv.ParseBool(val); err == nil {
+			c.Cleanup.DryRun = dryRun
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_EXCLUDE_IMAGES"); val != "" {
+		c.Cleanup.ExcludeImages = splitList(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_CONTAINERS_ENABLED"); val != "" {
+		if enabled, err := strconv.ParseBool(val); err == nil {
+			c.Cleanup.Containers.Enabled = enabled
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_CONTAINERS_MIN_AGE_HOURS"); val != "" {
+		if hours, err := strconv.Atoi(val); err == nil {
+			c.Cleanup.Containers.MinAgeHours = hours
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_VOLUMES_ENABLED"); val != "" {
+		if enabled, err := strconv.ParseBool(val); err == nil {
+			c.Cleanup.Volumes.Enabled = enabled
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_NETWORKS_ENABLED"); val != "" {
+		if enabled, err := strconv.ParseBool(val); err == nil {
+			c.Cleanup.Networks.Enabled = enabled
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_BUILD_CACHE_ENABLED"); val != "" {
+		if enabled, err := strconv.ParseBool(val); err == nil {
+			c.Cleanup.BuildCache.Enabled = enabled
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_BUILD_CACHE_MAX_SIZE"); val != "" {
+		c.Cleanup.BuildCache.MaxSize = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_ORPHANS_ENABLED"); val != "" {
+		if enabled, err := strconv.ParseBool(val); err == nil {
+			c.Cleanup.Orphans.Enabled = enabled
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_CLEANUP_ORPHANS_MIN_AGE_HOURS"); val != "" {
+		if hours, err := strconv.Atoi(val); err == nil {
+			c.Cleanup.Orphans.MinAgeHours = hours
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_LOG_LEVEL"); val != "" {
+		c.Log.Level = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_LOG_JSON"); val != "" {
+		if jsonLog, err := strconv.ParseBool(val); err == nil {
+			c.Log.JSON = jsonLog
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_LOG_FILE"); val != "" {
+		c.Log.File = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_LOG_MAX_SIZE"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil {
+			c.Log.MaxSize = size
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_LOG_MAX_BACKUPS"); val != "" {
+		if backups, err := strconv.Atoi(val); err == nil {
+			c.Log.MaxBackups = backups
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_LOG_MAX_AGE_DAYS"); val != "" {
+		if days, err := strconv.Atoi(val); err == nil {
+			c.Log.MaxAgeDays = days
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_LOG_COMPRESS"); val != "" {
+		if compress, err := strconv.ParseBool(val); err == nil {
+			c.Log.Compress = compress
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_LOG_NAME_LABELS"); val != "" {
+		c.Log.NameLabels = splitList(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_AUDIT_FILE"); val != "" {
+		c.Audit.File = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_AUDIT_MAX_SIZE"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil {
+			c.Audit.MaxSize = size
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_AUDIT_MAX_BACKUPS"); val != "" {
+		if backups, err := strconv.Atoi(val); err == nil {
+			c.Audit.MaxBackups = backups
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_WEBHOOK_URL"); val != "" {
+		c.Notifications.WebhookURL, c.Notifications.WebhookURLFile = val, ""
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_WEBHOOK_URL_FILE"); val != "" {
+		c.Notifications.WebhookURL, c.Notifications.WebhookURLFile = "", val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_WEBHOOK_SECRET"); val != "" {
+		c.Notifications.WebhookSecret, c.Notifications.WebhookSecretFile = val, ""
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_WEBHOOK_SECRET_FILE"); val != "" {
+		c.Notifications.WebhookSecret, c.Notifications.WebhookSecretFile = "", val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_REMIND_AFTER"); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			c.Notifications.RemindAfter = d
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_DIGEST"); val != "" {
+		c.Notifications.Digest = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_LEVEL"); val != "" {
+		c.Notifications.Level = val
+	}
+
+	// Space-separated, since the URLs themselves may contain commas
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_URLS"); val != "" {
+		c.Notifications.URLs = strings.Fields(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_URLS_FILE"); val != "" {
+		c.Notifications.URLsFile = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_EMAIL_HOST"); val != "" {
+		c.Notifications.Email.Host = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_EMAIL_PORT"); val != "" {
+		if port, err := strconv.Atoi(val); err == nil {
+			c.Notifications.Email.Port = port
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_EMAIL_USERNAME"); val != "" {
+		c.Notifications.Email.Username = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_EMAIL_PASSWORD"); val != "" {
+		c.Notifications.Email.Password, c.Notifications.Email.PasswordFile = val, ""
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_EMAIL_PASSWORD_FILE"); val != "" {
+		c.Notifications.Email.Password, c.Notifications.Email.PasswordFile = "", val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_EMAIL_FROM"); val != "" {
+		c.Notifications.Email.From = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_EMAIL_TO"); val != "" {
+		c.Notifications.Email.To = splitList(val)
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_EMAIL_TLS"); val != "" {
+		c.Notifications.Email.TLS = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_GOTIFY_URL"); val != "" {
+		c.Notifications.Gotify.URL = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_GOTIFY_TOKEN"); val != "" {
+		c.Notifications.Gotify.Token, c.Notifications.Gotify.TokenFile = val, ""
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_GOTIFY_TOKEN_FILE"); val != "" {
+		c.Notifications.Gotify.Token, c.Notifications.Gotify.TokenFile = "", val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_NTFY_URL"); val != "" {
+		c.Notifications.Ntfy.URL = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_NTFY_TOPIC"); val != "" {
+		c.Notifications.Ntfy.Topic = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_NTFY_TOKEN"); val != "" {
+		c.Notifications.Ntfy.Token, c.Notifications.Ntfy.TokenFile = val, ""
+	}
+
+	if val := os.Getenv("HARBORBUDDY_NOTIFICATIONS_NTFY_TOKEN_FILE"); val != "" {
+		c.Notifications.Ntfy.Token, c.Notifications.Ntfy.TokenFile = "", val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_HOOKS_PRE_CYCLE"); val != "" {
+		c.Hooks.PreCycle = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_HOOKS_POST_CYCLE"); val != "" {
+		c.Hooks.PostCycle = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_HOOKS_PRE_UPDATE"); val != "" {
+		c.Hooks.PreUpdate = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_HOOKS_POST_UPDATE"); val != "" {
+		c.Hooks.PostUpdate = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_LISTEN"); val != "" {
+		c.API.Listen = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MQTT_BROKER"); val != "" {
+		c.MQTT.Broker = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MQTT_USERNAME"); val != "" {
+		c.MQTT.Username = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MQTT_PASSWORD"); val != "" {
+		c.MQTT.Password, c.MQTT.PasswordFile = val, ""
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MQTT_PASSWORD_FILE"); val != "" {
+		c.MQTT.Password, c.MQTT.PasswordFile = "", val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MQTT_CLIENT_ID"); val != "" {
+		c.MQTT.ClientID = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MQTT_TOPIC_PREFIX"); val != "" {
+		c.MQTT.TopicPrefix = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_MQTT_QOS"); val != "" {
+		if qos, err := strconv.Atoi(val); err == nil {
+			c.MQTT.QoS = qos
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_WEBHOOK_SECRET"); val != "" {
+		c.API.WebhookSecret, c.API.WebhookSecretFile = val, ""
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_WEBHOOK_SECRET_FILE"); val != "" {
+		c.API.WebhookSecret, c.API.WebhookSecretFile = "", val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_TOKEN"); val != "" {
+		c.API.Token, c.API.TokenFile = val, ""
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_TOKEN_FILE"); val != "" {
+		c.API.Token, c.API.TokenFile = "", val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_USERNAME"); val != "" {
+		c.API.Username = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_PASSWORD"); val != "" {
+		c.API.Password, c.API.PasswordFile = val, ""
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_PASSWORD_FILE"); val != "" {
+		c.API.Password, c.API.PasswordFile = "", val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_TLS_CERT"); val != "" {
+		c.API.TLSCert = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_TLS_KEY"); val != "" {
+		c.API.TLSKey = val
+	}
+
+	if val := os.Getenv("HARBORBUDDY_API_READ_ONLY"); val != "" {
+		if readOnly, err := strconv.ParseBool(val); err == nil {
+			c.API.ReadOnly = readOnly
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_METRICS_PER_CONTAINER"); val != "" {
+		if perContainer, err := strconv.ParseBool(val); err == nil {
+			c.Metrics.PerContainer = perContainer
+		}
+	}
+
+	if val := os.Getenv("HARBORBUDDY_METRICS_MAX_CONTAINER_SERIES"); val != "" {
+		if maxSeries, err := strconv.Atoi(val); err == nil {
+			c.Metrics.MaxContainerSeries = maxSeries
+		}
+	}
+}
+
+// ValidateNotificationURLs checks that notifications.urls name services HarborBuddy can
+// notify. The notify package sets it, as only it knows the URL schemes.
+var ValidateNotificationURLs func(urls []string) error
+
+// Validate checks if the configuration is valid
+func (c *Config) Validate() error {
+	if c.Docker.Host == "" {
+		return fmt.Errorf("docker.host cannot be empty")
+	}
+
+	// If schedule_time is not set, check_interval must be positive
+	if c.Updates.ScheduleTime == "" && c.Updates.CheckInterval <= 0 {
+		return fmt.Errorf("updates.check_interval must be positive when schedule_time is not set")
+	}
+
+	if c.Updates.InitialDelay < 0 {
+		return fmt.Errorf("updates.initial_delay cannot be negative")
+	}
+
+	if c.Cleanup.CheckInterval < 0 {
+		return fmt.Errorf("cleanup.check_interval cannot be negative")
+	}
+
+	if _, err := c.Cleanup.UsageThreshold(); err != nil {
+		return err
+	}
+
+	if c.Cleanup.TriggerAtUsage != "" && c.Cleanup.UsageCheckInterval <= 0 {
+		return fmt.Errorf("cleanup.usage_check_interval must be positive")
+	}
+
+	for i, pattern := range c.Cleanup.ExcludeImages {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("cleanup.exclude_images[%d]: %w", i, err)
+		}
+	}
+
+	if c.Cleanup.Containers.MinAgeHours < 0 {
+		return fmt.Errorf("cleanup.containers.min_age_hours cannot be negative")
+	}
+
+	for i, pattern := range c.Cleanup.Containers.AllowNames {
+		if err := validateAllowPattern(pattern); err != nil {
+			return fmt.Errorf("cleanup.containers.allow_names[%d]: %w", i, err)
+		}
+	}
+
+	for i, pattern := range c.Cleanup.Containers.DenyNames {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("cleanup.containers.deny_names[%d]: %w", i, err)
+		}
+	}
+
+	if c.Cleanup.Volumes.MinAgeHours < 0 {
+		return fmt.Errorf("cleanup.volumes.min_age_hours cannot be negative")
+	}
+
+	for i, pattern := range c.Cleanup.Volumes.DenyNames {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("cleanup.volumes.deny_names[%d]: %w", i, err)
+		}
+	}
+
+	if c.Cleanup.Networks.MinAgeHours < 0 {
+		return fmt.Errorf("cleanup.networks.min_age_hours cannot be negative")
+	}
+
+	for i, pattern := range c.Cleanup.Networks.DenyNames {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("cleanup.networks.deny_names[%d]: %w", i, err)
+		}
+	}
+
+	if c.Cleanup.BuildCache.MaxSize != "" {
+		if _, err := util.ParseBytes(c.Cleanup.BuildCache.MaxSize); err != nil {
+			return fmt.Errorf("cleanup.build_cache.max_size: %w", err)
+		}
+	}
+
+	if c.Cleanup.Orphans.MinAgeHours < 0 {
+		return fmt.Errorf("cleanup.orphans.min_age_hours cannot be negative")
+	}
+
+	if c.Cleanup.ScheduleTime != "" {
+		if _, err := time.Parse("15:04", c.Cleanup.ScheduleTime); err != nil {
+			return fmt.Errorf("invalid cleanup.schedule_time format: %s (must be HH:MM, e.g., '04:30')", c.Cleanup.ScheduleTime)
+		}
+		if _, err := time.LoadLocation(c.Updates.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %s (use IANA timezone names like 'America/Los_Angeles' or 'UTC')", c.Updates.Timezone)
+		}
+	}
+
+	if c.Updates.StopTimeout <= 0 {
+		return fmt.Errorf("updates.stop_timeout must be positive")
+	}
+
+	if c.Updates.HealthTimeout < 0 {
+		return fmt.Errorf("updates.health_timeout cannot be negative")
+	}
+
+	if c.Updates.HealthGracePeriod < 0 {
+		return fmt.Errorf("updates.health_grace_period cannot be negative")
+	}
+
+	if c.Updates.HealthLogLines < 0 {
+		return fmt.Errorf("updates.health_log_lines cannot be negative")
+	}
+
+	if c.Updates.MaxParallelUpdates < 1 {
+		return fmt.Errorf("updates.max_parallel_updates must be at least 1")
+	}
+
+	if c.Updates.MaxUpdatesPerCycle < 0 {
+		return fmt.Errorf("updates.max_updates_per_cycle cannot be negative")
+	}
+
+	if c.Updates.StaggerDelay < 0 || c.Updates.StaggerJitter < 0 {
+		return fmt.Errorf("updates.stagger_delay and updates.stagger_jitter cannot be negative")
+	}
+
+	if c.Updates.HookTimeout <= 0 {
+		return fmt.Errorf("updates.hook_timeout must be positive")
+	}
+
+	if c.Updates.PullTimeout < 0 {
+		return fmt.Errorf("updates.pull_timeout cannot be negative")
+	}
+
+	if c.Updates.CycleTimeout < 0 {
+		return fmt.Errorf("updates.cycle_timeout cannot be negative")
+	}
+
+	if c.Updates.DrainTimeout < 0 {
+		return fmt.Errorf("updates.drain_timeout cannot be negative")
+	}
+
+	if c.Updates.PullRetries < 0 {
+		return fmt.Errorf("updates.pull_retries cannot be negative")
+	}
+
+	if c.Updates.MinImageAge < 0 {
+		return fmt.Errorf("updates.min_image_age cannot be negative")
+	}
+
+	if c.Updates.MinFreeSpace != "" {
+		if _, err := util.ParseBytes(c.Updates.MinFreeSpace); err != nil {
+			return fmt.Errorf("updates.min_free_space: %w", err)
+		}
+	}
+
+	if c.Updates.MaxConcurrentPulls < 0 {
+		return fmt.Errorf("updates.max_concurrent_pulls cannot be negative")
+	}
+
+	for host, limit := range c.Updates.RegistryPullLimits {
+		if host == "" {
+			return fmt.Errorf("updates.registry_pull_limits cannot contain an empty host")
+		}
+		if limit < 1 {
+			return fmt.Errorf("updates.registry_pull_limits.%s must be at least 1", host)
+		}
+	}
+
+	if c.Updates.CheckMethod != CheckMethodPull && c.Updates.CheckMethod != CheckMethodDigest {
+		return fmt.Errorf("invalid updates.check_method: %s (must be %q or %q)", c.Updates.CheckMethod, CheckMethodPull, CheckMethodDigest)
+	}
+
+	if !validPolicy(c.Updates.Policy) {
+		return fmt.Errorf("invalid updates.policy: %s (must be digest, patch, minor or major)", c.Updates.Policy)
+	}
+
+	if !ValidStrategy(c.Updates.Strategy) {
+		return fmt.Errorf("invalid updates.strategy: %s (must be blue_green or recreate)", c.Updates.Strategy)
+	}
+
+	for name, deps := range c.Updates.Dependencies {
+		if name == "" {
+			return fmt.Errorf("updates.dependencies: container name cannot be empty")
+		}
+		for _, dep := range deps {
+			if strings.TrimSpace(dep) == "" {
+				return fmt.Errorf("updates.dependencies.%s: dependency name cannot be empty", name)
+			}
+		}
+	}
+
+	for i, rule := range c.Updates.Policies {
+		if err := validatePattern(rule.Pattern); err != nil {
+			return fmt.Errorf("updates.policies[%d]: %w", i, err)
+		}
+		if !validPolicy(rule.Policy) {
+			return fmt.Errorf("updates.policies[%d]: invalid policy %s (must be digest, patch, minor or major)", i, rule.Policy)
+		}
+	}
+
+	for repo, tag := range c.Updates.Track {
+		if repo == "" || strings.ContainsAny(repo, "@*") || strings.Contains(repo[strings.LastIndex(repo, "/")+1:], ":") {
+			return fmt.Errorf("updates.track: invalid repository %q (must be a repository without a tag, e.g. \"nginx\")", repo)
+		}
+		if !tagFormat.MatchString(tag) {
+			return fmt.Errorf("updates.track.%s: invalid tag %q", repo, tag)
+		}
+	}
+
+	// If schedule_time is set, validate the format
+	if c.Updates.ScheduleTime != "" {
+		if _, err := time.Parse("15:04", c.Updates.ScheduleTime); err != nil {
+			return fmt.Errorf("invalid schedule_time format: %s (must be HH:MM, e.g., '03:00')", c.Updates.ScheduleTime)
+		}
+
+		// Validate timezone
+		if _, err := time.LoadLocation(c.Updates.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %s (use IANA timezone names like 'America/Los_Angeles' or 'UTC')", c.Updates.Timezone)
+		}
+	}
+
+	for i, pattern := range c.Updates.AllowImages {
+		if err := validateAllowPattern(pattern); err != nil {
+			return fmt.Errorf("updates.allow_images[%d]: %w", i, err)
+		}
+	}
+
+	for i, pattern := range c.Updates.DenyImages {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("updates.deny_images[%d]: %w", i, err)
+		}
+	}
+
+	for i, pattern := range c.Updates.AllowContainers {
+		if err := validateAllowPattern(pattern); err != nil {
+			return fmt.Errorf("updates.allow_containers[%d]: %w", i, err)
+		}
+	}
+
+	for i, pattern := range c.Updates.DenyContainers {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("updates.deny_containers[%d]: %w", i, err)
+		}
+	}
+
+	for i, pattern := range c.Updates.CanaryContainers {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("updates.canary_containers[%d]: %w", i, err)
+		}
+	}
+
+	if c.Cleanup.MinAgeHours < 0 {
+		return fmt.Errorf("cleanup.min_age_hours cannot be negative")
+	}
+
+	if c.Metrics.MaxContainerSeries < 0 {
+		return fmt.Errorf("metrics.max_container_series cannot be negative")
+	}
+
+	for _, label := range c.Log.NameLabels {
+		if strings.TrimSpace(label) == "" {
+			return fmt.Errorf("log.name_labels cannot contain empty entries")
+		}
+	}
+
+	switch c.Notifications.Level {
+	case NotifyAll, NotifyChanges, NotifyErrors:
+	default:
+		return fmt.Errorf("invalid notifications.level: %s (must be all, changes or errors)", c.Notifications.Level)
+	}
+	if c.Notifications.RemindAfter < 0 {
+		return fmt.Errorf("notifications.remind_after cannot be negative")
+	}
+	if c.Notifications.Digest != "" {
+		if _, err := time.Parse("15:04", c.Notifications.Digest); err != nil {
+			return fmt.Errorf("invalid notifications.digest format: %s (must be HH:MM, e.g., '09:00')", c.Notifications.Digest)
+		}
+		if _, err := time.LoadLocation(c.Updates.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %s (use IANA timezone names like 'America/Los_Angeles' or 'UTC')", c.Updates.Timezone)
+		}
+	}
+
+	if c.Notifications.hasChannels() && c.Notifications.Timeout <= 0 {
+		return fmt.Errorf("notifications.timeout must be positive")
+	}
+
+	if c.Notifications.WebhookURL != "" && !isHTTPURL(c.Notifications.WebhookURL) {
+		return fmt.Errorf("notifications.webhook_url must be an http(s) URL")
+	}
+
+	for i, raw := range c.Notifications.URLs {
+		if u, err := url.Parse(raw); err != nil || u.Scheme == "" {
+			return fmt.Errorf("notifications.urls[%d] must be a URL like \"scheme://...\"", i)
+		}
+	}
+	if ValidateNotificationURLs != nil {
+		if err := ValidateNotificationURLs(c.Notifications.URLs); err != nil {
+			return err
+		}
+	}
+
+	if email := c.Notifications.Email; email.Host != "" {
+		if email.Port <= 0 || email.Port > 65535 {
+			return fmt.Errorf("notifications.email.port must be between 1 and 65535")
+		}
+		if email.From == "" || len(email.To) == 0 {
+			return fmt.Errorf("notifications.email requires from and at least one to address")
+		}
+		switch email.TLS {
+		case EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone:
+		default:
+			return fmt.Errorf("invalid notifications.email.tls: %s (must be %q, %q or %q)", email.TLS, EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone)
+		}
+		for field, text := range map[string]string{"subject": email.Subject, "body": email.Body} {
+			if _, err := template.New(field).Parse(text); err != nil {
+				return fmt.Errorf("invalid notifications.email.%s template: %w", field, err)
+			}
+		}
+	}
+
+	if err := c.Notifications.Templates.Validate(); err != nil {
+		return fmt.Errorf("notifications.templates: %w", err)
+	}
+
+	if gotify := c.Notifications.Gotify; gotify.URL != "" {
+		if !isHTTPURL(gotify.URL) {
+			return fmt.Errorf("notifications.gotify.url must be an http(s) URL")
+		}
+		if gotify.Token == "" {
+			return fmt.Errorf("notifications.gotify requires an application token")
+		}
+		if err := validatePriorities("notifications.gotify.priorities", gotify.Priorities, 0, 10); err != nil {
+			return err
+		}
+		if err := gotify.MessageTemplates.Validate(); err != nil {
+			return fmt.Errorf("notifications.gotify: %w", err)
+		}
+	}
+
+	if ntfy := c.Notifications.Ntfy; ntfy.Topic != "" {
+		if !isHTTPURL(ntfy.URL) {
+			return fmt.Errorf("notifications.ntfy.url must be an http(s) URL")
+		}
+		if strings.Contains(ntfy.Topic, "/") {
+			return fmt.Errorf("notifications.ntfy.topic cannot contain '/'")
+		}
+		if err := validatePriorities("notifications.ntfy.priorities", ntfy.Priorities, 1, 5); err != nil {
+			return err
+		}
+		if err := ntfy.MessageTemplates.Validate(); err != nil {
+			return fmt.Errorf("notifications.ntfy: %w", err)
+		}
+	}
+
+	if err := c.MQTT.validate(); err != nil {
+		return err
+	}
+
+	if c.Hooks.Enabled() && c.Hooks.Timeout <= 0 {
+		return fmt.Errorf("hooks.timeout must be positive")
+	}
+
+	if c.Report.URL != "" {
+		if !isHTTPURL(c.Report.URL) {
+			return fmt.Errorf("report.url must be an http(s) URL")
+		}
+		if c.Report.Timeout <= 0 {
+			return fmt.Errorf("report.timeout must be positive")
+		}
+	}
+
+	// Only key-based signatures can be checked; keyless ones need Fulcio and Rekor
+	if c.Verify.Enabled && len(c.Verify.PublicKeys) == 0 {
+		return fmt.Errorf("verify.public_keys is required when verify.enabled is true")
+	}
+
+	if c.Scan.Enabled {
+		if c.Scan.Trivy == "" {
+			return fmt.Errorf("scan.trivy is required when scan.enabled is true")
+		}
+		if c.Scan.Server != "" && !isHTTPURL(c.Scan.Server) {
+			return fmt.Errorf("scan.server must be an http(s) URL")
+		}
+		if c.Scan.Timeout <= 0 {
+			return fmt.Errorf("scan.timeout must be positive")
+		}
+	}
+	if c.Scan.MaxCritical < 0 {
+		return fmt.Errorf("scan.max_critical cannot be negative")
+	}
+
+	switch c.SelfUpdate.Channel {
+	case SelfUpdateStable, SelfUpdateBeta, SelfUpdateNone:
+	default:
+		return fmt.Errorf("invalid selfupdate.channel: %s (must be stable, beta or none)", c.SelfUpdate.Channel)
+	}
+	if image := c.SelfUpdate.Image; strings.Contains(image, "@") || strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+		return fmt.Errorf("selfupdate.image must be a repository without a tag or digest, e.g. ghcr.io/mikeo7/harborbuddy")
+	}
+
+	if c.API.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.API.Listen); err != nil {
+			return fmt.Errorf("invalid api.listen address %q (e.g., ':8080'): %w", c.API.Listen, err)
+		}
+	}
+	if (c.API.Username == "") != (c.API.Password == "") {
+		return fmt.Errorf("api.username and api.password must be set together")
+	}
+	if (c.API.TLSCert == "") != (c.API.TLSKey == "") {
+		return fmt.Errorf("api.tls_cert and api.tls_key must be set together")
+	}
+
+	for host, auth := range c.Registries {
+		if strings.TrimSpace(host) == "" {
+			return fmt.Errorf("registries cannot contain an empty host")
+		}
+		if auth.Token == "" && (auth.Username == "" || auth.Password == "") {
+			return fmt.Errorf("registries.%s requires a token or both username and password", host)
+		}
+	}
+
+	if len(c.LabelFilter) > 0 && !c.RunOnce && !c.CleanupOnly {
+		return fmt.Errorf("label filter is only supported with --once or --cleanup-only")
+	}
+
+	switch c.Output {
+	case "", OutputText:
+	case OutputJSON:
+		if !c.RunOnce && !c.CleanupOnly {
+			return fmt.Errorf("--output json is only supported with --once, --cleanup-only or the update subcommand")
+		}
+	default:
+		return fmt.Errorf("invalid output format: %s (must be text or json)", c.Output)
+	}
+
+	if c.Force && len(c.Targets) == 0 {
+		return fmt.Errorf("--force is only supported with the update subcommand")
+	}
+
+	validLogLevels := map[string]bool{
+		"debug": true,
+		"info":  true,
+		"warn":  true,
+		"error": true,
+	}
+
+	if !validLogLevels[c.Log.Level] {
+		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.Log.Level)
+	}
+	if c.Log.MaxAgeDays < 0 {
+		return fmt.Errorf("log.max_age_days cannot be negative")
+	}
+
+	if c.Audit.File != "" {
+		if c.Audit.MaxSize <= 0 {
+			return fmt.Errorf("audit.max_size must be positive")
+		}
+		if c.Audit.MaxBackups < 0 {
+			return fmt.Errorf("audit.max_backups cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// ParseLabelFilter parses "key=value" entries into a label filter
+func ParseLabelFilter(entries []string) (map[string]string, error) {
+	filter := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label filter %q (must be key=value)", entry)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
+
+// tagFormat is what Docker accepts as an image tag
+var tagFormat = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// validPolicy reports whether p is a known update policy
+func validPolicy(p string) bool {
+	switch p {
+	case PolicyDigest, PolicyPatch, PolicyMinor, PolicyMajor:
+		return true
+	}
+	return false
+}
+
+// validatePattern rejects patterns the matcher can't handle: globs that don't parse, "re:"
+// patterns that don't compile, and negation outside allow lists
+func validatePattern(pattern string) error {
+	if strings.HasPrefix(pattern, util.NegationPrefix) {
+		return fmt.Errorf("invalid pattern %q: '!' negation is only supported in allow lists", pattern)
+	}
+	return util.ValidatePattern(pattern)
+}
+
+// validateAllowPattern checks a pattern of an allow list, which may be negated with '!'
+func validateAllowPattern(pattern string) error {
+	return util.ValidatePattern(strings.TrimPrefix(pattern, util.NegationPrefix))
+}
+
+// splitList splits a comma-separated environment value, dropping empty entries
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isHTTPURL reports whether s is an absolute http(s) URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validatePriorities checks that a per-event priority map only names known event types
+// and stays within the service's priority range
+func validatePriorities(field string, priorities map[string]int, lo, hi int) error {
+	for event, priority := range priorities {
+		switch event {
+		case "update", "update_available", "failure", "cleanup":
+		default:
+			return fmt.Errorf("invalid %s key %q (must be update, update_available, failure or cleanup)", field, event)
+		}
+		if priority < lo || priority > hi {
+			return fmt.Errorf("%s.%s must be between %d and %d", field, event, lo, hi)
+		}
+	}
+	return nil
+}
+
+// validate checks the MQTT settings when a broker is set
+func (m MQTTConfig) validate() error {
+	if m.Broker == "" {
+		return nil
+	}
+	u, err := url.Parse(m.Broker)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("mqtt.broker must be a URL like mqtt://host:1883 or mqtts://host:8883")
+	}
+	switch u.Scheme {
+	case "mqtt", "mqtts", "tcp", "ssl":
+	default:
+		return fmt.Errorf("invalid mqtt.broker scheme: %s (must be mqtt, mqtts, tcp or ssl)", u.Scheme)
+	}
+	if m.ClientID == "" {
+		return fmt.Errorf("mqtt.client_id cannot be empty")
+	}
+	if m.TopicPrefix == "" || strings.ContainsAny(m.TopicPrefix, "+#") || strings.HasPrefix(m.TopicPrefix, "/") || strings.HasSuffix(m.TopicPrefix, "/") {
+		return fmt.Errorf("mqtt.topic_prefix must be a topic without wildcards or leading and trailing '/', e.g. harborbuddy")
+	}
+	if m.QoS != 0 && m.QoS != 1 {
+		return fmt.Errorf("mqtt.qos must be 0 or 1 (QoS 2 is not supported)")
+	}
 	return nil
 }
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envReference matches "$VAR", "${VAR}", "${VAR:-default}" and the escape "$$"
+var envReference = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// templateAction matches a Go template action such as "{{range $i, $c := .Updated}}"
+var templateAction = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+
+// expandEnv replaces environment variable references in s, as os.ExpandEnv does: "$VAR" and
+// "${VAR}" become the variable's value, or nothing when it is unset; "${VAR:-default}" uses
+// default when it is unset or empty. "$$" stands for a literal "$". Inside a template action
+// ("{{ }}") only the braced forms are expanded, so templates keep their "$variables".
+func expandEnv(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range templateAction.FindAllStringIndex(s, -1) {
+		b.WriteString(expandReferences(s[last:loc[0]], true))
+		b.WriteString(expandReferences(s[loc[0]:loc[1]], false))
+		last = loc[1]
+	}
+	b.WriteString(expandReferences(s[last:], true))
+	return b.String()
+}
+
+// expandReferences expands the references in s, leaving "$VAR" alone unless bare is set
+func expandReferences(s string, bare bool) string {
+	return envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		match := envReference.FindStringSubmatch(ref)
+		if match[3] != "" {
+			if !bare {
+				return ref
+			}
+			return os.Getenv(match[3])
+		}
+		if val := os.Getenv(match[1]); val != "" {
+			return val
+		}
+		return match[2]
+	})
+}
+
+// expandNode expands environment variable references in every value below node. Keys are
+// left as they are. A plain value is typed again once expanded, so "port: ${SMTP_PORT}"
+// still loads as a number.
+func expandNode(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if expanded := expandEnv(node.Value); expanded != node.Value {
+			node.Value = expanded
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			expandNode(node.Content[i])
+		}
+	default:
+		for _, child := range node.Content {
+			expandNode(child)
+		}
+	}
+}
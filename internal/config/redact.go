@@ -0,0 +1,76 @@
+package config
+
+import (
+	"net/url"
+)
+
+// redacted replaces secrets when a configuration is printed
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the configuration that is safe to print: passwords and tokens
+// are replaced, and so are the credentials, paths and queries of URLs that may embed them
+// (webhooks, notification URLs). Server addresses are kept so the output still shows where
+// things go.
+func (c Config) Redacted() Config {
+	if len(c.Registries) > 0 {
+		registries := make(map[string]RegistryAuth, len(c.Registries))
+		for host, auth := range c.Registries {
+			auth.Password = redactSecret(auth.Password)
+			auth.Token = redactSecret(auth.Token)
+			registries[host] = auth
+		}
+		c.Registries = registries
+	}
+
+	n := &c.Notifications
+	n.WebhookURL = redactURL(n.WebhookURL)
+	n.WebhookSecret = redactSecret(n.WebhookSecret)
+	if len(n.URLs) > 0 {
+		urls := make([]string, len(n.URLs))
+		for i, u := range n.URLs {
+			urls[i] = redactURL(u)
+		}
+		n.URLs = urls
+	}
+	n.Email.Password = redactSecret(n.Email.Password)
+	n.Gotify.Token = redactSecret(n.Gotify.Token)
+	n.Ntfy.Token = redactSecret(n.Ntfy.Token)
+
+	c.Report.URL = redactURL(c.Report.URL)
+	c.API.WebhookSecret = redactSecret(c.API.WebhookSecret)
+	c.API.Token = redactSecret(c.API.Token)
+	c.API.Password = redactSecret(c.API.Password)
+	c.MQTT.Password = redactSecret(c.MQTT.Password)
+	return c
+}
+
+// redactSecret hides a secret, keeping an unset one empty so it still reads as unset
+func redactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL keeps a URL's scheme and host and hides its user info, path and query
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.Path != "" && u.Path != "/" {
+		u.Path = "/" + redacted
+		u.RawPath = ""
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redacted
+	}
+	u.Fragment = ""
+	return u.String()
+}
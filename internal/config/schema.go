@@ -0,0 +1,101 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schemaEnums lists the allowed values of fields that take one of a fixed set, by YAML path.
+// Array items share their array's path.
+var schemaEnums = map[string][]string{
+	"updates.check_method":    {CheckMethodPull, CheckMethodDigest},
+	"updates.policy":          {PolicyDigest, PolicyPatch, PolicyMinor, PolicyMajor},
+	"updates.policies.policy": {PolicyDigest, PolicyPatch, PolicyMinor, PolicyMajor},
+	"updates.strategy":        {StrategyBlueGreen, StrategyRecreate},
+	"log.level":               {"debug", "info", "warn", "error"},
+	"notifications.email.tls": {EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone},
+	"selfupdate.channel":      {SelfUpdateStable, SelfUpdateBeta, SelfUpdateNone},
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// JSONSchema describes the configuration file as a JSON Schema, for editors that validate
+// and complete YAML (e.g. the YAML language server's "# yaml-language-server: $schema=").
+func JSONSchema() ([]byte, error) {
+	schema := schemaFor(reflect.TypeOf(Config{}), "")
+
+	// A profile overrides any of the top-level settings, except profiles
+	profile := schemaFor(reflect.TypeOf(Config{}), "")
+	delete(profile["properties"].(map[string]interface{}), "profiles")
+	schema["properties"].(map[string]interface{})["profiles"] = map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": profile,
+	}
+
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "HarborBuddy configuration"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaFor builds the schema of a type found at the given YAML path
+func schemaFor(t reflect.Type, path string) map[string]interface{} {
+	if t == durationType {
+		return map[string]interface{}{
+			"type":        "string",
+			"pattern":     `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`,
+			"description": "Duration such as 30s, 15m or 1h30m",
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		addProperties(properties, t, path)
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), path)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), path)}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		schema := map[string]interface{}{"type": "string"}
+		if values := schemaEnums[path]; len(values) > 0 {
+			schema["enum"] = values
+		}
+		return schema
+	}
+}
+
+// addProperties adds a struct's YAML fields to properties, flattening inline structs
+func addProperties(properties map[string]interface{}, t reflect.Type, path string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if opts == "inline" {
+			addProperties(properties, field.Type, path)
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		properties[name] = schemaFor(field.Type, fieldPath)
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LoadSecretFiles reads the secrets configured with *_file settings (e.g. Docker secrets
+// mounted in /run/secrets) into the settings they stand for. A trailing newline is dropped.
+// Setting both a secret and its file is an error.
+func (c *Config) LoadSecretFiles() error {
+	hosts := make([]string, 0, len(c.Registries))
+	for host := range c.Registries {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		auth := c.Registries[host]
+		if err := readSecretFile(&auth.Password, auth.PasswordFile, "registries."+host+".password"); err != nil {
+			return err
+		}
+		if err := readSecretFile(&auth.Token, auth.TokenFile, "registries."+host+".token"); err != nil {
+			return err
+		}
+		c.Registries[host] = auth
+	}
+
+	if err := readSecretFile(&c.API.WebhookSecret, c.API.WebhookSecretFile, "api.webhook_secret"); err != nil {
+		return err
+	}
+	if err := readSecretFile(&c.API.Token, c.API.TokenFile, "api.token"); err != nil {
+		return err
+	}
+	if err := readSecretFile(&c.API.Password, c.API.PasswordFile, "api.password"); err != nil {
+		return err
+	}
+
+	if err := readSecretFile(&c.MQTT.Password, c.MQTT.PasswordFile, "mqtt.password"); err != nil {
+		return err
+	}
+
+	n := &c.Notifications
+	if err := readSecretFile(&n.WebhookURL, n.WebhookURLFile, "notifications.webhook_url"); err != nil {
+		return err
+	}
+	if err := readSecretFile(&n.WebhookSecret, n.WebhookSecretFile, "notifications.webhook_secret"); err != nil {
+		return err
+	}
+	if err := readSecretFile(&n.Email.Password, n.Email.PasswordFile, "notifications.email.password"); err != nil {
+		return err
+	}
+	if err := readSecretFile(&n.Gotify.Token, n.Gotify.TokenFile, "notifications.gotify.token"); err != nil {
+		return err
+	}
+	if err := readSecretFile(&n.Ntfy.Token, n.Ntfy.TokenFile, "notifications.ntfy.token"); err != nil {
+		return err
+	}
+
+	if n.URLsFile != "" {
+		data, err := os.ReadFile(n.URLsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read notifications.urls_file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				n.URLs = append(n.URLs, line)
+			}
+		}
+	}
+	return nil
+}
+
+// readSecretFile sets *value, the setting named field, to the contents of path if one is given
+func readSecretFile(value *string, path, field string) error {
+	if path == "" {
+		return nil
+	}
+	if *value != "" {
+		return fmt.Errorf("set either %s or %s_file, not both", field, field)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s_file: %w", field, err)
+	}
+	*value = strings.TrimRight(string(data), "\r\n")
+	if *value == "" {
+		return fmt.Errorf("%s_file %s is empty", field, path)
+	}
+	return nil
+}
+
+// Secrets lists the secret values in the configuration (passwords, tokens, and URLs that may
+// embed them along with their credentials), so they can be scrubbed from logs and API responses.
+func (c Config) Secrets() []string {
+	var secrets []string
+	add := func(values ...string) {
+		for _, v := range values {
+			if v != "" {
+				secrets = append(secrets, v)
+			}
+		}
+	}
+
+	for _, auth := range c.Registries {
+		add(auth.Password, auth.Token)
+	}
+
+	add(c.API.WebhookSecret, c.API.Token, c.API.Password, c.MQTT.Password)
+
+	n := c.Notifications
+	add(n.WebhookSecret, n.Email.Password, n.Gotify.Token, n.Ntfy.Token)
+	for _, raw := range append([]string{n.WebhookURL, c.Report.URL}, n.URLs...) {
+		add(raw)
+		add(urlCredentials(raw)...)
+	}
+	return secrets
+}
+
+// urlCredentials returns the credentials in a URL's user info: the password, or the user
+// name when it is the only part (e.g. "discord://token@id")
+func urlCredentials(raw string) []string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return nil
+	}
+	if password, ok := u.User.Password(); ok {
+		return []string{password}
+	}
+	return []string{u.User.Username()}
+}
@@ -0,0 +1,111 @@
+package dashboard
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+// requestTimeout bounds each API call, so a hung server doesn't freeze the screen
+const requestTimeout = 5 * time.Second
+
+// Target is the API the dashboard talks to and the credentials it sends
+type Target struct {
+	URL      string
+	Token    string
+	Username string
+	Password string
+	// SkipVerify accepts any server certificate, for a self-signed one served on localhost
+	SkipVerify bool
+}
+
+// TargetFor returns the API configured by cfg as seen from the same host, with its
+// credentials: api.listen ":8080" becomes "http://localhost:8080", or https with
+// api.tls_cert set
+func TargetFor(cfg config.APIConfig) Target {
+	scheme := "http"
+	if cfg.TLSCert != "" {
+		scheme = "https"
+	}
+	target := Target{Token: cfg.Token, Username: cfg.Username, Password: cfg.Password}
+
+	host, port, err := net.SplitHostPort(cfg.Listen)
+	if err != nil {
+		target.URL = scheme + "://" + cfg.Listen
+		return target
+	}
+	if host == "" || net.ParseIP(host) != nil && net.ParseIP(host).IsUnspecified() {
+		host = "localhost"
+	}
+	target.URL = scheme + "://" + net.JoinHostPort(host, port)
+	target.SkipVerify = scheme == "https" && (host == "localhost" || net.ParseIP(host).IsLoopback())
+	return target
+}
+
+// client calls HarborBuddy's HTTP API
+type client struct {
+	target Target
+	base   string
+	http   *http.Client
+}
+
+func newClient(target Target) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if target.SkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &client{
+		target: target,
+		base:   strings.TrimRight(target.URL, "/"),
+		http:   &http.Client{Timeout: requestTimeout, Transport: transport},
+	}
+}
+
+// get decodes the JSON response of a GET into v
+func (c *client) get(ctx context.Context, path string, v interface{}) error {
+	return c.do(ctx, http.MethodGet, path, v)
+}
+
+// do sends a request and decodes a successful response into v, if it isn't nil. Failures
+// carry the error message the API responded with.
+func (c *client) do(ctx context.Context, method, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, nil)
+	if err != nil {
+		return err
+	}
+	switch {
+	case c.target.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.target.Token)
+	case c.target.Password != "":
+		req.SetBasicAuth(c.target.Username, c.target.Password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+			return fmt.Errorf("%s", body.Error)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// Package dashboard is the terminal UI of "harborbuddy dashboard". It shows what a running
+// HarborBuddy is doing through its HTTP API: the managed containers, the last and next
+// cycle and the current cycle's log, with hotkeys to run a cycle or freeze a container.
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/api"
+	"github.com/MikeO7/HarborBuddy/internal/updater"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/moby/term"
+)
+
+// refreshInterval is how often the dashboard polls the API
+const refreshInterval = 2 * time.Second
+
+// maxLogLines bounds the cycle log the dashboard keeps
+const maxLogLines = 500
+
+// freezeReason is recorded on containers frozen with the f key
+const freezeReason = "frozen from the dashboard"
+
+// dashboard holds what the screen shows
+type dashboard struct {
+	api *client
+
+	status     api.Status
+	containers []updater.ListEntry
+	frozen     map[string]bool
+	logs       []log.Line
+	selected   int
+
+	message string // Outcome of the last hotkey
+	err     error  // Why the last refresh failed
+}
+
+// Run shows the dashboard of the target API on the terminal in until q is pressed or ctx ends
+func Run(ctx context.Context, target Target, in *os.File, out io.Writer) error {
+	fd, isTerminal := term.GetFdInfo(in)
+	if !isTerminal {
+		return errors.New("the dashboard needs a terminal (with Docker, use docker exec -it)")
+	}
+	d := &dashboard{api: newClient(target)}
+	// /status rather than /healthz, which needs no credentials, so bad ones are caught here
+	if err := d.api.get(ctx, "/status", nil); err != nil {
+		return fmt.Errorf("cannot reach the API at %s: %w", target.URL, err)
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer term.RestoreTerminal(fd, state)
+
+	// Draw on the alternate screen without a cursor, and give the screen back on exit
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan string)
+	go readKeys(in, keys)
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		d.refresh(ctx)
+		width, height := 80, 24
+		if size, err := term.GetWinsize(fd); err == nil && size.Width > 0 && size.Height > 0 {
+			width, height = int(size.Width), int(size.Height)
+		}
+		d.render(out, target.URL, width, height, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case key, ok := <-keys:
+			if !ok || !d.handle(ctx, key) {
+				return nil
+			}
+		}
+	}
+}
+
+// refresh fetches everything the screen shows
+func (d *dashboard) refresh(ctx context.Context) {
+	var status api.Status
+	if err := d.api.get(ctx, "/status", &status); err != nil {
+		d.err = err
+		return
+	}
+	// A new cycle starts a new log
+	if status.Running && !d.status.Running {
+		d.logs = nil
+	}
+	d.status = status
+
+	var containers []updater.ListEntry
+	if err := d.api.get(ctx, "/containers", &containers); err != nil {
+		d.err = err
+		return
+	}
+	d.containers = containers
+	d.selected = min(d.selected, max(len(containers)-1, 0))
+
+	// Without a state file nothing can be frozen, and the API answers with an error
+	var frozen []updater.FrozenContainer
+	_ = d.api.get(ctx, "/freeze", &frozen)
+	d.frozen = make(map[string]bool, len(frozen))
+	for _, f := range frozen {
+		d.frozen[f.Name] = true
+	}
+
+	var after uint64
+	if len(d.logs) > 0 {
+		after = d.logs[len(d.logs)-1].Seq
+	}
+	var lines []log.Line
+	if err := d.api.get(ctx, "/logs?after="+strconv.FormatUint(after, 10), &lines); err != nil {
+		d.err = err
+		return
+	}
+	d.logs = append(d.logs, lines...)
+	if over := len(d.logs) - maxLogLines; over > 0 {
+		d.logs = d.logs[over:]
+	}
+	d.err = nil
+}
+
+// handle acts on a key and returns false when the dashboard should quit
+func (d *dashboard) handle(ctx context.Context, key string) bool {
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "up":
+		d.selected = max(d.selected-1, 0)
+	case "down":
+		d.selected = min(d.selected+1, max(len(d.containers)-1, 0))
+	case "r":
+		if err := d.api.do(ctx, http.MethodPost, "/trigger", nil); err != nil {
+			d.message = "Run: " + err.Error()
+		} else {
+			d.message = "Cycle triggered"
+		}
+	case "f":
+		if len(d.containers) == 0 {
+			return true
+		}
+		name := d.containers[d.selected].Name
+		path := "/freeze/" + url.PathEscape(name)
+		if d.frozen[name] {
+			if err := d.api.do(ctx, http.MethodDelete, path, nil); err != nil {
+				d.message = "Unfreeze: " + err.Error()
+			} else {
+				d.message = "Unfroze " + name
+			}
+		} else {
+			if err := d.api.do(ctx, http.MethodPost, path+"?reason="+url.QueryEscape(freezeReason), nil); err != nil {
+				d.message = "Freeze: " + err.Error()
+			} else {
+				d.message = "Froze " + name + " until unfrozen"
+			}
+		}
+	}
+	return true
+}
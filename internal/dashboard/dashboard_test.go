@@ -0,0 +1,210 @@
+package dashboard
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+// fakeAPI serves canned responses and records the requests that change something
+type fakeAPI struct {
+	mu      sync.Mutex
+	running bool
+	frozen  bool
+	calls   []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.URL.Path == "/status":
+		if f.running {
+			w.Write([]byte(`{"running": true, "paused": {"reason": "migration", "paused_at": "2026-10-16T09:00:00Z"}}`))
+			return
+		}
+		w.Write([]byte(`{"running": false, "last_cycle": {"id": "a1b2c3d4", "finished_at": "2026-10-16T03:00:00Z", "duration": "1.2s", "success": false, "error": "pull failed"}}`))
+	case r.URL.Path == "/containers":
+		w.Write([]byte(`[{"name": "web", "image": "nginx:latest", "eligible": true, "reason": "eligible", "policy": "digest"},
+			{"name": "db", "image": "postgres:16", "eligible": false, "reason": "label com.harborbuddy.autoupdate=false", "policy": "digest"}]`))
+	case r.URL.Path == "/freeze" && r.Method == http.MethodGet:
+		if f.frozen {
+			w.Write([]byte(`[{"name": "web"}]`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	case r.URL.Path == "/logs":
+		if r.URL.Query().Get("after") == "0" {
+			w.Write([]byte(`[{"seq": 1, "text": "10:00:00 INF Starting update & cleanup cycle"}, {"seq": 2, "text": "10:00:01 INF Pulling nginx:latest"}]`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	case r.URL.Path == "/trigger":
+		f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error": "a cycle is already running or queued"}`))
+	case strings.HasPrefix(r.URL.Path, "/freeze/"):
+		f.calls = append(f.calls, r.Method+" "+r.URL.RequestURI())
+		f.frozen = r.Method == http.MethodPost
+		w.Write([]byte(`{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestDashboard_RefreshAndRender(t *testing.T) {
+	server := httptest.NewServer(&fakeAPI{})
+	defer server.Close()
+
+	d := &dashboard{api: newClient(Target{URL: server.URL})}
+	d.refresh(context.Background())
+	if d.err != nil {
+		t.Fatalf("refresh() error = %v", d.err)
+	}
+
+	var screen strings.Builder
+	d.render(&screen, server.URL, 120, 20, time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC))
+	out := screen.String()
+	for _, want := range []string{
+		"last cycle a1b2c3d4",
+		"failed: pull failed",
+		"nginx:latest",
+		"label com.harborbuddy.autoupdate=false",
+		"Pulling nginx:latest",
+		"r run a cycle now",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("screen is missing %q:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, reverse+"web ") {
+		t.Errorf("the first container isn't highlighted:\n%s", out)
+	}
+	if lines := strings.Split(out, "\r\n"); len(lines) != 20 {
+		t.Errorf("screen has %d lines, want the terminal's 20", len(lines))
+	}
+}
+
+func TestDashboard_NewCycleClearsLog(t *testing.T) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	d := &dashboard{api: newClient(Target{URL: server.URL})}
+	d.refresh(context.Background())
+	if len(d.logs) != 2 {
+		t.Fatalf("logs = %+v, want 2 lines", d.logs)
+	}
+
+	api.mu.Lock()
+	api.running = true
+	api.mu.Unlock()
+	d.refresh(context.Background())
+	if len(d.logs) != 2 || d.logs[0].Seq != 1 {
+		t.Errorf("logs = %+v, want the log fetched anew for the new cycle", d.logs)
+	}
+	if line := d.statusLine(time.Now()); !strings.HasPrefix(line, "paused until resumed (migration)") {
+		t.Errorf("statusLine() = %q, want the pause first", line)
+	}
+}
+
+func TestDashboard_Hotkeys(t *testing.T) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	d := &dashboard{api: newClient(Target{URL: server.URL})}
+	ctx := context.Background()
+	d.refresh(ctx)
+
+	d.handle(ctx, "r")
+	if d.message != "Run: a cycle is already running or queued" {
+		t.Errorf("message = %q, want the API's error", d.message)
+	}
+
+	d.handle(ctx, "f")
+	d.refresh(ctx)
+	if !d.frozen["web"] || d.message != "Froze web until unfrozen" {
+		t.Errorf("after f: frozen = %v, message = %q, want web frozen", d.frozen, d.message)
+	}
+	d.handle(ctx, "f")
+	d.handle(ctx, "down")
+	d.handle(ctx, "down")
+	if d.selected != 1 {
+		t.Errorf("selected = %d, want it to stop at the last container", d.selected)
+	}
+	if d.handle(ctx, "q") {
+		t.Error("q did not quit")
+	}
+
+	want := []string{"POST /trigger", "POST /freeze/web?reason=frozen+from+the+dashboard", "DELETE /freeze/web"}
+	if !slices.Equal(api.calls, want) {
+		t.Errorf("calls = %v, want %v", api.calls, want)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("r\x1b[A\x1b[Bjk\x1b[C\x03q"))
+	want := []string{"r", "up", "down", "down", "up", "ctrl-c", "q"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestTargetFor(t *testing.T) {
+	tests := map[string]string{
+		":8080":          "http://localhost:8080",
+		"0.0.0.0:8080":   "http://localhost:8080",
+		"[::]:8080":      "http://localhost:8080",
+		"127.0.0.1:9000": "http://127.0.0.1:9000",
+		"harborbuddy:80": "http://harborbuddy:80",
+	}
+	for listen, want := range tests {
+		if got := TargetFor(config.APIConfig{Listen: listen}); got.URL != want || got.SkipVerify {
+			t.Errorf("TargetFor(%q) = %+v, want %q", listen, got, want)
+		}
+	}
+
+	got := TargetFor(config.APIConfig{Listen: ":8443", Token: "s3cret", TLSCert: "tls.crt", TLSKey: "tls.key"})
+	if got.URL != "https://localhost:8443" || got.Token != "s3cret" || !got.SkipVerify {
+		t.Errorf("TargetFor() with TLS = %+v, want https to localhost with the token", got)
+	}
+	if got := TargetFor(config.APIConfig{Listen: "harborbuddy:8443", TLSCert: "tls.crt", TLSKey: "tls.key"}); got.SkipVerify {
+		t.Error("TargetFor() skips verifying the certificate of another host")
+	}
+}
+
+func TestClient_SendsCredentials(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	newClient(Target{URL: server.URL, Token: "s3cret"}).get(ctx, "/status", nil)
+	newClient(Target{URL: server.URL, Username: "admin", Password: "hunter2"}).get(ctx, "/status", nil)
+	want := []string{"Bearer s3cret", "Basic YWRtaW46aHVudGVyMg=="}
+	if !slices.Equal(got, want) {
+		t.Errorf("Authorization headers = %q, want %q", got, want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("héllo world", 6); got != "héllo…" {
+		t.Errorf("truncate() = %q, want héllo…", got)
+	}
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate() = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,46 @@
+package dashboard
+
+import "io"
+
+// readKeys sends the keys read from in until it fails, then closes keys
+func readKeys(in io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// parseKeys names the keys in a chunk of raw terminal input: "up" and "down" for the arrow
+// keys (j and k too), "ctrl-c", and printable characters as themselves. Other escape
+// sequences are dropped.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == 0x1b && i+2 < len(b) && (b[i+1] == '[' || b[i+1] == 'O'):
+			switch b[i+2] {
+			case 'A':
+				keys = append(keys, "up")
+			case 'B':
+				keys = append(keys, "down")
+			}
+			i += 2
+		case c == 0x03:
+			keys = append(keys, "ctrl-c")
+		case c == 'k':
+			keys = append(keys, "up")
+		case c == 'j':
+			keys = append(keys, "down")
+		case c >= 0x20 && c < 0x7f:
+			keys = append(keys, string(c))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,133 @@
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+// ANSI sequences the screen is drawn with
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	reverse     = "\x1b[7m"
+	bold        = "\x1b[1m"
+	reset       = "\x1b[0m"
+)
+
+// render draws the whole screen: a header with the scheduler's status, the containers with
+// the selected one highlighted, the tail of the cycle log and a footer with the hotkeys
+func (d *dashboard) render(w io.Writer, baseURL string, width, height int, now time.Time) {
+	var screen []string
+	add := func(style, line string) {
+		line = truncate(line, width)
+		if style != "" {
+			line = style + line + reset
+		}
+		screen = append(screen, line)
+	}
+
+	add(bold, fmt.Sprintf("HarborBuddy dashboard · %s · %s", baseURL, now.Format(time.TimeOnly)))
+	add("", d.statusLine(now))
+	add("", "")
+
+	// Containers take up to half the rows left for them and the log, scrolled to the selection
+	rows := max((height-8)/2, 1)
+	table := d.containerTable()
+	add(bold, table[0])
+	first := max(min(d.selected-rows/2, len(table)-1-rows), 0)
+	for i := first; i < min(first+rows, len(table)-1); i++ {
+		style := ""
+		if i == d.selected {
+			style = reverse
+		}
+		add(style, table[i+1])
+	}
+	add("", "")
+
+	title := "Log of the last cycle"
+	if d.status.Running {
+		title = "Log of the running cycle"
+	}
+	add(bold, title)
+	logRows := max(height-len(screen)-2, 0)
+	logs := d.logs[max(len(d.logs)-logRows, 0):]
+	for _, line := range logs {
+		add("", line.Text)
+	}
+	for i := len(logs); i < logRows; i++ {
+		add("", "")
+	}
+
+	add("", "")
+	footer := "↑/↓ select · r run a cycle now · f freeze/unfreeze · q quit"
+	switch {
+	case d.err != nil:
+		footer += " · " + d.err.Error()
+	case d.message != "":
+		footer += " · " + d.message
+	}
+	add(reverse, footer)
+
+	fmt.Fprint(w, clearScreen+strings.Join(screen, "\r\n"))
+}
+
+// statusLine summarizes whether a cycle runs, how the last one went and when the next is due
+func (d *dashboard) statusLine(now time.Time) string {
+	parts := []string{"idle"}
+	if d.status.Running {
+		parts[0] = "cycle running"
+	}
+	if p := d.status.Paused; p != nil {
+		parts[0] = p.String()
+	}
+	if last := d.status.LastCycle; last != nil {
+		outcome := "succeeded"
+		if !last.Success {
+			outcome = "failed: " + last.Error
+		}
+		parts = append(parts, fmt.Sprintf("last cycle %s at %s took %s, %s",
+			last.ID, last.FinishedAt.Local().Format("Jan 2 15:04"), last.Duration, outcome))
+	} else {
+		parts = append(parts, "no cycle yet")
+	}
+	if next := d.status.NextRun; next != nil {
+		parts = append(parts, fmt.Sprintf("next run %s (in %s)",
+			next.Local().Format("Jan 2 15:04"), next.Sub(now).Round(time.Second)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// containerTable lays out the containers, header first
+func (d *dashboard) containerTable() []string {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tIMAGE\tELIGIBLE\tPOLICY\tREASON")
+	for _, c := range d.containers {
+		eligible := "no"
+		if c.Eligible {
+			eligible = "yes"
+		}
+		if d.frozen[c.Name] {
+			eligible = "frozen"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Name, c.Image, eligible, c.Policy, c.Reason)
+	}
+	tw.Flush()
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// truncate cuts s to width characters, marking the cut with "…"
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	if width < 1 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,167 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// dockerHubHost is the normalized host for Docker Hub images
+const dockerHubHost = "docker.io"
+
+// RegistryCredentials holds credentials for a single registry
+type RegistryCredentials struct {
+	Username      string
+	Password      string
+	IdentityToken string // OAuth refresh token, as stored by "docker login" for some registries
+	RegistryToken string // Bearer token sent to the registry as-is
+}
+
+// Keychain resolves registry credentials for image references.
+// Explicitly configured credentials take priority over the Docker CLI config file.
+type Keychain struct {
+	static           map[string]RegistryCredentials // keyed by normalized host
+	dockerConfigPath string
+}
+
+// NewKeychain creates a keychain from configured credentials (keyed by registry host)
+// and an optional Docker CLI config.json path. The config file is re-read on every
+// lookup so "docker login" changes are picked up without a restart.
+func NewKeychain(static map[string]RegistryCredentials, dockerConfigPath string) *Keychain {
+	normalized := make(map[string]RegistryCredentials, len(static))
+	for host, creds := range static {
+		normalized[NormalizeRegistryHost(host)] = creds
+	}
+	return &Keychain{static: normalized, dockerConfigPath: dockerConfigPath}
+}
+
+// DefaultDockerConfigPath returns the Docker CLI config location, honouring $DOCKER_CONFIG
+func DefaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// Resolve returns the credentials for the registry hosting the image, if any
+func (k *Keychain) Resolve(imageRef string) (RegistryCredentials, bool, error) {
+	host := RegistryHost(imageRef)
+
+	if creds, ok := k.static[host]; ok {
+		return creds, true, nil
+	}
+
+	if k.dockerConfigPath == "" {
+		return RegistryCredentials{}, false, nil
+	}
+	return lookupDockerConfig(k.dockerConfigPath, host)
+}
+
+// RegistryAuth returns the encoded X-Registry-Auth value for the image, or "" if no credentials apply
+func (k *Keychain) RegistryAuth(imageRef string) (string, error) {
+	creds, ok, err := k.Resolve(imageRef)
+	if err != nil || !ok {
+		return "", err
+	}
+
+	encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      creds.Username,
+		Password:      creds.Password,
+		IdentityToken: creds.IdentityToken,
+		RegistryToken: creds.RegistryToken,
+		ServerAddress: RegistryHost(imageRef),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode registry auth: %w", err)
+	}
+	return encoded, nil
+}
+
+// RegistryHost returns the normalized registry host for an image reference (e.g., "ghcr.io", "docker.io")
+func RegistryHost(imageRef string) string {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return dockerHubHost
+	}
+	return NormalizeRegistryHost(reference.Domain(named))
+}
+
+// NormalizeRegistryHost strips schemes and paths and maps Docker Hub aliases to one host.
+// Docker CLI stores Docker Hub as "https://index.docker.io/v1/".
+func NormalizeRegistryHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	host, _, _ = strings.Cut(host, "/")
+	host = strings.ToLower(host)
+
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHubHost
+	}
+	return host
+}
+
+// dockerConfigFile is the subset of ~/.docker/config.json we understand.
+// Credential helpers (credsStore/credHelpers) are not supported.
+type dockerConfigFile struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+		RegistryToken string `json:"registrytoken"`
+	} `json:"auths"`
+}
+
+// lookupDockerConfig reads credentials for host from a Docker CLI config file
+func lookupDockerConfig(path, host string) (RegistryCredentials, bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return RegistryCredentials{}, false, nil
+	}
+	if err != nil {
+		return RegistryCredentials{}, false, fmt.Errorf("failed to read docker config: %w", err)
+	}
+
+	var file dockerConfigFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return RegistryCredentials{}, false, fmt.Errorf("failed to parse docker config %s: %w", path, err)
+	}
+
+	for key, entry := range file.Auths {
+		if NormalizeRegistryHost(key) != host {
+			continue
+		}
+
+		creds := RegistryCredentials{
+			Username:      entry.Username,
+			Password:      entry.Password,
+			IdentityToken: entry.IdentityToken,
+			RegistryToken: entry.RegistryToken,
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return RegistryCredentials{}, false, fmt.Errorf("invalid auth for %s in docker config: %w", key, err)
+			}
+			user, pass, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return RegistryCredentials{}, false, fmt.Errorf("invalid auth for %s in docker config: expected user:password", key)
+			}
+			creds.Username, creds.Password = user, pass
+		}
+		return creds, true, nil
+	}
+
+	return RegistryCredentials{}, false, nil
+}
@@ -0,0 +1,134 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+)
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"nginx", "docker.io"},
+		{"nginx:latest", "docker.io"},
+		{"library/nginx:1.25", "docker.io"},
+		{"ghcr.io/org/app:v1", "ghcr.io"},
+		{"registry.example.com:5000/team/app", "registry.example.com:5000"},
+		{"localhost/app", "localhost"},
+		{"GHCR.IO/org/app", "ghcr.io"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := RegistryHost(tt.image); got != tt.expected {
+				t.Errorf("RegistryHost(%q) = %q, want %q", tt.image, got, tt.expected)
+			}
+		})
+	}
+}
+
+func writeDockerConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write docker config: %v", err)
+	}
+	return path
+}
+
+func TestKeychain_Resolve(t *testing.T) {
+	hubAuth := base64.StdEncoding.EncodeToString([]byte("hubuser:hubpass"))
+	path := writeDockerConfig(t, `{
+		"auths": {
+			"https://index.docker.io/v1/": {"auth": "`+hubAuth+`"},
+			"ghcr.io": {"auth": "`+base64.StdEncoding.EncodeToString([]byte("fileuser:filepass"))+`"},
+			"registry.example.com": {"identitytoken": "refresh-token"}
+		}
+	}`)
+
+	k := NewKeychain(map[string]RegistryCredentials{
+		"https://ghcr.io": {Username: "configured", Password: "secret"},
+	}, path)
+
+	tests := []struct {
+		name   string
+		image  string
+		found  bool
+		expect RegistryCredentials
+	}{
+		{"docker hub from file", "nginx:latest", true, RegistryCredentials{Username: "hubuser", Password: "hubpass"}},
+		{"configured wins over file", "ghcr.io/org/app", true, RegistryCredentials{Username: "configured", Password: "secret"}},
+		{"identity token from file", "registry.example.com/app", true, RegistryCredentials{IdentityToken: "refresh-token"}},
+		{"unknown registry", "quay.io/org/app", false, RegistryCredentials{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, found, err := k.Resolve(tt.image)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if found != tt.found || creds != tt.expect {
+				t.Errorf("Resolve(%q) = %+v, %v; want %+v, %v", tt.image, creds, found, tt.expect, tt.found)
+			}
+		})
+	}
+}
+
+func TestKeychain_MissingOrInvalidConfig(t *testing.T) {
+	k := NewKeychain(nil, filepath.Join(t.TempDir(), "missing.json"))
+	if _, found, err := k.Resolve("nginx"); err != nil || found {
+		t.Errorf("missing config should resolve nothing without error, got found=%v err=%v", found, err)
+	}
+
+	k = NewKeychain(nil, writeDockerConfig(t, `{not json`))
+	if _, _, err := k.Resolve("nginx"); err == nil {
+		t.Error("invalid config should return an error")
+	}
+}
+
+func TestDockerClient_PullImage_SendsRegistryAuth(t *testing.T) {
+	transport := newMockTransport()
+
+	var header string
+	transport.register("POST", "/v1.41/images/create", func(req *http.Request) (*http.Response, error) {
+		header = req.Header.Get(registry.AuthHeader)
+		return jsonResponse(200, map[string]string{"status": "Downloaded"})
+	})
+	transport.register("GET", "/v1.41/images/ghcr.io/org/app:v1/json", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, map[string]interface{}{"Id": "sha256:new", "Config": map[string]interface{}{}})
+	})
+
+	cli, err := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	d := &DockerClient{cli: cli}
+	d.SetKeychain(NewKeychain(map[string]RegistryCredentials{
+		"ghcr.io": {Username: "octocat", Password: "token"},
+	}, ""))
+
+	if _, err := d.PullImage(context.Background(), "ghcr.io/org/app:v1"); err != nil {
+		t.Fatalf("PullImage() error = %v", err)
+	}
+
+	auth, err := registry.DecodeAuthConfig(header)
+	if err != nil {
+		t.Fatalf("failed to decode %s header %q: %v", registry.AuthHeader, header, err)
+	}
+	if auth.Username != "octocat" || auth.Password != "token" || auth.ServerAddress != "ghcr.io" {
+		t.Errorf("sent auth = %+v", auth)
+	}
+}
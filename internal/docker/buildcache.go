@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/docker/docker/api/types/build"
+)
+
+// PruneBuildCache removes build cache until at most keepBytes remain, oldest first.
+// A keepBytes of 0 removes all unused cache. It returns how many cache records were
+// deleted and the space reclaimed.
+func (d *DockerClient) PruneBuildCache(ctx context.Context, keepBytes int64) (int, int64, error) {
+	report, err := d.cli.BuildCachePrune(ctx, build.CachePruneOptions{
+		All: true,
+		// Daemons before API 1.48 only understand keep-storage; newer ones call it reserved-space
+		KeepStorage:   keepBytes,
+		ReservedSpace: keepBytes,
+	})
+	entry := audit.Entry{Action: "prune", Object: audit.ObjectBuildCache}
+	if err == nil {
+		entry.Detail = fmt.Sprintf("%d records, %d bytes reclaimed", len(report.CachesDeleted), report.SpaceReclaimed)
+	}
+	audit.Record(ctx, entry, err)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prune build cache: %w", err)
+	}
+	return len(report.CachesDeleted), int64(report.SpaceReclaimed), nil
+}
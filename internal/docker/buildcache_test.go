@@ -0,0 +1,49 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/client"
+)
+
+func TestDockerClient_PruneBuildCache(t *testing.T) {
+	transport := newMockTransport()
+
+	var query map[string]string
+	transport.register("POST", "/v1.41/build/prune", func(req *http.Request) (*http.Response, error) {
+		q := req.URL.Query()
+		query = map[string]string{
+			"all":            q.Get("all"),
+			"keep-storage":   q.Get("keep-storage"),
+			"reserved-space": q.Get("reserved-space"),
+		}
+		return jsonResponse(200, build.CachePruneReport{
+			CachesDeleted:  []string{"a", "b"},
+			SpaceReclaimed: 2048,
+		})
+	})
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	deleted, reclaimed, err := d.PruneBuildCache(context.Background(), 5*1024*1024*1024)
+	if err != nil {
+		t.Fatalf("PruneBuildCache failed: %v", err)
+	}
+
+	if deleted != 2 || reclaimed != 2048 {
+		t.Errorf("PruneBuildCache() = %d, %d, want 2, 2048", deleted, reclaimed)
+	}
+	want := map[string]string{"all": "1", "keep-storage": "5368709120", "reserved-space": "5368709120"}
+	for k, v := range want {
+		if query[k] != v {
+			t.Errorf("query %s = %q, want %q", k, query[k], v)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+)
+
+// Events stream timing; variables for tests
+var (
+	eventRetryDelay   = 5 * time.Second // How long Watch waits before resubscribing to a failed stream
+	eventStreamSettle = time.Second     // How long a new stream must run without failing to count as open
+)
+
+// CachingClient wraps a Client and caches InspectContainer results.
+// Entries are invalidated by Docker events and by mutating calls made through the wrapper.
+// The cache is only used while the events stream is healthy; otherwise calls pass straight through.
+type CachingClient struct {
+	Client
+
+	mu         sync.Mutex
+	watching   bool
+	entries    map[string]ContainerInfo // keyed by the ID or name used to inspect
+	generation uint64                   // bumped on every invalidation to discard racing inspects
+	hits       uint64
+	misses     uint64
+}
+
+// NewCachingClient wraps the given client with an inspect cache
+func NewCachingClient(inner Client) *CachingClient {
+	return &CachingClient{
+		Client:  inner,
+		entries: make(map[string]ContainerInfo),
+	}
+}
+
+// Watch subscribes to Docker events and keeps the cache coherent until ctx is cancelled.
+// The cache is only enabled once the stream is open, and if it drops, the cache is flushed
+// and disabled until the subscription is restored.
+func (c *CachingClient) Watch(ctx context.Context) {
+	for {
+		events, errs := c.Client.Events(ctx)
+		err := c.consume(ctx, events, errs)
+
+		c.setWatching(false)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Warnf("Docker events stream interrupted, inspect cache disabled until reconnected: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventRetryDelay):
+		}
+	}
+}
+
+// consume applies events to the cache until the stream ends. Docker's client subscribes in
+// the background and reports a subscription that fails, e.g. an unreachable daemon, on errs;
+// the stream counts as open, and the cache is enabled, once an event arrives or it has run
+// for eventStreamSettle without failing.
+func (c *CachingClient) consume(ctx context.Context, events <-chan Event, errs <-chan error) error {
+	settled := time.NewTimer(eventStreamSettle)
+	defer settled.Stop()
+	open := false
+	markOpen := func() {
+		if !open {
+			open = true
+			c.setWatching(true)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case <-settled.C:
+			markOpen()
+		case event, ok := <-events:
+			if !ok {
+				return <-errs
+			}
+			markOpen()
+			// Exec events don't change anything we inspect
+			if strings.HasPrefix(event.Action, "exec_") {
+				continue
+			}
+			if id := event.ContainerID(); id != "" {
+				c.Invalidate(id)
+			}
+		}
+	}
+}
+
+func (c *CachingClient) setWatching(watching bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.watching = watching
+	c.generation++
+	if !watching {
+		c.entries = make(map[string]ContainerInfo)
+	}
+}
+
+// Invalidate drops any cached entries for the given container ID or name
+func (c *CachingClient) Invalidate(idOrName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key, info := range c.entries {
+		if key == idOrName || info.ID == idOrName || info.Name == idOrName || strings.HasPrefix(info.ID, idOrName) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Stats returns the number of cache hits and misses so far
+func (c *CachingClient) Stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// InspectContainer returns a cached inspect result when available
+func (c *CachingClient) InspectContainer(ctx context.Context, id string) (ContainerInfo, error) {
+	c.mu.Lock()
+	if c.watching {
+		if info, ok := c.entries[id]; ok {
+			c.hits++
+			c.mu.Unlock()
+			return info, nil
+		}
+	}
+	c.misses++
+	generation := c.generation
+	c.mu.Unlock()
+
+	info, err := c.Client.InspectContainer(ctx, id)
+	if err != nil {
+		return info, err
+	}
+
+	c.mu.Lock()
+	if c.watching && c.generation == generation {
+		c.entries[id] = info
+	}
+	c.mu.Unlock()
+
+	return info, nil
+}
+
+// StopContainer stops a container and invalidates its cache entry
+func (c *CachingClient) StopContainer(ctx context.Context, id string, timeout int) error {
+	defer c.Invalidate(id)
+	return c.Client.StopContainer(ctx, id, timeout)
+}
+
+// StartContainer starts a container and invalidates its cache entry
+func (c *CachingClient) StartContainer(ctx context.Context, id string) error {
+	defer c.Invalidate(id)
+	return c.Client.StartContainer(ctx, id)
+}
+
+// RemoveContainer removes a container and invalidates its cache entry
+func (c *CachingClient) RemoveContainer(ctx context.Context, id string) error {
+	defer c.Invalidate(id)
+	return c.Client.RemoveContainer(ctx, id)
+}
+
+// RenameContainer renames a container and invalidates its cache entry
+func (c *CachingClient) RenameContainer(ctx context.Context, id, newName string) error {
+	defer c.Invalidate(id)
+	return c.Client.RenameContainer(ctx, id, newName)
+}
+
+// ReplaceContainer replaces a container and invalidates both old and new entries
+func (c *CachingClient) ReplaceContainer(ctx context.Context, oldID, newID, name string, opts ReplaceOptions) error {
+	defer c.Invalidate(newID)
+	defer c.Invalidate(oldID)
+	return c.Client.ReplaceContainer(ctx, oldID, newID, name, opts)
+}
+
+// RecreateContainer recreates a container and invalidates the old entry and its name
+func (c *CachingClient) RecreateContainer(ctx context.Context, old ContainerInfo, newImage string, opts ReplaceOptions) (string, error) {
+	defer c.Invalidate(old.Name)
+	defer c.Invalidate(old.ID)
+	return c.Client.RecreateContainer(ctx, old, newImage, opts)
+}
+
+// ReplaceAutoRemoveContainer replaces a --rm container and invalidates both old and new entries
+func (c *CachingClient) ReplaceAutoRemoveContainer(ctx context.Context, oldID, newID, name string, opts ReplaceOptions) error {
+	defer c.Invalidate(newID)
+	defer c.Invalidate(oldID)
+	return c.Client.ReplaceAutoRemoveContainer(ctx, oldID, newID, name, opts)
+}
@@ -0,0 +1,216 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it returns true or the deadline passes
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("condition not met before deadline")
+}
+
+func newWatchedCache(t *testing.T) (*CachingClient, *MockDockerClient, chan Event) {
+	t.Helper()
+	original := eventStreamSettle
+	eventStreamSettle = 10 * time.Millisecond
+	t.Cleanup(func() { eventStreamSettle = original })
+
+	mock := NewMockDockerClient()
+	mock.Containers = []ContainerInfo{{ID: "abc123", Name: "nginx"}}
+	mock.EventsChan = make(chan Event)
+
+	cache := NewCachingClient(mock)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go cache.Watch(ctx)
+
+	waitFor(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return cache.watching
+	})
+
+	return cache, mock, mock.EventsChan
+}
+
+func TestCachingClient_CachesWhileWatching(t *testing.T) {
+	cache, mock, _ := newWatchedCache(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.InspectContainer(ctx, "abc123"); err != nil {
+			t.Fatalf("InspectContainer() error = %v", err)
+		}
+	}
+
+	if len(mock.InspectedContainers) != 1 {
+		t.Errorf("expected 1 underlying inspect, got %d", len(mock.InspectedContainers))
+	}
+	hits, misses := cache.Stats()
+	if hits != 2 || misses != 1 {
+		t.Errorf("Stats() = %d hits, %d misses; want 2, 1", hits, misses)
+	}
+}
+
+func TestCachingClient_EventInvalidates(t *testing.T) {
+	cache, mock, events := newWatchedCache(t)
+	ctx := context.Background()
+
+	_, _ = cache.InspectContainer(ctx, "abc123")
+
+	// Exec events must not invalidate
+	events <- Event{Type: "container", Action: "exec_start: sh", ActorID: "abc123"}
+	_, _ = cache.InspectContainer(ctx, "abc123")
+	if len(mock.InspectedContainers) != 1 {
+		t.Fatalf("exec event should not invalidate, got %d inspects", len(mock.InspectedContainers))
+	}
+
+	events <- Event{Type: "container", Action: "die", ActorID: "abc123"}
+	waitFor(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return len(cache.entries) == 0
+	})
+
+	_, _ = cache.InspectContainer(ctx, "abc123")
+	if len(mock.InspectedContainers) != 2 {
+		t.Errorf("die event should invalidate, got %d inspects", len(mock.InspectedContainers))
+	}
+}
+
+func TestCachingClient_NetworkEventInvalidates(t *testing.T) {
+	cache, _, events := newWatchedCache(t)
+	_, _ = cache.InspectContainer(context.Background(), "nginx")
+
+	events <- Event{Type: "network", Action: "disconnect", ActorID: "net1", Attributes: map[string]string{"container": "abc123"}}
+	waitFor(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return len(cache.entries) == 0
+	})
+}
+
+func TestCachingClient_MutationsInvalidate(t *testing.T) {
+	cache, mock, _ := newWatchedCache(t)
+	ctx := context.Background()
+
+	_, _ = cache.InspectContainer(ctx, "abc123")
+	_ = cache.StopContainer(ctx, "abc123", 10)
+	_, _ = cache.InspectContainer(ctx, "abc123")
+
+	if len(mock.InspectedContainers) != 2 {
+		t.Errorf("StopContainer should invalidate, got %d inspects", len(mock.InspectedContainers))
+	}
+}
+
+func TestCachingClient_PassThroughWithoutWatch(t *testing.T) {
+	mock := NewMockDockerClient()
+	mock.Containers = []ContainerInfo{{ID: "abc123", Name: "nginx"}}
+	cache := NewCachingClient(mock)
+	ctx := context.Background()
+
+	_, _ = cache.InspectContainer(ctx, "abc123")
+	_, _ = cache.InspectContainer(ctx, "abc123")
+
+	if len(mock.InspectedContainers) != 2 {
+		t.Errorf("cache must not be used without an events stream, got %d inspects", len(mock.InspectedContainers))
+	}
+}
+
+func TestCachingClient_WaitsForStreamToOpen(t *testing.T) {
+	original := eventStreamSettle
+	eventStreamSettle = time.Hour
+	defer func() { eventStreamSettle = original }()
+
+	mock := NewMockDockerClient()
+	mock.Containers = []ContainerInfo{{ID: "abc123", Name: "nginx"}}
+	mock.EventsChan = make(chan Event)
+	cache := NewCachingClient(mock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cache.Watch(ctx)
+
+	// Subscribed, but nothing shows the stream is open yet
+	_, _ = cache.InspectContainer(ctx, "abc123")
+	_, _ = cache.InspectContainer(ctx, "abc123")
+	if len(mock.InspectedContainers) != 2 {
+		t.Errorf("cache used before the events stream was open, got %d inspects", len(mock.InspectedContainers))
+	}
+
+	mock.EventsChan <- Event{Type: "image", Action: "pull", ActorID: "nginx:latest"}
+	waitFor(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return cache.watching
+	})
+}
+
+func TestCachingClient_StreamErrorDisablesCache(t *testing.T) {
+	original := eventRetryDelay
+	eventRetryDelay = time.Hour
+	defer func() { eventRetryDelay = original }()
+
+	mock := NewMockDockerClient()
+	mock.Containers = []ContainerInfo{{ID: "abc123", Name: "nginx"}}
+	mock.EventsError = fmt.Errorf("connection refused")
+
+	cache := NewCachingClient(mock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		cache.Watch(ctx)
+		close(done)
+	}()
+
+	waitFor(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return !cache.watching && cache.generation >= 1
+	})
+
+	_, _ = cache.InspectContainer(ctx, "abc123")
+	_, _ = cache.InspectContainer(ctx, "abc123")
+	if len(mock.InspectedContainers) != 2 {
+		t.Errorf("cache should be bypassed after stream failure, got %d inspects", len(mock.InspectedContainers))
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Watch did not return after cancellation")
+	}
+}
+
+func TestEvent_ContainerID(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    Event
+		expected string
+	}{
+		{"container", Event{Type: "container", ActorID: "c1"}, "c1"},
+		{"network", Event{Type: "network", ActorID: "n1", Attributes: map[string]string{"container": "c2"}}, "c2"},
+		{"image", Event{Type: "image", ActorID: "sha256:img"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.ContainerID(); got != tt.expected {
+				t.Errorf("ContainerID() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
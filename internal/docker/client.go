@@ -2,8 +2,10 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
+	"io"
+	"syscall"
 
 	"github.com/docker/docker/client"
 )
@@ -19,19 +21,44 @@ type Client interface {
 	StartContainer(ctx context.Context, id string) error
 	RemoveContainer(ctx context.Context, id string) error
 	CreateContainerLike(ctx context.Context, old ContainerInfo, newImage string) (string, error)
-	ReplaceContainer(ctx context.Context, oldID, newID, name string, stopTimeout time.Duration) error
+	ReplaceContainer(ctx context.Context, oldID, newID, name string, opts ReplaceOptions) error
+	ReplaceAutoRemoveContainer(ctx context.Context, oldID, newID, name string, opts ReplaceOptions) error
+	RecreateContainer(ctx context.Context, old ContainerInfo, newImage string, opts ReplaceOptions) (string, error)
 	GetContainersUsingImage(ctx context.Context, imageID string) ([]string, error)
+	ListExitedContainers(ctx context.Context) ([]ContainerInfo, error)
+	ListStoppedContainers(ctx context.Context) ([]ContainerInfo, error)
 	RenameContainer(ctx context.Context, id, newName string) error
 	CreateHelperContainer(ctx context.Context, original ContainerInfo, image, name string, cmd []string) (string, error)
+	ExecContainer(ctx context.Context, id string, cmd []string) (ExecResult, error)
+	RunContainer(ctx context.Context, image string, cmd []string) (ExecResult, error)
 
 	// Image functions
 	InspectImage(ctx context.Context, image string) (ImageInfo, error)
+	TagImage(ctx context.Context, source, target string) error
 	ListDanglingImages(ctx context.Context) ([]ImageInfo, error)
+
+	// Volume and network functions
+	ListDanglingVolumes(ctx context.Context) ([]VolumeInfo, error)
+	RemoveVolume(ctx context.Context, name string) error
+	ListUnusedNetworks(ctx context.Context) ([]NetworkInfo, error)
+	RemoveNetwork(ctx context.Context, id string) error
+	PruneBuildCache(ctx context.Context, keepBytes int64) (int, int64, error)
+	DataRootUsage(ctx context.Context, path string) (DiskUsage, error)
+
+	// DaemonPlatform returns the OS and architecture the Docker daemon runs on
+	DaemonPlatform(ctx context.Context) (Platform, error)
+
+	// Ping checks that the Docker daemon answers
+	Ping(ctx context.Context) error
+
+	// Events streams daemon events until ctx is cancelled
+	Events(ctx context.Context) (<-chan Event, <-chan error)
 }
 
 // DockerClient implements the Client interface using Docker SDK
 type DockerClient struct {
-	cli *client.Client
+	cli      *client.Client
+	keychain *Keychain // optional registry credentials for pulls
 }
 
 // NewClient creates a new Docker client
@@ -55,6 +82,11 @@ func NewClient(host string) (*DockerClient, error) {
 	return &DockerClient{cli: cli}, nil
 }
 
+// SetKeychain sets the registry credentials used when pulling images
+func (d *DockerClient) SetKeychain(k *Keychain) {
+	d.keychain = k
+}
+
 // Close closes the Docker client connection
 func (d *DockerClient) Close() error {
 	if d.cli != nil {
@@ -62,3 +94,36 @@ func (d *DockerClient) Close() error {
 	}
 	return nil
 }
+
+// DaemonPlatform returns the OS and architecture the Docker daemon runs on
+func (d *DockerClient) DaemonPlatform(ctx context.Context) (Platform, error) {
+	version, err := d.cli.ServerVersion(ctx)
+	if err != nil {
+		return Platform{}, fmt.Errorf("failed to get Docker version: %w", err)
+	}
+	return Platform{OS: version.Os, Architecture: version.Arch}, nil
+}
+
+// Ping checks that the Docker daemon answers
+func (d *DockerClient) Ping(ctx context.Context) error {
+	if _, err := d.cli.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping docker daemon: %w", err)
+	}
+	return nil
+}
+
+// IsConnectionError reports whether err means the Docker daemon could not be reached at all,
+// as while it restarts: its socket is missing, refuses connections or hangs up mid-request.
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if client.IsErrConnectionFailed(err) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ENOENT) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
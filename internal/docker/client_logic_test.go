@@ -1,12 +1,23 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/MikeO7/HarborBuddy/internal/audit"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
@@ -70,7 +81,7 @@ func TestDockerClient_ReplaceContainer_Rollback(t *testing.T) {
 	d := &DockerClient{cli: cli}
 
 	// Act
-	err = d.ReplaceContainer(context.Background(), "old123", "new456", "my-app", 1*time.Second)
+	err = d.ReplaceContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{StopTimeout: time.Second})
 
 	// Assert
 	if err == nil {
@@ -125,11 +136,18 @@ func TestDockerClient_ReplaceContainer_Success(t *testing.T) {
 	)
 	d := &DockerClient{cli: cli}
 
-	err := d.ReplaceContainer(context.Background(), "old123", "new456", "my-app", 1*time.Second)
+	var steps []ReplaceStep
+	err := d.ReplaceContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{
+		StopTimeout: time.Second,
+		Progress:    func(step ReplaceStep) { steps = append(steps, step) },
+	})
 
 	if err != nil {
 		t.Errorf("expected success, got error: %v", err)
 	}
+	if want := []ReplaceStep{StepStopped, StepBackedUp, StepSwapped, StepStarted}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("progress steps = %v, want %v", steps, want)
+	}
 
 	calls := transport.getCalls()
 	expected := "DELETE /v1.41/containers/old123"
@@ -145,6 +163,211 @@ func TestDockerClient_ReplaceContainer_Success(t *testing.T) {
 	}
 }
 
+func TestDockerClient_ReplaceContainer_RunState(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     RunState
+		wantCalls []string
+		wantSteps []ReplaceStep
+	}{
+		{
+			name:  "stopped stays stopped",
+			state: RunStateStopped,
+			wantCalls: []string{
+				"POST /v1.41/containers/old123/stop",
+				"POST /v1.41/containers/old123/rename",
+				"POST /v1.41/containers/new456/rename",
+				"DELETE /v1.41/containers/old123",
+			},
+			wantSteps: []ReplaceStep{StepStopped, StepBackedUp, StepSwapped},
+		},
+		{
+			name:  "paused is paused again",
+			state: RunStatePaused,
+			wantCalls: []string{
+				"POST /v1.41/containers/old123/stop",
+				"POST /v1.41/containers/old123/rename",
+				"POST /v1.41/containers/new456/rename",
+				"POST /v1.41/containers/new456/start",
+				"POST /v1.41/containers/new456/pause",
+				"DELETE /v1.41/containers/old123",
+			},
+			wantSteps: []ReplaceStep{StepStopped, StepBackedUp, StepSwapped, StepStarted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := newMockTransport()
+			ok := func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) }
+			transport.register("POST", "/v1.41/containers/old123/stop", ok)
+			transport.register("POST", "/v1.41/containers/old123/rename", ok)
+			transport.register("POST", "/v1.41/containers/new456/rename", ok)
+			transport.register("POST", "/v1.41/containers/new456/start", ok)
+			transport.register("POST", "/v1.41/containers/new456/pause", ok)
+			transport.register("DELETE", "/v1.41/containers/old123", ok)
+
+			cli, _ := client.NewClientWithOpts(
+				client.WithHTTPClient(&http.Client{Transport: transport}),
+				client.WithVersion("1.41"),
+			)
+			d := &DockerClient{cli: cli}
+
+			var steps []ReplaceStep
+			err := d.ReplaceContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{
+				StopTimeout: time.Second,
+				Progress:    func(step ReplaceStep) { steps = append(steps, step) },
+				RunState:    tt.state,
+			})
+			if err != nil {
+				t.Fatalf("ReplaceContainer() error = %v", err)
+			}
+			if !reflect.DeepEqual(steps, tt.wantSteps) {
+				t.Errorf("progress steps = %v, want %v", steps, tt.wantSteps)
+			}
+			if calls := transport.getCalls(); !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRunStateOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		state *types.ContainerState
+		want  RunState
+	}{
+		{"listed", nil, RunStateRunning},
+		{"running", &types.ContainerState{Running: true}, RunStateRunning},
+		{"restarting", &types.ContainerState{Restarting: true}, RunStateRunning},
+		{"paused", &types.ContainerState{Running: true, Paused: true}, RunStatePaused},
+		{"exited", &types.ContainerState{Status: "exited"}, RunStateStopped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RunStateOf(ContainerInfo{State: tt.state}); got != tt.want {
+				t.Errorf("RunStateOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerClient_DaemonPlatform(t *testing.T) {
+	transport := newMockTransport()
+	transport.register("GET", "/v1.41/version", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, types.Version{Os: "linux", Arch: "arm64"})
+	})
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	platform, err := d.DaemonPlatform(context.Background())
+	if err != nil {
+		t.Fatalf("DaemonPlatform failed: %v", err)
+	}
+	if platform.String() != "linux/arm64" {
+		t.Errorf("DaemonPlatform() = %s, want linux/arm64", platform)
+	}
+	if got := (Platform{OS: "linux", Architecture: "arm", Variant: "v7"}).String(); got != "linux/arm/v7" {
+		t.Errorf("Platform.String() = %s, want linux/arm/v7", got)
+	}
+}
+
+func TestDockerClient_Ping_ConnectionLost(t *testing.T) {
+	transport := newMockTransport()
+	transport.register("HEAD", "/_ping", func(req *http.Request) (*http.Response, error) {
+		return nil, &net.OpError{Op: "dial", Net: "unix", Err: syscall.ECONNREFUSED}
+	})
+	transport.register("GET", "/_ping", func(req *http.Request) (*http.Response, error) {
+		return nil, &net.OpError{Op: "dial", Net: "unix", Err: syscall.ECONNREFUSED}
+	})
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	err := d.Ping(context.Background())
+	if !IsConnectionError(err) {
+		t.Errorf("Ping() error = %v, want a connection error", err)
+	}
+}
+
+func TestDockerClient_RunContainer(t *testing.T) {
+	transport := newMockTransport()
+	var created container.Config
+	transport.register("POST", "/v1.41/containers/create", func(req *http.Request) (*http.Response, error) {
+		_ = json.NewDecoder(req.Body).Decode(&created)
+		return jsonResponse(201, container.CreateResponse{ID: "probe1"})
+	})
+	transport.register("POST", "/v1.41/containers/probe1/wait", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, container.WaitResponse{StatusCode: 0})
+	})
+	transport.register("POST", "/v1.41/containers/probe1/start", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(204, nil)
+	})
+	transport.register("GET", "/v1.41/containers/probe1/logs", func(req *http.Request) (*http.Response, error) {
+		// One stdout frame in the multiplexed log format
+		payload := "HarborBuddy version 0.3.0\n"
+		frame := append([]byte{1, 0, 0, 0, 0, 0, 0, byte(len(payload))}, payload...)
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(frame)), Header: make(http.Header)}, nil
+	})
+	removed := false
+	transport.register("DELETE", "/v1.41/containers/probe1", func(req *http.Request) (*http.Response, error) {
+		removed = true
+		return jsonResponse(204, nil)
+	})
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	result, err := d.RunContainer(context.Background(), "harborbuddy:latest", []string{"--version"})
+	if err != nil {
+		t.Fatalf("RunContainer failed: %v", err)
+	}
+	if result.ExitCode != 0 || result.Output != "HarborBuddy version 0.3.0" {
+		t.Errorf("RunContainer() = %+v, want exit 0 with the version", result)
+	}
+	if created.Image != "harborbuddy:latest" || strings.Join(created.Cmd, " ") != "--version" {
+		t.Errorf("created %s %v, want harborbuddy:latest --version", created.Image, created.Cmd)
+	}
+	if !removed {
+		t.Error("throwaway container was not removed")
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"connection refused", fmt.Errorf("list: %w", syscall.ECONNREFUSED), true},
+		{"socket missing", &net.OpError{Op: "dial", Net: "unix", Err: syscall.ENOENT}, true},
+		{"stream cut off", fmt.Errorf("events: %w", io.ErrUnexpectedEOF), true},
+		{"api error", errors.New("Error response from daemon: No such container: web"), false},
+		{"cancelled", context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsConnectionError(tt.err); got != tt.want {
+				t.Errorf("IsConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDockerClient_ListContainers_Parsing(t *testing.T) {
 	transport := newMockTransport()
 
@@ -186,6 +409,47 @@ func TestDockerClient_ListContainers_Parsing(t *testing.T) {
 	}
 }
 
+func TestDockerClient_ListExitedContainers(t *testing.T) {
+	transport := newMockTransport()
+
+	var filtersParam string
+	transport.register("GET", "/v1.41/containers/json", func(req *http.Request) (*http.Response, error) {
+		filtersParam = req.URL.Query().Get("filters")
+		return jsonResponse(200, []types.Container{{ID: "done1"}, {ID: "gone1"}})
+	})
+	transport.register("GET", "/v1.41/containers/done1/json", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID:      "done1",
+				Name:    "/batch-job",
+				Created: "2023-01-01T12:00:00Z",
+				State:   &types.ContainerState{Status: "exited", FinishedAt: "2023-01-02T12:00:00Z"},
+			},
+			Config:          &container.Config{Image: "busybox"},
+			NetworkSettings: &types.NetworkSettings{},
+		})
+	})
+	// gone1 is removed before it can be inspected and falls through to a 404
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	containers, err := d.ListExitedContainers(context.Background())
+	if err != nil {
+		t.Fatalf("ListExitedContainers failed: %v", err)
+	}
+
+	if !strings.Contains(filtersParam, `"exited":true`) || !strings.Contains(filtersParam, `"dead":true`) {
+		t.Errorf("filters = %s, want status exited and dead", filtersParam)
+	}
+	if len(containers) != 1 || containers[0].Name != "batch-job" || containers[0].State.FinishedAt != "2023-01-02T12:00:00Z" {
+		t.Errorf("containers = %+v, want only the inspected batch-job with its finish time", containers)
+	}
+}
+
 func TestDockerClient_InspectContainer_Parsing(t *testing.T) {
 	transport := newMockTransport()
 
@@ -234,3 +498,301 @@ func TestDockerClient_InspectContainer_Parsing(t *testing.T) {
 		t.Error("Expected Config to be populated")
 	}
 }
+
+func TestDockerClient_ReplaceAutoRemoveContainer(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		transport := newMockTransport()
+		transport.register("POST", "/v1.41/containers/old123/stop", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("GET", "/v1.41/containers/old123/json", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(404, map[string]string{"message": "No such container: old123"})
+		})
+		transport.register("POST", "/v1.41/containers/new456/rename", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("POST", "/v1.41/containers/new456/start", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+
+		cli, _ := client.NewClientWithOpts(
+			client.WithHTTPClient(&http.Client{Transport: transport}),
+			client.WithVersion("1.41"),
+		)
+		d := &DockerClient{cli: cli}
+
+		if err := d.ReplaceAutoRemoveContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{StopTimeout: time.Second}); err != nil {
+			t.Fatalf("expected success, got error: %v", err)
+		}
+
+		for _, call := range transport.getCalls() {
+			if call == "POST /v1.41/containers/old123/rename" {
+				t.Error("auto-remove flow must not try to rename the old container")
+			}
+		}
+	})
+
+	t.Run("stop failure discards new container", func(t *testing.T) {
+		transport := newMockTransport()
+		transport.register("POST", "/v1.41/containers/old123/stop", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(500, map[string]string{"message": "stop failed"})
+		})
+		transport.register("DELETE", "/v1.41/containers/new456", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+
+		cli, _ := client.NewClientWithOpts(
+			client.WithHTTPClient(&http.Client{Transport: transport}),
+			client.WithVersion("1.41"),
+		)
+		d := &DockerClient{cli: cli}
+
+		if err := d.ReplaceAutoRemoveContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{StopTimeout: time.Second}); err == nil {
+			t.Fatal("expected error when stop fails")
+		}
+
+		found := false
+		for _, call := range transport.getCalls() {
+			if call == "DELETE /v1.41/containers/new456" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("new container should be removed when the old one can't be stopped. Calls: %v", transport.getCalls())
+		}
+	})
+
+	t.Run("start failure reports no rollback", func(t *testing.T) {
+		transport := newMockTransport()
+		transport.register("POST", "/v1.41/containers/old123/stop", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("GET", "/v1.41/containers/old123/json", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(404, map[string]string{"message": "No such container: old123"})
+		})
+		transport.register("POST", "/v1.41/containers/new456/rename", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("POST", "/v1.41/containers/new456/start", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(500, map[string]string{"message": "port is already allocated"})
+		})
+
+		cli, _ := client.NewClientWithOpts(
+			client.WithHTTPClient(&http.Client{Transport: transport}),
+			client.WithVersion("1.41"),
+		)
+		d := &DockerClient{cli: cli}
+
+		err := d.ReplaceAutoRemoveContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{StopTimeout: time.Second})
+		if err == nil || !strings.Contains(err.Error(), "no rollback possible") {
+			t.Errorf("expected no-rollback start error, got %v", err)
+		}
+	})
+
+	t.Run("paused is health checked and paused again", func(t *testing.T) {
+		original := healthPollInterval
+		healthPollInterval = time.Millisecond
+		defer func() { healthPollInterval = original }()
+
+		transport := newMockTransport()
+		ok := func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) }
+		transport.register("POST", "/v1.41/containers/old123/stop", ok)
+		transport.register("GET", "/v1.41/containers/old123/json", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(404, map[string]string{"message": "No such container: old123"})
+		})
+		transport.register("POST", "/v1.41/containers/new456/rename", ok)
+		transport.register("POST", "/v1.41/containers/new456/start", ok)
+		transport.register("POST", "/v1.41/containers/new456/pause", ok)
+		transport.register("GET", "/v1.41/containers/new456/json", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(200, map[string]interface{}{
+				"Id":    "new456",
+				"State": map[string]interface{}{"Running": true, "Health": map[string]interface{}{"Status": "healthy"}},
+			})
+		})
+
+		cli, _ := client.NewClientWithOpts(
+			client.WithHTTPClient(&http.Client{Transport: transport}),
+			client.WithVersion("1.41"),
+		)
+		d := &DockerClient{cli: cli}
+
+		err := d.ReplaceAutoRemoveContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{
+			StopTimeout:   time.Second,
+			HealthTimeout: time.Second,
+			RunState:      RunStatePaused,
+		})
+		if err != nil {
+			t.Fatalf("ReplaceAutoRemoveContainer() error = %v", err)
+		}
+		calls := transport.getCalls()
+		if want := []string{"GET /v1.41/containers/new456/json", "POST /v1.41/containers/new456/pause"}; !reflect.DeepEqual(calls[len(calls)-2:], want) {
+			t.Errorf("calls = %v, want the health check and then a pause", calls)
+		}
+	})
+
+	t.Run("unhealthy is reported and kept", func(t *testing.T) {
+		original := healthPollInterval
+		healthPollInterval = time.Millisecond
+		defer func() { healthPollInterval = original }()
+
+		transport := newMockTransport()
+		ok := func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) }
+		transport.register("POST", "/v1.41/containers/old123/stop", ok)
+		transport.register("GET", "/v1.41/containers/old123/json", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(404, map[string]string{"message": "No such container: old123"})
+		})
+		transport.register("POST", "/v1.41/containers/new456/rename", ok)
+		transport.register("POST", "/v1.41/containers/new456/start", ok)
+		transport.register("GET", "/v1.41/containers/new456/json", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(200, map[string]interface{}{
+				"Id":    "new456",
+				"State": map[string]interface{}{"Running": true, "Health": map[string]interface{}{"Status": "unhealthy"}},
+			})
+		})
+
+		cli, _ := client.NewClientWithOpts(
+			client.WithHTTPClient(&http.Client{Transport: transport}),
+			client.WithVersion("1.41"),
+		)
+		d := &DockerClient{cli: cli}
+
+		err := d.ReplaceAutoRemoveContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{
+			StopTimeout:   time.Second,
+			HealthTimeout: time.Second,
+		})
+		if !errors.Is(err, ErrUnhealthy) {
+			t.Fatalf("ReplaceAutoRemoveContainer() error = %v, want ErrUnhealthy", err)
+		}
+		for _, call := range transport.getCalls() {
+			if call == "DELETE /v1.41/containers/new456" {
+				t.Error("the new container must be kept, the old one is already gone")
+			}
+		}
+	})
+}
+
+func TestDockerClient_RecreateContainer(t *testing.T) {
+	old := ContainerInfo{
+		ID:      "old123",
+		Name:    "my-app",
+		ImageID: "sha256:old",
+		Config:  &container.Config{Image: "my-app:latest"},
+		State:   &types.ContainerState{Running: true},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		transport := newMockTransport()
+		var createdNames []string
+		transport.register("POST", "/v1.41/containers/old123/stop", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("DELETE", "/v1.41/containers/old123", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("POST", "/v1.41/containers/create", func(req *http.Request) (*http.Response, error) {
+			createdNames = append(createdNames, req.URL.Query().Get("name"))
+			return jsonResponse(201, map[string]string{"Id": "new456"})
+		})
+		transport.register("POST", "/v1.41/containers/new456/start", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+
+		cli, _ := client.NewClientWithOpts(client.WithHTTPClient(&http.Client{Transport: transport}), client.WithVersion("1.41"))
+		d := &DockerClient{cli: cli}
+
+		var steps []ReplaceStep
+		var created string
+		newID, err := d.RecreateContainer(context.Background(), old, "my-app:latest", ReplaceOptions{
+			StopTimeout: time.Second,
+			Progress:    func(step ReplaceStep) { steps = append(steps, step) },
+			Created:     func(id string) { created = id },
+		})
+		if err != nil {
+			t.Fatalf("RecreateContainer() error = %v", err)
+		}
+		if newID != "new456" || created != "new456" {
+			t.Errorf("new ID = %q, created = %q, want new456", newID, created)
+		}
+		if want := []string{"my-app"}; !reflect.DeepEqual(createdNames, want) {
+			t.Errorf("created names = %v, want the original name only", createdNames)
+		}
+		if want := []ReplaceStep{StepStopped, StepRemoved, StepStarted}; !reflect.DeepEqual(steps, want) {
+			t.Errorf("progress steps = %v, want %v", steps, want)
+		}
+		for _, call := range transport.getCalls() {
+			if strings.HasSuffix(call, "/rename") {
+				t.Errorf("recreate must not rename containers, got %s", call)
+			}
+		}
+	})
+
+	t.Run("start failure restores the old container", func(t *testing.T) {
+		transport := newMockTransport()
+		var createdImages []string
+		transport.register("POST", "/v1.41/containers/old123/stop", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("DELETE", "/v1.41/containers/old123", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("POST", "/v1.41/containers/create", func(req *http.Request) (*http.Response, error) {
+			var body struct{ Image string }
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			createdImages = append(createdImages, body.Image)
+			if len(createdImages) == 1 {
+				return jsonResponse(201, map[string]string{"Id": "new456"})
+			}
+			return jsonResponse(201, map[string]string{"Id": "restored789"})
+		})
+		transport.register("POST", "/v1.41/containers/new456/start", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(500, map[string]string{"message": "start failed"})
+		})
+		transport.register("POST", "/v1.41/containers/new456/stop", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("DELETE", "/v1.41/containers/new456", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+		transport.register("POST", "/v1.41/containers/restored789/start", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+
+		cli, _ := client.NewClientWithOpts(client.WithHTTPClient(&http.Client{Transport: transport}), client.WithVersion("1.41"))
+		d := &DockerClient{cli: cli}
+
+		_, err := d.RecreateContainer(context.Background(), old, "my-app:latest", ReplaceOptions{StopTimeout: time.Second})
+		if err == nil || !strings.Contains(err.Error(), "failed to start new container") || !strings.Contains(err.Error(), "restored the old container") {
+			t.Fatalf("RecreateContainer() error = %v, want a start failure with the old container restored", err)
+		}
+		if want := []string{"my-app:latest", "sha256:old"}; !reflect.DeepEqual(createdImages, want) {
+			t.Errorf("created images = %v, want %v", createdImages, want)
+		}
+		calls := transport.getCalls()
+		if last := calls[len(calls)-1]; last != "POST /v1.41/containers/restored789/start" {
+			t.Errorf("last call = %s, want the restored container started. Calls: %v", last, calls)
+		}
+	})
+}
+
+func TestDockerClient_ReplaceContainer_Audited(t *testing.T) {
+	transport := newMockTransport()
+	transport.register("POST", "/v1.41/containers/old123/stop", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+	transport.register("POST", "/v1.41/containers/old123/rename", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+	transport.register("POST", "/v1.41/containers/new456/rename", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+	transport.register("POST", "/v1.41/containers/new456/start", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+	transport.register("DELETE", "/v1.41/containers/old123", func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) })
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := audit.Open(path, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	ctx := audit.WithCycleID(audit.WithInitiator(context.Background(), audit.InitiatorAPI), "a1b2c3d4")
+	if err := d.ReplaceContainer(ctx, "old123", "new456", "my-app", ReplaceOptions{StopTimeout: time.Second}); err != nil {
+		t.Fatalf("ReplaceContainer() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e audit.Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("audit log line %q: %v", line, err)
+		}
+		if e.Initiator != audit.InitiatorAPI || e.CycleID != "a1b2c3d4" {
+			t.Errorf("entry %+v isn't attributed to the API's cycle", e)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s->%s", e.Action, e.ID, e.Before, e.After))
+	}
+	want := []string{"stop old123 ->", "rename old123 my-app->my-app-old-", "rename new456 ->my-app", "start new456 ->", "remove old123 ->"}
+	if len(got) != len(want) {
+		t.Fatalf("audit log = %q, want %q", got, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("audit entry %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -2,12 +2,17 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 )
 
@@ -38,6 +43,9 @@ func (d *DockerClient) ListContainers(ctx context.Context) ([]ContainerInfo, err
 			ImageID:   c.ImageID,
 			Labels:    c.Labels,
 			CreatedAt: time.Unix(c.Created, 0),
+			// HostConfig here only carries the network mode, which is all we need for dependencies
+			NetworkMode: c.HostConfig.NetworkMode,
+			Mounts:      mountSources(c.Mounts),
 			// State: nil, // types.Container only has State string, not *types.ContainerState
 			// Config: nil,
 			// HostConfig: nil,
@@ -67,7 +75,7 @@ func (d *DockerClient) InspectContainer(ctx context.Context, id string) (Contain
 		EndpointsConfig: inspect.NetworkSettings.Networks,
 	}
 
-	return ContainerInfo{
+	info := ContainerInfo{
 		ID:            inspect.ID,
 		Name:          name,
 		Image:         inspect.Config.Image,
@@ -78,7 +86,33 @@ func (d *DockerClient) InspectContainer(ctx context.Context, id string) (Contain
 		HostConfig:    inspect.HostConfig,
 		NetworkConfig: networkConfig,
 		State:         inspect.State,
-	}, nil
+		Mounts:        mountSources(inspect.Mounts),
+	}
+
+	if inspect.HostConfig != nil {
+		info.NetworkMode = string(inspect.HostConfig.NetworkMode)
+	}
+
+	return info, nil
+}
+
+// mountSources returns the volume name or, for a bind mount, the host path of each mount
+func mountSources(mounts []container.MountPoint) []string {
+	if len(mounts) == 0 {
+		return nil
+	}
+	sources := make([]string, 0, len(mounts))
+	for _, m := range mounts {
+		source := m.Source
+		if m.Type == mount.TypeVolume {
+			source = m.Name
+		}
+		// tmpfs mounts have no source
+		if source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
 }
 
 // StopContainer stops a container with the specified timeout
@@ -88,7 +122,9 @@ func (d *DockerClient) StopContainer(ctx context.Context, id string, timeout int
 		Timeout: &stopTimeout,
 	}
 
-	if err := d.cli.ContainerStop(ctx, id, opts); err != nil {
+	err := d.cli.ContainerStop(ctx, id, opts)
+	audit.Record(ctx, audit.Entry{Action: "stop", Object: audit.ObjectContainer, ID: id}, err)
+	if err != nil {
 		return fmt.Errorf("failed to stop container %s: %w", id, err)
 	}
 
@@ -97,28 +133,96 @@ func (d *DockerClient) StopContainer(ctx context.Context, id string, timeout int
 
 // StartContainer starts a container
 func (d *DockerClient) StartContainer(ctx context.Context, id string) error {
-	if err := d.cli.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+	err := d.cli.ContainerStart(ctx, id, container.StartOptions{})
+	audit.Record(ctx, audit.Entry{Action: "start", Object: audit.ObjectContainer, ID: id}, err)
+	if err != nil {
 		return fmt.Errorf("failed to start container %s: %w", id, err)
 	}
 
 	return nil
 }
 
+// PauseContainer pauses all processes of a container
+func (d *DockerClient) PauseContainer(ctx context.Context, id string) error {
+	err := d.cli.ContainerPause(ctx, id)
+	audit.Record(ctx, audit.Entry{Action: "pause", Object: audit.ObjectContainer, ID: id}, err)
+	if err != nil {
+		return fmt.Errorf("failed to pause container %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// bringUp leaves a container in the given run state: started unless it should stay stopped,
+// then paused if it should be paused
+func (d *DockerClient) bringUp(ctx context.Context, id string, state RunState) error {
+	if state == RunStateStopped {
+		return nil
+	}
+	if err := d.StartContainer(ctx, id); err != nil {
+		return err
+	}
+	if state == RunStatePaused {
+		return d.PauseContainer(ctx, id)
+	}
+	return nil
+}
+
 // RemoveContainer removes a container
 func (d *DockerClient) RemoveContainer(ctx context.Context, id string) error {
 	opts := container.RemoveOptions{
 		Force: true,
 	}
 
-	if err := d.cli.ContainerRemove(ctx, id, opts); err != nil {
+	err := d.cli.ContainerRemove(ctx, id, opts)
+	audit.Record(ctx, audit.Entry{Action: "remove", Object: audit.ObjectContainer, ID: id}, err)
+	if err != nil {
 		return fmt.Errorf("failed to remove container %s: %w", id, err)
 	}
 
 	return nil
 }
 
+// IsNotFound reports whether err means the container or image doesn't exist
+func IsNotFound(err error) bool {
+	return cerrdefs.IsNotFound(err)
+}
+
+// Errors ReplaceContainer and RecreateContainer wrap to tell which step of the swap failed
+var (
+	ErrCreateFailed = errors.New("failed to create new container")
+	ErrStartFailed  = errors.New("failed to start new container")
+	ErrUnhealthy    = errors.New("health check failed")
+)
+
+// Labels HarborBuddy puts on the containers it creates, so the leftovers of an interrupted
+// update can be told apart from the user's own containers
+const (
+	// ReplacesLabel holds the ID of the container a container was created to replace, which
+	// may be kept as a stopped "<name>-old-<timestamp>" backup
+	ReplacesLabel = "com.harborbuddy.replaces"
+	// HelperLabel marks the "<name>-updater-<timestamp>" helpers of a self-update
+	HelperLabel = "com.harborbuddy.helper"
+)
+
+// withLabel returns a copy of labels with key set to value
+func withLabel(labels map[string]string, key, value string) map[string]string {
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
 // CreateContainerLike creates a new container with the same configuration as the old one but with a new image
 func (d *DockerClient) CreateContainerLike(ctx context.Context, old ContainerInfo, newImage string) (string, error) {
+	// Create the new container with a temporary name
+	return d.createLike(ctx, old, newImage, old.Name+"-new")
+}
+
+// createLike creates a container named name with the old container's configuration and newImage
+func (d *DockerClient) createLike(ctx context.Context, old ContainerInfo, newImage, name string) (string, error) {
 	// Inspect the old image to detect default configuration
 	// We want to avoid "locking in" the old image's defaults if the user didn't explicitly set them.
 	// If the current config matches the old image's config, we assume it's a default and let the new image decide.
@@ -161,69 +265,294 @@ func (d *DockerClient) CreateContainerLike(ctx context.Context, old ContainerInf
 		NetworkDisabled: old.Config.NetworkDisabled,
 		MacAddress:      old.Config.MacAddress,
 		OnBuild:         old.Config.OnBuild,
-		Labels:          old.Config.Labels,
+		Labels:          withLabel(old.Config.Labels, ReplacesLabel, old.ID),
 		StopSignal:      old.Config.StopSignal,
 		StopTimeout:     old.Config.StopTimeout,
 		Shell:           old.Config.Shell,
 	}
 
-	// Create the new container with a temporary name
-	tempName := old.Name + "-new"
-	resp, err := d.cli.ContainerCreate(ctx, config, old.HostConfig, old.NetworkConfig, nil, tempName)
+	// The host config is copied whole: devices, GPU requests, ulimits, sysctls, capabilities,
+	// tmpfs mounts and resource limits can't be told apart from defaults, so none are dropped
+	hostConfig, err := cloneHostConfig(old.HostConfig)
+	if err != nil {
+		return "", err
+	}
+
+	networkConfig, extraNetworks := splitNetworks(old)
+	resp, err := d.cli.ContainerCreate(ctx, config, hostConfig, networkConfig, nil, name)
+	audit.Record(ctx, audit.Entry{Action: "create", Object: audit.ObjectContainer, Name: name, Image: newImage, Before: old.ID, After: resp.ID}, err)
 	if err != nil {
 		return "", fmt.Errorf("failed to create container: %w", err)
 	}
 
+	// Attach the other networks before the container ever starts, with their static IPs and aliases
+	if err := d.connectNetworks(ctx, resp.ID, extraNetworks); err != nil {
+		_ = d.RemoveContainer(ctx, resp.ID)
+		return "", err
+	}
+
 	return resp.ID, nil
 }
 
-// ReplaceContainer replaces an old container with a new one using a blue-green approach
-func (d *DockerClient) ReplaceContainer(ctx context.Context, oldID, newID, name string, stopTimeout time.Duration) error {
+// cloneHostConfig deep-copies a host config through its API encoding, so the copy sent to the
+// daemon is exactly what it reported. The client normalizes capabilities and drops deprecated
+// fields in place, which must not leak into the old container's (possibly cached) inspect.
+func cloneHostConfig(hc *container.HostConfig) (*container.HostConfig, error) {
+	if hc == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(hc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy host config: %w", err)
+	}
+	clone := new(container.HostConfig)
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, fmt.Errorf("failed to copy host config: %w", err)
+	}
+	return clone, nil
+}
+
+// ReplaceContainer replaces an old container with a new one using a blue-green approach.
+// The old container is kept as a stopped backup until the new one passes the health gate
+// (if enabled), and is restored if it doesn't. The new container is left in opts.RunState,
+// so a container that was stopped on purpose stays stopped.
+func (d *DockerClient) ReplaceContainer(ctx context.Context, oldID, newID, name string, opts ReplaceOptions) error {
 	backupName := fmt.Sprintf("%s-old-%d", name, time.Now().Unix())
-	timeoutSec := int(stopTimeout.Seconds())
+	timeoutSec := int(opts.StopTimeout.Seconds())
 
 	// 1. Stop the old container
 	if err := d.StopContainer(ctx, oldID, timeoutSec); err != nil {
 		return fmt.Errorf("failed to stop old container: %w", err)
 	}
+	opts.progress(StepStopped)
 
 	// 2. Rename the old container to a backup name
-	if err := d.cli.ContainerRename(ctx, oldID, backupName); err != nil {
-		// If rename fails, try to restart the old container to prevent downtime
-		_ = d.StartContainer(ctx, oldID)
+	if err := d.rename(ctx, oldID, name, backupName); err != nil {
+		// If rename fails, try to bring the old container back to prevent downtime
+		_ = d.bringUp(ctx, oldID, opts.RunState)
 		return fmt.Errorf("failed to rename old container to backup name: %w", err)
 	}
+	opts.progress(StepBackedUp)
 
 	// 3. Rename the new container to the original name
-	if err := d.cli.ContainerRename(ctx, newID, name); err != nil {
+	if err := d.rename(ctx, newID, "", name); err != nil {
 		// Rollback: try to rename old container back
-		_ = d.cli.ContainerRename(ctx, oldID, name)
-		_ = d.StartContainer(ctx, oldID)
+		_ = d.rename(ctx, oldID, backupName, name)
+		_ = d.bringUp(ctx, oldID, opts.RunState)
 		// Cleanup the new container
 		_ = d.RemoveContainer(ctx, newID)
 		return fmt.Errorf("failed to rename new container: %w", err)
 	}
+	opts.progress(StepSwapped)
+
+	// 4. Start the new container, unless the old one was stopped
+	if opts.RunState != RunStateStopped {
+		if err := d.StartContainer(ctx, newID); err != nil {
+			// Rollback: Stop new container, rename old one back, and restart it
+			_ = d.StopContainer(ctx, newID, timeoutSec)
+			_ = d.RemoveContainer(ctx, newID)
+			_ = d.rename(ctx, oldID, backupName, name)
+			_ = d.bringUp(ctx, oldID, opts.RunState)
+			return fmt.Errorf("%w: %w", ErrStartFailed, err)
+		}
+		opts.progress(StepStarted)
+
+		// 5. Health gate: the backup is only deleted once the new container is proven
+		if opts.HealthTimeout > 0 {
+			if err := d.waitHealthy(ctx, newID, opts); err != nil {
+				err = d.withLogs(ctx, newID, opts.LogLines, err)
+				// Rollback: same as a failed start
+				_ = d.StopContainer(ctx, newID, timeoutSec)
+				_ = d.RemoveContainer(ctx, newID)
+				_ = d.rename(ctx, oldID, backupName, name)
+				_ = d.bringUp(ctx, oldID, opts.RunState)
+				return fmt.Errorf("%w, rolled back to old container: %w", ErrUnhealthy, err)
+			}
+		}
+	}
 
-	// 4. Start the new container
-	if err := d.StartContainer(ctx, newID); err != nil {
-		// Rollback: Stop new container, rename old one back, and restart it
-		_ = d.StopContainer(ctx, newID, timeoutSec)
-		_ = d.RemoveContainer(ctx, newID)
-		_ = d.cli.ContainerRename(ctx, oldID, name)
-		_ = d.StartContainer(ctx, oldID)
-		return fmt.Errorf("failed to start new container: %w", err)
+	// 6. Pause the new container like the old one was; it already replaced it either way
+	var pauseErr error
+	if opts.RunState == RunStatePaused {
+		pauseErr = d.PauseContainer(ctx, newID)
 	}
 
-	// 5. Success: Remove the old container
+	// 7. Success: Remove the old container
 	if err := d.RemoveContainer(ctx, oldID); err != nil {
 		// This is not a critical error, but should be logged
 		// At this point, the service is up on the new container
 		return fmt.Errorf("warning: failed to remove old backup container %s: %w", backupName, err)
 	}
+	if pauseErr != nil {
+		return fmt.Errorf("warning: failed to pause new container like the old one: %w", pauseErr)
+	}
+
+	return nil
+}
+
+// RecreateContainer replaces a container in place: the old container is stopped and removed,
+// then the new one is created from newImage under the same name. Unlike ReplaceContainer the
+// name never points at a backup or a "-new" container, at the cost of a little more downtime.
+// If the new container can't be created, started or pass the health gate, the old one is
+// recreated from its previous image ID. Like ReplaceContainer, the new container is left in
+// opts.RunState. It returns the new container's ID.
+func (d *DockerClient) RecreateContainer(ctx context.Context, old ContainerInfo, newImage string, opts ReplaceOptions) (string, error) {
+	timeoutSec := int(opts.StopTimeout.Seconds())
+
+	// 1. Stop the old container
+	if err := d.StopContainer(ctx, old.ID, timeoutSec); err != nil {
+		return "", fmt.Errorf("failed to stop old container: %w", err)
+	}
+	opts.progress(StepStopped)
+
+	// 2. Remove it to free the name; the daemon does that itself for --rm containers
+	if old.HostConfig != nil && old.HostConfig.AutoRemove {
+		if err := d.waitForRemoval(ctx, old.ID); err != nil {
+			if rmErr := d.RemoveContainer(ctx, old.ID); rmErr != nil && !cerrdefs.IsNotFound(rmErr) {
+				return "", fmt.Errorf("auto-remove container %s was not removed after stop: %w", old.Name, rmErr)
+			}
+		}
+	} else if err := d.RemoveContainer(ctx, old.ID); err != nil {
+		_ = d.bringUp(ctx, old.ID, opts.RunState)
+		return "", fmt.Errorf("failed to remove old container: %w", err)
+	}
+	opts.progress(StepRemoved)
+
+	// 3. Create the new container under the original name
+	newID, err := d.createLike(ctx, old, newImage, old.Name)
+	if err != nil {
+		return "", d.restore(ctx, old, "", opts, fmt.Errorf("%w: %w", ErrCreateFailed, err))
+	}
+	opts.created(newID)
+
+	if opts.RunState != RunStateStopped {
+		// 4. Start it
+		if err := d.StartContainer(ctx, newID); err != nil {
+			return "", d.restore(ctx, old, newID, opts, fmt.Errorf("%w: %w", ErrStartFailed, err))
+		}
+		opts.progress(StepStarted)
+
+		// 5. Health gate
+		if opts.HealthTimeout > 0 {
+			if err := d.waitHealthy(ctx, newID, opts); err != nil {
+				err = d.withLogs(ctx, newID, opts.LogLines, err)
+				return "", d.restore(ctx, old, newID, opts, fmt.Errorf("%w: %w", ErrUnhealthy, err))
+			}
+		}
+	}
+
+	// 6. Pause it like the old one was
+	if opts.RunState == RunStatePaused {
+		if err := d.PauseContainer(ctx, newID); err != nil {
+			return newID, fmt.Errorf("warning: failed to pause new container like the old one: %w", err)
+		}
+	}
+
+	return newID, nil
+}
+
+// restore undoes a failed RecreateContainer: the new container, if any, is removed and the old
+// one is created again from its image ID and brought back to opts.RunState. The tag may already
+// point at the new image, so the restored container runs the image ID and, like a rollback,
+// is left alone by later updates until it is recreated from its tag. It returns cause,
+// annotated with the outcome.
+func (d *DockerClient) restore(ctx context.Context, old ContainerInfo, newID string, opts ReplaceOptions, cause error) error {
+	if newID != "" {
+		_ = d.StopContainer(ctx, newID, int(opts.StopTimeout.Seconds()))
+		if err := d.RemoveContainer(ctx, newID); err != nil {
+			return fmt.Errorf("%w; the old container could not be restored: %v", cause, err)
+		}
+	}
+
+	restoredID, err := d.createLike(ctx, old, old.ImageID, old.Name)
+	if err != nil {
+		return fmt.Errorf("%w; the old container could not be restored: %v", cause, err)
+	}
+	if err := d.bringUp(ctx, restoredID, opts.RunState); err != nil {
+		return fmt.Errorf("%w; the restored old container failed to start: %v", cause, err)
+	}
+	return fmt.Errorf("%w; restored the old container from image %s", cause, old.ImageID)
+}
+
+// autoRemoveWaitTimeout bounds how long we wait for the daemon to remove a stopped --rm container
+var autoRemoveWaitTimeout = 30 * time.Second
+
+// ReplaceAutoRemoveContainer replaces a container created with --rm (HostConfig.AutoRemove).
+// The daemon deletes such containers as soon as they stop, so the blue-green backup-rename and
+// rollback are impossible. The caller must have inspected the old container before calling this,
+// since its configuration is gone once it stops. Like ReplaceContainer, the new container is
+// left in opts.RunState and held to the health gate, but one that fails it is kept: the old
+// container is already gone. Progress isn't reported, as there is no backup to recover.
+func (d *DockerClient) ReplaceAutoRemoveContainer(ctx context.Context, oldID, newID, name string, opts ReplaceOptions) error {
+	timeoutSec := int(opts.StopTimeout.Seconds())
+
+	// 1. Stop the old container; the daemon removes it for us
+	if err := d.StopContainer(ctx, oldID, timeoutSec); err != nil {
+		// Still running, so nothing is lost: discard the new container
+		_ = d.RemoveContainer(ctx, newID)
+		return fmt.Errorf("failed to stop old container: %w", err)
+	}
+
+	// 2. Wait for the auto-removal to free the name, forcing it if the daemon is slow
+	if err := d.waitForRemoval(ctx, oldID); err != nil {
+		if rmErr := d.RemoveContainer(ctx, oldID); rmErr != nil && !cerrdefs.IsNotFound(rmErr) {
+			return fmt.Errorf("auto-remove container %s was not removed after stop: %w", name, rmErr)
+		}
+	}
+
+	// 3. Take over the original name
+	if err := d.rename(ctx, newID, "", name); err != nil {
+		return fmt.Errorf("failed to rename new container (old auto-remove container is already gone, no rollback possible): %w", err)
+	}
+
+	if opts.RunState == RunStateStopped {
+		return nil
+	}
+
+	// 4. Start the new container
+	if err := d.StartContainer(ctx, newID); err != nil {
+		return fmt.Errorf("%w (old auto-remove container is already gone, no rollback possible): %w", ErrStartFailed, err)
+	}
+
+	// 5. Health gate; with nothing to roll back to, the new container stays
+	if opts.HealthTimeout > 0 {
+		if err := d.waitHealthy(ctx, newID, opts); err != nil {
+			err = d.withLogs(ctx, newID, opts.LogLines, err)
+			return fmt.Errorf("%w (old auto-remove container is already gone, no rollback possible): %w", ErrUnhealthy, err)
+		}
+	}
+
+	// 6. Pause it like the old one was
+	if opts.RunState == RunStatePaused {
+		if err := d.PauseContainer(ctx, newID); err != nil {
+			return fmt.Errorf("warning: failed to pause new container like the old one: %w", err)
+		}
+	}
 
 	return nil
 }
 
+// waitForRemoval polls until the container no longer exists
+func (d *DockerClient) waitForRemoval(ctx context.Context, id string) error {
+	waitCtx, cancel := context.WithTimeout(ctx, autoRemoveWaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.cli.ContainerInspect(waitCtx, id); cerrdefs.IsNotFound(err) {
+			return nil
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf("timeout waiting for container %s to be removed", id)
+		case <-ticker.C:
+		}
+	}
+}
+
 // GetContainersUsingImage returns the IDs of containers using the specified image
 func (d *DockerClient) GetContainersUsingImage(ctx context.Context, imageID string) ([]string, error) {
 	filterArgs := filters.NewArgs()
@@ -245,9 +574,64 @@ func (d *DockerClient) GetContainersUsingImage(ctx context.Context, imageID stri
 	return ids, nil
 }
 
+// ListExitedContainers returns stopped (exited or dead) containers, inspected so that
+// State.FinishedAt is available
+func (d *DockerClient) ListExitedContainers(ctx context.Context) ([]ContainerInfo, error) {
+	containers, err := d.listByStatus(ctx, "exited", "dead")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exited containers: %w", err)
+	}
+	return containers, nil
+}
+
+// ListStoppedContainers returns every container that isn't running: exited, dead, or
+// created but never started. They are inspected like ListExitedContainers.
+func (d *DockerClient) ListStoppedContainers(ctx context.Context) ([]ContainerInfo, error) {
+	containers, err := d.listByStatus(ctx, "exited", "dead", "created")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stopped containers: %w", err)
+	}
+	return containers, nil
+}
+
+// listByStatus lists and inspects the containers in any of the given states
+func (d *DockerClient) listByStatus(ctx context.Context, statuses ...string) ([]ContainerInfo, error) {
+	filterArgs := filters.NewArgs()
+	for _, status := range statuses {
+		filterArgs.Add("status", status)
+	}
+
+	containers, err := d.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filterArgs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ContainerInfo, 0, len(containers))
+	for _, c := range containers {
+		info, err := d.InspectContainer(ctx, c.ID)
+		if err != nil {
+			// Removed between the list and the inspect
+			continue
+		}
+		result = append(result, info)
+	}
+
+	return result, nil
+}
+
 // RenameContainer renames a container
 func (d *DockerClient) RenameContainer(ctx context.Context, id, newName string) error {
-	return d.cli.ContainerRename(ctx, id, newName)
+	return d.rename(ctx, id, "", newName)
+}
+
+// rename renames a container from oldName ("" if not known) to newName
+func (d *DockerClient) rename(ctx context.Context, id, oldName, newName string) error {
+	err := d.cli.ContainerRename(ctx, id, newName)
+	audit.Record(ctx, audit.Entry{Action: "rename", Object: audit.ObjectContainer, ID: id, Before: oldName, After: newName}, err)
+	return err
 }
 
 // CreateHelperContainer creates a temporary helper container with overridden CMD
@@ -257,8 +641,9 @@ func (d *DockerClient) CreateHelperContainer(ctx context.Context, original Conta
 		Image: image,
 		Cmd:   cmd,
 		Env:   original.Config.Env,
-		// We inherit labels to ensure we don't break things, but maybe we should add a label "harborbuddy-helper"
-		Labels: original.Config.Labels,
+		// Labels are inherited so the helper stays in the same scope, and marked so cleanup
+		// knows it is ours
+		Labels: withLabel(original.Config.Labels, HelperLabel, "true"),
 	}
 
 	// We need to keep HostConfig (mounts!) but maybe relax other things
@@ -275,6 +660,7 @@ func (d *DockerClient) CreateHelperContainer(ctx context.Context, original Conta
 	newHostConfig.RestartPolicy = container.RestartPolicy{Name: "no"} // Helpers shouldn't restart
 
 	resp, err := d.cli.ContainerCreate(ctx, config, &newHostConfig, nil, nil, name)
+	audit.Record(ctx, audit.Entry{Action: "create", Object: audit.ObjectContainer, Name: name, Image: image, Before: original.ID, After: resp.ID, Detail: "helper"}, err)
 	if err != nil {
 		return "", fmt.Errorf("failed to create helper container: %w", err)
 	}
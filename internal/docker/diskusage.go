@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// DataRootUsage reports how full the filesystem holding Docker's data root is. The path
+// is where that filesystem is visible to HarborBuddy; empty uses the daemon's data root,
+// which only works when HarborBuddy runs on the host or has it mounted at the same path.
+func (d *DockerClient) DataRootUsage(ctx context.Context, path string) (DiskUsage, error) {
+	if path == "" {
+		info, err := d.cli.Info(ctx)
+		if err != nil {
+			return DiskUsage{}, fmt.Errorf("failed to get Docker data root: %w", err)
+		}
+		path = info.DockerRootDir
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return DiskUsage{}, fmt.Errorf("docker data root %s is not visible to HarborBuddy (mount it read-only or set cleanup.data_root): %w", path, err)
+	}
+
+	usage, err := statDisk(path)
+	if err != nil {
+		return DiskUsage{}, fmt.Errorf("failed to read disk usage of %s: %w", path, err)
+	}
+	return usage, nil
+}
@@ -0,0 +1,18 @@
+package docker
+
+import "syscall"
+
+func statDisk(path string) (DiskUsage, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return DiskUsage{}, err
+	}
+
+	blockSize := uint64(fs.Bsize)
+	return DiskUsage{
+		Path:      path,
+		Total:     fs.Blocks * blockSize,
+		Used:      (fs.Blocks - fs.Bfree) * blockSize,
+		Available: fs.Bavail * blockSize,
+	}, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package docker
+
+import "errors"
+
+func statDisk(path string) (DiskUsage, error) {
+	return DiskUsage{}, errors.New("disk usage is only supported on Linux")
+}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+)
+
+func TestDockerClient_DataRootUsage(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("disk usage is only supported on Linux")
+	}
+
+	dataRoot := t.TempDir()
+	transport := newMockTransport()
+	transport.register("GET", "/v1.41/info", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, system.Info{DockerRootDir: dataRoot})
+	})
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	usage, err := d.DataRootUsage(context.Background(), "")
+	if err != nil {
+		t.Fatalf("DataRootUsage failed: %v", err)
+	}
+	if usage.Path != dataRoot || usage.Total == 0 {
+		t.Errorf("DataRootUsage() = %+v, want the filesystem of %s", usage, dataRoot)
+	}
+	if p := usage.UsedPercent(); p < 0 || p > 100 {
+		t.Errorf("UsedPercent() = %v, want 0-100", p)
+	}
+
+	// A data root that isn't mounted into the container gets a hint
+	_, err = d.DataRootUsage(context.Background(), filepath.Join(dataRoot, "missing"))
+	if err == nil || !strings.Contains(err.Error(), "cleanup.data_root") {
+		t.Errorf("DataRootUsage(missing) error = %v, want a hint about cleanup.data_root", err)
+	}
+}
+
+func TestDiskUsage_UsedPercent(t *testing.T) {
+	// Root-reserved blocks count toward neither used nor available, as in df
+	u := DiskUsage{Total: 100, Used: 45, Available: 45}
+	if got := u.UsedPercent(); got != 50 {
+		t.Errorf("UsedPercent() = %v, want 50", got)
+	}
+	if got := (DiskUsage{}).UsedPercent(); got != 0 {
+		t.Errorf("empty UsedPercent() = %v, want 0", got)
+	}
+}
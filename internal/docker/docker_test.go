@@ -304,7 +304,7 @@ func TestMockDockerClient_ReplaceContainer(t *testing.T) {
 	t.Run("records replacement", func(t *testing.T) {
 		mock := NewMockDockerClient()
 
-		err := mock.ReplaceContainer(context.Background(), "old123", "new456", "test-container", 10*time.Second)
+		err := mock.ReplaceContainer(context.Background(), "old123", "new456", "test-container", ReplaceOptions{StopTimeout: 10 * time.Second})
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -322,7 +322,7 @@ func TestMockDockerClient_ReplaceContainer(t *testing.T) {
 		mock := NewMockDockerClient()
 		mock.ReplaceContainerError = fmt.Errorf("replace failed")
 
-		err := mock.ReplaceContainer(context.Background(), "old", "new", "name", time.Second)
+		err := mock.ReplaceContainer(context.Background(), "old", "new", "name", ReplaceOptions{StopTimeout: time.Second})
 		if err == nil {
 			t.Error("Expected error")
 		}
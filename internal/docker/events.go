@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Event is a Docker daemon event relevant to HarborBuddy
+type Event struct {
+	Type       string // "container", "network" or "image"
+	Action     string
+	ActorID    string
+	Attributes map[string]string
+	Time       time.Time
+}
+
+// ContainerID returns the ID of the container the event refers to, or "" if none.
+// Network connect/disconnect events carry the container in their attributes.
+func (e Event) ContainerID() string {
+	switch e.Type {
+	case string(events.ContainerEventType):
+		return e.ActorID
+	case string(events.NetworkEventType):
+		return e.Attributes["container"]
+	}
+	return ""
+}
+
+// Events streams container, network and image events until ctx is cancelled.
+// The error channel receives a single value when the stream ends.
+func (d *DockerClient) Events(ctx context.Context) (<-chan Event, <-chan error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("type", string(events.ContainerEventType))
+	filterArgs.Add("type", string(events.NetworkEventType))
+	filterArgs.Add("type", string(events.ImageEventType))
+
+	msgs, errs := d.cli.Events(ctx, events.ListOptions{Filters: filterArgs})
+
+	out := make(chan Event)
+	outErr := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case msg := <-msgs:
+				event := Event{
+					Type:       string(msg.Type),
+					Action:     string(msg.Action),
+					ActorID:    msg.Actor.ID,
+					Attributes: msg.Actor.Attributes,
+					Time:       time.Unix(0, msg.TimeNano),
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					outErr <- ctx.Err()
+					return
+				}
+			case err := <-errs:
+				outErr <- err
+				return
+			}
+		}
+	}()
+
+	return out, outErr
+}
@@ -0,0 +1,117 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ExecResult is the outcome of a command run inside a container
+type ExecResult struct {
+	ExitCode int
+	Output   string // Combined stdout and stderr, trimmed
+}
+
+// ExecContainer runs cmd inside a running container and waits for it to finish.
+// Cancel ctx to bound how long the command may take.
+func (d *DockerClient) ExecContainer(ctx context.Context, id string, cmd []string) (ExecResult, error) {
+	created, err := d.cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to create exec in container %s: %w", id, err)
+	}
+
+	attach, err := d.cli.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to attach to exec in container %s: %w", id, err)
+	}
+	defer attach.Close()
+
+	// The hijacked connection ignores ctx, so close it on cancellation to unblock the copy
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			attach.Close()
+		case <-done:
+		}
+	}()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, attach.Reader); err != nil {
+		if ctx.Err() != nil {
+			return ExecResult{}, fmt.Errorf("exec in container %s did not finish: %w", id, ctx.Err())
+		}
+		return ExecResult{}, fmt.Errorf("failed to read exec output from container %s: %w", id, err)
+	}
+	if ctx.Err() != nil {
+		return ExecResult{}, fmt.Errorf("exec in container %s did not finish: %w", id, ctx.Err())
+	}
+
+	inspect, err := d.cli.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to inspect exec in container %s: %w", id, err)
+	}
+
+	return ExecResult{
+		ExitCode: inspect.ExitCode,
+		Output:   strings.TrimSpace(output.String()),
+	}, nil
+}
+
+// RunContainer runs image with cmd in a throwaway container, without network, mounts or
+// restart policy, waits for it to exit and returns its output. The container is removed
+// afterwards. Cancel ctx to bound how long it may run.
+func (d *DockerClient) RunContainer(ctx context.Context, image string, cmd []string) (ExecResult, error) {
+	created, err := d.cli.ContainerCreate(ctx, &container.Config{Image: image, Cmd: cmd}, &container.HostConfig{NetworkMode: "none"}, nil, nil, "")
+	audit.Record(ctx, audit.Entry{Action: "create", Object: audit.ObjectContainer, Image: image, After: created.ID, Detail: "throwaway"}, err)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to create container from %s: %w", image, err)
+	}
+	defer func() {
+		_ = d.RemoveContainer(context.WithoutCancel(ctx), created.ID)
+	}()
+
+	waitCh, waitErrCh := d.cli.ContainerWait(ctx, created.ID, container.WaitConditionNextExit)
+	err = d.cli.ContainerStart(ctx, created.ID, container.StartOptions{})
+	audit.Record(ctx, audit.Entry{Action: "start", Object: audit.ObjectContainer, ID: created.ID}, err)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to start container from %s: %w", image, err)
+	}
+
+	var exitCode int
+	select {
+	case status := <-waitCh:
+		exitCode = int(status.StatusCode)
+	case err := <-waitErrCh:
+		if ctx.Err() != nil {
+			return ExecResult{}, fmt.Errorf("container from %s did not exit: %w", image, ctx.Err())
+		}
+		return ExecResult{}, fmt.Errorf("failed to wait for container from %s: %w", image, err)
+	}
+
+	logs, err := d.cli.ContainerLogs(ctx, created.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to read output of container from %s: %w", image, err)
+	}
+	defer logs.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, logs); err != nil {
+		return ExecResult{}, fmt.Errorf("failed to read output of container from %s: %w", image, err)
+	}
+
+	return ExecResult{
+		ExitCode: exitCode,
+		Output:   strings.TrimSpace(output.String()),
+	}, nil
+}
@@ -0,0 +1,236 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ReplaceOptions controls how ReplaceContainer swaps a container for its replacement
+type ReplaceOptions struct {
+	StopTimeout time.Duration // Grace period for stopping the old container
+
+	// HealthTimeout is how long the new container has to prove itself before the old one is
+	// deleted; on failure the old container is restored. Zero disables the health gate.
+	HealthTimeout time.Duration
+
+	// GracePeriod applies to images without a HEALTHCHECK: the new container passes if it is
+	// still running after this long
+	GracePeriod time.Duration
+
+	// LogLines is how many of the new container's last log lines a failed health gate keeps
+	// (see HealthLogs) before the container is removed. Zero keeps none.
+	LogLines int
+
+	// Progress, if set, is called after each step that changes the containers, so an
+	// interrupted replacement can be finished or rolled back later
+	Progress func(ReplaceStep)
+
+	// Created, if set, is called with the new container's ID by replacements that create it
+	// themselves (RecreateContainer)
+	Created func(id string)
+
+	// RunState is the state the old container was in, and the one the new container (or the
+	// restored old one) is left in: a stopped container isn't started or health checked, a
+	// paused one is paused again once it passed. Empty means running.
+	RunState RunState
+}
+
+// RunState is whether a container is running, paused or stopped
+type RunState string
+
+const (
+	RunStateRunning RunState = "running"
+	RunStatePaused  RunState = "paused"
+	RunStateStopped RunState = "stopped"
+)
+
+// RunStateOf returns the run state of an inspected container. Containers without a state,
+// as returned by ListContainers, are running.
+func RunStateOf(c ContainerInfo) RunState {
+	switch {
+	case c.State == nil:
+		return RunStateRunning
+	case c.State.Paused:
+		return RunStatePaused
+	case c.State.Running, c.State.Restarting:
+		return RunStateRunning
+	default:
+		return RunStateStopped
+	}
+}
+
+// ReplaceStep is the last completed step of a container replacement
+type ReplaceStep string
+
+const (
+	StepCreated  ReplaceStep = "created"   // New container created under a temporary name
+	StepStopped  ReplaceStep = "stopped"   // Old container stopped
+	StepBackedUp ReplaceStep = "backed_up" // Old container renamed to its backup name
+	StepSwapped  ReplaceStep = "swapped"   // New container renamed to the original name
+	StepStarted  ReplaceStep = "started"   // New container started, health gate pending
+	StepRemoved  ReplaceStep = "removed"   // Old container removed (recreate strategy)
+)
+
+// progress reports a completed step to the caller, if it asked
+func (o ReplaceOptions) progress(step ReplaceStep) {
+	if o.Progress != nil {
+		o.Progress(step)
+	}
+}
+
+// created reports the new container's ID to the caller, if it asked
+func (o ReplaceOptions) created(id string) {
+	if o.Created != nil {
+		o.Created(id)
+	}
+}
+
+// HealthError is a failed health gate, with the new container's last log lines
+type HealthError struct {
+	Err  error
+	Logs string
+}
+
+func (e *HealthError) Error() string { return e.Err.Error() }
+func (e *HealthError) Unwrap() error { return e.Err }
+
+// HealthLogs returns the log lines a failed health gate kept of the new container, or ""
+func HealthLogs(err error) string {
+	var healthErr *HealthError
+	if errors.As(err, &healthErr) {
+		return healthErr.Logs
+	}
+	return ""
+}
+
+// logTimeout bounds reading the log of a container that failed its health gate
+const logTimeout = 5 * time.Second
+
+// withLogs attaches the last lines of the container's log to a failed health gate, so the
+// reason the container failed outlives it. Reading the log is best effort.
+func (d *DockerClient) withLogs(ctx context.Context, id string, lines int, err error) error {
+	if lines <= 0 {
+		return err
+	}
+	logs, logErr := d.logTail(context.WithoutCancel(ctx), id, lines)
+	if logErr != nil || logs == "" {
+		return err
+	}
+	return &HealthError{Err: err, Logs: logs}
+}
+
+// logTail returns the last lines of a container's stdout and stderr
+func (d *DockerClient) logTail(ctx context.Context, id string, lines int) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, logTimeout)
+	defer cancel()
+
+	inspect, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	reader, err := d.cli.ContainerLogs(ctx, id, container.LogsOptions{ShowStdout: true, ShowStderr: true, Tail: strconv.Itoa(lines)})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	// Without a TTY, stdout and stderr come multiplexed
+	var out bytes.Buffer
+	if inspect.Config != nil && inspect.Config.Tty {
+		_, err = io.Copy(&out, reader)
+	} else {
+		_, err = stdcopy.StdCopy(&out, &out, reader)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out.String(), "\r\n"), nil
+}
+
+// healthPollInterval is how often the new container's state is checked; a variable for tests
+var healthPollInterval = time.Second
+
+// waitHealthy waits until the container passes its Docker healthcheck, or, without one, is
+// still running after the grace period. It fails early if the container exits or is reported
+// unhealthy.
+func (d *DockerClient) waitHealthy(ctx context.Context, id string, opts ReplaceOptions) error {
+	return pollHealthy(ctx, func(ctx context.Context) (*types.ContainerState, error) {
+		inspect, err := d.cli.ContainerInspect(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return inspect.State, nil
+	}, opts.HealthTimeout, opts.GracePeriod)
+}
+
+// WaitHealthy is the health gate of ReplaceContainer for containers started some other way,
+// e.g. by the self-update helper
+func WaitHealthy(ctx context.Context, client Client, id string, timeout, grace time.Duration) error {
+	return pollHealthy(ctx, func(ctx context.Context) (*types.ContainerState, error) {
+		info, err := client.InspectContainer(ctx, id)
+		return info.State, err
+	}, timeout, grace)
+}
+
+// pollHealthy polls a container's state through inspect until healthState settles it or
+// timeout passes
+func pollHealthy(ctx context.Context, inspect func(ctx context.Context) (*types.ContainerState, error), timeout, grace time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	graceUntil := time.Now().Add(min(grace, timeout))
+
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+
+	for {
+		state, err := inspect(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to inspect new container: %w", err)
+		}
+
+		if healthy, err := healthState(state, time.Now().After(graceUntil)); err != nil || healthy {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("new container not healthy after %v", timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// healthState evaluates one observation of a container's state: healthy, still pending, or failed
+func healthState(state *types.ContainerState, gracePassed bool) (bool, error) {
+	if state == nil {
+		return false, nil
+	}
+	if !state.Running || state.Restarting {
+		return false, fmt.Errorf("new container stopped running (exit code %d)", state.ExitCode)
+	}
+
+	if state.Health != nil && state.Health.Status != types.NoHealthcheck {
+		switch state.Health.Status {
+		case types.Healthy:
+			return true, nil
+		case types.Unhealthy:
+			return false, fmt.Errorf("new container reported unhealthy")
+		}
+		return false, nil // Still starting
+	}
+
+	return gracePassed, nil
+}
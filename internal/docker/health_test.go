@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+func TestHealthState(t *testing.T) {
+	tests := []struct {
+		name        string
+		state       *types.ContainerState
+		gracePassed bool
+		healthy     bool
+		wantErr     bool
+	}{
+		{"healthy", &types.ContainerState{Running: true, Health: &types.Health{Status: types.Healthy}}, false, true, false},
+		{"unhealthy", &types.ContainerState{Running: true, Health: &types.Health{Status: types.Unhealthy}}, false, false, true},
+		{"starting", &types.ContainerState{Running: true, Health: &types.Health{Status: types.Starting}}, true, false, false},
+		{"exited", &types.ContainerState{Running: false, ExitCode: 1}, true, false, true},
+		{"restarting", &types.ContainerState{Running: true, Restarting: true}, false, false, true},
+		{"no healthcheck within grace", &types.ContainerState{Running: true}, false, false, false},
+		{"no healthcheck after grace", &types.ContainerState{Running: true}, true, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthy, err := healthState(tt.state, tt.gracePassed)
+			if healthy != tt.healthy || (err != nil) != tt.wantErr {
+				t.Errorf("healthState() = %v, %v; want %v, error=%v", healthy, err, tt.healthy, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDockerClient_ReplaceContainer_HealthRollback(t *testing.T) {
+	original := healthPollInterval
+	healthPollInterval = time.Millisecond
+	defer func() { healthPollInterval = original }()
+
+	transport := newMockTransport()
+	ok := func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) }
+	transport.register("POST", "/v1.41/containers/old123/stop", ok)
+	transport.register("POST", "/v1.41/containers/old123/rename", ok)
+	transport.register("POST", "/v1.41/containers/new456/rename", ok)
+	transport.register("POST", "/v1.41/containers/new456/start", ok)
+	transport.register("POST", "/v1.41/containers/new456/stop", ok)
+	transport.register("DELETE", "/v1.41/containers/new456", ok)
+	transport.register("POST", "/v1.41/containers/old123/start", ok)
+	transport.register("GET", "/v1.41/containers/new456/json", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, map[string]interface{}{
+			"Id":    "new456",
+			"State": map[string]interface{}{"Running": true, "Health": map[string]interface{}{"Status": "unhealthy"}},
+		})
+	})
+
+	var tail string
+	transport.register("GET", "/v1.41/containers/new456/logs", func(req *http.Request) (*http.Response, error) {
+		tail = req.URL.Query().Get("tail")
+		// One stderr frame in the multiplexed log format
+		payload := "panic: missing DATABASE_URL\n"
+		frame := append([]byte{2, 0, 0, 0, 0, 0, 0, byte(len(payload))}, payload...)
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(frame)), Header: make(http.Header)}, nil
+	})
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	err := d.ReplaceContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{
+		StopTimeout:   time.Second,
+		HealthTimeout: time.Second,
+		LogLines:      20,
+	})
+	if err == nil || !strings.Contains(err.Error(), "health check failed") {
+		t.Fatalf("expected health check failure, got %v", err)
+	}
+	if logs := HealthLogs(err); logs != "panic: missing DATABASE_URL" || tail != "20" {
+		t.Errorf("HealthLogs() = %q (tail %q), want the new container's last 20 log lines", logs, tail)
+	}
+
+	calls := strings.Join(transport.getCalls(), "\n")
+	if strings.Contains(calls, "DELETE /v1.41/containers/old123") {
+		t.Error("old container must be kept when the new one is unhealthy")
+	}
+	for _, expected := range []string{"DELETE /v1.41/containers/new456", "POST /v1.41/containers/old123/start"} {
+		if !strings.Contains(calls, expected) {
+			t.Errorf("expected rollback call %s, calls:\n%s", expected, calls)
+		}
+	}
+}
+
+func TestDockerClient_ReplaceContainer_HealthyAfterStarting(t *testing.T) {
+	original := healthPollInterval
+	healthPollInterval = time.Millisecond
+	defer func() { healthPollInterval = original }()
+
+	transport := newMockTransport()
+	ok := func(req *http.Request) (*http.Response, error) { return jsonResponse(204, nil) }
+	transport.register("POST", "/v1.41/containers/old123/stop", ok)
+	transport.register("POST", "/v1.41/containers/old123/rename", ok)
+	transport.register("POST", "/v1.41/containers/new456/rename", ok)
+	transport.register("POST", "/v1.41/containers/new456/start", ok)
+	transport.register("DELETE", "/v1.41/containers/old123", ok)
+
+	inspects := 0
+	transport.register("GET", "/v1.41/containers/new456/json", func(req *http.Request) (*http.Response, error) {
+		inspects++
+		status := "starting"
+		if inspects > 2 {
+			status = "healthy"
+		}
+		return jsonResponse(200, map[string]interface{}{
+			"Id":    "new456",
+			"State": map[string]interface{}{"Running": true, "Health": map[string]interface{}{"Status": status}},
+		})
+	})
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	err := d.ReplaceContainer(context.Background(), "old123", "new456", "my-app", ReplaceOptions{
+		StopTimeout:   time.Second,
+		HealthTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("ReplaceContainer() error = %v", err)
+	}
+	if inspects < 3 {
+		t.Errorf("expected polling until healthy, got %d inspects", inspects)
+	}
+	if !strings.Contains(strings.Join(transport.getCalls(), "\n"), "DELETE /v1.41/containers/old123") {
+		t.Error("old container should be removed once the new one is healthy")
+	}
+}
@@ -3,27 +3,61 @@ package docker
 import (
 	"context"
 	"fmt"
-	"io"
 	"time"
 
+	"github.com/MikeO7/HarborBuddy/internal/audit"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
+	"github.com/rs/zerolog"
 )
 
-// PullImage pulls the latest version of an image
+// platformKey is the context key of the platform set by WithPlatform
+type platformKey struct{}
+
+// WithPlatform returns a context under which PullImage asks for the image built for p, rather
+// than letting the daemon pick its own platform from a multi-arch tag
+func WithPlatform(ctx context.Context, p Platform) context.Context {
+	return context.WithValue(ctx, platformKey{}, p)
+}
+
+// PlatformFrom returns the platform set by WithPlatform, if any
+func PlatformFrom(ctx context.Context) (Platform, bool) {
+	p, ok := ctx.Value(platformKey{}).(Platform)
+	return p, ok && p.Known()
+}
+
+// PullImage pulls the latest version of an image. Layer progress is logged at debug level to
+// the logger attached to ctx, if any (zerolog.Logger.WithContext). The platform set with
+// WithPlatform, if any, is requested.
 func (d *DockerClient) PullImage(ctx context.Context, imageName string) (ImageInfo, error) {
-	reader, err := d.cli.ImagePull(ctx, imageName, image.PullOptions{})
+	start := time.Now()
+	var opts image.PullOptions
+	if p, ok := PlatformFrom(ctx); ok {
+		opts.Platform = p.String()
+	}
+	if d.keychain != nil {
+		auth, err := d.keychain.RegistryAuth(imageName)
+		if err != nil {
+			return ImageInfo{}, fmt.Errorf("failed to resolve registry credentials for %s: %w", imageName, err)
+		}
+		opts.RegistryAuth = auth
+	}
+
+	reader, err := d.cli.ImagePull(ctx, imageName, opts)
 	if err != nil {
+		audit.Record(ctx, audit.Entry{Action: "pull", Object: audit.ObjectImage, Name: imageName}, err)
 		return ImageInfo{}, fmt.Errorf("failed to pull image %s: %w", imageName, err)
 	}
 	defer reader.Close()
 
-	// Consume the pull output
-	_, err = io.Copy(io.Discard, reader)
+	// Consume the pull output; the pull only finishes once it has been read to the end
+	downloaded, err := readPullStream(reader, imageName, zerolog.Ctx(ctx))
+	audit.Record(ctx, audit.Entry{Action: "pull", Object: audit.ObjectImage, Name: imageName}, err)
 	if err != nil {
-		return ImageInfo{}, fmt.Errorf("failed to read pull output for %s: %w", imageName, err)
+		return ImageInfo{}, fmt.Errorf("failed to pull image %s: %w", imageName, err)
 	}
+	duration := time.Since(start)
 
 	// Inspect the pulled image to get its ID
 	inspect, _, err := d.cli.ImageInspectWithRaw(ctx, imageName)
@@ -48,13 +82,17 @@ func (d *DockerClient) PullImage(ctx context.Context, imageName string) (ImageIn
 	}
 
 	return ImageInfo{
-		ID:        inspect.ID,
-		RepoTags:  inspect.RepoTags,
-		Dangling:  len(inspect.RepoTags) == 0,
-		CreatedAt: createdAt,
-		Size:      inspect.Size,
-		Labels:    inspect.Config.Labels,
-		Config:    imageConfig,
+		ID:           inspect.ID,
+		RepoTags:     inspect.RepoTags,
+		RepoDigests:  inspect.RepoDigests,
+		Dangling:     len(inspect.RepoTags) == 0,
+		CreatedAt:    createdAt,
+		Size:         inspect.Size,
+		Labels:       inspect.Config.Labels,
+		Config:       imageConfig,
+		Platform:     Platform{OS: inspect.Os, Architecture: inspect.Architecture, Variant: inspect.Variant},
+		PulledBytes:  downloaded,
+		PullDuration: duration,
 	}, nil
 }
 
@@ -83,13 +121,15 @@ func (d *DockerClient) InspectImage(ctx context.Context, imageName string) (Imag
 	}
 
 	return ImageInfo{
-		ID:        inspect.ID,
-		RepoTags:  inspect.RepoTags,
-		Dangling:  len(inspect.RepoTags) == 0,
-		CreatedAt: createdAt,
-		Size:      inspect.Size,
-		Labels:    inspect.Config.Labels,
-		Config:    imageConfig,
+		ID:          inspect.ID,
+		RepoTags:    inspect.RepoTags,
+		RepoDigests: inspect.RepoDigests,
+		Dangling:    len(inspect.RepoTags) == 0,
+		CreatedAt:   createdAt,
+		Size:        inspect.Size,
+		Labels:      inspect.Config.Labels,
+		Config:      imageConfig,
+		Platform:    Platform{OS: inspect.Os, Architecture: inspect.Architecture, Variant: inspect.Variant},
 	}, nil
 }
 
@@ -151,6 +191,7 @@ func (d *DockerClient) RemoveImage(ctx context.Context, imageID string) error {
 		Force:         false, // Don't force remove images in use
 		PruneChildren: true,
 	})
+	audit.Record(ctx, audit.Entry{Action: "remove", Object: audit.ObjectImage, ID: imageID}, err)
 	if err != nil {
 		return fmt.Errorf("failed to remove image %s: %w", imageID, err)
 	}
@@ -158,6 +199,18 @@ func (d *DockerClient) RemoveImage(ctx context.Context, imageID string) error {
 	return nil
 }
 
+// TagImage points the target reference (e.g., "nginx:harborbuddy-current") at the source image,
+// moving it off whatever image it pointed to before
+func (d *DockerClient) TagImage(ctx context.Context, source, target string) error {
+	err := d.cli.ImageTag(ctx, source, target)
+	audit.Record(ctx, audit.Entry{Action: "tag", Object: audit.ObjectImage, ID: source, After: target}, err)
+	if err != nil {
+		return fmt.Errorf("failed to tag image %s as %s: %w", source, target, err)
+	}
+
+	return nil
+}
+
 // GetImageID gets the ID of an image by name
 func (d *DockerClient) GetImageID(ctx context.Context, imageName string) (string, error) {
 	inspect, _, err := d.cli.ImageInspectWithRaw(ctx, imageName)
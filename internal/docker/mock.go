@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
 )
 
 // MockDockerClient is a mock implementation of the Client interface for testing
@@ -17,16 +19,35 @@ type MockDockerClient struct {
 	// Images to return from ListImages
 	Images []ImageInfo
 
+	// Volumes and networks to return from ListDanglingVolumes and ListUnusedNetworks
+	Volumes  []VolumeInfo
+	Networks []NetworkInfo
+
+	// Disk usage to return from DataRootUsage
+	DiskUsage DiskUsage
+
+	// Platform to return from DaemonPlatform
+	Platform Platform
+
 	// Record of operations for verification
-	PulledImages       []string
-	RemovedImages      []string
-	StoppedContainers  []string
-	StartedContainers  []string
-	RemovedContainers  []string
-	CreatedContainers  []CreateRequest
-	ReplacedContainers []ReplaceRequest
-	RenamedContainers  []RenameRequest
-	CreatedHelpers     []CreateHelperRequest
+	PulledImages        []string
+	PulledPlatforms     map[string]Platform // Platform requested by each pull that set one
+	RemovedImages       []string
+	TaggedImages        []TagRequest
+	StoppedContainers   []string
+	StartedContainers   []string
+	RemovedContainers   []string
+	CreatedContainers   []CreateRequest
+	ReplacedContainers  []ReplaceRequest
+	RecreatedContainers []RecreateRequest
+	AutoRemoveReplaced  []ReplaceRequest
+	RenamedContainers   []RenameRequest
+	CreatedHelpers      []CreateHelperRequest
+	ExecutedCommands    []ExecRequest
+	RanContainers       []RunRequest
+	RemovedVolumes      []string
+	RemovedNetworks     []string
+	BuildCachePrunes    []int64 // keepBytes of each PruneBuildCache call
 
 	// Control behavior
 	ListContainersError          error
@@ -34,18 +55,46 @@ type MockDockerClient struct {
 	PullImageError               error
 	ListImagesError              error
 	RemoveImageError             error
+	TagImageError                error
 	StopContainerError           error
 	CreateContainerError         error
 	StartContainerError          error
 	RemoveContainerError         error
 	ReplaceContainerError        error
+	ReplaceAutoRemoveError       error
+	RecreateContainerError       error
 	GetContainersUsingImageError error
 	ListDanglingImagesError      error
+	ListExitedContainersError    error
+	ListVolumesError             error
+	RemoveVolumeError            error
+	ListNetworksError            error
+	RemoveNetworkError           error
+	PruneBuildCacheError         error
+	DataRootUsageError           error
 	RenameContainerError         error
 	CreateHelperContainerError   error
+	ExecContainerError           error
+	RunContainerError            error
+	DaemonPlatformError          error
+	PingError                    error
+
+	// ExecResults maps a container ID to the result of commands run in it (default: exit 0)
+	ExecResults map[string]ExecResult
+
+	// RunResults maps an image to the result of running it in a throwaway container (default: exit 0)
+	RunResults map[string]ExecResult
 
 	// Image pull simulation
 	PullImageReturns map[string]ImageInfo
+
+	// Events simulation: events sent on EventsChan are delivered to subscribers.
+	// EventsError, if set, is returned as soon as the stream is opened.
+	EventsChan  chan Event
+	EventsError error
+
+	// InspectedContainers records every InspectContainer call
+	InspectedContainers []string
 }
 
 // CreateRequest records container creation attempts
@@ -60,6 +109,20 @@ type ReplaceRequest struct {
 	NewID       string
 	Name        string
 	StopTimeout time.Duration
+	Options     ReplaceOptions // Only set by ReplaceContainer
+}
+
+// RecreateRequest records in-place container recreation attempts
+type RecreateRequest struct {
+	Old      ContainerInfo
+	NewImage string
+	Options  ReplaceOptions
+}
+
+// TagRequest records image tagging attempts
+type TagRequest struct {
+	Source string
+	Target string
 }
 
 // RenameRequest records container rename attempts
@@ -76,6 +139,18 @@ type CreateHelperRequest struct {
 	Cmd      []string
 }
 
+// ExecRequest records commands run inside containers
+type ExecRequest struct {
+	ID  string
+	Cmd []string
+}
+
+// RunRequest records an image run in a throwaway container
+type RunRequest struct {
+	Image string
+	Cmd   []string
+}
+
 // NewMockDockerClient creates a new mock Docker client
 func NewMockDockerClient() *MockDockerClient {
 	return &MockDockerClient{
@@ -101,17 +176,20 @@ func (m *MockDockerClient) InspectContainer(ctx context.Context, id string) (Con
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.InspectedContainers = append(m.InspectedContainers, id)
+
 	if m.InspectContainerError != nil {
 		return ContainerInfo{}, m.InspectContainerError
 	}
 
+	// Like Docker, accept either the ID or the name
 	for _, c := range m.Containers {
-		if c.ID == id {
+		if c.ID == id || c.Name == id {
 			return c, nil
 		}
 	}
 
-	return ContainerInfo{}, fmt.Errorf("container not found: %s", id)
+	return ContainerInfo{}, fmt.Errorf("container %w: %s", cerrdefs.ErrNotFound, id)
 }
 
 // PullImage simulates pulling an image
@@ -120,6 +198,12 @@ func (m *MockDockerClient) PullImage(ctx context.Context, image string) (ImageIn
 	defer m.mu.Unlock()
 
 	m.PulledImages = append(m.PulledImages, image)
+	if p, ok := PlatformFrom(ctx); ok {
+		if m.PulledPlatforms == nil {
+			m.PulledPlatforms = map[string]Platform{}
+		}
+		m.PulledPlatforms[image] = p
+	}
 
 	if m.PullImageError != nil {
 		return ImageInfo{}, m.PullImageError
@@ -188,6 +272,19 @@ func (m *MockDockerClient) RemoveImage(ctx context.Context, id string) error {
 	return nil
 }
 
+// TagImage records the tag
+func (m *MockDockerClient) TagImage(ctx context.Context, source, target string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.TaggedImages = append(m.TaggedImages, TagRequest{Source: source, Target: target})
+
+	if m.TagImageError != nil {
+		return m.TagImageError
+	}
+	return nil
+}
+
 // StopContainer records the stop
 func (m *MockDockerClient) StopContainer(ctx context.Context, id string, timeout int) error {
 	m.mu.Lock()
@@ -245,7 +342,7 @@ func (m *MockDockerClient) RemoveContainer(ctx context.Context, id string) error
 }
 
 // ReplaceContainer records the replacement
-func (m *MockDockerClient) ReplaceContainer(ctx context.Context, oldID, newID, name string, stopTimeout time.Duration) error {
+func (m *MockDockerClient) ReplaceContainer(ctx context.Context, oldID, newID, name string, opts ReplaceOptions) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -253,7 +350,8 @@ func (m *MockDockerClient) ReplaceContainer(ctx context.Context, oldID, newID, n
 		OldID:       oldID,
 		NewID:       newID,
 		Name:        name,
-		StopTimeout: stopTimeout,
+		StopTimeout: opts.StopTimeout,
+		Options:     opts,
 	})
 
 	if m.ReplaceContainerError != nil {
@@ -262,6 +360,47 @@ func (m *MockDockerClient) ReplaceContainer(ctx context.Context, oldID, newID, n
 	return nil
 }
 
+// RecreateContainer records the recreation and reports its progress like the real client
+func (m *MockDockerClient) RecreateContainer(ctx context.Context, old ContainerInfo, newImage string, opts ReplaceOptions) (string, error) {
+	m.mu.Lock()
+	m.RecreatedContainers = append(m.RecreatedContainers, RecreateRequest{
+		Old:      old,
+		NewImage: newImage,
+		Options:  opts,
+	})
+	err := m.RecreateContainerError
+	m.mu.Unlock()
+
+	if err != nil {
+		return "", err
+	}
+	newID := "recreated-container-id-" + old.Name
+	opts.progress(StepStopped)
+	opts.progress(StepRemoved)
+	opts.created(newID)
+	opts.progress(StepStarted)
+	return newID, nil
+}
+
+// ReplaceAutoRemoveContainer records the auto-remove replacement
+func (m *MockDockerClient) ReplaceAutoRemoveContainer(ctx context.Context, oldID, newID, name string, opts ReplaceOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.AutoRemoveReplaced = append(m.AutoRemoveReplaced, ReplaceRequest{
+		OldID:       oldID,
+		NewID:       newID,
+		Name:        name,
+		StopTimeout: opts.StopTimeout,
+		Options:     opts,
+	})
+
+	if m.ReplaceAutoRemoveError != nil {
+		return m.ReplaceAutoRemoveError
+	}
+	return nil
+}
+
 // GetContainersUsingImage returns list of containers using image
 func (m *MockDockerClient) GetContainersUsingImage(ctx context.Context, imageID string) ([]string, error) {
 	m.mu.Lock()
@@ -298,6 +437,124 @@ func (m *MockDockerClient) ListDanglingImages(ctx context.Context) ([]ImageInfo,
 	return dangling, nil
 }
 
+// ListExitedContainers returns the configured containers whose state is exited or dead
+func (m *MockDockerClient) ListExitedContainers(ctx context.Context) ([]ContainerInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ListExitedContainersError != nil {
+		return nil, m.ListExitedContainersError
+	}
+
+	var exited []ContainerInfo
+	for _, c := range m.Containers {
+		if c.State != nil && (c.State.Status == "exited" || c.State.Status == "dead") {
+			exited = append(exited, c)
+		}
+	}
+	return exited, nil
+}
+
+// ListStoppedContainers returns the configured containers whose state is exited, dead or created
+func (m *MockDockerClient) ListStoppedContainers(ctx context.Context) ([]ContainerInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ListExitedContainersError != nil {
+		return nil, m.ListExitedContainersError
+	}
+
+	var stopped []ContainerInfo
+	for _, c := range m.Containers {
+		if c.State != nil && (c.State.Status == "exited" || c.State.Status == "dead" || c.State.Status == "created") {
+			stopped = append(stopped, c)
+		}
+	}
+	return stopped, nil
+}
+
+// ListDanglingVolumes returns the configured volumes
+func (m *MockDockerClient) ListDanglingVolumes(ctx context.Context) ([]VolumeInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ListVolumesError != nil {
+		return nil, m.ListVolumesError
+	}
+	return m.Volumes, nil
+}
+
+// RemoveVolume records the removal
+func (m *MockDockerClient) RemoveVolume(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RemovedVolumes = append(m.RemovedVolumes, name)
+	return m.RemoveVolumeError
+}
+
+// ListUnusedNetworks returns the configured networks
+func (m *MockDockerClient) ListUnusedNetworks(ctx context.Context) ([]NetworkInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ListNetworksError != nil {
+		return nil, m.ListNetworksError
+	}
+	return m.Networks, nil
+}
+
+// RemoveNetwork records the removal
+func (m *MockDockerClient) RemoveNetwork(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RemovedNetworks = append(m.RemovedNetworks, id)
+	return m.RemoveNetworkError
+}
+
+// PruneBuildCache records the prune
+func (m *MockDockerClient) PruneBuildCache(ctx context.Context, keepBytes int64) (int, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.PruneBuildCacheError != nil {
+		return 0, 0, m.PruneBuildCacheError
+	}
+	m.BuildCachePrunes = append(m.BuildCachePrunes, keepBytes)
+	return 0, 0, nil
+}
+
+// DataRootUsage returns the configured disk usage
+func (m *MockDockerClient) DataRootUsage(ctx context.Context, path string) (DiskUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.DataRootUsageError != nil {
+		return DiskUsage{}, m.DataRootUsageError
+	}
+	return m.DiskUsage, nil
+}
+
+// DaemonPlatform returns the configured platform
+func (m *MockDockerClient) DaemonPlatform(ctx context.Context) (Platform, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.DaemonPlatformError != nil {
+		return Platform{}, m.DaemonPlatformError
+	}
+	return m.Platform, nil
+}
+
+// Ping returns PingError
+func (m *MockDockerClient) Ping(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.PingError
+}
+
 // RenameContainer records the rename
 func (m *MockDockerClient) RenameContainer(ctx context.Context, id, newName string) error {
 	m.mu.Lock()
@@ -333,6 +590,79 @@ func (m *MockDockerClient) CreateHelperContainer(ctx context.Context, original C
 	return "helper-container-id-" + name, nil
 }
 
+// ExecContainer records the command and returns the configured result
+func (m *MockDockerClient) ExecContainer(ctx context.Context, id string, cmd []string) (ExecResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ExecutedCommands = append(m.ExecutedCommands, ExecRequest{
+		ID:  id,
+		Cmd: cmd,
+	})
+
+	if m.ExecContainerError != nil {
+		return ExecResult{}, m.ExecContainerError
+	}
+
+	return m.ExecResults[id], nil
+}
+
+// RunContainer records the run and returns the configured result
+func (m *MockDockerClient) RunContainer(ctx context.Context, image string, cmd []string) (ExecResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RanContainers = append(m.RanContainers, RunRequest{
+		Image: image,
+		Cmd:   cmd,
+	})
+
+	if m.RunContainerError != nil {
+		return ExecResult{}, m.RunContainerError
+	}
+
+	return m.RunResults[image], nil
+}
+
+// Events streams events sent on EventsChan until ctx is cancelled
+func (m *MockDockerClient) Events(ctx context.Context) (<-chan Event, <-chan error) {
+	m.mu.Lock()
+	source := m.EventsChan
+	streamErr := m.EventsError
+	m.mu.Unlock()
+
+	out := make(chan Event)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		if streamErr != nil {
+			errs <- streamErr
+			return
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			case event, ok := <-source:
+				if !ok {
+					errs <- fmt.Errorf("events stream closed")
+					return
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+		}
+	}()
+
+	return out, errs
+}
+
 // Close does nothing for the mock
 func (m *MockDockerClient) Close() error {
 	return nil
@@ -350,8 +680,15 @@ func (m *MockDockerClient) Reset() {
 	m.RemovedContainers = []string{}
 	m.CreatedContainers = []CreateRequest{}
 	m.ReplacedContainers = []ReplaceRequest{}
+	m.AutoRemoveReplaced = []ReplaceRequest{}
 	m.RenamedContainers = []RenameRequest{}
 	m.CreatedHelpers = []CreateHelperRequest{}
+	m.ExecutedCommands = []ExecRequest{}
+	m.RanContainers = []RunRequest{}
+	m.RemovedVolumes = []string{}
+	m.RemovedNetworks = []string{}
+	m.BuildCachePrunes = []int64{}
+	m.InspectedContainers = []string{}
 }
 
 // SetContainerState updates the state of a container for testing
@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+// splitNetworks splits the old container's network attachments into the networking config to
+// create its replacement with and the networks to connect it to afterwards. Docker only
+// applies one endpoint at create time on older APIs, so the network the container was started
+// on (its network mode) goes first and every other one is connected explicitly.
+func splitNetworks(old ContainerInfo) (*network.NetworkingConfig, map[string]*network.EndpointSettings) {
+	if old.NetworkConfig == nil || len(old.NetworkConfig.EndpointsConfig) == 0 {
+		return old.NetworkConfig, nil
+	}
+
+	mode := container.NetworkMode("")
+	if old.HostConfig != nil {
+		mode = old.HostConfig.NetworkMode
+	}
+	// Containers sharing another container's network stack have no endpoints of their own
+	if mode.IsContainer() {
+		return nil, nil
+	}
+
+	primary := mode.NetworkName()
+	if _, ok := old.NetworkConfig.EndpointsConfig[primary]; !ok {
+		names := make([]string, 0, len(old.NetworkConfig.EndpointsConfig))
+		for name := range old.NetworkConfig.EndpointsConfig {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		primary = names[0]
+	}
+
+	create := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+	extra := make(map[string]*network.EndpointSettings)
+	for name, endpoint := range old.NetworkConfig.EndpointsConfig {
+		settings := endpointConfig(endpoint, old.ID)
+		if name == primary {
+			create.EndpointsConfig[name] = settings
+		} else {
+			extra[name] = settings
+		}
+	}
+	return create, extra
+}
+
+// endpointConfig keeps the parts of an inspected endpoint the user configured: static IPs,
+// aliases, links, driver options and gateway priority. The rest (IDs, assigned addresses) is
+// operational data of the old container. Older daemons add the container's short ID as an
+// alias, which would point at the old container, so it's dropped.
+func endpointConfig(endpoint *network.EndpointSettings, oldID string) *network.EndpointSettings {
+	if endpoint == nil {
+		return &network.EndpointSettings{}
+	}
+	settings := &network.EndpointSettings{
+		Links:      endpoint.Links,
+		DriverOpts: endpoint.DriverOpts,
+		GwPriority: endpoint.GwPriority,
+	}
+	if endpoint.IPAMConfig != nil {
+		settings.IPAMConfig = endpoint.IPAMConfig.Copy()
+	}
+	for _, alias := range endpoint.Aliases {
+		if len(oldID) >= 12 && alias == oldID[:12] {
+			continue
+		}
+		settings.Aliases = append(settings.Aliases, alias)
+	}
+	return settings
+}
+
+// connectNetworks attaches a container to networks, in name order
+func (d *DockerClient) connectNetworks(ctx context.Context, id string, networks map[string]*network.EndpointSettings) error {
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := d.cli.NetworkConnect(ctx, name, id, networks[name])
+		audit.Record(ctx, audit.Entry{Action: "connect", Object: audit.ObjectContainer, ID: id, Detail: "network " + name}, err)
+		if err != nil {
+			return fmt.Errorf("failed to connect container to network %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+func TestSplitNetworks(t *testing.T) {
+	endpoints := map[string]*network.EndpointSettings{
+		"backend":  {Aliases: []string{"db", "0123456789ab"}, NetworkID: "n1", IPAddress: "172.20.0.5"},
+		"frontend": {IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "10.0.0.10"}, Aliases: []string{"web"}},
+		"metrics":  {DriverOpts: map[string]string{"com.example.opt": "1"}},
+	}
+
+	tests := []struct {
+		name        string
+		mode        container.NetworkMode
+		endpoints   map[string]*network.EndpointSettings
+		wantPrimary string
+		wantExtra   []string
+	}{
+		{"network mode first", "frontend", endpoints, "frontend", []string{"backend", "metrics"}},
+		{"first by name without a match", "default", endpoints, "backend", []string{"frontend", "metrics"}},
+		{"single network", "bridge", map[string]*network.EndpointSettings{"bridge": {}}, "bridge", nil},
+		{"shared network stack", "container:vpn", nil, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := ContainerInfo{
+				ID:            "0123456789abcdef",
+				HostConfig:    &container.HostConfig{NetworkMode: tt.mode},
+				NetworkConfig: &network.NetworkingConfig{EndpointsConfig: tt.endpoints},
+			}
+			create, extra := splitNetworks(old)
+
+			if tt.wantPrimary == "" {
+				if create != nil && len(create.EndpointsConfig) > 0 {
+					t.Errorf("create networks = %v, want none", create.EndpointsConfig)
+				}
+			} else if _, ok := create.EndpointsConfig[tt.wantPrimary]; !ok || len(create.EndpointsConfig) != 1 {
+				t.Errorf("create networks = %v, want only %s", create.EndpointsConfig, tt.wantPrimary)
+			}
+
+			var gotExtra []string
+			for name := range extra {
+				gotExtra = append(gotExtra, name)
+			}
+			if len(gotExtra) != len(tt.wantExtra) {
+				t.Fatalf("extra networks = %v, want %v", gotExtra, tt.wantExtra)
+			}
+			for _, name := range tt.wantExtra {
+				if _, ok := extra[name]; !ok {
+					t.Errorf("extra networks = %v, want %v", gotExtra, tt.wantExtra)
+				}
+			}
+		})
+	}
+}
+
+func TestEndpointConfig(t *testing.T) {
+	inspected := &network.EndpointSettings{
+		IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "10.0.0.10", IPv6Address: "fd00::10"},
+		Links:      []string{"cache:redis"},
+		Aliases:    []string{"web", "0123456789ab"},
+		DriverOpts: map[string]string{"com.example.opt": "1"},
+		GwPriority: 10,
+		NetworkID:  "n1",
+		EndpointID: "e1",
+		IPAddress:  "10.0.0.10",
+		MacAddress: "02:42:0a:00:00:0a",
+		DNSNames:   []string{"web", "0123456789ab"},
+	}
+
+	want := &network.EndpointSettings{
+		IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "10.0.0.10", IPv6Address: "fd00::10", LinkLocalIPs: []string{}},
+		Links:      []string{"cache:redis"},
+		Aliases:    []string{"web"},
+		DriverOpts: map[string]string{"com.example.opt": "1"},
+		GwPriority: 10,
+	}
+	if got := endpointConfig(inspected, "0123456789abcdef"); !reflect.DeepEqual(got, want) {
+		t.Errorf("endpointConfig() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCreateContainerLike_ConnectsAllNetworks(t *testing.T) {
+	transport := newMockTransport()
+	var created network.NetworkingConfig
+	transport.register("POST", "/v1.41/containers/create", func(req *http.Request) (*http.Response, error) {
+		var body struct{ NetworkingConfig network.NetworkingConfig }
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		created = body.NetworkingConfig
+		return jsonResponse(201, container.CreateResponse{ID: "new-id"})
+	})
+	connected := map[string]network.ConnectOptions{}
+	for _, name := range []string{"backend", "metrics"} {
+		transport.register("POST", "/v1.41/networks/"+name+"/connect", func(req *http.Request) (*http.Response, error) {
+			var body network.ConnectOptions
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			connected[name] = body
+			return jsonResponse(200, nil)
+		})
+	}
+
+	cli, _ := client.NewClientWithOpts(client.WithHTTPClient(&http.Client{Transport: transport}), client.WithVersion("1.41"))
+	d := &DockerClient{cli: cli}
+
+	old := ContainerInfo{
+		ID:         "0123456789abcdef",
+		Name:       "my-app",
+		ImageID:    "sha256:old-img",
+		Config:     &container.Config{},
+		HostConfig: &container.HostConfig{NetworkMode: "frontend"},
+		NetworkConfig: &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+			"frontend": {Aliases: []string{"web"}},
+			"backend":  {IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "172.20.0.5"}, Aliases: []string{"api", "0123456789ab"}},
+			"metrics":  {},
+		}},
+	}
+	if _, err := d.CreateContainerLike(context.Background(), old, "new-image"); err != nil {
+		t.Fatalf("CreateContainerLike failed: %v", err)
+	}
+
+	if len(created.EndpointsConfig) != 1 || created.EndpointsConfig["frontend"] == nil {
+		t.Errorf("created with networks %v, want only frontend", created.EndpointsConfig)
+	}
+	backend, ok := connected["backend"]
+	if !ok || backend.Container != "new-id" {
+		t.Fatalf("backend connect = %+v, want the new container", backend)
+	}
+	if backend.EndpointConfig.IPAMConfig == nil || backend.EndpointConfig.IPAMConfig.IPv4Address != "172.20.0.5" {
+		t.Errorf("backend IPAM = %+v, want the static IP kept", backend.EndpointConfig.IPAMConfig)
+	}
+	if !reflect.DeepEqual(backend.EndpointConfig.Aliases, []string{"api"}) {
+		t.Errorf("backend aliases = %v, want [api]", backend.EndpointConfig.Aliases)
+	}
+	if _, ok := connected["metrics"]; !ok {
+		t.Error("metrics network was not connected")
+	}
+}
+
+func TestCreateContainerLike_NetworkConnectFailureRemovesContainer(t *testing.T) {
+	transport := newMockTransport()
+	transport.register("POST", "/v1.41/containers/create", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(201, container.CreateResponse{ID: "new-id"})
+	})
+	transport.register("POST", "/v1.41/networks/backend/connect", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(409, map[string]string{"message": "Address already in use"})
+	})
+	transport.register("DELETE", "/v1.41/containers/new-id", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(204, nil)
+	})
+
+	cli, _ := client.NewClientWithOpts(client.WithHTTPClient(&http.Client{Transport: transport}), client.WithVersion("1.41"))
+	d := &DockerClient{cli: cli}
+
+	old := ContainerInfo{
+		ID:         "0123456789abcdef",
+		Name:       "my-app",
+		ImageID:    "sha256:old-img",
+		Config:     &container.Config{},
+		HostConfig: &container.HostConfig{NetworkMode: "frontend"},
+		NetworkConfig: &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+			"frontend": {},
+			"backend":  {IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "172.20.0.5"}},
+		}},
+	}
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := audit.Open(path, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	if _, err := d.CreateContainerLike(context.Background(), old, "new-image"); err == nil {
+		t.Fatal("expected an error when a network can't be connected")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"action":"connect"`) || !strings.Contains(string(data), "Address already in use") {
+		t.Errorf("audit log = %s, want the failed network connect recorded", data)
+	}
+
+	removed := false
+	for _, call := range transport.getCalls() {
+		if call == "DELETE /v1.41/containers/new-id" {
+			removed = true
+		}
+	}
+	if !removed {
+		t.Errorf("half-connected container was not removed, calls: %v", transport.getCalls())
+	}
+}
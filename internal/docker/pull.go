@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// pullMessage is one line of the JSON stream returned by an image pull
+type pullMessage struct {
+	Status   string `json:"status"`
+	ID       string `json:"id"` // Layer the status is about, if any
+	Progress *struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+	Error string `json:"error"`
+}
+
+// layerProgress is what a pull has downloaded of one layer
+type layerProgress struct {
+	status     string
+	downloaded int64
+	size       int64
+}
+
+// readPullStream consumes the output of an image pull, logging each layer's progress at debug
+// level, and returns how many bytes were downloaded. Errors reported in the stream, which the
+// daemon sends after the pull already started, are returned as errors.
+func readPullStream(r io.Reader, image string, logger *zerolog.Logger) (int64, error) {
+	layers := make(map[string]*layerProgress)
+
+	decoder := json.NewDecoder(r)
+	for {
+		var msg pullMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, err
+		}
+
+		if msg.ErrorDetail != nil && msg.ErrorDetail.Message != "" {
+			return 0, errors.New(msg.ErrorDetail.Message)
+		}
+		if msg.Error != "" {
+			return 0, errors.New(msg.Error)
+		}
+		if msg.ID == "" || strings.HasPrefix(msg.Status, "Pulling from") {
+			// Image-level status such as "Digest: ..." or "Status: Downloaded newer image"
+			continue
+		}
+
+		layer, ok := layers[msg.ID]
+		if !ok {
+			layer = &layerProgress{}
+			layers[msg.ID] = layer
+		}
+		switch msg.Status {
+		case "Downloading":
+			if msg.Progress != nil {
+				layer.downloaded = msg.Progress.Current
+				if msg.Progress.Total > 0 {
+					layer.size = msg.Progress.Total
+				}
+			}
+		case "Download complete":
+			if layer.size > 0 {
+				layer.downloaded = layer.size
+			}
+		}
+
+		// Progress ticks would flood the log; only changes of status are worth a line
+		if msg.Status != layer.status {
+			layer.status = msg.Status
+			logger.Debug().
+				Str("image", image).
+				Str("layer", msg.ID).
+				Str("status", msg.Status).
+				Msg("Pull progress")
+		}
+	}
+
+	var total int64
+	for _, layer := range layers {
+		total += layer.downloaded
+	}
+	return total, nil
+}
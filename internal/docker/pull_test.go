@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+
+	"github.com/rs/zerolog"
+)
+
+func TestReadPullStream(t *testing.T) {
+	tests := []struct {
+		name           string
+		stream         string
+		wantDownloaded int64
+		wantErr        string
+		wantLogged     []string
+	}{
+		{
+			name: "downloaded layers",
+			stream: `{"status":"Pulling from library/nginx","id":"latest"}
+{"status":"Already exists","progressDetail":{},"id":"aaaaaaaaaaaa"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"bbbbbbbbbbbb"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"cccccccccccc"}
+{"status":"Downloading","progressDetail":{"current":1000,"total":5000},"id":"bbbbbbbbbbbb"}
+{"status":"Downloading","progressDetail":{"current":4000,"total":5000},"id":"bbbbbbbbbbbb"}
+{"status":"Download complete","progressDetail":{},"id":"bbbbbbbbbbbb"}
+{"status":"Downloading","progressDetail":{"current":300,"total":700},"id":"cccccccccccc"}
+{"status":"Download complete","progressDetail":{},"id":"cccccccccccc"}
+{"status":"Extracting","progressDetail":{"current":5000,"total":5000},"id":"bbbbbbbbbbbb"}
+{"status":"Pull complete","progressDetail":{},"id":"bbbbbbbbbbbb"}
+{"status":"Pull complete","progressDetail":{},"id":"cccccccccccc"}
+{"status":"Digest: sha256:abc"}
+{"status":"Status: Downloaded newer image for nginx:latest"}
+`,
+			wantDownloaded: 5700,
+			wantLogged:     []string{`"layer":"bbbbbbbbbbbb","status":"Downloading"`, `"layer":"cccccccccccc","status":"Pull complete"`},
+		},
+		{
+			name: "up to date",
+			stream: `{"status":"Pulling from library/nginx","id":"latest"}
+{"status":"Digest: sha256:abc"}
+{"status":"Status: Image is up to date for nginx:latest"}
+`,
+			wantDownloaded: 0,
+		},
+		{
+			name: "error in stream",
+			stream: `{"status":"Pulling from library/nginx","id":"latest"}
+{"errorDetail":{"message":"unauthorized: authentication required"},"error":"unauthorized: authentication required"}
+`,
+			wantErr: "unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := zerolog.New(&logs).Level(zerolog.DebugLevel)
+
+			downloaded, err := readPullStream(strings.NewReader(tt.stream), "nginx:latest", &logger)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readPullStream() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readPullStream() error = %v", err)
+			}
+			if downloaded != tt.wantDownloaded {
+				t.Errorf("downloaded = %d, want %d", downloaded, tt.wantDownloaded)
+			}
+			for _, want := range tt.wantLogged {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("log missing %s:\n%s", want, logs.String())
+				}
+			}
+			if n := strings.Count(logs.String(), `"layer":"bbbbbbbbbbbb","status":"Downloading"`); n > 1 {
+				t.Errorf("logged %d Downloading lines for one layer, want one per status change", n)
+			}
+		})
+	}
+}
+
+func TestDockerClient_PullImage_RequestsPlatform(t *testing.T) {
+	transport := newMockTransport()
+
+	var platforms []string
+	transport.register("POST", "/v1.41/images/create", func(req *http.Request) (*http.Response, error) {
+		platforms = append(platforms, req.URL.Query().Get("platform"))
+		return jsonResponse(200, map[string]string{"status": "Downloaded"})
+	})
+	transport.register("GET", "/v1.41/images/ghcr.io/org/app:v1/json", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, map[string]interface{}{"Id": "sha256:new", "Architecture": "arm", "Variant": "v7", "Config": map[string]interface{}{}})
+	})
+
+	cli, err := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	d := &DockerClient{cli: cli}
+
+	ctx := WithPlatform(context.Background(), Platform{OS: "linux", Architecture: "arm", Variant: "v7"})
+	if _, err := d.PullImage(ctx, "ghcr.io/org/app:v1"); err != nil {
+		t.Fatalf("PullImage() error = %v", err)
+	}
+	if _, err := d.PullImage(context.Background(), "ghcr.io/org/app:v1"); err != nil {
+		t.Fatalf("PullImage() error = %v", err)
+	}
+	if len(platforms) != 2 || platforms[0] != "linux/arm/v7" || platforms[1] != "" {
+		t.Errorf("platforms requested = %q, want linux/arm/v7 and then the daemon's own", platforms)
+	}
+}
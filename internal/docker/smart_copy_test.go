@@ -1,13 +1,18 @@
 package docker
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
+	"reflect"
 	"testing"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-units"
 )
 
 func TestCreateContainerLike_SmartCopy(t *testing.T) {
@@ -161,3 +166,119 @@ func TestCreateContainerLike_SmartCopy(t *testing.T) {
 		})
 	}
 }
+
+// fullHostConfig sets the host settings users have reported losing across updates
+func fullHostConfig() *container.HostConfig {
+	memorySwap := int64(2 << 30)
+	pidsLimit := int64(200)
+	return &container.HostConfig{
+		Binds:         []string{"/srv/data:/data:ro"},
+		RestartPolicy: container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 5},
+		CapAdd:        strslice.StrSlice{"CAP_NET_ADMIN", "CAP_SYS_TIME"},
+		CapDrop:       strslice.StrSlice{"CAP_MKNOD"},
+		Sysctls:       map[string]string{"net.ipv4.ip_forward": "1", "net.core.somaxconn": "1024"},
+		Tmpfs:         map[string]string{"/run": "rw,noexec,size=64m"},
+		SecurityOpt:   []string{"no-new-privileges:true"},
+		ShmSize:       256 << 20,
+		Mounts: []mount.Mount{
+			{Type: mount.TypeTmpfs, Target: "/cache", TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 32 << 20}},
+		},
+		Resources: container.Resources{
+			Memory:     1 << 30,
+			MemorySwap: memorySwap,
+			NanoCPUs:   1500000000,
+			CpusetCpus: "0-1",
+			PidsLimit:  &pidsLimit,
+			Devices: []container.DeviceMapping{
+				{PathOnHost: "/dev/dri/renderD128", PathInContainer: "/dev/dri/renderD128", CgroupPermissions: "rwm"},
+				{PathOnHost: "/dev/ttyUSB0", PathInContainer: "/dev/zigbee", CgroupPermissions: "rw"},
+			},
+			DeviceRequests: []container.DeviceRequest{
+				{Driver: "nvidia", Count: -1, Capabilities: [][]string{{"gpu"}}, Options: map[string]string{}},
+				{Driver: "nvidia", DeviceIDs: []string{"GPU-3a23c669"}, Capabilities: [][]string{{"gpu", "compute", "utility"}}},
+			},
+			Ulimits: []*units.Ulimit{
+				{Name: "nofile", Soft: 65536, Hard: 65536},
+				{Name: "memlock", Soft: -1, Hard: -1},
+			},
+		},
+	}
+}
+
+func TestCreateContainerLike_HostConfigPreserved(t *testing.T) {
+	t.Run("sent byte for byte", func(t *testing.T) {
+		var received json.RawMessage
+		transport := newMockTransport()
+		transport.register("POST", "/v1.41/containers/create", func(req *http.Request) (*http.Response, error) {
+			var body struct{ HostConfig json.RawMessage }
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return jsonResponse(400, map[string]string{"message": err.Error()})
+			}
+			received = body.HostConfig
+			return jsonResponse(201, container.CreateResponse{ID: "new-id"})
+		})
+
+		cli, _ := client.NewClientWithOpts(client.WithHTTPClient(&http.Client{Transport: transport}), client.WithVersion("1.41"))
+		d := &DockerClient{cli: cli}
+
+		old := ContainerInfo{ID: "old-id", Name: "my-app", ImageID: "sha256:old-img", Config: &container.Config{}, HostConfig: fullHostConfig()}
+		if _, err := d.CreateContainerLike(context.Background(), old, "new-image"); err != nil {
+			t.Fatalf("CreateContainerLike failed: %v", err)
+		}
+
+		want, _ := json.Marshal(fullHostConfig())
+		if !bytes.Equal(received, want) {
+			t.Errorf("host config sent =\n%s\nwant\n%s", received, want)
+		}
+	})
+
+	t.Run("old container untouched", func(t *testing.T) {
+		transport := newMockTransport()
+		transport.register("POST", "/v1.44/containers/create", func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(201, container.CreateResponse{ID: "new-id"})
+		})
+
+		cli, _ := client.NewClientWithOpts(client.WithHTTPClient(&http.Client{Transport: transport}), client.WithVersion("1.44"))
+		d := &DockerClient{cli: cli}
+
+		// The client rewrites capabilities to CAP_NET_ADMIN and drops kernel memory on this API
+		hostConfig := &container.HostConfig{CapAdd: strslice.StrSlice{"net_admin"}, Resources: container.Resources{KernelMemory: 64 << 20}}
+		old := ContainerInfo{ID: "old-id", Name: "my-app", ImageID: "sha256:old-img", Config: &container.Config{}, HostConfig: hostConfig}
+		if _, err := d.CreateContainerLike(context.Background(), old, "new-image"); err != nil {
+			t.Fatalf("CreateContainerLike failed: %v", err)
+		}
+
+		want := &container.HostConfig{CapAdd: strslice.StrSlice{"net_admin"}, Resources: container.Resources{KernelMemory: 64 << 20}}
+		if !reflect.DeepEqual(old.HostConfig, want) {
+			t.Errorf("old host config changed to %+v", old.HostConfig)
+		}
+	})
+}
+
+func TestCreateContainerLike_ReplacesLabel(t *testing.T) {
+	var received container.Config
+	transport := newMockTransport()
+	transport.register("POST", "/v1.41/containers/create", func(req *http.Request) (*http.Response, error) {
+		if err := json.NewDecoder(req.Body).Decode(&received); err != nil {
+			return jsonResponse(400, map[string]string{"message": err.Error()})
+		}
+		return jsonResponse(201, container.CreateResponse{ID: "new-id"})
+	})
+
+	cli, _ := client.NewClientWithOpts(client.WithHTTPClient(&http.Client{Transport: transport}), client.WithVersion("1.41"))
+	d := &DockerClient{cli: cli}
+
+	labels := map[string]string{"app": "web", ReplacesLabel: "older-id"}
+	old := ContainerInfo{ID: "old-id", Name: "my-app", ImageID: "sha256:old-img", Config: &container.Config{Labels: labels}, HostConfig: &container.HostConfig{}}
+	if _, err := d.CreateContainerLike(context.Background(), old, "new-image"); err != nil {
+		t.Fatalf("CreateContainerLike failed: %v", err)
+	}
+
+	want := map[string]string{"app": "web", ReplacesLabel: "old-id"}
+	if !reflect.DeepEqual(received.Labels, want) {
+		t.Errorf("labels sent = %v, want %v", received.Labels, want)
+	}
+	if labels[ReplacesLabel] != "older-id" {
+		t.Error("the old container's labels were changed")
+	}
+}
@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
@@ -18,6 +19,13 @@ type ContainerInfo struct {
 	CreatedAt time.Time
 	State     *types.ContainerState
 
+	// NetworkMode is the container's network mode (e.g., "bridge", "host", "container:<id>")
+	NetworkMode string
+
+	// Mounts names what the container's volumes and bind mounts come from: the volume name, or
+	// the host path of a bind mount. Listing containers returns them too.
+	Mounts []string
+
 	// Config needed for recreation
 	// Note: These fields may be nil if the ContainerInfo was returned by ListContainers (optimization).
 	// They are populated by InspectContainer.
@@ -28,11 +36,81 @@ type ContainerInfo struct {
 
 // ImageInfo holds information about a Docker image
 type ImageInfo struct {
+	ID          string
+	RepoTags    []string
+	RepoDigests []string // "name@sha256:..." digests recorded when the image was pulled
+	Dangling    bool
+	CreatedAt   time.Time
+	Size        int64
+	Labels      map[string]string
+	Config      *container.Config // Config from image inspection
+	Platform    Platform          // OS and architecture the image is built for
+
+	// Set by PullImage: bytes downloaded (zero when every layer was already present) and how
+	// long the pull took
+	PulledBytes  int64
+	PullDuration time.Duration
+}
+
+// Platform is the OS and CPU architecture an image is built for or a daemon runs on
+type Platform struct {
+	OS           string // e.g. "linux"
+	Architecture string // e.g. "amd64", "arm64"
+	Variant      string // e.g. "v7" for 32-bit ARM; daemons don't report one
+}
+
+// String formats the platform like Docker's --platform flag, e.g. "linux/arm/v7"
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// Known reports whether the OS and architecture are set
+func (p Platform) Known() bool {
+	return p.OS != "" && p.Architecture != ""
+}
+
+// VolumeInfo holds information about a Docker volume
+type VolumeInfo struct {
+	Name      string
+	Driver    string
+	CreatedAt time.Time
+	Labels    map[string]string
+}
+
+// NetworkInfo holds information about a Docker network
+type NetworkInfo struct {
 	ID        string
-	RepoTags  []string
-	Dangling  bool
+	Name      string
+	Driver    string
 	CreatedAt time.Time
-	Size      int64
 	Labels    map[string]string
-	Config    *container.Config // Config from image inspection
+}
+
+// NetworkContainer returns the container whose network namespace this container joins
+// (the target of "network_mode: container:<id|name>"), or "" if it has its own.
+func (c ContainerInfo) NetworkContainer() string {
+	if ref, ok := strings.CutPrefix(c.NetworkMode, "container:"); ok {
+		return ref
+	}
+	return ""
+}
+
+// DiskUsage describes the filesystem holding Docker's data root
+type DiskUsage struct {
+	Path      string
+	Total     uint64 // Bytes
+	Used      uint64
+	Available uint64 // Bytes available to unprivileged users
+}
+
+// UsedPercent returns usage the way df reports it, excluding blocks reserved for root
+func (u DiskUsage) UsedPercent() float64 {
+	if u.Used+u.Available == 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Used+u.Available) * 100
 }
@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// ListDanglingVolumes returns volumes not referenced by any container, running or stopped
+func (d *DockerClient) ListDanglingVolumes(ctx context.Context) ([]VolumeInfo, error) {
+	resp, err := d.cli.VolumeList(ctx, volume.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("dangling", "true")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dangling volumes: %w", err)
+	}
+
+	result := make([]VolumeInfo, 0, len(resp.Volumes))
+	for _, v := range resp.Volumes {
+		if v == nil {
+			continue
+		}
+		createdAt, _ := time.Parse(time.RFC3339Nano, v.CreatedAt)
+		result = append(result, VolumeInfo{
+			Name:      v.Name,
+			Driver:    v.Driver,
+			CreatedAt: createdAt,
+			Labels:    v.Labels,
+		})
+	}
+
+	return result, nil
+}
+
+// RemoveVolume removes a volume. It fails if a container started using it in the meantime.
+func (d *DockerClient) RemoveVolume(ctx context.Context, name string) error {
+	err := d.cli.VolumeRemove(ctx, name, false)
+	audit.Record(ctx, audit.Entry{Action: "remove", Object: audit.ObjectVolume, ID: name}, err)
+	if err != nil {
+		return fmt.Errorf("failed to remove volume %s: %w", name, err)
+	}
+	return nil
+}
+
+// ListUnusedNetworks returns user-defined networks with no containers attached.
+// The predefined bridge, host and none networks are never included.
+func (d *DockerClient) ListUnusedNetworks(ctx context.Context) ([]NetworkInfo, error) {
+	networks, err := d.cli.NetworkList(ctx, network.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("dangling", "true")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unused networks: %w", err)
+	}
+
+	result := make([]NetworkInfo, 0, len(networks))
+	for _, n := range networks {
+		if isPredefinedNetwork(n.Name) {
+			continue
+		}
+		result = append(result, NetworkInfo{
+			ID:        n.ID,
+			Name:      n.Name,
+			Driver:    n.Driver,
+			CreatedAt: n.Created,
+			Labels:    n.Labels,
+		})
+	}
+
+	return result, nil
+}
+
+// RemoveNetwork removes a network
+func (d *DockerClient) RemoveNetwork(ctx context.Context, id string) error {
+	err := d.cli.NetworkRemove(ctx, id)
+	audit.Record(ctx, audit.Entry{Action: "remove", Object: audit.ObjectNetwork, ID: id}, err)
+	if err != nil {
+		return fmt.Errorf("failed to remove network %s: %w", id, err)
+	}
+	return nil
+}
+
+// isPredefinedNetwork reports whether the network is one Docker creates itself
+func isPredefinedNetwork(name string) bool {
+	switch name {
+	case "bridge", "host", "none", "ingress", "docker_gwbridge":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+func TestDockerClient_ListDanglingVolumes(t *testing.T) {
+	transport := newMockTransport()
+
+	var filtersParam string
+	transport.register("GET", "/v1.41/volumes", func(req *http.Request) (*http.Response, error) {
+		filtersParam = req.URL.Query().Get("filters")
+		return jsonResponse(200, volume.ListResponse{Volumes: []*volume.Volume{
+			{Name: "cache", Driver: "local", CreatedAt: "2024-05-01T10:00:00Z", Labels: map[string]string{"app": "web"}},
+			nil,
+		}})
+	})
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	volumes, err := d.ListDanglingVolumes(context.Background())
+	if err != nil {
+		t.Fatalf("ListDanglingVolumes failed: %v", err)
+	}
+
+	if filtersParam != `{"dangling":{"true":true}}` {
+		t.Errorf("filters = %s, want dangling=true", filtersParam)
+	}
+	if len(volumes) != 1 || volumes[0].Name != "cache" || volumes[0].CreatedAt.Year() != 2024 {
+		t.Errorf("volumes = %+v, want the cache volume with its creation time", volumes)
+	}
+}
+
+func TestDockerClient_ListUnusedNetworks(t *testing.T) {
+	transport := newMockTransport()
+
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	transport.register("GET", "/v1.41/networks", func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(200, []network.Summary{
+			{ID: "n1", Name: "bridge", Driver: "bridge"},
+			{ID: "n2", Name: "shop_default", Driver: "bridge", Created: created},
+		})
+	})
+
+	cli, _ := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: transport}),
+		client.WithVersion("1.41"),
+	)
+	d := &DockerClient{cli: cli}
+
+	networks, err := d.ListUnusedNetworks(context.Background())
+	if err != nil {
+		t.Fatalf("ListUnusedNetworks failed: %v", err)
+	}
+
+	if len(networks) != 1 || networks[0].Name != "shop_default" || !networks[0].CreatedAt.Equal(created) {
+		t.Errorf("networks = %+v, want only the user-defined shop_default", networks)
+	}
+}
@@ -0,0 +1,155 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+)
+
+// Replacement is a container replaced during a cycle
+type Replacement struct {
+	Container  string `json:"container"`
+	Image      string `json:"image"`
+	Target     string `json:"target,omitempty"` // New image reference when the update policy moved the tag
+	OldImageID string `json:"old_image_id"`
+	NewImageID string `json:"new_image_id"`
+	Digest     string `json:"digest,omitempty"` // Registry digest deployed, with updates.pin_digests
+}
+
+// Failure is a per-container error during a cycle
+type Failure struct {
+	Container string `json:"container"`
+	Category  string `json:"category"`
+	Error     string `json:"error"`
+}
+
+// Cycle is one journal entry: what an update cycle checked, pulled, replaced and failed on
+type Cycle struct {
+	ID          string        `json:"id"`
+	StartedAt   time.Time     `json:"started_at"`
+	DurationMs  int64         `json:"duration_ms"`
+	Checked     int           `json:"checked"`
+	Pulled      []string      `json:"pulled,omitempty"`
+	PulledBytes int64         `json:"pulled_bytes,omitempty"` // Downloaded by those pulls
+	Replaced    []Replacement `json:"replaced,omitempty"`
+	Restarted   []string      `json:"restarted,omitempty"` // Dependents recreated or restarted alongside a replacement
+	Failures    []Failure     `json:"failures,omitempty"`
+	Error       string        `json:"error,omitempty"` // Set when the cycle itself failed
+}
+
+// writeMu serializes appends from this process so lines never interleave
+var writeMu sync.Mutex
+
+// Append writes a cycle to the JSONL journal at path, creating it if needed
+func Append(path string, c Cycle) error {
+	line, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history entry: %w", err)
+	}
+	return nil
+}
+
+// Read returns up to limit cycles from the journal, newest first (limit <= 0 returns all).
+// A missing journal is empty; unparseable lines, e.g. from a crash mid-write, are skipped.
+func Read(path string, limit int) ([]Cycle, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return []Cycle{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	var cycles []Cycle
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var c Cycle
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			continue
+		}
+		cycles = append(cycles, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	// Newest first
+	for i, j := 0, len(cycles)-1; i < j; i, j = i+1, j-1 {
+		cycles[i], cycles[j] = cycles[j], cycles[i]
+	}
+	if limit > 0 && len(cycles) > limit {
+		cycles = cycles[:limit]
+	}
+	if cycles == nil {
+		cycles = []Cycle{}
+	}
+	return cycles, nil
+}
+
+// Format writes cycles as human-readable text for the history subcommand
+func Format(w io.Writer, cycles []Cycle) {
+	if len(cycles) == 0 {
+		fmt.Fprintln(w, "No update cycles recorded yet.")
+		return
+	}
+
+	for _, c := range cycles {
+		pulled := fmt.Sprintf("%d pulled", len(c.Pulled))
+		if c.PulledBytes > 0 {
+			pulled += " (" + util.FormatBytes(c.PulledBytes) + ")"
+		}
+		fmt.Fprintf(w, "%s  cycle %s  %d checked, %s, %d replaced, %d failed (%v)\n",
+			c.StartedAt.Local().Format("2006-01-02 15:04:05"), c.ID, c.Checked, pulled, len(c.Replaced), len(c.Failures),
+			(time.Duration(c.DurationMs) * time.Millisecond).Round(time.Millisecond))
+
+		if c.Error != "" {
+			fmt.Fprintf(w, "    ✗ cycle failed: %s\n", c.Error)
+		}
+		for _, r := range c.Replaced {
+			image := r.Image
+			if r.Target != "" {
+				image = r.Image + " → " + r.Target
+			}
+			fmt.Fprintf(w, "    ✓ %s (%s) %s → %s\n", r.Container, image, shortID(r.OldImageID), shortID(r.NewImageID))
+		}
+		for _, f := range c.Failures {
+			fmt.Fprintf(w, "    ✗ %s [%s] %s\n", f.Container, f.Category, f.Error)
+		}
+	}
+}
+
+// shortID trims "sha256:" and shortens an image ID for display
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
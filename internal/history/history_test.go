@@ -0,0 +1,78 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "history.jsonl")
+
+	for i, id := range []string{"a", "b", "c"} {
+		c := Cycle{ID: id, StartedAt: time.Date(2026, 1, 1, i, 0, 0, 0, time.UTC), Checked: i}
+		if err := Append(path, c); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	cycles, err := Read(path, 0)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(cycles) != 3 || cycles[0].ID != "c" || cycles[2].ID != "a" {
+		t.Errorf("Read() = %+v, want newest first", cycles)
+	}
+
+	limited, _ := Read(path, 2)
+	if len(limited) != 2 || limited[0].ID != "c" {
+		t.Errorf("Read(limit=2) = %+v", limited)
+	}
+}
+
+func TestRead_MissingAndCorrupt(t *testing.T) {
+	dir := t.TempDir()
+
+	cycles, err := Read(filepath.Join(dir, "missing.jsonl"), 10)
+	if err != nil || len(cycles) != 0 {
+		t.Errorf("missing journal should be empty, got %v, %v", cycles, err)
+	}
+
+	path := filepath.Join(dir, "history.jsonl")
+	content := `{"id":"ok1"}` + "\n" + `{"id":"trunc` + "\n" + `{"id":"ok2"}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cycles, err = Read(path, 0)
+	if err != nil || len(cycles) != 2 {
+		t.Errorf("corrupt lines should be skipped, got %+v, %v", cycles, err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	var buf bytes.Buffer
+	Format(&buf, nil)
+	if !strings.Contains(buf.String(), "No update cycles") {
+		t.Errorf("empty output = %q", buf.String())
+	}
+
+	buf.Reset()
+	Format(&buf, []Cycle{{
+		ID:          "abc123",
+		Checked:     4,
+		Pulled:      []string{"nginx:1.26"},
+		PulledBytes: 3 << 20,
+		Replaced:    []Replacement{{Container: "web", Image: "nginx:1.25", Target: "nginx:1.26", OldImageID: "sha256:0123456789abcdef", NewImageID: "sha256:fedcba9876543210"}},
+		Failures:    []Failure{{Container: "db", Category: "pull", Error: "not found"}},
+	}})
+
+	out := buf.String()
+	for _, want := range []string{"cycle abc123", "4 checked, 1 pulled (3.00 MB), 1 replaced, 1 failed", "web (nginx:1.25 → nginx:1.26) 0123456789ab → fedcba987654", "db [pull] not found"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
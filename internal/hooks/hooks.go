@@ -0,0 +1,114 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Hook names, exported to commands as HARBORBUDDY_HOOK
+const (
+	PreCycle   = "pre_cycle"
+	PostCycle  = "post_cycle"
+	PreUpdate  = "pre_update"
+	PostUpdate = "post_update"
+)
+
+// Env describes what a hook runs for. Empty fields are not exported.
+type Env struct {
+	CycleID     string
+	Container   string
+	ContainerID string
+	Image       string
+	OldImageID  string
+	NewImageID  string
+	Outcome     string // post_update and post_cycle: "success" or "failure"
+	Error       string
+
+	// post_cycle counts
+	Updated   int
+	Restarted int // Dependents recreated or restarted alongside an update, not updated themselves
+	Failed    int
+}
+
+// vars renders the environment as HARBORBUDDY_* variables, in a stable order
+func (e Env) vars(hook string) []string {
+	values := map[string]string{
+		"HARBORBUDDY_HOOK":           hook,
+		"HARBORBUDDY_CYCLE_ID":       e.CycleID,
+		"HARBORBUDDY_CONTAINER_NAME": e.Container,
+		"HARBORBUDDY_CONTAINER_ID":   e.ContainerID,
+		"HARBORBUDDY_IMAGE":          e.Image,
+		"HARBORBUDDY_OLD_IMAGE_ID":   e.OldImageID,
+		"HARBORBUDDY_NEW_IMAGE_ID":   e.NewImageID,
+		"HARBORBUDDY_OUTCOME":        e.Outcome,
+		"HARBORBUDDY_ERROR":          e.Error,
+	}
+	if hook == PostCycle {
+		values["HARBORBUDDY_UPDATED"] = fmt.Sprint(e.Updated)
+		values["HARBORBUDDY_RESTARTED"] = fmt.Sprint(e.Restarted)
+		values["HARBORBUDDY_FAILED"] = fmt.Sprint(e.Failed)
+	}
+
+	vars := make([]string, 0, len(values))
+	for key, val := range values {
+		if val != "" {
+			vars = append(vars, key+"="+val)
+		}
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// Run executes command with "sh -c" on the HarborBuddy host, with env added to the process
+// environment. It is a no-op for an empty command. A non-zero exit status, or running
+// longer than timeout, is returned as an error.
+func Run(ctx context.Context, hook, command string, env Env, timeout time.Duration, logger *zerolog.Logger) error {
+	if command == "" {
+		return nil
+	}
+
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(hookCtx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env.vars(hook)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Background children can hold the output pipe open after sh is killed; don't wait for them forever
+	cmd.WaitDelay = time.Second
+
+	logger.Debug().
+		Str("hook", hook).
+		Str("command", command).
+		Msg("Running hook")
+
+	start := time.Now()
+	err := cmd.Run()
+
+	event := logger.Debug()
+	if err != nil {
+		event = logger.Warn()
+	}
+	event.
+		Str("hook", hook).
+		Dur("duration", time.Since(start)).
+		Str("output", strings.TrimSpace(output.String())).
+		Msg("Hook finished")
+
+	if err != nil {
+		if hookCtx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s hook did not finish within %v", hook, timeout)
+		}
+		return fmt.Errorf("%s hook failed: %w", hook, err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRun_Environment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	logger := zerolog.Nop()
+
+	env := Env{
+		CycleID:    "abcd1234",
+		Container:  "web",
+		Image:      "nginx:1.27",
+		NewImageID: "sha256:new",
+		Outcome:    "success",
+	}
+	if err := Run(context.Background(), PostUpdate, "env > "+out, env, time.Minute, &logger); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"HARBORBUDDY_HOOK=post_update",
+		"HARBORBUDDY_CYCLE_ID=abcd1234",
+		"HARBORBUDDY_CONTAINER_NAME=web",
+		"HARBORBUDDY_IMAGE=nginx:1.27",
+		"HARBORBUDDY_NEW_IMAGE_ID=sha256:new",
+		"HARBORBUDDY_OUTCOME=success",
+	} {
+		if !strings.Contains(string(data), want+"\n") {
+			t.Errorf("hook environment is missing %s", want)
+		}
+	}
+	if strings.Contains(string(data), "HARBORBUDDY_OLD_IMAGE_ID=") || strings.Contains(string(data), "HARBORBUDDY_UPDATED=") {
+		t.Error("hook environment should omit empty fields and post_cycle counts")
+	}
+}
+
+func TestRun_CycleCounts(t *testing.T) {
+	vars := Env{Updated: 2, Restarted: 1, Failed: 0}.vars(PostCycle)
+	joined := strings.Join(vars, " ")
+	if !strings.Contains(joined, "HARBORBUDDY_UPDATED=2") || !strings.Contains(joined, "HARBORBUDDY_RESTARTED=1") || !strings.Contains(joined, "HARBORBUDDY_FAILED=0") {
+		t.Errorf("vars(post_cycle) = %v, want update, restart and failure counts", vars)
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	logger := zerolog.Nop()
+
+	if err := Run(context.Background(), PreCycle, "", Env{}, time.Minute, &logger); err != nil {
+		t.Errorf("Run() with no command error = %v, want nil", err)
+	}
+
+	err := Run(context.Background(), PreUpdate, "echo busy; exit 3", Env{}, time.Minute, &logger)
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("Run() error = %v, want the exit status", err)
+	}
+
+	err = Run(context.Background(), PreUpdate, "exec sleep 5", Env{}, 50*time.Millisecond, &logger)
+	if err == nil || !strings.Contains(err.Error(), "did not finish within") {
+		t.Errorf("Run() error = %v, want a timeout error", err)
+	}
+}
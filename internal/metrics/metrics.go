@@ -0,0 +1,364 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxContainerSeries caps how many containers get their own labelled series
+const DefaultMaxContainerSeries = 100
+
+// cycleDurationBuckets are the histogram upper bounds (seconds) for cycle durations.
+// Cycles range from sub-second no-ops to long multi-container pulls.
+var cycleDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800}
+
+// pullBytesBuckets are the histogram upper bounds (bytes) for what one pull downloads,
+// from a single small layer to multi-gigabyte ML images
+var pullBytesBuckets = []float64{1 << 20, 10 << 20, 50 << 20, 100 << 20, 250 << 20, 500 << 20, 1 << 30, 2 << 30, 5 << 30}
+
+// containerState holds the per-container gauges
+type containerState struct {
+	image               string
+	updateAvailable     bool
+	lastUpdate          time.Time
+	consecutiveFailures int
+}
+
+// histogram is a minimal cumulative Prometheus-style histogram
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Registry holds HarborBuddy metrics and renders them in the Prometheus text format.
+// It is safe for concurrent use.
+type Registry struct {
+	mu sync.Mutex
+
+	perContainer       bool
+	maxContainerSeries int
+	droppedSeries      uint64
+
+	containers     map[string]*containerState
+	cycleDurations map[string]*histogram // keyed by cycle kind ("update", "cleanup")
+	lastCycle      map[string]time.Time  // completion time keyed by cycle kind
+	cycleFailures  map[string]uint64     // keyed by cycle kind
+	pullBytes      *histogram            // bytes downloaded per pull
+
+	containersChecked     uint64
+	updatesApplied        uint64
+	pulls                 uint64
+	pullCacheHits         uint64
+	imagesRemoved         uint64
+	cleanupBytesReclaimed int64
+}
+
+// NewRegistry creates an empty registry with per-container series enabled
+func NewRegistry() *Registry {
+	return &Registry{
+		perContainer:       true,
+		maxContainerSeries: DefaultMaxContainerSeries,
+		containers:         make(map[string]*containerState),
+		cycleDurations:     make(map[string]*histogram),
+		lastCycle:          make(map[string]time.Time),
+		cycleFailures:      make(map[string]uint64),
+		pullBytes:          newHistogram(pullBytesBuckets),
+	}
+}
+
+// Default is the process-wide registry used by the updater and cleanup packages
+var Default = NewRegistry()
+
+// Configure sets the label cardinality controls.
+// Disabling per-container series drops any series already recorded.
+func (r *Registry) Configure(perContainer bool, maxContainerSeries int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.perContainer = perContainer
+	r.maxContainerSeries = maxContainerSeries
+	if !perContainer {
+		r.containers = make(map[string]*containerState)
+	}
+}
+
+// container returns the state for a container, creating it if the series budget allows.
+// Must be called with r.mu held. Returns nil when the series is not tracked.
+func (r *Registry) container(name, image string) *containerState {
+	if !r.perContainer || name == "" {
+		return nil
+	}
+
+	if state, ok := r.containers[name]; ok {
+		state.image = image
+		return state
+	}
+
+	if r.maxContainerSeries > 0 && len(r.containers) >= r.maxContainerSeries {
+		r.droppedSeries++
+		return nil
+	}
+
+	state := &containerState{image: image}
+	r.containers[name] = state
+	return state
+}
+
+// RecordCheck records the result of a successful update check for a container
+func (r *Registry) RecordCheck(name, image string, updateAvailable bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if state := r.container(name, image); state != nil {
+		state.updateAvailable = updateAvailable
+		state.consecutiveFailures = 0
+	}
+}
+
+// RecordUpdate records a successful container update
+func (r *Registry) RecordUpdate(name, image string, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.updatesApplied++
+
+	if state := r.container(name, image); state != nil {
+		state.updateAvailable = false
+		state.lastUpdate = at
+		state.consecutiveFailures = 0
+	}
+}
+
+// RecordFailure records a failed check or update for a container
+func (r *Registry) RecordFailure(name, image string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if state := r.container(name, image); state != nil {
+		state.consecutiveFailures++
+	}
+}
+
+// RetainContainers drops series for containers not in the given set.
+// Called at the end of a cycle so removed containers don't linger on dashboards.
+func (r *Registry) RetainContainers(names []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keep := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		keep[name] = struct{}{}
+	}
+
+	for name := range r.containers {
+		if _, ok := keep[name]; !ok {
+			delete(r.containers, name)
+		}
+	}
+}
+
+// IncContainersChecked counts a container checked for updates
+func (r *Registry) IncContainersChecked() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.containersChecked++
+}
+
+// IncPulls counts an image pull sent to the registry
+func (r *Registry) IncPulls() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pulls++
+}
+
+// ObservePull records how many bytes one pull downloaded
+func (r *Registry) ObservePull(downloaded int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pullBytes.observe(float64(downloaded))
+}
+
+// IncPullCacheHits counts a pull served from the per-cycle pull cache
+func (r *Registry) IncPullCacheHits() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pullCacheHits++
+}
+
+// RecordImageRemoved counts an image removed by cleanup and the space it freed
+func (r *Registry) RecordImageRemoved(size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.imagesRemoved++
+	r.cleanupBytesReclaimed += size
+}
+
+// RecordCycleFailure counts a cycle of the given kind that aborted with an error
+func (r *Registry) RecordCycleFailure(kind string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cycleFailures[kind]++
+}
+
+// ObserveCycle records the duration and completion time of a cycle of the given kind
+func (r *Registry) ObserveCycle(kind string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastCycle[kind] = time.Now()
+
+	h, ok := r.cycleDurations[kind]
+	if !ok {
+		h = newHistogram(cycleDurationBuckets)
+		r.cycleDurations[kind] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// Write renders all metrics in the Prometheus text exposition format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+
+	names := make([]string, 0, len(r.containers))
+	for name := range r.containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeHeader(&b, "harborbuddy_container_update_available", "gauge", "Whether a newer image is available for the container (1) or not (0).")
+	for _, name := range names {
+		state := r.containers[name]
+		fmt.Fprintf(&b, "harborbuddy_container_update_available{%s} %d\n", containerLabels(name, state.image), boolToInt(state.updateAvailable))
+	}
+
+	writeHeader(&b, "harborbuddy_container_last_update_timestamp_seconds", "gauge", "Unix time of the last successful update of the container.")
+	for _, name := range names {
+		state := r.containers[name]
+		if state.lastUpdate.IsZero() {
+			continue
+		}
+		fmt.Fprintf(&b, "harborbuddy_container_last_update_timestamp_seconds{%s} %d\n", containerLabels(name, state.image), state.lastUpdate.Unix())
+	}
+
+	writeHeader(&b, "harborbuddy_container_consecutive_failures", "gauge", "Number of consecutive failed checks or updates for the container.")
+	for _, name := range names {
+		state := r.containers[name]
+		fmt.Fprintf(&b, "harborbuddy_container_consecutive_failures{%s} %d\n", containerLabels(name, state.image), state.consecutiveFailures)
+	}
+
+	writeHeader(&b, "harborbuddy_metrics_dropped_container_series_total", "counter", "Container series not recorded because max_container_series was reached.")
+	fmt.Fprintf(&b, "harborbuddy_metrics_dropped_container_series_total %d\n", r.droppedSeries)
+
+	writeCounter(&b, "harborbuddy_containers_checked_total", "Containers checked for updates.", r.containersChecked)
+	writeCounter(&b, "harborbuddy_updates_applied_total", "Containers successfully updated.", r.updatesApplied)
+	writeCounter(&b, "harborbuddy_image_pulls_total", "Image pulls sent to registries.", r.pulls)
+	writeCounter(&b, "harborbuddy_pull_cache_hits_total", "Pulls served from the per-cycle pull cache.", r.pullCacheHits)
+	writeCounter(&b, "harborbuddy_cleanup_images_removed_total", "Images removed by cleanup.", r.imagesRemoved)
+	writeCounter(&b, "harborbuddy_cleanup_reclaimed_bytes_total", "Bytes reclaimed by cleanup.", uint64(r.cleanupBytesReclaimed))
+
+	writeHeader(&b, "harborbuddy_cycle_failures_total", "counter", "Cycles that aborted with an error.")
+	for _, kind := range sortedKeys(r.cycleFailures) {
+		fmt.Fprintf(&b, "harborbuddy_cycle_failures_total{kind=%q} %d\n", kind, r.cycleFailures[kind])
+	}
+
+	writeHeader(&b, "harborbuddy_last_cycle_timestamp_seconds", "gauge", "Unix time the last cycle of each kind completed.")
+	for _, kind := range sortedKeys(r.lastCycle) {
+		fmt.Fprintf(&b, "harborbuddy_last_cycle_timestamp_seconds{kind=%q} %d\n", kind, r.lastCycle[kind].Unix())
+	}
+
+	kinds := sortedKeys(r.cycleDurations)
+
+	writeHeader(&b, "harborbuddy_cycle_duration_seconds", "histogram", "Duration of update and cleanup cycles.")
+	for _, kind := range kinds {
+		h := r.cycleDurations[kind]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(&b, "harborbuddy_cycle_duration_seconds_bucket{kind=%q,le=%q} %d\n", kind, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(&b, "harborbuddy_cycle_duration_seconds_bucket{kind=%q,le=\"+Inf\"} %d\n", kind, h.count)
+		fmt.Fprintf(&b, "harborbuddy_cycle_duration_seconds_sum{kind=%q} %s\n", kind, formatFloat(h.sum))
+		fmt.Fprintf(&b, "harborbuddy_cycle_duration_seconds_count{kind=%q} %d\n", kind, h.count)
+	}
+
+	writeHeader(&b, "harborbuddy_image_pull_downloaded_bytes", "histogram", "Bytes downloaded by each image pull.")
+	for i, bound := range r.pullBytes.buckets {
+		fmt.Fprintf(&b, "harborbuddy_image_pull_downloaded_bytes_bucket{le=%q} %d\n", formatFloat(bound), r.pullBytes.counts[i])
+	}
+	fmt.Fprintf(&b, "harborbuddy_image_pull_downloaded_bytes_bucket{le=\"+Inf\"} %d\n", r.pullBytes.count)
+	fmt.Fprintf(&b, "harborbuddy_image_pull_downloaded_bytes_sum %s\n", formatFloat(r.pullBytes.sum))
+	fmt.Fprintf(&b, "harborbuddy_image_pull_downloaded_bytes_count %d\n", r.pullBytes.count)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := r.Write(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, kind)
+}
+
+func writeCounter(b *strings.Builder, name, help string, value uint64) {
+	writeHeader(b, name, "counter", help)
+	fmt.Fprintf(b, "%s %d\n", name, value)
+}
+
+// sortedKeys returns the keys of a kind-keyed map in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containerLabels renders the label set for a container series.
+// Only the name and image reference are used; IDs change on every update and would explode cardinality.
+func containerLabels(name, image string) string {
+	return fmt.Sprintf("container=%q,image=%q", name, image)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func formatFloat(f float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", f), "0"), ".")
+}
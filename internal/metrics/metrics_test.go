@@ -0,0 +1,242 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestRegistry_ContainerGauges(t *testing.T) {
+	r := NewRegistry()
+
+	r.RecordCheck("nginx", "nginx:latest", true)
+	r.RecordFailure("redis", "redis:7")
+	r.RecordFailure("redis", "redis:7")
+	r.RecordUpdate("web", "app:latest", time.Unix(1700000000, 0))
+
+	out := render(t, r)
+
+	expected := []string{
+		`harborbuddy_container_update_available{container="nginx",image="nginx:latest"} 1`,
+		`harborbuddy_container_update_available{container="web",image="app:latest"} 0`,
+		`harborbuddy_container_consecutive_failures{container="redis",image="redis:7"} 2`,
+		`harborbuddy_container_last_update_timestamp_seconds{container="web",image="app:latest"} 1700000000`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+
+	// Containers that were never updated must not report a zero timestamp
+	if strings.Contains(out, `harborbuddy_container_last_update_timestamp_seconds{container="nginx"`) {
+		t.Error("never-updated container should not have a last update timestamp")
+	}
+
+	if t.Failed() {
+		t.Logf("Output:\n%s", out)
+	}
+}
+
+func TestRegistry_SuccessResetsFailures(t *testing.T) {
+	r := NewRegistry()
+
+	r.RecordFailure("nginx", "nginx:latest")
+	r.RecordFailure("nginx", "nginx:latest")
+	r.RecordCheck("nginx", "nginx:latest", false)
+
+	out := render(t, r)
+	if !strings.Contains(out, `harborbuddy_container_consecutive_failures{container="nginx",image="nginx:latest"} 0`) {
+		t.Errorf("successful check should reset failures, got:\n%s", out)
+	}
+}
+
+func TestRegistry_CardinalityCap(t *testing.T) {
+	r := NewRegistry()
+	r.Configure(true, 2)
+
+	r.RecordCheck("a", "img", false)
+	r.RecordCheck("b", "img", false)
+	r.RecordCheck("c", "img", false) // over budget
+	r.RecordCheck("a", "img", true)  // existing series still updates
+
+	out := render(t, r)
+	if strings.Contains(out, `container="c"`) {
+		t.Error("series over the cap should be dropped")
+	}
+	if !strings.Contains(out, `harborbuddy_container_update_available{container="a",image="img"} 1`) {
+		t.Error("existing series should keep updating when at the cap")
+	}
+	if !strings.Contains(out, "harborbuddy_metrics_dropped_container_series_total 1") {
+		t.Error("dropped series should be counted")
+	}
+	if t.Failed() {
+		t.Logf("Output:\n%s", out)
+	}
+}
+
+func TestRegistry_PerContainerDisabled(t *testing.T) {
+	r := NewRegistry()
+	r.RecordCheck("nginx", "nginx:latest", true)
+	r.Configure(false, 0)
+	r.RecordCheck("redis", "redis:7", true)
+
+	out := render(t, r)
+	if strings.Contains(out, "container=") {
+		t.Errorf("no per-container series expected when disabled, got:\n%s", out)
+	}
+}
+
+func TestRegistry_RetainContainers(t *testing.T) {
+	r := NewRegistry()
+	r.RecordCheck("keep", "img", false)
+	r.RecordCheck("gone", "img", false)
+
+	r.RetainContainers([]string{"keep"})
+
+	out := render(t, r)
+	if strings.Contains(out, `container="gone"`) {
+		t.Error("containers not retained should be removed")
+	}
+	if !strings.Contains(out, `container="keep"`) {
+		t.Error("retained container should still be present")
+	}
+}
+
+func TestRegistry_CycleHistogram(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveCycle("update", 3*time.Second)
+	r.ObserveCycle("update", 45*time.Second)
+	r.ObserveCycle("cleanup", 500*time.Millisecond)
+
+	out := render(t, r)
+
+	expected := []string{
+		`harborbuddy_cycle_duration_seconds_bucket{kind="update",le="1"} 0`,
+		`harborbuddy_cycle_duration_seconds_bucket{kind="update",le="5"} 1`,
+		`harborbuddy_cycle_duration_seconds_bucket{kind="update",le="60"} 2`,
+		`harborbuddy_cycle_duration_seconds_bucket{kind="update",le="+Inf"} 2`,
+		`harborbuddy_cycle_duration_seconds_sum{kind="update"} 48`,
+		`harborbuddy_cycle_duration_seconds_count{kind="update"} 2`,
+		`harborbuddy_cycle_duration_seconds_bucket{kind="cleanup",le="1"} 1`,
+		`harborbuddy_cycle_duration_seconds_sum{kind="cleanup"} 0.5`,
+		"# TYPE harborbuddy_cycle_duration_seconds histogram",
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Logf("Output:\n%s", out)
+	}
+}
+
+func TestRegistry_PullHistogram(t *testing.T) {
+	r := NewRegistry()
+	r.ObservePull(0)
+	r.ObservePull(30 << 20)
+
+	out := render(t, r)
+
+	expected := []string{
+		`harborbuddy_image_pull_downloaded_bytes_bucket{le="1048576"} 1`,
+		`harborbuddy_image_pull_downloaded_bytes_bucket{le="52428800"} 2`,
+		`harborbuddy_image_pull_downloaded_bytes_bucket{le="+Inf"} 2`,
+		`harborbuddy_image_pull_downloaded_bytes_sum 31457280`,
+		`harborbuddy_image_pull_downloaded_bytes_count 2`,
+		"# TYPE harborbuddy_image_pull_downloaded_bytes histogram",
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Logf("Output:\n%s", out)
+	}
+}
+
+func TestRegistry_LabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.RecordCheck(`we"ird`, "img", false)
+
+	out := render(t, r)
+	if !strings.Contains(out, `container="we\"ird"`) {
+		t.Errorf("label values should be escaped, got:\n%s", out)
+	}
+}
+
+func TestRegistry_Counters(t *testing.T) {
+	r := NewRegistry()
+	r.IncContainersChecked()
+	r.IncContainersChecked()
+	r.IncPulls()
+	r.IncPullCacheHits()
+	r.RecordUpdate("web", "app:latest", time.Now())
+	r.RecordImageRemoved(1024)
+	r.RecordImageRemoved(2048)
+	r.RecordCycleFailure("update")
+	r.ObserveCycle("cleanup", time.Second)
+
+	out := render(t, r)
+
+	expected := []string{
+		"harborbuddy_containers_checked_total 2",
+		"harborbuddy_updates_applied_total 1",
+		"harborbuddy_image_pulls_total 1",
+		"harborbuddy_pull_cache_hits_total 1",
+		"harborbuddy_cleanup_images_removed_total 2",
+		"harborbuddy_cleanup_reclaimed_bytes_total 3072",
+		`harborbuddy_cycle_failures_total{kind="update"} 1`,
+		`harborbuddy_last_cycle_timestamp_seconds{kind="cleanup"} `,
+		"# TYPE harborbuddy_updates_applied_total counter",
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Logf("Output:\n%s", out)
+	}
+}
+
+func TestRegistry_UpdateCounterIgnoresSeriesCap(t *testing.T) {
+	r := NewRegistry()
+	r.Configure(false, 0)
+	r.RecordUpdate("web", "app:latest", time.Now())
+
+	if out := render(t, r); !strings.Contains(out, "harborbuddy_updates_applied_total 1") {
+		t.Errorf("updates must be counted even without per-container series:\n%s", out)
+	}
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.IncPulls()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if rec.Code != 200 {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "harborbuddy_image_pulls_total 1") {
+		t.Errorf("body missing pull counter:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+const defaultEmailSubject = `HarborBuddy on {{.Hostname}}: {{len .Updated}} updated{{if .Available}}, {{len .Available}} available{{end}}{{if .Failed}}, {{len .Failed}} failed{{end}}`
+
+const defaultEmailBody = `HarborBuddy cycle {{.CycleID}} on {{.Hostname}}
+{{if .Updated}}
+Updated:
+{{range .Updated}}  - {{.Container}} ({{.Image}}) {{.ShortOldImageID}} -> {{.ShortNewImageID}}{{with .VersionChange}}, version {{.}}{{end}}
+{{with .ReleaseURL}}    {{.}}
+{{end}}{{end}}{{end}}{{if .Available}}
+Update available (not applied):
+{{range .Available}}  - {{.Container}} ({{.Image}}) {{.ShortNewImageID}}{{with .NewVersion}}, version {{.}}{{end}}
+{{with .ReleaseURL}}    {{.}}
+{{end}}{{end}}{{end}}{{if .Failed}}
+Failed:
+{{range .Failed}}  - {{if .Container}}{{.Container}} ({{.Image}}): {{end}}{{.Error}}
+{{with .Logs}}    Last log lines:
+{{.}}
+{{end}}{{end}}{{end}}{{with .Cleanup}}
+Cleanup: {{.ImagesRemoved}} images{{if .ContainersRemoved}}, {{.ContainersRemoved}} stopped containers{{end}}{{if .VolumesRemoved}}, {{.VolumesRemoved}} volumes{{end}}{{if .NetworksRemoved}}, {{.NetworksRemoved}} networks{{end}} removed, {{.BytesReclaimed}} bytes reclaimed
+{{end}}`
+
+// Summary is the data available to email subject and body templates
+type Summary struct {
+	CycleID   string
+	Hostname  string
+	Events    []Event // Every event, in the order it happened
+	Updated   []Event
+	Available []Event
+	Failed    []Event
+	Cleanup   *Event
+}
+
+// EmailNotifier collects a cycle's events and sends them as one summary email on Flush
+type EmailNotifier struct {
+	cfg     config.EmailConfig
+	timeout time.Duration
+	subject *template.Template
+	body    *template.Template
+
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewEmail creates an email notifier. Templates are validated with the configuration, so a
+// parse error here falls back to the built-in template.
+func NewEmail(cfg config.EmailConfig, timeout time.Duration) *EmailNotifier {
+	return &EmailNotifier{
+		cfg:     cfg,
+		timeout: timeout,
+		subject: parseTemplate("subject", cfg.Subject, defaultEmailSubject),
+		body:    parseTemplate("body", cfg.Body, defaultEmailBody),
+	}
+}
+
+func parseTemplate(name, text, fallback string) *template.Template {
+	if text != "" {
+		if t, err := template.New(name).Parse(text); err == nil {
+			return t
+		}
+	}
+	return template.Must(template.New(name).Parse(fallback))
+}
+
+// Notify queues the event for the cycle summary
+func (e *EmailNotifier) Notify(ctx context.Context, event Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+	return nil
+}
+
+// Flush sends the queued events as one email. Nothing is sent if no events were queued.
+func (e *EmailNotifier) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	events := e.events
+	e.events = nil
+	e.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	msg, err := e.render(summarize(events))
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, msg)
+}
+
+// summarize groups events by type for the templates
+func summarize(events []Event) Summary {
+	s := Summary{Events: events}
+	s.Hostname, _ = os.Hostname()
+	for i, event := range events {
+		if s.CycleID == "" {
+			s.CycleID = event.CycleID
+		}
+		switch event.Type {
+		case EventUpdate:
+			s.Updated = append(s.Updated, event)
+		case EventUpdateAvailable:
+			s.Available = append(s.Available, event)
+		case EventFailure:
+			s.Failed = append(s.Failed, event)
+		case EventCleanup:
+			s.Cleanup = &events[i]
+		}
+	}
+	return s
+}
+
+// render builds the RFC 5322 message
+func (e *EmailNotifier) render(s Summary) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := e.subject.Execute(&subject, s); err != nil {
+		return nil, fmt.Errorf("failed to render email subject: %w", err)
+	}
+	if err := e.body.Execute(&body, s); err != nil {
+		return nil, fmt.Errorf("failed to render email body: %w", err)
+	}
+
+	// Headers must stay on one line
+	oneLine := strings.NewReplacer("\r", " ", "\n", " ")
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", oneLine.Replace(e.cfg.From))
+	fmt.Fprintf(&msg, "To: %s\r\n", oneLine.Replace(strings.Join(e.cfg.To, ", ")))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.TrimSpace(oneLine.Replace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body.String(), "\r\n", "\n"), "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
+
+// send delivers the message over SMTP
+func (e *EmailNotifier) send(ctx context.Context, msg []byte) error {
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: e.cfg.Host}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	var conn net.Conn
+	var err error
+	if e.cfg.TLS == config.EmailTLSImplicit {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer c.Close()
+
+	if e.cfg.TLS == config.EmailTLSStartTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to STARTTLS: %w", err)
+		}
+	}
+
+	if e.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(e.cfg.From); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %w", err)
+	}
+	for _, to := range e.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return c.Quit()
+}
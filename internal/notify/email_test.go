@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+// fakeSMTP accepts one plaintext SMTP session and returns the recipients and message it received
+func fakeSMTP(t *testing.T) (host string, port int, received <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var session strings.Builder
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 fake")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				session.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if data == ".\r\n" {
+						break
+					}
+					session.WriteString(data)
+				}
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				out <- session.String()
+				return
+			default:
+				reply("502 unsupported")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, out
+}
+
+func TestEmailNotifier_BatchesCycle(t *testing.T) {
+	host, port, received := fakeSMTP(t)
+
+	e := NewEmail(config.EmailConfig{
+		Host: host,
+		Port: port,
+		From: "harborbuddy@example.com",
+		To:   []string{"ops@example.com", "dev@example.com"},
+		TLS:  config.EmailTLSNone,
+	}, 5*time.Second)
+
+	ctx := context.Background()
+	events := []Event{
+		{Type: EventUpdate, CycleID: "abcd1234", Container: "web", Image: "nginx:latest", OldImageID: "sha256:0123456789abcdef", NewImageID: "sha256:fedcba9876543210"},
+		{Type: EventFailure, CycleID: "abcd1234", Container: "db", Image: "postgres:16", Error: "pull failed"},
+		{Type: EventCleanup, CycleID: "abcd1234", ImagesRemoved: 3, BytesReclaimed: 1024},
+	}
+	for _, event := range events {
+		if err := e.Notify(ctx, event); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+
+	if err := e.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var session string
+	select {
+	case session = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email received")
+	}
+
+	for _, want := range []string{
+		"MAIL FROM:<harborbuddy@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<dev@example.com>",
+		"updated, 1 failed\r\n",
+		"  - web (nginx:latest) 0123456789ab -> fedcba987654\r\n",
+		"  - db (postgres:16): pull failed\r\n",
+		"Cleanup: 3 images removed",
+	} {
+		if !strings.Contains(session, want) {
+			t.Errorf("email is missing %q:\n%s", want, session)
+		}
+	}
+
+	// The queue is drained, so a second flush sends nothing
+	if err := e.Flush(ctx); err != nil {
+		t.Errorf("second Flush() error = %v, want nil with nothing queued", err)
+	}
+}
+
+func TestEmailNotifier_Templates(t *testing.T) {
+	e := NewEmail(config.EmailConfig{
+		From:    "harborbuddy@example.com",
+		To:      []string{"ops@example.com"},
+		Subject: "[{{.CycleID}}]\n{{len .Updated}} updates",
+		Body:    "{{range .Updated}}{{.Container}}\n{{end}}",
+	}, time.Second)
+
+	msg, err := e.render(summarize([]Event{
+		{Type: EventUpdate, CycleID: "abcd1234", Container: "web"},
+		{Type: EventUpdate, Container: "api"},
+	}))
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	got := string(msg)
+	if !strings.Contains(got, "Subject: [abcd1234] 2 updates\r\n") {
+		t.Errorf("subject not rendered on one line:\n%s", got)
+	}
+	if !strings.HasSuffix(got, "\r\n\r\nweb\r\napi\r\n") {
+		t.Errorf("body not rendered with CRLF line endings:\n%s", got)
+	}
+}
+
+func TestEmailNotifier_ConnectionError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	e := NewEmail(config.EmailConfig{
+		Host: "127.0.0.1",
+		Port: port,
+		From: "harborbuddy@example.com",
+		To:   []string{"ops@example.com"},
+		TLS:  config.EmailTLSNone,
+	}, time.Second)
+	_ = e.Notify(context.Background(), Event{Type: EventUpdate})
+
+	err = e.Flush(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "127.0.0.1:"+strconv.Itoa(port)) {
+		t.Errorf("Flush() error = %v, want a connection error naming the server", err)
+	}
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+// defaultGotifyPriorities follow Gotify's convention that 8 and above is urgent
+var defaultGotifyPriorities = map[EventType]int{
+	EventUpdate:          5,
+	EventUpdateAvailable: 4,
+	EventFailure:         8,
+	EventCleanup:         2,
+}
+
+// GotifyNotifier pushes events to a Gotify server as messages
+type GotifyNotifier struct {
+	*pushNotifier
+	cfg    config.GotifyConfig
+	client *http.Client
+}
+
+// NewGotify creates a Gotify notifier with the given request timeout
+func NewGotify(cfg config.GotifyConfig, timeout time.Duration) *GotifyNotifier {
+	g := &GotifyNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+	g.pushNotifier = newPush(cfg.MessageTemplates, g.push)
+	return g
+}
+
+// push posts a message and fails on any non-2xx response
+func (g *GotifyNotifier) push(ctx context.Context, msg pushed) error {
+	return postJSON(ctx, g.client, "gotify", strings.TrimSuffix(g.cfg.URL, "/")+"/message", map[string]interface{}{
+		"title":    msg.Title,
+		"message":  msg.Message,
+		"priority": priorityFor(msg.Type, g.cfg.Priorities, defaultGotifyPriorities),
+	}, http.Header{"X-Gotify-Key": {g.cfg.Token}})
+}
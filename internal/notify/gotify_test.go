@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+func TestGotifyNotifier_Notify(t *testing.T) {
+	var path, token string
+	var received struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		token = r.Header.Get("X-Gotify-Key")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	g := NewGotify(config.GotifyConfig{URL: server.URL + "/", Token: "app-token"}, time.Second)
+	event := Event{Type: EventFailure, Container: "web", Image: "nginx:latest", Error: "pull failed"}
+	if err := g.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if path != "/message" {
+		t.Errorf("path = %q, want /message", path)
+	}
+	if token != "app-token" {
+		t.Errorf("X-Gotify-Key = %q, want app-token", token)
+	}
+	if received.Title != "Failed to update web" || !strings.Contains(received.Message, "pull failed") {
+		t.Errorf("received %+v, want the failure for web", received)
+	}
+	if received.Priority != 8 {
+		t.Errorf("priority = %d, want the default failure priority 8", received.Priority)
+	}
+}
+
+func TestGotifyNotifier_Priorities(t *testing.T) {
+	var received struct {
+		Priority int `json:"priority"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	g := NewGotify(config.GotifyConfig{URL: server.URL, Token: "t", Priorities: map[string]int{"update": 0}}, time.Second)
+
+	tests := []struct {
+		event EventType
+		want  int
+	}{
+		{EventUpdate, 0},
+		{EventUpdateAvailable, 4},
+		{EventCleanup, 2},
+	}
+	for _, tt := range tests {
+		if err := g.Notify(context.Background(), Event{Type: tt.event}); err != nil {
+			t.Fatalf("Notify(%s) error = %v", tt.event, err)
+		}
+		if received.Priority != tt.want {
+			t.Errorf("priority for %s = %d, want %d", tt.event, received.Priority, tt.want)
+		}
+	}
+}
+
+func TestGotifyNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	err := NewGotify(config.GotifyConfig{URL: server.URL, Token: "wrong"}, time.Second).Notify(context.Background(), Event{Type: EventUpdate})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Notify() error = %v, want 401 error", err)
+	}
+}
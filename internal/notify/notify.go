@@ -0,0 +1,279 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/rs/zerolog"
+)
+
+// EventType identifies what a notification is about
+type EventType string
+
+const (
+	EventUpdate          EventType = "update"
+	EventUpdateAvailable EventType = "update_available"
+	EventFailure         EventType = "failure"
+	EventCleanup         EventType = "cleanup"
+)
+
+// Outcome values reported in events
+const (
+	OutcomeSuccess       = "success"
+	OutcomeFailure       = "failure"
+	OutcomeNotApplied    = "not_applied"    // Update found but deliberately left alone (monitor-only)
+	OutcomeUnverified    = "unverified"     // Update found but its image signature could not be verified
+	OutcomeWrongPlatform = "wrong_platform" // Update found but its image is built for another OS or architecture
+	OutcomeVulnerable    = "vulnerable"     // Update found but its image has more critical vulnerabilities than scan.max_critical
+	OutcomeUnscanned     = "unscanned"      // Update found but its image could not be scanned for vulnerabilities
+	OutcomeLowDiskSpace  = "low_disk_space" // Update check skipped: the pull would leave less than updates.min_free_space free
+	OutcomeTimedOut      = "timed_out"      // The cycle ran past updates.cycle_timeout and was aborted
+	OutcomeCanaryFailed  = "canary_failed"  // A canary's update failed, so the cycle's other updates were aborted
+	OutcomeInterrupted   = "interrupted"    // A self-update stopped before it finished
+)
+
+// Event is the payload delivered to notifiers
+type Event struct {
+	Type       EventType `json:"event"`
+	Outcome    string    `json:"outcome"`
+	CycleID    string    `json:"cycle_id,omitempty"`
+	Container  string    `json:"container,omitempty"`
+	Image      string    `json:"image,omitempty"`
+	OldImageID string    `json:"old_image_id,omitempty"`
+	NewImageID string    `json:"new_image_id,omitempty"`
+	Target     string    `json:"target,omitempty"` // Newer digest suggested for a container pinned by digest
+	Error      string    `json:"error,omitempty"`
+	Logs       string    `json:"logs,omitempty"` // Last log lines of a container that failed its health check
+
+	// Release details from the images' OCI labels, when they carry them
+	OldVersion string `json:"old_version,omitempty"`
+	NewVersion string `json:"new_version,omitempty"`
+	ReleaseURL string `json:"release_url,omitempty"`
+
+	// Cleanup results
+	ImagesRemoved     int   `json:"images_removed,omitempty"`
+	ContainersRemoved int   `json:"containers_removed,omitempty"`
+	VolumesRemoved    int   `json:"volumes_removed,omitempty"`
+	NetworksRemoved   int   `json:"networks_removed,omitempty"`
+	BytesReclaimed    int64 `json:"bytes_reclaimed,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ShortOldImageID returns the old image ID without "sha256:", truncated for display
+func (e Event) ShortOldImageID() string { return shortImageID(e.OldImageID) }
+
+// ShortNewImageID returns the new image ID without "sha256:", truncated for display
+func (e Event) ShortNewImageID() string { return shortImageID(e.NewImageID) }
+
+// VersionChange describes the version move from the images' labels, e.g. "1.4.2 → 1.5.0", or
+// "" when the new image has no version label
+func (e Event) VersionChange() string {
+	if e.NewVersion == "" {
+		return ""
+	}
+	if e.OldVersion == "" || e.OldVersion == e.NewVersion {
+		return e.NewVersion
+	}
+	return e.OldVersion + " → " + e.NewVersion
+}
+
+// shortImageID trims "sha256:" and truncates to 12 characters
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// Notifier delivers events to an external system
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Flusher is implemented by notifiers that batch events until the end of a cycle
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// New builds the notifier described by the configuration.
+// It returns a no-op notifier when no destination is configured.
+func New(cfg config.NotificationsConfig) Notifier {
+	var targets multi
+	if cfg.WebhookURL != "" {
+		targets = append(targets, NewWebhook(cfg.WebhookURL, cfg.WebhookSecret, cfg.Timeout))
+	}
+	if cfg.Email.Host != "" {
+		targets = append(targets, NewEmail(cfg.Email, cfg.Timeout))
+	}
+	if cfg.Gotify.URL != "" {
+		gotify := cfg.Gotify
+		gotify.MessageTemplates = gotify.MessageTemplates.Or(cfg.Templates)
+		targets = append(targets, NewGotify(gotify, cfg.Timeout))
+	}
+	if cfg.Ntfy.Topic != "" {
+		ntfy := cfg.Ntfy
+		ntfy.MessageTemplates = ntfy.MessageTemplates.Or(cfg.Templates)
+		targets = append(targets, NewNtfy(ntfy, cfg.Timeout))
+	}
+	for _, raw := range cfg.URLs {
+		// URLs are checked at startup, so this only skips schemes registered too late
+		n, err := FromURL(raw, URLOptions{Timeout: cfg.Timeout, Templates: cfg.Templates, WebhookSecret: cfg.WebhookSecret})
+		if err != nil {
+			log.Warnf("Skipping notification URL: %v", err)
+			continue
+		}
+		targets = append(targets, n)
+	}
+
+	switch len(targets) {
+	case 0:
+		return nop{}
+	case 1:
+		return &filtered{cfg: cfg, next: targets[0]}
+	}
+	return &filtered{cfg: cfg, next: targets}
+}
+
+// Join returns a notifier delivering events to all of the given ones
+func Join(notifiers ...Notifier) Notifier {
+	return multi(notifiers)
+}
+
+// nop discards all events
+type nop struct{}
+
+func (nop) Notify(ctx context.Context, event Event) error { return nil }
+
+// multi delivers events to every destination
+type multi []Notifier
+
+func (m multi) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multi) Flush(ctx context.Context) error {
+	var errs []error
+	for _, n := range m {
+		if f, ok := n.(Flusher); ok {
+			if err := f.Flush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// filtered drops events whose type is disabled in the configuration, or that are below
+// notifications.level
+type filtered struct {
+	cfg  config.NotificationsConfig
+	next Notifier
+}
+
+func (f *filtered) Notify(ctx context.Context, event Event) error {
+	if !f.enabled(event.Type) || !f.atLevel(event) {
+		return nil
+	}
+	return f.next.Notify(ctx, event)
+}
+
+func (f *filtered) Flush(ctx context.Context) error {
+	if next, ok := f.next.(Flusher); ok {
+		return next.Flush(ctx)
+	}
+	return nil
+}
+
+func (f *filtered) enabled(t EventType) bool {
+	switch t {
+	case EventUpdate:
+		return f.cfg.OnUpdate
+	case EventUpdateAvailable:
+		return f.cfg.OnUpdateAvailable
+	case EventFailure:
+		return f.cfg.OnFailure
+	case EventCleanup:
+		return f.cfg.OnCleanup
+	}
+	return false
+}
+
+// atLevel reports whether event is worth sending at notifications.level: "errors" only lets
+// failures through, "changes" also updates, updates found and cleanups that removed something
+func (f *filtered) atLevel(event Event) bool {
+	switch f.cfg.Level {
+	case config.NotifyErrors:
+		return event.Type == EventFailure
+	case config.NotifyChanges:
+		if event.Type == EventCleanup {
+			return event.ImagesRemoved+event.ContainersRemoved+event.VolumesRemoved+event.NetworksRemoved > 0 || event.BytesReclaimed > 0
+		}
+	}
+	return true
+}
+
+// Send fills in the cycle ID and timestamp and delivers the event.
+// Delivery failures are logged rather than returned so they never fail a cycle.
+func Send(ctx context.Context, notifier Notifier, event Event, logger *zerolog.Logger) {
+	if event.CycleID == "" {
+		event.CycleID = CycleID(ctx)
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if err := notifier.Notify(ctx, event); err != nil {
+		logger.Warn().Err(err).Str("event", string(event.Type)).Msg("Failed to send notification")
+	}
+}
+
+// Flush delivers events batched by the notifier, if it batches any.
+// Like Send, failures are logged rather than returned.
+func Flush(ctx context.Context, notifier Notifier, logger *zerolog.Logger) {
+	f, ok := notifier.(Flusher)
+	if !ok {
+		return
+	}
+	if err := f.Flush(ctx); err != nil {
+		logger.Warn().Err(err).Msg("Failed to send notification summary")
+	}
+}
+
+type cycleIDKey struct{}
+
+type notifierKey struct{}
+
+// WithNotifier returns a context carrying the notifier shared by the phases of a cycle,
+// so batching notifiers can summarize the whole cycle
+func WithNotifier(ctx context.Context, n Notifier) context.Context {
+	return context.WithValue(ctx, notifierKey{}, n)
+}
+
+// FromContext returns the notifier stored in ctx, or nil if none
+func FromContext(ctx context.Context) Notifier {
+	n, _ := ctx.Value(notifierKey{}).(Notifier)
+	return n
+}
+
+// WithCycleID returns a context carrying the ID of the current cycle
+func WithCycleID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, cycleIDKey{}, id)
+}
+
+// CycleID returns the cycle ID stored in ctx, or "" if none
+func CycleID(ctx context.Context) string {
+	id, _ := ctx.Value(cycleIDKey{}).(string)
+	return id
+}
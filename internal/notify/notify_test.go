@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// recorder collects events for assertions
+type recorder struct {
+	events []Event
+	err    error
+}
+
+func (r *recorder) Notify(ctx context.Context, event Event) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestNew_NoDestination(t *testing.T) {
+	n := New(config.NotificationsConfig{OnUpdate: true})
+	if _, ok := n.(nop); !ok {
+		t.Errorf("New() without webhook_url = %T, want nop", n)
+	}
+}
+
+func TestFiltered(t *testing.T) {
+	rec := &recorder{}
+	f := &filtered{
+		cfg:  config.NotificationsConfig{OnUpdate: true, OnFailure: false, OnCleanup: true},
+		next: rec,
+	}
+
+	for _, typ := range []EventType{EventUpdate, EventUpdateAvailable, EventFailure, EventCleanup, "unknown"} {
+		if err := f.Notify(context.Background(), Event{Type: typ}); err != nil {
+			t.Fatalf("Notify(%s) error = %v", typ, err)
+		}
+	}
+
+	if len(rec.events) != 2 || rec.events[0].Type != EventUpdate || rec.events[1].Type != EventCleanup {
+		t.Errorf("delivered events = %v, want [update cleanup]", rec.events)
+	}
+}
+
+func TestFiltered_Level(t *testing.T) {
+	events := []Event{
+		{Type: EventUpdate, Container: "web"},
+		{Type: EventUpdateAvailable, Container: "db"},
+		{Type: EventFailure, Container: "api"},
+		{Type: EventCleanup},
+		{Type: EventCleanup, ImagesRemoved: 2, BytesReclaimed: 1 << 20},
+	}
+	all := config.NotificationsConfig{OnUpdate: true, OnUpdateAvailable: true, OnFailure: true, OnCleanup: true}
+
+	tests := []struct {
+		level string
+		want  int
+	}{
+		{config.NotifyAll, 5},
+		{"", 5},
+		{config.NotifyChanges, 4},
+		{config.NotifyErrors, 1},
+	}
+	for _, tt := range tests {
+		rec := &recorder{}
+		cfg := all
+		cfg.Level = tt.level
+		f := &filtered{cfg: cfg, next: rec}
+		for _, event := range events {
+			_ = f.Notify(context.Background(), event)
+		}
+		if len(rec.events) != tt.want {
+			t.Errorf("level %q delivered %d events, want %d: %+v", tt.level, len(rec.events), tt.want, rec.events)
+		}
+		if tt.level == config.NotifyErrors && len(rec.events) == 1 && rec.events[0].Type != EventFailure {
+			t.Errorf("level errors delivered %+v, want only the failure", rec.events)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	rec := &recorder{}
+	logger := zerolog.Nop()
+	ctx := WithCycleID(context.Background(), "abcd1234")
+
+	Send(ctx, rec, Event{Type: EventUpdate, Container: "web"}, &logger)
+
+	if len(rec.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(rec.events))
+	}
+	got := rec.events[0]
+	if got.CycleID != "abcd1234" {
+		t.Errorf("CycleID = %q, want abcd1234", got.CycleID)
+	}
+	if got.Timestamp.IsZero() || time.Since(got.Timestamp) > time.Minute {
+		t.Errorf("Timestamp not set: %v", got.Timestamp)
+	}
+
+	// Delivery errors must not panic or propagate
+	rec.err = errors.New("connection refused")
+	Send(context.Background(), rec, Event{Type: EventFailure}, &logger)
+	if rec.events[1].CycleID != "" {
+		t.Errorf("CycleID without context = %q, want empty", rec.events[1].CycleID)
+	}
+}
+
+// batcher records flushes in addition to events
+type batcher struct {
+	recorder
+	flushes int
+}
+
+func (b *batcher) Flush(ctx context.Context) error {
+	b.flushes++
+	return nil
+}
+
+func TestFlush_ReachesBatchingDestinations(t *testing.T) {
+	logger := zerolog.Nop()
+	rec, batch := &recorder{}, &batcher{}
+	n := &filtered{
+		cfg:  config.NotificationsConfig{OnUpdate: true},
+		next: multi{rec, batch},
+	}
+
+	Send(context.Background(), n, Event{Type: EventUpdate}, &logger)
+	Flush(context.Background(), n, &logger)
+
+	if len(rec.events) != 1 || len(batch.events) != 1 {
+		t.Errorf("delivered %d and %d events, want 1 to each destination", len(rec.events), len(batch.events))
+	}
+	if batch.flushes != 1 {
+		t.Errorf("flushes = %d, want 1", batch.flushes)
+	}
+
+	// Notifiers that don't batch are left alone
+	Flush(context.Background(), rec, &logger)
+}
+
+func TestNotifierContext(t *testing.T) {
+	if n := FromContext(context.Background()); n != nil {
+		t.Errorf("FromContext() without notifier = %v, want nil", n)
+	}
+
+	rec := &recorder{}
+	if n := FromContext(WithNotifier(context.Background(), rec)); n != rec {
+		t.Errorf("FromContext() = %v, want the stored notifier", n)
+	}
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+// defaultNtfyPriorities use ntfy's 1 (min) to 5 (max) scale, where 3 is the default
+var defaultNtfyPriorities = map[EventType]int{
+	EventUpdate:          3,
+	EventUpdateAvailable: 3,
+	EventFailure:         5,
+	EventCleanup:         2,
+}
+
+// ntfyTags are shown as emoji next to the message
+var ntfyTags = map[EventType][]string{
+	EventUpdate:          {"whale", "white_check_mark"},
+	EventUpdateAvailable: {"whale", "new"},
+	EventFailure:         {"whale", "x"},
+	EventCleanup:         {"whale", "broom"},
+}
+
+// NtfyNotifier publishes events to an ntfy topic
+type NtfyNotifier struct {
+	*pushNotifier
+	cfg    config.NtfyConfig
+	client *http.Client
+}
+
+// NewNtfy creates an ntfy notifier with the given request timeout
+func NewNtfy(cfg config.NtfyConfig, timeout time.Duration) *NtfyNotifier {
+	n := &NtfyNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+	n.pushNotifier = newPush(cfg.MessageTemplates, n.push)
+	return n
+}
+
+// push publishes a message and fails on any non-2xx response. It uses ntfy's JSON
+// publishing so titles don't have to fit in a header.
+func (n *NtfyNotifier) push(ctx context.Context, msg pushed) error {
+	header := http.Header{}
+	if n.cfg.Token != "" {
+		header.Set("Authorization", "Bearer "+n.cfg.Token)
+	}
+	return postJSON(ctx, n.client, "ntfy", n.cfg.URL, map[string]interface{}{
+		"topic":    n.cfg.Topic,
+		"title":    msg.Title,
+		"message":  msg.Message,
+		"priority": priorityFor(msg.Type, n.cfg.Priorities, defaultNtfyPriorities),
+		"tags":     ntfyTags[msg.Type],
+	}, header)
+}
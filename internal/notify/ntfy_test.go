@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+type ntfyMessage struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags"`
+}
+
+func TestNtfyNotifier_Notify(t *testing.T) {
+	var auth string
+	var received ntfyMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	n := NewNtfy(config.NtfyConfig{URL: server.URL, Topic: "homelab", Token: "tk_secret"}, time.Second)
+	event := Event{Type: EventUpdate, Container: "web", Image: "nginx:latest", OldImageID: "sha256:0123456789abcdef", NewImageID: "sha256:fedcba9876543210"}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if auth != "Bearer tk_secret" {
+		t.Errorf("Authorization = %q, want Bearer tk_secret", auth)
+	}
+	if received.Topic != "homelab" || received.Title != "Updated web" {
+		t.Errorf("received %+v, want the update for web on topic homelab", received)
+	}
+	if !strings.Contains(received.Message, "0123456789ab → fedcba987654") {
+		t.Errorf("message = %q, want short image IDs", received.Message)
+	}
+	if received.Priority != 3 || len(received.Tags) == 0 {
+		t.Errorf("priority/tags = %d/%v, want default priority 3 and tags", received.Priority, received.Tags)
+	}
+}
+
+func TestNtfyNotifier_Priorities(t *testing.T) {
+	var received ntfyMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	n := NewNtfy(config.NtfyConfig{URL: server.URL, Topic: "homelab", Priorities: map[string]int{"update_available": 1}}, time.Second)
+
+	tests := []struct {
+		event EventType
+		want  int
+	}{
+		{EventUpdateAvailable, 1},
+		{EventFailure, 5},
+		{EventCleanup, 2},
+	}
+	for _, tt := range tests {
+		if err := n.Notify(context.Background(), Event{Type: tt.event}); err != nil {
+			t.Fatalf("Notify(%s) error = %v", tt.event, err)
+		}
+		if received.Priority != tt.want {
+			t.Errorf("priority for %s = %d, want %d", tt.event, received.Priority, tt.want)
+		}
+	}
+}
+
+func TestNtfyNotifier_NoToken(t *testing.T) {
+	auth := "unset"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewNtfy(config.NtfyConfig{URL: server.URL, Topic: "homelab"}, time.Second).Notify(context.Background(), Event{Type: EventCleanup})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Notify() error = %v, want 403 error", err)
+	}
+	if auth != "" {
+		t.Errorf("Authorization = %q, want none without a token", auth)
+	}
+}
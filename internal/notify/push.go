@@ -0,0 +1,244 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+)
+
+// MessageData is the data available to push notification title and message templates.
+// The embedded Summary covers the event being sent, or the whole cycle in summary mode.
+type MessageData struct {
+	Summary
+	Event   *Event // The event being sent; nil in summary mode
+	Title   string // Built-in title
+	Message string // Built-in message
+}
+
+// pushed is a rendered push notification
+type pushed struct {
+	Type    EventType // Decides priority; the most severe event in summary mode
+	Title   string
+	Message string
+}
+
+// pushNotifier renders events with the configured templates and hands them to a push
+// service, either one message per event or one summary per cycle
+type pushNotifier struct {
+	send    func(ctx context.Context, msg pushed) error
+	title   *template.Template // nil uses the built-in title
+	message *template.Template // nil uses the built-in message
+	summary bool
+
+	mu     sync.Mutex
+	events []Event
+}
+
+// newPush creates a push notifier. Templates are validated with the configuration, so a
+// parse error here falls back to the built-in text.
+func newPush(tmpl config.MessageTemplates, send func(ctx context.Context, msg pushed) error) *pushNotifier {
+	return &pushNotifier{
+		send:    send,
+		title:   parseOptionalTemplate("title", tmpl.Title),
+		message: parseOptionalTemplate("message", tmpl.Message),
+		summary: tmpl.Summary,
+	}
+}
+
+func parseOptionalTemplate(name, text string) *template.Template {
+	if text == "" {
+		return nil
+	}
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+// Notify sends the event, or queues it for the cycle summary in summary mode. Only summary
+// mode keeps events, so a channel sending each event holds nothing between them.
+func (p *pushNotifier) Notify(ctx context.Context, event Event) error {
+	if p.summary {
+		p.mu.Lock()
+		p.events = append(p.events, event)
+		p.mu.Unlock()
+		return nil
+	}
+
+	data := MessageData{Summary: summarize([]Event{event}), Event: &event}
+	data.Title, data.Message = pushMessage(event)
+	msg, err := p.render(data, event.Type)
+	if err != nil {
+		return err
+	}
+	return p.send(ctx, msg)
+}
+
+// Flush ends the cycle, sending the queued events as one message in summary mode
+func (p *pushNotifier) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	events := p.events
+	p.events = nil
+	p.mu.Unlock()
+
+	if !p.summary || len(events) == 0 {
+		return nil
+	}
+
+	data := MessageData{Summary: summarize(events)}
+	var title, message bytes.Buffer
+	if err := defaultSummaryTitle.Execute(&title, data.Summary); err != nil {
+		return fmt.Errorf("failed to render summary title: %w", err)
+	}
+	if err := defaultSummaryMessage.Execute(&message, data.Summary); err != nil {
+		return fmt.Errorf("failed to render summary message: %w", err)
+	}
+	data.Title, data.Message = strings.TrimSpace(title.String()), strings.TrimSpace(message.String())
+
+	msg, err := p.render(data, mostSevere(events))
+	if err != nil {
+		return err
+	}
+	return p.send(ctx, msg)
+}
+
+// render applies the configured templates, keeping the built-in text where none is set
+func (p *pushNotifier) render(data MessageData, eventType EventType) (pushed, error) {
+	msg := pushed{Type: eventType, Title: data.Title, Message: data.Message}
+	if p.title != nil {
+		var buf bytes.Buffer
+		if err := p.title.Execute(&buf, data); err != nil {
+			return msg, fmt.Errorf("failed to render notification title: %w", err)
+		}
+		msg.Title = strings.TrimSpace(buf.String())
+	}
+	if p.message != nil {
+		var buf bytes.Buffer
+		if err := p.message.Execute(&buf, data); err != nil {
+			return msg, fmt.Errorf("failed to render notification message: %w", err)
+		}
+		msg.Message = strings.TrimSpace(buf.String())
+	}
+	return msg, nil
+}
+
+// Built-in summary text shares the email templates
+var (
+	defaultSummaryTitle   = template.Must(template.New("title").Parse(defaultEmailSubject))
+	defaultSummaryMessage = template.Must(template.New("message").Parse(defaultEmailBody))
+)
+
+// mostSevere picks the event type that decides a summary's priority
+func mostSevere(events []Event) EventType {
+	severity := map[EventType]int{EventCleanup: 1, EventUpdateAvailable: 2, EventUpdate: 3, EventFailure: 4}
+	worst := EventCleanup
+	for _, event := range events {
+		if severity[event.Type] > severity[worst] {
+			worst = event.Type
+		}
+	}
+	return worst
+}
+
+// pushMessage renders an event as the short title and text shown by push services
+func pushMessage(event Event) (title, message string) {
+	switch event.Type {
+	case EventUpdate:
+		change := event.VersionChange()
+		if change == "" {
+			change = event.ShortOldImageID() + " → " + event.ShortNewImageID()
+		}
+		return "Updated " + event.Container,
+			withReleaseURL(fmt.Sprintf("%s is now running %s (%s)", event.Container, event.Image, change), event)
+	case EventUpdateAvailable:
+		version := event.NewVersion
+		if version == "" {
+			version = event.ShortNewImageID()
+		}
+		if event.Target != "" {
+			version = event.Target
+		}
+		return "Update available for " + event.Container,
+			withReleaseURL(fmt.Sprintf("%s has a newer %s (%s) that was not applied", event.Container, event.Image, version), event)
+	case EventFailure:
+		if event.Container == "" {
+			return "Update cycle failed", event.Error
+		}
+		message := fmt.Sprintf("%s (%s): %s", event.Container, event.Image, event.Error)
+		if event.Logs != "" {
+			message += "\n\n" + event.Logs
+		}
+		return "Failed to update " + event.Container, message
+	case EventCleanup:
+		removed := []string{fmt.Sprintf("%d images", event.ImagesRemoved)}
+		if event.ContainersRemoved > 0 {
+			removed = append(removed, fmt.Sprintf("%d stopped containers", event.ContainersRemoved))
+		}
+		if event.VolumesRemoved > 0 {
+			removed = append(removed, fmt.Sprintf("%d volumes", event.VolumesRemoved))
+		}
+		if event.NetworksRemoved > 0 {
+			removed = append(removed, fmt.Sprintf("%d networks", event.NetworksRemoved))
+		}
+		return "Cleanup finished",
+			fmt.Sprintf("Removed %s, reclaimed %s", strings.Join(removed, ", "), util.FormatBytes(event.BytesReclaimed))
+	}
+	return "HarborBuddy", string(event.Type)
+}
+
+// withReleaseURL adds the event's release notes link on its own line, if it has one
+func withReleaseURL(message string, event Event) string {
+	if event.ReleaseURL == "" {
+		return message
+	}
+	return message + "\n" + event.ReleaseURL
+}
+
+// priorityFor returns the configured priority for the event type, or the built-in default
+func priorityFor(eventType EventType, configured map[string]int, defaults map[EventType]int) int {
+	if p, ok := configured[string(eventType)]; ok {
+		return p
+	}
+	return defaults[eventType]
+}
+
+// postJSON sends payload to url as JSON with the given extra headers and fails on any
+// non-2xx response. service names the destination in errors.
+func postJSON(ctx context.Context, client *http.Client, service, url string, payload interface{}, header http.Header) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", service, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", service, err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "HarborBuddy")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s message: %w", service, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", service, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// signatureHeader carries "sha256=" and the hex HMAC-SHA256 of a webhook's body, keyed with
+// notifications.webhook_secret, so receivers can check an event came from HarborBuddy
+const signatureHeader = "X-Signature"
+
+// WebhookNotifier POSTs events as JSON to a URL
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhook creates a webhook notifier with the given request timeout. A non-empty secret
+// signs every payload in the X-Signature header.
+func NewWebhook(url, secret string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// sign returns the X-Signature value of body for secret
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify sends the event and fails on any non-2xx response
+func (w *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "HarborBuddy")
+	if w.secret != "" {
+		req.Header.Set(signatureHeader, sign(w.secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	var received Event
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := NewWebhook(server.URL, "", time.Second)
+	event := Event{
+		Type:       EventUpdate,
+		Outcome:    OutcomeSuccess,
+		CycleID:    "abcd1234",
+		Container:  "web",
+		Image:      "nginx:latest",
+		OldImageID: "sha256:old",
+		NewImageID: "sha256:new",
+		Timestamp:  time.Unix(1700000000, 0).UTC(),
+	}
+
+	if err := w.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	if received != event {
+		t.Errorf("received %+v, want %+v", received, event)
+	}
+}
+
+func TestWebhookNotifier_Payload(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	event := Event{Type: EventCleanup, Outcome: OutcomeSuccess, ImagesRemoved: 2, BytesReclaimed: 1024}
+	if err := NewWebhook(server.URL, "", time.Second).Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	for _, key := range []string{"event", "outcome", "images_removed", "bytes_reclaimed", "timestamp"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("payload missing %q: %v", key, body)
+		}
+	}
+	if _, ok := body["container"]; ok {
+		t.Errorf("empty container should be omitted: %v", body)
+	}
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewWebhook(server.URL, "", time.Second).Notify(context.Background(), Event{Type: EventFailure})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Notify() error = %v, want 500 status error", err)
+	}
+}
+
+func TestWebhookNotifier_Signature(t *testing.T) {
+	var body []byte
+	var signatures []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signatures = append(signatures, r.Header.Get("X-Signature"))
+	}))
+	defer server.Close()
+
+	event := Event{Type: EventUpdate, Outcome: OutcomeSuccess, Container: "web"}
+	if err := NewWebhook(server.URL, "s3cret", time.Second).Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signatures[0] != want {
+		t.Errorf("X-Signature = %q, want %q", signatures[0], want)
+	}
+
+	if err := NewWebhook(server.URL, "", time.Second).Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if signatures[1] != "" {
+		t.Errorf("X-Signature = %q without a secret, want none", signatures[1])
+	}
+}
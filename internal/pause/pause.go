@@ -0,0 +1,99 @@
+// Package pause keeps the pause switch: a file whose presence suspends every update and
+// cleanup. "harborbuddy pause" and POST /pause write it, but touching it by hand works too.
+package pause
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Pause describes why and until when HarborBuddy is paused
+type Pause struct {
+	Until    time.Time `json:"until,omitempty"` // Zero means until resumed
+	Reason   string    `json:"reason,omitempty"`
+	PausedAt time.Time `json:"paused_at"`
+}
+
+// Expired reports whether the pause no longer applies at now
+func (p Pause) Expired(now time.Time) bool {
+	return !p.Until.IsZero() && !now.Before(p.Until)
+}
+
+// String describes the pause, e.g. "paused until 2026-10-16 18:00 UTC (migration)"
+func (p Pause) String() string {
+	s := "paused until resumed"
+	if !p.Until.IsZero() {
+		s = "paused until " + p.Until.Format("2006-01-02 15:04 MST")
+	}
+	if p.Reason != "" {
+		s += " (" + p.Reason + ")"
+	}
+	return s
+}
+
+// Read returns the pause in force at now, if any. An empty file, as left by touch, pauses
+// until it is removed; so does one that can't be parsed, since it was put there to stop
+// HarborBuddy. An expired pause is removed.
+func Read(path string, now time.Time) (Pause, bool, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Pause{}, false, nil
+	}
+	if err != nil {
+		return Pause{}, false, fmt.Errorf("failed to read pause file: %w", err)
+	}
+
+	var p Pause
+	if strings.TrimSpace(string(raw)) == "" || json.Unmarshal(raw, &p) != nil {
+		p = Pause{}
+		if info, err := os.Stat(path); err == nil {
+			p.PausedAt = info.ModTime().UTC().Truncate(time.Second)
+		}
+		return p, true, nil
+	}
+	if p.Expired(now) {
+		_ = os.Remove(path)
+		return Pause{}, false, nil
+	}
+	return p, true, nil
+}
+
+// Set pauses HarborBuddy for d, or until resumed if d is 0, replacing any pause in force
+func Set(path string, d time.Duration, reason string) (Pause, error) {
+	if d < 0 {
+		return Pause{}, fmt.Errorf("pause duration cannot be negative")
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	p := Pause{Reason: reason, PausedAt: now}
+	if d > 0 {
+		p.Until = now.Add(d)
+	}
+
+	raw, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return Pause{}, fmt.Errorf("failed to encode pause: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return Pause{}, fmt.Errorf("failed to create pause file directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(raw, '\n'), 0644); err != nil {
+		return Pause{}, fmt.Errorf("failed to write pause file: %w", err)
+	}
+	return p, nil
+}
+
+// Clear resumes HarborBuddy. It returns false if it wasn't paused.
+func Clear(path string) (bool, error) {
+	_, paused, err := Read(path, time.Now())
+	if err != nil || !paused {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil {
+		return false, fmt.Errorf("failed to remove pause file: %w", err)
+	}
+	return true, nil
+}
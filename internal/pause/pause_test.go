@@ -0,0 +1,59 @@
+package pause
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetReadClear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "PAUSE")
+
+	if _, paused, err := Read(path, time.Now()); paused || err != nil {
+		t.Fatalf("Read() without a file = %v, %v, want not paused", paused, err)
+	}
+
+	set, err := Set(path, 4*time.Hour, "migration")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	got, paused, err := Read(path, time.Now())
+	if !paused || err != nil || got != set {
+		t.Errorf("Read() = %+v, %v, %v, want %+v", got, paused, err, set)
+	}
+	if _, paused, _ := Read(path, set.Until); paused {
+		t.Error("Read() after the pause ran out still reports it")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expired pause file still exists (%v)", err)
+	}
+
+	if _, err := Set(path, 0, ""); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if resumed, err := Clear(path); !resumed || err != nil {
+		t.Errorf("Clear() = %v, %v, want resumed", resumed, err)
+	}
+	if resumed, err := Clear(path); resumed || err != nil {
+		t.Errorf("Clear() when not paused = %v, %v, want false", resumed, err)
+	}
+}
+
+func TestRead_TouchedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "PAUSE")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, paused, err := Read(path, time.Now().Add(24*time.Hour))
+	if !paused || err != nil {
+		t.Fatalf("Read() of an empty file = %v, %v, want paused", paused, err)
+	}
+	if !p.Until.IsZero() || p.PausedAt.IsZero() {
+		t.Errorf("Read() = %+v, want paused since the file was touched, until resumed", p)
+	}
+	if p.String() != "paused until resumed" {
+		t.Errorf("String() = %q", p.String())
+	}
+}
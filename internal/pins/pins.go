@@ -0,0 +1,157 @@
+package pins
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pin holds a container or image repository at a tag or digest
+type Pin struct {
+	Ref    string `yaml:"pin"`    // Tag ("16.2") or digest ("sha256:...")
+	Reason string `yaml:"reason"` // Why it is pinned, shown when the container is skipped
+
+	// Freeze marks a container frozen with "harborbuddy freeze" rather than pinned in the
+	// file: it is held at whatever it runs, until Until (zero means until unfrozen)
+	Freeze bool      `yaml:"-"`
+	Until  time.Time `yaml:"-"`
+}
+
+// UnmarshalYAML accepts the short form `name: "16.2"` as well as `{pin, reason}`
+func (p *Pin) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&p.Ref)
+	}
+	type plain Pin
+	return node.Decode((*plain)(p))
+}
+
+// IsDigest reports whether the pin is an exact image digest rather than a tag
+func (p Pin) IsDigest() bool {
+	return strings.HasPrefix(p.Ref, "sha256:")
+}
+
+// String describes the pin for logs, e.g. "pinned to 16.2 (INC-1423 review)" or "frozen
+// until 2026-10-19 15:00 UTC (incident)"
+func (p Pin) String() string {
+	s := "pinned to " + p.Ref
+	if p.Freeze {
+		s = "frozen"
+		if !p.Until.IsZero() {
+			s += " until " + p.Until.Format("2006-01-02 15:04 MST")
+		}
+	}
+	if p.Reason != "" {
+		s += " (" + p.Reason + ")"
+	}
+	return s
+}
+
+// Set is the contents of a pins file. Containers are pinned by name; images by
+// repository ("ghcr.io/acme/api") or by an allow/deny style pattern ("ghcr.io/acme/*").
+type Set struct {
+	Containers map[string]Pin `yaml:"containers"`
+	Images     map[string]Pin `yaml:"images"`
+}
+
+// WithFreezes returns a copy of the set in which frozen containers, keyed by name, are
+// pinned where they are, over any pin the file gives them. s may be nil.
+func (s *Set) WithFreezes(freezes map[string]Pin) *Set {
+	if len(freezes) == 0 {
+		return s
+	}
+	merged := &Set{Containers: make(map[string]Pin, len(freezes))}
+	if s != nil {
+		merged.Images = s.Images
+		for name, pin := range s.Containers {
+			merged.Containers[name] = pin
+		}
+	}
+	for name, pin := range freezes {
+		pin.Freeze = true
+		merged.Containers[name] = pin
+	}
+	return merged
+}
+
+// Len returns how many pins the set holds
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.Containers) + len(s.Images)
+}
+
+// Parse reads a pins file
+func Parse(data []byte) (*Set, error) {
+	var s Set
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	for name, pin := range s.Containers {
+		if pin.Ref == "" {
+			return nil, fmt.Errorf("containers.%s: pin cannot be empty", name)
+		}
+	}
+	for repo, pin := range s.Images {
+		if pin.Ref == "" {
+			return nil, fmt.Errorf("images.%s: pin cannot be empty", repo)
+		}
+	}
+	return &s, nil
+}
+
+// Loader reads a pins file and re-reads it whenever it changes on disk, so pins can be
+// edited without restarting HarborBuddy. It is safe for concurrent use.
+type Loader struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	set     *Set
+}
+
+// NewLoader creates a loader for the pins file at path
+func NewLoader(path string) *Loader {
+	return &Loader{path: path}
+}
+
+// Load returns the current pins and whether they were (re)loaded by this call. A missing
+// file means no pins. If the file can't be read or parsed, the pins loaded before stay in
+// force and are returned along with the error.
+func (l *Loader) Load() (*Set, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		changed := l.set != nil
+		l.set, l.modTime, l.size = nil, time.Time{}, 0
+		return nil, changed, nil
+	}
+	if err != nil {
+		return l.set, false, fmt.Errorf("failed to read pins file: %w", err)
+	}
+	if info.ModTime().Equal(l.modTime) && info.Size() == l.size {
+		return l.set, false, nil
+	}
+
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return l.set, false, fmt.Errorf("failed to read pins file: %w", err)
+	}
+	set, err := Parse(data)
+	if err != nil {
+		// Remember the broken version so the error is reported once, not every cycle
+		l.modTime, l.size = info.ModTime(), info.Size()
+		return l.set, false, fmt.Errorf("failed to parse pins file %s, keeping the previous pins: %w", l.path, err)
+	}
+	l.set, l.modTime, l.size = set, info.ModTime(), info.Size()
+	return set, true, nil
+}
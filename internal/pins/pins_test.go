@@ -0,0 +1,87 @@
+package pins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	set, err := Parse([]byte(`
+containers:
+  postgres: "16.2"
+  api:
+    pin: sha256:4f1c
+    reason: INC-1423 review
+images:
+  ghcr.io/acme/*: "2.3.1"
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if got := set.Containers["postgres"]; got.Ref != "16.2" || got.IsDigest() {
+		t.Errorf("postgres = %+v, want tag 16.2", got)
+	}
+	if got := set.Containers["api"]; !got.IsDigest() || got.String() != "pinned to sha256:4f1c (INC-1423 review)" {
+		t.Errorf("api = %+v (%s), want digest with reason", got, got)
+	}
+	if set.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", set.Len())
+	}
+
+	if _, err := Parse([]byte("containers:\n  web: \"\"\n")); err == nil {
+		t.Error("expected an error for an empty pin")
+	}
+}
+
+func TestLoader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pins.yml")
+	loader := NewLoader(path)
+
+	set, reloaded, err := loader.Load()
+	if set != nil || reloaded || err != nil {
+		t.Fatalf("missing file: Load() = %v, %v, %v; want no pins", set, reloaded, err)
+	}
+
+	write := func(content string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+
+	write("containers:\n  web: \"1.0\"\n", start)
+	if set, reloaded, err := loader.Load(); err != nil || !reloaded || set.Containers["web"].Ref != "1.0" {
+		t.Fatalf("Load() = %+v, %v, %v; want web pinned to 1.0", set, reloaded, err)
+	}
+	if _, reloaded, _ := loader.Load(); reloaded {
+		t.Error("unchanged file should not be reloaded")
+	}
+
+	write("containers:\n  web: \"2.0\"\n", start.Add(time.Minute))
+	if set, reloaded, _ := loader.Load(); !reloaded || set.Containers["web"].Ref != "2.0" {
+		t.Errorf("edited file: Load() = %+v, %v; want web pinned to 2.0", set, reloaded)
+	}
+
+	write("containers: [not a map", start.Add(2*time.Minute))
+	set, _, err = loader.Load()
+	if err == nil || set.Containers["web"].Ref != "2.0" {
+		t.Errorf("broken file: Load() = %+v, %v; want the previous pins and an error", set, err)
+	}
+	if _, _, err := loader.Load(); err != nil {
+		t.Errorf("a broken file should only be reported once, got %v", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if set, reloaded, _ := loader.Load(); set != nil || !reloaded {
+		t.Errorf("removed file: Load() = %+v, %v; want no pins", set, reloaded)
+	}
+}
@@ -0,0 +1,392 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+)
+
+// manifestAccept lists the manifest media types we accept, so the registry returns the
+// same digest Docker records in RepoDigests (the index digest for multi-arch images)
+var manifestAccept = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ErrNotFound is matched by errors for manifests or blobs the registry doesn't have
+var ErrNotFound = errors.New("not found in registry")
+
+// ErrUnauthorized is matched by errors for requests the registry refused for lack of access:
+// a 401 or 403, or credentials it asked for and HarborBuddy doesn't have
+var ErrUnauthorized = errors.New("unauthorized")
+
+// statusError is a registry response other than 200
+type statusError struct {
+	status   string
+	code     int
+	imageRef string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("registry returned %s for %s", e.status, e.imageRef)
+}
+
+func (e *statusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.code == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.code == http.StatusUnauthorized || e.code == http.StatusForbidden
+	}
+	return false
+}
+
+// Credentials resolves registry credentials for an image reference
+type Credentials interface {
+	Resolve(imageRef string) (docker.RegistryCredentials, bool, error)
+}
+
+// Client queries registries over the Distribution API
+type Client struct {
+	http   *http.Client
+	creds  Credentials
+	scheme string // "https"; tests use plain http
+}
+
+// NewClient creates a registry client. creds may be nil for anonymous access.
+func NewClient(creds Credentials) *Client {
+	return &Client{
+		http:   &http.Client{Timeout: 30 * time.Second},
+		creds:  creds,
+		scheme: "https",
+	}
+}
+
+// KeychainFromConfig builds the credential chain used for pulls and registry queries:
+// configured registries first, then the Docker CLI config file.
+func KeychainFromConfig(registries map[string]config.RegistryAuth) *docker.Keychain {
+	creds := make(map[string]docker.RegistryCredentials, len(registries))
+	for host, auth := range registries {
+		creds[host] = docker.RegistryCredentials{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			RegistryToken: auth.Token,
+		}
+	}
+	return docker.NewKeychain(creds, docker.DefaultDockerConfigPath())
+}
+
+// manifestRef returns what to ask the registry for: the digest of a reference pinned by one,
+// otherwise its tag, "latest" if it names none
+func manifestRef(named reference.Named) string {
+	if digested, ok := named.(reference.Digested); ok {
+		return digested.Digest().String()
+	}
+	if tagged, ok := reference.TagNameOnly(named).(reference.Tagged); ok {
+		return tagged.Tag()
+	}
+	return "latest"
+}
+
+// ManifestDigest returns the digest the registry currently serves for the image's tag,
+// using a HEAD request so no layers (or even the manifest body) are downloaded.
+func (c *Client) ManifestDigest(ctx context.Context, imageRef string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %s: %w", imageRef, err)
+	}
+
+	resp, err := c.do(ctx, http.MethodHead, c.repositoryURL(named)+"/manifests/"+manifestRef(named), imageRef, strings.Join(manifestAccept, ", "))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a digest for %s", imageRef)
+	}
+	return digest, nil
+}
+
+// Manifest downloads the single-platform manifest the registry serves for imageRef, such as
+// the OCI manifest of a cosign signature
+func (c *Client) Manifest(ctx context.Context, imageRef string) ([]byte, error) {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %s: %w", imageRef, err)
+	}
+
+	accept := "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json"
+	resp, err := c.do(ctx, http.MethodGet, c.repositoryURL(named)+"/manifests/"+manifestRef(named), imageRef, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest of %s: %w", imageRef, err)
+	}
+	return body, nil
+}
+
+// Blob downloads a small blob of the image's repository and checks it against its digest
+func (c *Client) Blob(ctx context.Context, imageRef, blobDigest string) ([]byte, error) {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %s: %w", imageRef, err)
+	}
+	want, err := digest.Parse(blobDigest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob digest %s: %w", blobDigest, err)
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, c.repositoryURL(named)+"/blobs/"+want.String(), imageRef, "*/*")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s of %s: %w", blobDigest, imageRef, err)
+	}
+	if got := want.Algorithm().FromBytes(body); got != want {
+		return nil, fmt.Errorf("blob %s of %s has digest %s", blobDigest, imageRef, got)
+	}
+	return body, nil
+}
+
+// ListTags returns every tag of the image's repository, following pagination
+func (c *Client) ListTags(ctx context.Context, imageRef string) ([]string, error) {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %s: %w", imageRef, err)
+	}
+
+	base := c.repositoryURL(named)
+	next := base + "/tags/list"
+	var tags []string
+
+	for next != "" {
+		resp, err := c.do(ctx, http.MethodGet, next, imageRef, "application/json")
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&page)
+		link := resp.Header.Get("Link")
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tag list for %s: %w", imageRef, err)
+		}
+		tags = append(tags, page.Tags...)
+
+		next, err = nextPage(next, link)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// repositoryURL returns the Distribution API base URL for the image's repository
+func (c *Client) repositoryURL(named reference.Named) string {
+	host := reference.Domain(named)
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s", c.scheme, host, reference.Path(named))
+}
+
+// do performs a registry request, answering an auth challenge once if the registry
+// asks for one. Any status other than 200 is an error; the caller closes the body.
+func (c *Client) do(ctx context.Context, method, target, imageRef, accept string) (*http.Response, error) {
+	resp, err := c.send(ctx, method, target, accept, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		authHeader, err := c.authorize(ctx, imageRef, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.send(ctx, method, target, accept, authHeader); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &statusError{status: resp.Status, code: resp.StatusCode, imageRef: imageRef}
+	}
+	return resp, nil
+}
+
+func (c *Client) send(ctx context.Context, method, target, accept, authHeader string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registry: %w", err)
+	}
+	return resp, nil
+}
+
+// nextPage resolves the RFC 5988 Link header of a paginated response, e.g.
+// `</v2/app/tags/list?n=100&last=v1>; rel="next"`, or returns "" on the last page
+func nextPage(current, link string) (string, error) {
+	target, _, ok := strings.Cut(link, ";")
+	if !ok || !strings.Contains(link, `rel="next"`) {
+		return "", nil
+	}
+	target = strings.Trim(strings.TrimSpace(target), "<>")
+
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", fmt.Errorf("invalid registry URL %s: %w", current, err)
+	}
+	next, err := base.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid pagination link %q: %w", link, err)
+	}
+	return next.String(), nil
+}
+
+// authorize answers a registry auth challenge and returns the Authorization header to retry with
+func (c *Client) authorize(ctx context.Context, imageRef, challenge string) (string, error) {
+	var creds docker.RegistryCredentials
+	if c.creds != nil {
+		found, ok, err := c.creds.Resolve(imageRef)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			creds = found
+		}
+	}
+
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if creds.Username == "" {
+			return "", fmt.Errorf("registry requires credentials for %s: %w", imageRef, ErrUnauthorized)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(creds.Username, creds.Password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		if creds.RegistryToken != "" {
+			return "Bearer " + creds.RegistryToken, nil
+		}
+		token, err := c.fetchToken(ctx, params, creds)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	}
+	return "", fmt.Errorf("unsupported registry auth challenge %q", challenge)
+}
+
+// fetchToken requests a bearer token from the registry's token service
+func (c *Client) fetchToken(ctx context.Context, params map[string]string, creds docker.RegistryCredentials) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry auth challenge has no realm")
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %s: %w", realm, err)
+	}
+	query := tokenURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		query.Set("scope", scope)
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	if creds.Username != "" {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch registry token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token service returned %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("token service returned no token")
+}
+
+// parseChallenge splits a WWW-Authenticate header into its lowercased scheme and parameters,
+// e.g. `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			params[key] = value
+		}
+	}
+
+	return strings.ToLower(scheme), params
+}
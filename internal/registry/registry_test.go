@@ -0,0 +1,247 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+// testClient returns a client that talks plain HTTP to the test server
+func testClient(creds Credentials) *Client {
+	c := NewClient(creds)
+	c.scheme = "http"
+	return c
+}
+
+func TestManifestDigest_Anonymous(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/v2/team/app/manifests/v1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+			t.Errorf("Accept header missing index media type: %q", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Docker-Content-Digest", "sha256:abc")
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	digest, err := testClient(nil).ManifestDigest(context.Background(), host+"/team/app:v1")
+	if err != nil {
+		t.Fatalf("ManifestDigest() error = %v", err)
+	}
+	if digest != "sha256:abc" {
+		t.Errorf("digest = %q, want sha256:abc", digest)
+	}
+}
+
+func TestManifestDigest_References(t *testing.T) {
+	const pinned = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Header().Set("Docker-Content-Digest", "sha256:abc")
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	for _, ref := range []string{host + "/app", host + "/app@" + pinned, host + "/app:v1@" + pinned} {
+		if _, err := testClient(nil).ManifestDigest(context.Background(), ref); err != nil {
+			t.Errorf("ManifestDigest(%s) error = %v", ref, err)
+		}
+	}
+
+	want := []string{"/v2/app/manifests/latest", "/v2/app/manifests/" + pinned, "/v2/app/manifests/" + pinned}
+	if strings.Join(requested, " ") != strings.Join(want, " ") {
+		t.Errorf("requested %v, want %v", requested, want)
+	}
+}
+
+func TestManifestDigest_BearerToken(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			user, pass, _ := r.BasicAuth()
+			if user != "octocat" || pass != "secret" {
+				t.Errorf("token request basic auth = %q/%q", user, pass)
+			}
+			if scope := r.URL.Query().Get("scope"); scope != "repository:team/app:pull" {
+				t.Errorf("scope = %q", scope)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "t0ken"})
+		case "/v2/team/app/manifests/latest":
+			if r.Header.Get("Authorization") != "Bearer t0ken" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test",scope="repository:team/app:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:def")
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	keychain := docker.NewKeychain(map[string]docker.RegistryCredentials{
+		host: {Username: "octocat", Password: "secret"},
+	}, "")
+
+	digest, err := testClient(keychain).ManifestDigest(context.Background(), host+"/team/app")
+	if err != nil {
+		t.Fatalf("ManifestDigest() error = %v", err)
+	}
+	if digest != "sha256:def" {
+		t.Errorf("digest = %q, want sha256:def", digest)
+	}
+}
+
+func TestManifestDigest_BasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "hunter2" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", "sha256:123")
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	keychain := KeychainFromConfig(map[string]config.RegistryAuth{
+		host: {Username: "admin", Password: "hunter2"},
+	})
+
+	digest, err := testClient(keychain).ManifestDigest(context.Background(), host+"/app:v2")
+	if err != nil {
+		t.Fatalf("ManifestDigest() error = %v", err)
+	}
+	if digest != "sha256:123" {
+		t.Errorf("digest = %q, want sha256:123", digest)
+	}
+}
+
+func TestListTags_Pagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/team/app/tags/list" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/team/app/tags/list?n=2&last=1.1>; rel="next"`)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "team/app", "tags": []string{"1.0", "1.1"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "team/app", "tags": []string{"1.2"}})
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	tags, err := testClient(nil).ListTags(context.Background(), host+"/team/app:1.0")
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if strings.Join(tags, ",") != "1.0,1.1,1.2" {
+		t.Errorf("tags = %v, want [1.0 1.1 1.2]", tags)
+	}
+}
+
+func TestManifestDigest_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		errMsg  string
+		denied  bool
+	}{
+		{"not found", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) }, "404", false},
+		{"missing digest header", func(w http.ResponseWriter, r *http.Request) {}, "did not return a digest", false},
+		{"basic auth without credentials", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}, "requires credentials", true},
+		{"forbidden", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusForbidden) }, "403", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			host := strings.TrimPrefix(server.URL, "http://")
+			_, err := testClient(nil).ManifestDigest(context.Background(), host+"/app:v1")
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("error = %v, want containing %q", err, tt.errMsg)
+			}
+			if errors.Is(err, ErrUnauthorized) != tt.denied {
+				t.Errorf("errors.Is(%v, ErrUnauthorized) = %v, want %v", err, !tt.denied, tt.denied)
+			}
+		})
+	}
+}
+
+func TestManifestAndBlob(t *testing.T) {
+	payload := []byte(`{"critical":{}}`)
+	payloadDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(payload))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/team/app/manifests/sha256-abc.sig", "/v2/team/app/manifests/" + payloadDigest:
+			_, _ = w.Write([]byte(`{"layers":[]}`))
+		case "/v2/team/app/blobs/" + payloadDigest:
+			_, _ = w.Write(payload)
+		case "/v2/team/app/blobs/sha256:0000000000000000000000000000000000000000000000000000000000000000":
+			_, _ = w.Write(payload)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	client := testClient(nil)
+
+	manifest, err := client.Manifest(context.Background(), host+"/team/app:sha256-abc.sig")
+	if err != nil || string(manifest) != `{"layers":[]}` {
+		t.Errorf("Manifest() = %q, %v", manifest, err)
+	}
+	manifest, err = client.Manifest(context.Background(), host+"/team/app@"+payloadDigest)
+	if err != nil || string(manifest) != `{"layers":[]}` {
+		t.Errorf("Manifest() by digest = %q, %v", manifest, err)
+	}
+	if _, err := client.Manifest(context.Background(), host+"/team/app:sha256-def.sig"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Manifest() of a missing tag error = %v, want ErrNotFound", err)
+	}
+
+	blob, err := client.Blob(context.Background(), host+"/team/app", payloadDigest)
+	if err != nil || string(blob) != string(payload) {
+		t.Errorf("Blob() = %q, %v", blob, err)
+	}
+	if _, err := client.Blob(context.Background(), host+"/team/app", "sha256:0000000000000000000000000000000000000000000000000000000000000000"); err == nil {
+		t.Error("Blob() with content not matching its digest succeeded, want an error")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	if scheme != "bearer" {
+		t.Errorf("scheme = %q, want bearer", scheme)
+	}
+	want := map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/nginx:pull",
+	}
+	for key, value := range want {
+		if params[key] != value {
+			t.Errorf("params[%q] = %q, want %q", key, params[key], value)
+		}
+	}
+}
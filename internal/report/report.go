@@ -0,0 +1,307 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outcome values of a cycle
+const (
+	OutcomeSuccess = "success"
+	OutcomePartial = "partial" // The cycle ran but some containers failed
+	OutcomeFailure = "failure" // The cycle itself failed
+)
+
+// Container is a container the cycle updated, or found an update for
+type Container struct {
+	Name       string `json:"name"`
+	Image      string `json:"image"`
+	Target     string `json:"target,omitempty"` // New image reference when the update policy moved the tag, or the newer digest of a container pinned by digest
+	OldImageID string `json:"old_image_id,omitempty"`
+	NewImageID string `json:"new_image_id,omitempty"`
+	OldSize    int64  `json:"old_size_bytes,omitempty"`
+	NewSize    int64  `json:"new_size_bytes,omitempty"`
+	SizeDelta  int64  `json:"size_delta_bytes"` // NewSize - OldSize, 0 if either is unknown
+}
+
+// Skipped is a container the cycle left alone, and why
+type Skipped struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Scan is the vulnerability scan of a new image
+type Scan struct {
+	Name        string   `json:"name"`
+	Image       string   `json:"image"`
+	NewImageID  string   `json:"new_image_id,omitempty"`
+	Critical    int      `json:"critical"`
+	High        int      `json:"high"`
+	Medium      int      `json:"medium"`
+	Low         int      `json:"low"`
+	Unknown     int      `json:"unknown"`
+	CriticalIDs []string `json:"critical_ids,omitempty"`
+	Passed      bool     `json:"passed"` // The update was allowed to go ahead
+	Error       string   `json:"error,omitempty"`
+}
+
+// Failure is a per-container error
+type Failure struct {
+	Name     string `json:"name"`
+	Image    string `json:"image,omitempty"`
+	Category string `json:"category"`
+	Error    string `json:"error"`
+}
+
+// Cleanup holds the cleanup phase's results
+type Cleanup struct {
+	ImagesRemoved     int   `json:"images_removed"`
+	ContainersRemoved int   `json:"containers_removed"`
+	VolumesRemoved    int   `json:"volumes_removed"`
+	NetworksRemoved   int   `json:"networks_removed"`
+	BytesReclaimed    int64 `json:"bytes_reclaimed"`
+	DurationMs        int64 `json:"duration_ms"`
+	DryRun            bool  `json:"dry_run,omitempty"` // Counts are what would have been removed
+}
+
+// Report is the structured summary of one cycle. The phases of a cycle fill it in through
+// the context; every method is safe to call on a nil Report, so they don't need to check
+// whether reporting is enabled.
+type Report struct {
+	CycleID     string      `json:"cycle_id"`
+	Hostname    string      `json:"hostname"`
+	StartedAt   time.Time   `json:"started_at"`
+	FinishedAt  time.Time   `json:"finished_at"`
+	DurationMs  int64       `json:"duration_ms"`
+	Outcome     string      `json:"outcome"`
+	Error       string      `json:"error,omitempty"`
+	DryRun      bool        `json:"dry_run"`
+	MonitorOnly bool        `json:"monitor_only"`
+	Checked     int         `json:"checked"`
+	Updated     []Container `json:"updated"`
+	Restarted   []string    `json:"restarted,omitempty"` // Dependents recreated or restarted alongside an update, not updated themselves
+	Available   []Container `json:"available"`           // Updates found but not applied (monitor-only, or newer digests of pinned images)
+	Skipped     []Skipped   `json:"skipped"`
+	Scans       []Scan      `json:"scans,omitempty"` // With scan.enabled, one per new image scanned
+	Failed      []Failure   `json:"failed"`
+	SizeDelta   int64       `json:"size_delta_bytes"` // Sum over updated containers
+	Cleanup     *Cleanup    `json:"cleanup,omitempty"`
+
+	mu sync.Mutex
+}
+
+// New starts a report for a cycle
+func New(cycleID string, dryRun, monitorOnly bool) *Report {
+	hostname, _ := os.Hostname()
+	return &Report{
+		CycleID:     cycleID,
+		Hostname:    hostname,
+		StartedAt:   time.Now(),
+		DryRun:      dryRun,
+		MonitorOnly: monitorOnly,
+		Updated:     []Container{},
+		Available:   []Container{},
+		Skipped:     []Skipped{},
+		Failed:      []Failure{},
+	}
+}
+
+// AddUpdated records a replaced container, computing its size delta
+func (r *Report) AddUpdated(c Container) {
+	if r == nil {
+		return
+	}
+	if c.OldSize > 0 && c.NewSize > 0 {
+		c.SizeDelta = c.NewSize - c.OldSize
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Updated = append(r.Updated, c)
+}
+
+// AddRestarted records a dependent recreated or restarted alongside an update
+func (r *Report) AddRestarted(name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Restarted = append(r.Restarted, name)
+}
+
+// AddAvailable records an update that was found but not applied
+func (r *Report) AddAvailable(c Container) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Available = append(r.Available, c)
+}
+
+// AddSkipped records a container the cycle left alone
+func (r *Report) AddSkipped(name, reason string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Skipped = append(r.Skipped, Skipped{Name: name, Reason: reason})
+}
+
+// AddScan records the vulnerability scan of a new image
+func (r *Report) AddScan(s Scan) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Scans = append(r.Scans, s)
+}
+
+// AddFailure records a per-container error
+func (r *Report) AddFailure(f Failure) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Failed = append(r.Failed, f)
+}
+
+// SetChecked records how many containers were checked for updates
+func (r *Report) SetChecked(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Checked = n
+}
+
+// SetCleanup records the cleanup phase's results
+func (r *Report) SetCleanup(c Cleanup) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Cleanup = &c
+}
+
+// Finish stamps the end of the cycle and its outcome. err is the error that ended the cycle, if any.
+func (r *Report) Finish(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = time.Now()
+	r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+	r.SizeDelta = 0
+	for _, c := range r.Updated {
+		r.SizeDelta += c.SizeDelta
+	}
+
+	switch {
+	case err != nil:
+		r.Outcome = OutcomeFailure
+		r.Error = err.Error()
+	case len(r.Failed) > 0:
+		r.Outcome = OutcomePartial
+	default:
+		r.Outcome = OutcomeSuccess
+	}
+}
+
+// MarshalJSON encodes the report under its lock
+func (r *Report) MarshalJSON() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	type plain Report
+	return json.Marshal((*plain)(r))
+}
+
+// Encode writes the report to w as indented JSON
+func (r *Report) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return nil
+}
+
+// WriteFile replaces the file at path with the report, atomically (temp file + rename) so
+// readers never see a partial report
+func (r *Report) WriteFile(path string) error {
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(raw, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace report file: %w", err)
+	}
+	return nil
+}
+
+// Post sends the report as JSON to url and fails on any non-2xx response
+func (r *Report) Post(ctx context.Context, url string, timeout time.Duration) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create report request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "HarborBuddy")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send report: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("report endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+type reportKey struct{}
+
+// WithReport returns a context carrying the report the phases of a cycle fill in
+func WithReport(ctx context.Context, r *Report) context.Context {
+	return context.WithValue(ctx, reportKey{}, r)
+}
+
+// FromContext returns the report stored in ctx, or nil if reporting is disabled
+func FromContext(ctx context.Context) *Report {
+	r, _ := ctx.Value(reportKey{}).(*Report)
+	return r
+}
@@ -0,0 +1,146 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport_Finish(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		err         error
+		wantOutcome string
+	}{
+		{"clean cycle", 0, nil, OutcomeSuccess},
+		{"container failed", 1, nil, OutcomePartial},
+		{"cycle failed", 0, errors.New("docker unreachable"), OutcomeFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New("abcd1234", false, false)
+			for i := 0; i < tt.failures; i++ {
+				r.AddFailure(Failure{Name: "web", Category: "pull", Error: "boom"})
+			}
+			r.Finish(tt.err)
+
+			if r.Outcome != tt.wantOutcome {
+				t.Errorf("Outcome = %s, want %s", r.Outcome, tt.wantOutcome)
+			}
+			if tt.err != nil && r.Error != tt.err.Error() {
+				t.Errorf("Error = %q, want %q", r.Error, tt.err.Error())
+			}
+			if r.FinishedAt.Before(r.StartedAt) {
+				t.Errorf("FinishedAt %v is before StartedAt %v", r.FinishedAt, r.StartedAt)
+			}
+		})
+	}
+}
+
+func TestReport_SizeDelta(t *testing.T) {
+	r := New("abcd1234", false, false)
+	r.AddUpdated(Container{Name: "web", OldSize: 100, NewSize: 150})
+	r.AddUpdated(Container{Name: "db", OldSize: 300, NewSize: 280})
+	r.AddUpdated(Container{Name: "cache", NewSize: 50}) // Old image size unknown
+	r.Finish(nil)
+
+	if r.Updated[0].SizeDelta != 50 || r.Updated[1].SizeDelta != -20 || r.Updated[2].SizeDelta != 0 {
+		t.Errorf("size deltas = %d, %d, %d, want 50, -20, 0", r.Updated[0].SizeDelta, r.Updated[1].SizeDelta, r.Updated[2].SizeDelta)
+	}
+	if r.SizeDelta != 30 {
+		t.Errorf("SizeDelta = %d, want 30", r.SizeDelta)
+	}
+}
+
+func TestReport_NilIsNoop(t *testing.T) {
+	var r *Report
+	r.AddUpdated(Container{Name: "web"})
+	r.AddAvailable(Container{Name: "web"})
+	r.AddSkipped("web", "up to date")
+	r.AddFailure(Failure{Name: "web"})
+	r.SetChecked(1)
+	r.SetCleanup(Cleanup{})
+	r.Finish(nil)
+
+	if FromContext(context.Background()) != nil {
+		t.Error("FromContext() without a report should be nil")
+	}
+}
+
+func TestReport_WriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "latest.json")
+
+	r := New("abcd1234", true, false)
+	r.AddSkipped("web", "dry-run")
+	r.SetCleanup(Cleanup{ImagesRemoved: 2, BytesReclaimed: 1024})
+	r.Finish(nil)
+	if err := r.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	if got["cycle_id"] != "abcd1234" || got["dry_run"] != true || got["outcome"] != OutcomeSuccess {
+		t.Errorf("report = %v, want cycle ID, dry run and outcome", got)
+	}
+	// Empty lists stay lists so dashboards don't have to handle null
+	if updated, ok := got["updated"].([]interface{}); !ok || len(updated) != 0 {
+		t.Errorf("updated = %v, want []", got["updated"])
+	}
+	if cleanup, ok := got["cleanup"].(map[string]interface{}); !ok || cleanup["images_removed"] != float64(2) {
+		t.Errorf("cleanup = %v, want images_removed 2", got["cleanup"])
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp file left behind: %v", err)
+	}
+}
+
+func TestReport_Post(t *testing.T) {
+	var received Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode report: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	r := New("abcd1234", false, false)
+	r.AddUpdated(Container{Name: "web", Image: "nginx:latest"})
+	r.Finish(nil)
+	if err := r.Post(context.Background(), server.URL, time.Second); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if received.CycleID != "abcd1234" || len(received.Updated) != 1 {
+		t.Errorf("received %+v, want the cycle with one update", &received)
+	}
+}
+
+func TestReport_PostErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := New("abcd1234", false, false).Post(context.Background(), server.URL, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Post() error = %v, want 502 error", err)
+	}
+}
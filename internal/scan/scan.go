@@ -0,0 +1,173 @@
+// Package scan checks new images for known vulnerabilities with Trivy, either against its
+// own database or through a Trivy server.
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+)
+
+// maxListed caps how many critical vulnerability IDs a summary keeps
+const maxListed = 10
+
+// Summary counts an image's vulnerabilities by severity
+type Summary struct {
+	Critical int      `json:"critical"`
+	High     int      `json:"high"`
+	Medium   int      `json:"medium"`
+	Low      int      `json:"low"`
+	Unknown  int      `json:"unknown"`
+	IDs      []string `json:"critical_ids,omitempty"` // The first critical vulnerabilities, e.g. CVE-2024-3094
+}
+
+// String describes the summary, e.g. "2 critical, 5 high, 11 medium, 30 low"
+func (s Summary) String() string {
+	out := fmt.Sprintf("%d critical, %d high, %d medium, %d low", s.Critical, s.High, s.Medium, s.Low)
+	if s.Unknown > 0 {
+		out += fmt.Sprintf(", %d unknown", s.Unknown)
+	}
+	return out
+}
+
+// Runner runs a command and returns its standard output
+type Runner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// Trivy scans images by running the trivy binary
+type Trivy struct {
+	binary        string
+	server        string
+	ignoreUnfixed bool
+	images        []string
+	timeout       time.Duration
+	run           Runner
+}
+
+// New checks that the trivy binary of cfg can be found. Scans are run through run, or
+// executed directly if it is nil.
+func New(cfg config.ScanConfig, run Runner) (*Trivy, error) {
+	binary := cfg.Trivy
+	if run == nil {
+		path, err := exec.LookPath(binary)
+		if err != nil {
+			return nil, fmt.Errorf("trivy binary not found: %w", err)
+		}
+		binary, run = path, execute
+	}
+	return &Trivy{
+		binary:        binary,
+		server:        cfg.Server,
+		ignoreUnfixed: cfg.IgnoreUnfixed,
+		images:        cfg.Images,
+		timeout:       cfg.Timeout,
+		run:           run,
+	}, nil
+}
+
+// Applies reports whether image is scanned, per scan.images
+func (t *Trivy) Applies(image string) bool {
+	if len(t.images) == 0 {
+		return true
+	}
+	for _, pattern := range t.images {
+		if util.MatchPattern(image, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan runs trivy against imageRef, which it finds in the local Docker daemon first, and
+// counts the vulnerabilities it reports
+func (t *Trivy) Scan(ctx context.Context, imageRef string) (Summary, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	args := []string{"image", "--format", "json", "--quiet", "--scanners", "vuln", "--timeout", t.timeout.String()}
+	if t.server != "" {
+		args = append(args, "--server", t.server)
+	}
+	if t.ignoreUnfixed {
+		args = append(args, "--ignore-unfixed")
+	}
+	args = append(args, imageRef)
+
+	out, err := t.run(ctx, t.binary, args...)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return Summary{}, fmt.Errorf("trivy did not finish within %v", t.timeout)
+		}
+		return Summary{}, err
+	}
+	return summarize(out)
+}
+
+// report is the part of trivy's JSON output that is counted
+type report struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// summarize counts the vulnerabilities in trivy's JSON output. One found in several
+// packages of the image counts once per package, as trivy lists it.
+func summarize(out []byte) (Summary, error) {
+	var r report
+	if err := json.Unmarshal(out, &r); err != nil {
+		return Summary{}, fmt.Errorf("failed to parse trivy output: %w", err)
+	}
+
+	var s Summary
+	for _, result := range r.Results {
+		for _, v := range result.Vulnerabilities {
+			switch strings.ToUpper(v.Severity) {
+			case "CRITICAL":
+				s.Critical++
+				if len(s.IDs) < maxListed && !slices.Contains(s.IDs, v.VulnerabilityID) {
+					s.IDs = append(s.IDs, v.VulnerabilityID)
+				}
+			case "HIGH":
+				s.High++
+			case "MEDIUM":
+				s.Medium++
+			case "LOW":
+				s.Low++
+			default:
+				s.Unknown++
+			}
+		}
+	}
+	return s, nil
+}
+
+// execute runs name and returns its output; trivy's messages on stderr become the error
+func execute(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("trivy failed: %w: %s", err, lastLine(msg))
+		}
+		return nil, fmt.Errorf("trivy failed: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// lastLine returns the last line of s, where trivy puts the fatal error
+func lastLine(s string) string {
+	return s[strings.LastIndex(s, "\n")+1:]
+}
@@ -0,0 +1,96 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+const trivyOutput = `{
+  "SchemaVersion": 2,
+  "ArtifactName": "nginx:latest",
+  "Results": [
+    {"Target": "nginx:latest (debian 12.5)", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2024-3094", "PkgName": "xz-utils", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "CVE-2024-3094", "PkgName": "liblzma5", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "CVE-2023-4911", "PkgName": "libc6", "Severity": "HIGH"},
+      {"VulnerabilityID": "CVE-2023-1111", "PkgName": "curl", "Severity": "MEDIUM"}
+    ]},
+    {"Target": "usr/local/bin/app", "Vulnerabilities": [
+      {"VulnerabilityID": "GHSA-xxxx", "PkgName": "golang.org/x/net", "Severity": "LOW"},
+      {"VulnerabilityID": "CVE-2022-0000", "PkgName": "zlib", "Severity": "UNKNOWN"}
+    ]},
+    {"Target": "etc/os-release"}
+  ]
+}`
+
+func TestTrivy_Scan(t *testing.T) {
+	var gotArgs []string
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = append([]string{name}, args...)
+		return []byte(trivyOutput), nil
+	}
+	cfg := config.ScanConfig{Trivy: "/usr/local/bin/trivy", Server: "http://trivy:4954", IgnoreUnfixed: true, Timeout: 5 * time.Minute}
+	scanner, err := New(cfg, run)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	got, err := scanner.Scan(context.Background(), "nginx:latest")
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	want := Summary{Critical: 2, High: 1, Medium: 1, Low: 1, Unknown: 1, IDs: []string{"CVE-2024-3094"}}
+	if got.String() != want.String() || !slices.Equal(got.IDs, want.IDs) {
+		t.Errorf("Scan() = %+v, want %+v", got, want)
+	}
+	if got.String() != "2 critical, 1 high, 1 medium, 1 low, 1 unknown" {
+		t.Errorf("String() = %q", got.String())
+	}
+
+	args := strings.Join(gotArgs, " ")
+	for _, want := range []string{"/usr/local/bin/trivy image", "--format json", "--server http://trivy:4954", "--ignore-unfixed", "--timeout 5m0s"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("trivy ran as %q, missing %q", args, want)
+		}
+	}
+	if gotArgs[len(gotArgs)-1] != "nginx:latest" {
+		t.Errorf("trivy ran as %q, want the image last", args)
+	}
+}
+
+func TestTrivy_ScanFails(t *testing.T) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, errors.New("trivy failed: exit status 1: FATAL unable to connect to server")
+	}
+	scanner, _ := New(config.ScanConfig{Trivy: "trivy", Timeout: time.Minute}, run)
+	if _, err := scanner.Scan(context.Background(), "nginx:latest"); err == nil {
+		t.Error("Scan() succeeded, want trivy's error")
+	}
+
+	garbled := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("2024-06-01T10:00:00Z INFO Need to update DB"), nil
+	}
+	scanner, _ = New(config.ScanConfig{Trivy: "trivy", Timeout: time.Minute}, garbled)
+	if _, err := scanner.Scan(context.Background(), "nginx:latest"); err == nil || !strings.Contains(err.Error(), "failed to parse trivy output") {
+		t.Errorf("Scan() error = %v, want a parse error", err)
+	}
+}
+
+func TestNew_MissingBinary(t *testing.T) {
+	if _, err := New(config.ScanConfig{Trivy: "/nonexistent/trivy", Timeout: time.Minute}, nil); err == nil {
+		t.Error("New() succeeded without a trivy binary")
+	}
+}
+
+func TestTrivy_Applies(t *testing.T) {
+	scanner, _ := New(config.ScanConfig{Trivy: "trivy", Images: []string{"ghcr.io/acme/*"}}, func(context.Context, string, ...string) ([]byte, error) { return nil, nil })
+	if !scanner.Applies("ghcr.io/acme/api:1") || scanner.Applies("nginx:latest") {
+		t.Error("Applies() does not follow scan.images")
+	}
+}
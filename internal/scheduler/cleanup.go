@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/MikeO7/HarborBuddy/internal/cleanup"
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+)
+
+// phaseMu keeps an independently scheduled cleanup from pruning images while an update
+// cycle is between pulling an image and recreating the container from it
+var phaseMu sync.Mutex
+
+// runCleanupLoop runs cleanup on its own schedule until ctx is cancelled. The first run
+// happens at the next scheduled time, or one interval after startup.
+func runCleanupLoop(ctx context.Context, cfg config.Config, dockerClient docker.Client) {
+	location, err := time.LoadLocation(cfg.Updates.Timezone)
+	if err != nil {
+		log.ErrorErr("Cleanup schedule disabled", err)
+		return
+	}
+
+	for {
+		now := time.Now().In(location)
+		nextRun := now.Add(cfg.Cleanup.CheckInterval)
+		if cfg.Cleanup.ScheduleTime != "" {
+			nextRun = calculateNextRun(now, cfg.Cleanup.ScheduleTime, location)
+		}
+		log.Infof("🧹 Next cleanup: %s (%s)", nextRun.Format("2006-01-02 15:04:05 MST"), util.FormatRelative(nextRun, now))
+
+		timer := time.NewTimer(nextRun.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if err := runCleanupCycle(ctx, cfg, dockerClient); err != nil {
+				log.ErrorErr("Error in cleanup cycle", err)
+			}
+		}
+	}
+}
+
+// runCleanupCycle runs one cleanup outside the update cycle, with its own cycle ID
+func runCleanupCycle(ctx context.Context, cfg config.Config, dockerClient docker.Client) error {
+	if !cfg.CleanupOnly {
+		if _, ok := awaitDaemon(ctx, dockerClient); !ok {
+			return nil
+		}
+	}
+
+	if paused(cfg, "cleanup") {
+		return nil
+	}
+
+	cycleID := generateCycleID()
+	logger := log.WithFields(map[string]interface{}{"cycle_id": cycleID})
+	ctx = notify.WithCycleID(ctx, cycleID)
+	ctx = audit.WithCycleID(ctx, cycleID)
+	ctx, finishReport := startReport(ctx, cfg, cycleID)
+
+	phaseMu.Lock()
+	defer phaseMu.Unlock()
+
+	err := cleanup.RunCleanup(ctx, cfg, dockerClient, logger)
+	finishReport(err)
+	return err
+}
+
+// usageTrigger runs cleanup when the filesystem holding Docker's data root fills past a
+// threshold. It fires once per crossing, so a cleanup that can't free enough space doesn't
+// run again until usage has dropped back below the threshold.
+type usageTrigger struct {
+	cfg          config.Config
+	dockerClient docker.Client
+	threshold    float64
+	fired        bool
+}
+
+// runUsageTriggerLoop checks disk usage every cleanup.usage_check_interval until ctx is
+// cancelled. It gives up if the data root can't be read at startup.
+func runUsageTriggerLoop(ctx context.Context, cfg config.Config, dockerClient docker.Client) {
+	threshold, err := cfg.Cleanup.UsageThreshold()
+	if err != nil || threshold == 0 {
+		return
+	}
+
+	usage, err := dockerClient.DataRootUsage(ctx, cfg.Cleanup.DataRoot)
+	if err != nil {
+		log.ErrorErr("Disk usage cleanup trigger disabled", err)
+		return
+	}
+	log.Infof("💾 Cleanup will also run when %s is over %.0f%% full (now %.1f%%)", usage.Path, threshold, usage.UsedPercent())
+
+	trigger := &usageTrigger{cfg: cfg, dockerClient: dockerClient, threshold: threshold}
+	trigger.check(ctx)
+
+	ticker := time.NewTicker(cfg.Cleanup.UsageCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			trigger.check(ctx)
+		}
+	}
+}
+
+// check probes disk usage and runs cleanup if it has just crossed the threshold
+func (t *usageTrigger) check(ctx context.Context) {
+	usage, err := t.dockerClient.DataRootUsage(ctx, t.cfg.Cleanup.DataRoot)
+	if docker.IsConnectionError(err) {
+		// The update loop reports the outage and waits for the daemon
+		log.Debugf("Skipping disk usage check: %v", err)
+		return
+	}
+	if err != nil {
+		log.Warnf("Failed to check disk usage: %v", err)
+		return
+	}
+
+	percent := usage.UsedPercent()
+	if percent < t.threshold {
+		if t.fired {
+			log.Infof("💾 %s is back under %.0f%% full (%.1f%%)", usage.Path, t.threshold, percent)
+		}
+		t.fired = false
+		return
+	}
+	if t.fired {
+		log.Debugf("%s is still %.1f%% full, waiting for it to drop before cleaning up again", usage.Path, percent)
+		return
+	}
+
+	t.fired = true
+	log.Warnf("💾 %s is %.1f%% full (%s free), running cleanup", usage.Path, percent, util.FormatBytes(int64(usage.Available)))
+	if err := runCleanupCycle(ctx, t.cfg, t.dockerClient); err != nil {
+		log.ErrorErr("Error in disk usage cleanup", err)
+		return
+	}
+
+	if after, err := t.dockerClient.DataRootUsage(ctx, t.cfg.Cleanup.DataRoot); err == nil && after.UsedPercent() >= t.threshold {
+		log.Warnf("💾 %s is still %.1f%% full after cleanup; enable more cleanup options or free space manually", after.Path, after.UsedPercent())
+	}
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+func TestRunCleanupLoop_Interval(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:dangling", Dangling: true, CreatedAt: time.Now().Add(-48 * time.Hour)},
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.CheckInterval = 20 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 70*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		runCleanupLoop(ctx, cfg, mockClient)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runCleanupLoop did not exit on cancellation")
+	}
+
+	// Not at startup, then once per interval
+	if n := len(mockClient.RemovedImages); n < 1 || n > 3 {
+		t.Errorf("cleanup ran %d times in 70ms with a 20ms interval, want 1-3", n)
+	}
+}
+
+func TestRunCleanupLoop_InvalidTimezone(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cleanup.ScheduleTime = "04:00"
+	cfg.Updates.Timezone = "Nowhere/Special"
+
+	done := make(chan struct{})
+	go func() {
+		runCleanupLoop(context.Background(), cfg, docker.NewMockDockerClient())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runCleanupLoop should give up on an invalid timezone")
+	}
+}
+
+func TestUsageTrigger_FiresOncePerCrossing(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:dangling", Dangling: true, CreatedAt: time.Now().Add(-48 * time.Hour)},
+	}
+
+	cfg := config.Default()
+	cfg.Cleanup.TriggerAtUsage = "85%"
+	trigger := &usageTrigger{cfg: cfg, dockerClient: mockClient, threshold: 85}
+
+	full := docker.DiskUsage{Path: "/var/lib/docker", Used: 90, Available: 10}
+	roomy := docker.DiskUsage{Path: "/var/lib/docker", Used: 50, Available: 50}
+
+	steps := []struct {
+		usage docker.DiskUsage
+		runs  int // cleanup runs so far
+	}{
+		{roomy, 0},
+		{full, 1},
+		{full, 1}, // still over the threshold, no repeat
+		{roomy, 1},
+		{full, 2}, // crossed again
+	}
+
+	for i, step := range steps {
+		mockClient.DiskUsage = step.usage
+		trigger.check(context.Background())
+		if n := len(mockClient.RemovedImages); n != step.runs {
+			t.Fatalf("step %d: cleanup ran %d times, want %d", i, n, step.runs)
+		}
+	}
+}
+
+func TestRunUsageTriggerLoop_UnreadableDataRoot(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.DataRootUsageError = context.DeadlineExceeded
+
+	cfg := config.Default()
+	cfg.Cleanup.TriggerAtUsage = "85%"
+
+	done := make(chan struct{})
+	go func() {
+		runUsageTriggerLoop(context.Background(), cfg, mockClient)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runUsageTriggerLoop should give up when the data root can't be read")
+	}
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/updater"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+)
+
+// Backoff between pings while the Docker daemon is unreachable; variables so tests can shorten them
+var (
+	daemonRetryDelay    = time.Second
+	daemonRetryMaxDelay = time.Minute
+)
+
+// awaitDaemon returns once the Docker daemon answers, so a long-running scheduler rides out a
+// daemon restart instead of failing every step of every cycle until it is back. While the
+// daemon can't be reached it pings with a backoff, logging the outage once rather than on
+// every attempt. Other ping errors don't hold the cycle up; its own steps report them. It
+// returns false if ctx is cancelled first.
+func awaitDaemon(ctx context.Context, dockerClient docker.Client) (reconnected, ok bool) {
+	err := dockerClient.Ping(ctx)
+	if !docker.IsConnectionError(err) {
+		return false, ctx.Err() == nil
+	}
+
+	logger := log.WithFields(map[string]interface{}{"phase": "reconnect"})
+	logger.Warn().Err(err).Msg("🔌 Docker daemon unreachable, waiting for it to come back")
+
+	lost := time.Now()
+	delay := daemonRetryDelay
+	for docker.IsConnectionError(err) {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, false
+		case <-timer.C:
+		}
+		delay = min(delay*2, daemonRetryMaxDelay)
+
+		err = dockerClient.Ping(ctx)
+		if docker.IsConnectionError(err) {
+			logger.Debug().Err(err).Str("next_attempt", util.HumanizeDuration(delay)).Msg("Docker daemon still unreachable")
+		}
+	}
+
+	logger.Info().Str("downtime", util.HumanizeDuration(time.Since(lost))).Msg("🔌 Reconnected to the Docker daemon")
+	return true, ctx.Err() == nil
+}
+
+// resumeAfterReconnect finishes or undoes replacements the daemon's restart cut off, as at
+// startup, so the next cycle starts from containers in a known state
+func resumeAfterReconnect(ctx context.Context, cfg config.Config, dockerClient docker.Client) {
+	logger := log.WithFields(map[string]interface{}{"phase": "recovery"})
+	if err := updater.Recover(ctx, cfg, dockerClient, logger); err != nil {
+		logger.Error().Err(err).Msg("Some interrupted updates could not be recovered")
+	}
+	removeOrphans(ctx, cfg, dockerClient, logger)
+}
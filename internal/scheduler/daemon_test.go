@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+)
+
+// restartingDaemonClient refuses pings until the daemon has "restarted"
+type restartingDaemonClient struct {
+	*docker.MockDockerClient
+	downFor int32 // Pings refused before the daemon answers again
+	pings   *atomic.Int32
+}
+
+func (c restartingDaemonClient) Ping(ctx context.Context) error {
+	if c.pings.Add(1) <= c.downFor {
+		return fmt.Errorf("failed to ping docker daemon: %w", syscall.ECONNREFUSED)
+	}
+	return nil
+}
+
+func shortenDaemonRetry(t *testing.T) {
+	delay, maxDelay := daemonRetryDelay, daemonRetryMaxDelay
+	daemonRetryDelay, daemonRetryMaxDelay = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { daemonRetryDelay, daemonRetryMaxDelay = delay, maxDelay })
+}
+
+func TestRunCycle_WaitsForDaemon(t *testing.T) {
+	shortenDaemonRetry(t)
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:new"}}
+
+	cfg := config.Default()
+	cfg.State.File = ""
+	pings := &atomic.Int32{}
+	client := restartingDaemonClient{MockDockerClient: mockClient, downFor: 5, pings: pings}
+
+	if err := runCycle(context.Background(), cfg, client); err != nil {
+		t.Fatalf("runCycle() error = %v", err)
+	}
+	if got := pings.Load(); got != 6 {
+		t.Errorf("pings = %d, want 6", got)
+	}
+	if len(mockClient.ReplacedContainers) != 1 {
+		t.Errorf("replaced = %+v, want web updated once the daemon is back", mockClient.ReplacedContainers)
+	}
+}
+
+func TestAwaitDaemon(t *testing.T) {
+	shortenDaemonRetry(t)
+	mockClient := docker.NewMockDockerClient()
+
+	t.Run("reachable", func(t *testing.T) {
+		reconnected, ok := awaitDaemon(context.Background(), mockClient)
+		if reconnected || !ok {
+			t.Errorf("awaitDaemon() = %v, %v, want false, true", reconnected, ok)
+		}
+	})
+
+	t.Run("other errors don't hold the cycle up", func(t *testing.T) {
+		failing := docker.NewMockDockerClient()
+		failing.PingError = errors.New("Error response from daemon: 500 Internal Server Error")
+		reconnected, ok := awaitDaemon(context.Background(), failing)
+		if reconnected || !ok {
+			t.Errorf("awaitDaemon() = %v, %v, want false, true", reconnected, ok)
+		}
+	})
+
+	t.Run("shutdown while waiting", func(t *testing.T) {
+		down := restartingDaemonClient{MockDockerClient: mockClient, downFor: 1 << 30, pings: &atomic.Int32{}}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		reconnected, ok := awaitDaemon(ctx, down)
+		if reconnected || ok {
+			t.Errorf("awaitDaemon() = %v, %v, want false, false", reconnected, ok)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+)
+
+// runDigestLoop sends the digest of pending updates every day at notifications.digest until
+// ctx is cancelled
+func runDigestLoop(ctx context.Context, cfg config.Config) {
+	location, err := time.LoadLocation(cfg.Updates.Timezone)
+	if err != nil {
+		log.ErrorErr("Notification digest disabled", err)
+		return
+	}
+
+	for {
+		now := time.Now().In(location)
+		nextRun := calculateNextRun(now, cfg.Notifications.Digest, location)
+		log.Debugf("📰 Next notification digest: %s (%s)", nextRun.Format("2006-01-02 15:04:05 MST"), util.FormatRelative(nextRun, now))
+
+		timer := time.NewTimer(nextRun.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			sendDigest(ctx, cfg)
+		}
+	}
+}
+
+// sendDigest notifies the updates still pending in the state store as one summary: push
+// services get a single message and email a single mail, webhooks an event per update.
+// Nothing is sent if no update is pending.
+func sendDigest(ctx context.Context, cfg config.Config) {
+	logger := log.WithFields(map[string]interface{}{"phase": "digest"})
+	store, err := state.Open(cfg.State.File)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to read pending updates for the notification digest")
+		return
+	}
+	pending := store.PendingUpdates()
+	if len(pending) == 0 {
+		logger.Debug().Msg("No pending updates, skipping the notification digest")
+		return
+	}
+
+	names := make([]string, 0, len(pending))
+	for name := range pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	notifications := cfg.Notifications
+	notifications.Templates.Summary = true
+	notifier := notify.New(notifications)
+	for _, name := range names {
+		p := pending[name]
+		notify.Send(ctx, notifier, notify.Event{
+			Type:       notify.EventUpdateAvailable,
+			Outcome:    notify.OutcomeNotApplied,
+			Container:  name,
+			Image:      p.Image,
+			OldImageID: p.OldImageID,
+			NewImageID: p.NewImageID,
+			OldVersion: p.OldVersion,
+			NewVersion: p.NewVersion,
+			ReleaseURL: p.ReleaseURL,
+			Timestamp:  p.FoundAt,
+		}, logger)
+	}
+	notify.Flush(ctx, notifier, logger)
+	logger.Info().Msgf("📰 Sent the notification digest of %d pending updates", len(pending))
+}
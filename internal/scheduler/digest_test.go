@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+)
+
+func TestSendDigest(t *testing.T) {
+	var mu sync.Mutex
+	var containers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event struct {
+			Container string `json:"container"`
+		}
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		containers = append(containers, event.Container)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.Notifications.WebhookURL = server.URL
+	cfg.Notifications.Digest = "09:00"
+	cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+
+	sendDigest(context.Background(), cfg)
+	if len(containers) != 0 {
+		t.Fatalf("sent %v without pending updates, want nothing", containers)
+	}
+
+	store, err := state.Open(cfg.State.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetPending("web", state.Pending{Image: "nginx:latest", NewImageID: "sha256:nginx"})
+	store.SetPending("db", state.Pending{Image: "postgres:16", NewImageID: "sha256:pg"})
+
+	sendDigest(context.Background(), cfg)
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(containers, []string{"db", "web"}) {
+		t.Errorf("digest notified %v, want db and web", containers)
+	}
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+)
+
+// Variables so tests can shorten them
+var (
+	// startCheckDelay gathers a burst of events, like a compose project coming up, into one check
+	startCheckDelay = 10 * time.Second
+	// startWatchRetryDelay is the wait before resubscribing to a failed events stream
+	startWatchRetryDelay = 5 * time.Second
+)
+
+// startWatcher runs a targeted update check when a container starts or an image is pulled by
+// hand (updates.on_container_start), instead of leaving it to the next scheduled cycle.
+// Events arriving while a cycle runs are ignored: they are mostly the cycle's own
+// replacements and pulls, and the cycle checks every container anyway.
+type startWatcher struct {
+	cfg          config.Config
+	dockerClient docker.Client
+
+	started map[string]bool // IDs of containers started since the last check
+	pulled  map[string]bool // Images pulled since the last check
+}
+
+// runStartWatchLoop follows the Docker events stream until ctx is cancelled, resubscribing
+// when it drops
+func runStartWatchLoop(ctx context.Context, cfg config.Config, dockerClient docker.Client) {
+	log.Info("👂 Checking containers as they start (updates.on_container_start)")
+	w := &startWatcher{cfg: cfg, dockerClient: dockerClient}
+	for {
+		events, errs := dockerClient.Events(ctx)
+		err := w.consume(ctx, events, errs)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Warnf("Docker events stream interrupted, checks on container start paused until reconnected: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(startWatchRetryDelay):
+		}
+	}
+}
+
+// consume records start and pull events, checking what they name startCheckDelay after the
+// first of a burst, until the stream ends
+func (w *startWatcher) consume(ctx context.Context, events <-chan docker.Event, errs <-chan error) error {
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case <-flush:
+			flush = nil
+			w.check(ctx)
+		case event, ok := <-events:
+			if !ok {
+				return <-errs
+			}
+			if !w.record(event) {
+				continue
+			}
+			if flush == nil {
+				flush = time.After(startCheckDelay)
+			}
+		}
+	}
+}
+
+// record notes a container start or an image pull, and reports whether it did
+func (w *startWatcher) record(event docker.Event) bool {
+	isStart := event.Type == "container" && event.Action == "start"
+	isPull := event.Type == "image" && event.Action == "pull"
+	if !isStart && !isPull {
+		return false
+	}
+	if cycles.Status().Running {
+		log.Debugf("Ignoring %s %s event during an update cycle", event.Type, event.Action)
+		return false
+	}
+
+	if isStart {
+		if w.started == nil {
+			w.started = make(map[string]bool)
+		}
+		w.started[event.ActorID] = true
+	} else {
+		if w.pulled == nil {
+			w.pulled = make(map[string]bool)
+		}
+		w.pulled[familiarImage(event.ActorID)] = true
+	}
+	return true
+}
+
+// check runs an update cycle for the containers started, or running an image pulled, since
+// the last check
+func (w *startWatcher) check(ctx context.Context) {
+	started, pulled := w.started, w.pulled
+	w.started, w.pulled = nil, nil
+
+	containers, err := w.dockerClient.ListContainers(ctx)
+	if err != nil {
+		log.Warnf("Failed to list containers to check after they started: %v", err)
+		return
+	}
+	var targets []string
+	for _, c := range containers {
+		if started[c.ID] || pulled[familiarImage(c.Image)] {
+			targets = append(targets, c.Name)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	log.Infof("👂 Checking %s after a container start or image pull", strings.Join(targets, ", "))
+	runTargetedCycle(ctx, w.cfg, w.dockerClient, targets)
+}
+
+// familiarImage writes an image reference the way "docker ps" shows it, so a pulled
+// "docker.io/library/nginx" matches a container running "nginx:latest"
+func familiarImage(ref string) string {
+	ref = strings.TrimPrefix(ref, "docker.io/")
+	ref = strings.TrimPrefix(ref, "library/")
+	if !strings.Contains(ref, "@") {
+		if i := strings.LastIndex(ref, ":"); i < 0 || strings.Contains(ref[i:], "/") {
+			ref += ":latest"
+		}
+	}
+	return ref
+}
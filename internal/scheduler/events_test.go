@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestFamiliarImage(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                            "nginx:latest",
+		"nginx:1.25":                       "nginx:1.25",
+		"docker.io/library/nginx:1.25":     "nginx:1.25",
+		"docker.io/grafana/grafana":        "grafana/grafana:latest",
+		"ghcr.io/acme/app:v2":              "ghcr.io/acme/app:v2",
+		"localhost:5000/app":               "localhost:5000/app:latest",
+		"nginx@sha256:0123456789abcdef":    "nginx@sha256:0123456789abcdef",
+		"registry.example.com:443/app:dev": "registry.example.com:443/app:dev",
+	}
+	for ref, want := range tests {
+		if got := familiarImage(ref); got != want {
+			t.Errorf("familiarImage(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+func TestRunStartWatchLoop(t *testing.T) {
+	original, originalDelay := cycles, startCheckDelay
+	cycles = newTracker()
+	startCheckDelay = 10 * time.Millisecond
+	defer func() { cycles, startCheckDelay = original, originalDelay }()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.EventsChan = make(chan docker.Event)
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "web-id", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "cache-id", Name: "cache", Image: "redis", ImageID: "sha256:old-redis", Config: &container.Config{Image: "redis"}},
+		{ID: "db-id", Name: "db", Image: "postgres:16", ImageID: "sha256:old-postgres", Config: &container.Config{Image: "postgres:16"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runStartWatchLoop(ctx, config.Default(), mockClient)
+
+	mockClient.EventsChan <- docker.Event{Type: "container", Action: "start", ActorID: "web-id"}
+	mockClient.EventsChan <- docker.Event{Type: "container", Action: "die", ActorID: "db-id"}
+	mockClient.EventsChan <- docker.Event{Type: "image", Action: "pull", ActorID: "docker.io/library/redis:latest"}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for cycles.Status().LastCycle == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("no check ran after the events")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	pulled := mockClient.PulledImages
+	if len(pulled) != 2 || !slices.Contains(pulled, "nginx:latest") || !slices.Contains(pulled, "redis") {
+		t.Errorf("pulled %v, want only the started web and the pulled redis checked", pulled)
+	}
+}
+
+func TestStartWatcher_IgnoresEventsDuringCycle(t *testing.T) {
+	original := cycles
+	cycles = newTracker()
+	defer func() { cycles = original }()
+
+	w := &startWatcher{}
+	cycles.begin()
+	if w.record(docker.Event{Type: "container", Action: "start", ActorID: "web-id"}) {
+		t.Error("record() took a start event during a cycle")
+	}
+	cycles.finish("cycle", time.Now(), nil)
+	if !w.record(docker.Event{Type: "container", Action: "start", ActorID: "web-id"}) || !w.started["web-id"] {
+		t.Error("record() ignored a start event between cycles")
+	}
+}
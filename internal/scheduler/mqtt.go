@@ -0,0 +1,218 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Reconnection and publish timing of the MQTT link; variables for tests
+var (
+	mqttRetryDelay     = 5 * time.Second // Doubles after each failed first connect, up to mqttMaxRetryDelay
+	mqttMaxRetryDelay  = 5 * time.Minute // Also caps paho's reconnection backoff
+	mqttPublishTimeout = 10 * time.Second
+)
+
+// mqttBroker is the MQTT link of a long-running scheduler, nil unless mqtt.broker is set
+var mqttBroker *mqttLink
+
+// mqttLink keeps HarborBuddy connected to an MQTT broker. Under the topic prefix it keeps a
+// retained "status" of online or offline (the broker publishes offline if we vanish), a
+// retained "cycle" with the last cycle report, publishes notification events to "events" and
+// runs the commands sent to "command". Messages published while disconnected are dropped.
+type mqttLink struct {
+	cfg       config.MQTTConfig
+	tls       *tls.Config
+	onCommand func(command string)
+
+	mu     sync.Mutex
+	client paho.Client
+}
+
+// newMQTTLink prepares a link; run connects it
+func newMQTTLink(cfg config.MQTTConfig, onCommand func(command string)) (*mqttLink, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mqtt.ca_file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("mqtt.ca_file %s contains no PEM certificates", cfg.CAFile)
+		}
+	}
+	return &mqttLink{cfg: cfg, tls: tlsConfig, onCommand: onCommand}, nil
+}
+
+func (l *mqttLink) topic(name string) string {
+	return l.cfg.TopicPrefix + "/" + name
+}
+
+// brokerURL is mqtt.broker with the default port of its scheme filled in, which paho needs
+func brokerURL(broker string) string {
+	u, err := url.Parse(broker)
+	if err != nil || u.Port() != "" {
+		return broker
+	}
+	port := "1883"
+	if u.Scheme == "mqtts" || u.Scheme == "ssl" {
+		port = "8883"
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), port)
+	return u.String()
+}
+
+// options configures the paho client. Once connected it reconnects on its own; every
+// (re)connection subscribes to the command topic again and announces HarborBuddy online.
+func (l *mqttLink) options() *paho.ClientOptions {
+	opts := paho.NewClientOptions().
+		AddBroker(brokerURL(l.cfg.Broker)).
+		SetClientID(l.cfg.ClientID).
+		SetUsername(l.cfg.Username).
+		SetPassword(l.cfg.Password).
+		SetTLSConfig(l.tls).
+		SetCleanSession(true).
+		SetKeepAlive(30*time.Second).
+		SetConnectTimeout(mqttPublishTimeout).
+		SetWill(l.topic("status"), "offline", byte(l.cfg.QoS), true).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(mqttMaxRetryDelay).
+		// Commands can take a while to hand off, don't hold up the client's message loop
+		SetOrderMatters(false)
+
+	opts.SetOnConnectHandler(func(client paho.Client) {
+		token := client.Subscribe(l.topic("command"), byte(l.cfg.QoS), func(_ paho.Client, m paho.Message) {
+			l.onCommand(strings.TrimSpace(string(m.Payload())))
+		})
+		if err := waitToken(context.Background(), token); err != nil {
+			log.Warnf("Failed to subscribe to %s: %v", l.topic("command"), err)
+		}
+		token = client.Publish(l.topic("status"), byte(l.cfg.QoS), true, "online")
+		if err := waitToken(context.Background(), token); err != nil {
+			log.Warnf("Failed to publish to %s: %v", l.topic("status"), err)
+		}
+		log.Infof("📡 Connected to MQTT broker %s", l.cfg.Broker)
+	})
+	opts.SetConnectionLostHandler(func(_ paho.Client, err error) {
+		log.Warnf("Lost connection to MQTT broker %s, reconnecting: %v", l.cfg.Broker, err)
+	})
+	return opts
+}
+
+// run connects the link, retrying until the broker answers, and keeps it connected until ctx
+// ends, then marks HarborBuddy offline
+func (l *mqttLink) run(ctx context.Context) {
+	client := paho.NewClient(l.options())
+	delay := mqttRetryDelay
+	for {
+		err := waitToken(ctx, client.Connect())
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warnf("Failed to connect to MQTT broker %s, retrying in %v: %v", l.cfg.Broker, delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, mqttMaxRetryDelay)
+	}
+	l.setClient(client)
+
+	<-ctx.Done()
+	l.setClient(nil)
+	publishCtx, cancel := context.WithTimeout(context.Background(), mqttPublishTimeout)
+	_ = waitToken(publishCtx, client.Publish(l.topic("status"), byte(l.cfg.QoS), true, "offline"))
+	cancel()
+	client.Disconnect(250)
+}
+
+// waitToken waits for a paho operation to complete, up to mqttPublishTimeout or until ctx ends
+func waitToken(ctx context.Context, token paho.Token) error {
+	ctx, cancel := context.WithTimeout(ctx, mqttPublishTimeout)
+	defer cancel()
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *mqttLink) setClient(client paho.Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.client = client
+}
+
+// publish sends a message under the topic prefix, or drops it while disconnected
+func (l *mqttLink) publish(ctx context.Context, name string, payload []byte, retain bool) error {
+	l.mu.Lock()
+	client := l.client
+	l.mu.Unlock()
+	if client == nil || !client.IsConnectionOpen() {
+		log.Debugf("Not connected to the MQTT broker, dropping the message for %s", l.topic(name))
+		return nil
+	}
+
+	if err := waitToken(ctx, client.Publish(l.topic(name), byte(l.cfg.QoS), retain, payload)); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", l.topic(name), err)
+	}
+	return nil
+}
+
+// Notify implements notify.Notifier, publishing every event to <prefix>/events whatever the
+// notifications.on_* settings say, so automations can pick what they react to
+func (l *mqttLink) Notify(ctx context.Context, event notify.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return l.publish(ctx, "events", payload, false)
+}
+
+// runMQTTCommand runs a command sent to <prefix>/command: "run" starts a cycle like
+// POST /trigger, "update <container>..." checks and updates the named containers now
+func runMQTTCommand(ctx context.Context, cfg config.Config, dockerClient docker.Client, command string) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return
+	}
+
+	switch fields[0] {
+	case "run":
+		if cycles.request(audit.InitiatorMQTT) {
+			log.Info("📡 Cycle triggered over MQTT")
+		} else {
+			log.Info("📡 Cycle requested over MQTT, but one is already running or queued")
+		}
+	case "update":
+		if len(fields) == 1 {
+			log.Warn("Ignoring MQTT update command without container names")
+			return
+		}
+		log.Infof("📡 Updating %s as requested over MQTT", strings.Join(fields[1:], ", "))
+		cycles.targeted.add(ctx, cfg, dockerClient, audit.InitiatorMQTT, fields[1:])
+	default:
+		log.Warnf("Ignoring unknown MQTT command %q (use \"run\" or \"update <container>...\")", command)
+	}
+}
@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestRunMQTTCommand(t *testing.T) {
+	original := cycles
+	cycles = newTracker()
+	defer func() { cycles = original }()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "web-id", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "db-id", Name: "db", Image: "postgres:16", ImageID: "sha256:old-postgres", Config: &container.Config{Image: "postgres:16"}},
+	}
+	ctx := context.Background()
+
+	runMQTTCommand(ctx, config.Default(), mockClient, "bogus")
+	runMQTTCommand(ctx, config.Default(), mockClient, "update")
+	select {
+	case <-cycles.trigger:
+		t.Fatal("an invalid command triggered a cycle")
+	default:
+	}
+
+	runMQTTCommand(ctx, config.Default(), mockClient, "run")
+	select {
+	case initiator := <-cycles.trigger:
+		if initiator != audit.InitiatorMQTT {
+			t.Errorf("run queued a cycle for %q, want it audited as %q", initiator, audit.InitiatorMQTT)
+		}
+	default:
+		t.Error("run did not trigger a cycle")
+	}
+
+	runMQTTCommand(ctx, config.Default(), mockClient, "update web")
+	deadline := time.Now().Add(2 * time.Second)
+	for cycles.Status().LastCycle == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("update did not run a cycle")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pulled := mockClient.PulledImages; len(pulled) != 1 || pulled[0] != "nginx:latest" {
+		t.Errorf("pulled %v, want only web checked", pulled)
+	}
+}
+
+func TestNewMQTTLink_CAFile(t *testing.T) {
+	cfg := config.Default().MQTT
+	cfg.CAFile = filepath.Join(t.TempDir(), "ca.pem")
+	if _, err := newMQTTLink(cfg, nil); err == nil {
+		t.Error("newMQTTLink() accepted a missing CA file")
+	}
+
+	if err := os.WriteFile(cfg.CAFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newMQTTLink(cfg, nil); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("newMQTTLink() error = %v, want the CA file rejected", err)
+	}
+}
+
+func TestMQTTLink_DropsWhileDisconnected(t *testing.T) {
+	link, err := newMQTTLink(config.Default().MQTT, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := link.Notify(context.Background(), notify.Event{Type: notify.EventUpdateAvailable, Container: "web"}); err != nil {
+		t.Errorf("Notify() while disconnected = %v, want the event dropped", err)
+	}
+	if got := link.topic("cycle"); got != "harborbuddy/cycle" {
+		t.Errorf("topic() = %s, want harborbuddy/cycle", got)
+	}
+}
+
+func TestBrokerURL(t *testing.T) {
+	tests := map[string]string{
+		"mqtt://broker":     "mqtt://broker:1883",
+		"mqtts://broker":    "mqtts://broker:8883",
+		"ssl://broker":      "ssl://broker:8883",
+		"tcp://broker:1884": "tcp://broker:1884",
+		"mqtt://[::1]":      "mqtt://[::1]:1883",
+	}
+	for broker, want := range tests {
+		if got := brokerURL(broker); got != want {
+			t.Errorf("brokerURL(%s) = %s, want %s", broker, got, want)
+		}
+	}
+}
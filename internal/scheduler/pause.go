@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/pause"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+)
+
+// paused reports whether the pause switch is on, logging that what would have run is skipped
+func paused(cfg config.Config, what string) bool {
+	p := currentPause(cfg)
+	if p != nil {
+		log.Infof("⏸️ Skipping %s, HarborBuddy is %s", what, p)
+	}
+	return p != nil
+}
+
+// currentPause returns the pause in force, or nil. A pause file that can't be read counts as
+// a pause until resumed: it is there to stop HarborBuddy.
+func currentPause(cfg config.Config) *pause.Pause {
+	if cfg.State.PauseFile == "" {
+		return nil
+	}
+	p, ok, err := pause.Read(cfg.State.PauseFile, time.Now())
+	if err != nil {
+		return &pause.Pause{Reason: err.Error()}
+	}
+	if !ok {
+		return nil
+	}
+	return &p
+}
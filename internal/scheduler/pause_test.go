@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/api"
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+func TestRunCycle_Paused(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.ListContainersError = fmt.Errorf("docker error")
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:dangling", Dangling: true, CreatedAt: time.Now().Add(-48 * time.Hour)},
+	}
+
+	cfg := config.Default()
+	cfg.RunOnce = true
+	cfg.State.PauseFile = filepath.Join(t.TempDir(), "PAUSE")
+	if err := os.WriteFile(cfg.State.PauseFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runCycle(context.Background(), cfg, mockClient); err != nil {
+		t.Errorf("runCycle() while paused error = %v, want the cycle skipped", err)
+	}
+	if err := runCleanupCycle(context.Background(), cfg, mockClient); err != nil || len(mockClient.RemovedImages) != 0 {
+		t.Errorf("runCleanupCycle() while paused = %v, removed %v, want nothing done", err, mockClient.RemovedImages)
+	}
+
+	os.Remove(cfg.State.PauseFile)
+	if err := runCycle(context.Background(), cfg, mockClient); err == nil {
+		t.Error("runCycle() after resuming did not run")
+	}
+}
+
+func TestTracker_PauseResume(t *testing.T) {
+	tr := newTracker()
+	if _, err := tr.Pause(time.Hour, ""); err == nil {
+		t.Error("Pause() without a pause file succeeded")
+	}
+
+	cfg := config.Default()
+	cfg.State.PauseFile = filepath.Join(t.TempDir(), "PAUSE")
+	tr.setConfig(cfg)
+
+	if _, err := tr.Pause(4*time.Hour, "migration"); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if p := tr.Status().Paused; p == nil || p.Reason != "migration" || p.Until.IsZero() {
+		t.Errorf("Status().Paused = %+v, want the pause", p)
+	}
+
+	if err := tr.Resume(); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if p := tr.Status().Paused; p != nil {
+		t.Errorf("Status().Paused = %+v after resuming", p)
+	}
+	if err := tr.Resume(); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Resume() when not paused error = %v, want ErrNotFound", err)
+	}
+}
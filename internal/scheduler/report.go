@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+)
+
+// stdout receives the cycle report of a one-shot run with --output json
+var stdout io.Writer = os.Stdout
+
+// startReport attaches a cycle report to ctx when report.file or report.url is set, the
+// report is printed (--output json) or published to MQTT. The returned function finishes and delivers the report;
+// delivery failures are logged rather than returned so they never fail a cycle.
+func startReport(ctx context.Context, cfg config.Config, cycleID string) (context.Context, func(err error)) {
+	if !cfg.Report.Enabled() && cfg.Output != config.OutputJSON && mqttBroker == nil {
+		return ctx, func(error) {}
+	}
+
+	rep := report.New(cycleID, cfg.Updates.DryRun, cfg.Updates.MonitorOnly)
+	return report.WithReport(ctx, rep), func(err error) {
+		rep.Finish(err)
+		if cfg.Report.File != "" {
+			if err := rep.WriteFile(cfg.Report.File); err != nil {
+				log.Warnf("Failed to write cycle report: %v", err)
+			}
+		}
+		if cfg.Report.URL != "" {
+			// Still deliver the report of a cycle cut short by shutdown
+			if err := rep.Post(context.WithoutCancel(ctx), cfg.Report.URL, cfg.Report.Timeout); err != nil {
+				log.Warnf("Failed to send cycle report: %v", err)
+			}
+		}
+		if mqttBroker != nil {
+			var buf bytes.Buffer
+			if err := rep.Encode(&buf); err == nil {
+				err = mqttBroker.publish(context.WithoutCancel(ctx), "cycle", buf.Bytes(), true)
+			}
+			if err != nil {
+				log.Warnf("Failed to publish cycle report: %v", err)
+			}
+		}
+		if cfg.Output == config.OutputJSON {
+			if err := rep.Encode(stdout); err != nil {
+				log.Warnf("Failed to print cycle report: %v", err)
+			}
+		}
+	}
+}
@@ -2,19 +2,29 @@ package scheduler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"crypto/rand"
 	"encoding/hex"
 
+	"github.com/MikeO7/HarborBuddy/internal/api"
+	"github.com/MikeO7/HarborBuddy/internal/audit"
 	"github.com/MikeO7/HarborBuddy/internal/cleanup"
 	"github.com/MikeO7/HarborBuddy/internal/config"
 	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/selfupdate"
 	"github.com/MikeO7/HarborBuddy/internal/updater"
+	"github.com/MikeO7/HarborBuddy/internal/watchdog"
 	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
 )
 
 // Run starts the scheduler main loop
@@ -30,19 +40,122 @@ func Run(cfg config.Config, dockerClient docker.Client) error {
 
 	log.Info("HarborBuddy started")
 
+	// Docker actions are audited as a cycle's unless a one-shot command, the API or MQTT asked
+	if cfg.RunOnce || cfg.CleanupOnly || cfg.Rollback != "" {
+		ctx = audit.WithInitiator(ctx, audit.InitiatorCLI)
+	}
+
+	// The API only makes sense for long-running modes
+	if cfg.API.Listen != "" && !cfg.RunOnce && !cfg.CleanupOnly && cfg.Rollback == "" {
+		cycles.setConfig(cfg)
+		cycles.setExplainer(func(ctx context.Context, name string) (interface{}, error) {
+			explanation, err := updater.ExplainContainer(ctx, cfg, dockerClient, name, log.WithFields(map[string]interface{}{"phase": "api"}))
+			if errors.Is(err, updater.ErrNoSuchContainer) {
+				return nil, fmt.Errorf("%w: %v", api.ErrNotFound, err)
+			}
+			return explanation, err
+		})
+		cycles.setLister(func(ctx context.Context) (interface{}, error) {
+			return updater.List(ctx, cfg, dockerClient, log.WithFields(map[string]interface{}{"phase": "api"}))
+		})
+		cycles.setUpdater(func(name string) error {
+			return updateContainer(ctx, cfg, dockerClient, name)
+		})
+		cycles.setImagePushed(func(image string) ([]string, error) {
+			return checkPushedImage(ctx, cfg, dockerClient, image)
+		})
+		go func() {
+			server := api.NewServer(cycles, api.Options{
+				Listen:        cfg.API.Listen,
+				HistoryFile:   cfg.State.HistoryFile,
+				WebhookSecret: cfg.API.WebhookSecret,
+				Auth:          api.Auth{Token: cfg.API.Token, Username: cfg.API.Username, Password: cfg.API.Password},
+				TLSCert:       cfg.API.TLSCert,
+				TLSKey:        cfg.API.TLSKey,
+				ReadOnly:      cfg.API.ReadOnly,
+			})
+			if err := server.Run(ctx); err != nil {
+				log.ErrorErr("API server stopped", err)
+			}
+		}()
+	}
+
+	if cfg.MQTT.Broker != "" && !cfg.RunOnce && !cfg.CleanupOnly && cfg.Rollback == "" {
+		link, err := newMQTTLink(cfg.MQTT, func(command string) {
+			runMQTTCommand(ctx, cfg, dockerClient, command)
+		})
+		if err != nil {
+			return err
+		}
+		mqttBroker = link
+		go link.run(ctx)
+	}
+
+	// A self-update's helper is gone by now; say how the update that started us went
+	selfupdate.ReportLast(ctx, notify.New(cfg.Notifications))
+
+	// Finish or undo replacements a previous run was stopped in the middle of, before
+	// anything else touches those containers
+	if err := updater.Recover(ctx, cfg, dockerClient, log.WithFields(map[string]interface{}{"phase": "recovery"})); err != nil {
+		log.ErrorErr("Some interrupted updates could not be recovered", err)
+	}
+	removeOrphans(ctx, cfg, dockerClient, log.WithFields(map[string]interface{}{"phase": "recovery"}))
+
+	// Rollback mode
+	if cfg.Rollback != "" {
+		log.Infof("Rolling back container %s", cfg.Rollback)
+		cycleID := generateCycleID()
+		logger := log.WithFields(map[string]interface{}{"cycle_id": cycleID})
+		return updater.Rollback(audit.WithCycleID(ctx, cycleID), cfg, dockerClient, cfg.Rollback, logger)
+	}
+
 	// Run once mode
 	if cfg.RunOnce {
-		log.Info("Running in once mode")
+		if len(cfg.Targets) > 0 {
+			log.Infof("Updating %s", strings.Join(cfg.Targets, ", "))
+		} else {
+			log.Info("Running in once mode")
+		}
 		return runCycle(ctx, cfg, dockerClient)
 	}
 
 	// Cleanup only mode
 	if cfg.CleanupOnly {
 		log.Info("Running in cleanup-only mode")
-		// For one-off mode, we generate a cycle ID too
-		cycleID := generateCycleID()
-		logger := log.WithFields(map[string]interface{}{"cycle_id": cycleID})
-		return cleanup.RunCleanup(ctx, cfg, dockerClient, logger)
+		return runCleanupCycle(ctx, cfg, dockerClient)
+	}
+
+	if cfg.Cleanup.Enabled && cfg.Cleanup.TriggerAtUsage != "" {
+		go runUsageTriggerLoop(ctx, cfg, dockerClient)
+	}
+
+	// Cleanup with its own schedule runs alongside the update loop rather than after each cycle
+	if cfg.Cleanup.Enabled && cfg.Cleanup.Independent() {
+		if cfg.Cleanup.RunBeforeUpdates {
+			log.Warn("cleanup.run_before_updates is ignored: cleanup has its own schedule")
+		}
+		go runCleanupLoop(ctx, cfg, dockerClient)
+		cfg.Cleanup.Enabled = false
+	}
+
+	if cfg.Notifications.Digest != "" {
+		if cfg.State.File == "" {
+			log.Warn("notifications.digest needs state.file to remember pending updates, no digest will be sent")
+		} else {
+			go runDigestLoop(ctx, cfg)
+		}
+	}
+
+	if cfg.Updates.Enabled && cfg.Updates.OnContainerStart {
+		go func() {
+			// Containers started at boot are left to the first cycle, after updates.initial_delay
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(cfg.Updates.InitialDelay):
+			}
+			runStartWatchLoop(ctx, cfg, dockerClient)
+		}()
 	}
 
 	// Normal loop mode - check if using scheduled time or interval
@@ -57,14 +170,20 @@ func Run(cfg config.Config, dockerClient docker.Client) error {
 func runIntervalMode(ctx context.Context, cfg config.Config, dockerClient docker.Client) error {
 	log.Infof("Starting scheduler with interval: %v", cfg.Updates.CheckInterval)
 
-	// Run initial cycle immediately
+	// Run the initial cycle immediately, or once updates.initial_delay has let the host settle
+	if !awaitInitialDelay(ctx, cfg, dockerClient) {
+		log.Info("Scheduler stopped")
+		return nil
+	}
 	if err := runCycle(ctx, cfg, dockerClient); err != nil {
 		log.ErrorErr("Error in initial cycle", err)
 	}
+	log.Infof("⏳ Next check in %s", util.HumanizeDuration(cfg.Updates.CheckInterval))
 
 	// Set up ticker for periodic cycles
 	ticker := time.NewTicker(cfg.Updates.CheckInterval)
 	defer ticker.Stop()
+	cycles.setNextRun(time.Now().Add(cfg.Updates.CheckInterval))
 
 	for {
 		select {
@@ -75,6 +194,40 @@ func runIntervalMode(ctx context.Context, cfg config.Config, dockerClient docker
 			if err := runCycle(ctx, cfg, dockerClient); err != nil {
 				log.ErrorErr("Error in update cycle", err)
 			}
+			cycles.setNextRun(time.Now().Add(cfg.Updates.CheckInterval))
+			log.Infof("⏳ Next check in %s", util.HumanizeDuration(cfg.Updates.CheckInterval))
+		case initiator := <-cycles.trigger:
+			log.Info("Running on-demand cycle")
+			if err := runCycle(audit.WithInitiator(ctx, initiator), cfg, dockerClient); err != nil {
+				log.ErrorErr("Error in on-demand cycle", err)
+			}
+		}
+	}
+}
+
+// awaitInitialDelay waits updates.initial_delay before the first interval cycle, e.g. so a
+// host that just booted isn't checked while its services are still starting. On-demand
+// cycles still run meanwhile. It returns false if ctx is cancelled first.
+func awaitInitialDelay(ctx context.Context, cfg config.Config, dockerClient docker.Client) bool {
+	if cfg.Updates.InitialDelay <= 0 {
+		return true
+	}
+	log.Infof("⏳ Delaying the first check by %s (updates.initial_delay)", util.HumanizeDuration(cfg.Updates.InitialDelay))
+	cycles.setNextRun(time.Now().Add(cfg.Updates.InitialDelay))
+
+	timer := time.NewTimer(cfg.Updates.InitialDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case initiator := <-cycles.trigger:
+			log.Info("Running on-demand cycle")
+			if err := runCycle(audit.WithInitiator(ctx, initiator), cfg, dockerClient); err != nil {
+				log.ErrorErr("Error in on-demand cycle", err)
+			}
 		}
 	}
 }
@@ -94,7 +247,8 @@ func runScheduledMode(ctx context.Context, cfg config.Config, dockerClient docke
 		nextRun := calculateNextRun(now, cfg.Updates.ScheduleTime, location)
 		waitDuration := nextRun.Sub(now)
 
-		log.Infof("⏳ Next scheduled run: %s (in %v)", nextRun.Format("2006-01-02 15:04:05 MST"), waitDuration.Round(time.Second))
+		log.Infof("⏳ Next scheduled run: %s (%s)", nextRun.Format("2006-01-02 15:04:05 MST"), util.FormatRelative(nextRun, now))
+		cycles.setNextRun(nextRun)
 
 		// Wait until scheduled time or cancellation
 		timer := time.NewTimer(waitDuration)
@@ -108,6 +262,13 @@ func runScheduledMode(ctx context.Context, cfg config.Config, dockerClient docke
 			if err := runCycle(ctx, cfg, dockerClient); err != nil {
 				log.ErrorErr("Error in scheduled cycle", err)
 			}
+		case initiator := <-cycles.trigger:
+			// Run now; the loop then waits for the same scheduled time again
+			timer.Stop()
+			log.Info("Running on-demand cycle")
+			if err := runCycle(audit.WithInitiator(ctx, initiator), cfg, dockerClient); err != nil {
+				log.ErrorErr("Error in on-demand cycle", err)
+			}
 		}
 	}
 }
@@ -137,15 +298,67 @@ func calculateNextRun(now time.Time, scheduleTime string, location *time.Locatio
 }
 
 // runCycle runs a single update and cleanup cycle
-func runCycle(ctx context.Context, cfg config.Config, dockerClient docker.Client) error {
+func runCycle(ctx context.Context, cfg config.Config, dockerClient docker.Client) (err error) {
+	// A long-running scheduler waits out a daemon restart rather than failing the cycle
+	reconnected := false
+	if !cfg.RunOnce {
+		var ok bool
+		if reconnected, ok = awaitDaemon(ctx, dockerClient); !ok {
+			return nil
+		}
+	}
+
+	if paused(cfg, "the update & cleanup cycle") {
+		return nil
+	}
+
 	cycleID := generateCycleID()
 	// Create a scoped logger for this cycle
 	cycleLogger := log.WithFields(map[string]interface{}{"cycle_id": cycleID})
+	ctx = notify.WithCycleID(ctx, cycleID)
+	ctx = audit.WithCycleID(ctx, cycleID)
+
+	// Share one notifier across update and cleanup so batched notifications cover the whole cycle
+	notifier := notify.New(cfg.Notifications)
+	if mqttBroker != nil {
+		notifier = notify.Join(notifier, mqttBroker)
+	}
+	ctx = notify.WithNotifier(ctx, notifier)
+	defer notify.Flush(ctx, notifier, cycleLogger)
+
+	ctx, finishReport := startReport(ctx, cfg, cycleID)
+	defer func() { finishReport(err) }()
+
+	phaseMu.Lock()
+	defer phaseMu.Unlock()
+
+	if reconnected {
+		resumeAfterReconnect(ctx, cfg, dockerClient)
+	}
+
+	startTime := time.Now()
+	cycles.begin()
+	defer func() { cycles.finish(cycleID, startTime, err) }()
+
+	// Runs first of the deferred calls, so the report and status see a timeout as the error
+	ctx, endWatch := startWatchdog(ctx, cfg, cycleLogger)
+	defer func() { err = endWatch(err) }()
 
 	cycleLogger.Info().Msg("➖➖➖➖ Starting update & cleanup cycle ➖➖➖➖")
 	cycleLogger.Info().Msgf("⚙️ Configuration: Updates=%v, DryRun=%v, Cleanup=%v",
 		cfg.Updates.Enabled, cfg.Updates.DryRun, cfg.Cleanup.Enabled)
 
+	// cleanup.run_before_updates frees space before new images are pulled, for small disks. A
+	// failed cleanup doesn't hold the updates back; the cycle still reports it at the end.
+	cleanupFirst := cfg.Cleanup.Enabled && cfg.Cleanup.RunBeforeUpdates
+	var cleanupErr error
+	if cleanupFirst {
+		cycleLogger.Info().Msg("🧹 Cleaning up before updates (cleanup.run_before_updates)")
+		if cleanupErr = runCleanupPhase(ctx, cfg, dockerClient, cycleLogger); cleanupErr != nil {
+			cycleLogger.Error().Err(cleanupErr).Msg("Cleanup before updates failed, updating anyway")
+		}
+	}
+
 	// Run updates if enabled
 	if cfg.Updates.Enabled {
 		if err := updater.RunUpdateCycle(ctx, cfg, dockerClient, cycleLogger); err != nil {
@@ -155,19 +368,63 @@ func runCycle(ctx context.Context, cfg config.Config, dockerClient docker.Client
 		cycleLogger.Info().Msg("Updates are disabled, skipping update cycle")
 	}
 
-	// Run cleanup if enabled
-	if cfg.Cleanup.Enabled {
-		if err := cleanup.RunCleanup(ctx, cfg, dockerClient, cycleLogger); err != nil {
-			return err
-		}
-	} else {
-		cycleLogger.Debug().Msg("Cleanup is disabled, skipping")
+	if !cleanupFirst {
+		cleanupErr = runCleanupPhase(ctx, cfg, dockerClient, cycleLogger)
+	}
+	if cleanupErr != nil {
+		return cleanupErr
 	}
 
 	cycleLogger.Info().Msg("➖➖➖➖ Cycle complete ➖➖➖➖")
 	return nil
 }
 
+// runCleanupPhase sweeps up leftover containers and, if enabled, runs cleanup
+func runCleanupPhase(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) error {
+	removeOrphans(ctx, cfg, dockerClient, logger)
+
+	if !cfg.Cleanup.Enabled {
+		logger.Debug().Msg("Cleanup is disabled, skipping")
+		return nil
+	}
+	return cleanup.RunCleanup(ctx, cfg, dockerClient, logger)
+}
+
+// runTargetedCycle runs a cycle for the named containers only, like "harborbuddy update",
+// leaving cleanup to the scheduled cycles
+func runTargetedCycle(ctx context.Context, cfg config.Config, dockerClient docker.Client, targets []string) {
+	cfg.Targets = targets
+	cfg.Cleanup.Enabled = false
+	if err := runCycle(ctx, cfg, dockerClient); err != nil {
+		log.ErrorErr("Error in targeted cycle", err)
+	}
+}
+
+// updateContainer queues a targeted cycle for the named container, as asked through the API
+func updateContainer(ctx context.Context, cfg config.Config, dockerClient docker.Client, name string) error {
+	containers, err := dockerClient.ListContainers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+	for _, c := range containers {
+		if c.Name == name {
+			log.Infof("Updating %s as requested through the API", name)
+			cycles.targeted.add(ctx, cfg, dockerClient, audit.InitiatorAPI, []string{name})
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no running container named %s", api.ErrNotFound, name)
+}
+
+// removeOrphans sweeps up backup and helper containers left by failed updates. It is
+// housekeeping for HarborBuddy's own mess, so failures are logged rather than failing the cycle.
+func removeOrphans(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) {
+	defer watchdog.Begin(ctx, "removing leftover containers")()
+	if _, err := cleanup.RemoveOrphans(ctx, cfg, dockerClient, logger); err != nil && ctx.Err() == nil {
+		logger.Error().Err(err).Msg("Failed to remove leftover containers")
+	}
+}
+
 // generateCycleID returns a short random ID for the cycle
 func generateCycleID() string {
 	b := make([]byte, 4) // 4 bytes = 8 hex chars
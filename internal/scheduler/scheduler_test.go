@@ -1,14 +1,25 @@
 package scheduler
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/MikeO7/HarborBuddy/internal/api"
 	"github.com/MikeO7/HarborBuddy/internal/config"
 	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/report"
 	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/docker/docker/api/types/container"
 )
 
 func init() {
@@ -428,6 +439,113 @@ func TestRunIntervalMode_Loop(t *testing.T) {
 	// without injecting a spy, but we know MockClient tracks pulls)
 }
 
+func TestRunIntervalMode_InitialDelay(t *testing.T) {
+	original := cycles
+	cycles = newTracker()
+	defer func() { cycles = original }()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "web-id", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	cfg := config.Default()
+	cfg.Updates.InitialDelay = time.Hour
+	cfg.Cleanup.Enabled = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := runIntervalMode(ctx, cfg, mockClient); err != nil {
+		t.Fatalf("runIntervalMode() error = %v", err)
+	}
+	if len(mockClient.PulledImages) != 0 {
+		t.Errorf("pulled %v during the initial delay, want nothing", mockClient.PulledImages)
+	}
+	if next := cycles.Status().NextRun; next == nil || time.Until(*next) < 59*time.Minute {
+		t.Errorf("next run = %v, want the end of the initial delay", next)
+	}
+
+	// An on-demand cycle doesn't wait for the delay
+	cycles.trigger <- "api"
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := runIntervalMode(ctx, cfg, mockClient); err != nil {
+		t.Fatalf("runIntervalMode() error = %v", err)
+	}
+	if len(mockClient.PulledImages) != 1 {
+		t.Errorf("pulled %v, want the on-demand cycle to check web", mockClient.PulledImages)
+	}
+}
+
+// orderedClient records the order of pulls and image removals
+type orderedClient struct {
+	*docker.MockDockerClient
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *orderedClient) PullImage(ctx context.Context, image string) (docker.ImageInfo, error) {
+	c.mu.Lock()
+	c.calls = append(c.calls, "pull "+image)
+	c.mu.Unlock()
+	return c.MockDockerClient.PullImage(ctx, image)
+}
+
+func (c *orderedClient) RemoveImage(ctx context.Context, id string) error {
+	c.mu.Lock()
+	c.calls = append(c.calls, "remove "+id)
+	c.mu.Unlock()
+	return c.MockDockerClient.RemoveImage(ctx, id)
+}
+
+func TestRunCycle_CleanupBeforeUpdates(t *testing.T) {
+	for _, before := range []bool{false, true} {
+		mockClient := docker.NewMockDockerClient()
+		mockClient.Containers = []docker.ContainerInfo{
+			{ID: "web-id", Name: "web", Image: "nginx:latest", ImageID: "sha256:nginx", Config: &container.Config{Image: "nginx:latest"}},
+		}
+		mockClient.Images = []docker.ImageInfo{{ID: "sha256:dangling", Dangling: true, CreatedAt: time.Now().Add(-48 * time.Hour)}}
+		mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:nginx"}}
+		client := &orderedClient{MockDockerClient: mockClient}
+
+		cfg := config.Default()
+		cfg.RunOnce = true
+		cfg.Cleanup.Enabled = true
+		cfg.Cleanup.RunBeforeUpdates = before
+		if err := runCycle(context.Background(), cfg, client); err != nil {
+			t.Fatalf("runCycle() error = %v", err)
+		}
+
+		want := []string{"pull nginx:latest", "remove sha256:dangling"}
+		if before {
+			want = []string{"remove sha256:dangling", "pull nginx:latest"}
+		}
+		if !slices.Equal(client.calls, want) {
+			t.Errorf("run_before_updates=%v: calls = %v, want %v", before, client.calls, want)
+		}
+	}
+}
+
+func TestRunCycle_CleanupBeforeUpdatesFails(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "web-id", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:new-nginx"}}
+	mockClient.ListDanglingImagesError = fmt.Errorf("cleanup error")
+
+	cfg := config.Default()
+	cfg.RunOnce = true
+	cfg.Cleanup.Enabled = true
+	cfg.Cleanup.DanglingOnly = true
+	cfg.Cleanup.RunBeforeUpdates = true
+	if err := runCycle(context.Background(), cfg, mockClient); err == nil {
+		t.Error("runCycle() = nil, want the cleanup error reported")
+	}
+	if len(mockClient.ReplacedContainers) != 1 {
+		t.Errorf("replaced %v, want web updated despite the failed cleanup", mockClient.ReplacedContainers)
+	}
+}
+
 func TestRunCycle_UpdateError(t *testing.T) {
 	t.Log("Testing runCycle with update error")
 
@@ -596,3 +714,128 @@ func TestRunScheduledMode_CycleError(t *testing.T) {
 		t.Errorf("runScheduledMode should not propagate cycle error: %v", err)
 	}
 }
+
+func TestRunCycle_WritesReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.ListContainersError = fmt.Errorf("docker error")
+
+	cfg := config.Default()
+	cfg.Report.File = path
+
+	if err := runCycle(context.Background(), cfg, mockClient); err == nil {
+		t.Fatal("Expected error from runCycle when update fails")
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	var rep report.Report
+	if err := json.Unmarshal(raw, &rep); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	if rep.Outcome != report.OutcomeFailure || rep.Error != "docker error" || rep.CycleID == "" {
+		t.Errorf("report = %+v, want a failed cycle with its error and ID", &rep)
+	}
+}
+
+func TestRunCycle_PrintsJSONReport(t *testing.T) {
+	var buf bytes.Buffer
+	original := stdout
+	stdout = &buf
+	defer func() { stdout = original }()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:new"}}
+
+	cfg := config.Default()
+	cfg.RunOnce = true
+	cfg.Output = config.OutputJSON
+
+	if err := runCycle(context.Background(), cfg, mockClient); err != nil {
+		t.Fatalf("runCycle() error = %v", err)
+	}
+
+	var rep report.Report
+	if err := json.Unmarshal(buf.Bytes(), &rep); err != nil {
+		t.Fatalf("stdout is not a JSON report: %v\n%s", err, buf.String())
+	}
+	if rep.Outcome != report.OutcomeSuccess || len(rep.Updated) != 1 || rep.Updated[0].Name != "web" {
+		t.Errorf("report = %+v, want web updated", &rep)
+	}
+}
+
+// hangingPullClient never finishes a pull until its context is done, like a wedged daemon
+type hangingPullClient struct {
+	*docker.MockDockerClient
+}
+
+func (c hangingPullClient) PullImage(ctx context.Context, image string) (docker.ImageInfo, error) {
+	<-ctx.Done()
+	return docker.ImageInfo{}, ctx.Err()
+}
+
+func TestRunCycle_Timeout(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old", Config: &container.Config{Image: "nginx:latest"}},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.CycleTimeout = 50 * time.Millisecond
+	cfg.Updates.PullRetries = 0
+
+	done := make(chan error, 1)
+	go func() { done <- runCycle(context.Background(), cfg, hangingPullClient{mockClient}) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("runCycle() error = %v, want a deadline error", err)
+		}
+		if !strings.Contains(err.Error(), "while checking web (nginx:latest), pulling nginx:latest") {
+			t.Errorf("runCycle() error = %q, want it to name the stuck steps", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runCycle() did not stop at the cycle timeout")
+	}
+
+	if status := cycles.Status(); status.LastCycle == nil || status.LastCycle.Success {
+		t.Errorf("last cycle = %+v, want a failure", status.LastCycle)
+	}
+}
+
+func TestUpdateContainer(t *testing.T) {
+	original := cycles
+	cycles = newTracker()
+	defer func() { cycles = original }()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "web-id", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "db-id", Name: "db", Image: "postgres:16", ImageID: "sha256:old-postgres", Config: &container.Config{Image: "postgres:16"}},
+	}
+
+	if err := updateContainer(context.Background(), config.Default(), mockClient, "missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("updateContainer(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := updateContainer(context.Background(), config.Default(), mockClient, "web"); err != nil {
+		t.Fatalf("updateContainer(web) error = %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for cycles.Status().LastCycle == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("no cycle ran for the container")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pulled := mockClient.PulledImages; len(pulled) != 1 || pulled[0] != "nginx:latest" {
+		t.Errorf("pulled %v, want only web checked", pulled)
+	}
+}
@@ -0,0 +1,269 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/api"
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/pause"
+	"github.com/MikeO7/HarborBuddy/internal/updater"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+)
+
+// tracker records cycle results and queues on-demand runs for the API
+type tracker struct {
+	mu      sync.Mutex
+	running bool
+	last    *api.CycleResult
+	nextRun time.Time
+
+	trigger  chan string // holds at most one pending on-demand run, by its audit initiator
+	targeted targetQueue // containers asked for by name, checked in coalesced targeted cycles
+
+	explain     func(ctx context.Context, name string) (interface{}, error) // set by Run
+	list        func(ctx context.Context) (interface{}, error)              // set by Run
+	update      func(name string) error                                     // set by Run
+	imagePushed func(image string) ([]string, error)                        // set by Run
+	cfg         config.Config                                               // set by Run, for freezes and pausing
+
+	logStart uint64 // Last log line before the current or last cycle began
+}
+
+// cycles is the scheduler's tracker, exposed to the API as its Controller
+var cycles = newTracker()
+
+func newTracker() *tracker {
+	return &tracker{trigger: make(chan string, 1)}
+}
+
+// Status implements api.Controller
+func (t *tracker) Status() api.Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := api.Status{Running: t.running, Paused: currentPause(t.cfg)}
+	if t.last != nil {
+		last := *t.last
+		status.LastCycle = &last
+	}
+	if !t.nextRun.IsZero() {
+		next := t.nextRun
+		status.NextRun = &next
+	}
+	return status
+}
+
+// Explain implements api.Controller
+func (t *tracker) Explain(ctx context.Context, name string) (interface{}, error) {
+	t.mu.Lock()
+	explain := t.explain
+	t.mu.Unlock()
+	if explain == nil {
+		return nil, errors.New("explain is not available yet")
+	}
+	return explain(ctx, name)
+}
+
+// Containers implements api.Controller
+func (t *tracker) Containers(ctx context.Context) (interface{}, error) {
+	t.mu.Lock()
+	list := t.list
+	t.mu.Unlock()
+	if list == nil {
+		return nil, errors.New("the container list is not available yet")
+	}
+	return list(ctx)
+}
+
+// Update implements api.Controller
+func (t *tracker) Update(name string) error {
+	t.mu.Lock()
+	update := t.update
+	t.mu.Unlock()
+	if update == nil {
+		return errors.New("updates are not available yet")
+	}
+	return update(name)
+}
+
+// RedactedConfig implements api.Controller
+func (t *tracker) RedactedConfig() interface{} {
+	return t.config().Redacted()
+}
+
+// Logs implements api.Controller
+func (t *tracker) Logs(after uint64) []log.Line {
+	t.mu.Lock()
+	after = max(after, t.logStart)
+	t.mu.Unlock()
+	return log.Recent(after)
+}
+
+// Freeze implements api.Controller
+func (t *tracker) Freeze(name string, d time.Duration, reason string) (interface{}, error) {
+	frozen, err := updater.Freeze(t.config(), name, d, reason)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("❄️ Froze container %s: %s", name, frozen)
+	return frozen, nil
+}
+
+// Unfreeze implements api.Controller
+func (t *tracker) Unfreeze(name string) error {
+	unfrozen, err := updater.Unfreeze(t.config(), name)
+	if err != nil {
+		return err
+	}
+	if !unfrozen {
+		return fmt.Errorf("%w: container %s is not frozen", api.ErrNotFound, name)
+	}
+	log.Infof("Unfroze container %s", name)
+	return nil
+}
+
+// Frozen implements api.Controller
+func (t *tracker) Frozen() (interface{}, error) {
+	return updater.Frozen(t.config())
+}
+
+// Pause implements api.Controller
+func (t *tracker) Pause(d time.Duration, reason string) (interface{}, error) {
+	cfg := t.config()
+	if cfg.State.PauseFile == "" {
+		return nil, errors.New("pausing needs a pause file; set state.pause_file (HARBORBUDDY_PAUSE_FILE)")
+	}
+	p, err := pause.Set(cfg.State.PauseFile, d, reason)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("⏸️ HarborBuddy is %s", p)
+	return p, nil
+}
+
+// Resume implements api.Controller
+func (t *tracker) Resume() error {
+	cfg := t.config()
+	if cfg.State.PauseFile == "" {
+		return fmt.Errorf("%w: HarborBuddy is not paused", api.ErrNotFound)
+	}
+	resumed, err := pause.Clear(cfg.State.PauseFile)
+	if err != nil {
+		return err
+	}
+	if !resumed {
+		return fmt.Errorf("%w: HarborBuddy is not paused", api.ErrNotFound)
+	}
+	log.Info("▶️ HarborBuddy resumed")
+	return nil
+}
+
+// config returns the configuration Run set, for freezes and the pause switch
+func (t *tracker) config() config.Config {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cfg
+}
+
+// setConfig gives the tracker the configuration freezes are kept under
+func (t *tracker) setConfig(cfg config.Config) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cfg = cfg
+}
+
+// Trigger implements api.Controller
+func (t *tracker) Trigger() bool {
+	return t.request(audit.InitiatorAPI)
+}
+
+// request queues an on-demand run asked for by initiator. It returns false if one is already
+// running or queued.
+func (t *tracker) request(initiator string) bool {
+	t.mu.Lock()
+	running := t.running
+	t.mu.Unlock()
+	if running {
+		return false
+	}
+
+	select {
+	case t.trigger <- initiator:
+		return true
+	default:
+		return false // already queued
+	}
+}
+
+// ImagePushed implements api.Controller
+func (t *tracker) ImagePushed(image string) ([]string, error) {
+	t.mu.Lock()
+	imagePushed := t.imagePushed
+	t.mu.Unlock()
+	if imagePushed == nil {
+		return nil, errors.New("the webhook is not available yet")
+	}
+	return imagePushed(image)
+}
+
+func (t *tracker) setImagePushed(imagePushed func(image string) ([]string, error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.imagePushed = imagePushed
+}
+
+func (t *tracker) setLister(list func(ctx context.Context) (interface{}, error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.list = list
+}
+
+func (t *tracker) setUpdater(update func(name string) error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.update = update
+}
+
+func (t *tracker) setExplainer(explain func(ctx context.Context, name string) (interface{}, error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.explain = explain
+}
+
+func (t *tracker) begin() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = true
+	t.logStart = log.LastSeq()
+}
+
+func (t *tracker) finish(id string, started time.Time, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	finished := time.Now()
+	result := &api.CycleResult{
+		ID:         id,
+		StartedAt:  started,
+		FinishedAt: finished,
+		Duration:   finished.Sub(started).Round(time.Millisecond).String(),
+		Success:    err == nil,
+	}
+	if err != nil {
+		result.Error = log.Redact(err.Error())
+	}
+
+	t.running = false
+	t.last = result
+}
+
+func (t *tracker) setNextRun(next time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextRun = next
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+)
+
+func TestTracker_Status(t *testing.T) {
+	tr := newTracker()
+
+	if status := tr.Status(); status.Running || status.LastCycle != nil || status.NextRun != nil {
+		t.Errorf("initial status = %+v, want empty", status)
+	}
+
+	started := time.Now()
+	tr.begin()
+	if !tr.Status().Running {
+		t.Error("Running should be true during a cycle")
+	}
+
+	tr.finish("abcd1234", started, errors.New("docker error"))
+	next := time.Now().Add(time.Hour)
+	tr.setNextRun(next)
+
+	status := tr.Status()
+	if status.Running {
+		t.Error("Running should be false after a cycle")
+	}
+	if status.LastCycle == nil || status.LastCycle.ID != "abcd1234" || status.LastCycle.Success || status.LastCycle.Error != "docker error" {
+		t.Errorf("LastCycle = %+v", status.LastCycle)
+	}
+	if status.NextRun == nil || !status.NextRun.Equal(next) {
+		t.Errorf("NextRun = %v, want %v", status.NextRun, next)
+	}
+}
+
+func TestTracker_LogsOfCurrentCycle(t *testing.T) {
+	tr := newTracker()
+	log.Info("before the cycle")
+	tr.begin()
+	log.Info("during the cycle")
+
+	lines := tr.Logs(0)
+	if len(lines) != 1 || !strings.Contains(lines[0].Text, "during the cycle") {
+		t.Errorf("Logs(0) = %+v, want only the cycle's line", lines)
+	}
+	if more := tr.Logs(lines[0].Seq); len(more) != 0 {
+		t.Errorf("Logs(after the last) = %+v, want none", more)
+	}
+}
+
+func TestTracker_Trigger(t *testing.T) {
+	tr := newTracker()
+
+	if !tr.Trigger() {
+		t.Fatal("first Trigger() should be accepted")
+	}
+	if tr.Trigger() {
+		t.Error("second Trigger() should be rejected while one is queued")
+	}
+
+	<-tr.trigger
+	tr.begin()
+	if tr.Trigger() {
+		t.Error("Trigger() should be rejected while a cycle is running")
+	}
+}
+
+func TestRunIntervalMode_Trigger(t *testing.T) {
+	original := cycles
+	cycles = newTracker()
+	defer func() { cycles = original }()
+
+	cfg := config.Config{
+		Updates: config.UpdatesConfig{
+			Enabled:       true,
+			CheckInterval: time.Hour,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- runIntervalMode(ctx, cfg, docker.NewMockDockerClient())
+	}()
+
+	// Wait for the initial cycle, then request another
+	waitForCycle := func(previous string) string {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if last := cycles.Status().LastCycle; last != nil && last.ID != previous {
+				return last.ID
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatal("cycle did not complete")
+		return ""
+	}
+
+	first := waitForCycle("")
+
+	// The trigger may race with the loop starting; retry until it is queued
+	deadline := time.Now().Add(2 * time.Second)
+	for !cycles.Trigger() {
+		if time.Now().After(deadline) {
+			t.Fatal("Trigger() was rejected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	waitForCycle(first)
+
+	if cycles.Status().NextRun == nil {
+		t.Error("NextRun should be set in interval mode")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("runIntervalMode returned error: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+// targetQueue coalesces requests to check named containers, from the API, MQTT and the
+// image-pushed webhook, into targeted cycles that run one at a time. Names asked for while
+// a targeted cycle runs wait, and run together in the next one, so a burst of pushes starts
+// one cycle rather than a cycle each.
+type targetQueue struct {
+	mu       sync.Mutex
+	pending  map[string][]string // Names waiting to be checked, by audit initiator
+	order    []string            // Initiators with names pending, in the order they first asked
+	draining bool
+}
+
+// add queues names to be checked for initiator, and starts running the queue unless it
+// already is
+func (q *targetQueue) add(ctx context.Context, cfg config.Config, dockerClient docker.Client, initiator string, names []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending == nil {
+		q.pending = make(map[string][]string)
+	}
+	if _, ok := q.pending[initiator]; !ok {
+		q.order = append(q.order, initiator)
+	}
+	for _, name := range names {
+		if !slices.Contains(q.pending[initiator], name) {
+			q.pending[initiator] = append(q.pending[initiator], name)
+		}
+	}
+
+	if !q.draining {
+		q.draining = true
+		go q.drain(ctx, cfg, dockerClient)
+	}
+}
+
+// drain runs a targeted cycle for each initiator's pending names until none are left
+func (q *targetQueue) drain(ctx context.Context, cfg config.Config, dockerClient docker.Client) {
+	for {
+		q.mu.Lock()
+		if len(q.order) == 0 || ctx.Err() != nil {
+			q.pending, q.order = nil, nil
+			q.draining = false
+			q.mu.Unlock()
+			return
+		}
+		initiator := q.order[0]
+		names := q.pending[initiator]
+		q.order = q.order[1:]
+		delete(q.pending, initiator)
+		q.mu.Unlock()
+
+		runTargetedCycle(audit.WithInitiator(ctx, initiator), cfg, dockerClient, names)
+	}
+}
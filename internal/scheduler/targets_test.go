@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestTargetQueue_Coalesces(t *testing.T) {
+	original := cycles
+	cycles = newTracker()
+	defer func() { cycles = original }()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "app-id", Name: "app", Image: "ghcr.io/acme/app:v1", ImageID: "sha256:old-app", Config: &container.Config{Image: "ghcr.io/acme/app:v1"}},
+		{ID: "web-id", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	ctx := context.Background()
+	q := &cycles.targeted
+
+	// Requests arriving while a targeted cycle runs wait for the next one
+	q.draining = true
+	q.add(ctx, config.Default(), mockClient, audit.InitiatorAPI, []string{"app"})
+	q.add(ctx, config.Default(), mockClient, audit.InitiatorAPI, []string{"app", "web"})
+	q.add(ctx, config.Default(), mockClient, audit.InitiatorMQTT, []string{"web"})
+	if !slices.Equal(q.order, []string{audit.InitiatorAPI, audit.InitiatorMQTT}) || !slices.Equal(q.pending[audit.InitiatorAPI], []string{"app", "web"}) {
+		t.Fatalf("queue = %v %v, want the API's app and web merged ahead of MQTT's web", q.order, q.pending)
+	}
+
+	q.drain(ctx, config.Default(), mockClient)
+	if q.draining || len(q.order) != 0 {
+		t.Errorf("queue still draining %v with %v pending", q.draining, q.order)
+	}
+	pulled := slices.Clone(mockClient.PulledImages)
+	if len(pulled) == 3 {
+		slices.Sort(pulled[:2])
+	}
+	if !slices.Equal(pulled, []string{"ghcr.io/acme/app:v1", "nginx:latest", "nginx:latest"}) {
+		t.Errorf("pulled %v, want one cycle for app and web, then one for web", mockClient.PulledImages)
+	}
+
+	// Once idle, a request starts a cycle of its own
+	q.add(ctx, config.Default(), mockClient, audit.InitiatorAPI, []string{"app"})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		q.mu.Lock()
+		draining := q.draining
+		q.mu.Unlock()
+		if !draining {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the queued request never ran")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(mockClient.PulledImages) != 4 {
+		t.Errorf("pulled %v, want app checked again", mockClient.PulledImages)
+	}
+}
@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/watchdog"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// startWatchdog bounds a cycle by updates.cycle_timeout and tracks the steps it is on. The
+// returned function ends the watch: if the deadline passed, it logs the steps that were
+// stuck, sends a failure notification and returns the timeout as the cycle's error.
+func startWatchdog(ctx context.Context, cfg config.Config, logger *zerolog.Logger) (context.Context, func(err error) error) {
+	timeout := cfg.Updates.CycleTimeout
+	if timeout <= 0 {
+		return ctx, func(err error) error { return err }
+	}
+
+	notifier := notify.FromContext(ctx)
+	cycleCtx, cancel := context.WithTimeout(ctx, timeout)
+	cycleCtx, tracker := watchdog.With(cycleCtx)
+
+	return cycleCtx, func(err error) error {
+		defer cancel()
+		if !errors.Is(cycleCtx.Err(), context.DeadlineExceeded) {
+			return err
+		}
+
+		deadline, _ := cycleCtx.Deadline()
+		steps := tracker.RunningAt(deadline)
+		names := make([]string, 0, len(steps))
+		for _, step := range steps {
+			names = append(names, step.Name)
+			logger.Error().
+				Str("step", step.Name).
+				Str("stuck_for", util.HumanizeDuration(deadline.Sub(step.Started))).
+				Msg("⏱️ Cycle step did not finish before the cycle timeout")
+		}
+
+		reason := fmt.Sprintf("cycle timed out after %s", util.HumanizeDuration(timeout))
+		if len(names) > 0 {
+			reason += " while " + strings.Join(names, ", ")
+		}
+		logger.Error().Str("hint", "Check that the Docker daemon and registries respond, or raise updates.cycle_timeout").Msg("⏱️ " + reason)
+
+		if notifier != nil {
+			// The cycle's context is done; the notification must still go out
+			notify.Send(context.WithoutCancel(ctx), notifier, notify.Event{
+				Type:    notify.EventFailure,
+				Outcome: notify.OutcomeTimedOut,
+				Error:   reason,
+			}, logger)
+		}
+		return fmt.Errorf("%s: %w", reason, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/api"
+	"github.com/MikeO7/HarborBuddy/internal/audit"
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+)
+
+// checkPushedImage queues a targeted cycle for the containers running the repository of an image a
+// CI pipeline or registry reported pushed, and returns their names. Any tag counts: their
+// tag policy decides whether the push moves them.
+func checkPushedImage(ctx context.Context, cfg config.Config, dockerClient docker.Client, image string) ([]string, error) {
+	containers, err := dockerClient.ListContainers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	repo := repository(image)
+	var targets []string
+	for _, c := range containers {
+		if repository(c.Image) == repo {
+			targets = append(targets, c.Name)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w: no running container uses %s", api.ErrNotFound, image)
+	}
+
+	log.Infof("📬 %s was pushed, checking %s", image, strings.Join(targets, ", "))
+	cycles.targeted.add(ctx, cfg, dockerClient, audit.InitiatorAPI, targets)
+	return targets, nil
+}
+
+// repository returns an image reference's repository the way "docker ps" shows it, without
+// the tag or digest, e.g. "ghcr.io/acme/app" or "nginx"
+func repository(ref string) string {
+	ref, _, _ = strings.Cut(familiarImage(ref), "@")
+	if i := strings.LastIndex(ref, ":"); i >= 0 && !strings.Contains(ref[i:], "/") {
+		return ref[:i]
+	}
+	return ref
+}
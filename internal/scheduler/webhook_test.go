@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/api"
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestRepository(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                           "nginx",
+		"docker.io/library/nginx:1.25":    "nginx",
+		"ghcr.io/acme/app:v2":             "ghcr.io/acme/app",
+		"localhost:5000/app":              "localhost:5000/app",
+		"ghcr.io/acme/app:v2@sha256:abcd": "ghcr.io/acme/app",
+	}
+	for ref, want := range tests {
+		if got := repository(ref); got != want {
+			t.Errorf("repository(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+func TestCheckPushedImage(t *testing.T) {
+	original := cycles
+	cycles = newTracker()
+	defer func() { cycles = original }()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "app-id", Name: "app", Image: "ghcr.io/acme/app:v1", ImageID: "sha256:old-app", Config: &container.Config{Image: "ghcr.io/acme/app:v1"}},
+		{ID: "worker-id", Name: "worker", Image: "ghcr.io/acme/app:v1", ImageID: "sha256:old-app", Config: &container.Config{Image: "ghcr.io/acme/app:v1"}},
+		{ID: "web-id", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+
+	if _, err := checkPushedImage(context.Background(), config.Default(), mockClient, "ghcr.io/acme/other:v1"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("checkPushedImage() of an image nothing runs error = %v, want ErrNotFound", err)
+	}
+
+	targets, err := checkPushedImage(context.Background(), config.Default(), mockClient, "ghcr.io/acme/app:v2")
+	if err != nil || !slices.Equal(targets, []string{"app", "worker"}) {
+		t.Fatalf("checkPushedImage() = %v, %v, want app and worker", targets, err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for cycles.Status().LastCycle == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("no cycle ran after the push")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pulled := mockClient.PulledImages; len(pulled) != 1 || pulled[0] != "ghcr.io/acme/app:v1" {
+		t.Errorf("pulled %v, want only the pushed repository's image checked", pulled)
+	}
+}
@@ -0,0 +1,36 @@
+package selfupdate
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+// Platform returns the platform the running binary is built for. HarborBuddy pulls its own
+// new image for it, so a multi-arch tag can't hand it a build that doesn't run here.
+func Platform() docker.Platform {
+	var settings []debug.BuildSetting
+	if info, ok := debug.ReadBuildInfo(); ok {
+		settings = info.Settings
+	}
+	return platformOf(runtime.GOOS, runtime.GOARCH, settings)
+}
+
+// platformOf names the platform of a binary built for goos/goarch with the given build
+// settings. On 32-bit ARM the variant is the ARM version the binary was built for (GOARM=7
+// is "v7"); left out if the build didn't record it, so the daemon picks its own.
+func platformOf(goos, goarch string, settings []debug.BuildSetting) docker.Platform {
+	p := docker.Platform{OS: goos, Architecture: goarch}
+	if goarch != "arm" {
+		return p
+	}
+	for _, s := range settings {
+		if s.Key == "GOARM" && s.Value != "" {
+			version, _, _ := strings.Cut(s.Value, ",") // e.g. "7,softfloat"
+			p.Variant = "v" + version
+		}
+	}
+	return p
+}
@@ -0,0 +1,38 @@
+package selfupdate
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+func TestPlatformOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		goarch   string
+		settings []debug.BuildSetting
+		want     string
+	}{
+		{"amd64", "amd64", []debug.BuildSetting{{Key: "GOAMD64", Value: "v1"}}, "linux/amd64"},
+		{"arm64", "arm64", nil, "linux/arm64"},
+		{"armv7", "arm", []debug.BuildSetting{{Key: "GOARM", Value: "7"}}, "linux/arm/v7"},
+		{"armv6 softfloat", "arm", []debug.BuildSetting{{Key: "GOARM", Value: "6,softfloat"}}, "linux/arm/v6"},
+		{"arm without GOARM", "arm", nil, "linux/arm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := platformOf("linux", tt.goarch, tt.settings).String(); got != tt.want {
+				t.Errorf("platformOf() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	got := Platform()
+	if want := (docker.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}); got.OS != want.OS || got.Architecture != want.Architecture {
+		t.Errorf("Platform() = %s, want the running binary's %s", got, want)
+	}
+}
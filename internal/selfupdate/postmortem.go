@@ -0,0 +1,175 @@
+package selfupdate
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+)
+
+// LogFile is where a self-update is logged, by the instance starting it and by the helper
+// container, so the log outlives the helper. Set by main; empty disables it.
+var LogFile string
+
+// Outcomes of a self-update, as recorded in LogFile
+const (
+	OutcomeSucceeded   = "succeeded"
+	OutcomeFailed      = "failed"
+	OutcomeRolledBack  = "rolled_back" // The new version failed and the previous one was restored
+	OutcomeInterrupted = "interrupted" // The helper stopped, or never started, before finishing
+)
+
+// entry is a line of LogFile: a JSON log entry, a few of them marked with selfupdate_* fields
+type entry struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+	Error     string    `json:"error,omitempty"`
+	Container string    `json:"container_name,omitempty"`
+	Image     string    `json:"image,omitempty"`
+	Started   bool      `json:"selfupdate_started,omitempty"`
+	Outcome   string    `json:"selfupdate_outcome,omitempty"`
+	Reported  bool      `json:"selfupdate_reported,omitempty"`
+}
+
+// LastUpdate is what LogFile says about the most recent self-update
+type LastUpdate struct {
+	Container string
+	Image     string
+	Outcome   string
+	Error     string
+	Started   time.Time
+	Finished  time.Time // Zero when interrupted
+}
+
+// startLog begins LogFile afresh for a self-update of container to image
+func startLog(container, image string) error {
+	if LogFile == "" {
+		return nil
+	}
+	return writeEntry(os.O_CREATE|os.O_TRUNC|os.O_WRONLY, entry{
+		Level:     "info",
+		Message:   fmt.Sprintf("Self-update of %s to %s started", container, image),
+		Container: container,
+		Image:     image,
+		Started:   true,
+	})
+}
+
+// abortLog records in LogFile that the helper never started. The instance that tried already
+// reported it, so it is marked as reported.
+func abortLog(err error) {
+	if LogFile == "" {
+		return
+	}
+	_ = writeEntry(os.O_APPEND|os.O_WRONLY, entry{
+		Level:    "error",
+		Message:  "Self-update helper failed to start",
+		Error:    err.Error(),
+		Outcome:  OutcomeFailed,
+		Reported: true,
+	})
+}
+
+// writeEntry writes one line to LogFile, opened with flag
+func writeEntry(flag int, e entry) error {
+	f, err := os.OpenFile(LogFile, flag, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	e.Time = time.Now()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ReadLast reads the self-update recorded in path. ok is false if there is none, or it was
+// already reported.
+func ReadLast(path string) (last LastUpdate, ok bool, err error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return LastUpdate{}, false, nil
+	}
+	if err != nil {
+		return LastUpdate{}, false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		if e.Started {
+			last = LastUpdate{Container: e.Container, Image: e.Image, Outcome: OutcomeInterrupted, Started: e.Time}
+			ok = true
+		}
+		if e.Outcome != "" {
+			last.Outcome, last.Error, last.Finished = e.Outcome, e.Error, e.Time
+		}
+		if e.Reported {
+			ok = false
+		}
+	}
+	return last, ok, scanner.Err()
+}
+
+// ReportLast says how the last self-update went, now that its helper container is gone, in
+// the log and through notifier. Each self-update is reported once; the report is appended
+// to LogFile.
+func ReportLast(ctx context.Context, notifier notify.Notifier) {
+	if LogFile == "" {
+		return
+	}
+	logger := log.WithFields(map[string]interface{}{"phase": "selfupdate"})
+
+	last, ok, err := ReadLast(LogFile)
+	if err != nil {
+		logger.Warn().Err(err).Str("file", LogFile).Msg("Failed to read the self-update log")
+		return
+	}
+	if !ok {
+		return
+	}
+
+	event := notify.Event{Type: notify.EventFailure, Container: last.Container, Image: last.Image, Error: last.Error}
+	switch last.Outcome {
+	case OutcomeSucceeded:
+		logger.Info().Str("image", last.Image).Msgf("✅ Self-update to %s succeeded", last.Image)
+		event.Type, event.Outcome = notify.EventUpdate, notify.OutcomeSuccess
+	case OutcomeRolledBack:
+		logger.Error().Str("image", last.Image).Str("error", last.Error).Str("log", LogFile).Msgf("↩️ Self-update to %s failed, the previous version was restored", last.Image)
+		event.Outcome = notify.OutcomeFailure
+	case OutcomeFailed:
+		logger.Error().Str("image", last.Image).Str("error", last.Error).Str("log", LogFile).Msgf("❌ Self-update to %s failed", last.Image)
+		event.Outcome = notify.OutcomeFailure
+	default:
+		logger.Warn().Str("image", last.Image).Str("log", LogFile).Msgf("⚠️ Self-update to %s was interrupted before it finished", last.Image)
+		event.Outcome, event.Error = notify.OutcomeInterrupted, "self-update helper stopped before it finished"
+	}
+
+	if notifier != nil {
+		notify.Send(ctx, notifier, event, logger)
+		notify.Flush(ctx, notifier, logger)
+	}
+
+	if err := writeEntry(os.O_APPEND|os.O_WRONLY, entry{
+		Level:    "info",
+		Message:  fmt.Sprintf("Self-update outcome (%s) reported by HarborBuddy %s", last.Outcome, CurrentVersion),
+		Reported: true,
+	}); err != nil {
+		logger.Warn().Err(err).Str("file", LogFile).Msg("Failed to mark the self-update as reported")
+	}
+}
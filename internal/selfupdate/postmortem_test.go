@@ -0,0 +1,123 @@
+package selfupdate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+type eventRecorder struct {
+	events []notify.Event
+}
+
+func (r *eventRecorder) Notify(ctx context.Context, event notify.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+// useLogFile points LogFile at a file in a temporary directory for the test
+func useLogFile(t *testing.T) string {
+	original := LogFile
+	LogFile = filepath.Join(t.TempDir(), "selfupdate.log")
+	t.Cleanup(func() { LogFile = original })
+	return LogFile
+}
+
+func TestReadLast(t *testing.T) {
+	path := useLogFile(t)
+
+	if _, ok, err := ReadLast(path); ok || err != nil {
+		t.Fatalf("ReadLast() without a log = %v, %v, want nothing", ok, err)
+	}
+
+	if err := startLog("harborbuddy", "harborbuddy:latest"); err != nil {
+		t.Fatal(err)
+	}
+	last, ok, err := ReadLast(path)
+	if !ok || err != nil || last.Outcome != OutcomeInterrupted || last.Container != "harborbuddy" {
+		t.Errorf("ReadLast() after the start = %+v, %v, %v, want an interrupted update", last, ok, err)
+	}
+
+	// The helper's own log lines, then its outcome
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	_, _ = f.WriteString(`{"level":"info","time":"2026-10-16T03:00:05Z","message":"Updater: Removing old container..."}` + "\n")
+	_, _ = f.WriteString(`{"level":"error","selfupdate_outcome":"failed","image":"harborbuddy:latest","error":"failed to create new container: no space left on device","time":"2026-10-16T03:00:06Z","message":"Updater: ❌ Self-update failed"}` + "\n")
+	f.Close()
+
+	last, ok, err = ReadLast(path)
+	if !ok || err != nil || last.Outcome != OutcomeFailed || last.Error != "failed to create new container: no space left on device" {
+		t.Errorf("ReadLast() after the helper failed = %+v, %v, %v, want the failure", last, ok, err)
+	}
+
+	abortLog(errors.New("helper did not start"))
+	if _, ok, _ := ReadLast(path); ok {
+		t.Error("ReadLast() found an update the instance that tried it already reported")
+	}
+}
+
+func TestReportLast(t *testing.T) {
+	path := useLogFile(t)
+	log.Initialize(log.Config{Level: "info", Output: io.Discard})
+
+	// A helper logging to the file, as it does in updater mode
+	if err := startLog("harborbuddy", "harborbuddy:latest"); err != nil {
+		t.Fatal(err)
+	}
+	log.Initialize(log.Config{Level: "info", Output: io.Discard, File: path})
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "target-123", Name: "harborbuddy", State: &types.ContainerState{}, Config: &container.Config{Image: "harborbuddy:old"}},
+		{ID: "new-container-id-harborbuddy", State: &types.ContainerState{Running: true}},
+	}
+	originalGrace := GracePeriod
+	GracePeriod = 0
+	defer func() { GracePeriod = originalGrace }()
+	if err := RunUpdater(context.Background(), mockClient, "target-123", "harborbuddy:latest"); err != nil {
+		t.Fatalf("RunUpdater() error = %v", err)
+	}
+	log.Initialize(log.Config{Level: "info", Output: io.Discard})
+
+	recorder := &eventRecorder{}
+	ReportLast(context.Background(), recorder)
+	if len(recorder.events) != 1 || recorder.events[0].Type != notify.EventUpdate || recorder.events[0].Container != "harborbuddy" {
+		t.Fatalf("events = %+v, want one update of harborbuddy", recorder.events)
+	}
+
+	// Reported once only
+	ReportLast(context.Background(), recorder)
+	if len(recorder.events) != 1 {
+		t.Errorf("events = %+v, want the self-update reported once", recorder.events)
+	}
+}
+
+func TestTrigger_PassesLogFile(t *testing.T) {
+	path := useLogFile(t)
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.RunResults = map[string]docker.ExecResult{"harborbuddy:latest": {Output: "HarborBuddy version 0.2.0"}}
+	originalExitFunc := ExitFunc
+	ExitFunc = func(code int) {}
+	defer func() { ExitFunc = originalExitFunc }()
+
+	myContainer := docker.ContainerInfo{ID: "my-container-123", Name: "harborbuddy", Config: &container.Config{}, HostConfig: &container.HostConfig{}}
+	if err := Trigger(context.Background(), mockClient, myContainer, "harborbuddy:latest"); err != nil {
+		t.Fatalf("Trigger() error = %v", err)
+	}
+
+	cmd := mockClient.CreatedHelpers[0].Cmd
+	if cmd[0] != "--updater-mode" || cmd[len(cmd)-2] != "--updater-log" || cmd[len(cmd)-1] != path {
+		t.Errorf("helper cmd = %v, want the updater flags with --updater-log %s", cmd, path)
+	}
+	if last, ok, _ := ReadLast(path); !ok || last.Image != "harborbuddy:latest" {
+		t.Errorf("ReadLast() = %+v, %v, want the started self-update", last, ok)
+	}
+}
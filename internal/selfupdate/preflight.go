@@ -0,0 +1,93 @@
+package selfupdate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+// CurrentVersion is the version of the running binary, set by main
+var CurrentVersion string
+
+// PreflightTimeout bounds the pre-flight run of a new image. It can be overridden in tests.
+var PreflightTimeout = 30 * time.Second
+
+// versionPattern matches the output of --version, e.g. "HarborBuddy version 0.2.0 (commit: ...)"
+var versionPattern = regexp.MustCompile(`HarborBuddy version (\S+)`)
+
+// Preflight runs the new image with --version in a throwaway container before HarborBuddy
+// hands itself over to it, so a broken build can't leave it stopped with nothing to replace
+// it. The binary has to start, exit cleanly and report a version no older than the running
+// one; the same version is fine, as rebuilds and beta builds keep it. It returns the version
+// the new image reports.
+func Preflight(ctx context.Context, client docker.Client, newImage string) (string, error) {
+	runCtx, cancel := context.WithTimeout(ctx, PreflightTimeout)
+	defer cancel()
+
+	result, err := client.RunContainer(runCtx, newImage, []string{"--version"})
+	if err != nil {
+		return "", fmt.Errorf("new image did not run: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("new image exited with code %d: %s", result.ExitCode, result.Output)
+	}
+
+	match := versionPattern.FindStringSubmatch(result.Output)
+	if match == nil {
+		return "", fmt.Errorf("new image did not report a HarborBuddy version: %q", result.Output)
+	}
+	version := match[1]
+	if olderVersion(version, CurrentVersion) {
+		return version, fmt.Errorf("new image reports version %s, older than the running %s", version, CurrentVersion)
+	}
+	return version, nil
+}
+
+// olderVersion reports whether dotted version a is older than b. Versions that aren't
+// numeric (e.g. "dev") can't be ordered and never count as older.
+func olderVersion(a, b string) bool {
+	pa, okA := versionParts(a)
+	pb, okB := versionParts(b)
+	if !okA || !okB {
+		return false
+	}
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
+
+// versionParts splits "v1.2.3" into its numbers, ignoring a pre-release or build suffix
+func versionParts(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+
+	var parts []int
+	for _, field := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
@@ -0,0 +1,96 @@
+package selfupdate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+func TestPreflight(t *testing.T) {
+	original := CurrentVersion
+	defer func() { CurrentVersion = original }()
+	CurrentVersion = "0.2.0"
+
+	tests := []struct {
+		name        string
+		result      docker.ExecResult
+		runErr      error
+		wantVersion string
+		wantErr     string
+	}{
+		{"newer", docker.ExecResult{Output: "HarborBuddy version 0.3.0 (commit: abc1234, linux/amd64)"}, nil, "0.3.0", ""},
+		{"same version rebuilt", docker.ExecResult{Output: "HarborBuddy version 0.2.0 (commit: def5678, linux/amd64)"}, nil, "0.2.0", ""},
+		{"older", docker.ExecResult{Output: "HarborBuddy version 0.1.9 (commit: abc1234, linux/amd64)"}, nil, "0.1.9", "older than the running 0.2.0"},
+		{"crashes", docker.ExecResult{ExitCode: 139, Output: "segmentation fault"}, nil, "", "exited with code 139"},
+		{"not HarborBuddy", docker.ExecResult{Output: "nginx version: nginx/1.27.0"}, nil, "", "did not report a HarborBuddy version"},
+		{"won't start", docker.ExecResult{}, errors.New("exec format error"), "", "new image did not run"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := docker.NewMockDockerClient()
+			mockClient.RunResults = map[string]docker.ExecResult{"harborbuddy:latest": tt.result}
+			mockClient.RunContainerError = tt.runErr
+
+			version, err := Preflight(context.Background(), mockClient, "harborbuddy:latest")
+			if version != tt.wantVersion {
+				t.Errorf("Preflight() version = %q, want %q", version, tt.wantVersion)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Preflight() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Preflight() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if len(mockClient.RanContainers) != 1 || strings.Join(mockClient.RanContainers[0].Cmd, " ") != "--version" {
+				t.Errorf("ran = %+v, want the new image with --version", mockClient.RanContainers)
+			}
+		})
+	}
+}
+
+func TestTrigger_PreflightFails(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.RunResults = map[string]docker.ExecResult{"harborbuddy:latest": {ExitCode: 1, Output: "exec /harborbuddy: no such file or directory"}}
+
+	exitCalled := false
+	originalExitFunc := ExitFunc
+	ExitFunc = func(code int) { exitCalled = true }
+	defer func() { ExitFunc = originalExitFunc }()
+
+	myContainer := docker.ContainerInfo{ID: "my-container-123", Name: "harborbuddy"}
+	err := Trigger(context.Background(), mockClient, myContainer, "harborbuddy:latest")
+	if err == nil || !strings.Contains(err.Error(), "pre-flight check") {
+		t.Errorf("Trigger() error = %v, want a pre-flight failure", err)
+	}
+	if exitCalled {
+		t.Error("HarborBuddy exited although the new image is broken")
+	}
+	if len(mockClient.CreatedHelpers) != 0 {
+		t.Errorf("helpers = %+v, want none", mockClient.CreatedHelpers)
+	}
+}
+
+func TestOlderVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"0.1.9", "0.2.0", true},
+		{"0.2.0", "0.2.0", false},
+		{"0.10.0", "0.9.1", false},
+		{"v1.2", "1.2.1", true},
+		{"1.3.0-beta.1", "1.2.9", false},
+		{"dev", "0.2.0", false},
+		{"0.1.0", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := olderVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("olderVersion(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package selfupdate
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -13,8 +14,36 @@ import (
 // ExitFunc is the function called to exit the process. It can be overridden in tests.
 var ExitFunc = os.Exit
 
-// RunUpdater is the entrypoint for the temporary helper container
+// How long the new container has to prove itself before the helper restores the previous
+// version: it must pass its healthcheck within HealthTimeout or, without one, still be
+// running after GracePeriod. They can be overridden in tests.
+var (
+	HealthTimeout = 2 * time.Minute
+	GracePeriod   = 15 * time.Second
+)
+
+// ErrRolledBack means the new version failed and the helper brought the previous one back
+var ErrRolledBack = errors.New("previous version restored")
+
+// RunUpdater is the entrypoint for the temporary helper container. Its outcome is logged
+// with a selfupdate_outcome field, for the replaced instance to report from LogFile.
 func RunUpdater(ctx context.Context, client docker.Client, targetID string, newImage string) error {
+	if err := replaceTarget(ctx, client, targetID, newImage); err != nil {
+		outcome := OutcomeFailed
+		if errors.Is(err, ErrRolledBack) {
+			outcome = OutcomeRolledBack
+		}
+		log.WithFields(map[string]interface{}{"selfupdate_outcome": outcome, "image": newImage}).
+			Error().Err(err).Msg("Updater: ❌ Self-update failed")
+		return err
+	}
+	log.WithFields(map[string]interface{}{"selfupdate_outcome": OutcomeSucceeded, "image": newImage}).
+		Info().Msg("Updater: ✅ Update complete. Exiting.")
+	return nil
+}
+
+// replaceTarget waits for the target container to stop and recreates it from newImage
+func replaceTarget(ctx context.Context, client docker.Client, targetID string, newImage string) error {
 	log.Info("Updater: 🔄 Started. Waiting for target to stop...")
 
 	// 1. Wait for the target container to stop
@@ -100,20 +129,64 @@ func RunUpdater(ctx context.Context, client docker.Client, targetID string, newI
 
 	// 5. Start the new container
 	log.Info("Updater: 🚀 Starting new container...")
-	if err := client.StartContainer(ctx, tempID); err != nil {
-		return fmt.Errorf("failed to start new container: %w", err)
+	startErr := client.StartContainer(ctx, tempID)
+	if startErr != nil {
+		startErr = fmt.Errorf("failed to start new container: %w", startErr)
+	}
+
+	// 6. Make sure it stays up; a broken release mustn't leave the host without HarborBuddy
+	if startErr == nil {
+		log.Info("Updater: Waiting for the new container to prove healthy...")
+		startErr = docker.WaitHealthy(ctx, client, tempID, HealthTimeout, GracePeriod)
+	}
+	if startErr != nil {
+		log.ErrorErr("Updater: New version failed, restoring the previous one", startErr)
+		if err := restorePrevious(ctx, client, tempID, oldContainer); err != nil {
+			return fmt.Errorf("new version failed (%v) and the previous one could not be restored: %w", startErr, err)
+		}
+		return fmt.Errorf("%w after the new version failed: %v", ErrRolledBack, startErr)
 	}
 
-	log.Info("Updater: ✅ Update complete. Exiting.")
+	return nil
+}
+
+// restorePrevious replaces the failed new container with one created like old from the image
+// ID old ran. Like --rollback, that pins it to the image ID, so later cycles leave it alone
+// until it is recreated from a tag again.
+func restorePrevious(ctx context.Context, client docker.Client, failedID string, old docker.ContainerInfo) error {
+	if err := client.RemoveContainer(ctx, failedID); err != nil {
+		return fmt.Errorf("failed to remove new container: %w", err)
+	}
+
+	id, err := client.CreateContainerLike(ctx, old, old.ImageID)
+	if err != nil {
+		return fmt.Errorf("failed to recreate previous container: %w", err)
+	}
+	if err := client.RenameContainer(ctx, id, old.Name); err != nil {
+		_ = client.RemoveContainer(ctx, id)
+		return fmt.Errorf("failed to rename previous container: %w", err)
+	}
+	if err := client.StartContainer(ctx, id); err != nil {
+		return fmt.Errorf("failed to start previous container: %w", err)
+	}
+
+	log.Infof("Updater: ↩️ Previous version restored from image %s", old.ImageID)
 	return nil
 }
 
 // Trigger starts the update process
 func Trigger(ctx context.Context, client docker.Client, myContainer docker.ContainerInfo, newImage string) error {
+	// Once the helper starts this instance exits, so make sure the new image works first
+	version, err := Preflight(ctx, client, newImage)
+	if err != nil {
+		return fmt.Errorf("self-update aborted, the new image failed its pre-flight check: %w", err)
+	}
+	log.Infof("Self-Update: ✅ New image runs and reports version %s", version)
+
 	log.Info("Self-Update: Triggering helper process...")
 
 	// We need to spawn a container that runs:
-	// /app/harborbuddy --updater-mode --target-container-id <myID> --new-image-id <newImage>
+	// harborbuddy --updater-mode --target-container-id <myID> --new-image-id <newImage>
 
 	// We reuse the current configuration for the helper, but we need to ensure it has:
 	// 1. Docker socket mounted
@@ -121,13 +194,16 @@ func Trigger(ctx context.Context, client docker.Client, myContainer docker.Conta
 
 	// Ideally, the helper uses the NEW image. We already pulled it.
 
-	// Override entrypoint/cmd
+	// Override cmd; the image's entrypoint is the binary, so these are its arguments
 	cmd := []string{
-		"/app/harborbuddy", // Assuming binary path, need to verify
 		"--updater-mode",
 		"--target-container-id", myContainer.ID,
 		"--new-image-id", newImage,
 	}
+	// The helper mounts what this container mounts, so it can log next to our state
+	if LogFile != "" {
+		cmd = append(cmd, "--updater-log", LogFile)
+	}
 
 	// Create the helper container
 	// We need a specialized create function or use the raw client, but we are in `internal`.
@@ -149,7 +225,11 @@ func Trigger(ctx context.Context, client docker.Client, myContainer docker.Conta
 
 	log.Infof("Self-Update: 🚀 Helper %s created. Starting...", helperID)
 
+	if err := startLog(myContainer.Name, newImage); err != nil {
+		log.Warnf("Self-Update: Failed to start the self-update log %s: %v", LogFile, err)
+	}
 	if err := client.StartContainer(ctx, helperID); err != nil {
+		abortLog(err)
 		return fmt.Errorf("failed to start helper: %w", err)
 	}
 
@@ -3,7 +3,9 @@ package selfupdate
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 	"time"
@@ -39,7 +41,12 @@ func TestRunUpdater(t *testing.T) {
 				Image: "harborbuddy:old",
 			},
 		},
+		// The new container, once started
+		{ID: "new-container-id-" + targetName, State: &types.ContainerState{Running: true}},
 	}
+	originalGrace := GracePeriod
+	GracePeriod = 0
+	defer func() { GracePeriod = originalGrace }()
 
 	// We need to simulate the target stopping asynchronously
 	go func() {
@@ -318,6 +325,7 @@ func TestTrigger_Success(t *testing.T) {
 	})
 
 	mockClient := docker.NewMockDockerClient()
+	mockClient.RunResults = map[string]docker.ExecResult{"harborbuddy:latest": {Output: "HarborBuddy version 0.2.0 (commit: abc1234, linux/amd64)"}}
 	ctx := context.Background()
 
 	myContainer := docker.ContainerInfo{
@@ -400,6 +408,7 @@ func TestTrigger_CreateHelperFails(t *testing.T) {
 	})
 
 	mockClient := docker.NewMockDockerClient()
+	mockClient.RunResults = map[string]docker.ExecResult{"harborbuddy:latest": {Output: "HarborBuddy version 0.2.0 (commit: abc1234, linux/amd64)"}}
 	mockClient.CreateHelperContainerError = fmt.Errorf("failed to create helper")
 
 	ctx := context.Background()
@@ -436,6 +445,7 @@ func TestTrigger_StartHelperFails(t *testing.T) {
 	})
 
 	mockClient := docker.NewMockDockerClient()
+	mockClient.RunResults = map[string]docker.ExecResult{"harborbuddy:latest": {Output: "HarborBuddy version 0.2.0 (commit: abc1234, linux/amd64)"}}
 	mockClient.StartContainerError = fmt.Errorf("failed to start helper")
 
 	ctx := context.Background()
@@ -463,3 +473,35 @@ func TestTrigger_StartHelperFails(t *testing.T) {
 		t.Error("Exit should not be called when helper start fails")
 	}
 }
+
+func TestRunUpdater_RollsBackCrashingVersion(t *testing.T) {
+	log.Initialize(log.Config{Level: "info", Output: io.Discard})
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{
+			ID:      "target-123",
+			Name:    "harborbuddy",
+			ImageID: "sha256:previous",
+			State:   &types.ContainerState{},
+			Config:  &container.Config{Image: "harborbuddy:latest"},
+		},
+		// The new version crash-loops under its restart policy
+		{ID: "new-container-id-harborbuddy", State: &types.ContainerState{Restarting: true, ExitCode: 1}},
+	}
+
+	err := RunUpdater(context.Background(), mockClient, "target-123", "harborbuddy:latest")
+	if !errors.Is(err, ErrRolledBack) {
+		t.Fatalf("RunUpdater() error = %v, want the previous version restored", err)
+	}
+
+	if len(mockClient.CreatedContainers) != 2 || mockClient.CreatedContainers[1].NewImage != "sha256:previous" {
+		t.Fatalf("created = %+v, want the new version, then the previous image", mockClient.CreatedContainers)
+	}
+	if len(mockClient.RemovedContainers) != 2 || mockClient.RemovedContainers[1] != "new-container-id-harborbuddy" {
+		t.Errorf("removed = %v, want the target, then the crashing container", mockClient.RemovedContainers)
+	}
+	if len(mockClient.StartedContainers) != 2 {
+		t.Errorf("started = %v, want the new version and the restored one", mockClient.StartedContainers)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package state
+
+// lockFile is a no-op off Linux, where HarborBuddy doesn't run
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,317 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record is what HarborBuddy remembers about a container's last update
+type Record struct {
+	Image           string    `json:"image"`             // Image reference the container ran before the update
+	PreviousImageID string    `json:"previous_image_id"` // Image ID to roll back to
+	ImageID         string    `json:"image_id"`          // Image ID installed by the update
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Inflight is a container replacement in progress. It is saved before each step and
+// cleared once the replacement finishes or rolls back, so one left behind means HarborBuddy
+// was stopped mid-replacement.
+type Inflight struct {
+	Name       string    `json:"name"`             // Original container name
+	OldID      string    `json:"old_id"`           // Container being replaced
+	NewID      string    `json:"new_id,omitempty"` // Replacement, once created
+	WasRunning bool      `json:"was_running"`      // Whether the old container was running
+	Step       string    `json:"step"`             // Last completed step
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// Freeze keeps a container from being updated until it expires or is lifted
+type Freeze struct {
+	Until    time.Time `json:"until,omitempty"` // Zero means until unfrozen
+	Reason   string    `json:"reason,omitempty"`
+	FrozenAt time.Time `json:"frozen_at"`
+}
+
+// Expired reports whether the freeze no longer applies at now
+func (f Freeze) Expired(now time.Time) bool {
+	return !f.Until.IsZero() && !now.Before(f.Until)
+}
+
+// Pending is an update found but left alone (monitor-only), remembered so its notification
+// isn't repeated every cycle and the daily digest can list it
+type Pending struct {
+	Image      string    `json:"image"`
+	OldImageID string    `json:"old_image_id"`
+	NewImageID string    `json:"new_image_id"`
+	OldVersion string    `json:"old_version,omitempty"`
+	NewVersion string    `json:"new_version,omitempty"`
+	ReleaseURL string    `json:"release_url,omitempty"`
+	FoundAt    time.Time `json:"found_at"`
+	NotifiedAt time.Time `json:"notified_at,omitempty"` // Zero until a notification was sent
+}
+
+// file is the on-disk layout, versioned so it can evolve
+type file struct {
+	Version    int                 `json:"version"`
+	Containers map[string]Record   `json:"containers"`         // keyed by container name
+	Inflight   map[string]Inflight `json:"inflight,omitempty"` // keyed by container name
+	Frozen     map[string]Freeze   `json:"frozen,omitempty"`   // keyed by container name
+	Pending    map[string]Pending  `json:"pending,omitempty"`  // keyed by container name
+}
+
+// Store persists update records as JSON. It is safe for concurrent use.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data file
+}
+
+// Open loads the store at path; a missing file is an empty store
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, data: file{Version: 1, Containers: map[string]Record{}}}
+
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if s.data.Containers == nil {
+		s.data.Containers = map[string]Record{}
+	}
+	return s, nil
+}
+
+// Get returns the record for a container name
+func (s *Store) Get(name string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.data.Containers[name]
+	return rec, ok
+}
+
+// Put records a container's update and saves the store
+func (s *Store) Put(name string, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Containers[name] = rec
+	return s.save()
+}
+
+// PreviousImageIDs returns the images kept for rollback, which cleanup must not remove
+func (s *Store) PreviousImageIDs() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make(map[string]bool, len(s.data.Containers))
+	for _, rec := range s.data.Containers {
+		ids[rec.PreviousImageID] = true
+	}
+	return ids
+}
+
+// SetInflight records the progress of a replacement and saves the store
+func (s *Store) SetInflight(rec Inflight) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Inflight == nil {
+		s.data.Inflight = map[string]Inflight{}
+	}
+	s.data.Inflight[rec.Name] = rec
+	return s.save()
+}
+
+// ClearInflight forgets a replacement that finished or was rolled back and saves the store
+func (s *Store) ClearInflight(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Inflight[name]; !ok {
+		return nil
+	}
+	delete(s.data.Inflight, name)
+	return s.save()
+}
+
+// Inflight returns the replacements left unfinished, ordered by container name
+func (s *Store) Inflight() []Inflight {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recs := make([]Inflight, 0, len(s.data.Inflight))
+	for _, rec := range s.data.Inflight {
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Name < recs[j].Name })
+	return recs
+}
+
+// Pending returns the pending update remembered for a container name
+func (s *Store) Pending(name string) (Pending, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.data.Pending[name]
+	return p, ok
+}
+
+// SetPending remembers a container's pending update and saves the store
+func (s *Store) SetPending(name string, p Pending) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Pending == nil {
+		s.data.Pending = map[string]Pending{}
+	}
+	s.data.Pending[name] = p
+	return s.save()
+}
+
+// ClearPending forgets a container's pending update, once it is up to date, and saves the store
+func (s *Store) ClearPending(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Pending[name]; !ok {
+		return nil
+	}
+	delete(s.data.Pending, name)
+	return s.save()
+}
+
+// PrunePending forgets the pending updates of containers not in exists, such as ones removed
+// since their update was found, and saves the store if any were dropped. It returns the
+// names it dropped.
+func (s *Store) PrunePending(exists map[string]bool) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pruned []string
+	for name := range s.data.Pending {
+		if !exists[name] {
+			delete(s.data.Pending, name)
+			pruned = append(pruned, name)
+		}
+	}
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+	sort.Strings(pruned)
+	return pruned, s.save()
+}
+
+// PendingUpdates returns the pending updates, keyed by container name
+func (s *Store) PendingUpdates() map[string]Pending {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := make(map[string]Pending, len(s.data.Pending))
+	for name, p := range s.data.Pending {
+		pending[name] = p
+	}
+	return pending
+}
+
+// Freeze freezes a container, replacing any freeze it had, and saves the store. Expired
+// freezes are dropped.
+func (s *Store) Freeze(name string, f Freeze) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	frozen := s.activeFreezes(f.FrozenAt)
+	frozen[name] = f
+	s.data.Frozen = frozen
+	return s.write()
+}
+
+// Unfreeze lifts a container's freeze and saves the store. It returns false if the
+// container wasn't frozen.
+func (s *Store) Unfreeze(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	frozen := s.activeFreezes(time.Now())
+	if _, ok := frozen[name]; !ok {
+		return false, nil
+	}
+	delete(frozen, name)
+	s.data.Frozen = frozen
+	return true, s.write()
+}
+
+// Frozen returns the freezes in force at now, keyed by container name. Freezes are read
+// from the file on every call, so ones made by another process (the CLI, or the API while a
+// cycle holds its own store) apply straight away.
+func (s *Store) Frozen(now time.Time) map[string]Freeze {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.activeFreezes(now)
+}
+
+// activeFreezes reads the freezes on disk, dropping the ones expired at now
+func (s *Store) activeFreezes(now time.Time) map[string]Freeze {
+	frozen := make(map[string]Freeze)
+	for name, f := range s.diskFreezes() {
+		if !f.Expired(now) {
+			frozen[name] = f
+		}
+	}
+	return frozen
+}
+
+// diskFreezes returns the freezes currently saved in the file
+func (s *Store) diskFreezes() map[string]Freeze {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return s.data.Frozen
+	}
+	var onDisk file
+	if json.Unmarshal(raw, &onDisk) != nil {
+		return s.data.Frozen
+	}
+	return onDisk.Frozen
+}
+
+// save writes the store, keeping the freezes saved since it was opened. The file is locked
+// from reading the freezes to writing them back, so a freeze made by another process in
+// between isn't lost.
+func (s *Store) save() error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	s.data.Frozen = s.diskFreezes()
+	return s.write()
+}
+
+// write writes the store atomically (temp file + rename) so a crash can't truncate it
+func (s *Store) write() error {
+	raw, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
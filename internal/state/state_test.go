@@ -0,0 +1,247 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore_PutAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() on missing file error = %v", err)
+	}
+	if _, ok := s.Get("web"); ok {
+		t.Error("empty store should have no records")
+	}
+
+	rec := Record{Image: "nginx:latest", PreviousImageID: "sha256:old", ImageID: "sha256:new", UpdatedAt: time.Now().UTC().Truncate(time.Second)}
+	if err := s.Put("web", rec); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	got, ok := reopened.Get("web")
+	if !ok || got != rec {
+		t.Errorf("Get() = %+v, %v; want %+v", got, ok, rec)
+	}
+	if !reopened.PreviousImageIDs()["sha256:old"] {
+		t.Error("PreviousImageIDs() should include the retained image")
+	}
+}
+
+func TestOpen_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("expected an error for a corrupt state file")
+	}
+}
+
+func TestStore_Inflight(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	web := Inflight{Name: "web", OldID: "old1", Step: "created", StartedAt: time.Now().UTC().Truncate(time.Second)}
+	if err := s.SetInflight(web); err != nil {
+		t.Fatalf("SetInflight() error = %v", err)
+	}
+	web.NewID, web.Step = "new1", "stopped"
+	if err := s.SetInflight(web); err != nil {
+		t.Fatalf("SetInflight() error = %v", err)
+	}
+	if err := s.SetInflight(Inflight{Name: "db", OldID: "old2", Step: "created"}); err != nil {
+		t.Fatalf("SetInflight() error = %v", err)
+	}
+	if err := s.ClearInflight("db"); err != nil {
+		t.Fatalf("ClearInflight() error = %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	got := reopened.Inflight()
+	if len(got) != 1 || got[0] != web {
+		t.Errorf("Inflight() = %+v, want [%+v]", got, web)
+	}
+}
+
+func TestStore_Pending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	web := Pending{Image: "nginx:latest", OldImageID: "sha256:old", NewImageID: "sha256:new", FoundAt: time.Now().UTC().Truncate(time.Second)}
+	if err := s.SetPending("web", web); err != nil {
+		t.Fatalf("SetPending() error = %v", err)
+	}
+	if err := s.SetPending("db", Pending{Image: "postgres:16", NewImageID: "sha256:pg"}); err != nil {
+		t.Fatalf("SetPending() error = %v", err)
+	}
+	if err := s.ClearPending("db"); err != nil {
+		t.Fatalf("ClearPending() error = %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got, ok := reopened.Pending("web"); !ok || got != web {
+		t.Errorf("Pending(web) = %+v, %v, want %+v", got, ok, web)
+	}
+	if got := reopened.PendingUpdates(); len(got) != 1 {
+		t.Errorf("PendingUpdates() = %+v, want only web", got)
+	}
+}
+
+func TestStore_PrunePending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"web", "db", "old"} {
+		if err := s.SetPending(name, Pending{Image: name + ":latest"}); err != nil {
+			t.Fatalf("SetPending() error = %v", err)
+		}
+	}
+
+	pruned, err := s.PrunePending(map[string]bool{"web": true, "db": true})
+	if err != nil {
+		t.Fatalf("PrunePending() error = %v", err)
+	}
+	if len(pruned) != 1 || pruned[0] != "old" {
+		t.Errorf("PrunePending() = %v, want [old]", pruned)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, ok := reopened.Pending("old"); ok {
+		t.Error("the pending update of a removed container was saved")
+	}
+	if got := reopened.PendingUpdates(); len(got) != 2 {
+		t.Errorf("PendingUpdates() = %+v, want web and db", got)
+	}
+}
+
+func TestStore_Freeze(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	if err := s.Freeze("db", Freeze{Until: now.Add(72 * time.Hour), Reason: "incident", FrozenAt: now}); err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+	if err := s.Freeze("web", Freeze{FrozenAt: now}); err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+
+	if frozen := s.Frozen(now.Add(time.Hour)); len(frozen) != 2 || frozen["db"].Reason != "incident" {
+		t.Errorf("Frozen() = %+v, want db and web", frozen)
+	}
+	if frozen := s.Frozen(now.Add(72 * time.Hour)); len(frozen) != 1 || !frozen["web"].Until.IsZero() {
+		t.Errorf("Frozen() after db's freeze expired = %+v, want web only", frozen)
+	}
+
+	if ok, err := s.Unfreeze("web"); !ok || err != nil {
+		t.Errorf("Unfreeze(web) = %v, %v; want true", ok, err)
+	}
+	if ok, _ := s.Unfreeze("web"); ok {
+		t.Error("Unfreeze(web) twice = true, want false")
+	}
+}
+
+func TestStore_SaveKeepsFreezes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	// A cycle opens the store, then a freeze is made through another one
+	cycle, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Freeze("db", Freeze{FrozenAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cycle.Put("web", Record{Image: "nginx:latest"}); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Frozen(time.Now())["db"]; !ok {
+		t.Error("saving the cycle's store dropped a freeze made since it was opened")
+	}
+	if _, ok := reopened.Get("web"); !ok {
+		t.Error("the cycle's record wasn't saved")
+	}
+}
+
+func TestStore_FreezeWhileAnotherStoreSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cycle, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Like a cycle recording updates while the CLI freezes containers: every freeze must
+	// survive the cycle's saves
+	now := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 50 {
+			if err := cycle.Put(fmt.Sprintf("app-%d", i), Record{Image: "nginx:latest"}); err != nil {
+				t.Errorf("Put() error = %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := range 50 {
+			if err := cli.Freeze(fmt.Sprintf("db-%d", i), Freeze{FrozenAt: now}); err != nil {
+				t.Errorf("Freeze() error = %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frozen := reopened.Frozen(now); len(frozen) != 50 {
+		t.Errorf("Frozen() has %d containers, want all 50 freezes kept", len(frozen))
+	}
+}
@@ -0,0 +1,95 @@
+// Package status keeps a JSON file of what the last update check found for each managed
+// container, for tools such as Portainer templates or scripts that want HarborBuddy's
+// findings without its API. Docker labels can't be changed on a running container, so the
+// file is where they are published.
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Container is the last check of one container
+type Container struct {
+	Image           string     `json:"image"`
+	ImageID         string     `json:"image_id"` // The image the container runs
+	CheckedAt       time.Time  `json:"checked_at"`
+	UpdateAvailable bool       `json:"update_available"`
+	LatestImage     string     `json:"latest_image,omitempty"` // The tag the update policy moves to, when it isn't Image
+	LatestImageID   string     `json:"latest_image_id,omitempty"`
+	LatestDigest    string     `json:"latest_digest,omitempty"` // Registry digest of the latest image, when it was pulled
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`    // When HarborBuddy last replaced the container
+}
+
+// File is the status file, keyed by container name
+type File struct {
+	UpdatedAt  time.Time            `json:"updated_at"`
+	Containers map[string]Container `json:"containers"`
+}
+
+// Read loads the status file at path. A missing file is empty.
+func Read(path string) (File, error) {
+	f := File{Containers: map[string]Container{}}
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return f, fmt.Errorf("failed to read status file: %w", err)
+	}
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return File{Containers: map[string]Container{}}, fmt.Errorf("failed to parse status file: %w", err)
+	}
+	if f.Containers == nil {
+		f.Containers = map[string]Container{}
+	}
+	return f, nil
+}
+
+// Update merges checked into the status file at path. Containers not in running, which no
+// longer exist, are dropped; those running but not checked this time keep their last entry,
+// and a checked one its last update time. The file is replaced atomically so readers never
+// see a partial one.
+func Update(path string, checked map[string]Container, running []string) error {
+	f, err := Read(path)
+	if err != nil {
+		// A corrupt file is rebuilt from this check rather than blocking it for good
+		f = File{Containers: map[string]Container{}}
+	}
+
+	exists := make(map[string]bool, len(running))
+	for _, name := range running {
+		exists[name] = true
+	}
+	for name := range f.Containers {
+		if !exists[name] {
+			delete(f.Containers, name)
+		}
+	}
+	for name, c := range checked {
+		if c.UpdatedAt == nil {
+			c.UpdatedAt = f.Containers[name].UpdatedAt
+		}
+		f.Containers[name] = c
+	}
+	f.UpdatedAt = time.Now().UTC()
+
+	raw, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create status directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(raw, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace status file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "harborbuddy-status.json")
+	updatedAt := time.Date(2026, 10, 1, 3, 0, 0, 0, time.UTC)
+
+	first := map[string]Container{
+		"web": {Image: "nginx:latest", ImageID: "sha256:new-nginx", UpdatedAt: &updatedAt},
+		"db":  {Image: "postgres:16", ImageID: "sha256:postgres"},
+		"old": {Image: "redis:7", ImageID: "sha256:redis"},
+	}
+	if err := Update(path, first, []string{"web", "db", "old"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// "old" was removed, "db" wasn't checked this time, "web" was checked again
+	second := map[string]Container{
+		"web": {Image: "nginx:latest", ImageID: "sha256:new-nginx", UpdateAvailable: true, LatestImageID: "sha256:newer-nginx"},
+	}
+	if err := Update(path, second, []string{"web", "db"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	f, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(f.Containers) != 2 {
+		t.Fatalf("containers = %+v, want web and db", f.Containers)
+	}
+	web := f.Containers["web"]
+	if !web.UpdateAvailable || web.LatestImageID != "sha256:newer-nginx" {
+		t.Errorf("web = %+v, want the latest check", web)
+	}
+	if web.UpdatedAt == nil || !web.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("web.UpdatedAt = %v, want the last update kept", web.UpdatedAt)
+	}
+	if f.Containers["db"].ImageID != "sha256:postgres" {
+		t.Errorf("db = %+v, want its last entry kept", f.Containers["db"])
+	}
+	if f.UpdatedAt.IsZero() {
+		t.Error("UpdatedAt is not set")
+	}
+}
+
+func TestUpdate_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "harborbuddy-status.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Error("Read() of a corrupt file succeeded")
+	}
+
+	if err := Update(path, map[string]Container{"web": {Image: "nginx:latest"}}, []string{"web"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if f, err := Read(path); err != nil || len(f.Containers) != 1 {
+		t.Errorf("Read() = %+v, %v, want the file rebuilt", f, err)
+	}
+}
@@ -0,0 +1,296 @@
+package updater
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/history"
+	"github.com/MikeO7/HarborBuddy/internal/hooks"
+	"github.com/MikeO7/HarborBuddy/internal/metrics"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/MikeO7/HarborBuddy/internal/watchdog"
+	"github.com/rs/zerolog"
+)
+
+// updateCandidate is a container found to have an update
+type updateCandidate struct {
+	Container docker.ContainerInfo
+	Target    string // Image reference to run, a newer tag when the policy allows one
+	NewImage  docker.ImageInfo
+	Release   releaseInfo // Versions and release notes link from the images' OCI labels
+	Logger    *zerolog.Logger
+
+	// CurrentCreated is when the image the container runs was built, zero if unknown
+	CurrentCreated time.Time
+	Canary         bool // Updated before the others, see isCanary
+}
+
+// applyResult is the outcome of applying a group of updates
+type applyResult struct {
+	updated   int
+	restarted []string // Dependents recreated or restarted alongside an update, not updated themselves
+	errors    errorTally
+	replaced  []history.Replacement
+	failures  []history.Failure
+}
+
+// applier replaces containers for one update cycle
+type applier struct {
+	cfg          config.Config
+	dockerClient docker.Client
+	containers   []docker.ContainerInfo // Every container listed this cycle
+	graph        dependencyGraph
+	volumesFrom  volumesFromRefs
+	notifier     notify.Notifier
+	store        *state.Store
+	statuses     *statusRecorder
+	cycleID      string
+	stagger      *stagger
+	logger       *zerolog.Logger
+}
+
+// applyGroups applies each group of related updates in order, running up to
+// updates.max_parallel_updates groups at once. Results are returned in group order.
+func (a *applier) applyGroups(ctx context.Context, groups [][]updateCandidate) []applyResult {
+	results := make([]applyResult, len(groups))
+
+	workers := min(max(a.cfg.Updates.MaxParallelUpdates, 1), len(groups))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = a.applyGroup(ctx, groups[i])
+			}
+		}()
+	}
+
+	for i := range groups {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// applyGroup applies a group of related updates one at a time, stopping early if ctx is cancelled
+func (a *applier) applyGroup(ctx context.Context, group []updateCandidate) applyResult {
+	result := applyResult{errors: errorTally{}}
+
+	// Old IDs of containers already recreated alongside a container they are linked to
+	recreated := make(map[string]bool)
+	// A candidate recreated alongside another one is recreated on its own target right away
+	targets := make(map[string]string, len(group))
+	candidates := make(map[string]bool, len(group))
+	for _, candidate := range group {
+		targets[candidate.Container.ID] = candidate.Target
+		candidates[candidate.Container.Name] = true
+	}
+
+	for _, candidate := range group {
+		if ctx.Err() != nil {
+			break
+		}
+		a.apply(ctx, candidate, recreated, targets, &result)
+	}
+
+	// Candidates count as updated or failed on their own, not as restarted dependents, and a
+	// dependent restarted for several of its dependencies counts once
+	seen := make(map[string]bool, len(result.restarted))
+	result.restarted = slices.DeleteFunc(result.restarted, func(name string) bool {
+		skip := candidates[name] || seen[name]
+		seen[name] = true
+		return skip
+	})
+	for _, name := range result.restarted {
+		report.FromContext(ctx).AddRestarted(name)
+	}
+	return result
+}
+
+// apply replaces one container with its updated image
+func (a *applier) apply(ctx context.Context, candidate updateCandidate, recreated map[string]bool, targets map[string]string, result *applyResult) {
+	container := candidate.Container
+	containerLogger := candidate.Logger
+
+	if recreated[container.ID] {
+		// It was recreated on its target, so the update is already applied
+		containerLogger.Debug().Msg("Already recreated with the container it is linked to")
+		metrics.Default.RecordUpdate(container.Name, container.Image, time.Now())
+		notify.Send(ctx, a.notifier, updateEvent(candidate), containerLogger)
+		recordUpdate(a.store, container, candidate.NewImage, containerLogger)
+		a.statuses.updated(container.Name, candidate.NewImage)
+		result.replaced = append(result.replaced, a.pinDeployed(ctx, candidate, replacement(container, candidate.Target, candidate.NewImage)))
+		a.reportUpdate(ctx, candidate)
+		result.updated++
+		return
+	}
+
+	if err := a.stagger.wait(ctx, containerLogger); err != nil {
+		return
+	}
+	defer a.stagger.done()
+	defer watchdog.Begin(ctx, "replacing "+container.Name)()
+
+	// Don't start a replacement once shutdown began, but let a started one finish
+	if ctx.Err() != nil {
+		return
+	}
+	cycleCtx := ctx
+	ctx, stopDrain := drainContext(ctx, a.cfg.Updates.DrainTimeout, containerLogger)
+	defer stopDrain()
+
+	// Stop consumers so they don't hit the dependency mid-replacement; starting them
+	// again afterwards restarts them even when their own image is unchanged
+	stopped := stopDependents(ctx, a.dockerClient, a.graph, a.containers, container, recreated, int(a.cfg.Updates.StopTimeout.Seconds()), containerLogger)
+
+	hookEnv := hooks.Env{
+		CycleID:     a.cycleID,
+		Container:   container.Name,
+		ContainerID: container.ID,
+		Image:       candidate.Target,
+		OldImageID:  container.ImageID,
+		NewImageID:  candidate.NewImage.ID,
+	}
+	var newID string
+	err := hooks.Run(ctx, hooks.PreUpdate, a.cfg.Hooks.PreUpdate, hookEnv, a.cfg.Hooks.Timeout, containerLogger)
+	if err != nil {
+		err = withCategory(categoryHook, err)
+	} else {
+		newID, err = updateContainer(ctx, a.cfg, a.dockerClient, a.store, container, candidate.Target, containerLogger)
+		runPostUpdateScript(ctx, a.cfg, hookEnv, err, containerLogger)
+	}
+	if err != nil {
+		category := classifyError(cycleCtx, err)
+		// A container that failed its health check is gone after the rollback; its last log
+		// lines say why it failed
+		logs := docker.HealthLogs(err)
+		failure := containerLogger.Error().Err(err).Str("error_category", string(category))
+		if logs != "" {
+			failure = failure.Str("container_logs", logs)
+		}
+		failure.Msg("Failed to update container")
+		metrics.Default.RecordFailure(container.Name, container.Image)
+		notify.Send(ctx, a.notifier, notify.Event{
+			Type:       notify.EventFailure,
+			Outcome:    notify.OutcomeFailure,
+			Container:  container.Name,
+			Image:      container.Image,
+			OldImageID: container.ImageID,
+			NewImageID: candidate.NewImage.ID,
+			Error:      err.Error(),
+			Logs:       logs,
+		}, containerLogger)
+		result.errors.add(category)
+		result.failures = append(result.failures, history.Failure{Container: container.Name, Category: string(category), Error: err.Error()})
+		report.FromContext(ctx).AddFailure(report.Failure{Name: container.Name, Image: container.Image, Category: string(category), Error: err.Error()})
+		// The old container is back after rollback; let its consumers reconnect
+		result.restarted = append(result.restarted, startDependents(ctx, a.dockerClient, stopped, recreated, containerLogger)...)
+		return
+	}
+	metrics.Default.RecordUpdate(container.Name, container.Image, time.Now())
+	notify.Send(ctx, a.notifier, updateEvent(candidate), containerLogger)
+	recordUpdate(a.store, container, candidate.NewImage, containerLogger)
+	a.statuses.updated(container.Name, candidate.NewImage)
+	result.replaced = append(result.replaced, a.pinDeployed(ctx, candidate, replacement(container, candidate.Target, candidate.NewImage)))
+	a.reportUpdate(ctx, candidate)
+	recreateLinkedDependents(ctx, a.cfg, a.dockerClient, a.store, a.containers, a.volumesFrom, container, newID, targets, recreated, result, a.logger)
+	result.restarted = append(result.restarted, startDependents(ctx, a.dockerClient, stopped, recreated, containerLogger)...)
+
+	// updateContainer logs the friendly "Updated" message
+	result.updated++
+}
+
+// reportUpdate adds a replaced container to the cycle report, if one is being collected.
+// The old image is still present until cleanup, so its size can be looked up.
+func (a *applier) reportUpdate(ctx context.Context, candidate updateCandidate) {
+	rep := report.FromContext(ctx)
+	if rep == nil {
+		return
+	}
+	entry := reportEntry(candidate)
+	if old, err := a.dockerClient.InspectImage(ctx, candidate.Container.ImageID); err == nil {
+		entry.OldSize = old.Size
+	}
+	rep.AddUpdated(entry)
+}
+
+// updateGroups splits candidates into groups that can be replaced independently of each
+// other. Containers sharing a network namespace, volumes or a compose project, or linked
+// by a dependency, directly or through containers that aren't being updated, end up in the
+// same group. Candidates keep their
+// relative order within a group, and groups are ordered by their first candidate.
+func updateGroups(containers []docker.ContainerInfo, graph dependencyGraph, volumesFrom volumesFromRefs, candidates []updateCandidate) [][]updateCandidate {
+	parent := make(map[string]string, len(containers))
+	var find func(id string) string
+	find = func(id string) string {
+		p, ok := parent[id]
+		if !ok || p == id {
+			return id
+		}
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+	union := func(a, b string) {
+		if ra, rb := find(a), find(b); ra != rb {
+			parent[ra] = rb
+		}
+	}
+
+	projects := make(map[string]string) // compose project -> a container in it
+	for _, c := range containers {
+		if volumesFrom.linked(c) {
+			for _, other := range containers {
+				if other.ID != c.ID && (refersTo(c.NetworkContainer(), other) || volumesFrom.mounts(c, other)) {
+					union(c.ID, other.ID)
+				}
+			}
+		}
+		for _, dep := range graph[c.ID] {
+			union(c.ID, dep.ID)
+		}
+		if project, _ := composeService(c); project != "" {
+			if first, ok := projects[project]; ok {
+				union(c.ID, first)
+			} else {
+				projects[project] = c.ID
+			}
+		}
+	}
+
+	var groups [][]updateCandidate
+	index := make(map[string]int)
+	for _, candidate := range candidates {
+		root := find(candidate.Container.ID)
+		i, ok := index[root]
+		if !ok {
+			i = len(groups)
+			index[root] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], candidate)
+	}
+	return groups
+}
+
+// mergeInto adds a group's outcome to the cycle journal and error tally, and returns
+// how many containers it updated
+func (r applyResult) mergeInto(journal *history.Cycle, errorCounts errorTally) int {
+	for category, count := range r.errors {
+		errorCounts[category] += count
+	}
+	journal.Replaced = append(journal.Replaced, r.replaced...)
+	journal.Restarted = append(journal.Restarted, r.restarted...)
+	journal.Failures = append(journal.Failures, r.failures...)
+	return r.updated
+}
@@ -0,0 +1,177 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/rs/zerolog"
+)
+
+func TestUpdateGroups(t *testing.T) {
+	containers := []docker.ContainerInfo{
+		{ID: "vpn", Name: "vpn"},
+		{ID: "torrent", Name: "torrent", NetworkMode: "container:vpn"},
+		{ID: "db", Name: "db", Labels: map[string]string{composeProjectLabel: "app", composeServiceLabel: "db"}},
+		{ID: "web", Name: "web", Labels: map[string]string{composeProjectLabel: "app", composeServiceLabel: "web"}},
+		{ID: "proxy", Name: "proxy"},
+		// Not being updated, but ties the proxy to the vpn group
+		{ID: "sidecar", Name: "sidecar", NetworkMode: "container:proxy", Labels: map[string]string{composeProjectLabel: "vpnstack", composeServiceLabel: "sidecar"}},
+		{ID: "vpn-ui", Name: "vpn-ui", Labels: map[string]string{composeProjectLabel: "vpnstack", composeServiceLabel: "ui"}},
+		{ID: "ui-helper", Name: "ui-helper", NetworkMode: "container:vpn-ui"},
+		{ID: "vpn-link", Name: "vpn-link", NetworkMode: "container:vpn", Labels: map[string]string{composeProjectLabel: "vpnstack", composeServiceLabel: "link"}},
+		{ID: "redis", Name: "redis"},
+	}
+
+	var candidates []updateCandidate
+	for _, name := range []string{"vpn", "db", "redis", "torrent", "web", "proxy"} {
+		candidates = append(candidates, updateCandidate{Container: docker.ContainerInfo{ID: name, Name: name}})
+	}
+
+	var got [][]string
+	for _, group := range updateGroups(containers, buildDependencyGraph(containers, nil), nil, candidates) {
+		var names []string
+		for _, c := range group {
+			names = append(names, c.Container.Name)
+		}
+		got = append(got, names)
+	}
+
+	want := [][]string{{"vpn", "torrent", "proxy"}, {"db", "web"}, {"redis"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("updateGroups() = %v, want %v", got, want)
+	}
+}
+
+// concurrencyClient records how many replacements were in flight at once
+type concurrencyClient struct {
+	*docker.MockDockerClient
+
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (c *concurrencyClient) ReplaceContainer(ctx context.Context, oldID, newID, name string, opts docker.ReplaceOptions) error {
+	c.mu.Lock()
+	c.inFlight++
+	c.peak = max(c.peak, c.inFlight)
+	c.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return c.MockDockerClient.ReplaceContainer(ctx, oldID, newID, name, opts)
+}
+
+func TestRunUpdateCycle_MaxParallelUpdates(t *testing.T) {
+	for _, parallel := range []int{1, 2} {
+		t.Run(fmt.Sprintf("max %d", parallel), func(t *testing.T) {
+			mockClient := docker.NewMockDockerClient()
+			for i := 0; i < 4; i++ {
+				image := fmt.Sprintf("app%d:latest", i)
+				mockClient.Containers = append(mockClient.Containers, docker.ContainerInfo{
+					ID:      fmt.Sprintf("container%d", i),
+					Name:    fmt.Sprintf("app%d", i),
+					Image:   image,
+					ImageID: "sha256:old",
+				})
+				mockClient.PullImageReturns[image] = docker.ImageInfo{ID: "sha256:new"}
+			}
+			client := &concurrencyClient{MockDockerClient: mockClient}
+
+			cfg := config.Default()
+			cfg.Updates.MaxParallelUpdates = parallel
+
+			logger := zerolog.Nop()
+			if err := RunUpdateCycle(context.Background(), cfg, client, &logger); err != nil {
+				t.Fatalf("RunUpdateCycle() error = %v", err)
+			}
+
+			if n := len(mockClient.ReplacedContainers); n != 4 {
+				t.Errorf("replaced %d containers, want 4", n)
+			}
+			if client.peak != parallel {
+				t.Errorf("peak concurrent replacements = %d, want %d", client.peak, parallel)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_Report(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "web", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx"},
+		{ID: "db", Name: "db", Image: "postgres:16", ImageID: "sha256:postgres"},
+		{ID: "pinned", Name: "pinned", Image: "redis:7", ImageID: "sha256:redis", Labels: map[string]string{"com.harborbuddy.autoupdate": "false"}},
+	}
+	mockClient.Images = []docker.ImageInfo{{ID: "sha256:old-nginx", Size: 100}}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx", Size: 150},
+		"postgres:16":  {ID: "sha256:postgres", Size: 300},
+	}
+
+	rep := report.New("abcd1234", false, false)
+	ctx := report.WithReport(context.Background(), rep)
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(ctx, config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+	rep.Finish(nil)
+
+	if rep.Checked != 2 {
+		t.Errorf("Checked = %d, want 2", rep.Checked)
+	}
+	want := []report.Container{{Name: "web", Image: "nginx:latest", OldImageID: "sha256:old-nginx", NewImageID: "sha256:new-nginx", OldSize: 100, NewSize: 150, SizeDelta: 50}}
+	if !reflect.DeepEqual(rep.Updated, want) {
+		t.Errorf("Updated = %+v, want %+v", rep.Updated, want)
+	}
+	if rep.SizeDelta != 50 {
+		t.Errorf("SizeDelta = %d, want 50", rep.SizeDelta)
+	}
+
+	skipped := map[string]string{}
+	for _, s := range rep.Skipped {
+		skipped[s.Name] = s.Reason
+	}
+	if skipped["db"] != "up to date" || skipped["pinned"] != "label com.harborbuddy.autoupdate=false" {
+		t.Errorf("Skipped = %+v, want db up to date and pinned by label", rep.Skipped)
+	}
+	if rep.Outcome != report.OutcomeSuccess || len(rep.Failed) != 0 {
+		t.Errorf("Outcome = %s with failures %+v, want success", rep.Outcome, rep.Failed)
+	}
+}
+
+func TestRunUpdateCycle_HealthFailureLogs(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{{ID: "web", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx"}}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:new-nginx"}}
+	mockClient.ReplaceContainerError = &docker.HealthError{
+		Err:  fmt.Errorf("%w, rolled back to old container: new container reported unhealthy", docker.ErrUnhealthy),
+		Logs: "panic: missing DATABASE_URL",
+	}
+
+	recorder := &eventRecorder{}
+	ctx := notify.WithNotifier(context.Background(), recorder)
+	logger := zerolog.Nop()
+	_ = RunUpdateCycle(ctx, config.Default(), mockClient, &logger)
+
+	var failures []notify.Event
+	for _, event := range recorder.events {
+		if event.Type == notify.EventFailure {
+			failures = append(failures, event)
+		}
+	}
+	if len(failures) != 1 || failures[0].Logs != "panic: missing DATABASE_URL" {
+		t.Errorf("failure events = %+v, want one carrying the container's last log lines", failures)
+	}
+}
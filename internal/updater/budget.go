@@ -0,0 +1,90 @@
+package updater
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// priorityLabel orders updates when updates.max_updates_per_cycle defers some: higher goes
+// first, unlabelled containers count as 0
+const priorityLabel = "com.harborbuddy.priority"
+
+// priorityOf returns a candidate's com.harborbuddy.priority, 0 if missing or not a number
+func priorityOf(candidate updateCandidate) int {
+	priority, err := strconv.Atoi(candidate.Container.Labels[priorityLabel])
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+// ranksBefore orders candidates for updates.max_updates_per_cycle: canaries first, then
+// higher priority, then the container running the oldest image, as it has gone longest
+// without an update; unknown build times count as newest
+func ranksBefore(a, b updateCandidate) bool {
+	if a.Canary != b.Canary {
+		return a.Canary
+	}
+	if pa, pb := priorityOf(a), priorityOf(b); pa != pb {
+		return pa > pb
+	}
+	if a.CurrentCreated.IsZero() != b.CurrentCreated.IsZero() {
+		return b.CurrentCreated.IsZero()
+	}
+	if !a.CurrentCreated.Equal(b.CurrentCreated) {
+		return a.CurrentCreated.Before(b.CurrentCreated)
+	}
+	return a.Container.Name < b.Container.Name
+}
+
+// limitUpdates splits the groups of related candidates (see updateGroups) into up to max
+// candidates to apply this cycle and the ones deferred to a later cycle (max 0 applies all).
+// Groups are applied or deferred whole, so a compose project or dependency chain is never
+// left half updated. A group ranks as its best member by ranksBefore, and groups that don't
+// fit in what is left of max are skipped for smaller ones ranked after them. A group larger
+// than max is still applied on its own when it ranks first, or it would never be updated.
+// Groups to apply keep their original order.
+func limitUpdates(groups [][]updateCandidate, max int) (apply, deferred []updateCandidate) {
+	total := 0
+	for _, group := range groups {
+		total += len(group)
+	}
+	if max <= 0 || total <= max {
+		return slices.Concat(groups...), nil
+	}
+
+	best := make([]updateCandidate, len(groups))
+	for i, group := range groups {
+		best[i] = group[0]
+		for _, candidate := range group[1:] {
+			if ranksBefore(candidate, best[i]) {
+				best[i] = candidate
+			}
+		}
+	}
+	ranked := make([]int, len(groups))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranksBefore(best[ranked[i]], best[ranked[j]])
+	})
+
+	chosen := make(map[int]bool)
+	left := max
+	for n, i := range ranked {
+		if len(groups[i]) <= left || n == 0 {
+			chosen[i] = true
+			left -= len(groups[i])
+		}
+	}
+	for i, group := range groups {
+		if chosen[i] {
+			apply = append(apply, group...)
+		} else {
+			deferred = append(deferred, group...)
+		}
+	}
+	return apply, deferred
+}
@@ -0,0 +1,133 @@
+package updater
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestLimitUpdates(t *testing.T) {
+	built := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	candidate := func(name, priority string, age time.Duration) updateCandidate {
+		c := updateCandidate{Container: docker.ContainerInfo{Name: name, Labels: map[string]string{}}}
+		if priority != "" {
+			c.Container.Labels[priorityLabel] = priority
+		}
+		if age > 0 {
+			c.CurrentCreated = built.Add(-age)
+		}
+		return c
+	}
+	names := func(candidates []updateCandidate) []string {
+		var out []string
+		for _, c := range candidates {
+			out = append(out, c.Container.Name)
+		}
+		return out
+	}
+
+	candidates := []updateCandidate{
+		candidate("web", "", 24*time.Hour),
+		candidate("unknown-age", "", 0),
+		candidate("cache", "", 90*24*time.Hour),
+		candidate("db", "10", time.Hour),
+		candidate("worker", "not a number", 30*24*time.Hour),
+	}
+
+	tests := []struct {
+		max      int
+		apply    []string
+		deferred []string
+	}{
+		{0, []string{"web", "unknown-age", "cache", "db", "worker"}, nil},
+		{5, []string{"web", "unknown-age", "cache", "db", "worker"}, nil},
+		{1, []string{"db"}, []string{"web", "unknown-age", "cache", "worker"}},
+		{3, []string{"cache", "db", "worker"}, []string{"web", "unknown-age"}},
+		{4, []string{"web", "cache", "db", "worker"}, []string{"unknown-age"}},
+	}
+	singles := make([][]updateCandidate, len(candidates))
+	for i, c := range candidates {
+		singles[i] = []updateCandidate{c}
+	}
+	for _, tt := range tests {
+		apply, deferred := limitUpdates(singles, tt.max)
+		if got := names(apply); !slices.Equal(got, tt.apply) {
+			t.Errorf("limitUpdates(%d) applies %v, want %v", tt.max, got, tt.apply)
+		}
+		if got := names(deferred); !slices.Equal(got, tt.deferred) {
+			t.Errorf("limitUpdates(%d) defers %v, want %v", tt.max, got, tt.deferred)
+		}
+	}
+}
+
+func TestLimitUpdates_Groups(t *testing.T) {
+	built := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	candidate := func(name string, age time.Duration) updateCandidate {
+		return updateCandidate{Container: docker.ContainerInfo{Name: name}, CurrentCreated: built.Add(-age)}
+	}
+	names := func(candidates []updateCandidate) []string {
+		var out []string
+		for _, c := range candidates {
+			out = append(out, c.Container.Name)
+		}
+		return out
+	}
+	// app-db runs the oldest image, so its project ranks first and is applied whole
+	app := []updateCandidate{candidate("app-web", time.Hour), candidate("app-db", 90*24*time.Hour)}
+	media := []updateCandidate{candidate("media-plex", 30*24*time.Hour), candidate("media-sonarr", 30*24*time.Hour)}
+	cache := []updateCandidate{candidate("cache", 24*time.Hour)}
+
+	tests := []struct {
+		max      int
+		apply    []string
+		deferred []string
+	}{
+		{1, []string{"app-web", "app-db"}, []string{"media-plex", "media-sonarr", "cache"}},
+		{3, []string{"app-web", "app-db", "cache"}, []string{"media-plex", "media-sonarr"}},
+		{4, []string{"app-web", "app-db", "media-plex", "media-sonarr"}, []string{"cache"}},
+	}
+	for _, tt := range tests {
+		apply, deferred := limitUpdates([][]updateCandidate{app, media, cache}, tt.max)
+		if got := names(apply); !slices.Equal(got, tt.apply) {
+			t.Errorf("limitUpdates(%d) applies %v, want %v", tt.max, got, tt.apply)
+		}
+		if got := names(deferred); !slices.Equal(got, tt.deferred) {
+			t.Errorf("limitUpdates(%d) defers %v, want %v", tt.max, got, tt.deferred)
+		}
+	}
+}
+
+func TestRunUpdateCycle_MaxUpdatesPerCycle(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "cache", Image: "redis:7", ImageID: "sha256:old-redis", Config: &container.Config{Image: "redis:7"}},
+	}
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:old-nginx", CreatedAt: time.Now().Add(-24 * time.Hour)},
+		{ID: "sha256:old-redis", CreatedAt: time.Now().Add(-90 * 24 * time.Hour)},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+		"redis:7":      {ID: "sha256:new-redis"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.MaxUpdatesPerCycle = 1
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	// Both are pulled, only the container on the older image is replaced
+	if len(mockClient.CreatedContainers) != 1 || mockClient.CreatedContainers[0].NewImage != "redis:7" {
+		t.Errorf("created %+v, want only the cache container updated", mockClient.CreatedContainers)
+	}
+}
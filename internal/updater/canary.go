@@ -0,0 +1,57 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/rs/zerolog"
+)
+
+// canaryLabel marks a container to update before the others, like updates.canary_containers
+const canaryLabel = "com.harborbuddy.canary"
+
+// isCanary reports whether a container is updated first, to vet its new image before the rest
+func isCanary(container docker.ContainerInfo, cfg config.UpdatesConfig) bool {
+	if container.Labels[canaryLabel] == "true" {
+		return true
+	}
+	for _, pattern := range cfg.CanaryContainers {
+		if matchesPattern(container.Name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCanaries separates the canaries from the other candidates, keeping their order
+func splitCanaries(candidates []updateCandidate) (canaries, others []updateCandidate) {
+	for _, candidate := range candidates {
+		if candidate.Canary {
+			canaries = append(canaries, candidate)
+		} else {
+			others = append(others, candidate)
+		}
+	}
+	return canaries, others
+}
+
+// abortAfterCanary skips the updates left in the cycle once the canary named failed, and
+// sends a notification saying so
+func abortAfterCanary(ctx context.Context, failed string, aborted []updateCandidate, notifier notify.Notifier, logger *zerolog.Logger) {
+	reason := fmt.Sprintf("aborted: canary %s failed", failed)
+	logger.Error().Msgf("🐤 Canary %s failed its update, aborting the %d remaining updates of this cycle", failed, len(aborted))
+	for _, candidate := range aborted {
+		candidate.Logger.Warn().Str("image", candidate.Container.Image).Msgf("Not updating: canary %s failed", failed)
+		report.FromContext(ctx).AddSkipped(candidate.Container.Name, reason)
+	}
+	notify.Send(ctx, notifier, notify.Event{
+		Type:      notify.EventFailure,
+		Outcome:   notify.OutcomeCanaryFailed,
+		Container: failed,
+		Error:     fmt.Sprintf("canary %s failed its update, %d remaining updates were aborted", failed, len(aborted)),
+	}, logger)
+}
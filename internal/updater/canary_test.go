@@ -0,0 +1,100 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+// failingReplaceClient fails the replacement of one container, as a failed health check does
+type failingReplaceClient struct {
+	*docker.MockDockerClient
+	fail string
+}
+
+func (f *failingReplaceClient) ReplaceContainer(ctx context.Context, oldID, newID, name string, opts docker.ReplaceOptions) error {
+	if name == f.fail {
+		return errors.New("new container failed its health check, rolled back")
+	}
+	return f.MockDockerClient.ReplaceContainer(ctx, oldID, newID, name, opts)
+}
+
+func TestIsCanary(t *testing.T) {
+	cfg := config.UpdatesConfig{CanaryContainers: []string{"*-canary"}}
+	tests := []struct {
+		container docker.ContainerInfo
+		want      bool
+	}{
+		{docker.ContainerInfo{Name: "web-canary"}, true},
+		{docker.ContainerInfo{Name: "web", Labels: map[string]string{canaryLabel: "true"}}, true},
+		{docker.ContainerInfo{Name: "web", Labels: map[string]string{canaryLabel: "false"}}, false},
+		{docker.ContainerInfo{Name: "web"}, false},
+	}
+	for _, tt := range tests {
+		if got := isCanary(tt.container, cfg); got != tt.want {
+			t.Errorf("isCanary(%s, %v) = %v, want %v", tt.container.Name, tt.container.Labels, got, tt.want)
+		}
+	}
+}
+
+func canaryContainers() *docker.MockDockerClient {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "cache", Image: "redis:7", ImageID: "sha256:old-redis", Config: &container.Config{Image: "redis:7"}},
+		{ID: "container3", Name: "web-canary", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+		"redis:7":      {ID: "sha256:new-redis"},
+	}
+	return mockClient
+}
+
+func TestRunUpdateCycle_CanaryFirst(t *testing.T) {
+	mockClient := canaryContainers()
+	cfg := config.Default()
+	cfg.Updates.CanaryContainers = []string{"*-canary"}
+
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.ReplacedContainers) != 3 || mockClient.ReplacedContainers[0].Name != "web-canary" {
+		t.Errorf("replaced = %+v, want web-canary first, then the others", mockClient.ReplacedContainers)
+	}
+}
+
+func TestRunUpdateCycle_CanaryFailureAborts(t *testing.T) {
+	mockClient := canaryContainers()
+	client := &failingReplaceClient{MockDockerClient: mockClient, fail: "web-canary"}
+	cfg := config.Default()
+	cfg.Updates.CanaryContainers = []string{"*-canary"}
+
+	recorder := &eventRecorder{}
+	ctx := notify.WithNotifier(context.Background(), recorder)
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(ctx, cfg, client, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	for _, r := range mockClient.ReplacedContainers {
+		t.Errorf("replaced %s after the canary failed", r.Name)
+	}
+	var aborted []notify.Event
+	for _, event := range recorder.events {
+		if event.Outcome == notify.OutcomeCanaryFailed {
+			aborted = append(aborted, event)
+		}
+	}
+	if len(aborted) != 1 || aborted[0].Container != "web-canary" {
+		t.Errorf("canary_failed events = %+v, want one for web-canary", aborted)
+	}
+}
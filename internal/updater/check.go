@@ -0,0 +1,233 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/pins"
+	"github.com/rs/zerolog"
+)
+
+// Exit codes of `harborbuddy check`, for cron and CI gating
+const (
+	CheckUpToDate        = 0 // Nothing to update
+	CheckUpdateAvailable = 1 // At least one eligible container has an update
+	CheckFailed          = 2 // At least one container couldn't be checked
+)
+
+// ErrNoSuchContainer is matched by errors for a container name that isn't running
+var ErrNoSuchContainer = errors.New("no running container")
+
+// CheckResult is one container's row in the output of `harborbuddy check`
+type CheckResult struct {
+	Name            string
+	Image           string // Reference the container runs
+	Target          string // Newer tag its policy would move to, if any
+	CurrentDigest   string // Manifest digest the local image was pulled from
+	AvailableDigest string // Manifest digest the registry serves for the target
+	Eligible        bool
+	UpdateAvailable bool
+	Reason          string
+	Err             error // Set when the container couldn't be checked
+}
+
+// Check compares running containers with what their registries serve, without pulling
+// anything or touching a container. names limits it to containers with those names or
+// ID prefixes; empty checks them all.
+func Check(ctx context.Context, cfg config.Config, dockerClient docker.Client, names []string, logger *zerolog.Logger) ([]CheckResult, error) {
+	containers, err := dockerClient.ListContainers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	selected, err := selectContainers(containers, names)
+	if err != nil {
+		return nil, err
+	}
+
+	reg := newRegistry(cfg)
+	pinSet := loadPins(cfg, logger)
+
+	results := make([]CheckResult, len(selected))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 5) // Same concurrency limit as an update cycle
+	for i, c := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = checkContainer(ctx, cfg, dockerClient, reg, pinSet, c)
+		}()
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// selectContainers picks the named containers, in the order given
+func selectContainers(containers []docker.ContainerInfo, names []string) ([]docker.ContainerInfo, error) {
+	if len(names) == 0 {
+		return containers, nil
+	}
+	selected := make([]docker.ContainerInfo, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, c := range containers {
+			if c.Name == name || strings.HasPrefix(c.ID, name) {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w named %s", ErrNoSuchContainer, name)
+		}
+	}
+	return selected, nil
+}
+
+// checkContainer applies the same eligibility rules, pins and tag policy as an update cycle,
+// then compares digests instead of pulling
+func checkContainer(ctx context.Context, cfg config.Config, dockerClient docker.Client, reg Registry, pinSet *pins.Set, c docker.ContainerInfo) CheckResult {
+	result := CheckResult{Name: c.Name, Image: c.Image}
+	fail := func(err error) CheckResult {
+		result.Err = err
+		result.Reason = err.Error()
+		return result
+	}
+
+	decision, ceiling := decide(c, cfg, pinSet)
+	if !decision.Eligible {
+		result.Reason = decision.Reason
+		return result
+	}
+	result.Eligible = true
+
+	local, err := dockerClient.InspectImage(ctx, c.ImageID)
+	if err != nil {
+		return fail(fmt.Errorf("failed to inspect local image: %w", err))
+	}
+	result.CurrentDigest = repoDigest(local, c.Image)
+
+	image := trackedImage(c.Image, cfg.Updates.Track)
+	target, err := resolveTarget(ctx, reg, image, PolicyFor(image, cfg.Updates), ceiling)
+	if err != nil {
+		return fail(fmt.Errorf("failed to list registry tags: %w", err))
+	}
+	remote, err := reg.ManifestDigest(ctx, target)
+	if err != nil {
+		return fail(fmt.Errorf("failed to query registry: %w", err))
+	}
+	result.AvailableDigest = remote
+
+	switch {
+	case target != c.Image:
+		result.Target = target
+		result.UpdateAvailable = true
+		_, tag, _ := splitImageTag(target)
+		result.Reason = "newer tag " + tag
+		if target == image {
+			result.Reason = "tracked tag " + tag
+		}
+	case len(local.RepoDigests) == 0:
+		// Locally built or loaded images were never pulled from the registry
+		return fail(errors.New("local image has no repo digest to compare"))
+	case result.CurrentDigest == remote || hasRepoDigest(local, remote):
+		result.Reason = "up to date"
+	default:
+		result.UpdateAvailable = true
+		result.Reason = "new image for the tag"
+	}
+	return result
+}
+
+// repoDigest returns the digest the local image was pulled as for the image's repository,
+// or the first one it has
+func repoDigest(local docker.ImageInfo, image string) string {
+	repo, _, _ := splitImageTag(image)
+	first := ""
+	for _, repoDigest := range local.RepoDigests {
+		name, digest, ok := strings.Cut(repoDigest, "@")
+		if !ok {
+			continue
+		}
+		if name == repo {
+			return digest
+		}
+		if first == "" {
+			first = digest
+		}
+	}
+	return first
+}
+
+// hasRepoDigest reports whether the local image was pulled from the given manifest digest
+func hasRepoDigest(local docker.ImageInfo, digest string) bool {
+	for _, repoDigest := range local.RepoDigests {
+		if _, d, ok := strings.Cut(repoDigest, "@"); ok && d == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckExitCode summarizes results as CheckFailed, CheckUpdateAvailable or CheckUpToDate.
+// Failures win, so a registry outage can't pass a CI gate.
+func CheckExitCode(results []CheckResult) int {
+	code := CheckUpToDate
+	for _, r := range results {
+		if r.Err != nil {
+			return CheckFailed
+		}
+		if r.UpdateAvailable {
+			code = CheckUpdateAvailable
+		}
+	}
+	return code
+}
+
+// FormatCheck prints check results as a table followed by a summary line
+func FormatCheck(w io.Writer, results []CheckResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No running containers to check.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tIMAGE\tCURRENT\tAVAILABLE\tELIGIBLE\tREASON")
+	updates, failed := 0, 0
+	for _, r := range results {
+		eligible := "no"
+		if r.Eligible {
+			eligible = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, r.Image, shortDigest(r.CurrentDigest), shortDigest(r.AvailableDigest), eligible, r.Reason)
+		if r.Err != nil {
+			failed++
+		} else if r.UpdateAvailable {
+			updates++
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d checked: %d updates available", len(results), updates)
+	if failed > 0 {
+		fmt.Fprintf(w, ", %d could not be checked", failed)
+	}
+	fmt.Fprintln(w)
+}
+
+// shortDigest trims "sha256:" and shortens a digest for display
+func shortDigest(digest string) string {
+	if digest == "" {
+		return "-"
+	}
+	return shortID(strings.TrimPrefix(digest, "sha256:"))
+}
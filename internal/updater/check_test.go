@@ -0,0 +1,99 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/rs/zerolog"
+)
+
+func checkTestClient() *docker.MockDockerClient {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "aaa111", Name: "web", Image: "nginx:latest", ImageID: "sha256:nginx"},
+		{ID: "bbb222", Name: "cache", Image: "redis:7.2.1", ImageID: "sha256:redis"},
+		{ID: "ccc333", Name: "db", Image: "postgres:16", ImageID: "sha256:postgres", Labels: map[string]string{"com.harborbuddy.autoupdate": "false"}},
+		{ID: "ddd444", Name: "app", Image: "acme/app:latest", ImageID: "sha256:app"},
+	}
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:nginx", RepoDigests: []string{"nginx@sha256:aaa"}},
+		{ID: "sha256:redis", RepoDigests: []string{"redis@sha256:r721"}},
+		{ID: "sha256:postgres", RepoDigests: []string{"postgres@sha256:ppp"}},
+		{ID: "sha256:app"}, // Built locally
+	}
+	return mockClient
+}
+
+func TestCheck(t *testing.T) {
+	withRegistry(t, stubRegistry{
+		digests: map[string]string{
+			"nginx:latest": "sha256:bbb",
+			"redis:7.2.4":  "sha256:r724",
+		},
+		tags: map[string][]string{
+			"redis:7.2.1": {"7.2.1", "7.2.4", "7.4.0"},
+		},
+	})
+
+	cfg := config.Default()
+	cfg.Updates.Policies = []config.PolicyRule{{Pattern: "redis:*", Policy: config.PolicyPatch}}
+	logger := zerolog.Nop()
+
+	results, err := Check(context.Background(), cfg, checkTestClient(), nil, &logger)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	byName := make(map[string]CheckResult)
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+	if r := byName["web"]; !r.UpdateAvailable || r.CurrentDigest != "sha256:aaa" || r.AvailableDigest != "sha256:bbb" {
+		t.Errorf("web = %+v, want a new digest for the tag", r)
+	}
+	if r := byName["cache"]; !r.UpdateAvailable || r.Target != "redis:7.2.4" || r.Reason != "newer tag 7.2.4" {
+		t.Errorf("cache = %+v, want newer tag 7.2.4", r)
+	}
+	if r := byName["db"]; r.Eligible || r.UpdateAvailable || r.Reason != "label com.harborbuddy.autoupdate=false" {
+		t.Errorf("db = %+v, want skipped by label", r)
+	}
+	if r := byName["app"]; r.Err == nil {
+		t.Errorf("app = %+v, want an error for an image without repo digest", r)
+	}
+	if got := CheckExitCode(results); got != CheckFailed {
+		t.Errorf("CheckExitCode() = %d, want %d", got, CheckFailed)
+	}
+
+	var out bytes.Buffer
+	FormatCheck(&out, results)
+	for _, want := range []string{"NAME", "web", "nginx:latest", "aaa", "bbb", "newer tag 7.2.4", "4 checked: 2 updates available, 1 could not be checked"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCheck_SelectedContainers(t *testing.T) {
+	withRegistry(t, stubRegistry{digests: map[string]string{"nginx:latest": "sha256:aaa"}})
+	cfg := config.Default()
+	logger := zerolog.Nop()
+
+	results, err := Check(context.Background(), cfg, checkTestClient(), []string{"aaa1"}, &logger)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "web" || results[0].Reason != "up to date" {
+		t.Errorf("results = %+v, want web up to date", results)
+	}
+	if got := CheckExitCode(results); got != CheckUpToDate {
+		t.Errorf("CheckExitCode() = %d, want %d", got, CheckUpToDate)
+	}
+
+	if _, err := Check(context.Background(), cfg, checkTestClient(), []string{"missing"}, &logger); err == nil {
+		t.Error("expected an error for an unknown container")
+	}
+}
@@ -0,0 +1,66 @@
+package updater
+
+import (
+	"context"
+	"sync"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+// checkResult is the outcome of checking an image for an update: the reference the policy
+// picked, the pulled image, and whether it differs from the one running
+type checkResult struct {
+	target      string
+	newImage    docker.ImageInfo
+	needsUpdate bool
+	err         error
+}
+
+type checkCacheEntry struct {
+	result    checkResult
+	checkedBy string // Container whose check is shared
+	ready     chan struct{}
+}
+
+// checkCache shares update checks between the containers of a cycle running the same image,
+// so registry queries, digest comparisons and their logs scale with the distinct images
+// rather than the containers. Like SafePullCache, concurrent checks of one image wait for
+// the first.
+type checkCache struct {
+	mu      sync.Mutex
+	entries map[string]*checkCacheEntry
+}
+
+func newCheckCache() *checkCache {
+	return &checkCache{entries: make(map[string]*checkCacheEntry)}
+}
+
+// checkKey identifies what a check depends on: the image the container runs and the one it
+// follows, the pin ceiling, and the local image to compare against
+func checkKey(c docker.ContainerInfo, image, ceiling string) string {
+	return c.Image + "\x00" + image + "\x00" + ceiling + "\x00" + c.ImageID
+}
+
+// do returns the result for key, running check for the first container asking. hit tells
+// whether the result was shared, and checkedBy names the container that was checked.
+func (c *checkCache) do(ctx context.Context, key, name string, check func() checkResult) (result checkResult, checkedBy string, hit bool) {
+	c.mu.Lock()
+	entry, exists := c.entries[key]
+	if !exists {
+		entry = &checkCacheEntry{checkedBy: name, ready: make(chan struct{})}
+		c.entries[key] = entry
+		c.mu.Unlock()
+
+		entry.result = check()
+		close(entry.ready)
+		return entry.result, name, false
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.result, entry.checkedBy, true
+	case <-ctx.Done():
+		return checkResult{err: ctx.Err()}, entry.checkedBy, false
+	}
+}
@@ -0,0 +1,66 @@
+package updater
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+// countingRegistry counts the digest lookups made through a stubRegistry
+type countingRegistry struct {
+	stubRegistry
+	mu      sync.Mutex
+	lookups map[string]int
+}
+
+func (r *countingRegistry) ManifestDigest(ctx context.Context, imageRef string) (string, error) {
+	r.mu.Lock()
+	r.lookups[imageRef]++
+	r.mu.Unlock()
+	return r.stubRegistry.ManifestDigest(ctx, imageRef)
+}
+
+func TestRunUpdateCycle_SharesChecksPerImage(t *testing.T) {
+	reg := &countingRegistry{
+		stubRegistry: stubRegistry{digests: map[string]string{"nginx:latest": "sha256:aaa"}},
+		lookups:      make(map[string]int),
+	}
+	withRegistry(t, reg)
+
+	mockClient := docker.NewMockDockerClient()
+	for _, name := range []string{"web1", "web2", "web3"} {
+		mockClient.Containers = append(mockClient.Containers, docker.ContainerInfo{
+			ID: name + "-id", Name: name, Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"},
+		})
+	}
+	// Still on an older pull of the same tag, so it is checked on its own
+	mockClient.Containers = append(mockClient.Containers, docker.ContainerInfo{
+		ID: "web4-id", Name: "web4", Image: "nginx:latest", ImageID: "sha256:older-nginx", Config: &container.Config{Image: "nginx:latest"},
+	})
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:old-nginx", RepoDigests: []string{"nginx@sha256:aaa"}},
+		{ID: "sha256:older-nginx", RepoDigests: []string{"nginx@sha256:000"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:old-nginx"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.CheckMethod = config.CheckMethodDigest
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if got := reg.lookups["nginx:latest"]; got != 2 {
+		t.Errorf("digest lookups = %d, want 2 (one per local image)", got)
+	}
+	if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].OldID != "web4-id" {
+		t.Errorf("replaced %v, want only web4", mockClient.ReplacedContainers)
+	}
+}
@@ -0,0 +1,35 @@
+package updater
+
+import (
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+)
+
+// Labels Docker Compose puts on the containers it creates
+const (
+	composeProjectLabel   = "com.docker.compose.project"
+	composeServiceLabel   = "com.docker.compose.service"
+	composeDependsOnLabel = "com.docker.compose.depends_on"
+)
+
+// composeService returns the container's compose project and service, or "" if it isn't compose-managed
+func composeService(c docker.ContainerInfo) (project, service string) {
+	project, service = c.Labels[composeProjectLabel], c.Labels[composeServiceLabel]
+	if project == "" || service == "" {
+		return "", ""
+	}
+	return project, service
+}
+
+// composeDependencies returns the services a compose container depends on. Compose records
+// depends_on as "service:condition:restart" entries, e.g. "db:service_healthy:false,cache:service_started:false".
+func composeDependencies(c docker.ContainerInfo) []string {
+	var services []string
+	for _, entry := range strings.Split(c.Labels[composeDependsOnLabel], ",") {
+		if service, _, _ := strings.Cut(strings.TrimSpace(entry), ":"); service != "" {
+			services = append(services, service)
+		}
+	}
+	return services
+}
@@ -0,0 +1,108 @@
+package updater
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func composeContainer(id, service, dependsOn, image string) docker.ContainerInfo {
+	labels := map[string]string{
+		composeProjectLabel: "shop",
+		composeServiceLabel: service,
+	}
+	if dependsOn != "" {
+		labels[composeDependsOnLabel] = dependsOn
+	}
+	return docker.ContainerInfo{
+		ID:      id,
+		Name:    "shop-" + service + "-1",
+		Image:   image,
+		ImageID: "sha256:old-" + service,
+		Labels:  labels,
+		Config:  &container.Config{Image: image},
+	}
+}
+
+func TestComposeDependencies(t *testing.T) {
+	c := composeContainer("app1", "app", "db:service_healthy:false, cache:service_started:true", "app:latest")
+	if got := composeDependencies(c); !reflect.DeepEqual(got, []string{"db", "cache"}) {
+		t.Errorf("composeDependencies() = %v, want [db cache]", got)
+	}
+
+	if got := composeDependencies(docker.ContainerInfo{}); got != nil {
+		t.Errorf("composeDependencies() without label = %v, want nil", got)
+	}
+}
+
+func TestComposeDependentsAndDepth(t *testing.T) {
+	db := composeContainer("db1", "db", "", "postgres:16")
+	app := composeContainer("app1", "app", "db:service_healthy:false", "app:latest")
+	worker := composeContainer("worker1", "worker", "app:service_started:false", "worker:latest")
+	other := docker.ContainerInfo{ID: "other1", Name: "other", Labels: map[string]string{
+		composeProjectLabel: "blog", composeServiceLabel: "web", composeDependsOnLabel: "db:service_started:false",
+	}}
+	containers := []docker.ContainerInfo{worker, other, app, db}
+
+	graph := buildDependencyGraph(containers, nil)
+
+	var names []string
+	for _, c := range graph.dependents(containers, db) {
+		names = append(names, c.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"shop-app-1", "shop-worker-1"}) {
+		t.Errorf("dependents(db) = %v, want nearest first within the project", names)
+	}
+
+	depths := []struct {
+		c    docker.ContainerInfo
+		want int
+	}{{db, 0}, {app, 1}, {worker, 2}, {other, 0}}
+	for _, tt := range depths {
+		if got := graph.depth(tt.c); got != tt.want {
+			t.Errorf("depth(%s) = %d, want %d", tt.c.Name, got, tt.want)
+		}
+	}
+}
+
+func TestRunUpdateCycle_ComposeOrdering(t *testing.T) {
+	t.Log("Testing that compose dependencies update first with their consumers stopped")
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		composeContainer("app1", "app", "db:service_healthy:false", "app:latest"),
+		composeContainer("worker1", "worker", "app:service_started:false", "worker:latest"),
+		composeContainer("db1", "db", "", "postgres:16"),
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"app:latest":    {ID: "sha256:new-app"},
+		"worker:latest": {ID: "sha256:old-worker"},
+		"postgres:16":   {ID: "sha256:new-db"},
+	}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	var created []string
+	for _, req := range mockClient.CreatedContainers {
+		created = append(created, req.OldContainer.Name)
+	}
+	if !reflect.DeepEqual(created, []string{"shop-db-1", "shop-app-1"}) {
+		t.Errorf("update order = %v, want db before app", created)
+	}
+
+	// db's consumers are stopped deepest first, then app's own consumer around its update
+	if want := []string{"worker1", "app1", "worker1"}; !reflect.DeepEqual(mockClient.StoppedContainers, want) {
+		t.Errorf("stopped = %v, want %v", mockClient.StoppedContainers, want)
+	}
+	if want := []string{"app1", "worker1", "worker1"}; !reflect.DeepEqual(mockClient.StartedContainers, want) {
+		t.Errorf("started = %v, want %v", mockClient.StartedContainers, want)
+	}
+}
@@ -1,10 +1,13 @@
 package updater
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/MikeO7/HarborBuddy/internal/config"
 	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
 )
 
 // UpdateDecision represents whether and why a container should be updated
@@ -16,6 +19,14 @@ type UpdateDecision struct {
 
 // DetermineEligibility checks if a container is eligible for updates
 func DetermineEligibility(container docker.ContainerInfo, cfg config.UpdatesConfig) UpdateDecision {
+	// Containers of another HarborBuddy instance's scope are none of our business
+	if !cfg.InScope(container.Labels) {
+		return UpdateDecision{
+			Eligible: false,
+			Reason:   outOfScopeReason(container.Labels),
+		}
+	}
+
 	// Check the autoupdate label
 	if label, exists := container.Labels["com.harborbuddy.autoupdate"]; exists {
 		if label == "false" {
@@ -26,6 +37,40 @@ func DetermineEligibility(container docker.ContainerInfo, cfg config.UpdatesConf
 		}
 	}
 
+	// With label_enable, containers have to opt in
+	if cfg.LabelEnable && container.Labels["com.harborbuddy.autoupdate"] != "true" {
+		return UpdateDecision{
+			Eligible: false,
+			Reason:   "label_enable is on and label com.harborbuddy.autoupdate=true is not set",
+		}
+	}
+
+	// Rolled-back containers run a bare image ID that can't be pulled
+	if pinnedToImageID(container.Image) {
+		return UpdateDecision{
+			Eligible: false,
+			Reason:   "pinned to an image ID (rolled back)",
+		}
+	}
+
+	// Containers started from the tag updates.pin_digests keeps run a local-only tag
+	if runsCurrentTag(container.Image) {
+		return UpdateDecision{
+			Eligible: false,
+			Reason:   "runs the local " + CurrentTag + " tag",
+		}
+	}
+
+	// Check container name deny patterns
+	for _, pattern := range cfg.DenyContainers {
+		if util.MatchPattern(container.Name, pattern) {
+			return UpdateDecision{
+				Eligible: false,
+				Reason:   "container name matches deny pattern: " + pattern,
+			}
+		}
+	}
+
 	// Check deny patterns
 	for _, pattern := range cfg.DenyImages {
 		if matchesPattern(container.Image, pattern) {
@@ -36,20 +81,28 @@ func DetermineEligibility(container docker.ContainerInfo, cfg config.UpdatesConf
 		}
 	}
 
+	// Check container name allow patterns (if not empty)
+	if reason, ok := checkAllowList(container.Name, cfg.AllowContainers); !ok {
+		return UpdateDecision{
+			Eligible: false,
+			Reason:   "container name " + reason,
+		}
+	}
+
 	// Check allow patterns (if not empty)
-	if len(cfg.AllowImages) > 0 {
-		allowed := false
-		for _, pattern := range cfg.AllowImages {
-			if matchesPattern(container.Image, pattern) {
-				allowed = true
-				break
-			}
+	if reason, ok := checkAllowList(container.Image, cfg.AllowImages); !ok {
+		return UpdateDecision{
+			Eligible: false,
+			Reason:   reason,
 		}
-		if !allowed {
-			return UpdateDecision{
-				Eligible: false,
-				Reason:   "does not match any allow pattern",
-			}
+	}
+
+	// Containers started from image@sha256:... run exactly that manifest; updates.suggest_digests
+	// can still report a newer one
+	if pinnedByDigest(container.Image) {
+		return UpdateDecision{
+			Eligible: false,
+			Reason:   reasonPinnedByDigest,
 		}
 	}
 
@@ -59,41 +112,118 @@ func DetermineEligibility(container docker.ContainerInfo, cfg config.UpdatesConf
 	}
 }
 
-// matchesPattern checks if an image matches a pattern
-// Supports:
-// - "*" matches everything
-// - "repo:tag" exact match
-// - "repo:*" matches any tag for repo
-// - "registry.io/org/*" matches any repo under registry.io/org/
-func matchesPattern(image, pattern string) bool {
-	// Universal wildcard
-	if pattern == "*" {
-		return true
-	}
-
-	// Exact match
-	if image == pattern {
-		return true
-	}
-
-	// Pattern with wildcards
-	// Check for wildcards directly to avoid full string search if possible
-	// Optimization: Avoid strings.Contains, strings.HasSuffix, and strings.TrimSuffix
-	// for common wildcard patterns to reduce allocations and CPU cycles.
-	pLen := len(pattern)
-	if pLen > 0 {
-		if pattern[pLen-1] == '*' {
-			// e.g., "postgres:*" or "registry.io/org/*"
-			// Check if image starts with pattern[:pLen-1]
-			// This avoids allocating a new string for the prefix
-			return strings.HasPrefix(image, pattern[:pLen-1])
+// outOfScopeReason explains why a container belongs to another instance
+func outOfScopeReason(labels map[string]string) string {
+	if scope := labels[config.ScopeLabel]; scope != "" {
+		return "label " + config.ScopeLabel + "=" + scope + " belongs to another scope"
+	}
+	return "label " + config.ScopeLabel + " not set"
+}
+
+// checkAllowList applies an allow list (see util.MatchAllowList) and says why a value it
+// doesn't let through was left out
+func checkAllowList(value string, patterns []string) (reason string, ok bool) {
+	if util.MatchAllowList(value, patterns) {
+		return "", true
+	}
+	for _, pattern := range patterns {
+		if negated, isNegated := strings.CutPrefix(pattern, util.NegationPrefix); isNegated && matchesPattern(value, negated) {
+			return "excluded by allow pattern: " + pattern, false
 		}
-		if pattern[0] == '*' {
-			// e.g., "*:latest"
-			// Check if image ends with pattern[1:]
-			return strings.HasSuffix(image, pattern[1:])
+	}
+	return "does not match any allow pattern", false
+}
+
+// matchesPattern checks if an image matches an allow/deny pattern (see util.MatchPattern)
+func matchesPattern(image, pattern string) bool {
+	return util.MatchPattern(image, pattern)
+}
+
+// PatternMatch describes how a single allow/deny pattern applies to an image
+type PatternMatch struct {
+	List    string // "deny" or "allow"
+	Pattern string
+	Rule    string // how the pattern is interpreted, e.g. "exact match"
+	Matched bool
+}
+
+// PatternExplanation describes how the allow/deny patterns apply to an image
+type PatternExplanation struct {
+	Image    string
+	Matches  []PatternMatch
+	Decision UpdateDecision
+}
+
+// ExplainPatterns evaluates every allow/deny pattern against an image, in the order
+// DetermineEligibility applies them. Container labels and name patterns are not considered.
+func ExplainPatterns(image string, cfg config.UpdatesConfig) PatternExplanation {
+	explanation := PatternExplanation{
+		Image:    image,
+		Decision: DetermineEligibility(docker.ContainerInfo{Image: image}, cfg),
+	}
+
+	for _, pattern := range cfg.DenyImages {
+		explanation.Matches = append(explanation.Matches, PatternMatch{
+			List:    "deny",
+			Pattern: pattern,
+			Rule:    patternRule(pattern),
+			Matched: matchesPattern(image, pattern),
+		})
+	}
+	for _, pattern := range cfg.AllowImages {
+		explanation.Matches = append(explanation.Matches, PatternMatch{
+			List:    "allow",
+			Pattern: pattern,
+			Rule:    patternRule(pattern),
+			Matched: matchesPattern(image, strings.TrimPrefix(pattern, util.NegationPrefix)),
+		})
+	}
+
+	return explanation
+}
+
+// String renders the explanation for terminal output
+func (e PatternExplanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Image: %s\n", e.Image)
+
+	if len(e.Matches) == 0 {
+		b.WriteString("No allow/deny patterns configured\n")
+	}
+	for _, m := range e.Matches {
+		mark := "✗"
+		if m.Matched {
+			mark = "✓"
 		}
+		fmt.Fprintf(&b, "  %s %-5s %-30q %s\n", mark, m.List, m.Pattern, m.Rule)
 	}
 
-	return false
+	verdict := "not eligible"
+	if e.Decision.Eligible {
+		verdict = "eligible"
+	}
+	fmt.Fprintf(&b, "Result: %s (%s)\n", verdict, e.Decision.Reason)
+	return b.String()
+}
+
+// patternRule describes how matchesPattern interprets a pattern
+func patternRule(pattern string) string {
+	if negated, ok := strings.CutPrefix(pattern, util.NegationPrefix); ok {
+		return "excludes what " + patternRule(negated) + " matches"
+	}
+	switch {
+	case strings.HasPrefix(pattern, util.RegexPrefix):
+		return "regular expression " + strconv.Quote(strings.TrimPrefix(pattern, util.RegexPrefix)) + " on the whole value"
+	case strings.ContainsAny(pattern, "?[") || strings.Count(pattern, "*") > 1 ||
+		(strings.Contains(pattern, "*") && !strings.HasPrefix(pattern, "*") && !strings.HasSuffix(pattern, "*")):
+		return "glob"
+	case pattern == "*":
+		return "matches everything"
+	case strings.HasSuffix(pattern, "*"):
+		return "prefix match on " + strconv.Quote(pattern[:len(pattern)-1])
+	case strings.HasPrefix(pattern, "*"):
+		return "suffix match on " + strconv.Quote(pattern[1:])
+	default:
+		return "exact match"
+	}
 }
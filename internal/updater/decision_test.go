@@ -1,6 +1,7 @@
 package updater
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/MikeO7/HarborBuddy/internal/config"
@@ -35,6 +36,27 @@ func TestMatchesPattern(t *testing.T) {
 
 		// No Wildcard
 		{"no wildcard partial fail", "nginx:latest", "nginx", false},
+
+		// Globs
+		{"glob mid wildcard", "ghcr.io/acme/api:latest", "ghcr.io/*:latest", true},
+		{"glob mid wildcard crosses slashes", "ghcr.io/acme/team/api:latest", "ghcr.io/*/api:*", true},
+		{"glob mid wildcard fail", "ghcr.io/acme/api:1.0", "ghcr.io/*:latest", false},
+		{"glob contains", "bitnami/postgresql:16", "*postgres*", true},
+		{"glob question mark", "redis:7", "redis:?", true},
+		{"glob question mark one character", "redis:7.2", "redis:?", false},
+		{"glob class", "redis:6.2", "redis:[67].*", true},
+		{"glob class fail", "redis:5.0", "redis:[67].*", false},
+		{"glob negated class", "redis:5.0", "redis:[!67].*", true},
+		{"glob range", "app:v3", "app:v[0-9]", true},
+		{"glob dots are literal", "appXv3", "app.v[0-9]", false},
+		{"glob unclosed class", "redis:[6", "redis:[6", true},
+
+		// Regular expressions
+		{"regex", "ghcr.io/acme/api:v1.4.2", `re:ghcr\.io/acme/.+:v\d+\.\d+\.\d+`, true},
+		{"regex whole value", "ghcr.io/acme/api:v1.4.2-rc1", `re:ghcr\.io/acme/.+:v\d+\.\d+\.\d+`, false},
+		{"regex alternation", "mariadb:11", "re:(mysql|mariadb):.*", true},
+		{"regex alternation anchored", "my-mysql:8", "re:mysql|mariadb:.*", false},
+		{"invalid regex", "postgres:16", "re:postgres:(16", false},
 	}
 
 	for _, tt := range tests {
@@ -128,6 +150,121 @@ func TestDetermineEligibility(t *testing.T) {
 			expectEligible: false,
 			expectReason:   "matches deny pattern: nginx:*",
 		},
+		{
+			name: "scoped instance skips unscoped container",
+			container: docker.ContainerInfo{
+				Image: "nginx:latest",
+			},
+			config: config.UpdatesConfig{
+				AllowImages: []string{"*"},
+				Scope:       "teamA",
+			},
+			expectEligible: false,
+			expectReason:   "label com.harborbuddy.scope not set",
+		},
+		{
+			name: "scoped instance manages its scope",
+			container: docker.ContainerInfo{
+				Image:  "nginx:latest",
+				Labels: map[string]string{"com.harborbuddy.scope": "teamA"},
+			},
+			config: config.UpdatesConfig{
+				AllowImages: []string{"*"},
+				Scope:       "teamA",
+			},
+			expectEligible: true,
+			expectReason:   "eligible for updates",
+		},
+		{
+			name: "unscoped instance skips scoped container",
+			container: docker.ContainerInfo{
+				Image:  "nginx:latest",
+				Labels: map[string]string{"com.harborbuddy.scope": "teamB"},
+			},
+			config: config.UpdatesConfig{
+				AllowImages: []string{"*"},
+			},
+			expectEligible: false,
+			expectReason:   "label com.harborbuddy.scope=teamB belongs to another scope",
+		},
+		{
+			name: "label enable without opt-in",
+			container: docker.ContainerInfo{
+				Image: "nginx:latest",
+			},
+			config: config.UpdatesConfig{
+				AllowImages: []string{"*"},
+				LabelEnable: true,
+			},
+			expectEligible: false,
+			expectReason:   "label_enable is on and label com.harborbuddy.autoupdate=true is not set",
+		},
+		{
+			name: "label enable with opt-in",
+			container: docker.ContainerInfo{
+				Image:  "nginx:latest",
+				Labels: map[string]string{"com.harborbuddy.autoupdate": "true"},
+			},
+			config: config.UpdatesConfig{
+				AllowImages: []string{"*"},
+				LabelEnable: true,
+			},
+			expectEligible: true,
+			expectReason:   "eligible for updates",
+		},
+		{
+			name: "label enable still applies deny patterns",
+			container: docker.ContainerInfo{
+				Image:  "postgres:16",
+				Labels: map[string]string{"com.harborbuddy.autoupdate": "true"},
+			},
+			config: config.UpdatesConfig{
+				AllowImages: []string{"*"},
+				DenyImages:  []string{"postgres:*"},
+				LabelEnable: true,
+			},
+			expectEligible: false,
+			expectReason:   "matches deny pattern: postgres:*",
+		},
+		{
+			name: "container name deny",
+			container: docker.ContainerInfo{
+				Name:  "nginx-staging",
+				Image: "nginx:latest",
+			},
+			config: config.UpdatesConfig{
+				AllowImages:    []string{"*"},
+				DenyContainers: []string{"*-staging"},
+			},
+			expectEligible: false,
+			expectReason:   "container name matches deny pattern: *-staging",
+		},
+		{
+			name: "container name not allowed",
+			container: docker.ContainerInfo{
+				Name:  "nginx-prod",
+				Image: "nginx:latest",
+			},
+			config: config.UpdatesConfig{
+				AllowImages:     []string{"*"},
+				AllowContainers: []string{"nginx-dev*"},
+			},
+			expectEligible: false,
+			expectReason:   "container name does not match any allow pattern",
+		},
+		{
+			name: "container name allowed",
+			container: docker.ContainerInfo{
+				Name:  "nginx-dev2",
+				Image: "nginx:latest",
+			},
+			config: config.UpdatesConfig{
+				AllowImages:     []string{"*"},
+				AllowContainers: []string{"nginx-dev*"},
+			},
+			expectEligible: true,
+			expectReason:   "eligible for updates",
+		},
 	}
 
 	for _, tt := range tests {
@@ -144,3 +281,164 @@ func TestDetermineEligibility(t *testing.T) {
 		})
 	}
 }
+
+func TestDetermineEligibility_AllowListNegation(t *testing.T) {
+	tests := []struct {
+		name         string
+		container    docker.ContainerInfo
+		config       config.UpdatesConfig
+		wantEligible bool
+		wantReason   string
+	}{
+		{
+			name:         "negation alone allows the rest",
+			container:    docker.ContainerInfo{Name: "web", Image: "nginx:1.27"},
+			config:       config.UpdatesConfig{AllowImages: []string{"!*:latest"}},
+			wantEligible: true,
+			wantReason:   "eligible for updates",
+		},
+		{
+			name:         "negation alone excludes",
+			container:    docker.ContainerInfo{Name: "web", Image: "nginx:latest"},
+			config:       config.UpdatesConfig{AllowImages: []string{"!*:latest"}},
+			wantEligible: false,
+			wantReason:   "excluded by allow pattern: !*:latest",
+		},
+		{
+			name:         "negation wins over a match",
+			container:    docker.ContainerInfo{Name: "api", Image: "ghcr.io/acme/api:beta"},
+			config:       config.UpdatesConfig{AllowImages: []string{"ghcr.io/acme/*", "!*:beta"}},
+			wantEligible: false,
+			wantReason:   "excluded by allow pattern: !*:beta",
+		},
+		{
+			name:         "negation order doesn't matter",
+			container:    docker.ContainerInfo{Name: "api", Image: "ghcr.io/acme/api:beta"},
+			config:       config.UpdatesConfig{AllowImages: []string{"!*:beta", "ghcr.io/acme/*"}},
+			wantEligible: false,
+			wantReason:   "excluded by allow pattern: !*:beta",
+		},
+		{
+			name:         "positive patterns still required",
+			container:    docker.ContainerInfo{Name: "cache", Image: "redis:7"},
+			config:       config.UpdatesConfig{AllowImages: []string{"ghcr.io/acme/*", "!*:beta"}},
+			wantEligible: false,
+			wantReason:   "does not match any allow pattern",
+		},
+		{
+			name:         "negated regex",
+			container:    docker.ContainerInfo{Name: "api", Image: "ghcr.io/acme/api:1.5.0-rc.1"},
+			config:       config.UpdatesConfig{AllowImages: []string{"*", `!re:.*-(rc|beta)\.\d+`}},
+			wantEligible: false,
+			wantReason:   `excluded by allow pattern: !re:.*-(rc|beta)\.\d+`,
+		},
+		{
+			name:         "negated container name",
+			container:    docker.ContainerInfo{Name: "web-canary", Image: "nginx:1.27"},
+			config:       config.UpdatesConfig{AllowImages: []string{"*"}, AllowContainers: []string{"web-*", "!*-canary"}},
+			wantEligible: false,
+			wantReason:   "container name excluded by allow pattern: !*-canary",
+		},
+		{
+			name:         "glob container name",
+			container:    docker.ContainerInfo{Name: "web-2", Image: "nginx:1.27"},
+			config:       config.UpdatesConfig{AllowImages: []string{"*"}, AllowContainers: []string{"web-[0-9]"}},
+			wantEligible: true,
+			wantReason:   "eligible for updates",
+		},
+		{
+			name:         "regex deny",
+			container:    docker.ContainerInfo{Name: "db", Image: "postgres:16.4"},
+			config:       config.UpdatesConfig{AllowImages: []string{"*"}, DenyImages: []string{`re:(postgres|mysql):\d+(\.\d+)*`}},
+			wantEligible: false,
+			wantReason:   `matches deny pattern: re:(postgres|mysql):\d+(\.\d+)*`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := DetermineEligibility(tt.container, tt.config)
+			if decision.Eligible != tt.wantEligible || decision.Reason != tt.wantReason {
+				t.Errorf("DetermineEligibility() = %v (%s), want %v (%s)", decision.Eligible, decision.Reason, tt.wantEligible, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPatternRule(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"*", "matches everything"},
+		{"nginx:*", `prefix match on "nginx:"`},
+		{"*:latest", `suffix match on ":latest"`},
+		{"nginx:1.27", "exact match"},
+		{"ghcr.io/*/api:*", "glob"},
+		{"redis:[67]", "glob"},
+		{"re:mysql:.*", `regular expression "mysql:.*" on the whole value`},
+		{"!*:latest", `excludes what suffix match on ":latest" matches`},
+	}
+	for _, tt := range tests {
+		if got := patternRule(tt.pattern); got != tt.want {
+			t.Errorf("patternRule(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestExplainPatterns(t *testing.T) {
+	cfg := config.UpdatesConfig{
+		AllowImages: []string{"ghcr.io/org/*", "*"},
+		DenyImages:  []string{"postgres:*", "*:beta"},
+	}
+
+	tests := []struct {
+		name         string
+		image        string
+		wantEligible bool
+		wantMatched  []string
+	}{
+		{"denied by prefix", "postgres:16", false, []string{"postgres:*", "*"}},
+		{"denied by suffix", "app:beta", false, []string{"*:beta", "*"}},
+		{"allowed", "ghcr.io/org/app:1", true, []string{"ghcr.io/org/*", "*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explanation := ExplainPatterns(tt.image, cfg)
+
+			if explanation.Decision.Eligible != tt.wantEligible {
+				t.Errorf("Eligible = %v, want %v", explanation.Decision.Eligible, tt.wantEligible)
+			}
+			if len(explanation.Matches) != 4 {
+				t.Fatalf("expected all 4 patterns to be evaluated, got %d", len(explanation.Matches))
+			}
+
+			var matched []string
+			for _, m := range explanation.Matches {
+				if m.Matched {
+					matched = append(matched, m.Pattern)
+				}
+			}
+			if strings.Join(matched, ",") != strings.Join(tt.wantMatched, ",") {
+				t.Errorf("matched patterns = %v, want %v", matched, tt.wantMatched)
+			}
+		})
+	}
+}
+
+func TestPatternExplanation_String(t *testing.T) {
+	cfg := config.UpdatesConfig{DenyImages: []string{"postgres:*"}}
+	out := ExplainPatterns("postgres:16", cfg).String()
+
+	for _, want := range []string{
+		"Image: postgres:16",
+		`✓ deny  "postgres:*"`,
+		`prefix match on "postgres:"`,
+		"Result: not eligible (matches deny pattern: postgres:*)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/history"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+// refersTo reports whether a "container:<ref>" network mode reference points at the given container.
+// Docker accepts the full ID, any unique ID prefix, or the container name.
+func refersTo(ref string, target docker.ContainerInfo) bool {
+	if ref == "" {
+		return false
+	}
+	return ref == target.Name || strings.HasPrefix(target.ID, ref)
+}
+
+// volumesFromRefs maps a container ID to the containers it mounts volumes from
+// (HostConfig.VolumesFrom, "<id|name>[:ro|:rw]"). Listing containers doesn't return them,
+// so they are inspected once per cycle, and only when there are updates to apply.
+type volumesFromRefs map[string][]string
+
+// inspectVolumesFrom collects the --volumes-from references of the given containers.
+// A container mounting another's volumes has the same mounts, so only containers sharing a
+// mount with another one are inspected. Containers that can't be inspected are left out.
+func inspectVolumesFrom(ctx context.Context, dockerClient docker.Client, containers []docker.ContainerInfo, logger *zerolog.Logger) volumesFromRefs {
+	refs := make(volumesFromRefs)
+	for _, c := range sharingMounts(containers) {
+		full, err := dockerClient.InspectContainer(ctx, c.ID)
+		if err != nil {
+			logger.Debug().Err(err).Str("container_name", c.Name).Msg("Failed to inspect container for --volumes-from")
+			continue
+		}
+		if full.HostConfig != nil && len(full.HostConfig.VolumesFrom) > 0 {
+			refs[c.ID] = full.HostConfig.VolumesFrom
+		}
+	}
+	return refs
+}
+
+// sharingMounts returns the containers with a volume or bind mount another container has too
+func sharingMounts(containers []docker.ContainerInfo) []docker.ContainerInfo {
+	users := make(map[string]int)
+	for _, c := range containers {
+		for _, source := range c.Mounts {
+			users[source]++
+		}
+	}
+
+	var sharing []docker.ContainerInfo
+	for _, c := range containers {
+		for _, source := range c.Mounts {
+			if users[source] > 1 {
+				sharing = append(sharing, c)
+				break
+			}
+		}
+	}
+	return sharing
+}
+
+// mounts reports whether c mounts volumes from target
+func (v volumesFromRefs) mounts(c, target docker.ContainerInfo) bool {
+	for _, entry := range v[c.ID] {
+		if ref, _, _ := strings.Cut(entry, ":"); refersTo(ref, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// linked reports whether c joins another container's network namespace or mounts its volumes
+func (v volumesFromRefs) linked(c docker.ContainerInfo) bool {
+	return c.NetworkContainer() != "" || len(v[c.ID]) > 0
+}
+
+// linkedDependents returns the containers that share the target's network namespace or
+// mount its volumes with --volumes-from. Both hold a reference to the target container
+// that goes stale when it is replaced.
+func linkedDependents(containers []docker.ContainerInfo, volumesFrom volumesFromRefs, target docker.ContainerInfo) []docker.ContainerInfo {
+	var dependents []docker.ContainerInfo
+	for _, c := range containers {
+		if c.ID != target.ID && (refersTo(c.NetworkContainer(), target) || volumesFrom.mounts(c, target)) {
+			dependents = append(dependents, c)
+		}
+	}
+	return dependents
+}
+
+// recreateLinkedDependents recreates every container sharing the parent's network namespace
+// or volumes, following chains of dependents. Recreated containers are recorded by their old
+// ID, and in result as restarted; those that fail to be recreated are recorded there as
+// failures. A dependent with an update of its own this cycle is recreated on its target from
+// targets, keyed by container ID; the others keep their image reference.
+func recreateLinkedDependents(ctx context.Context, cfg config.Config, dockerClient docker.Client, store *state.Store, containers []docker.ContainerInfo, volumesFrom volumesFromRefs, parent docker.ContainerInfo, parentNewID string, targets map[string]string, recreated map[string]bool, result *applyResult, logger *zerolog.Logger) {
+	for _, dependent := range linkedDependents(containers, volumesFrom, parent) {
+		if recreated[dependent.ID] {
+			continue
+		}
+
+		depLogger := logger.With().
+			Str("container_id", shortID(dependent.ID)).
+			Str("container_name", dependent.Name).
+			Logger()
+
+		newID, err := recreateLinkedDependent(ctx, cfg, dockerClient, store, dependent, parent, parentNewID, targets[dependent.ID], &depLogger)
+		if err != nil {
+			category := classifyError(ctx, err)
+			depLogger.Error().Err(err).Str("error_category", string(category)).Msg("Failed to recreate linked dependent")
+			result.errors.add(category)
+			result.failures = append(result.failures, history.Failure{Container: dependent.Name, Category: string(category), Error: err.Error()})
+			report.FromContext(ctx).AddFailure(report.Failure{Name: dependent.Name, Image: dependent.Image, Category: string(category), Error: err.Error()})
+			continue
+		}
+		recreated[dependent.ID] = true
+		result.restarted = append(result.restarted, dependent.Name)
+
+		recreateLinkedDependents(ctx, cfg, dockerClient, store, containers, volumesFrom, dependent, newID, targets, recreated, result, logger)
+	}
+}
+
+// recreateLinkedDependent recreates a container that shares the network namespace or
+// volumes of a container that was just replaced. Without this, the dependent keeps running
+// inside the old (now removed) namespace and silently loses networking, or keeps the old
+// container's volumes after the new one got fresh ones. It is recreated from image, or from
+// its own image reference when image is empty.
+func recreateLinkedDependent(ctx context.Context, cfg config.Config, dockerClient docker.Client, store *state.Store, dependent, parent docker.ContainerInfo, parentNewID, image string, logger *zerolog.Logger) (string, error) {
+	fullContainer, err := dockerClient.InspectContainer(ctx, dependent.ID)
+	if err != nil {
+		return "", withCategory(categoryInspect, fmt.Errorf("failed to inspect linked dependent: %w", err))
+	}
+
+	if fullContainer.HostConfig != nil {
+		// Copy before modifying; the inspect result may be shared with the inspect cache
+		hostConfig := *fullContainer.HostConfig
+
+		// References by ID changed with the replacement; references by name still resolve
+		if ref := fullContainer.NetworkContainer(); refersTo(ref, parent) && ref != parent.Name {
+			hostConfig.NetworkMode = container.NetworkMode("container:" + parentNewID)
+		}
+		if len(hostConfig.VolumesFrom) > 0 {
+			hostConfig.VolumesFrom = make([]string, len(fullContainer.HostConfig.VolumesFrom))
+			for i, entry := range fullContainer.HostConfig.VolumesFrom {
+				ref, mode, hasMode := strings.Cut(entry, ":")
+				if refersTo(ref, parent) && ref != parent.Name {
+					entry = parentNewID
+					if hasMode {
+						entry += ":" + mode
+					}
+				}
+				hostConfig.VolumesFrom[i] = entry
+			}
+		}
+		fullContainer.HostConfig = &hostConfig
+	}
+
+	track := beginReplacement(store, fullContainer, dependent.Name, logger)
+	defer track.finish(ctx)
+
+	if image == "" {
+		image = fullContainer.Image
+	}
+	newID, err := swapContainer(ctx, cfg, dockerClient, fullContainer, image, dependent.Name, track, logger)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info().
+		Str("container_name", dependent.Name).
+		Str("linked_to", parent.Name).
+		Str("old_id", shortID(dependent.ID)).
+		Str("new_id", shortID(newID)).
+		Msg("🔗 Recreated container linked to the replaced one")
+	return newID, nil
+}
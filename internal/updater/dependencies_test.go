@@ -0,0 +1,288 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/history"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestLinkedDependents(t *testing.T) {
+	vpn := docker.ContainerInfo{ID: "abcdef1234567890", Name: "vpn"}
+	containers := []docker.ContainerInfo{
+		vpn,
+		{ID: "c1", Name: "by-id", NetworkMode: "container:abcdef1234567890"},
+		{ID: "c2", Name: "by-short-id", NetworkMode: "container:abcdef123456"},
+		{ID: "c3", Name: "by-name", NetworkMode: "container:vpn"},
+		{ID: "c4", Name: "bridge", NetworkMode: "bridge"},
+		{ID: "c5", Name: "other", NetworkMode: "container:something-else"},
+		{ID: "c6", Name: "backup"},
+		{ID: "c7", Name: "other-backup"},
+	}
+	volumesFrom := volumesFromRefs{
+		"c6": {"vpn:ro"},
+		"c7": {"something-else"},
+	}
+
+	dependents := linkedDependents(containers, volumesFrom, vpn)
+
+	var names []string
+	for _, d := range dependents {
+		names = append(names, d.Name)
+	}
+	want := []string{"by-id", "by-short-id", "by-name", "backup"}
+	if len(names) != len(want) {
+		t.Fatalf("linkedDependents() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("linkedDependents()[%d] = %s, want %s", i, names[i], want[i])
+		}
+	}
+}
+
+// newNetworkDependencyClient returns a mock with a VPN container that has an update
+// and an app container sharing its network namespace
+func newNetworkDependencyClient(appNetworkMode string, appHasUpdate bool) *docker.MockDockerClient {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{
+			ID:          "app-id",
+			Name:        "app",
+			Image:       "app:latest",
+			ImageID:     "sha256:app-old",
+			NetworkMode: appNetworkMode,
+			Config:      &container.Config{Image: "app:latest"},
+			HostConfig:  &container.HostConfig{NetworkMode: container.NetworkMode(appNetworkMode)},
+		},
+		{
+			ID:         "vpn-id",
+			Name:       "vpn",
+			Image:      "vpn:latest",
+			ImageID:    "sha256:vpn-old",
+			Config:     &container.Config{Image: "vpn:latest"},
+			HostConfig: &container.HostConfig{NetworkMode: "bridge"},
+		},
+	}
+
+	appImage := "sha256:app-old"
+	if appHasUpdate {
+		appImage = "sha256:app-new"
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"vpn:latest": {ID: "sha256:vpn-new"},
+		"app:latest": {ID: appImage},
+	}
+	return mockClient
+}
+
+func TestRunUpdateCycle_RecreatesNetworkDependents(t *testing.T) {
+	tests := []struct {
+		name         string
+		networkMode  string
+		appHasUpdate bool
+		wantMode     container.NetworkMode
+	}{
+		{"referenced by ID", "container:vpn-id", false, "container:new-container-id-vpn"},
+		{"referenced by name", "container:vpn", false, "container:vpn"},
+		{"dependent also has an update", "container:vpn-id", true, "container:new-container-id-vpn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := newNetworkDependencyClient(tt.networkMode, tt.appHasUpdate)
+
+			testLogger := zerolog.New(zerolog.NewConsoleWriter())
+			if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+				t.Fatalf("RunUpdateCycle() error = %v", err)
+			}
+
+			// The VPN must be replaced first, and the app exactly once afterwards
+			if len(mockClient.CreatedContainers) != 2 {
+				t.Fatalf("expected 2 created containers, got %d", len(mockClient.CreatedContainers))
+			}
+			if got := mockClient.CreatedContainers[0].OldContainer.Name; got != "vpn" {
+				t.Errorf("first recreated container = %s, want vpn", got)
+			}
+
+			app := mockClient.CreatedContainers[1].OldContainer
+			if app.Name != "app" {
+				t.Fatalf("second recreated container = %s, want app", app.Name)
+			}
+			if app.HostConfig.NetworkMode != tt.wantMode {
+				t.Errorf("app network mode = %s, want %s", app.HostConfig.NetworkMode, tt.wantMode)
+			}
+
+			if len(mockClient.ReplacedContainers) != 2 {
+				t.Errorf("expected 2 replacements, got %d", len(mockClient.ReplacedContainers))
+			}
+
+			// The inspect result must not be modified in place
+			if mockClient.Containers[0].HostConfig.NetworkMode != container.NetworkMode(tt.networkMode) {
+				t.Errorf("original host config was mutated: %s", mockClient.Containers[0].HostConfig.NetworkMode)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_RecreatesVolumesFromDependents(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{
+			ID:         "backup-id",
+			Name:       "backup",
+			Image:      "backup:latest",
+			ImageID:    "sha256:backup",
+			Mounts:     []string{"data-vol", "/srv/backups"},
+			Config:     &container.Config{Image: "backup:latest"},
+			HostConfig: &container.HostConfig{VolumesFrom: []string{"data-id:ro", "other"}},
+		},
+		{
+			ID:         "data-id",
+			Name:       "data",
+			Image:      "data:latest",
+			ImageID:    "sha256:data-old",
+			Mounts:     []string{"data-vol"},
+			Config:     &container.Config{Image: "data:latest"},
+			HostConfig: &container.HostConfig{},
+		},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"data:latest":   {ID: "sha256:data-new"},
+		"backup:latest": {ID: "sha256:backup"},
+	}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.CreatedContainers) != 2 {
+		t.Fatalf("expected 2 created containers, got %d", len(mockClient.CreatedContainers))
+	}
+	backup := mockClient.CreatedContainers[1].OldContainer
+	if backup.Name != "backup" {
+		t.Fatalf("second recreated container = %s, want backup", backup.Name)
+	}
+	if want := []string{"new-container-id-data:ro", "other"}; !reflect.DeepEqual(backup.HostConfig.VolumesFrom, want) {
+		t.Errorf("backup volumes-from = %v, want %v", backup.HostConfig.VolumesFrom, want)
+	}
+	// The network mode is left alone for a container only linked by volumes
+	if backup.HostConfig.NetworkMode != "" {
+		t.Errorf("backup network mode = %q, want unchanged", backup.HostConfig.NetworkMode)
+	}
+	if got := mockClient.Containers[0].HostConfig.VolumesFrom[0]; got != "data-id:ro" {
+		t.Errorf("original host config was mutated: %s", got)
+	}
+}
+
+func TestSharingMounts(t *testing.T) {
+	containers := []docker.ContainerInfo{
+		{ID: "db", Mounts: []string{"db-data"}},
+		{ID: "backup", Mounts: []string{"db-data", "/srv/backups"}},
+		{ID: "web", Mounts: []string{"/srv/www"}},
+		{ID: "cache"},
+	}
+
+	var ids []string
+	for _, c := range sharingMounts(containers) {
+		ids = append(ids, c.ID)
+	}
+	if want := []string{"db", "backup"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("sharingMounts() = %v, want %v", ids, want)
+	}
+}
+
+// BenchmarkRunUpdateCycle runs a cycle on a host with many containers, one of them out of
+// date, and reports the containers inspected per cycle
+func BenchmarkRunUpdateCycle(b *testing.B) {
+	logger := zerolog.Nop()
+	inspects := 0
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		mockClient := docker.NewMockDockerClient()
+		mockClient.PullImageReturns = make(map[string]docker.ImageInfo)
+		for n := 0; n < 500; n++ {
+			image := fmt.Sprintf("app-%d:latest", n)
+			mockClient.Containers = append(mockClient.Containers, docker.ContainerInfo{
+				ID:         fmt.Sprintf("app-%d-id", n),
+				Name:       fmt.Sprintf("app-%d", n),
+				Image:      image,
+				ImageID:    "sha256:" + image,
+				Mounts:     []string{fmt.Sprintf("app-%d-data", n)},
+				Config:     &container.Config{Image: image},
+				HostConfig: &container.HostConfig{},
+			})
+			if n > 0 {
+				mockClient.PullImageReturns[image] = docker.ImageInfo{ID: "sha256:" + image}
+			}
+		}
+		b.StartTimer()
+
+		if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &logger); err != nil {
+			b.Fatalf("RunUpdateCycle() error = %v", err)
+		}
+		inspects += len(mockClient.InspectedContainers)
+	}
+	b.ReportMetric(float64(inspects)/float64(b.N), "inspects/op")
+}
+
+func TestRunUpdateCycle_DependentRecreatedOnItsTarget(t *testing.T) {
+	withRegistry(t, stubRegistry{tags: map[string][]string{
+		"app:1.0.1": {"1.0.1", "1.0.2"},
+	}})
+
+	mockClient := newNetworkDependencyClient("container:vpn-id", false)
+	mockClient.Containers[0].Image = "app:1.0.1"
+	mockClient.Containers[0].Config.Image = "app:1.0.1"
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"vpn:latest": {ID: "sha256:vpn-new"},
+		"app:1.0.2":  {ID: "sha256:app-new"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.Policy = config.PolicyPatch
+	rep := report.New("abcd1234", false, false)
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(report.WithReport(context.Background(), rep), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	// Recreated alongside the VPN, the app moves to its own update rather than its old tag
+	if len(mockClient.CreatedContainers) != 2 || mockClient.CreatedContainers[1].NewImage != "app:1.0.2" {
+		t.Fatalf("created %+v, want the app recreated on app:1.0.2", mockClient.CreatedContainers)
+	}
+	if len(rep.Updated) != 2 || len(rep.Restarted) != 0 {
+		t.Errorf("updated %d, restarted %v, want both containers updated", len(rep.Updated), rep.Restarted)
+	}
+}
+
+func TestRunUpdateCycle_NetworkDependentFailure(t *testing.T) {
+	mockClient := &failingReplaceClient{MockDockerClient: newNetworkDependencyClient("container:vpn-id", false), fail: "app"}
+	cfg := config.Default()
+	cfg.State.HistoryFile = filepath.Join(t.TempDir(), "history.jsonl")
+	rep := report.New("abcd1234", false, false)
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(report.WithReport(context.Background(), rep), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(rep.Failed) != 1 || rep.Failed[0].Name != "app" {
+		t.Errorf("report failures = %+v, want the app", rep.Failed)
+	}
+	cycles, err := history.Read(cfg.State.HistoryFile, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cycles) != 1 || len(cycles[0].Failures) != 1 || cycles[0].Failures[0].Container != "app" {
+		t.Errorf("journal = %+v, want the app's failure recorded", cycles)
+	}
+}
@@ -0,0 +1,39 @@
+package updater
+
+import (
+	"context"
+
+	"github.com/MikeO7/HarborBuddy/internal/history"
+)
+
+// CurrentTag is the local tag updates.pin_digests moves to each image an update deploys
+const CurrentTag = "harborbuddy-current"
+
+// pinDeployed tags the image a candidate was updated to as <repository>:harborbuddy-current
+// and adds its registry digest to r, when updates.pin_digests is on. Failing to tag only
+// warns: the update itself went through.
+func (a *applier) pinDeployed(ctx context.Context, candidate updateCandidate, r history.Replacement) history.Replacement {
+	if !a.cfg.Updates.PinDigests {
+		return r
+	}
+	repo, _, ok := splitImageTag(candidate.Target)
+	if !ok {
+		return r
+	}
+
+	r.Digest = repoDigest(candidate.NewImage, candidate.Target)
+	current := repo + ":" + CurrentTag
+	if err := a.dockerClient.TagImage(ctx, candidate.NewImage.ID, current); err != nil {
+		candidate.Logger.Warn().Err(err).Msgf("Failed to tag the deployed image as %s", current)
+		return r
+	}
+	candidate.Logger.Info().Str("digest", r.Digest).Msgf("📌 Tagged the deployed image as %s", current)
+	return r
+}
+
+// runsCurrentTag reports whether a container runs a local harborbuddy-current tag, which
+// no registry has to pull
+func runsCurrentTag(image string) bool {
+	_, tag, ok := splitImageTag(image)
+	return ok && tag == CurrentTag
+}
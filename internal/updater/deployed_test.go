@@ -0,0 +1,95 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/history"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestRunUpdateCycle_PinDigests(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx", RepoDigests: []string{"nginx@sha256:abc123"}},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.PinDigests = true
+	cfg.State.HistoryFile = filepath.Join(t.TempDir(), "history.jsonl")
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	want := docker.TagRequest{Source: "sha256:new-nginx", Target: "nginx:harborbuddy-current"}
+	if len(mockClient.TaggedImages) != 1 || mockClient.TaggedImages[0] != want {
+		t.Errorf("tagged %+v, want [%+v]", mockClient.TaggedImages, want)
+	}
+	cycles, err := history.Read(cfg.State.HistoryFile, 0)
+	if err != nil || len(cycles) != 1 || len(cycles[0].Replaced) != 1 {
+		t.Fatalf("history.Read() = %+v, %v; want one replacement", cycles, err)
+	}
+	if got := cycles[0].Replaced[0].Digest; got != "sha256:abc123" {
+		t.Errorf("recorded digest = %q, want sha256:abc123", got)
+	}
+}
+
+func TestRunUpdateCycle_PinDigestsTagFailure(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+	mockClient.TagImageError = errors.New("no such image")
+
+	cfg := config.Default()
+	cfg.Updates.PinDigests = true
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	// The update still counts
+	if len(mockClient.ReplacedContainers) != 1 {
+		t.Errorf("replaced %d containers, want 1", len(mockClient.ReplacedContainers))
+	}
+}
+
+func TestRunUpdateCycle_PinDigestsOff(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.TaggedImages) != 0 {
+		t.Errorf("tagged %+v without updates.pin_digests", mockClient.TaggedImages)
+	}
+}
+
+func TestDetermineEligibility_CurrentTag(t *testing.T) {
+	c := docker.ContainerInfo{Name: "web-debug", Image: "nginx:harborbuddy-current"}
+	if decision := DetermineEligibility(c, config.Default().Updates); decision.Eligible {
+		t.Errorf("DetermineEligibility() = %+v, want a container on the local tag skipped", decision)
+	}
+}
@@ -0,0 +1,53 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/registry"
+)
+
+// DigestResolver looks up the manifest digest a registry currently serves for an image reference
+type DigestResolver interface {
+	ManifestDigest(ctx context.Context, imageRef string) (string, error)
+}
+
+// Registry is the set of registry queries the updater makes
+type Registry interface {
+	DigestResolver
+	TagLister
+}
+
+// newRegistry is a variable to allow stubbing the registry in tests
+var newRegistry = func(cfg config.Config) Registry {
+	return registry.NewClient(registry.KeychainFromConfig(cfg.Registries))
+}
+
+// digestUpToDate reports whether the container's local image was pulled from the manifest
+// the registry currently serves for its tag
+func digestUpToDate(ctx context.Context, dockerClient docker.Client, resolver DigestResolver, container docker.ContainerInfo) (bool, error) {
+	remote, err := resolver.ManifestDigest(ctx, container.Image)
+	if err != nil {
+		return false, err
+	}
+
+	local, err := dockerClient.InspectImage(ctx, container.ImageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect local image: %w", err)
+	}
+
+	// Locally built or loaded images have no repo digest to compare against
+	if len(local.RepoDigests) == 0 {
+		return false, fmt.Errorf("local image %s has no repo digest", shortID(container.ImageID))
+	}
+
+	for _, repoDigest := range local.RepoDigests {
+		if _, digest, ok := strings.Cut(repoDigest, "@"); ok && digest == remote {
+			return true, nil
+		}
+	}
+	return false, nil
+}
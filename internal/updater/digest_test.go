@@ -0,0 +1,101 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+// stubRegistry returns fixed registry digests and tags keyed by image reference
+type stubRegistry struct {
+	digests map[string]string
+	tags    map[string][]string
+}
+
+func (s stubRegistry) ManifestDigest(ctx context.Context, imageRef string) (string, error) {
+	if digest, ok := s.digests[imageRef]; ok {
+		return digest, nil
+	}
+	return "", errors.New("registry unreachable")
+}
+
+func (s stubRegistry) ListTags(ctx context.Context, imageRef string) ([]string, error) {
+	if tags, ok := s.tags[imageRef]; ok {
+		return tags, nil
+	}
+	return nil, errors.New("registry unreachable")
+}
+
+func withRegistry(t *testing.T, reg Registry) {
+	t.Helper()
+	original := newRegistry
+	newRegistry = func(cfg config.Config) Registry { return reg }
+	t.Cleanup(func() { newRegistry = original })
+}
+
+func digestTestClient() *docker.MockDockerClient {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:old-nginx", RepoDigests: []string{"nginx@sha256:aaa"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+	return mockClient
+}
+
+func TestRunUpdateCycle_DigestCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		remote      map[string]string
+		dryRun      bool
+		wantPulls   int
+		wantUpdates int
+	}{
+		{"digest unchanged skips pull", map[string]string{"nginx:latest": "sha256:aaa"}, false, 0, 0},
+		{"digest changed pulls and updates", map[string]string{"nginx:latest": "sha256:bbb"}, false, 1, 1},
+		{"registry error falls back to pull", map[string]string{}, false, 1, 1},
+		{"dry-run reports without pulling", map[string]string{"nginx:latest": "sha256:bbb"}, true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRegistry(t, stubRegistry{digests: tt.remote})
+			mockClient := digestTestClient()
+
+			cfg := config.Default()
+			cfg.Updates.CheckMethod = config.CheckMethodDigest
+			cfg.Updates.DryRun = tt.dryRun
+
+			testLogger := zerolog.New(zerolog.NewConsoleWriter())
+			if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+				t.Fatalf("RunUpdateCycle() error = %v", err)
+			}
+
+			if len(mockClient.PulledImages) != tt.wantPulls {
+				t.Errorf("pulled %v, want %d pulls", mockClient.PulledImages, tt.wantPulls)
+			}
+			if len(mockClient.ReplacedContainers) != tt.wantUpdates {
+				t.Errorf("replaced %d containers, want %d", len(mockClient.ReplacedContainers), tt.wantUpdates)
+			}
+		})
+	}
+}
+
+func TestDigestUpToDate_NoRepoDigest(t *testing.T) {
+	mockClient := digestTestClient()
+	mockClient.Images = []docker.ImageInfo{{ID: "sha256:old-nginx"}}
+
+	_, err := digestUpToDate(context.Background(), mockClient, stubRegistry{digests: map[string]string{"nginx:latest": "sha256:aaa"}}, mockClient.Containers[0])
+	if err == nil {
+		t.Error("expected an error for a local image without repo digests")
+	}
+}
@@ -0,0 +1,37 @@
+package updater
+
+import (
+	"context"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// drainContext returns a context for a container replacement that outlives a shutdown of
+// ctx by up to grace, so SIGTERM doesn't cut the replacement off between stopping the old
+// container and starting the new one. Once grace has passed the replacement is cancelled
+// too; its rollback still leaves the old container behind for recovery on the next start.
+func drainContext(ctx context.Context, grace time.Duration, logger *zerolog.Logger) (context.Context, context.CancelFunc) {
+	drain, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		if grace <= 0 {
+			cancel()
+			return
+		}
+		logger.Warn().Msgf("Shutting down: finishing this replacement first (up to %s)", util.HumanizeDuration(grace))
+
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			logger.Warn().Msg("Replacement did not finish within updates.drain_timeout, abandoning it")
+			cancel()
+		case <-drain.Done():
+		}
+	})
+	return drain, func() {
+		stop()
+		cancel()
+	}
+}
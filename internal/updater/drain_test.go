@@ -0,0 +1,74 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+// slowReplaceClient signals when a replacement starts, then takes a while, failing if its
+// context is cancelled in the meantime
+type slowReplaceClient struct {
+	*docker.MockDockerClient
+	started chan struct{}
+	takes   time.Duration
+}
+
+func (c *slowReplaceClient) ReplaceContainer(ctx context.Context, oldID, newID, name string, opts docker.ReplaceOptions) error {
+	close(c.started)
+	select {
+	case <-time.After(c.takes):
+		return c.MockDockerClient.ReplaceContainer(ctx, oldID, newID, name, opts)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRunUpdateCycle_ShutdownDrainsReplacement(t *testing.T) {
+	tests := []struct {
+		name         string
+		drainTimeout time.Duration
+		wantReplaced int
+	}{
+		{"replacement finishes within the drain timeout", time.Second, 1},
+		{"replacement cut off after the drain timeout", 10 * time.Millisecond, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := docker.NewMockDockerClient()
+			mockClient.Containers = []docker.ContainerInfo{
+				{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+				{ID: "container2", Name: "api", Image: "httpd:latest", ImageID: "sha256:old-httpd", Config: &container.Config{Image: "httpd:latest"}},
+			}
+			client := &slowReplaceClient{MockDockerClient: mockClient, started: make(chan struct{}), takes: 100 * time.Millisecond}
+
+			cfg := config.Default()
+			cfg.Updates.HealthTimeout = 0
+			cfg.Updates.DrainTimeout = tt.drainTimeout
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				<-client.started
+				cancel() // SIGTERM in the middle of the first replacement
+			}()
+
+			logger := zerolog.Nop()
+			err := RunUpdateCycle(ctx, cfg, client, &logger)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("RunUpdateCycle() error = %v, want the cycle interrupted", err)
+			}
+			// The second container is never started on
+			if n := len(mockClient.ReplacedContainers); n != tt.wantReplaced {
+				t.Errorf("replaced %d containers, want %d", n, tt.wantReplaced)
+			}
+		})
+	}
+}
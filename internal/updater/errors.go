@@ -0,0 +1,193 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/registry"
+	cerrdefs "github.com/containerd/errdefs"
+)
+
+// errorCategory classifies why a container failed during a cycle
+type errorCategory string
+
+const (
+	categoryAuth     errorCategory = "auth"
+	categoryNetwork  errorCategory = "network"
+	categoryTimeout  errorCategory = "timeout" // Cut off by updates.cycle_timeout
+	categoryPull     errorCategory = "pull"
+	categoryInspect  errorCategory = "inspect"
+	categoryHook     errorCategory = "hook"
+	categoryCreate   errorCategory = "create"
+	categoryStart    errorCategory = "start"
+	categoryHealth   errorCategory = "health"
+	categoryRollback errorCategory = "rollback"
+	categoryOther    errorCategory = "other"
+)
+
+// categoryOrder is the display order of categories in the cycle summary
+var categoryOrder = []errorCategory{
+	categoryAuth,
+	categoryNetwork,
+	categoryTimeout,
+	categoryPull,
+	categoryInspect,
+	categoryHook,
+	categoryCreate,
+	categoryStart,
+	categoryHealth,
+	categoryRollback,
+	categoryOther,
+}
+
+// categorizedError tags an error with the update step that produced it.
+// The message is left untouched so existing log output doesn't change.
+type categorizedError struct {
+	category errorCategory
+	err      error
+}
+
+func (e *categorizedError) Error() string { return e.err.Error() }
+func (e *categorizedError) Unwrap() error { return e.err }
+
+// withCategory tags err with the given step category
+func withCategory(category errorCategory, err error) error {
+	return &categorizedError{category: category, err: err}
+}
+
+// authMessages and networkMessages are phrases of registry and daemon errors that only reach
+// HarborBuddy as text, such as pull failures streamed back by the daemon. They are whole
+// phrases, so a digest or container name containing "401" or "eof" isn't mistaken for one.
+var authMessages = []string{
+	"unauthorized", "authentication required", "pull access denied", "access to the resource is denied",
+	"no basic auth credentials", "status 401", "status 403", "401 unauthorized", "403 forbidden",
+}
+
+var networkMessages = []string{
+	"connection refused", "connection reset", "no such host", "i/o timeout", "tls handshake timeout",
+	"network is unreachable", "unexpected eof", "temporary failure in name resolution",
+}
+
+// classifyError maps an error to a category. ctx is the cycle's context: a step cut off by
+// the cycle's own deadline timed out, whatever it was waiting on. Otherwise auth and network
+// problems win over the step that hit them, since they are the actual root cause.
+func classifyError(ctx context.Context, err error) errorCategory {
+	if err == nil {
+		return categoryOther
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && (errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)) {
+		return categoryTimeout
+	}
+	if isAuthError(err) {
+		return categoryAuth
+	}
+	if isNetworkError(err) {
+		return categoryNetwork
+	}
+
+	var ce *categorizedError
+	if errors.As(err, &ce) {
+		return ce.category
+	}
+
+	return categoryOther
+}
+
+// checkHint suggests what to look at when checking a container for updates failed
+func checkHint(category errorCategory, err error) string {
+	switch {
+	case category == categoryAuth:
+		return "Authentication failed - check `config.json`"
+	case category == categoryNetwork:
+		return "Check that the registry is reachable from the Docker host"
+	case category == categoryTimeout:
+		return "The cycle ran out of time; raise updates.cycle_timeout if checks are slow"
+	case errors.Is(err, registry.ErrNotFound) || cerrdefs.IsNotFound(err):
+		return "Image not found"
+	}
+	return "Check image name spelling and registry credentials"
+}
+
+// isAuthError reports whether err is a registry or daemon refusing access
+func isAuthError(err error) bool {
+	if errors.Is(err, registry.ErrUnauthorized) || cerrdefs.IsUnauthorized(err) || cerrdefs.IsPermissionDenied(err) {
+		return true
+	}
+	return containsPhrase(err, authMessages)
+}
+
+// isNetworkError reports whether err is a failure to reach a registry or the daemon
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+		return true
+	}
+	return containsPhrase(err, networkMessages)
+}
+
+// containsPhrase reports whether err's message contains one of phrases, ignoring case
+func containsPhrase(err error, phrases []string) bool {
+	msg := strings.ToLower(err.Error())
+	for _, phrase := range phrases {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyReplaceError maps a ReplaceContainer failure to create, start, health or rollback.
+// ReplaceContainer rolls back on every failure; only a failed create, start or health check of the new container is reported separately.
+func classifyReplaceError(err error) errorCategory {
+	switch {
+	case errors.Is(err, docker.ErrCreateFailed):
+		return categoryCreate
+	case errors.Is(err, docker.ErrStartFailed):
+		return categoryStart
+	case errors.Is(err, docker.ErrUnhealthy):
+		return categoryHealth
+	}
+	return categoryRollback
+}
+
+// errorTally counts errors per category. It is not safe for concurrent use.
+type errorTally map[errorCategory]int
+
+// add records an error under its category
+func (t errorTally) add(category errorCategory) {
+	t[category]++
+}
+
+// total returns the number of errors across all categories
+func (t errorTally) total() int {
+	n := 0
+	for _, count := range t {
+		n += count
+	}
+	return n
+}
+
+// String renders the tally as "auth=1, network=2" in a stable order
+func (t errorTally) String() string {
+	parts := make([]string, 0, len(t))
+	for _, category := range categoryOrder {
+		if count := t[category]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", category, count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fields returns the tally as a map suitable for structured logging
+func (t errorTally) fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(t))
+	for category, count := range t {
+		fields[string(category)] = count
+	}
+	return fields
+}
@@ -0,0 +1,146 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/registry"
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/rs/zerolog"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected errorCategory
+	}{
+		{"nil", nil, categoryOther},
+		{"registry 401", fmt.Errorf("failed to pull image: unauthorized: 401"), categoryAuth},
+		{"denied", fmt.Errorf("pull access denied for private/app"), categoryAuth},
+		{"dns", fmt.Errorf("dial tcp: lookup registry: no such host"), categoryNetwork},
+		{"timeout", fmt.Errorf("failed to pull image: %w", &net.DNSError{Err: "timeout", Name: "registry", IsTimeout: true}), categoryNetwork},
+		{"deadline", context.DeadlineExceeded, categoryNetwork},
+		{"truncated response", fmt.Errorf("failed to read manifest: %w", io.ErrUnexpectedEOF), categoryNetwork},
+		{"daemon 401", fmt.Errorf("failed to pull image: %w", cerrdefs.ErrUnauthenticated), categoryAuth},
+		{"registry 403", fmt.Errorf("outer: %w", fmt.Errorf("registry returned 403 Forbidden: %w", registry.ErrUnauthorized)), categoryAuth},
+		{"401 in a digest", withCategory(categoryPull, fmt.Errorf("manifest for app@sha256:4011aa unknown")), categoryPull},
+		{"eof in a name", withCategory(categoryStart, fmt.Errorf("geoffrey exited with code 1")), categoryStart},
+		{"denied in a name", withCategory(categoryCreate, fmt.Errorf("conflict: container denied-list exists")), categoryCreate},
+		{"tagged pull", withCategory(categoryPull, fmt.Errorf("manifest unknown")), categoryPull},
+		{"tagged create", withCategory(categoryCreate, fmt.Errorf("conflict")), categoryCreate},
+		{"auth wins over step", withCategory(categoryCreate, fmt.Errorf("403 forbidden")), categoryAuth},
+		{"wrapped tag", fmt.Errorf("outer: %w", withCategory(categoryStart, fmt.Errorf("boom"))), categoryStart},
+		{"untagged", fmt.Errorf("something odd"), categoryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(context.Background(), tt.err); got != tt.expected {
+				t.Errorf("classifyError(%v) = %s, want %s", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClassifyError_CycleTimeout(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	<-ctx.Done()
+
+	for _, err := range []error{context.DeadlineExceeded, fmt.Errorf("failed to pull image: %w", context.Canceled)} {
+		if got := classifyError(ctx, err); got != categoryTimeout {
+			t.Errorf("classifyError(%v) after the cycle deadline = %s, want timeout", err, got)
+		}
+	}
+	// Other errors keep their category even once the cycle timed out
+	if got := classifyError(ctx, fmt.Errorf("pull access denied for private/app")); got != categoryAuth {
+		t.Errorf("classifyError() = %s, want auth", got)
+	}
+}
+
+func TestCheckHint(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"auth", fmt.Errorf("pull access denied for private/app"), "Authentication failed - check `config.json`"},
+		{"not found", fmt.Errorf("manifest for app:1.2: %w", registry.ErrNotFound), "Image not found"},
+		{"404 in a digest", fmt.Errorf("manifest for app@sha256:4041aa unknown"), "Check image name spelling and registry credentials"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkHint(classifyError(context.Background(), tt.err), tt.err); got != tt.want {
+				t.Errorf("checkHint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyReplaceError(t *testing.T) {
+	if got := classifyReplaceError(fmt.Errorf("%w: port in use", docker.ErrStartFailed)); got != categoryStart {
+		t.Errorf("start failure classified as %s, want start", got)
+	}
+	if got := classifyReplaceError(fmt.Errorf("%w: no such image", docker.ErrCreateFailed)); got != categoryCreate {
+		t.Errorf("create failure classified as %s, want create", got)
+	}
+	if got := classifyReplaceError(fmt.Errorf("%w, rolled back to old container: new container reported unhealthy", docker.ErrUnhealthy)); got != categoryHealth {
+		t.Errorf("health failure classified as %s, want health", got)
+	}
+	if got := classifyReplaceError(fmt.Errorf("failed to rename new container: conflict")); got != categoryRollback {
+		t.Errorf("rename failure classified as %s, want rollback", got)
+	}
+}
+
+func TestErrorTally(t *testing.T) {
+	tally := errorTally{}
+	if tally.String() != "" || tally.total() != 0 {
+		t.Errorf("empty tally should render empty, got %q", tally.String())
+	}
+
+	tally.add(categoryStart)
+	tally.add(categoryAuth)
+	tally.add(categoryAuth)
+
+	if tally.total() != 3 {
+		t.Errorf("total() = %d, want 3", tally.total())
+	}
+	if got := tally.String(); got != "auth=2, start=1" {
+		t.Errorf("String() = %q, want %q", got, "auth=2, start=1")
+	}
+}
+
+func TestRunUpdateCycle_ErrorSummaryByCategory(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "nginx", Image: "nginx:latest", ImageID: "sha256:old"},
+	}
+	mockClient.PullImageError = fmt.Errorf("unauthorized: authentication required")
+
+	var logBuf bytes.Buffer
+	testLogger := zerolog.New(&logBuf)
+
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	logs := logBuf.String()
+	if !strings.Contains(logs, "1 errors (auth=1)") {
+		t.Errorf("summary should break errors down by category")
+	}
+	if !strings.Contains(logs, `"errors_by_category":{"auth":1}`) {
+		t.Errorf("summary should carry structured category counts")
+	}
+	if t.Failed() {
+		t.Logf("Actual logs: %s", logs)
+	}
+}
@@ -0,0 +1,196 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/pins"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// Outcomes of a rule in an Explanation
+const (
+	RulePass    = "pass"     // The rule lets the container through
+	RuleBlock   = "block"    // The rule keeps the container from being updated
+	RuleMatch   = "match"    // An allow pattern matches
+	RuleNoMatch = "no match" // A pattern doesn't match
+	RuleOff     = "off"      // The rule isn't configured
+)
+
+// RuleResult is how one rule applies to a container
+type RuleResult struct {
+	Rule    string `json:"rule"`    // e.g. "deny_images", "label com.harborbuddy.autoupdate"
+	Detail  string `json:"detail"`  // What was compared, e.g. the pattern and how it is read
+	Outcome string `json:"outcome"` // RulePass, RuleBlock, RuleMatch, RuleNoMatch or RuleOff
+}
+
+// Explanation walks through every rule an update cycle applies to a container, in the order
+// it applies them, and names the one that decided
+type Explanation struct {
+	Container string       `json:"container"`
+	Image     string       `json:"image"`
+	Rules     []RuleResult `json:"rules"`
+	Eligible  bool         `json:"eligible"`
+	Reason    string       `json:"reason"`
+	DecidedBy string       `json:"decided_by,omitempty"` // Rule that made the container ineligible
+}
+
+// ExplainContainer explains the rules for the running container with this name or ID prefix
+func ExplainContainer(ctx context.Context, cfg config.Config, dockerClient docker.Client, name string, logger *zerolog.Logger) (Explanation, error) {
+	containers, err := dockerClient.ListContainers(ctx)
+	if err != nil {
+		return Explanation{}, fmt.Errorf("failed to list containers: %w", err)
+	}
+	selected, err := selectContainers(containers, []string{name})
+	if err != nil {
+		return Explanation{}, err
+	}
+	return Explain(selected[0], cfg, loadPins(cfg, logger)), nil
+}
+
+// Explain evaluates every eligibility rule and pin against a container. Unlike an update
+// cycle it doesn't stop at the first rule that blocks, so a pattern set can be debugged in
+// one go; the verdict is the cycle's own.
+func Explain(c docker.ContainerInfo, cfg config.Config, pinSet *pins.Set) Explanation {
+	u := cfg.Updates
+	e := Explanation{Container: c.Name, Image: c.Image}
+	add := func(rule, detail, outcome string) {
+		e.Rules = append(e.Rules, RuleResult{Rule: rule, Detail: detail, Outcome: outcome})
+		if outcome == RuleBlock && e.DecidedBy == "" {
+			e.DecidedBy = rule
+		}
+	}
+	passOrBlock := func(blocked bool) string {
+		if blocked {
+			return RuleBlock
+		}
+		return RulePass
+	}
+
+	scope := fmt.Sprintf("instance scope %q, container label %s=%q", u.Scope, config.ScopeLabel, c.Labels[config.ScopeLabel])
+	add("scope", scope, passOrBlock(!u.InScope(c.Labels)))
+
+	autoupdate, labelled := c.Labels["com.harborbuddy.autoupdate"]
+	if labelled {
+		add("label com.harborbuddy.autoupdate", fmt.Sprintf("set to %q", autoupdate), passOrBlock(autoupdate == "false"))
+	} else {
+		add("label com.harborbuddy.autoupdate", "not set", RulePass)
+	}
+
+	if u.LabelEnable {
+		add("label_enable", "containers must opt in with com.harborbuddy.autoupdate=true", passOrBlock(autoupdate != "true"))
+	} else {
+		add("label_enable", "containers don't have to opt in", RuleOff)
+	}
+
+	add("image ID", "a rolled-back container runs a bare image ID", passOrBlock(pinnedToImageID(c.Image)))
+	add("local tag", CurrentTag+" only exists locally", passOrBlock(runsCurrentTag(c.Image)))
+
+	for _, pattern := range u.DenyContainers {
+		outcome := RuleNoMatch
+		if matchesPattern(c.Name, pattern) {
+			outcome = RuleBlock
+		}
+		add("deny_containers", fmt.Sprintf("%q (%s) against %s", pattern, patternRule(pattern), c.Name), outcome)
+	}
+	for _, pattern := range u.DenyImages {
+		outcome := RuleNoMatch
+		if matchesPattern(c.Image, pattern) {
+			outcome = RuleBlock
+		}
+		add("deny_images", fmt.Sprintf("%q (%s) against %s", pattern, patternRule(pattern), c.Image), outcome)
+	}
+
+	e.addAllowList("allow_containers", u.AllowContainers, c.Name)
+	e.addAllowList("allow_images", u.AllowImages, c.Image)
+
+	pinned := "image@sha256:... runs exactly that manifest"
+	if u.SuggestDigests {
+		pinned += "; suggest_digests reports newer ones"
+	}
+	add("pinned by digest", pinned, passOrBlock(pinnedByDigest(c.Image)))
+
+	if pin, ok := pinFor(pinSet, c); ok {
+		if ceiling, frozen := checkPin(c.Image, pin); pin.Freeze {
+			add("pins", pin.String()+" (harborbuddy freeze)", RuleBlock)
+		} else if frozen {
+			add("pins", pin.String()+", frozen", RuleBlock)
+		} else {
+			add("pins", pin.String()+", may move up to "+ceiling, RulePass)
+		}
+	} else if pinSet != nil {
+		add("pins", "no pin for this container or its image", RulePass)
+	} else {
+		add("pins", "no pins file", RuleOff)
+	}
+
+	decision, _ := decide(c, cfg, pinSet)
+	e.Eligible, e.Reason = decision.Eligible, decision.Reason
+	if e.Eligible {
+		e.DecidedBy = ""
+	}
+	return e
+}
+
+// addAllowList adds the patterns of an allow list. A "!" pattern that matches blocks; when
+// the list has other patterns and none of them matches, the list as a whole blocks.
+func (e *Explanation) addAllowList(rule string, patterns []string, subject string) {
+	if len(patterns) == 0 {
+		e.Rules = append(e.Rules, RuleResult{Rule: rule, Detail: "empty, allows everything", Outcome: RuleOff})
+		return
+	}
+	block := func(detail string) {
+		e.Rules = append(e.Rules, RuleResult{Rule: rule, Detail: detail, Outcome: RuleBlock})
+		if e.DecidedBy == "" {
+			e.DecidedBy = rule
+		}
+	}
+
+	excluded := false
+	for _, pattern := range patterns {
+		detail := fmt.Sprintf("%q (%s) against %s", pattern, patternRule(pattern), subject)
+		negated, isNegated := strings.CutPrefix(pattern, util.NegationPrefix)
+		switch {
+		case isNegated && matchesPattern(subject, negated):
+			excluded = true
+			block(detail)
+		case !isNegated && matchesPattern(subject, pattern):
+			e.Rules = append(e.Rules, RuleResult{Rule: rule, Detail: detail, Outcome: RuleMatch})
+		default:
+			e.Rules = append(e.Rules, RuleResult{Rule: rule, Detail: detail, Outcome: RuleNoMatch})
+		}
+	}
+	if !excluded && !util.MatchAllowList(subject, patterns) {
+		block("no pattern matches")
+	}
+}
+
+// String renders the explanation for terminal output
+func (e Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Container: %s\nImage: %s\n", e.Container, e.Image)
+	for _, r := range e.Rules {
+		mark := " "
+		switch r.Outcome {
+		case RuleBlock:
+			mark = "✗"
+		case RulePass, RuleMatch:
+			mark = "✓"
+		}
+		fmt.Fprintf(&b, "  %s %-34s %-8s %s\n", mark, r.Rule, r.Outcome, r.Detail)
+	}
+
+	verdict := "eligible"
+	if !e.Eligible {
+		verdict = "not eligible"
+	}
+	fmt.Fprintf(&b, "Result: %s (%s)\n", verdict, e.Reason)
+	if e.DecidedBy != "" {
+		fmt.Fprintf(&b, "Decided by: %s\n", e.DecidedBy)
+	}
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/pins"
+	"github.com/rs/zerolog"
+)
+
+func TestExplain(t *testing.T) {
+	cfg := config.Default()
+	cfg.Updates.DenyContainers = []string{"*-staging"}
+	cfg.Updates.DenyImages = []string{"postgres:*", "mysql:*"}
+	cfg.Updates.AllowImages = []string{"ghcr.io/acme/*"}
+
+	t.Run("every rule after the first that blocks", func(t *testing.T) {
+		c := docker.ContainerInfo{Name: "db-staging", Image: "postgres:16"}
+		e := Explain(c, cfg, nil)
+
+		if e.Eligible || e.DecidedBy != "deny_containers" || e.Reason != "container name matches deny pattern: *-staging" {
+			t.Errorf("Explain() = eligible %v, decided by %q (%s), want the name deny pattern to decide", e.Eligible, e.DecidedBy, e.Reason)
+		}
+		outcomes := make(map[string][]string)
+		for _, r := range e.Rules {
+			outcomes[r.Rule] = append(outcomes[r.Rule], r.Outcome)
+		}
+		if got := strings.Join(outcomes["deny_images"], ","); got != "block,no match" {
+			t.Errorf("deny_images outcomes = %s, want the postgres pattern to block too", got)
+		}
+		if got := strings.Join(outcomes["allow_images"], ","); got != "no match,block" {
+			t.Errorf("allow_images outcomes = %s, want no match, then the list blocking", got)
+		}
+	})
+
+	t.Run("eligible", func(t *testing.T) {
+		c := docker.ContainerInfo{Name: "api", Image: "ghcr.io/acme/api:1.4.0"}
+		e := Explain(c, cfg, &pins.Set{})
+
+		if !e.Eligible || e.DecidedBy != "" {
+			t.Errorf("Explain() = eligible %v, decided by %q, want eligible", e.Eligible, e.DecidedBy)
+		}
+		for _, r := range e.Rules {
+			if r.Outcome == RuleBlock {
+				t.Errorf("rule %s blocks an eligible container: %s", r.Rule, r.Detail)
+			}
+		}
+		if out := e.String(); !strings.Contains(out, "Result: eligible") || !strings.Contains(out, `"ghcr.io/acme/*" (prefix match on "ghcr.io/acme/") against ghcr.io/acme/api:1.4.0`) {
+			t.Errorf("String() = %s", out)
+		}
+	})
+
+	t.Run("negated allow pattern", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Updates.AllowImages = []string{"ghcr.io/acme/*", "!*:beta"}
+		e := Explain(docker.ContainerInfo{Name: "api", Image: "ghcr.io/acme/api:beta"}, cfg, nil)
+
+		if e.Eligible || e.DecidedBy != "allow_images" || e.Reason != "excluded by allow pattern: !*:beta" {
+			t.Errorf("Explain() = eligible %v, decided by %q (%s), want the negated pattern to decide", e.Eligible, e.DecidedBy, e.Reason)
+		}
+		var outcomes []string
+		for _, r := range e.Rules {
+			if r.Rule == "allow_images" {
+				outcomes = append(outcomes, r.Outcome)
+			}
+		}
+		if got := strings.Join(outcomes, ","); got != "match,block" {
+			t.Errorf("allow_images outcomes = %s, want the match, then the exclusion", got)
+		}
+	})
+
+	t.Run("pinned", func(t *testing.T) {
+		c := docker.ContainerInfo{Name: "api", Image: "ghcr.io/acme/api:1.4.0"}
+		set := &pins.Set{Containers: map[string]pins.Pin{"api": {Ref: "1.4.0", Reason: "release freeze"}}}
+		e := Explain(c, cfg, set)
+
+		if e.Eligible || e.DecidedBy != "pins" {
+			t.Errorf("Explain() = eligible %v, decided by %q, want the pin to decide", e.Eligible, e.DecidedBy)
+		}
+	})
+
+	t.Run("pinned by digest", func(t *testing.T) {
+		c := docker.ContainerInfo{Name: "web", Image: "ghcr.io/acme/web:1.25@" + pinnedDigest}
+		e := Explain(c, cfg, nil)
+
+		if e.Eligible || e.DecidedBy != "pinned by digest" || e.Reason != reasonPinnedByDigest {
+			t.Errorf("Explain() = eligible %v, decided by %q (%s), want the digest to decide", e.Eligible, e.DecidedBy, e.Reason)
+		}
+	})
+}
+
+func TestExplainContainer(t *testing.T) {
+	pinsFile := filepath.Join(t.TempDir(), "pins.yml")
+	if err := os.WriteFile(pinsFile, []byte("containers:\n  cache: \"7.2.1\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{{ID: "c3", Name: "cache", Image: "redis:7.2.1"}}
+	cfg := config.Default()
+	cfg.Updates.PinsFile = pinsFile
+	logger := zerolog.Nop()
+
+	e, err := ExplainContainer(context.Background(), cfg, mockClient, "cache", &logger)
+	if err != nil || e.Reason != "pinned to 7.2.1" {
+		t.Errorf("ExplainContainer() = %+v, %v, want the pin from the pins file", e, err)
+	}
+
+	if _, err := ExplainContainer(context.Background(), cfg, mockClient, "web", &logger); !errors.Is(err, ErrNoSuchContainer) {
+		t.Errorf("ExplainContainer() error = %v, want ErrNoSuchContainer", err)
+	}
+}
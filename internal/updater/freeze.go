@@ -0,0 +1,100 @@
+package updater
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/pins"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+)
+
+// FrozenContainer is a container frozen with Freeze
+type FrozenContainer struct {
+	Name string `json:"name"`
+	state.Freeze
+}
+
+// String describes the freeze, e.g. "frozen until 2026-10-19 15:00 UTC (incident)"
+func (f FrozenContainer) String() string {
+	return pins.Pin{Freeze: true, Until: f.Until, Reason: f.Reason}.String()
+}
+
+// openFreezes opens the state file freezes are kept in
+func openFreezes(cfg config.Config) (*state.Store, error) {
+	if cfg.State.File == "" {
+		return nil, fmt.Errorf("freezing containers needs a state file; set state.file (HARBORBUDDY_STATE_FILE)")
+	}
+	return state.Open(cfg.State.File)
+}
+
+// Freeze keeps the named container from being updated for d, or until it is unfrozen if d
+// is 0. Like a pin, a freeze is overridden by --force. The container doesn't have to exist
+// yet; the freeze applies to whatever container has the name.
+func Freeze(cfg config.Config, name string, d time.Duration, reason string) (FrozenContainer, error) {
+	if name == "" {
+		return FrozenContainer{}, fmt.Errorf("container name cannot be empty")
+	}
+	if d < 0 {
+		return FrozenContainer{}, fmt.Errorf("freeze duration cannot be negative")
+	}
+	store, err := openFreezes(cfg)
+	if err != nil {
+		return FrozenContainer{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	f := state.Freeze{Reason: reason, FrozenAt: now}
+	if d > 0 {
+		f.Until = now.Add(d)
+	}
+	if err := store.Freeze(name, f); err != nil {
+		return FrozenContainer{}, err
+	}
+	return FrozenContainer{Name: name, Freeze: f}, nil
+}
+
+// Unfreeze lifts a container's freeze. It returns false if the container wasn't frozen.
+func Unfreeze(cfg config.Config, name string) (bool, error) {
+	store, err := openFreezes(cfg)
+	if err != nil {
+		return false, err
+	}
+	return store.Unfreeze(name)
+}
+
+// Frozen lists the containers frozen now, ordered by name
+func Frozen(cfg config.Config) ([]FrozenContainer, error) {
+	store, err := openFreezes(cfg)
+	if err != nil {
+		return nil, err
+	}
+	frozen := []FrozenContainer{}
+	for name, f := range store.Frozen(time.Now()) {
+		frozen = append(frozen, FrozenContainer{Name: name, Freeze: f})
+	}
+	sort.Slice(frozen, func(i, j int) bool { return frozen[i].Name < frozen[j].Name })
+	return frozen, nil
+}
+
+// FormatFrozen prints frozen containers as a table
+func FormatFrozen(w io.Writer, frozen []FrozenContainer) {
+	if len(frozen) == 0 {
+		fmt.Fprintln(w, "No frozen containers.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tFROZEN AT\tUNTIL\tREASON")
+	for _, f := range frozen {
+		until := "unfrozen"
+		if !f.Until.IsZero() {
+			until = f.Until.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Name, f.FrozenAt.Local().Format("2006-01-02 15:04"), until, f.Reason)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,87 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestFreeze(t *testing.T) {
+	cfg := config.Default()
+	if _, err := Freeze(cfg, "db", 0, ""); err == nil {
+		t.Error("Freeze() without a state file succeeded")
+	}
+
+	cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+	frozen, err := Freeze(cfg, "db", 72*time.Hour, "incident")
+	if err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+	if got := frozen.Until.Sub(frozen.FrozenAt); got != 72*time.Hour {
+		t.Errorf("frozen for %v, want 72h", got)
+	}
+	if !strings.HasPrefix(frozen.String(), "frozen until ") || !strings.HasSuffix(frozen.String(), "(incident)") {
+		t.Errorf("String() = %q", frozen.String())
+	}
+
+	list, err := Frozen(cfg)
+	if err != nil || len(list) != 1 || list[0].Name != "db" {
+		t.Fatalf("Frozen() = %+v, %v; want db", list, err)
+	}
+	var out bytes.Buffer
+	FormatFrozen(&out, list)
+	if !strings.Contains(out.String(), "db") || !strings.Contains(out.String(), "incident") {
+		t.Errorf("FormatFrozen() = %q", out.String())
+	}
+
+	if ok, err := Unfreeze(cfg, "db"); !ok || err != nil {
+		t.Errorf("Unfreeze() = %v, %v; want true", ok, err)
+	}
+	if list, _ := Frozen(cfg); len(list) != 0 {
+		t.Errorf("Frozen() after Unfreeze() = %+v", list)
+	}
+}
+
+func TestRunUpdateCycle_SkipsFrozen(t *testing.T) {
+	cfg := config.Default()
+	cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+	if _, err := Freeze(cfg, "db", time.Hour, "incident"); err != nil {
+		t.Fatal(err)
+	}
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "db", Image: "postgres:16", ImageID: "sha256:old-postgres", Config: &container.Config{Image: "postgres:16"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+		"postgres:16":  {ID: "sha256:new-postgres"},
+	}
+
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+	if len(mockClient.PulledImages) != 1 || mockClient.PulledImages[0] != "nginx:latest" {
+		t.Errorf("pulled %v, want only nginx:latest (db frozen)", mockClient.PulledImages)
+	}
+
+	// Forcing overrides a freeze like a pin
+	cfg.Force = true
+	mockClient.PulledImages = nil
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+	if len(mockClient.PulledImages) != 2 {
+		t.Errorf("pulled %v with --force, want both images", mockClient.PulledImages)
+	}
+}
@@ -0,0 +1,162 @@
+package updater
+
+import (
+	"context"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/rs/zerolog"
+)
+
+// dependsOnLabel lists the containers, by name, that a container depends on, e.g. "db,redis".
+// Dependencies are updated first, and replacing one restarts its dependents.
+const dependsOnLabel = "com.harborbuddy.depends-on"
+
+// declaredDependencies returns the container names c depends on through the depends-on
+// label and updates.dependencies
+func declaredDependencies(c docker.ContainerInfo, configured map[string][]string) []string {
+	var names []string
+	for _, name := range strings.Split(c.Labels[dependsOnLabel], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	for _, name := range configured[c.Name] {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// dependencyGraph maps a container ID to the containers it directly depends on, through
+// compose depends_on (within its project) or declared dependencies
+type dependencyGraph map[string][]docker.ContainerInfo
+
+// buildDependencyGraph resolves the dependencies of every container. Dependencies on
+// containers that aren't running are ignored.
+func buildDependencyGraph(containers []docker.ContainerInfo, configured map[string][]string) dependencyGraph {
+	byName := make(map[string]docker.ContainerInfo, len(containers))
+	byService := make(map[[2]string]docker.ContainerInfo)
+	for _, c := range containers {
+		byName[c.Name] = c
+		if project, service := composeService(c); project != "" {
+			byService[[2]string{project, service}] = c
+		}
+	}
+
+	graph := make(dependencyGraph)
+	for _, c := range containers {
+		seen := map[string]bool{c.ID: true}
+		add := func(dep docker.ContainerInfo, ok bool) {
+			if ok && !seen[dep.ID] {
+				seen[dep.ID] = true
+				graph[c.ID] = append(graph[c.ID], dep)
+			}
+		}
+
+		if project, _ := composeService(c); project != "" {
+			for _, service := range composeDependencies(c) {
+				dep, ok := byService[[2]string{project, service}]
+				add(dep, ok)
+			}
+		}
+		for _, name := range declaredDependencies(c, configured) {
+			dep, ok := byName[name]
+			add(dep, ok)
+		}
+	}
+	return graph
+}
+
+// dependsOn reports whether c directly depends on target
+func (g dependencyGraph) dependsOn(c, target docker.ContainerInfo) bool {
+	for _, dep := range g[c.ID] {
+		if dep.ID == target.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// dependents returns the containers that depend on target, directly or transitively,
+// nearest first
+func (g dependencyGraph) dependents(containers []docker.ContainerInfo, target docker.ContainerInfo) []docker.ContainerInfo {
+	var dependents []docker.ContainerInfo
+	seen := map[string]bool{target.ID: true}
+	queue := []docker.ContainerInfo{target}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, c := range containers {
+			if !seen[c.ID] && g.dependsOn(c, current) {
+				seen[c.ID] = true
+				dependents = append(dependents, c)
+				queue = append(queue, c)
+			}
+		}
+	}
+	return dependents
+}
+
+// depth returns how many levels of dependencies a container has, so updating in ascending
+// depth replaces dependencies before their consumers
+func (g dependencyGraph) depth(c docker.ContainerInfo) int {
+	var depth func(c docker.ContainerInfo, visiting map[string]bool) int
+	depth = func(c docker.ContainerInfo, visiting map[string]bool) int {
+		if visiting[c.ID] {
+			return 0 // Don't loop on a dependency cycle
+		}
+		visiting[c.ID] = true
+		defer delete(visiting, c.ID)
+
+		deepest := 0
+		for _, dep := range g[c.ID] {
+			deepest = max(deepest, depth(dep, visiting)+1)
+		}
+		return deepest
+	}
+	return depth(c, map[string]bool{})
+}
+
+// stopDependents stops the running consumers of a container before it is replaced,
+// deepest dependents first, and returns them in the order to start them again.
+// Containers already recreated this cycle are skipped.
+func stopDependents(ctx context.Context, dockerClient docker.Client, graph dependencyGraph, containers []docker.ContainerInfo, target docker.ContainerInfo, recreated map[string]bool, stopTimeout int, logger *zerolog.Logger) []docker.ContainerInfo {
+	dependents := graph.dependents(containers, target)
+
+	var stopped []docker.ContainerInfo
+	for i := len(dependents) - 1; i >= 0; i-- {
+		dependent := dependents[i]
+		if recreated[dependent.ID] {
+			continue
+		}
+		if err := dockerClient.StopContainer(ctx, dependent.ID, stopTimeout); err != nil {
+			logger.Warn().Err(err).Str("dependent", dependent.Name).Msg("Failed to stop dependent")
+			continue
+		}
+		logger.Info().Str("dependent", dependent.Name).Msg("Stopped dependent")
+		stopped = append([]docker.ContainerInfo{dependent}, stopped...)
+	}
+	return stopped
+}
+
+// startDependents starts the consumers stopped by stopDependents again, except those
+// recreated (and so already started) in the meantime, and returns the names of those it started
+func startDependents(ctx context.Context, dockerClient docker.Client, stopped []docker.ContainerInfo, recreated map[string]bool, logger *zerolog.Logger) []string {
+	var started []string
+	for _, dependent := range stopped {
+		if recreated[dependent.ID] {
+			continue
+		}
+		if err := dockerClient.StartContainer(ctx, dependent.ID); err != nil {
+			logger.Error().Err(err).Str("dependent", dependent.Name).Msg("Failed to restart dependent")
+			continue
+		}
+		logger.Info().Str("dependent", dependent.Name).Msg("Restarted dependent")
+		started = append(started, dependent.Name)
+	}
+	return started
+}
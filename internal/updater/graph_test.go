@@ -0,0 +1,116 @@
+package updater
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/rs/zerolog"
+)
+
+func TestDeclaredDependencies(t *testing.T) {
+	c := docker.ContainerInfo{Name: "web", Labels: map[string]string{dependsOnLabel: "db, redis,"}}
+	configured := map[string][]string{"web": {"search"}, "other": {"cache"}}
+
+	if got := declaredDependencies(c, configured); !reflect.DeepEqual(got, []string{"db", "redis", "search"}) {
+		t.Errorf("declaredDependencies() = %v, want [db redis search]", got)
+	}
+	if got := declaredDependencies(docker.ContainerInfo{Name: "plain"}, configured); got != nil {
+		t.Errorf("declaredDependencies() without label or config = %v, want nil", got)
+	}
+}
+
+func TestBuildDependencyGraph(t *testing.T) {
+	db := docker.ContainerInfo{ID: "db1", Name: "db"}
+	redis := docker.ContainerInfo{ID: "redis1", Name: "redis"}
+	web := docker.ContainerInfo{ID: "web1", Name: "web", Labels: map[string]string{dependsOnLabel: "db,missing"}}
+	worker := docker.ContainerInfo{ID: "worker1", Name: "worker"}
+	containers := []docker.ContainerInfo{db, redis, web, worker}
+
+	graph := buildDependencyGraph(containers, map[string][]string{
+		"worker": {"web", "redis"},
+		"web":    {"db"}, // Also on the label, listed once
+	})
+
+	names := func(cs []docker.ContainerInfo) []string {
+		var out []string
+		for _, c := range cs {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+
+	if got := names(graph[web.ID]); !reflect.DeepEqual(got, []string{"db"}) {
+		t.Errorf("web depends on %v, want [db]", got)
+	}
+	if got := names(graph[worker.ID]); !reflect.DeepEqual(got, []string{"web", "redis"}) {
+		t.Errorf("worker depends on %v, want [web redis]", got)
+	}
+	if got := names(graph.dependents(containers, db)); !reflect.DeepEqual(got, []string{"web", "worker"}) {
+		t.Errorf("dependents(db) = %v, want [web worker]", got)
+	}
+	if got := graph.depth(worker); got != 2 {
+		t.Errorf("depth(worker) = %d, want 2", got)
+	}
+}
+
+func TestBuildDependencyGraph_Cycle(t *testing.T) {
+	a := docker.ContainerInfo{ID: "a1", Name: "a", Labels: map[string]string{dependsOnLabel: "b"}}
+	b := docker.ContainerInfo{ID: "b1", Name: "b", Labels: map[string]string{dependsOnLabel: "a"}}
+	containers := []docker.ContainerInfo{a, b}
+
+	graph := buildDependencyGraph(containers, nil)
+	if got := graph.dependents(containers, a); len(got) != 1 || got[0].Name != "b" {
+		t.Errorf("dependents(a) = %v, want [b]", got)
+	}
+	_ = graph.depth(a) // Must terminate
+}
+
+func TestRunUpdateCycle_DeclaredDependencies(t *testing.T) {
+	t.Log("Testing that replacing a dependency restarts declared dependents with unchanged images")
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "worker1", Name: "worker", Image: "worker:latest", ImageID: "sha256:worker"},
+		{ID: "web1", Name: "web", Image: "web:latest", ImageID: "sha256:old-web", Labels: map[string]string{dependsOnLabel: "db"}},
+		{ID: "db1", Name: "db", Image: "postgres:16", ImageID: "sha256:old-db"},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"worker:latest": {ID: "sha256:worker"},
+		"web:latest":    {ID: "sha256:new-web"},
+		"postgres:16":   {ID: "sha256:new-db"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.Dependencies = map[string][]string{"worker": {"web"}}
+
+	rep := report.New("abcd1234", false, false)
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(report.WithReport(context.Background(), rep), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	var created []string
+	for _, req := range mockClient.CreatedContainers {
+		created = append(created, req.OldContainer.Name)
+	}
+	if !reflect.DeepEqual(created, []string{"db", "web"}) {
+		t.Errorf("update order = %v, want db before web", created)
+	}
+
+	// The worker's image didn't change, but it is restarted around both of its dependencies
+	if want := []string{"worker1", "web1", "worker1"}; !reflect.DeepEqual(mockClient.StoppedContainers, want) {
+		t.Errorf("stopped = %v, want %v", mockClient.StoppedContainers, want)
+	}
+	if want := []string{"web1", "worker1", "worker1"}; !reflect.DeepEqual(mockClient.StartedContainers, want) {
+		t.Errorf("started = %v, want %v", mockClient.StartedContainers, want)
+	}
+
+	// Restarting the worker isn't an update of its own
+	if len(rep.Updated) != 2 || !reflect.DeepEqual(rep.Restarted, []string{"worker"}) {
+		t.Errorf("updated %d, restarted %v, want 2 updated and the worker restarted once", len(rep.Updated), rep.Restarted)
+	}
+}
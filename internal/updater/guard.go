@@ -0,0 +1,99 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// refusal is why an update that was found won't be applied
+type refusal struct {
+	Outcome string // Notification outcome, e.g. notify.OutcomeUnverified
+	Reason  string // Shown in the cycle report and the notification
+}
+
+// vetUpdate checks the image pulled for an update before it may replace the container: it has
+// to be built for the platform the container's current image is (or, if unknown, the daemon's)
+// and, with verification on, carry a trusted signature. It returns nil when the update may go
+// ahead.
+func vetUpdate(ctx context.Context, verifier ImageVerifier, daemon, current docker.Platform, target string, newImage docker.ImageInfo, logger *zerolog.Logger) *refusal {
+	if reason := platformMismatch(newImage.Platform, current, daemon); reason != "" {
+		logger.Warn().
+			Str("image", target).
+			Str("new_id", shortID(newImage.ID)).
+			Str("image_platform", newImage.Platform.String()).
+			Msgf("⛔ Skipping update: %s", reason)
+		return &refusal{Outcome: notify.OutcomeWrongPlatform, Reason: reason}
+	}
+
+	if verifier != nil && verifier.Applies(target) {
+		if err := verifyImage(ctx, verifier, target, newImage); err != nil {
+			logger.Warn().
+				Err(err).
+				Str("image", target).
+				Str("new_id", shortID(newImage.ID)).
+				Msg("🔏 Skipping update: image signature could not be verified")
+			return &refusal{Outcome: notify.OutcomeUnverified, Reason: "signature verification failed: " + err.Error()}
+		}
+		logger.Info().Str("image", target).Msg("🔏 Image signature verified")
+	}
+	return nil
+}
+
+// platformMismatch explains why an image built for image can't replace a container running
+// an image built for current on a daemon running on daemon, or returns "". The container's
+// own image wins over the daemon, so containers deliberately run under emulation keep
+// updating; the daemon is only consulted when the current image's platform is unknown.
+func platformMismatch(image, current, daemon docker.Platform) string {
+	if !image.Known() {
+		return ""
+	}
+	if current.Known() {
+		if !samePlatform(image, current) {
+			return fmt.Sprintf("image is built for %s, the container runs %s", image, current)
+		}
+		return ""
+	}
+	if daemon.Known() && (image.OS != daemon.OS || normalizeArch(image.Architecture) != normalizeArch(daemon.Architecture)) {
+		return fmt.Sprintf("image is built for %s, the Docker host runs %s", image, daemon)
+	}
+	return ""
+}
+
+// samePlatform compares platforms; a variant only counts when both sides report one
+func samePlatform(a, b docker.Platform) bool {
+	if a.OS != b.OS || normalizeArch(a.Architecture) != normalizeArch(b.Architecture) {
+		return false
+	}
+	return a.Variant == "" || b.Variant == "" || a.Variant == b.Variant
+}
+
+// normalizeArch maps kernel architecture names to the ones images use
+func normalizeArch(arch string) string {
+	switch arch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	}
+	return arch
+}
+
+// imageTooNew explains why an update to image waits for a later cycle under
+// updates.min_image_age, or returns "". The age is taken from the image's build time; images
+// without one, or built reproducibly with a fixed epoch, are never held back.
+func imageTooNew(image docker.ImageInfo, minAge time.Duration, now time.Time) string {
+	if minAge <= 0 || image.CreatedAt.IsZero() {
+		return ""
+	}
+	age := now.Sub(image.CreatedAt)
+	if age >= minAge {
+		return ""
+	}
+	return fmt.Sprintf("image is %s old, updates wait until it is %s old", util.HumanizeDuration(max(age, 0)), util.HumanizeDuration(minAge))
+}
@@ -0,0 +1,143 @@
+package updater
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestPlatformMismatch(t *testing.T) {
+	amd64 := docker.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := docker.Platform{OS: "linux", Architecture: "arm64"}
+	armv7 := docker.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}
+	armv6 := docker.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}
+
+	tests := []struct {
+		name    string
+		image   docker.Platform
+		current docker.Platform
+		daemon  docker.Platform
+		want    string
+	}{
+		{"same as the container", arm64, arm64, arm64, ""},
+		{"another arch than the container", amd64, arm64, arm64, "image is built for linux/amd64, the container runs linux/arm64"},
+		{"another variant", armv6, armv7, docker.Platform{}, "image is built for linux/arm/v6, the container runs linux/arm/v7"},
+		{"variant missing on one side", docker.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, arm64, arm64, ""},
+		{"emulated container keeps updating", amd64, amd64, arm64, ""},
+		{"unknown container image, checked against the daemon", amd64, docker.Platform{}, arm64, "image is built for linux/amd64, the Docker host runs linux/arm64"},
+		{"kernel arch names", arm64, docker.Platform{}, docker.Platform{OS: "linux", Architecture: "aarch64"}, ""},
+		{"nothing known", amd64, docker.Platform{}, docker.Platform{}, ""},
+		{"image platform unknown", docker.Platform{}, arm64, arm64, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := platformMismatch(tt.image, tt.current, tt.daemon); got != tt.want {
+				t.Errorf("platformMismatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_WrongPlatform(t *testing.T) {
+	arm64 := docker.Platform{OS: "linux", Architecture: "arm64"}
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Platform = arm64
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "api", Image: "httpd:latest", ImageID: "sha256:old-httpd", Config: &container.Config{Image: "httpd:latest"}},
+	}
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:old-nginx", Platform: arm64},
+		{ID: "sha256:old-httpd", Platform: arm64},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx", Platform: arm64},
+		"httpd:latest": {ID: "sha256:new-httpd", Platform: docker.Platform{OS: "linux", Architecture: "amd64"}},
+	}
+
+	recorder := &eventRecorder{}
+	ctx := notify.WithNotifier(context.Background(), recorder)
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(ctx, config.Default(), mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].Name != "web" {
+		t.Errorf("replaced = %+v, want only web", mockClient.ReplacedContainers)
+	}
+
+	var refused []notify.Event
+	for _, event := range recorder.events {
+		if event.Outcome == notify.OutcomeWrongPlatform {
+			refused = append(refused, event)
+		}
+	}
+	if len(refused) != 1 || refused[0].Container != "api" || refused[0].Error != "image is built for linux/amd64, the container runs linux/arm64" {
+		t.Errorf("wrong platform events = %+v, want one for api", refused)
+	}
+}
+
+func TestImageTooNew(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		created time.Time
+		minAge  time.Duration
+		want    string
+	}{
+		{"disabled", now.Add(-time.Hour), 0, ""},
+		{"old enough", now.Add(-72 * time.Hour), 48 * time.Hour, ""},
+		{"too new", now.Add(-5 * time.Hour), 48 * time.Hour, "image is 5h old, updates wait until it is 2 days old"},
+		{"build time unknown", time.Time{}, 48 * time.Hour, ""},
+		{"clock skew", now.Add(time.Hour), time.Hour, "image is 0s old, updates wait until it is 1h old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := imageTooNew(docker.ImageInfo{CreatedAt: tt.created}, tt.minAge, now); got != tt.want {
+				t.Errorf("imageTooNew() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_MinImageAge(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "api", Image: "httpd:latest", ImageID: "sha256:old-httpd", Config: &container.Config{Image: "httpd:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx", CreatedAt: time.Now().Add(-72 * time.Hour)},
+		"httpd:latest": {ID: "sha256:new-httpd", CreatedAt: time.Now().Add(-time.Hour)},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.MinImageAge = 48 * time.Hour
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+	if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].Name != "web" {
+		t.Errorf("replaced = %+v, want only web", mockClient.ReplacedContainers)
+	}
+
+	// A forced update doesn't wait
+	cfg.Force = true
+	cfg.Targets = []string{"api"}
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+	if len(mockClient.ReplacedContainers) != 2 || mockClient.ReplacedContainers[1].Name != "api" {
+		t.Errorf("replaced = %+v, want api forced through", mockClient.ReplacedContainers)
+	}
+}
@@ -0,0 +1,119 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/hooks"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/rs/zerolog"
+)
+
+// Lifecycle hook labels. The command runs with "sh -c" inside the container: pre-update in the
+// old container before it is stopped, post-update in the new one once it has replaced it.
+const (
+	preUpdateLabel  = "com.harborbuddy.lifecycle.pre-update"
+	postUpdateLabel = "com.harborbuddy.lifecycle.post-update"
+)
+
+// hookTimeout returns the timeout for a hook: the "<label>-timeout" label if it parses, else the default
+func hookTimeout(c docker.ContainerInfo, label string, fallback time.Duration) time.Duration {
+	if val, ok := c.Labels[label+"-timeout"]; ok {
+		if d, err := time.ParseDuration(val); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+// runHook runs the command in the given label inside container id. It is a no-op if the
+// container has no such label. A non-zero exit status is returned as an error.
+func runHook(ctx context.Context, dockerClient docker.Client, c docker.ContainerInfo, id, label string, fallback time.Duration, logger *zerolog.Logger) error {
+	command := c.Labels[label]
+	if command == "" {
+		return nil
+	}
+
+	timeout := hookTimeout(c, label, fallback)
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	logger.Info().
+		Str("hook", label).
+		Str("command", command).
+		Msg("Running lifecycle hook")
+
+	start := time.Now()
+	result, err := dockerClient.ExecContainer(hookCtx, id, []string{"sh", "-c", command})
+	if err != nil {
+		if hookCtx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s hook did not finish within %v", label, timeout)
+		}
+		return fmt.Errorf("%s hook failed: %w", label, err)
+	}
+
+	event := logger.Debug()
+	if result.ExitCode != 0 {
+		event = logger.Warn()
+	}
+	event.
+		Str("hook", label).
+		Int("exit_code", result.ExitCode).
+		Dur("duration", time.Since(start)).
+		Str("output", result.Output).
+		Msg("Lifecycle hook finished")
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s hook exited with status %d", label, result.ExitCode)
+	}
+	return nil
+}
+
+// runPostUpdateHook runs the post-update hook in the new container. The update has already
+// happened, so a failure is only logged.
+func runPostUpdateHook(ctx context.Context, cfg config.Config, dockerClient docker.Client, c docker.ContainerInfo, newID string, logger *zerolog.Logger) {
+	// A replacement left stopped or paused like the old container can't run commands
+	if docker.RunStateOf(c) != docker.RunStateRunning {
+		return
+	}
+	if err := runHook(ctx, dockerClient, c, newID, postUpdateLabel, cfg.Updates.HookTimeout, logger); err != nil {
+		logger.Warn().Err(err).Msg("Post-update hook failed; the container was updated anyway")
+	}
+}
+
+// runPostUpdateScript runs the host post_update hook with the outcome of the update.
+// Like the label hook, a failure is only logged.
+func runPostUpdateScript(ctx context.Context, cfg config.Config, env hooks.Env, updateErr error, logger *zerolog.Logger) {
+	env.Outcome = notify.OutcomeSuccess
+	if updateErr != nil {
+		env.Outcome = notify.OutcomeFailure
+		env.Error = updateErr.Error()
+	}
+	if err := hooks.Run(ctx, hooks.PostUpdate, cfg.Hooks.PostUpdate, env, cfg.Hooks.Timeout, logger); err != nil {
+		logger.Warn().Err(err).Msg("Post-update hook failed")
+	}
+}
+
+// runPostCycleHook runs the host post_cycle hook. A cycle that failed as a whole passes its
+// error, so the hook runs on every cycle that got past pre_cycle. A failure of the hook itself
+// is only logged.
+func runPostCycleHook(ctx context.Context, cfg config.Config, env hooks.Env, cycleErr error, logger *zerolog.Logger) {
+	if cycleErr != nil {
+		env.Outcome = notify.OutcomeFailure
+		env.Error = cycleErr.Error()
+	}
+	if err := hooks.Run(ctx, hooks.PostCycle, cfg.Hooks.PostCycle, env, cfg.Hooks.Timeout, logger); err != nil {
+		logger.Warn().Err(err).Msg("Post-cycle hook failed")
+	}
+}
+
+// cycleOutcome reports a cycle as failed if any container failed
+func cycleOutcome(tally errorTally) string {
+	if tally.total() > 0 {
+		return notify.OutcomeFailure
+	}
+	return notify.OutcomeSuccess
+}
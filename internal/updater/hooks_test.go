@@ -0,0 +1,216 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func hookedContainer(labels map[string]string) docker.ContainerInfo {
+	return docker.ContainerInfo{
+		ID:      "web1",
+		Name:    "web",
+		Image:   "nginx:latest",
+		ImageID: "sha256:old",
+		Labels:  labels,
+		State:   &types.ContainerState{Running: true},
+		Config:  &container.Config{Image: "nginx:latest"},
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	c := docker.ContainerInfo{Labels: map[string]string{
+		preUpdateLabel + "-timeout":  "2m",
+		postUpdateLabel + "-timeout": "soon",
+	}}
+
+	if got := hookTimeout(c, preUpdateLabel, time.Minute); got != 2*time.Minute {
+		t.Errorf("hookTimeout(pre) = %v, want 2m from the label", got)
+	}
+	if got := hookTimeout(c, postUpdateLabel, time.Minute); got != time.Minute {
+		t.Errorf("hookTimeout(post) = %v, want the default for an unparsable label", got)
+	}
+}
+
+func TestRunUpdateCycle_LifecycleHooks(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{hookedContainer(map[string]string{
+		preUpdateLabel:  "nginx -s quit",
+		postUpdateLabel: "curl -fs localhost/warm",
+	})}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:new"}}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	want := []docker.ExecRequest{
+		{ID: "web1", Cmd: []string{"sh", "-c", "nginx -s quit"}},
+		{ID: "new-container-id-web", Cmd: []string{"sh", "-c", "curl -fs localhost/warm"}},
+	}
+	if !reflect.DeepEqual(mockClient.ExecutedCommands, want) {
+		t.Errorf("executed = %+v, want pre-update in the old container and post-update in the new one", mockClient.ExecutedCommands)
+	}
+	if len(mockClient.ReplacedContainers) != 1 {
+		t.Errorf("replaced %d containers, want 1", len(mockClient.ReplacedContainers))
+	}
+}
+
+func TestRunUpdateCycle_PausedContainerKeepsState(t *testing.T) {
+	paused := hookedContainer(map[string]string{
+		preUpdateLabel:  "nginx -s quit",
+		postUpdateLabel: "curl -fs localhost/warm",
+	})
+	paused.State = &types.ContainerState{Running: true, Paused: true}
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{paused}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:new"}}
+
+	testLogger := zerolog.Nop()
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].Options.RunState != docker.RunStatePaused {
+		t.Fatalf("replaced = %+v, want one replacement left paused", mockClient.ReplacedContainers)
+	}
+	if len(mockClient.ExecutedCommands) != 0 {
+		t.Errorf("executed = %+v, want no hooks in paused containers", mockClient.ExecutedCommands)
+	}
+}
+
+func TestRunUpdateCycle_PreUpdateHookFailureSkipsUpdate(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{hookedContainer(map[string]string{
+		preUpdateLabel: "drain",
+	})}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:new"}}
+	mockClient.ExecResults = map[string]docker.ExecResult{"web1": {ExitCode: 1, Output: "still busy"}}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.CreatedContainers) != 0 || len(mockClient.ReplacedContainers) != 0 {
+		t.Error("container was replaced despite the failed pre-update hook")
+	}
+}
+
+func TestRunUpdateCycle_PostUpdateHookFailureKeepsUpdate(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{hookedContainer(map[string]string{
+		postUpdateLabel: "warm-cache",
+	})}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:new"}}
+	mockClient.ExecResults = map[string]docker.ExecResult{"new-container-id-web": {ExitCode: 2}}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v, want post-update failures to be non-fatal", err)
+	}
+
+	if len(mockClient.ReplacedContainers) != 1 {
+		t.Errorf("replaced %d containers, want 1", len(mockClient.ReplacedContainers))
+	}
+}
+
+func TestRunUpdateCycle_HostHooks(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hooks.log")
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{hookedContainer(nil)}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"nginx:latest": {ID: "sha256:new"}}
+
+	cfg := config.Default()
+	record := `echo "$HARBORBUDDY_HOOK $HARBORBUDDY_CONTAINER_NAME $HARBORBUDDY_OUTCOME $HARBORBUDDY_UPDATED" >> ` + out
+	cfg.Hooks = config.HooksConfig{
+		PreCycle:   record,
+		PostCycle:  record,
+		PreUpdate:  record,
+		PostUpdate: record,
+		Timeout:    time.Minute,
+	}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pre_cycle   \npre_update web  \npost_update web success \npost_cycle  success 1\n"
+	if string(data) != want {
+		t.Errorf("hooks ran as:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestRunUpdateCycle_PreCycleHookFailureSkipsCycle(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{hookedContainer(nil)}
+
+	cfg := config.Default()
+	cfg.Hooks.PreCycle = "exit 1"
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err == nil {
+		t.Fatal("RunUpdateCycle() error = nil, want the pre-cycle hook failure")
+	}
+
+	if len(mockClient.PulledImages) != 0 {
+		t.Errorf("pulled %v despite the failed pre-cycle hook", mockClient.PulledImages)
+	}
+}
+
+func TestRunUpdateCycle_PostCycleHookOnFailedCycle(t *testing.T) {
+	tests := []struct {
+		name      string
+		listError error
+		targets   []string
+		wantError string
+	}{
+		{"listing fails", errors.New("cannot connect to the Docker daemon"), nil, "cannot connect to the Docker daemon"},
+		{"no target matches", nil, []string{"db"}, "no container matches db"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "hooks.log")
+
+			mockClient := docker.NewMockDockerClient()
+			mockClient.Containers = []docker.ContainerInfo{hookedContainer(nil)}
+			mockClient.ListContainersError = tt.listError
+
+			cfg := config.Default()
+			cfg.Targets = tt.targets
+			cfg.Hooks.PostCycle = `echo "$HARBORBUDDY_HOOK $HARBORBUDDY_OUTCOME $HARBORBUDDY_ERROR" >> ` + out
+
+			testLogger := zerolog.Nop()
+			if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err == nil {
+				t.Fatal("RunUpdateCycle() error = nil, want the cycle to fail")
+			}
+
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatalf("post_cycle hook didn't run: %v", err)
+			}
+			if want := "post_cycle failure " + tt.wantError + "\n"; string(data) != want {
+				t.Errorf("post_cycle hook ran as %q, want %q", data, want)
+			}
+		})
+	}
+}
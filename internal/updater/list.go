@@ -0,0 +1,113 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/pins"
+	"github.com/rs/zerolog"
+)
+
+// labelPrefix marks the container labels that change how HarborBuddy treats a container
+const labelPrefix = "com.harborbuddy."
+
+// ListEntry is one container's row in the output of `harborbuddy list`
+type ListEntry struct {
+	Name     string            `json:"name"`
+	Image    string            `json:"image"`
+	Eligible bool              `json:"eligible"`
+	Reason   string            `json:"reason"`
+	Policy   string            `json:"policy"`           // Tag policy the image falls under
+	Labels   map[string]string `json:"labels,omitempty"` // com.harborbuddy.* labels set on the container
+}
+
+// List explains, for every running container, whether an update cycle would consider it and
+// why, without asking a registry
+func List(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) ([]ListEntry, error) {
+	containers, err := dockerClient.ListContainers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	pinSet := loadPins(cfg, logger)
+
+	entries := make([]ListEntry, 0, len(containers))
+	for _, c := range containers {
+		decision, _ := decide(c, cfg, pinSet)
+		entry := ListEntry{
+			Name:     c.Name,
+			Image:    c.Image,
+			Eligible: decision.Eligible,
+			Reason:   decision.Reason,
+			Policy:   PolicyFor(c.Image, cfg.Updates),
+		}
+		for key, value := range c.Labels {
+			if strings.HasPrefix(key, labelPrefix) {
+				if entry.Labels == nil {
+					entry.Labels = make(map[string]string)
+				}
+				entry.Labels[key] = value
+			}
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// decide applies the eligibility rules and pins an update cycle applies to a container. The
+// ceiling is the newest tag a pin lets an eligible container move to, if any.
+func decide(c docker.ContainerInfo, cfg config.Config, pinSet *pins.Set) (decision UpdateDecision, ceiling string) {
+	decision = DetermineEligibility(c, cfg.Updates)
+	if !decision.Eligible {
+		return decision, ""
+	}
+	if pin, ok := pinFor(pinSet, c); ok {
+		var frozen bool
+		ceiling, frozen = checkPin(c.Image, pin)
+		decision.Eligible = !frozen
+		decision.Reason = pin.String()
+	}
+	return decision, ceiling
+}
+
+// FormatList prints list entries as a table followed by a summary line
+func FormatList(w io.Writer, entries []ListEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No running containers.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tIMAGE\tELIGIBLE\tPOLICY\tREASON\tLABELS")
+	eligibleCount := 0
+	for _, e := range entries {
+		eligible := "no"
+		if e.Eligible {
+			eligible = "yes"
+			eligibleCount++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Name, e.Image, eligible, e.Policy, e.Reason, formatLabels(e.Labels))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d containers: %d eligible for updates\n", len(entries), eligibleCount)
+}
+
+// formatLabels prints labels as sorted "key=value" pairs without the HarborBuddy prefix
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, strings.TrimPrefix(key, labelPrefix)+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,71 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/rs/zerolog"
+)
+
+func TestList(t *testing.T) {
+	pinsFile := filepath.Join(t.TempDir(), "pins.yml")
+	if err := os.WriteFile(pinsFile, []byte("containers:\n  cache: \"7.2.1\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "c1", Name: "web", Image: "nginx:latest", Labels: map[string]string{"com.harborbuddy.depends-on": "db", "maintainer": "ops"}},
+		{ID: "c2", Name: "db", Image: "postgres:16", Labels: map[string]string{"com.harborbuddy.autoupdate": "false"}},
+		{ID: "c3", Name: "cache", Image: "redis:7.2.1"},
+		{ID: "c4", Name: "app", Image: "ghcr.io/acme/app:1.4.0"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.PinsFile = pinsFile
+	cfg.Updates.Policies = []config.PolicyRule{{Pattern: "ghcr.io/acme/*", Policy: config.PolicyMinor}}
+	logger := zerolog.Nop()
+
+	entries, err := List(context.Background(), cfg, mockClient, &logger)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	want := []struct {
+		name     string
+		eligible bool
+		reason   string
+		policy   string
+	}{
+		{"app", true, "eligible for updates", config.PolicyMinor},
+		{"cache", false, "pinned to 7.2.1", config.PolicyDigest},
+		{"db", false, "label com.harborbuddy.autoupdate=false", config.PolicyDigest},
+		{"web", true, "eligible for updates", config.PolicyDigest},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Name != w.name || e.Eligible != w.eligible || e.Reason != w.reason || e.Policy != w.policy {
+			t.Errorf("entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+	if _, ok := entries[3].Labels["maintainer"]; ok || entries[3].Labels["com.harborbuddy.depends-on"] != "db" {
+		t.Errorf("web labels = %v, want only the HarborBuddy ones", entries[3].Labels)
+	}
+
+	var out bytes.Buffer
+	FormatList(&out, entries)
+	for _, s := range []string{"NAME", "depends-on=db", "autoupdate=false", "4 containers: 2 eligible for updates"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("output missing %q:\n%s", s, out.String())
+		}
+	}
+}
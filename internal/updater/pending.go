@@ -0,0 +1,87 @@
+package updater
+
+import (
+	"context"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/rs/zerolog"
+)
+
+// announceAvailable sends the update_available notification of an update monitor-only leaves
+// alone. With the state store it is sent once per new image, then again only every
+// notifications.remind_after, and not at all when the daily digest reports pending updates.
+// Without the store every cycle sends it, as nothing remembers it was sent.
+func announceAvailable(ctx context.Context, cfg config.NotificationsConfig, store *state.Store, notifier notify.Notifier, event notify.Event, logger *zerolog.Logger) {
+	if store == nil {
+		notify.Send(ctx, notifier, event, logger)
+		return
+	}
+
+	now := time.Now()
+	pending, known := store.Pending(event.Container)
+	if !known || pending.NewImageID != event.NewImageID {
+		pending = state.Pending{FoundAt: now}
+	}
+	pending.Image = event.Image
+	pending.OldImageID, pending.NewImageID = event.OldImageID, event.NewImageID
+	pending.OldVersion, pending.NewVersion, pending.ReleaseURL = event.OldVersion, event.NewVersion, event.ReleaseURL
+
+	switch {
+	case cfg.Digest != "":
+		logger.Debug().Msg("Update available is left to the daily digest")
+	case pending.NotifiedAt.IsZero(), cfg.RemindAfter > 0 && now.Sub(pending.NotifiedAt) >= cfg.RemindAfter:
+		notify.Send(ctx, notifier, event, logger)
+		pending.NotifiedAt = now
+	default:
+		logger.Debug().Time("notified_at", pending.NotifiedAt).Msg("Update available was already notified, not repeating it")
+	}
+
+	if err := store.SetPending(event.Container, pending); err != nil {
+		logger.Warn().Err(err).Msg("Failed to remember the pending update")
+	}
+}
+
+// forgetPending drops a container's pending update once it is up to date or updated, so a
+// later update is notified again
+func forgetPending(store *state.Store, name string, logger *zerolog.Logger) {
+	if store == nil {
+		return
+	}
+	if err := store.ClearPending(name); err != nil {
+		logger.Warn().Err(err).Msg("Failed to forget the pending update")
+	}
+}
+
+// prunePending forgets the pending updates of containers that no longer exist, so a removed
+// container doesn't linger in the daily digest. running is the cycle's container list; stopped
+// containers still exist and keep theirs. Nothing is pruned if they can't be listed.
+func prunePending(ctx context.Context, dockerClient docker.Client, store *state.Store, running []docker.ContainerInfo, logger *zerolog.Logger) {
+	if store == nil || len(store.PendingUpdates()) == 0 {
+		return
+	}
+	stopped, err := dockerClient.ListStoppedContainers(ctx)
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to list stopped containers, keeping pending updates")
+		return
+	}
+
+	exists := make(map[string]bool, len(running)+len(stopped))
+	for _, c := range running {
+		exists[c.Name] = true
+	}
+	for _, c := range stopped {
+		exists[c.Name] = true
+	}
+	pruned, err := store.PrunePending(exists)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to forget the pending updates of removed containers")
+		return
+	}
+	if len(pruned) > 0 {
+		logger.Debug().Strs("containers", pruned).Msg("Forgot the pending updates of removed containers")
+	}
+}
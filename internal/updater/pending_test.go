@@ -0,0 +1,117 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/docker/docker/api/types"
+)
+
+func TestAnnounceAvailable(t *testing.T) {
+	logger := log.WithFields(nil)
+	ctx := context.Background()
+	event := func(newID string) notify.Event {
+		return notify.Event{Type: notify.EventUpdateAvailable, Container: "web", Image: "nginx:latest", OldImageID: "sha256:old", NewImageID: newID}
+	}
+	openStore := func(t *testing.T) *state.Store {
+		store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+
+	t.Run("once per new image", func(t *testing.T) {
+		store, recorder := openStore(t), &eventRecorder{}
+		cfg := config.Default().Notifications
+		announceAvailable(ctx, cfg, store, recorder, event("sha256:new1"), logger)
+		announceAvailable(ctx, cfg, store, recorder, event("sha256:new1"), logger)
+		if len(recorder.events) != 1 {
+			t.Fatalf("sent %d notifications for the same pending update, want 1", len(recorder.events))
+		}
+		announceAvailable(ctx, cfg, store, recorder, event("sha256:new2"), logger)
+		if len(recorder.events) != 2 {
+			t.Errorf("sent %d notifications, want another for the newer image", len(recorder.events))
+		}
+
+		forgetPending(store, "web", logger)
+		announceAvailable(ctx, cfg, store, recorder, event("sha256:new2"), logger)
+		if len(recorder.events) != 3 {
+			t.Errorf("sent %d notifications, want one again after the container was up to date", len(recorder.events))
+		}
+	})
+
+	t.Run("reminder", func(t *testing.T) {
+		store, recorder := openStore(t), &eventRecorder{}
+		cfg := config.Default().Notifications
+		cfg.RemindAfter = time.Hour
+		store.SetPending("web", state.Pending{NewImageID: "sha256:new1", NotifiedAt: time.Now().Add(-2 * time.Hour)})
+
+		announceAvailable(ctx, cfg, store, recorder, event("sha256:new1"), logger)
+		announceAvailable(ctx, cfg, store, recorder, event("sha256:new1"), logger)
+		if len(recorder.events) != 1 {
+			t.Errorf("sent %d notifications, want one reminder after remind_after", len(recorder.events))
+		}
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		store, recorder := openStore(t), &eventRecorder{}
+		cfg := config.Default().Notifications
+		cfg.Digest = "09:00"
+		announceAvailable(ctx, cfg, store, recorder, event("sha256:new1"), logger)
+		if len(recorder.events) != 0 {
+			t.Errorf("sent %d notifications, want them left to the digest", len(recorder.events))
+		}
+		if p, ok := store.Pending("web"); !ok || p.NewImageID != "sha256:new1" || p.Image != "nginx:latest" {
+			t.Errorf("Pending(web) = %+v, %v, want the update remembered for the digest", p, ok)
+		}
+	})
+
+	t.Run("without a state file", func(t *testing.T) {
+		recorder := &eventRecorder{}
+		cfg := config.Default().Notifications
+		announceAvailable(ctx, cfg, nil, recorder, event("sha256:new1"), logger)
+		announceAvailable(ctx, cfg, nil, recorder, event("sha256:new1"), logger)
+		if len(recorder.events) != 2 {
+			t.Errorf("sent %d notifications, want one every cycle without a store", len(recorder.events))
+		}
+	})
+}
+
+func TestPrunePending(t *testing.T) {
+	logger := log.WithFields(nil)
+	store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"web", "worker", "removed"} {
+		store.SetPending(name, state.Pending{Image: "nginx:latest", NewImageID: "sha256:new"})
+	}
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "worker-id", Name: "worker", State: &types.ContainerState{Status: "exited"}},
+	}
+	running := []docker.ContainerInfo{{ID: "web-id", Name: "web"}}
+
+	mockClient.ListExitedContainersError = errors.New("daemon unavailable")
+	prunePending(context.Background(), mockClient, store, running, logger)
+	if got := store.PendingUpdates(); len(got) != 3 {
+		t.Errorf("PendingUpdates() = %v, want nothing pruned when stopped containers can't be listed", got)
+	}
+
+	mockClient.ListExitedContainersError = nil
+	prunePending(context.Background(), mockClient, store, running, logger)
+	got := store.PendingUpdates()
+	if _, ok := got["removed"]; ok || len(got) != 2 {
+		t.Errorf("PendingUpdates() = %v, want web and the stopped worker only", got)
+	}
+}
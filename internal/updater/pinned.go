@@ -0,0 +1,93 @@
+package updater
+
+import (
+	"context"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/rs/zerolog"
+)
+
+// reasonPinnedByDigest is why containers started from an image pinned by digest are skipped
+const reasonPinnedByDigest = "pinned by digest"
+
+// pinnedByDigest reports whether a container runs an image reference pinned to a manifest
+// digest, such as nginx@sha256:... or nginx:1.25@sha256:...
+func pinnedByDigest(image string) bool {
+	return strings.Contains(image, "@")
+}
+
+// pinnedTag returns the tagged reference a container pinned by digest follows: the tag written
+// next to the digest (nginx:1.25@sha256:...), or else a tag of the same repository its image
+// was also pulled as. It returns "" when neither is known.
+func pinnedTag(ctx context.Context, dockerClient docker.Client, c docker.ContainerInfo) string {
+	name, _, _ := strings.Cut(c.Image, "@")
+	if repo, _, _ := splitImageTag(name); repo != name {
+		return name
+	}
+
+	local, err := dockerClient.InspectImage(ctx, c.ImageID)
+	if err != nil {
+		return ""
+	}
+	for _, tag := range local.RepoTags {
+		if repo, _, ok := splitImageTag(tag); ok && repo == name {
+			return tag
+		}
+	}
+	return ""
+}
+
+// suggestDigest looks up what a container pinned by digest would run if it followed its tag,
+// moved by its update policy, for updates.suggest_digests. A newer digest is logged, listed in
+// the cycle report and announced like any update available, so notifications.remind_after
+// and the daily digest apply; the container is never replaced.
+func suggestDigest(ctx context.Context, reg Registry, dockerClient docker.Client, c docker.ContainerInfo, cfg config.Config, store *state.Store, notifier notify.Notifier, logger *zerolog.Logger) {
+	tagged := pinnedTag(ctx, dockerClient, c)
+	if tagged == "" {
+		logger.Debug().Str("image", c.Image).Msg("No tag known for the pinned digest, can't suggest a newer one")
+		return
+	}
+
+	target, err := resolveTarget(ctx, reg, tagged, PolicyFor(tagged, cfg.Updates), "")
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to list registry tags, checking the pinned tag only")
+	}
+	latest, err := reg.ManifestDigest(ctx, target)
+	if err != nil {
+		logger.Warn().Err(err).Str("image", target).Msg("Failed to look up a newer digest for the pinned image")
+		return
+	}
+	if _, pinned, _ := strings.Cut(c.Image, "@"); latest == pinned {
+		logger.Debug().Str("image", c.Image).Msgf("Pinned digest is still the one %s serves", target)
+		forgetPending(store, c.Name, logger)
+		return
+	}
+
+	suggested := target + "@" + latest
+	logger.Info().
+		Str("image", c.Image).
+		Str("suggested", suggested).
+		Msg("📌 Newer digest available for image pinned by digest, not applied")
+	// The new image isn't pulled, so the suggested digest stands in for its ID: the pending
+	// update is announced again only once a different digest is suggested
+	announceAvailable(ctx, cfg.Notifications, store, notifier, notify.Event{
+		Type:       notify.EventUpdateAvailable,
+		Outcome:    notify.OutcomeNotApplied,
+		Container:  c.Name,
+		Image:      c.Image,
+		OldImageID: c.ImageID,
+		NewImageID: latest,
+		Target:     suggested,
+	}, logger)
+	report.FromContext(ctx).AddAvailable(report.Container{
+		Name:       c.Name,
+		Image:      c.Image,
+		Target:     suggested,
+		OldImageID: c.ImageID,
+	})
+}
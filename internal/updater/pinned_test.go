@@ -0,0 +1,107 @@
+package updater
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+const (
+	pinnedDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	newerDigest  = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func TestDetermineEligibility_PinnedByDigest(t *testing.T) {
+	for _, image := range []string{"nginx@" + pinnedDigest, "nginx:1.25@" + pinnedDigest, "localhost:5000/app@" + pinnedDigest} {
+		decision := DetermineEligibility(docker.ContainerInfo{Name: "web", Image: image}, config.Default().Updates)
+		if decision.Eligible || decision.Reason != reasonPinnedByDigest {
+			t.Errorf("DetermineEligibility(%s) = %+v, want it skipped as pinned by digest", image, decision)
+		}
+		if forceEligible(docker.ContainerInfo{Image: image}, config.Default().Updates) {
+			t.Errorf("forceEligible(%s) = true, want a forced update to leave it alone", image)
+		}
+	}
+}
+
+func TestPinnedTag(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:nginx", RepoTags: []string{"mirror.local/nginx:1.25", "nginx:1.25"}},
+	}
+
+	tests := []struct {
+		name  string
+		image string
+		id    string
+		want  string
+	}{
+		{"tag next to the digest", "redis:7.2@" + pinnedDigest, "sha256:redis", "redis:7.2"},
+		{"tag of the local image", "nginx@" + pinnedDigest, "sha256:nginx", "nginx:1.25"},
+		{"no tag known", "postgres@" + pinnedDigest, "sha256:postgres", ""},
+		{"registry port is not a tag", "localhost:5000/app@" + pinnedDigest, "sha256:app", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := docker.ContainerInfo{Image: tt.image, ImageID: tt.id}
+			if got := pinnedTag(context.Background(), mockClient, c); got != tt.want {
+				t.Errorf("pinnedTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_SuggestDigests(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:1.25@" + pinnedDigest, ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:1.25@" + pinnedDigest}},
+		{ID: "container2", Name: "cache", Image: "redis:latest@" + pinnedDigest, ImageID: "sha256:old-redis", Config: &container.Config{Image: "redis:latest@" + pinnedDigest}},
+	}
+	withRegistry(t, stubRegistry{
+		digests: map[string]string{"nginx:1.27": newerDigest, "redis:latest": pinnedDigest},
+		tags:    map[string][]string{"nginx:1.25": {"1.25", "1.26", "1.27"}},
+	})
+
+	cfg := config.Default()
+	cfg.Updates.SuggestDigests = true
+	cfg.Updates.Policies = []config.PolicyRule{{Pattern: "nginx:*", Policy: config.PolicyMinor}}
+	cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+	recorder := &eventRecorder{}
+	rep := report.New("abcd1234", false, false)
+	ctx := report.WithReport(notify.WithNotifier(context.Background(), recorder), rep)
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(ctx, cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.PulledImages) != 0 || len(mockClient.ReplacedContainers) != 0 {
+		t.Errorf("pinned containers were pulled %v or replaced %v", mockClient.PulledImages, mockClient.ReplacedContainers)
+	}
+
+	want := "nginx:1.27@" + newerDigest
+	if len(rep.Available) != 1 || rep.Available[0].Name != "web" || rep.Available[0].Target != want {
+		t.Errorf("available = %+v, want web with %s", rep.Available, want)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Type != notify.EventUpdateAvailable || recorder.events[0].Target != want {
+		t.Errorf("events = %+v, want one update available for web", recorder.events)
+	}
+	for _, skipped := range rep.Skipped {
+		if skipped.Reason != reasonPinnedByDigest {
+			t.Errorf("%s skipped for %q, want %q", skipped.Name, skipped.Reason, reasonPinnedByDigest)
+		}
+	}
+
+	// Like any update available, the suggestion is announced once per new digest
+	if err := RunUpdateCycle(ctx, cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+	if len(recorder.events) != 1 {
+		t.Errorf("events = %+v, want the same suggestion not notified again", recorder.events)
+	}
+}
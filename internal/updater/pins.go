@@ -0,0 +1,116 @@
+package updater
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/pins"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/rs/zerolog"
+)
+
+// pinLoaders keeps each pins file loaded between cycles, so it is only re-read after it changes
+var pinLoaders sync.Map // path -> *pins.Loader
+
+// loadPins returns the pins in force for this cycle, logging when the file was (re)loaded,
+// with the containers frozen in the state file pinned where they are. A file that fails to
+// parse leaves the previous pins in force.
+func loadPins(cfg config.Config, logger *zerolog.Logger) *pins.Set {
+	var set *pins.Set
+	if cfg.Updates.PinsFile != "" {
+		loader, _ := pinLoaders.LoadOrStore(cfg.Updates.PinsFile, pins.NewLoader(cfg.Updates.PinsFile))
+		var reloaded bool
+		var err error
+		set, reloaded, err = loader.(*pins.Loader).Load()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to load pins")
+		} else if reloaded {
+			logger.Info().Msgf("📌 Loaded %d pins from %s", set.Len(), cfg.Updates.PinsFile)
+		}
+	}
+	return set.WithFreezes(loadFreezes(cfg, logger))
+}
+
+// loadFreezes returns the freezes in force, as pins keyed by container name
+func loadFreezes(cfg config.Config, logger *zerolog.Logger) map[string]pins.Pin {
+	if cfg.State.File == "" {
+		return nil
+	}
+	store, err := state.Open(cfg.State.File)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load frozen containers")
+		return nil
+	}
+	freezes := make(map[string]pins.Pin)
+	for name, f := range store.Frozen(time.Now()) {
+		freezes[name] = pins.Pin{Reason: f.Reason, Until: f.Until}
+	}
+	return freezes
+}
+
+// pinFor finds the pin for a container: by name first, then by image repository, then by
+// image pattern in sorted order so the choice is stable
+func pinFor(set *pins.Set, container docker.ContainerInfo) (pins.Pin, bool) {
+	if set == nil {
+		return pins.Pin{}, false
+	}
+	if pin, ok := set.Containers[container.Name]; ok {
+		return pin, true
+	}
+	if repo, _, ok := splitImageTag(container.Image); ok {
+		if pin, ok := set.Images[repo]; ok {
+			return pin, true
+		}
+	}
+
+	patterns := make([]string, 0, len(set.Images))
+	for pattern := range set.Images {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if matchesPattern(container.Image, pattern) {
+			return set.Images[pattern], true
+		}
+	}
+	return pins.Pin{}, false
+}
+
+// checkPin decides what a pin allows for a container running image. A freeze, a digest pin,
+// or a tag pin on the tag the container already runs, freezes it (tags can be re-pushed). A container
+// on an older version of the pinned tag may still move, but no further than ceiling. Anything
+// else is frozen too, since there is no telling whether an update would move past the pin.
+func checkPin(image string, pin pins.Pin) (ceiling string, frozen bool) {
+	if pin.Freeze || pin.IsDigest() {
+		return "", true
+	}
+	_, tag, ok := splitImageTag(image)
+	if !ok || tag == pin.Ref {
+		return "", true
+	}
+
+	current, okCurrent := parseVersion(tag)
+	pinned, okPinned := parseVersion(pin.Ref)
+	if okCurrent && okPinned && current.sameFlavour(pinned) && current.compare(pinned) < 0 {
+		return pin.Ref, false
+	}
+	return "", true
+}
+
+// tagsUpTo drops the tags newer than ceiling, or formatted differently from it
+func tagsUpTo(tags []string, ceiling string) []string {
+	limit, ok := parseVersion(ceiling)
+	if !ok {
+		return nil
+	}
+	kept := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if v, ok := parseVersion(tag); ok && v.sameFlavour(limit) && v.compare(limit) <= 0 {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
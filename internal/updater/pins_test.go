@@ -0,0 +1,113 @@
+package updater
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/pins"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestCheckPin(t *testing.T) {
+	tests := []struct {
+		name        string
+		image       string
+		pin         string
+		wantCeiling string
+		wantFrozen  bool
+	}{
+		{"digest pin", "postgres:16.2", "sha256:4f1c", "", true},
+		{"already on the pinned tag", "postgres:16.2", "16.2", "", true},
+		{"older version may move up to the pin", "nginx:1.25.1", "1.25.3", "1.25.3", false},
+		{"newer than the pin", "nginx:1.26.0", "1.25.3", "", true},
+		{"different flavour", "nginx:1.25.1-alpine", "1.25.3", "", true},
+		{"moving tag", "nginx:latest", "1.25.3", "", true},
+		{"digest reference", "nginx@sha256:abc", "1.25.3", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ceiling, frozen := checkPin(tt.image, pins.Pin{Ref: tt.pin})
+			if ceiling != tt.wantCeiling || frozen != tt.wantFrozen {
+				t.Errorf("checkPin(%q, %q) = %q, %v; want %q, %v", tt.image, tt.pin, ceiling, frozen, tt.wantCeiling, tt.wantFrozen)
+			}
+		})
+	}
+}
+
+func TestPinFor(t *testing.T) {
+	set := &pins.Set{
+		Containers: map[string]pins.Pin{"web": {Ref: "1.0"}},
+		Images: map[string]pins.Pin{
+			"ghcr.io/acme/api": {Ref: "2.0"},
+			"ghcr.io/acme/*":   {Ref: "3.0"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		image   string
+		wantRef string
+	}{
+		{"web", "ghcr.io/acme/api:1.5", "1.0"},
+		{"api", "ghcr.io/acme/api:1.5", "2.0"},
+		{"worker", "ghcr.io/acme/worker:1.5", "3.0"},
+		{"db", "postgres:16.2", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pin, ok := pinFor(set, docker.ContainerInfo{Name: tt.name, Image: tt.image})
+			if ok != (tt.wantRef != "") || pin.Ref != tt.wantRef {
+				t.Errorf("pinFor(%s) = %+v, %v; want %q", tt.name, pin, ok, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_Pins(t *testing.T) {
+	withRegistry(t, stubRegistry{tags: map[string][]string{
+		"nginx:1.25.1": {"1.25.1", "1.25.2", "1.25.3", "1.25.4"},
+	}})
+
+	pinsFile := filepath.Join(t.TempDir(), "pins.yml")
+	err := os.WriteFile(pinsFile, []byte(`
+containers:
+  db:
+    pin: "16.2"
+    reason: incident review
+images:
+  nginx: "1.25.3"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:1.25.1", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:1.25.1"}},
+		{ID: "container2", Name: "db", Image: "postgres:16.2", ImageID: "sha256:old-postgres", Config: &container.Config{Image: "postgres:16.2"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:1.25.3":  {ID: "sha256:nginx-1.25.3"},
+		"postgres:16.2": {ID: "sha256:new-postgres"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.Policy = config.PolicyPatch
+	cfg.Updates.PinsFile = pinsFile
+
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.PulledImages) != 1 || mockClient.PulledImages[0] != "nginx:1.25.3" {
+		t.Errorf("pulled %v, want only nginx:1.25.3 (capped by the pin, db frozen)", mockClient.PulledImages)
+	}
+}
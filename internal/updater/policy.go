@@ -0,0 +1,190 @@
+package updater
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+// PolicyFor returns the update policy for an image: the first matching per-image rule,
+// otherwise the global policy
+func PolicyFor(image string, cfg config.UpdatesConfig) string {
+	for _, rule := range cfg.Policies {
+		if matchesPattern(image, rule.Pattern) {
+			return rule.Policy
+		}
+	}
+	if cfg.Policy == "" {
+		return config.PolicyDigest
+	}
+	return cfg.Policy
+}
+
+// version is a numeric version tag such as "v1.25.3-alpine".
+// Prefix and suffix are kept so only tags of the same flavour are compared.
+type version struct {
+	prefix  string
+	parts   []int
+	suffix  string
+	literal string
+}
+
+// parseVersion parses tags like "1.25", "v2.0.1" or "1.25.3-alpine"; ok is false for
+// tags that aren't versions ("latest", "stable", "bookworm")
+func parseVersion(tag string) (version, bool) {
+	v := version{literal: tag}
+
+	rest := tag
+	if strings.HasPrefix(rest, "v") {
+		v.prefix, rest = "v", rest[1:]
+	}
+
+	numeric := rest
+	if i := strings.IndexAny(rest, "-+_"); i >= 0 {
+		numeric, v.suffix = rest[:i], rest[i:]
+	}
+
+	fields := strings.Split(numeric, ".")
+	if len(fields) > 3 {
+		return version{}, false
+	}
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.parts = append(v.parts, n)
+	}
+	return v, true
+}
+
+// sameFlavour reports whether o is formatted like v ("1.25-alpine" vs "1.26-alpine"),
+// which keeps prereleases and variants out of the comparison
+func (v version) sameFlavour(o version) bool {
+	return v.prefix == o.prefix && v.suffix == o.suffix && len(v.parts) == len(o.parts)
+}
+
+// compare returns -1, 0 or 1 as v is older than, equal to or newer than o
+func (v version) compare(o version) int {
+	for i := range v.parts {
+		switch {
+		case v.parts[i] < o.parts[i]:
+			return -1
+		case v.parts[i] > o.parts[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// allows reports whether moving from v to candidate stays within the policy.
+// Components the tag doesn't have can't change: "1.25" has no patch level to bump.
+func (v version) allows(candidate version, policy string) bool {
+	fixed := 0
+	switch policy {
+	case config.PolicyPatch:
+		fixed = 2
+	case config.PolicyMinor:
+		fixed = 1
+	case config.PolicyMajor:
+		fixed = 0
+	default:
+		return false
+	}
+
+	for i := 0; i < fixed && i < len(v.parts); i++ {
+		if candidate.parts[i] != v.parts[i] {
+			return false
+		}
+	}
+	return fixed < len(v.parts)
+}
+
+// newestTag returns the newest tag the policy allows moving to from current,
+// or "" if current is already the newest (or isn't a version at all)
+func newestTag(current string, tags []string, policy string) string {
+	cur, ok := parseVersion(current)
+	if !ok {
+		return ""
+	}
+
+	best := cur
+	for _, tag := range tags {
+		candidate, ok := parseVersion(tag)
+		if !ok || !cur.sameFlavour(candidate) || !cur.allows(candidate, policy) {
+			continue
+		}
+		if candidate.compare(best) > 0 {
+			best = candidate
+		}
+	}
+
+	if best.literal == current {
+		return ""
+	}
+	return best.literal
+}
+
+// splitImageTag splits "repo:tag" without mistaking a registry port for a tag.
+// Digest-pinned references return ok=false.
+func splitImageTag(image string) (repo, tag string, ok bool) {
+	if strings.Contains(image, "@") {
+		return "", "", false
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return image, "latest", true
+	}
+	return image[:i], image[i+1:], true
+}
+
+// trackedImage returns the image a container follows: its repository at the tag
+// updates.track gives it, if any, otherwise the image it runs. Images pinned by digest are
+// left alone.
+func trackedImage(image string, track config.Track) string {
+	repo, _, ok := splitImageTag(image)
+	if !ok {
+		return image
+	}
+	if tag, tracked := track[repo]; tracked {
+		return repo + ":" + tag
+	}
+	return image
+}
+
+// TagLister lists the tags available for an image's repository
+type TagLister interface {
+	ListTags(ctx context.Context, imageRef string) ([]string, error)
+}
+
+// resolveTarget returns the image reference the container should run under its policy:
+// a newer version tag if one is allowed, otherwise the container's current reference.
+// A non-empty ceiling (a pinned tag) caps how far the tag may move.
+func resolveTarget(ctx context.Context, lister TagLister, image, policy, ceiling string) (string, error) {
+	if policy == config.PolicyDigest {
+		return image, nil
+	}
+
+	repo, tag, ok := splitImageTag(image)
+	if !ok {
+		return image, nil
+	}
+	if _, isVersion := parseVersion(tag); !isVersion {
+		return image, nil
+	}
+
+	tags, err := lister.ListTags(ctx, image)
+	if err != nil {
+		return image, err
+	}
+
+	if ceiling != "" {
+		tags = tagsUpTo(tags, ceiling)
+	}
+	if newer := newestTag(tag, tags, policy); newer != "" {
+		return repo + ":" + newer, nil
+	}
+	return image, nil
+}
@@ -0,0 +1,195 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestPolicyFor(t *testing.T) {
+	cfg := config.UpdatesConfig{
+		Policy: config.PolicyMinor,
+		Policies: []config.PolicyRule{
+			{Pattern: "postgres:*", Policy: config.PolicyPatch},
+			{Pattern: "*:latest", Policy: config.PolicyDigest},
+		},
+	}
+
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"postgres:16.2", config.PolicyPatch},
+		{"nginx:latest", config.PolicyDigest},
+		{"nginx:1.25", config.PolicyMinor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := PolicyFor(tt.image, cfg); got != tt.expected {
+				t.Errorf("PolicyFor(%q) = %q, want %q", tt.image, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewestTag(t *testing.T) {
+	tags := []string{
+		"latest", "1.24.0", "1.25.3", "1.25.4", "1.25.10", "1.26.0", "2.0.0", "2.0.0-rc1",
+		"1.25", "1.26", "2.0", "1.25.4-alpine", "1.26.1-alpine", "v1.25.5",
+	}
+
+	tests := []struct {
+		name     string
+		current  string
+		policy   string
+		expected string
+	}{
+		{"patch stays in minor", "1.25.3", config.PolicyPatch, "1.25.10"},
+		{"minor stays in major", "1.25.3", config.PolicyMinor, "1.26.0"},
+		{"major takes newest", "1.25.3", config.PolicyMajor, "2.0.0"},
+		{"prereleases ignored", "1.26.0", config.PolicyMajor, "2.0.0"},
+		{"already newest", "2.0.0", config.PolicyMajor, ""},
+		{"variant suffix kept", "1.25.4-alpine", config.PolicyMinor, "1.26.1-alpine"},
+		{"two-part tag has no patch level", "1.25", config.PolicyPatch, ""},
+		{"two-part tag minor", "1.25", config.PolicyMinor, "1.26"},
+		{"v prefix kept", "v1.25.3", config.PolicyPatch, "v1.25.5"},
+		{"non-version tag", "latest", config.PolicyMajor, ""},
+		{"digest policy never moves", "1.25.3", config.PolicyDigest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newestTag(tt.current, tags, tt.policy); got != tt.expected {
+				t.Errorf("newestTag(%q, %s) = %q, want %q", tt.current, tt.policy, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitImageTag(t *testing.T) {
+	tests := []struct {
+		image string
+		repo  string
+		tag   string
+		ok    bool
+	}{
+		{"nginx:1.25", "nginx", "1.25", true},
+		{"nginx", "nginx", "latest", true},
+		{"registry.example.com:5000/app", "registry.example.com:5000/app", "latest", true},
+		{"registry.example.com:5000/app:v2", "registry.example.com:5000/app", "v2", true},
+		{"nginx@sha256:abc", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			repo, tag, ok := splitImageTag(tt.image)
+			if repo != tt.repo || tag != tt.tag || ok != tt.ok {
+				t.Errorf("splitImageTag(%q) = %q, %q, %v; want %q, %q, %v", tt.image, repo, tag, ok, tt.repo, tt.tag, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTrackedImage(t *testing.T) {
+	track := config.Track{"nginx": "1.25-alpine", "ghcr.io/org/app": "stable"}
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"nginx", "nginx:1.25-alpine"},
+		{"nginx:latest", "nginx:1.25-alpine"},
+		{"ghcr.io/org/app:latest", "ghcr.io/org/app:stable"},
+		{"ghcr.io/org/app-worker:latest", "ghcr.io/org/app-worker:latest"},
+		{"redis:7", "redis:7"},
+		{"nginx@sha256:abc", "nginx@sha256:abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := trackedImage(tt.image, track); got != tt.want {
+				t.Errorf("trackedImage(%q) = %q, want %q", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_TrackRetags(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:1.25-alpine": {ID: "sha256:nginx-1.25-alpine"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.Track = config.Track{"nginx": "1.25-alpine"}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.PulledImages) != 1 || mockClient.PulledImages[0] != "nginx:1.25-alpine" {
+		t.Errorf("pulled %v, want [nginx:1.25-alpine]", mockClient.PulledImages)
+	}
+	if len(mockClient.CreatedContainers) != 1 || mockClient.CreatedContainers[0].NewImage != "nginx:1.25-alpine" {
+		t.Errorf("created %+v, want one container from nginx:1.25-alpine", mockClient.CreatedContainers)
+	}
+}
+
+func TestRunUpdateCycle_MinorPolicyRetags(t *testing.T) {
+	withRegistry(t, stubRegistry{tags: map[string][]string{
+		"nginx:1.25.3": {"1.25.3", "1.25.4", "1.26.1", "2.0.0"},
+	}})
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:1.25.3", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:1.25.3"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:1.26.1": {ID: "sha256:nginx-1.26.1"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.Policy = config.PolicyMinor
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.PulledImages) != 1 || mockClient.PulledImages[0] != "nginx:1.26.1" {
+		t.Errorf("pulled %v, want [nginx:1.26.1]", mockClient.PulledImages)
+	}
+	if len(mockClient.CreatedContainers) != 1 || mockClient.CreatedContainers[0].NewImage != "nginx:1.26.1" {
+		t.Errorf("created %+v, want one container from nginx:1.26.1", mockClient.CreatedContainers)
+	}
+}
+
+func TestRunUpdateCycle_PolicyTagListFailure(t *testing.T) {
+	withRegistry(t, stubRegistry{})
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:1.25.3", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:1.25.3"}},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.Policy = config.PolicyMajor
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	// Falls back to following the pinned tag
+	if len(mockClient.PulledImages) != 1 || mockClient.PulledImages[0] != "nginx:1.25.3" {
+		t.Errorf("pulled %v, want [nginx:1.25.3]", mockClient.PulledImages)
+	}
+}
@@ -0,0 +1,180 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/watchdog"
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/rs/zerolog"
+)
+
+// pullRetryDelay is the wait before the first retry of a failed pull, doubled for each
+// further retry up to pullRetryMaxDelay; variables for tests
+var (
+	pullRetryDelay    = 2 * time.Second
+	pullRetryMaxDelay = time.Minute
+)
+
+// transientPullMessages mark pull errors worth another try when the daemon didn't classify them,
+// as with errors reported in the middle of the pull stream
+var transientPullMessages = []string{
+	"toomanyrequests",
+	"too many requests",
+	"rate limit",
+	"timeout",
+	"timed out",
+	"connection reset",
+	"connection refused",
+	"no such host",
+	"temporary failure in name resolution",
+	"unexpected eof",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+}
+
+// pullLimiter bounds how many pulls run at once, overall and per registry host, so many
+// images updating together don't saturate bandwidth or trip a registry's rate limit
+type pullLimiter struct {
+	global     chan struct{}            // nil when unlimited
+	registries map[string]chan struct{} // keyed by registry host
+}
+
+// newPullLimiter applies updates.max_concurrent_pulls and updates.registry_pull_limits
+func newPullLimiter(cfg config.UpdatesConfig) *pullLimiter {
+	l := &pullLimiter{registries: make(map[string]chan struct{})}
+	if cfg.MaxConcurrentPulls > 0 {
+		l.global = make(chan struct{}, cfg.MaxConcurrentPulls)
+	}
+	for host, limit := range cfg.RegistryPullLimits {
+		if limit > 0 {
+			l.registries[docker.NormalizeRegistryHost(host)] = make(chan struct{}, limit)
+		}
+	}
+	return l
+}
+
+// acquire waits for a slot to pull image and returns the function releasing it. The registry
+// slot is taken first, so pulls queued for a busy registry don't hold up the others.
+func (l *pullLimiter) acquire(ctx context.Context, image string, logger *zerolog.Logger) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	host := docker.RegistryHost(image)
+	slots := make([]chan struct{}, 0, 2)
+	if registry, ok := l.registries[host]; ok {
+		slots = append(slots, registry)
+	}
+	if l.global != nil {
+		slots = append(slots, l.global)
+	}
+
+	release := func(taken []chan struct{}) func() {
+		return func() {
+			for _, slot := range taken {
+				<-slot
+			}
+		}
+	}
+	for i, slot := range slots {
+		select {
+		case slot <- struct{}{}:
+			continue
+		default:
+		}
+		logger.Debug().Str("image", image).Str("registry", host).Msg("Waiting for a pull slot")
+		select {
+		case slot <- struct{}{}:
+		case <-ctx.Done():
+			release(slots[:i])()
+			return nil, ctx.Err()
+		}
+	}
+	return release(slots), nil
+}
+
+// pullImage pulls image, bounding each attempt by updates.pull_timeout and retrying transient
+// failures (registry 5xx, rate limits, DNS and connection errors) up to updates.pull_retries
+// times with exponential backoff. Permanent failures such as a missing tag or denied access
+// are returned right away. Each attempt holds a slot of limiter, which may be nil.
+func pullImage(ctx context.Context, dockerClient docker.Client, image string, cfg config.UpdatesConfig, limiter *pullLimiter, logger *zerolog.Logger) (docker.ImageInfo, error) {
+	delay := pullRetryDelay
+	for attempt := 0; ; attempt++ {
+		release, err := limiter.acquire(ctx, image, logger)
+		if err != nil {
+			return docker.ImageInfo{}, err
+		}
+		info, err := pullOnce(ctx, dockerClient, image, cfg.PullTimeout, logger)
+		release()
+		if err == nil || attempt >= cfg.PullRetries || ctx.Err() != nil || !transientPullError(err) {
+			return info, err
+		}
+
+		logger.Warn().
+			Err(err).
+			Str("image", image).
+			Int("attempt", attempt+1).
+			Str("retry_in", delay.String()).
+			Msg("Pull failed, retrying")
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return docker.ImageInfo{}, err
+		}
+		delay = min(delay*2, pullRetryMaxDelay)
+	}
+}
+
+// pullOnce makes one pull attempt, given at most timeout (0 means no limit)
+func pullOnce(ctx context.Context, dockerClient docker.Client, image string, timeout time.Duration, logger *zerolog.Logger) (docker.ImageInfo, error) {
+	defer watchdog.Begin(ctx, "pulling "+image)()
+
+	cycleCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Layer progress is logged through the context logger
+	info, err := dockerClient.PullImage(logger.WithContext(ctx), image)
+	// The cycle's own deadline (updates.cycle_timeout) can expire here too; that isn't the pull's
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && cycleCtx.Err() == nil {
+		return info, fmt.Errorf("pull did not finish within %v: %w", timeout, err)
+	}
+	return info, err
+}
+
+// transientPullError reports whether a failed pull may succeed if tried again
+func transientPullError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || cerrdefs.IsDeadlineExceeded(err) {
+		return true
+	}
+	if cerrdefs.IsNotFound(err) || cerrdefs.IsUnauthorized(err) || cerrdefs.IsPermissionDenied(err) || cerrdefs.IsInvalidArgument(err) {
+		return false
+	}
+	if cerrdefs.IsUnavailable(err) || cerrdefs.IsInternal(err) || cerrdefs.IsResourceExhausted(err) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, transient := range transientPullMessages {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
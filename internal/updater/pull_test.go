@@ -0,0 +1,227 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/rs/zerolog"
+)
+
+// flakyPullClient fails the first pulls with err, then pulls normally
+type flakyPullClient struct {
+	*docker.MockDockerClient
+	failures int
+	err      error
+	attempts int
+}
+
+func (f *flakyPullClient) PullImage(ctx context.Context, image string) (docker.ImageInfo, error) {
+	f.attempts++
+	if f.attempts <= f.failures {
+		return docker.ImageInfo{}, f.err
+	}
+	return f.MockDockerClient.PullImage(ctx, image)
+}
+
+// slowPullClient blocks until the pull is cancelled
+type slowPullClient struct {
+	*docker.MockDockerClient
+}
+
+func (s *slowPullClient) PullImage(ctx context.Context, image string) (docker.ImageInfo, error) {
+	<-ctx.Done()
+	return docker.ImageInfo{}, ctx.Err()
+}
+
+func TestPullImage_Retries(t *testing.T) {
+	defer func(d time.Duration) { pullRetryDelay = d }(pullRetryDelay)
+	pullRetryDelay = time.Millisecond
+
+	rateLimited := fmt.Errorf("failed to pull image nginx:latest: %w", errors.New("toomanyrequests: You have reached your pull rate limit"))
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		retries      int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"transient failure retried", 2, rateLimited, 3, false, 3},
+		{"registry 5xx retried", 1, cerrdefs.ErrUnavailable, 3, false, 2},
+		{"retries exhausted", 5, rateLimited, 2, true, 3},
+		{"no retries configured", 1, rateLimited, 0, true, 1},
+		{"missing image not retried", 1, fmt.Errorf("failed to pull image: %w", cerrdefs.ErrNotFound), 3, true, 1},
+		{"denied access not retried", 1, cerrdefs.ErrUnauthenticated, 3, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &flakyPullClient{MockDockerClient: docker.NewMockDockerClient(), failures: tt.failures, err: tt.err}
+			cfg := config.Default().Updates
+			cfg.PullRetries = tt.retries
+			logger := zerolog.Nop()
+
+			_, err := pullImage(context.Background(), client, "nginx:latest", cfg, nil, &logger)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("pullImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if client.attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", client.attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestPullImage_Timeout(t *testing.T) {
+	defer func(d time.Duration) { pullRetryDelay = d }(pullRetryDelay)
+	pullRetryDelay = time.Millisecond
+
+	cfg := config.Default().Updates
+	cfg.PullTimeout = 10 * time.Millisecond
+	cfg.PullRetries = 1
+	logger := zerolog.Nop()
+
+	start := time.Now()
+	_, err := pullImage(context.Background(), &slowPullClient{docker.NewMockDockerClient()}, "nginx:latest", cfg, nil, &logger)
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("pullImage() error = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("pullImage() took %v, want each attempt cut off at the pull timeout", elapsed)
+	}
+}
+
+func TestPullImage_StopsOnCancel(t *testing.T) {
+	defer func(d time.Duration) { pullRetryDelay = d }(pullRetryDelay)
+	pullRetryDelay = time.Hour
+
+	client := &flakyPullClient{MockDockerClient: docker.NewMockDockerClient(), failures: 5, err: cerrdefs.ErrUnavailable}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	logger := zerolog.Nop()
+
+	if _, err := pullImage(ctx, client, "nginx:latest", config.Default().Updates, nil, &logger); err == nil {
+		t.Fatal("pullImage() succeeded, want the pull error once cancelled")
+	}
+	if client.attempts != 1 {
+		t.Errorf("attempts = %d, want no retry after cancellation", client.attempts)
+	}
+}
+
+// countingPullClient records the most pulls in flight at once, overall and per image
+type countingPullClient struct {
+	*docker.MockDockerClient
+	mu       sync.Mutex
+	inFlight map[string]int
+	peak     map[string]int
+	total    atomic.Int32
+	peakAll  atomic.Int32
+}
+
+func (c *countingPullClient) PullImage(ctx context.Context, image string) (docker.ImageInfo, error) {
+	host := docker.RegistryHost(image)
+	c.mu.Lock()
+	c.inFlight[host]++
+	c.peak[host] = max(c.peak[host], c.inFlight[host])
+	c.mu.Unlock()
+	n := c.total.Add(1)
+	for {
+		peak := c.peakAll.Load()
+		if n <= peak || c.peakAll.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	c.total.Add(-1)
+	c.mu.Lock()
+	c.inFlight[host]--
+	c.mu.Unlock()
+	return docker.ImageInfo{ID: "sha256:" + image}, nil
+}
+
+func TestPullImage_Limits(t *testing.T) {
+	logger := zerolog.Nop()
+	client := &countingPullClient{
+		MockDockerClient: docker.NewMockDockerClient(),
+		inFlight:         make(map[string]int),
+		peak:             make(map[string]int),
+	}
+	cfg := config.Default().Updates
+	cfg.MaxConcurrentPulls = 3
+	cfg.RegistryPullLimits = map[string]int{"docker.io": 1}
+	limiter := newPullLimiter(cfg)
+
+	images := []string{
+		"nginx:latest", "redis:7", "postgres:16",
+		"ghcr.io/a/one:1", "ghcr.io/a/two:1", "ghcr.io/a/three:1", "ghcr.io/a/four:1",
+	}
+	var wg sync.WaitGroup
+	for _, image := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := pullImage(context.Background(), client, image, cfg, limiter, &logger); err != nil {
+				t.Errorf("pullImage(%s) failed: %v", image, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := client.peak["docker.io"]; got != 1 {
+		t.Errorf("docker.io pulls at once = %d, want 1", got)
+	}
+	if got := client.peakAll.Load(); got > 3 {
+		t.Errorf("pulls at once = %d, want at most 3", got)
+	}
+}
+
+func TestPullLimiter_AcquireStopsOnCancel(t *testing.T) {
+	logger := zerolog.Nop()
+	cfg := config.Default().Updates
+	cfg.RegistryPullLimits = map[string]int{"docker.io": 1}
+	cfg.MaxConcurrentPulls = 2
+	limiter := newPullLimiter(cfg)
+
+	release, err := limiter.acquire(context.Background(), "nginx:latest", &logger)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx, "redis:7", &logger); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire with docker.io busy = %v, want deadline exceeded", err)
+	}
+
+	// The global slot is still free for other registries
+	other, err := limiter.acquire(context.Background(), "ghcr.io/a/one:1", &logger)
+	if err != nil {
+		t.Fatalf("acquire for another registry failed: %v", err)
+	}
+	other()
+}
+
+func TestNewPullLimiter_NormalizesHosts(t *testing.T) {
+	cfg := config.Default().Updates
+	cfg.RegistryPullLimits = map[string]int{"index.docker.io": 1, "https://GHCR.io": 2}
+	limiter := newPullLimiter(cfg)
+
+	// Keys are matched like the hosts in Docker credentials, so Docker Hub aliases and schemes apply
+	for image, want := range map[string]int{"nginx:latest": 1, "ghcr.io/a/one:1": 2} {
+		if got := cap(limiter.registries[docker.RegistryHost(image)]); got != want {
+			t.Errorf("pull limit for %s = %d, want %d", image, got, want)
+		}
+	}
+}
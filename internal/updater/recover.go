@@ -0,0 +1,191 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/rs/zerolog"
+)
+
+// stepCreating is saved before the new container is created, when its ID isn't known yet
+const stepCreating = "creating"
+
+// inflight saves a replacement's progress to the state file, so Recover can finish or undo
+// it if HarborBuddy is stopped halfway. A nil inflight, used when state.file is off, records
+// nothing.
+type inflight struct {
+	store  *state.Store
+	rec    state.Inflight
+	logger *zerolog.Logger
+}
+
+// beginReplacement records that full is about to be replaced. Call it before creating the
+// new container, since that already leaves a "-new" container behind.
+func beginReplacement(store *state.Store, full docker.ContainerInfo, name string, logger *zerolog.Logger) *inflight {
+	if store == nil {
+		return nil
+	}
+	t := &inflight{
+		store: store,
+		rec: state.Inflight{
+			Name:       name,
+			OldID:      full.ID,
+			WasRunning: full.State != nil && full.State.Running,
+			Step:       stepCreating,
+			StartedAt:  time.Now(),
+		},
+		logger: logger,
+	}
+	t.save()
+	return t
+}
+
+// created records the new container's ID
+func (t *inflight) created(newID string) {
+	if t == nil {
+		return
+	}
+	t.rec.NewID = newID
+	t.step(docker.StepCreated)
+}
+
+// step records the last completed step; it is passed to ReplaceOptions.Progress
+func (t *inflight) step(step docker.ReplaceStep) {
+	if t == nil {
+		return
+	}
+	t.rec.Step = string(step)
+	t.save()
+}
+
+// finish forgets the replacement once it succeeded or rolled back. If ctx was cancelled the
+// rollback may not have reached Docker, so the record is kept for Recover.
+func (t *inflight) finish(ctx context.Context) {
+	if t == nil || ctx.Err() != nil {
+		return
+	}
+	if err := t.store.ClearInflight(t.rec.Name); err != nil {
+		t.logger.Warn().Err(err).Msg("Failed to clear replacement progress")
+	}
+}
+
+func (t *inflight) save() {
+	if err := t.store.SetInflight(t.rec); err != nil {
+		t.logger.Warn().Err(err).Msg("Failed to save replacement progress")
+	}
+}
+
+// Recover finishes or rolls back replacements that were interrupted, e.g. by HarborBuddy
+// being killed mid-update, so no "-old-<ts>" backup or "-new" container is left orphaned.
+// A replacement whose new container had started and is still running is finished; any
+// other is rolled back to the old container. Replacements that can't be recovered stay
+// recorded and are retried on the next start. Dry-run only reports them.
+func Recover(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) error {
+	store := openState(cfg, logger)
+	if store == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, rec := range store.Inflight() {
+		if cfg.Updates.DryRun {
+			logger.Warn().Str("container_name", rec.Name).Str("step", rec.Step).Msg("[DRY-RUN] Would recover interrupted update")
+			continue
+		}
+
+		recLogger := logger.With().
+			Str("container_name", rec.Name).
+			Str("step", rec.Step).
+			Logger()
+
+		if err := recoverReplacement(ctx, dockerClient, rec, &recLogger); err != nil {
+			recLogger.Error().Err(err).Msg("Failed to recover interrupted replacement, will retry on next start")
+			errs = append(errs, fmt.Errorf("%s: %w", rec.Name, err))
+			continue
+		}
+		if err := store.ClearInflight(rec.Name); err != nil {
+			recLogger.Warn().Err(err).Msg("Failed to clear replacement progress")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recoverReplacement puts one interrupted replacement into a consistent state
+func recoverReplacement(ctx context.Context, dockerClient docker.Client, rec state.Inflight, logger *zerolog.Logger) error {
+	// Before its ID was saved, the new container can only be found by name: its temporary one,
+	// or the original one once the recreate strategy removed the old container
+	newRef := rec.NewID
+	if newRef == "" {
+		newRef = rec.Name + "-new"
+		if rec.Step == string(docker.StepRemoved) {
+			newRef = rec.Name
+		}
+	}
+
+	old, oldErr := dockerClient.InspectContainer(ctx, rec.OldID)
+	if oldErr != nil && !docker.IsNotFound(oldErr) {
+		return oldErr
+	}
+	replacement, newErr := dockerClient.InspectContainer(ctx, newRef)
+	if newErr != nil && !docker.IsNotFound(newErr) {
+		return newErr
+	}
+	oldExists, newExists := oldErr == nil, newErr == nil
+
+	switch {
+	case newExists && rec.Step == string(docker.StepStarted) && isRunning(replacement):
+		// Only the backup's removal was left
+		if oldExists {
+			if err := dockerClient.RemoveContainer(ctx, old.ID); err != nil {
+				return fmt.Errorf("failed to remove backup container %s: %w", old.Name, err)
+			}
+		}
+		logger.Info().Str("new_id", shortID(replacement.ID)).Msg("♻️  Finished interrupted update")
+
+	case oldExists:
+		// The new container is removed first to free the original name
+		if newExists {
+			if err := dockerClient.RemoveContainer(ctx, replacement.ID); err != nil {
+				return fmt.Errorf("failed to remove new container %s: %w", replacement.Name, err)
+			}
+		}
+		if old.Name != rec.Name {
+			if err := dockerClient.RenameContainer(ctx, old.ID, rec.Name); err != nil {
+				return fmt.Errorf("failed to rename %s back to %s: %w", old.Name, rec.Name, err)
+			}
+		}
+		if rec.WasRunning && !isRunning(old) {
+			if err := dockerClient.StartContainer(ctx, old.ID); err != nil {
+				return fmt.Errorf("failed to start old container: %w", err)
+			}
+		}
+		logger.Info().Str("old_id", shortID(old.ID)).Msg("⏪ Rolled back interrupted update")
+
+	case newExists:
+		// The old container is gone, as with --rm containers, so the new one is all there is
+		if replacement.Name != rec.Name {
+			if err := dockerClient.RenameContainer(ctx, replacement.ID, rec.Name); err != nil {
+				return fmt.Errorf("failed to rename %s to %s: %w", replacement.Name, rec.Name, err)
+			}
+		}
+		if rec.WasRunning && !isRunning(replacement) {
+			if err := dockerClient.StartContainer(ctx, replacement.ID); err != nil {
+				return fmt.Errorf("%w: %w", docker.ErrStartFailed, err)
+			}
+		}
+		logger.Warn().Str("new_id", shortID(replacement.ID)).Msg("♻️  Old container is gone, finished interrupted update with the new one")
+
+	default:
+		logger.Warn().Msg("Neither the old nor the new container exists anymore, nothing to recover")
+	}
+	return nil
+}
+
+func isRunning(c docker.ContainerInfo) bool {
+	return c.State != nil && c.State.Running
+}
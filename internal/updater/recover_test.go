@@ -0,0 +1,183 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestRecover(t *testing.T) {
+	running := &types.ContainerState{Running: true}
+	stopped := &types.ContainerState{}
+
+	tests := []struct {
+		name        string
+		rec         state.Inflight
+		containers  []docker.ContainerInfo
+		wantRemoved []string
+		wantRenamed []docker.RenameRequest
+		wantStarted []string
+	}{
+		{
+			name: "new container started, finishes by removing the backup",
+			rec:  state.Inflight{Name: "web", OldID: "old1", NewID: "new1", WasRunning: true, Step: string(docker.StepStarted)},
+			containers: []docker.ContainerInfo{
+				{ID: "old1", Name: "web-old-1700000000", State: stopped},
+				{ID: "new1", Name: "web", State: running},
+			},
+			wantRemoved: []string{"old1"},
+		},
+		{
+			name: "interrupted after the swap, rolls back",
+			rec:  state.Inflight{Name: "web", OldID: "old1", NewID: "new1", WasRunning: true, Step: string(docker.StepSwapped)},
+			containers: []docker.ContainerInfo{
+				{ID: "old1", Name: "web-old-1700000000", State: stopped},
+				{ID: "new1", Name: "web", State: stopped},
+			},
+			wantRemoved: []string{"new1"},
+			wantRenamed: []docker.RenameRequest{{ID: "old1", NewName: "web"}},
+			wantStarted: []string{"old1"},
+		},
+		{
+			name: "started but no longer running, rolls back",
+			rec:  state.Inflight{Name: "web", OldID: "old1", NewID: "new1", WasRunning: true, Step: string(docker.StepStarted)},
+			containers: []docker.ContainerInfo{
+				{ID: "old1", Name: "web-old-1700000000", State: stopped},
+				{ID: "new1", Name: "web", State: stopped},
+			},
+			wantRemoved: []string{"new1"},
+			wantRenamed: []docker.RenameRequest{{ID: "old1", NewName: "web"}},
+			wantStarted: []string{"old1"},
+		},
+		{
+			name: "interrupted while creating, removes the temporary container by name",
+			rec:  state.Inflight{Name: "web", OldID: "old1", WasRunning: true, Step: stepCreating},
+			containers: []docker.ContainerInfo{
+				{ID: "old1", Name: "web", State: running},
+				{ID: "new1", Name: "web-new", State: stopped},
+			},
+			wantRemoved: []string{"new1"},
+		},
+		{
+			name: "old container was stopped, stays stopped",
+			rec:  state.Inflight{Name: "web", OldID: "old1", NewID: "new1", Step: string(docker.StepBackedUp)},
+			containers: []docker.ContainerInfo{
+				{ID: "old1", Name: "web-old-1700000000", State: stopped},
+				{ID: "new1", Name: "web-new", State: stopped},
+			},
+			wantRemoved: []string{"new1"},
+			wantRenamed: []docker.RenameRequest{{ID: "old1", NewName: "web"}},
+		},
+		{
+			name: "old container gone, finishes with the new one",
+			rec:  state.Inflight{Name: "web", OldID: "old1", NewID: "new1", WasRunning: true, Step: string(docker.StepCreated)},
+			containers: []docker.ContainerInfo{
+				{ID: "new1", Name: "web-new", State: stopped},
+			},
+			wantRenamed: []docker.RenameRequest{{ID: "new1", NewName: "web"}},
+			wantStarted: []string{"new1"},
+		},
+		{
+			name: "recreate interrupted after removing the old container, starts the new one",
+			rec:  state.Inflight{Name: "web", OldID: "old1", WasRunning: true, Step: string(docker.StepRemoved)},
+			containers: []docker.ContainerInfo{
+				{ID: "new1", Name: "web", State: stopped},
+			},
+			wantStarted: []string{"new1"},
+		},
+		{
+			name: "both gone, nothing to do",
+			rec:  state.Inflight{Name: "web", OldID: "old1", NewID: "new1", Step: string(docker.StepSwapped)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+			store, _ := state.Open(cfg.State.File)
+			if err := store.SetInflight(tt.rec); err != nil {
+				t.Fatal(err)
+			}
+
+			mockClient := docker.NewMockDockerClient()
+			mockClient.Containers = tt.containers
+			logger := zerolog.Nop()
+
+			if err := Recover(context.Background(), cfg, mockClient, &logger); err != nil {
+				t.Fatalf("Recover() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(mockClient.RemovedContainers, tt.wantRemoved) {
+				t.Errorf("removed %v, want %v", mockClient.RemovedContainers, tt.wantRemoved)
+			}
+			if !reflect.DeepEqual(mockClient.RenamedContainers, tt.wantRenamed) {
+				t.Errorf("renamed %v, want %v", mockClient.RenamedContainers, tt.wantRenamed)
+			}
+			if !reflect.DeepEqual(mockClient.StartedContainers, tt.wantStarted) {
+				t.Errorf("started %v, want %v", mockClient.StartedContainers, tt.wantStarted)
+			}
+
+			reopened, _ := state.Open(cfg.State.File)
+			if left := reopened.Inflight(); len(left) != 0 {
+				t.Errorf("recovered replacement still recorded: %+v", left)
+			}
+		})
+	}
+}
+
+func TestRecover_KeepsRecordOnError(t *testing.T) {
+	cfg := config.Default()
+	cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+	store, _ := state.Open(cfg.State.File)
+	if err := store.SetInflight(state.Inflight{Name: "web", OldID: "old1", NewID: "new1", Step: string(docker.StepSwapped)}); err != nil {
+		t.Fatal(err)
+	}
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.InspectContainerError = errors.New("daemon unreachable")
+	logger := zerolog.Nop()
+
+	if err := Recover(context.Background(), cfg, mockClient, &logger); err == nil {
+		t.Fatal("expected an error when Docker can't be asked")
+	}
+	reopened, _ := state.Open(cfg.State.File)
+	if left := reopened.Inflight(); len(left) != 1 {
+		t.Errorf("record should be kept for the next start, got %+v", left)
+	}
+}
+
+func TestRunUpdateCycle_ClearsInflight(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+
+	cfg := config.Default()
+	cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+	logger := zerolog.Nop()
+
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+	if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].Options.Progress == nil {
+		t.Fatal("replacement should report its progress")
+	}
+
+	store, _ := state.Open(cfg.State.File)
+	if left := store.Inflight(); len(left) != 0 {
+		t.Errorf("finished replacement still recorded: %+v", left)
+	}
+}
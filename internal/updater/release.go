@@ -0,0 +1,93 @@
+package updater
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// OCI image labels describing the release an image contains
+const (
+	versionLabel  = "org.opencontainers.image.version"
+	revisionLabel = "org.opencontainers.image.revision"
+	sourceLabel   = "org.opencontainers.image.source"
+	urlLabel      = "org.opencontainers.image.url"
+)
+
+// releaseInfo is what the OCI labels of the current and new image say about an update
+type releaseInfo struct {
+	OldVersion string
+	NewVersion string
+	URL        string // Where to read what changed: a commit comparison, release page or homepage
+}
+
+// releaseOf reads the release labels of the current and new image. The link compares the two
+// revisions when both come from a GitHub or GitLab repository, and otherwise points at the
+// repository's releases or the image's homepage.
+func releaseOf(current, updated map[string]string) releaseInfo {
+	release := releaseInfo{
+		OldVersion: current[versionLabel],
+		NewVersion: updated[versionLabel],
+	}
+
+	source := strings.TrimSuffix(strings.TrimSuffix(updated[sourceLabel], "/"), ".git")
+	host := ""
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "https" || u.Scheme == "http") {
+		host = u.Host
+	}
+	oldRev, newRev := current[revisionLabel], updated[revisionLabel]
+	sameSource := strings.TrimSuffix(strings.TrimSuffix(current[sourceLabel], "/"), ".git") == source
+
+	switch {
+	case host == "github.com" && sameSource && oldRev != "" && newRev != "" && oldRev != newRev:
+		release.URL = source + "/compare/" + oldRev + "..." + newRev
+	case host == "gitlab.com" && sameSource && oldRev != "" && newRev != "" && oldRev != newRev:
+		release.URL = source + "/-/compare/" + oldRev + "..." + newRev
+	case host == "github.com":
+		release.URL = source + "/releases"
+	case host != "":
+		release.URL = source
+	default:
+		release.URL = updated[urlLabel]
+	}
+	return release
+}
+
+// logUpdateFound announces an update, with the versions and release link when the images
+// carry OCI labels
+func logUpdateFound(container docker.ContainerInfo, target string, newImage docker.ImageInfo, release releaseInfo, logger *zerolog.Logger) {
+	displayImg := util.GetImageFriendlyName(newImage.Labels, container.Labels)
+	if displayImg == "" {
+		displayImg = shortID(newImage.ID)
+	}
+
+	now := time.Now()
+	event := logger.Info().
+		Str("container_name", container.Name).
+		Str("image", container.Image).
+		Str("current_id", shortID(container.ImageID)).
+		Str("new_id", displayImg)
+	if !container.CreatedAt.IsZero() {
+		event = event.Str("running_since", util.FormatRelative(container.CreatedAt, now))
+	}
+	if !newImage.CreatedAt.IsZero() {
+		event = event.Str("new_image_built", util.FormatRelative(newImage.CreatedAt, now))
+	}
+	if target != container.Image {
+		event = event.Str("target", target)
+	}
+	if release.OldVersion != "" {
+		event = event.Str("old_version", release.OldVersion)
+	}
+	if release.NewVersion != "" {
+		event = event.Str("new_version", release.NewVersion)
+	}
+	if release.URL != "" {
+		event = event.Str("release_notes", release.URL)
+	}
+	event.Msg("🚀 Update found")
+}
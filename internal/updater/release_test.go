@@ -0,0 +1,100 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestReleaseOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		current map[string]string
+		updated map[string]string
+		want    releaseInfo
+	}{
+		{
+			name: "github revisions",
+			current: map[string]string{
+				versionLabel: "1.4.2", sourceLabel: "https://github.com/acme/api", revisionLabel: "aaa111",
+			},
+			updated: map[string]string{
+				versionLabel: "1.5.0", sourceLabel: "https://github.com/acme/api.git", revisionLabel: "bbb222",
+			},
+			want: releaseInfo{OldVersion: "1.4.2", NewVersion: "1.5.0", URL: "https://github.com/acme/api/compare/aaa111...bbb222"},
+		},
+		{
+			name:    "gitlab revisions",
+			current: map[string]string{sourceLabel: "https://gitlab.com/acme/api", revisionLabel: "aaa111"},
+			updated: map[string]string{sourceLabel: "https://gitlab.com/acme/api", revisionLabel: "bbb222"},
+			want:    releaseInfo{URL: "https://gitlab.com/acme/api/-/compare/aaa111...bbb222"},
+		},
+		{
+			name:    "github without the old revision",
+			current: map[string]string{},
+			updated: map[string]string{versionLabel: "2.0", sourceLabel: "https://github.com/acme/api/", revisionLabel: "bbb222"},
+			want:    releaseInfo{NewVersion: "2.0", URL: "https://github.com/acme/api/releases"},
+		},
+		{
+			name:    "repository moved",
+			current: map[string]string{sourceLabel: "https://github.com/old/api", revisionLabel: "aaa111"},
+			updated: map[string]string{sourceLabel: "https://github.com/acme/api", revisionLabel: "bbb222"},
+			want:    releaseInfo{URL: "https://github.com/acme/api/releases"},
+		},
+		{
+			name:    "other host",
+			updated: map[string]string{sourceLabel: "https://git.example.com/acme/api"},
+			want:    releaseInfo{URL: "https://git.example.com/acme/api"},
+		},
+		{
+			name:    "homepage only",
+			updated: map[string]string{urlLabel: "https://acme.example.com"},
+			want:    releaseInfo{URL: "https://acme.example.com"},
+		},
+		{
+			name: "no labels",
+			want: releaseInfo{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := releaseOf(tt.current, tt.updated); got != tt.want {
+				t.Errorf("releaseOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_ReleaseInNotification(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "api", Image: "ghcr.io/acme/api:1", ImageID: "sha256:old-api", Config: &container.Config{Image: "ghcr.io/acme/api:1"}},
+	}
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:old-api", Labels: map[string]string{versionLabel: "1.4.2", sourceLabel: "https://github.com/acme/api", revisionLabel: "aaa111"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"ghcr.io/acme/api:1": {ID: "sha256:new-api", Labels: map[string]string{versionLabel: "1.5.0", sourceLabel: "https://github.com/acme/api", revisionLabel: "bbb222"}},
+	}
+
+	recorder := &eventRecorder{}
+	ctx := notify.WithNotifier(context.Background(), recorder)
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(ctx, config.Default(), mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("events = %+v, want one update", recorder.events)
+	}
+	event := recorder.events[0]
+	if event.Type != notify.EventUpdate || event.VersionChange() != "1.4.2 → 1.5.0" || event.ReleaseURL != "https://github.com/acme/api/compare/aaa111...bbb222" {
+		t.Errorf("update event = %+v", event)
+	}
+}
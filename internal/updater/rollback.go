@@ -0,0 +1,99 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/rs/zerolog"
+)
+
+// openState opens the update history store, or returns nil if it is disabled or unreadable
+func openState(cfg config.Config, logger *zerolog.Logger) *state.Store {
+	if cfg.State.File == "" {
+		return nil
+	}
+	store, err := state.Open(cfg.State.File)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Update history unavailable, rollback will not be possible for this cycle")
+		return nil
+	}
+	return store
+}
+
+// recordUpdate remembers the image a container ran before its update so it can be rolled back
+func recordUpdate(store *state.Store, container docker.ContainerInfo, newImage docker.ImageInfo, logger *zerolog.Logger) {
+	if store == nil {
+		return
+	}
+	err := store.Put(container.Name, state.Record{
+		Image:           container.Image,
+		PreviousImageID: container.ImageID,
+		ImageID:         newImage.ID,
+		UpdatedAt:       time.Now(),
+	})
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to record update history")
+	}
+	forgetPending(store, container.Name, logger)
+}
+
+// pinnedToImageID reports whether a container runs an image ID rather than a reference,
+// as rolled-back containers do
+func pinnedToImageID(image string) bool {
+	return strings.HasPrefix(image, "sha256:")
+}
+
+// Rollback recreates a container from the image it ran before its last update.
+// The container is pinned to that image ID, so later cycles leave it alone until it is
+// recreated from a tag again.
+func Rollback(ctx context.Context, cfg config.Config, dockerClient docker.Client, name string, logger *zerolog.Logger) error {
+	if cfg.State.File == "" {
+		return fmt.Errorf("rollback needs update history; set state.file (HARBORBUDDY_STATE_FILE)")
+	}
+	store, err := state.Open(cfg.State.File)
+	if err != nil {
+		return err
+	}
+
+	rec, ok := store.Get(name)
+	if !ok {
+		return fmt.Errorf("no recorded update for container %s", name)
+	}
+
+	current, err := dockerClient.InspectContainer(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container for rollback: %w", err)
+	}
+	if current.ImageID == rec.PreviousImageID {
+		return fmt.Errorf("container %s already runs %s", name, shortID(rec.PreviousImageID))
+	}
+
+	if _, err := dockerClient.InspectImage(ctx, rec.PreviousImageID); err != nil {
+		return fmt.Errorf("previous image %s is no longer available: %w", shortID(rec.PreviousImageID), err)
+	}
+
+	rollbackLogger := logger.With().
+		Str("container_name", name).
+		Str("from_id", shortID(current.ImageID)).
+		Str("to_id", shortID(rec.PreviousImageID)).
+		Logger()
+	rollbackLogger.Info().Msgf("⏪ Rolling back to the image before the update of %s", rec.UpdatedAt.Format(time.RFC3339))
+
+	track := beginReplacement(store, current, current.Name, &rollbackLogger)
+	defer track.finish(ctx)
+
+	newID, err := swapContainer(ctx, cfg, dockerClient, current, rec.PreviousImageID, current.Name, track, &rollbackLogger)
+	if err != nil {
+		return err
+	}
+
+	rollbackLogger.Info().
+		Str("new_container_id", shortID(newID)).
+		Msgf("✅ Rolled back %s (was %s); it stays pinned to this image until recreated from a tag", name, rec.Image)
+	return nil
+}
@@ -0,0 +1,106 @@
+package updater
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestRunUpdateCycle_RecordsHistory(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+
+	cfg := config.Default()
+	cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	store, err := state.Open(cfg.State.File)
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	rec, ok := store.Get("web")
+	if !ok || rec.PreviousImageID != "sha256:old-nginx" || rec.ImageID != "sha256:new-nginx" || rec.Image != "nginx:latest" {
+		t.Errorf("recorded %+v, %v", rec, ok)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	store, _ := state.Open(statePath)
+	if err := store.Put("web", state.Record{Image: "nginx:latest", PreviousImageID: "sha256:old-nginx", ImageID: "sha256:new-nginx"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.State.File = statePath
+	logger := zerolog.Nop()
+
+	t.Run("recreates from the previous image", func(t *testing.T) {
+		mockClient := docker.NewMockDockerClient()
+		mockClient.Containers = []docker.ContainerInfo{
+			{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:new-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		}
+		mockClient.Images = []docker.ImageInfo{{ID: "sha256:old-nginx"}}
+
+		if err := Rollback(context.Background(), cfg, mockClient, "web", &logger); err != nil {
+			t.Fatalf("Rollback() error = %v", err)
+		}
+		if len(mockClient.CreatedContainers) != 1 || mockClient.CreatedContainers[0].NewImage != "sha256:old-nginx" {
+			t.Errorf("created %+v, want one container from sha256:old-nginx", mockClient.CreatedContainers)
+		}
+		if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].OldID != "container1" {
+			t.Errorf("replaced %+v, want container1", mockClient.ReplacedContainers)
+		}
+	})
+
+	errorCases := []struct {
+		name      string
+		cfg       config.Config
+		container string
+		imageID   string
+		errMsg    string
+	}{
+		{"no state file", config.Default(), "web", "sha256:new-nginx", "state.file"},
+		{"unknown container", cfg, "db", "sha256:new-nginx", "no recorded update"},
+		{"already rolled back", cfg, "web", "sha256:old-nginx", "already runs"},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := docker.NewMockDockerClient()
+			mockClient.Containers = []docker.ContainerInfo{
+				{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: tt.imageID, Config: &container.Config{Image: "nginx:latest"}},
+			}
+
+			err := Rollback(context.Background(), tt.cfg, mockClient, tt.container, &logger)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Rollback() error = %v, want containing %q", err, tt.errMsg)
+			}
+			if len(mockClient.CreatedContainers) != 0 {
+				t.Error("no container should be created on error")
+			}
+		})
+	}
+}
+
+func TestDetermineEligibility_PinnedImageID(t *testing.T) {
+	decision := DetermineEligibility(docker.ContainerInfo{Image: "sha256:old-nginx"}, config.Default().Updates)
+	if decision.Eligible {
+		t.Error("containers pinned to an image ID must not be updated")
+	}
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/MikeO7/HarborBuddy/internal/scan"
+	"github.com/rs/zerolog"
+)
+
+// ImageScanner checks a pulled image for known vulnerabilities
+type ImageScanner interface {
+	Applies(image string) bool
+	Scan(ctx context.Context, imageRef string) (scan.Summary, error)
+}
+
+// newScanner is a variable to allow stubbing scans in tests. It returns nil when scanning
+// is off.
+var newScanner = func(cfg config.Config) (ImageScanner, error) {
+	if !cfg.Scan.Enabled {
+		return nil, nil
+	}
+	s, err := scan.New(cfg.Scan, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up vulnerability scanning: %w", err)
+	}
+	return s, nil
+}
+
+// scanUpdate scans the image pulled for an update of container and records the result in
+// the cycle report. It returns nil when the update may go ahead: the image has at most
+// maxCritical critical vulnerabilities. An image that can't be scanned is refused.
+func scanUpdate(ctx context.Context, scanner ImageScanner, maxCritical int, container, target string, newImage docker.ImageInfo, logger *zerolog.Logger) *refusal {
+	if scanner == nil || !scanner.Applies(target) {
+		return nil
+	}
+
+	entry := report.Scan{Name: container, Image: target, NewImageID: newImage.ID}
+	summary, err := scanner.Scan(ctx, target)
+	if err != nil {
+		logger.Warn().
+			Err(err).
+			Str("image", target).
+			Str("new_id", shortID(newImage.ID)).
+			Msg("🛡️ Skipping update: image could not be scanned for vulnerabilities")
+		entry.Error = err.Error()
+		report.FromContext(ctx).AddScan(entry)
+		return &refusal{Outcome: notify.OutcomeUnscanned, Reason: "vulnerability scan failed: " + err.Error()}
+	}
+
+	entry.Critical, entry.High, entry.Medium, entry.Low, entry.Unknown = summary.Critical, summary.High, summary.Medium, summary.Low, summary.Unknown
+	entry.CriticalIDs = summary.IDs
+	entry.Passed = summary.Critical <= maxCritical
+	report.FromContext(ctx).AddScan(entry)
+
+	if !entry.Passed {
+		reason := fmt.Sprintf("image has %d critical vulnerabilities, at most %d allowed", summary.Critical, maxCritical)
+		logger.Warn().
+			Str("image", target).
+			Str("new_id", shortID(newImage.ID)).
+			Str("vulnerabilities", summary.String()).
+			Strs("critical_ids", summary.IDs).
+			Msgf("🛡️ Skipping update: %s", reason)
+		return &refusal{Outcome: notify.OutcomeVulnerable, Reason: reason}
+	}
+	logger.Info().
+		Str("image", target).
+		Str("vulnerabilities", summary.String()).
+		Msg("🛡️ Image scanned for vulnerabilities")
+	return nil
+}
@@ -0,0 +1,111 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/report"
+	"github.com/MikeO7/HarborBuddy/internal/scan"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+// stubScanner returns the summaries in results, and an error for any other image
+type stubScanner struct {
+	results map[string]scan.Summary
+}
+
+func (s stubScanner) Applies(image string) bool { return true }
+
+func (s stubScanner) Scan(ctx context.Context, imageRef string) (scan.Summary, error) {
+	if summary, ok := s.results[imageRef]; ok {
+		return summary, nil
+	}
+	return scan.Summary{}, errors.New("trivy failed: exit status 1")
+}
+
+func withScanner(t *testing.T, s ImageScanner, err error) {
+	t.Helper()
+	original := newScanner
+	newScanner = func(cfg config.Config) (ImageScanner, error) { return s, err }
+	t.Cleanup(func() { newScanner = original })
+}
+
+func TestRunUpdateCycle_Scan(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "api", Image: "httpd:latest", ImageID: "sha256:old-httpd", Config: &container.Config{Image: "httpd:latest"}},
+		{ID: "container3", Name: "db", Image: "postgres:16", ImageID: "sha256:old-postgres", Config: &container.Config{Image: "postgres:16"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+		"httpd:latest": {ID: "sha256:new-httpd"},
+		"postgres:16":  {ID: "sha256:new-postgres"},
+	}
+	withScanner(t, stubScanner{results: map[string]scan.Summary{
+		"nginx:latest": {Critical: 1, High: 4},
+		"httpd:latest": {Critical: 3, IDs: []string{"CVE-2024-3094"}},
+	}}, nil)
+
+	cfg := config.Default()
+	cfg.Scan.MaxCritical = 1
+	recorder := &eventRecorder{}
+	rep := report.New("abcd1234", false, false)
+	ctx := report.WithReport(notify.WithNotifier(context.Background(), recorder), rep)
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(ctx, cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].Name != "web" {
+		t.Errorf("replaced = %+v, want only web (1 critical vulnerability)", mockClient.ReplacedContainers)
+	}
+
+	refused := map[string]notify.Event{}
+	for _, event := range recorder.events {
+		if event.Outcome == notify.OutcomeVulnerable || event.Outcome == notify.OutcomeUnscanned {
+			refused[event.Container] = event
+		}
+	}
+	if refused["api"].Outcome != notify.OutcomeVulnerable || refused["api"].Error != "image has 3 critical vulnerabilities, at most 1 allowed" {
+		t.Errorf("api event = %+v, want it refused as vulnerable", refused["api"])
+	}
+	if refused["db"].Outcome != notify.OutcomeUnscanned {
+		t.Errorf("db event = %+v, want it refused as unscanned", refused["db"])
+	}
+
+	scans := map[string]report.Scan{}
+	for _, s := range rep.Scans {
+		scans[s.Name] = s
+	}
+	if s := scans["web"]; !s.Passed || s.Critical != 1 || s.High != 4 || s.NewImageID != "sha256:new-nginx" {
+		t.Errorf("web scan = %+v, want it passed", s)
+	}
+	if s := scans["api"]; s.Passed || s.Critical != 3 || len(s.CriticalIDs) != 1 {
+		t.Errorf("api scan = %+v, want it failed with its critical IDs", s)
+	}
+	if s := scans["db"]; s.Passed || s.Error == "" {
+		t.Errorf("db scan = %+v, want the scan error", s)
+	}
+}
+
+func TestRunUpdateCycle_ScannerSetupFails(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	withScanner(t, nil, errors.New("trivy binary not found"))
+
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &logger); err == nil {
+		t.Fatal("RunUpdateCycle() succeeded, want the scanner error")
+	}
+	if len(mockClient.PulledImages) != 0 {
+		t.Errorf("cycle went ahead without a scanner: pulled %v", mockClient.PulledImages)
+	}
+}
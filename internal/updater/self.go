@@ -0,0 +1,30 @@
+package updater
+
+import (
+	"github.com/MikeO7/HarborBuddy/internal/config"
+)
+
+// Tags published for each self-update channel
+var channelTags = map[string]string{
+	config.SelfUpdateStable: "latest",
+	config.SelfUpdateBeta:   "beta",
+}
+
+// selfImage returns the image HarborBuddy's own container follows: the channel's tag of
+// selfupdate.image, or of the repository it runs from. On the stable channel a version tag
+// it already runs (e.g. "1.4") is kept, so the tag policy and pins still decide how far it
+// moves. Images pinned by digest are left alone.
+func selfImage(running string, cfg config.SelfUpdateConfig) string {
+	repo, tag, ok := splitImageTag(running)
+	if !ok {
+		return running
+	}
+	if cfg.Image != "" {
+		repo = cfg.Image
+	}
+
+	if _, isVersion := parseVersion(tag); isVersion && cfg.Channel == config.SelfUpdateStable {
+		return repo + ":" + tag
+	}
+	return repo + ":" + channelTags[cfg.Channel]
+}
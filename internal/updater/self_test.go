@@ -0,0 +1,112 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/selfupdate"
+	"github.com/rs/zerolog"
+)
+
+func TestSelfImage(t *testing.T) {
+	stable := config.SelfUpdateConfig{Channel: config.SelfUpdateStable}
+	beta := config.SelfUpdateConfig{Channel: config.SelfUpdateBeta}
+	mirror := config.SelfUpdateConfig{Channel: config.SelfUpdateStable, Image: "registry.example.com/mirror/harborbuddy"}
+
+	tests := []struct {
+		name    string
+		running string
+		cfg     config.SelfUpdateConfig
+		want    string
+	}{
+		{"stable from latest", "ghcr.io/mikeo7/harborbuddy:latest", stable, "ghcr.io/mikeo7/harborbuddy:latest"},
+		{"stable keeps a version tag", "ghcr.io/mikeo7/harborbuddy:1.4", stable, "ghcr.io/mikeo7/harborbuddy:1.4"},
+		{"back to stable from beta", "ghcr.io/mikeo7/harborbuddy:beta", stable, "ghcr.io/mikeo7/harborbuddy:latest"},
+		{"beta", "ghcr.io/mikeo7/harborbuddy:1.4", beta, "ghcr.io/mikeo7/harborbuddy:beta"},
+		{"untagged", "ghcr.io/mikeo7/harborbuddy", beta, "ghcr.io/mikeo7/harborbuddy:beta"},
+		{"another repository", "ghcr.io/mikeo7/harborbuddy:latest", mirror, "registry.example.com/mirror/harborbuddy:latest"},
+		{"registry with a port", "localhost:5000/harborbuddy:latest", beta, "localhost:5000/harborbuddy:beta"},
+		{"pinned by digest", "ghcr.io/mikeo7/harborbuddy@sha256:abc", beta, "ghcr.io/mikeo7/harborbuddy@sha256:abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selfImage(tt.running, tt.cfg); got != tt.want {
+				t.Errorf("selfImage(%q) = %q, want %q", tt.running, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_SelfUpdateChannel(t *testing.T) {
+	originalIsSelfFunc := isSelfFunc
+	defer func() { isSelfFunc = originalIsSelfFunc }()
+	isSelfFunc = func(id string) (bool, error) { return id == "self", nil }
+
+	newClient := func() *docker.MockDockerClient {
+		mockClient := docker.NewMockDockerClient()
+		mockClient.Containers = []docker.ContainerInfo{
+			{ID: "self", Name: "harborbuddy", Image: "ghcr.io/mikeo7/harborbuddy:latest", ImageID: "sha256:old"},
+		}
+		mockClient.PullImageReturns = map[string]docker.ImageInfo{
+			"ghcr.io/mikeo7/harborbuddy:latest": {ID: "sha256:old"},
+			"ghcr.io/mikeo7/harborbuddy:beta":   {ID: "sha256:old"},
+		}
+		return mockClient
+	}
+	logger := zerolog.Nop()
+
+	t.Run("none", func(t *testing.T) {
+		mockClient := newClient()
+		cfg := config.Default()
+		cfg.SelfUpdate.Channel = config.SelfUpdateNone
+		if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+			t.Fatalf("RunUpdateCycle() error = %v", err)
+		}
+		if len(mockClient.PulledImages) != 0 {
+			t.Errorf("pulled = %v, want nothing with self-update disabled", mockClient.PulledImages)
+		}
+	})
+
+	t.Run("beta", func(t *testing.T) {
+		mockClient := newClient()
+		cfg := config.Default()
+		cfg.SelfUpdate.Channel = config.SelfUpdateBeta
+		if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+			t.Fatalf("RunUpdateCycle() error = %v", err)
+		}
+		if len(mockClient.PulledImages) != 1 || mockClient.PulledImages[0] != "ghcr.io/mikeo7/harborbuddy:beta" {
+			t.Errorf("pulled = %v, want the beta tag", mockClient.PulledImages)
+		}
+	})
+}
+
+func TestRunUpdateCycle_SelfPullsOwnPlatform(t *testing.T) {
+	originalIsSelfFunc := isSelfFunc
+	defer func() { isSelfFunc = originalIsSelfFunc }()
+	isSelfFunc = func(id string) (bool, error) { return id == "self", nil }
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "self", Name: "harborbuddy", Image: "ghcr.io/mikeo7/harborbuddy:latest", ImageID: "sha256:old"},
+		{ID: "web", Name: "web", Image: "nginx:latest", ImageID: "sha256:nginx"},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"ghcr.io/mikeo7/harborbuddy:latest": {ID: "sha256:old"},
+		"nginx:latest":                      {ID: "sha256:nginx"},
+	}
+	logger := zerolog.Nop()
+
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+	want := selfupdate.Platform()
+	if got := mockClient.PulledPlatforms["ghcr.io/mikeo7/harborbuddy:latest"]; got != want {
+		t.Errorf("own image pulled for %s, want the binary's %s", got, want)
+	}
+	if got, ok := mockClient.PulledPlatforms["nginx:latest"]; ok {
+		t.Errorf("other image pulled for %s, want the daemon to pick", got)
+	}
+}
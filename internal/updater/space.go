@@ -0,0 +1,85 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// errLowDiskSpace is matched by errors for pulls held back by updates.min_free_space
+var errLowDiskSpace = errors.New("not enough free disk space")
+
+// spaceGuard holds back pulls that would leave less than updates.min_free_space free on the
+// disk holding Docker's data root. Pulls in flight keep their estimated size reserved, so
+// pulls running together can't fill the disk between them. Its methods are safe to call on a
+// nil guard, which a cycle gets when the setting is off.
+type spaceGuard struct {
+	dockerClient docker.Client
+	path         string // cleanup.data_root
+	minFree      int64
+
+	mu       sync.Mutex
+	reserved int64
+}
+
+// newSpaceGuard returns the cycle's guard, or nil when updates.min_free_space is off. If the
+// data root's usage can't be read the guard is off for the cycle, rather than blocking every
+// pull.
+func newSpaceGuard(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) *spaceGuard {
+	if cfg.Updates.MinFreeSpace == "" {
+		return nil
+	}
+	minFree, err := util.ParseBytes(cfg.Updates.MinFreeSpace)
+	if err != nil || minFree == 0 {
+		return nil
+	}
+	if _, err := dockerClient.DataRootUsage(ctx, cfg.Cleanup.DataRoot); err != nil {
+		logger.Warn().Err(err).Msg("Can't check free disk space, pulling without updates.min_free_space")
+		return nil
+	}
+	return &spaceGuard{dockerClient: dockerClient, path: cfg.Cleanup.DataRoot, minFree: minFree}
+}
+
+// reserve checks there is room to pull image, estimated at size bytes, and sets that much
+// aside until the returned function is called. It returns an error matching errLowDiskSpace
+// when the pull would leave less than the minimum free.
+func (g *spaceGuard) reserve(ctx context.Context, image string, size int64, logger *zerolog.Logger) (func(), error) {
+	if g == nil {
+		return func() {}, nil
+	}
+	usage, err := g.dockerClient.DataRootUsage(ctx, g.path)
+	if err != nil {
+		logger.Debug().Err(err).Str("image", image).Msg("Failed to check free disk space, pulling anyway")
+		return func() {}, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	free := int64(usage.Available) - g.reserved
+	if free-size < g.minFree {
+		return nil, fmt.Errorf("%w: %s free on %s, pulling %s needs about %s and %s must stay free (updates.min_free_space)",
+			errLowDiskSpace, util.FormatBytes(max(free, 0)), usage.Path, image, util.FormatBytes(size), util.FormatBytes(g.minFree))
+	}
+	g.reserved += size
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.reserved -= size
+	}, nil
+}
+
+// pullEstimate guesses how much disk a pull of a new version of the container's image takes:
+// the size of the image it runs now, or 0 if that can't be inspected
+func pullEstimate(ctx context.Context, dockerClient docker.Client, container docker.ContainerInfo) int64 {
+	current, err := dockerClient.InspectImage(ctx, container.ImageID)
+	if err != nil {
+		return 0
+	}
+	return current.Size
+}
@@ -0,0 +1,100 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestSpaceGuard_Reserve(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.DiskUsage = docker.DiskUsage{Path: "/var/lib/docker", Available: 8 * util.GB}
+	cfg := config.Default()
+	cfg.Updates.MinFreeSpace = "5g"
+	logger := zerolog.Nop()
+
+	guard := newSpaceGuard(context.Background(), cfg, mockClient, &logger)
+	if guard == nil {
+		t.Fatal("newSpaceGuard() = nil, want a guard")
+	}
+
+	release, err := guard.reserve(context.Background(), "nginx:latest", 2*util.GB, &logger)
+	if err != nil {
+		t.Fatalf("reserve() error = %v, want room for the first pull", err)
+	}
+	// The first pull's 2 GB is still reserved, so another 2 GB would leave 4 GB
+	if _, err := guard.reserve(context.Background(), "postgres:16", 2*util.GB, &logger); !errors.Is(err, errLowDiskSpace) {
+		t.Errorf("reserve() error = %v, want errLowDiskSpace", err)
+	}
+	release()
+	if _, err := guard.reserve(context.Background(), "postgres:16", 2*util.GB, &logger); err != nil {
+		t.Errorf("reserve() error = %v once the first pull finished", err)
+	}
+}
+
+func TestNewSpaceGuard_Off(t *testing.T) {
+	logger := zerolog.Nop()
+	mockClient := docker.NewMockDockerClient()
+	if guard := newSpaceGuard(context.Background(), config.Default(), mockClient, &logger); guard != nil {
+		t.Error("newSpaceGuard() returned a guard without updates.min_free_space")
+	}
+
+	// A data root HarborBuddy can't see leaves pulls unguarded rather than blocked
+	cfg := config.Default()
+	cfg.Updates.MinFreeSpace = "5g"
+	mockClient.DataRootUsageError = errors.New("docker data root /var/lib/docker is not visible to HarborBuddy")
+	guard := newSpaceGuard(context.Background(), cfg, mockClient, &logger)
+	if guard != nil {
+		t.Error("newSpaceGuard() returned a guard for an unreadable data root")
+	}
+	if _, err := guard.reserve(context.Background(), "nginx:latest", util.TB, &logger); err != nil {
+		t.Errorf("reserve() on a nil guard error = %v", err)
+	}
+}
+
+func TestRunUpdateCycle_MinFreeSpace(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.DiskUsage = docker.DiskUsage{Path: "/var/lib/docker", Available: 6 * util.GB}
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "db", Image: "postgres:16", ImageID: "sha256:old-postgres", Config: &container.Config{Image: "postgres:16"}},
+	}
+	mockClient.Images = []docker.ImageInfo{
+		{ID: "sha256:old-nginx", Size: 200 * util.MB},
+		{ID: "sha256:old-postgres", Size: 2 * util.GB},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+		"postgres:16":  {ID: "sha256:new-postgres"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.MinFreeSpace = "5g"
+	recorder := &eventRecorder{}
+	ctx := notify.WithNotifier(context.Background(), recorder)
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(ctx, cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if !slices.Equal(mockClient.PulledImages, []string{"nginx:latest"}) {
+		t.Errorf("pulled %v, want only nginx:latest (postgres would leave 4 GB free)", mockClient.PulledImages)
+	}
+	var skipped []string
+	for _, event := range recorder.events {
+		if event.Outcome == notify.OutcomeLowDiskSpace {
+			skipped = append(skipped, event.Container)
+		}
+	}
+	if !slices.Equal(skipped, []string{"db"}) {
+		t.Errorf("low disk space notifications for %v, want [db]", skipped)
+	}
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/rs/zerolog"
+)
+
+// stagger spaces out container replacements by updates.stagger_delay plus up to
+// updates.stagger_jitter. Replacements in parallel workers share one schedule, so starts
+// are spaced out across all of them. It is safe for concurrent use.
+type stagger struct {
+	delay  time.Duration
+	jitter time.Duration
+
+	mu   sync.Mutex
+	next time.Time // Earliest start of the next replacement
+}
+
+// gap returns the delay plus a fresh random jitter
+func (s *stagger) gap() time.Duration {
+	if s.jitter <= 0 {
+		return s.delay
+	}
+	return s.delay + rand.N(s.jitter+1)
+}
+
+// wait blocks until the next replacement may start and reserves that slot.
+// It returns ctx.Err() if ctx is cancelled first.
+func (s *stagger) wait(ctx context.Context, logger *zerolog.Logger) error {
+	if s == nil || (s.delay <= 0 && s.jitter <= 0) {
+		return nil
+	}
+
+	s.mu.Lock()
+	start := time.Now()
+	if s.next.After(start) {
+		start = s.next
+	}
+	s.next = start.Add(s.gap())
+	s.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+	logger.Info().Msgf("⏳ Waiting %s before replacing (stagger)", util.HumanizeDuration(delay))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// done records that a replacement finished, so the next one waits a full gap after it
+// rather than after it started
+func (s *stagger) done() {
+	if s == nil || (s.delay <= 0 && s.jitter <= 0) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if next := time.Now().Add(s.gap()); next.After(s.next) {
+		s.next = next
+	}
+}
@@ -0,0 +1,84 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/rs/zerolog"
+)
+
+func TestStagger_Gap(t *testing.T) {
+	s := &stagger{delay: 10 * time.Second, jitter: 5 * time.Second}
+	for i := 0; i < 100; i++ {
+		if gap := s.gap(); gap < 10*time.Second || gap > 15*time.Second {
+			t.Fatalf("gap() = %v, want between 10s and 15s", gap)
+		}
+	}
+}
+
+func TestStagger_Wait(t *testing.T) {
+	logger := zerolog.Nop()
+	s := &stagger{delay: 40 * time.Millisecond}
+
+	start := time.Now()
+	if err := s.wait(context.Background(), &logger); err != nil {
+		t.Fatalf("first wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("first replacement waited %v, want no wait", elapsed)
+	}
+
+	if err := s.wait(context.Background(), &logger); err != nil {
+		t.Fatalf("second wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("second replacement started after %v, want at least 40ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.wait(ctx, &logger); err == nil {
+		t.Error("wait() with a cancelled context should return an error")
+	}
+
+	// Disabled staggering never waits
+	var disabled *stagger
+	if err := disabled.wait(ctx, &logger); err != nil {
+		t.Errorf("nil stagger wait() error = %v", err)
+	}
+}
+
+func TestRunUpdateCycle_StaggerDelay(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	for i := 0; i < 3; i++ {
+		image := fmt.Sprintf("app%d:latest", i)
+		mockClient.Containers = append(mockClient.Containers, docker.ContainerInfo{
+			ID:      fmt.Sprintf("container%d", i),
+			Name:    fmt.Sprintf("app%d", i),
+			Image:   image,
+			ImageID: "sha256:old",
+		})
+		mockClient.PullImageReturns[image] = docker.ImageInfo{ID: "sha256:new"}
+	}
+
+	cfg := config.Default()
+	cfg.Updates.StaggerDelay = 30 * time.Millisecond
+
+	logger := zerolog.Nop()
+	start := time.Now()
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if n := len(mockClient.ReplacedContainers); n != 3 {
+		t.Errorf("replaced %d containers, want 3", n)
+	}
+	// Two gaps between three replacements
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("cycle took %v, want at least 60ms with a 30ms stagger", elapsed)
+	}
+}
@@ -0,0 +1,90 @@
+package updater
+
+import (
+	"sync"
+	"time"
+
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/status"
+	"github.com/rs/zerolog"
+)
+
+// statusRecorder collects what a cycle found for each container, for the status file
+// (state.status_file). Its methods are safe to call on a nil recorder, which a cycle gets
+// when the file is disabled.
+type statusRecorder struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]status.Container
+}
+
+// newStatusRecorder returns a recorder writing to path, or nil if path is empty
+func newStatusRecorder(path string) *statusRecorder {
+	if path == "" {
+		return nil
+	}
+	return &statusRecorder{path: path, entries: map[string]status.Container{}}
+}
+
+// checked records the update check of c: newImage is what target resolved to, if anything
+// was pulled
+func (s *statusRecorder) checked(c docker.ContainerInfo, target string, newImage docker.ImageInfo, needsUpdate bool) {
+	if s == nil {
+		return
+	}
+	entry := status.Container{
+		Image:           c.Image,
+		ImageID:         c.ImageID,
+		CheckedAt:       time.Now().UTC(),
+		UpdateAvailable: needsUpdate,
+		LatestImageID:   c.ImageID,
+	}
+	if newImage.ID != "" {
+		entry.LatestImageID = newImage.ID
+		entry.LatestDigest, _ = pulledDigest(target, newImage)
+	}
+	if target != c.Image {
+		entry.LatestImage = target
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[c.Name] = entry
+}
+
+// updated records that the container name now runs newImage
+func (s *statusRecorder) updated(name string, newImage docker.ImageInfo) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[name]
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	entry.ImageID, entry.UpdateAvailable, entry.UpdatedAt = newImage.ID, false, &now
+	if entry.LatestImage != "" {
+		entry.Image, entry.LatestImage = entry.LatestImage, ""
+	}
+	s.entries[name] = entry
+}
+
+// write merges the cycle's findings into the status file; containers is every container
+// listed this cycle
+func (s *statusRecorder) write(containers []docker.ContainerInfo, logger *zerolog.Logger) {
+	if s == nil {
+		return
+	}
+	names := make([]string, 0, len(containers))
+	for _, c := range containers {
+		names = append(names, c.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := status.Update(s.path, s.entries, names); err != nil {
+		logger.Warn().Err(err).Msg("Failed to write the status file")
+	}
+}
@@ -0,0 +1,63 @@
+package updater
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/status"
+	"github.com/rs/zerolog"
+)
+
+func TestRunUpdateCycle_StatusFile(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "web", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx"},
+		{ID: "db", Name: "db", Image: "postgres:16", ImageID: "sha256:postgres"},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx", RepoDigests: []string{"nginx@sha256:abc"}},
+		"postgres:16":  {ID: "sha256:postgres", RepoDigests: []string{"postgres@sha256:def"}},
+	}
+
+	cfg := config.Default()
+	cfg.State.StatusFile = filepath.Join(t.TempDir(), "harborbuddy-status.json")
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	f, err := status.Read(cfg.State.StatusFile)
+	if err != nil {
+		t.Fatalf("status.Read() error = %v", err)
+	}
+	web := f.Containers["web"]
+	if web.UpdateAvailable || web.ImageID != "sha256:new-nginx" || web.UpdatedAt == nil || web.LatestDigest != "sha256:abc" {
+		t.Errorf("web = %+v, want it updated to the new image", web)
+	}
+	if db := f.Containers["db"]; db.UpdateAvailable || db.LatestImageID != "sha256:postgres" || db.CheckedAt.IsZero() {
+		t.Errorf("db = %+v, want it up to date", db)
+	}
+
+	// Monitor-only leaves the update available; web is gone, so its entry is dropped
+	mockClient = docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{{ID: "db", Name: "db", Image: "postgres:16", ImageID: "sha256:postgres"}}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{"postgres:16": {ID: "sha256:new-postgres"}}
+	cfg.Updates.MonitorOnly = true
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	f, err = status.Read(cfg.State.StatusFile)
+	if err != nil {
+		t.Fatalf("status.Read() error = %v", err)
+	}
+	if _, ok := f.Containers["web"]; ok || len(f.Containers) != 1 {
+		t.Errorf("containers = %+v, want only db", f.Containers)
+	}
+	if db := f.Containers["db"]; !db.UpdateAvailable || db.ImageID != "sha256:postgres" || db.LatestImageID != "sha256:new-postgres" {
+		t.Errorf("db = %+v, want its update available", db)
+	}
+}
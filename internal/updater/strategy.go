@@ -0,0 +1,66 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/rs/zerolog"
+)
+
+// strategyLabel overrides updates.strategy for one container ("blue_green" or "recreate")
+const strategyLabel = "com.harborbuddy.strategy"
+
+// strategyFor returns how a container is replaced: its label if valid, else the configured
+// strategy
+func strategyFor(container docker.ContainerInfo, cfg config.UpdatesConfig) string {
+	if strategy, ok := container.Labels[strategyLabel]; ok && config.ValidStrategy(strategy) {
+		return strategy
+	}
+	return cfg.Strategy
+}
+
+// swapContainer replaces full with a container created from image, using the container's update
+// strategy, and returns the new container's ID. A replacement that only ended with a warning is
+// logged and counts as a success. Progress is saved to track.
+func swapContainer(ctx context.Context, cfg config.Config, dockerClient docker.Client, full docker.ContainerInfo, image, name string, track *inflight, logger *zerolog.Logger) (string, error) {
+	if label, ok := full.Labels[strategyLabel]; ok && !config.ValidStrategy(label) {
+		logger.Warn().Msgf("Ignoring invalid %s label %q, using %s", strategyLabel, label, cfg.Updates.Strategy)
+	}
+
+	if strategyFor(full, cfg.Updates) == config.StrategyRecreate {
+		newID, err := dockerClient.RecreateContainer(ctx, full, image, replaceOptions(cfg, full, track))
+		if err != nil {
+			if isWarning(err) {
+				logger.Warn().Msg(err.Error())
+				return newID, nil
+			}
+			return "", withCategory(classifyReplaceError(err), fmt.Errorf("failed to recreate container: %w", err))
+		}
+		return newID, nil
+	}
+
+	newID, err := dockerClient.CreateContainerLike(ctx, full, image)
+	if err != nil {
+		return "", withCategory(categoryCreate, fmt.Errorf("%w: %w", docker.ErrCreateFailed, err))
+	}
+	track.created(newID)
+
+	if err := replaceContainer(ctx, cfg, dockerClient, full, full.ID, newID, name, track); err != nil {
+		// The replacement handles its own rollback and cleanup; a leftover backup is only a warning
+		if isWarning(err) {
+			logger.Warn().Msg(err.Error())
+			return newID, nil
+		}
+		return "", withCategory(classifyReplaceError(err), fmt.Errorf("failed to replace container: %w", err))
+	}
+	return newID, nil
+}
+
+// isWarning reports whether a replacement error only means the update finished with a loose
+// end, such as a backup that couldn't be removed
+func isWarning(err error) bool {
+	return strings.HasPrefix(err.Error(), "warning")
+}
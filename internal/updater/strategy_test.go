@@ -0,0 +1,69 @@
+package updater
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func TestStrategyFor(t *testing.T) {
+	cfg := config.Default().Updates
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{"default", nil, config.StrategyBlueGreen},
+		{"label", map[string]string{strategyLabel: "recreate"}, config.StrategyRecreate},
+		{"invalid label", map[string]string{strategyLabel: "rolling"}, config.StrategyBlueGreen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strategyFor(docker.ContainerInfo{Labels: tt.labels}, cfg); got != tt.want {
+				t.Errorf("strategyFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_RecreateStrategy(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}, Labels: map[string]string{strategyLabel: "recreate"}},
+		{ID: "container2", Name: "api", Image: "httpd:latest", ImageID: "sha256:old-httpd", Config: &container.Config{Image: "httpd:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+		"httpd:latest": {ID: "sha256:new-httpd"},
+	}
+
+	cfg := config.Default()
+	cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+	logger := zerolog.Nop()
+
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.RecreatedContainers) != 1 || mockClient.RecreatedContainers[0].Old.Name != "web" || mockClient.RecreatedContainers[0].NewImage != "nginx:latest" {
+		t.Errorf("recreated = %+v, want only web", mockClient.RecreatedContainers)
+	}
+	if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].Name != "api" {
+		t.Errorf("replaced = %+v, want only api (blue-green)", mockClient.ReplacedContainers)
+	}
+
+	store, _ := state.Open(cfg.State.File)
+	if left := store.Inflight(); len(left) != 0 {
+		t.Errorf("finished replacement still recorded: %+v", left)
+	}
+	if rec, ok := store.Get("web"); !ok || rec.PreviousImageID != "sha256:old-nginx" {
+		t.Errorf("state for web = %+v, %v; want the previous image recorded", rec, ok)
+	}
+}
@@ -0,0 +1,29 @@
+package updater
+
+import (
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+)
+
+// matchesTargets reports whether a container is one of the targets of a manual
+// `harborbuddy update`, by name or name pattern ("web-*"). No targets match everything.
+func matchesTargets(container docker.ContainerInfo, targets []string) bool {
+	if len(targets) == 0 {
+		return true
+	}
+	for _, target := range targets {
+		if util.MatchPattern(container.Name, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// forceEligible reports whether --force can override why a container was skipped. Rolled-back
+// containers run a bare image ID with nothing to pull, and containers pinned by digest pull the
+// same manifest again, so even a forced update can't move them. Containers of another instance's
+// scope are never ours to update.
+func forceEligible(container docker.ContainerInfo, cfg config.UpdatesConfig) bool {
+	return !pinnedToImageID(container.Image) && !pinnedByDigest(container.Image) && cfg.InScope(container.Labels)
+}
@@ -0,0 +1,83 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+func targetsTestClient() *docker.MockDockerClient {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web-1", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "web-2", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container3", Name: "db", Image: "postgres:16", ImageID: "sha256:old-postgres", Config: &container.Config{Image: "postgres:16"}, Labels: map[string]string{"com.harborbuddy.autoupdate": "false"}},
+		{ID: "container4", Name: "cache", Image: "redis:7", ImageID: "sha256:old-redis", Config: &container.Config{Image: "redis:7"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+		"postgres:16":  {ID: "sha256:new-postgres"},
+		"redis:7":      {ID: "sha256:new-redis"},
+	}
+	return mockClient
+}
+
+func TestRunUpdateCycle_Targets(t *testing.T) {
+	tests := []struct {
+		name         string
+		force        bool
+		wantReplaced []string
+	}{
+		{name: "honors labels", wantReplaced: []string{"container1", "container2"}},
+		{name: "force overrides labels", force: true, wantReplaced: []string{"container1", "container2", "container3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := targetsTestClient()
+			cfg := config.Default()
+			cfg.RunOnce = true
+			cfg.Targets = []string{"web-*", "db"}
+			cfg.Force = tt.force
+			logger := zerolog.Nop()
+
+			if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err != nil {
+				t.Fatalf("RunUpdateCycle() error = %v", err)
+			}
+
+			var replaced []string
+			for _, r := range mockClient.ReplacedContainers {
+				replaced = append(replaced, r.OldID)
+			}
+			sort.Strings(replaced)
+			if !reflect.DeepEqual(replaced, tt.wantReplaced) {
+				t.Errorf("replaced %v, want %v", replaced, tt.wantReplaced)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCycle_TargetErrors(t *testing.T) {
+	logger := zerolog.Nop()
+
+	cfg := config.Default()
+	cfg.RunOnce = true
+	cfg.Targets = []string{"missing"}
+	if err := RunUpdateCycle(context.Background(), cfg, targetsTestClient(), &logger); err == nil {
+		t.Error("expected an error when no container matches")
+	}
+
+	mockClient := targetsTestClient()
+	mockClient.PullImageError = errors.New("registry unreachable")
+	cfg.Targets = []string{"cache"}
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &logger); err == nil {
+		t.Error("expected an error when a targeted container fails to update")
+	}
+}
@@ -2,15 +2,24 @@ package updater
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/MikeO7/HarborBuddy/internal/config"
 	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/history"
+	"github.com/MikeO7/HarborBuddy/internal/hooks"
+	"github.com/MikeO7/HarborBuddy/internal/metrics"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/report"
 	"github.com/MikeO7/HarborBuddy/internal/selfupdate"
+	"github.com/MikeO7/HarborBuddy/internal/state"
+	"github.com/MikeO7/HarborBuddy/internal/watchdog"
 	"github.com/MikeO7/HarborBuddy/pkg/log"
 	"github.com/MikeO7/HarborBuddy/pkg/util"
 	"github.com/rs/zerolog"
@@ -78,37 +87,127 @@ func (c *SafePullCache) GetOrPull(ctx context.Context, image string, pullFunc fu
 	}
 }
 
+// PulledBytes returns the bytes downloaded by the pulls made through the cache
+func (c *SafePullCache) PulledBytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total int64
+	for _, entry := range c.cache {
+		select {
+		case <-entry.ready:
+			if entry.err == nil {
+				total += entry.info.PulledBytes
+			}
+		default: // Still in flight
+		}
+	}
+	return total
+}
+
+// Pulled returns the images successfully pulled through the cache, sorted
+func (c *SafePullCache) Pulled() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var images []string
+	for image, entry := range c.cache {
+		select {
+		case <-entry.ready:
+			if entry.err == nil {
+				images = append(images, image)
+			}
+		default: // Still in flight
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
 // RunUpdateCycle performs the update logic for all containers
 func RunUpdateCycle(ctx context.Context, cfg config.Config, dockerClient docker.Client, logger *zerolog.Logger) error {
 	startTime := time.Now()
 	logger.Info().Msg("Starting update cycle")
 
+	journal := history.Cycle{ID: notify.CycleID(ctx), StartedAt: startTime}
+
+	// The pre-cycle hook can veto the whole cycle (e.g., a load balancer that couldn't be paused)
+	if err := hooks.Run(ctx, hooks.PreCycle, cfg.Hooks.PreCycle, hooks.Env{CycleID: journal.ID}, cfg.Hooks.Timeout, logger); err != nil {
+		logger.Error().Err(err).Msg("Pre-cycle hook failed, skipping update cycle")
+		journal.Error = err.Error()
+		writeHistory(cfg, journal, logger)
+		return err
+	}
+
 	// Discovery phase: list all containers
+	endStep := watchdog.Begin(ctx, "listing containers")
 	containers, err := dockerClient.ListContainers(ctx)
+	endStep()
 	if err != nil {
 		log.ErrorWithHint("Failed to list containers", "Ensure Docker daemon is running and socket is accessible", err)
-		return err
+		return failCycle(ctx, cfg, journal, hooks.Env{}, err, logger)
 	}
 
 	logger.Info().Msgf("🔎 Checking %d containers for updates...", len(containers))
 
-	// Safe pull cache for this cycle
+	// Safe pull cache for this cycle; pulls get their own limits, separate from the checks'
 	pullCache := NewSafePullCache()
+	checks := newCheckCache()
+	limiter := newPullLimiter(cfg.Updates)
+	space := newSpaceGuard(ctx, cfg, dockerClient, logger)
+	notifier := notify.FromContext(ctx)
+	if notifier == nil {
+		notifier = notify.New(cfg.Notifications)
+		defer notify.Flush(ctx, notifier, logger)
+	}
+	rep := report.FromContext(ctx)
+	store := openState(cfg, logger)
+	prunePending(ctx, dockerClient, store, containers, logger)
+	pinSet := loadPins(cfg, logger)
+	statuses := newStatusRecorder(cfg.State.StatusFile)
+
+	// A verifier that can't load its keys would let unsigned images through, so stop here
+	verifier, err := newVerifier(cfg)
+	if err != nil {
+		logger.Error().Err(err).Msg("Signature verification is enabled but not usable, skipping update cycle")
+		return failCycle(ctx, cfg, journal, hooks.Env{}, err, logger)
+	}
+	// Likewise, a scanner that can't run would let vulnerable images through
+	scanner, err := newScanner(cfg)
+	if err != nil {
+		logger.Error().Err(err).Msg("Vulnerability scanning is enabled but not usable, skipping update cycle")
+		return failCycle(ctx, cfg, journal, hooks.Env{}, err, logger)
+	}
+
+	// Pulled images are checked against the platform the daemon runs on
+	daemonPlatform, err := dockerClient.DaemonPlatform(ctx)
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to get the Docker host platform")
+	}
+
+	// Tag policies and digest checks ask the registry before pulling anything
+	reg := newRegistry(cfg)
+	var resolver DigestResolver
+	if cfg.Updates.CheckMethod == config.CheckMethodDigest {
+		resolver = reg
+	}
 
 	// Use a mutex to protect shared counters if we were parallelizing (we aren't yet fully, but good practice)
 	// Actually, we are running check in parallel!
 	var candidatesMu sync.Mutex
-	type updateCandidate struct {
-		Container docker.ContainerInfo
-		NewImage  docker.ImageInfo
-		Logger    *zerolog.Logger
-	}
 	// Pre-allocate to avoid resizing during concurrent append
 	updateCandidates := make([]updateCandidate, 0, len(containers))
 
 	skippedCount := 0
-	errorCount := 0
 	updatedCount := 0
+	targeted := 0
+	errorCounts := errorTally{}
+
+	// Names of containers checked this cycle, used to retire stale metric series
+	checkedNames := make([]string, 0, len(containers))
+
+	// IDs of HarborBuddy's own container, which is replaced last and through a helper
+	selfIDs := make(map[string]bool)
 
 	// Parallel check
 	var wg sync.WaitGroup
@@ -118,146 +217,548 @@ func RunUpdateCycle(ctx context.Context, cfg config.Config, dockerClient docker.
 		// Check for context cancellation
 		if err := ctx.Err(); err != nil {
 			logger.Warn().Msg("Update cycle interrupted")
-			return err
+			return failCycle(ctx, cfg, journal, hooks.Env{}, err, logger)
+		}
+
+		// One-shot runs can be scoped with --label-filter
+		if !util.MatchLabels(container.Labels, cfg.LabelFilter) {
+			logger.Debug().
+				Str("container_id", shortID(container.ID)).
+				Str("container_name", container.Name).
+				Msg("Skipping container: does not match label filter")
+			rep.AddSkipped(container.Name, "does not match label filter")
+			candidatesMu.Lock()
+			skippedCount++
+			candidatesMu.Unlock()
+			continue
+		}
+
+		// "harborbuddy update <name>" only touches the containers it names
+		if !matchesTargets(container, cfg.Targets) {
+			logger.Debug().
+				Str("container_id", shortID(container.ID)).
+				Str("container_name", container.Name).
+				Msg("Skipping container: not an update target")
+			candidatesMu.Lock()
+			skippedCount++
+			candidatesMu.Unlock()
+			continue
 		}
+		targeted++
 
 		// Determine eligibility
 		decision := DetermineEligibility(container, cfg.Updates)
 
+		if !decision.Eligible && cfg.Force && forceEligible(container, cfg.Updates) {
+			logger.Warn().
+				Str("container_id", shortID(container.ID)).
+				Str("container_name", container.Name).
+				Msgf("Forcing update despite %s", decision.Reason)
+			decision.Eligible = true
+		}
+
 		if !decision.Eligible {
 			// Optimization: Avoid creating a child logger just to skip
 			logger.Debug().
 				Str("container_id", shortID(container.ID)).
 				Str("container_name", container.Name).
 				Msgf("Skipping container: %s", decision.Reason)
+			rep.AddSkipped(container.Name, decision.Reason)
+			candidatesMu.Lock()
 			skippedCount++
+			candidatesMu.Unlock()
+
+			// Containers pinned by digest are never updated, but may hear of a newer digest
+			if decision.Reason == reasonPinnedByDigest && cfg.Updates.SuggestDigests {
+				l := logger.With().
+					Str("container_id", shortID(container.ID)).
+					Str("container_name", container.Name).
+					Logger()
+				wg.Add(1)
+				go func(c docker.ContainerInfo) {
+					defer wg.Done()
+					semaphore <- struct{}{}
+					defer func() { <-semaphore }()
+					suggestDigest(ctx, reg, dockerClient, c, cfg, store, notifier, &l)
+				}(container)
+			}
 			continue
 		}
 
+		// Containers follow the tag updates.track gives their repository, and HarborBuddy's
+		// own container follows selfupdate.channel, rather than the tag they run
+		image := trackedImage(container.Image, cfg.Updates.Track)
+		isSelf, err := isSelfFunc(container.ID)
+		if err != nil {
+			logger.Warn().Err(err).Str("container_name", container.Name).Msg("Failed to check if container is self")
+			candidatesMu.Lock()
+			errorCounts.add(categoryOther)
+			candidatesMu.Unlock()
+		}
+		if isSelf {
+			if cfg.SelfUpdate.Channel == config.SelfUpdateNone {
+				logger.Debug().
+					Str("container_id", shortID(container.ID)).
+					Str("container_name", container.Name).
+					Msg("Skipping container: self-update is disabled")
+				rep.AddSkipped(container.Name, "self-update disabled (selfupdate.channel: none)")
+				candidatesMu.Lock()
+				skippedCount++
+				candidatesMu.Unlock()
+				continue
+			}
+			image = selfImage(container.Image, cfg.SelfUpdate)
+			selfIDs[container.ID] = true
+		}
+
+		// The pins file freezes containers, or caps how far their tag may move
+		var ceiling string
+		if pin, ok := pinFor(pinSet, container); ok && cfg.Force {
+			logger.Warn().
+				Str("container_id", shortID(container.ID)).
+				Str("container_name", container.Name).
+				Msgf("Forcing update despite being %s", pin)
+		} else if ok {
+			var frozen bool
+			if ceiling, frozen = checkPin(container.Image, pin); frozen {
+				logger.Info().
+					Str("container_id", shortID(container.ID)).
+					Str("container_name", container.Name).
+					Msgf("📌 Skipping container: %s", pin)
+				rep.AddSkipped(container.Name, pin.String())
+				candidatesMu.Lock()
+				skippedCount++
+				candidatesMu.Unlock()
+				continue
+			}
+		}
+
 		// Create contextual logger for this container
 		containerLogger := logger.With().
 			Str("container_id", shortID(container.ID)).
 			Str("container_name", container.Name).
 			Logger()
 		containerLoggerPtr := &containerLogger
+		checkedNames = append(checkedNames, container.Name)
+
+		// HarborBuddy's own image is pulled for the platform its binary is built for, so a
+		// multi-arch tag can't hand it a build that doesn't run here
+		pullCtx := ctx
+		if isSelf {
+			pullCtx = docker.WithPlatform(ctx, selfupdate.Platform())
+		}
 
 		wg.Add(1)
-		go func(c docker.ContainerInfo, l *zerolog.Logger) {
+		go func(c docker.ContainerInfo, image, ceiling string, pullCtx context.Context, l *zerolog.Logger) {
 			defer wg.Done()
 			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
+			defer watchdog.Begin(ctx, "checking "+c.Name+" ("+c.Image+")")()
 
-			// Check updates
-			needsUpdate, err := checkForUpdate(ctx, dockerClient, c, cfg.Updates.DryRun, l, pullCache)
+			// Check updates, once for all the containers running the same image
+			metrics.Default.IncContainersChecked()
+			result, checkedBy, shared := checks.do(ctx, checkKey(c, image, ceiling), c.Name, func() checkResult {
+				target, err := resolveTarget(ctx, reg, image, PolicyFor(image, cfg.Updates), ceiling)
+				if err != nil {
+					l.Warn().Err(err).Msg("Failed to list registry tags, staying on the current tag")
+				}
+				newImage, needsUpdate, err := checkForUpdate(pullCtx, dockerClient, resolver, c, target, cfg.Updates, l, pullCache, limiter, space)
+				return checkResult{target: target, newImage: newImage, needsUpdate: needsUpdate, err: err}
+			})
+			if shared {
+				l.Debug().Str("checked_with", checkedBy).Msg("Using the update check of a container running the same image")
+				// The shared check spared this container a pull too
+				if result.err == nil && result.newImage.ID != "" {
+					metrics.Default.IncPullCacheHits()
+				}
+			}
+			target, newImage, needsUpdate, err := result.target, result.newImage, result.needsUpdate, result.err
+			if errors.Is(err, errLowDiskSpace) {
+				l.Warn().Err(err).Str("image", target).Msg("💾 Skipping update check: not enough free disk space to pull")
+				notify.Send(ctx, notifier, notify.Event{
+					Type:       notify.EventFailure,
+					Outcome:    notify.OutcomeLowDiskSpace,
+					Container:  c.Name,
+					Image:      c.Image,
+					OldImageID: c.ImageID,
+					Error:      err.Error(),
+				}, l)
+				candidatesMu.Lock()
+				skippedCount++
+				candidatesMu.Unlock()
+				rep.AddSkipped(c.Name, err.Error())
+				return
+			}
 			if err != nil {
 				// We don't have access to ErrorWithHint on 'l' (zerolog logger) directly easily unless we wrap or use global
 				// But we can just use normal logging here or improved message.
 				// The global log.ErrorWithHint uses global logger.
 				// We can mimic it: l.Error().Err(err).Str("hint", "...").Msg(...)
 
-				// Provide hint for common pull errors
-				hint := "Check image name spelling and registry credentials"
-				if strings.Contains(err.Error(), "404") {
-					hint = "Image not found"
-				} else if strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "403") {
-					hint = "Authentication failed - check `config.json`"
+				category := classifyError(ctx, err)
+				hint := checkHint(category, err)
+				l.Error().Err(err).Str("hint", hint).Str("error_category", string(category)).Msg("Failed to check for updates")
+				metrics.Default.RecordFailure(c.Name, c.Image)
+				notify.Send(ctx, notifier, notify.Event{
+					Type:       notify.EventFailure,
+					Outcome:    notify.OutcomeFailure,
+					Container:  c.Name,
+					Image:      c.Image,
+					OldImageID: c.ImageID,
+					Error:      err.Error(),
+				}, l)
+				candidatesMu.Lock()
+				errorCounts.add(category)
+				journal.Failures = append(journal.Failures, history.Failure{Container: c.Name, Category: string(category), Error: err.Error()})
+				candidatesMu.Unlock()
+				rep.AddFailure(report.Failure{Name: c.Name, Image: c.Image, Category: string(category), Error: err.Error()})
+				return
+			}
+
+			// Dry-run can't tell whether an update exists, so don't report it either way
+			if !cfg.Updates.DryRun {
+				metrics.Default.RecordCheck(c.Name, c.Image, needsUpdate)
+				statuses.checked(c, target, newImage, needsUpdate)
+			}
+
+			if !needsUpdate {
+				candidatesMu.Lock()
+				skippedCount++
+				candidatesMu.Unlock()
+				if cfg.Updates.DryRun {
+					rep.AddSkipped(c.Name, "dry-run")
+				} else {
+					rep.AddSkipped(c.Name, "up to date")
+					forgetPending(store, c.Name, l)
 				}
+				return
+			}
 
-				l.Error().Err(err).Str("hint", hint).Msg("Failed to check for updates")
+			// The current image's labels give the version being left and its platform
+			current, err := dockerClient.InspectImage(ctx, c.ImageID)
+			if err != nil {
+				l.Debug().Err(err).Msg("Failed to inspect the current image")
+			}
+			release := releaseOf(current.Labels, newImage.Labels)
+			logUpdateFound(c, target, newImage, release, l)
+
+			// Images younger than updates.min_image_age wait for a later cycle, unless forced
+			if wait := imageTooNew(newImage, cfg.Updates.MinImageAge, time.Now()); wait != "" && !cfg.Force {
+				l.Info().
+					Str("image", target).
+					Str("new_id", shortID(newImage.ID)).
+					Msgf("⏳ Holding back update: %s", wait)
 				candidatesMu.Lock()
-				errorCount++
+				skippedCount++
 				candidatesMu.Unlock()
+				rep.AddSkipped(c.Name, wait)
 				return
 			}
 
-			if !needsUpdate {
+			// The new image must run here and, with verification on, be signed by a trusted key;
+			// with scanning on, it must not have too many critical vulnerabilities
+			refused := vetUpdate(ctx, verifier, daemonPlatform, current.Platform, target, newImage, l)
+			if refused == nil {
+				refused = scanUpdate(ctx, scanner, cfg.Scan.MaxCritical, c.Name, target, newImage, l)
+			}
+			if refused != nil {
+				notify.Send(ctx, notifier, notify.Event{
+					Type:       notify.EventFailure,
+					Outcome:    refused.Outcome,
+					Container:  c.Name,
+					Image:      c.Image,
+					OldImageID: c.ImageID,
+					NewImageID: newImage.ID,
+					Error:      refused.Reason,
+				}, l)
 				candidatesMu.Lock()
 				skippedCount++
 				candidatesMu.Unlock()
+				rep.AddSkipped(c.Name, refused.Reason)
 				return
 			}
 
 			// If needs update, add to candidates
-			// We need to re-fetch the image info or just store what we found?
-			// checkForUpdate returns bool, but we need the new image info to proceed?
-			// Actually checkForUpdate logic just checks compatibility.
-			// The current implementation re-pulls inside checkForUpdate but doesn't return the ImageInfo.
-			// We should probably rely on updateContainer doing the work or refactor.
-			// Currently updateContainer re-pulls/creates.
-
-			// For now, just add to candidates list
 			candidatesMu.Lock()
 			updateCandidates = append(updateCandidates, updateCandidate{
-				Container: c,
-				Logger:    l,
+				Container:      c,
+				Target:         target,
+				NewImage:       newImage,
+				Release:        release,
+				Logger:         l,
+				CurrentCreated: current.CreatedAt,
+				Canary:         isCanary(c, cfg.Updates),
 			})
 			candidatesMu.Unlock()
 
-		}(container, containerLoggerPtr)
+		}(container, image, ceiling, pullCtx, containerLoggerPtr)
 	}
 
 	wg.Wait()
 
-	// Apply updates sequentially
+	if len(cfg.Targets) > 0 && targeted == 0 {
+		return failCycle(ctx, cfg, journal, hooks.Env{}, fmt.Errorf("no container matches %s", strings.Join(cfg.Targets, ", ")), logger)
+	}
+
+	// Monitor-only reports what it found and never touches a container
+	if cfg.Updates.MonitorOnly && len(updateCandidates) > 0 {
+		logger.Info().Msgf("👀 Monitor-only: %d updates available, not applying", len(updateCandidates))
+		for _, candidate := range updateCandidates {
+			candidate.Logger.Info().
+				Str("image", candidate.Container.Image).
+				Str("new_id", shortID(candidate.NewImage.ID)).
+				Msg("📣 Update available (monitor-only, not applied)")
+			announceAvailable(ctx, cfg.Notifications, store, notifier, notify.Event{
+				Type:       notify.EventUpdateAvailable,
+				Outcome:    notify.OutcomeNotApplied,
+				Container:  candidate.Container.Name,
+				Image:      candidate.Container.Image,
+				OldImageID: candidate.Container.ImageID,
+				NewImageID: candidate.NewImage.ID,
+				OldVersion: candidate.Release.OldVersion,
+				NewVersion: candidate.Release.NewVersion,
+				ReleaseURL: candidate.Release.URL,
+			}, candidate.Logger)
+			rep.AddAvailable(reportEntry(candidate))
+		}
+		skippedCount += len(updateCandidates)
+		updateCandidates = nil
+	}
+
+	// Replace network namespace and volume providers before the containers linked to them,
+	// and dependencies (compose depends_on or declared) before the containers that depend on them
+	var graph dependencyGraph
+	var volumesFrom volumesFromRefs
+	if len(updateCandidates) > 0 {
+		graph = buildDependencyGraph(containers, cfg.Updates.Dependencies)
+		volumesFrom = inspectVolumesFrom(ctx, dockerClient, containers, logger)
+	}
+
+	// Spread updates over several cycles, deferring related containers together; containers
+	// named on the command line aren't limited
+	if len(cfg.Targets) == 0 {
+		var deferred []updateCandidate
+		groups := updateGroups(containers, graph, volumesFrom, updateCandidates)
+		updateCandidates, deferred = limitUpdates(groups, cfg.Updates.MaxUpdatesPerCycle)
+		if len(deferred) > 0 {
+			logger.Info().Msgf("⏸️  Applying %d of %d updates this cycle (updates.max_updates_per_cycle), deferring %d", len(updateCandidates), len(updateCandidates)+len(deferred), len(deferred))
+		}
+		for _, candidate := range deferred {
+			candidate.Logger.Info().
+				Str("image", candidate.Container.Image).
+				Str("new_id", shortID(candidate.NewImage.ID)).
+				Msg("⏸️  Deferring update to a later cycle")
+			rep.AddSkipped(candidate.Container.Name, "deferred: max_updates_per_cycle reached")
+		}
+		skippedCount += len(deferred)
+	}
+
+	// Apply updates
 	if len(updateCandidates) > 0 {
 		logger.Info().Msgf("♻️  Found %d containers to update. Applying updates...", len(updateCandidates))
 
+		depths := make(map[string]int, len(updateCandidates))
 		for _, candidate := range updateCandidates {
-			if err := ctx.Err(); err != nil {
-				logger.Warn().Msg("Update cycle interrupted during application")
-				return err
+			depths[candidate.Container.ID] = graph.depth(candidate.Container)
+		}
+		sort.SliceStable(updateCandidates, func(i, j int) bool {
+			a, b := updateCandidates[i].Container, updateCandidates[j].Container
+			if joinsA, joinsB := volumesFrom.linked(a), volumesFrom.linked(b); joinsA != joinsB {
+				return !joinsA
 			}
+			return depths[a.ID] < depths[b.ID]
+		})
 
-			container := candidate.Container
-			containerLogger := candidate.Logger
+		// HarborBuddy replaces itself last, once nothing else is mid-replacement
+		var self []updateCandidate
+		others := make([]updateCandidate, 0, len(updateCandidates))
+		for _, candidate := range updateCandidates {
+			if selfIDs[candidate.Container.ID] {
+				self = append(self, candidate)
+			} else {
+				others = append(others, candidate)
+			}
+		}
 
-			// Double check if it's a self-update situation
-			// Note: isSelf is likely a helper in this package
-			isSelf, err := isSelfFunc(container.ID)
-			if err != nil {
-				containerLogger.Warn().Err(err).Msg("Failed to check if container is self")
-				errorCount++
+		// Related containers are replaced in order, independent groups in parallel
+		a := &applier{
+			cfg:          cfg,
+			dockerClient: dockerClient,
+			containers:   containers,
+			graph:        graph,
+			volumesFrom:  volumesFrom,
+			notifier:     notifier,
+			store:        store,
+			statuses:     statuses,
+			cycleID:      journal.ID,
+			stagger:      &stagger{delay: cfg.Updates.StaggerDelay, jitter: cfg.Updates.StaggerJitter},
+			logger:       logger,
+		}
+		// Canaries go first; if one fails, nothing else is updated this cycle
+		canaries, others := splitCanaries(others)
+		if len(canaries) > 0 {
+			logger.Info().Msgf("🐤 Updating %d canaries before the other %d containers", len(canaries), len(others)+len(self))
+			failed := ""
+			for _, result := range a.applyGroups(ctx, updateGroups(containers, graph, volumesFrom, canaries)) {
+				updatedCount += result.mergeInto(&journal, errorCounts)
+				if failed == "" && len(result.failures) > 0 {
+					failed = result.failures[0].Container
+				}
+			}
+			if failed != "" {
+				aborted := append(others, self...)
+				abortAfterCanary(ctx, failed, aborted, notifier, logger)
+				skippedCount += len(aborted)
+				others, self = nil, nil
 			}
+		}
 
-			if isSelf {
-				containerLogger.Info().Msg("Self-update detected! Triggering helper...")
+		groups := updateGroups(containers, graph, volumesFrom, others)
+		if cfg.Updates.MaxParallelUpdates > 1 && len(groups) > 1 {
+			logger.Info().Msgf("Replacing %d independent groups of containers, up to %d at a time", len(groups), cfg.Updates.MaxParallelUpdates)
+		}
+		for _, result := range a.applyGroups(ctx, groups) {
+			updatedCount += result.mergeInto(&journal, errorCounts)
+		}
+		if err := ctx.Err(); err != nil {
+			logger.Warn().Msg("Update cycle interrupted during application")
+			return failCycle(ctx, cfg, journal, hooks.Env{Updated: updatedCount, Restarted: len(journal.Restarted), Failed: errorCounts.total()}, err, logger)
+		}
 
-				// CRITICAL FIX: The 'container' struct here comes from ListContainers,
-				// so it is "shallow" (Config field is nil).
-				// We MUST inspect the container to get the full configuration (Env, Mounts, etc.)
-				// before passing it to Trigger, otherwise CreateHelperContainer will panic
-				// when trying to access Config.Env.
-				fullSelfContainer, err := dockerClient.InspectContainer(ctx, container.ID)
-				if err != nil {
-					containerLogger.Error().Err(err).Msg("Failed to inspect self container for update")
-					errorCount++
-					continue
-				}
+		for _, candidate := range self {
+			candidate.Logger.Info().Msg("Self-update detected! Triggering helper...")
 
-				if err := selfupdate.Trigger(ctx, dockerClient, fullSelfContainer, container.Image); err != nil {
-					containerLogger.Error().Err(err).Msg("Failed to trigger self-update")
-					errorCount++
-				}
+			// CRITICAL FIX: The 'container' struct here comes from ListContainers,
+			// so it is "shallow" (Config field is nil).
+			// We MUST inspect the container to get the full configuration (Env, Mounts, etc.)
+			// before passing it to Trigger, otherwise CreateHelperContainer will panic
+			// when trying to access Config.Env.
+			fullSelfContainer, err := dockerClient.InspectContainer(ctx, candidate.Container.ID)
+			if err != nil {
+				candidate.Logger.Error().Err(err).Msg("Failed to inspect self container for update")
+				errorCounts.add(categoryInspect)
 				continue
 			}
 
-			if err := updateContainer(ctx, cfg, dockerClient, container, containerLogger); err != nil {
-				containerLogger.Error().Err(err).Msg("Failed to update container")
-				errorCount++
-				continue
+			if err := selfupdate.Trigger(ctx, dockerClient, fullSelfContainer, candidate.Target); err != nil {
+				candidate.Logger.Error().Err(err).Msg("Failed to trigger self-update")
+				errorCounts.add(classifyError(ctx, err))
+				notify.Send(ctx, notifier, notify.Event{
+					Type:       notify.EventFailure,
+					Outcome:    notify.OutcomeFailure,
+					Container:  candidate.Container.Name,
+					Image:      candidate.Target,
+					OldImageID: candidate.Container.ImageID,
+					NewImageID: candidate.NewImage.ID,
+					Error:      err.Error(),
+				}, candidate.Logger)
 			}
-
-			// Friendly update message implied by updateContainer success
-			// logger.Info().Msgf("✅ Updated %s to ...", ...) -- updateContainer does this
-			updatedCount++
 		}
 	}
 
-	logger.Info().Msgf("✨ Update cycle complete: %d updated, %d skipped, %d errors, %d total (taken %v)",
-		updatedCount, skippedCount, errorCount, len(containers), time.Since(startTime).Round(time.Millisecond))
+	metrics.Default.RetainContainers(checkedNames)
+	metrics.Default.ObserveCycle("update", time.Since(startTime))
+
+	journal.DurationMs = time.Since(startTime).Milliseconds()
+	journal.Checked = len(checkedNames)
+	rep.SetChecked(len(checkedNames))
+	journal.Pulled = pullCache.Pulled()
+	journal.PulledBytes = pullCache.PulledBytes()
+	writeHistory(cfg, journal, logger)
+	statuses.write(containers, logger)
+
+	runPostCycleHook(ctx, cfg, hooks.Env{
+		CycleID:   journal.ID,
+		Outcome:   cycleOutcome(errorCounts),
+		Updated:   updatedCount,
+		Restarted: len(journal.Restarted),
+		Failed:    errorCounts.total(),
+	}, nil, logger)
+
+	errorSummary := fmt.Sprintf("%d errors", errorCounts.total())
+	if breakdown := errorCounts.String(); breakdown != "" {
+		errorSummary += " (" + breakdown + ")"
+	}
+
+	logger.Info().
+		Fields(map[string]interface{}{"errors_by_category": errorCounts.fields()}).
+		Int64("pulled_bytes", journal.PulledBytes).
+		Msgf("✨ Update cycle complete: %d updated, %d dependents restarted, %d skipped, %s, %d total, %s downloaded (taken %v)",
+			updatedCount, len(journal.Restarted), skippedCount, errorSummary, len(containers), util.FormatBytes(journal.PulledBytes), time.Since(startTime).Round(time.Millisecond))
+
+	// A manual update should fail loudly, a scheduled cycle carries on with the other containers
+	if len(cfg.Targets) > 0 && errorCounts.total() > 0 {
+		return fmt.Errorf("%d of %d targeted containers failed to update", errorCounts.total(), targeted)
+	}
 	return nil
 }
 
+// failCycle records a cycle that ended early with err in the metrics, the journal and the
+// post_cycle hook, whose env carries the counts so far, and returns err. The hook runs even
+// when the cycle was interrupted, bounded by hooks.timeout.
+func failCycle(ctx context.Context, cfg config.Config, journal history.Cycle, env hooks.Env, err error, logger *zerolog.Logger) error {
+	metrics.Default.RecordCycleFailure("update")
+	journal.Error = err.Error()
+	writeHistory(cfg, journal, logger)
+	env.CycleID = journal.ID
+	runPostCycleHook(context.WithoutCancel(ctx), cfg, env, err, logger)
+	return err
+}
+
+// replacement builds the journal entry for a successfully updated container
+func replacement(container docker.ContainerInfo, target string, newImage docker.ImageInfo) history.Replacement {
+	r := history.Replacement{
+		Container:  container.Name,
+		Image:      container.Image,
+		OldImageID: container.ImageID,
+		NewImageID: newImage.ID,
+	}
+	if target != container.Image {
+		r.Target = target
+	}
+	return r
+}
+
+// reportEntry builds the cycle report entry for a container with an update
+func reportEntry(candidate updateCandidate) report.Container {
+	c := report.Container{
+		Name:       candidate.Container.Name,
+		Image:      candidate.Container.Image,
+		OldImageID: candidate.Container.ImageID,
+		NewImageID: candidate.NewImage.ID,
+		NewSize:    candidate.NewImage.Size,
+	}
+	if candidate.Target != candidate.Container.Image {
+		c.Target = candidate.Target
+	}
+	return c
+}
+
+// writeHistory appends the cycle to the journal, if one is configured
+func writeHistory(cfg config.Config, journal history.Cycle, logger *zerolog.Logger) {
+	if cfg.State.HistoryFile == "" {
+		return
+	}
+	if err := history.Append(cfg.State.HistoryFile, journal); err != nil {
+		logger.Warn().Err(err).Msg("Failed to write update history")
+	}
+}
+
+// updateEvent builds the notification for a successfully updated container
+func updateEvent(candidate updateCandidate) notify.Event {
+	return notify.Event{
+		Type:       notify.EventUpdate,
+		Outcome:    notify.OutcomeSuccess,
+		Container:  candidate.Container.Name,
+		Image:      candidate.Container.Image,
+		OldImageID: candidate.Container.ImageID,
+		NewImageID: candidate.NewImage.ID,
+		OldVersion: candidate.Release.OldVersion,
+		NewVersion: candidate.Release.NewVersion,
+		ReleaseURL: candidate.Release.URL,
+	}
+}
+
 // isSelfFunc is a variable to allow mocking in tests
 var isSelfFunc = isSelf
 
@@ -294,92 +795,183 @@ func checkIsSelf(targetID string, hostname string, cgroupContent string) bool {
 	return false
 }
 
-// checkForUpdate checks if a container needs updating
-func checkForUpdate(ctx context.Context, dockerClient docker.Client, container docker.ContainerInfo, dryRun bool, logger *zerolog.Logger, pullCache *SafePullCache) (bool, error) {
+// checkForUpdate checks if a container needs updating to target and returns the pulled image.
+// With a digest resolver, the registry is asked first and nothing is pulled when the
+// local image is current; if the registry can't be queried we fall back to pulling.
+func checkForUpdate(ctx context.Context, dockerClient docker.Client, resolver DigestResolver, container docker.ContainerInfo, target string, updates config.UpdatesConfig, logger *zerolog.Logger, pullCache *SafePullCache, limiter *pullLimiter, space *spaceGuard) (docker.ImageInfo, bool, error) {
 	// Get current image ID
 	currentImageID := container.ImageID
+	retag := target != container.Image
+	dryRun := updates.DryRun
+
+	if retag && dryRun {
+		logger.Info().
+			Str("image", container.Image).
+			Str("target", target).
+			Msg("[DRY-RUN] 🚀 Newer tag allowed by update policy, would pull and update")
+		return docker.ImageInfo{}, false, nil
+	}
+
+	if resolver != nil && !retag {
+		upToDate, err := digestUpToDate(ctx, dockerClient, resolver, container)
+		switch {
+		case err != nil:
+			logger.Debug().Err(err).Msg("Registry digest check failed, falling back to pull")
+		case upToDate:
+			logger.Debug().Msgf("Registry digest matches local image %s", shortID(currentImageID))
+			return docker.ImageInfo{}, false, nil
+		case dryRun:
+			logger.Info().
+				Str("image", container.Image).
+				Str("current_id", shortID(currentImageID)).
+				Msg("[DRY-RUN] 🚀 Update available (registry digest changed), would pull and update")
+			return docker.ImageInfo{}, false, nil
+		}
+	}
 
 	if dryRun {
 		// In dry-run mode, we can't actually pull to check for updates
 		// We log this limitation to be clear
 		logger.Debug().Msgf("Pulling image %s", container.Image)
 		logger.Info().Msgf("[DRY-RUN] Skipping image pull for %s. Cannot determine if update is available without pulling.", container.Image)
-		return false, nil
+		return docker.ImageInfo{}, false, nil
 	}
 
 	// Get image info from cache or pull
-	newImage, err, hit := pullCache.GetOrPull(ctx, container.Image, func() (docker.ImageInfo, error) {
-		logger.Debug().Msgf("Pulling image %s", container.Image)
-		return dockerClient.PullImage(ctx, container.Image)
+	newImage, err, hit := pullCache.GetOrPull(ctx, target, func() (docker.ImageInfo, error) {
+		// With updates.min_free_space, a pull that could fill the disk waits for a later cycle
+		release, err := space.reserve(ctx, target, pullEstimate(ctx, dockerClient, container), logger)
+		if err != nil {
+			return docker.ImageInfo{}, err
+		}
+		defer release()
+
+		logger.Debug().Msgf("Pulling image %s", target)
+		metrics.Default.IncPulls()
+		info, err := pullImage(ctx, dockerClient, target, updates, limiter, logger)
+		if err == nil {
+			metrics.Default.ObservePull(info.PulledBytes)
+			logPull(target, info, logger)
+		}
+		return info, err
 	})
 
+	if errors.Is(err, errLowDiskSpace) {
+		return docker.ImageInfo{}, false, err
+	}
 	if err != nil {
-		return false, fmt.Errorf("failed to pull image: %w", err)
+		return docker.ImageInfo{}, false, withCategory(categoryPull, fmt.Errorf("failed to pull image: %w", err))
 	}
 
 	if hit {
-		logger.Debug().Msgf("Using cached pull result for %s", container.Image)
+		metrics.Default.IncPullCacheHits()
+		logger.Debug().Msgf("Using cached pull result for %s", target)
 	}
 
 	// Compare image IDs
 	if currentImageID == newImage.ID {
 		logger.Debug().Msgf("Image IDs match: %s", shortID(currentImageID))
-		return false, nil
+		return newImage, false, nil
 	}
 
-	friendlyName := util.GetImageFriendlyName(newImage.Labels)
-	displayImg := newImage.ID
-	if friendlyName != "" {
-		displayImg = friendlyName
-	}
-	// Fallback to shortID if no friendly name but keep ID for ref
-	if friendlyName == "" {
-		displayImg = shortID(newImage.ID)
-	}
+	return newImage, true, nil
+}
 
+// logPull reports what a pull downloaded. Pulls of images already present locally only get a
+// debug line, since every cycle makes one per image.
+func logPull(image string, info docker.ImageInfo, logger *zerolog.Logger) {
+	if info.PulledBytes == 0 {
+		logger.Debug().
+			Str("image", image).
+			Dur("duration", info.PullDuration).
+			Msg("Pull downloaded nothing, image is present locally")
+		return
+	}
 	logger.Info().
-		Str("container_name", container.Name).
-		Str("image", container.Image).
-		Str("current_id", shortID(currentImageID)).
-		Str("new_id", displayImg).
-		Msg("🚀 Update found")
-	return true, nil
+		Str("image", image).
+		Str("downloaded", util.FormatBytes(info.PulledBytes)).
+		Int64("downloaded_bytes", info.PulledBytes).
+		Str("duration", info.PullDuration.Round(time.Millisecond).String()).
+		Msg("📥 Pulled image")
 }
 
-// updateContainer updates a container with a new image
-func updateContainer(ctx context.Context, cfg config.Config, dockerClient docker.Client, container docker.ContainerInfo, logger *zerolog.Logger) error {
+// updateContainer recreates a container from image and returns the replacement container ID
+func updateContainer(ctx context.Context, cfg config.Config, dockerClient docker.Client, store *state.Store, container docker.ContainerInfo, image string, logger *zerolog.Logger) (string, error) {
 	// We need full container info (Config, HostConfig, etc.) which ListContainers doesn't provide
 	// So we inspect the container first
 	fullContainer, err := dockerClient.InspectContainer(ctx, container.ID)
 	if err != nil {
-		return fmt.Errorf("failed to inspect container for update: %w", err)
+		return "", withCategory(categoryInspect, fmt.Errorf("failed to inspect container for update: %w", err))
 	}
 
-	logger.Info().
-		Str("container", fullContainer.Name).
-		Msg("Stopping container")
+	// The pre-update hook lets the app drain before it is stopped; if it fails the container
+	// keeps running untouched
+	runState := docker.RunStateOf(fullContainer)
+	if runState == docker.RunStateRunning {
+		if err := runHook(ctx, dockerClient, fullContainer, container.ID, preUpdateLabel, cfg.Updates.HookTimeout, logger); err != nil {
+			return "", withCategory(categoryHook, err)
+		}
+	}
 
-	// Create new container with updated image
-	newID, err := dockerClient.CreateContainerLike(ctx, fullContainer, fullContainer.Image)
-	if err != nil {
-		return fmt.Errorf("failed to create new container: %w", err)
+	if runState == docker.RunStateRunning {
+		logger.Info().
+			Str("container", fullContainer.Name).
+			Msg("Stopping container")
+	} else {
+		// Stopped or paused on purpose, so the replacement is left the same way
+		logger.Info().
+			Str("container", fullContainer.Name).
+			Str("state", string(runState)).
+			Msg("Container is not running; its replacement will be left the same way")
 	}
 
-	// Replace the old container with the new one
-	if err := dockerClient.ReplaceContainer(ctx, container.ID, newID, container.Name, cfg.Updates.StopTimeout); err != nil {
-		// The new ReplaceContainer handles its own rollback and cleanup.
-		// We just need to check if the error is a warning or a fatal error.
-		if err.Error()[0:7] == "warning" {
-			logger.Warn().Msg(err.Error())
-			return nil // Not a fatal error
-		}
-		return fmt.Errorf("failed to replace container: %w", err)
+	track := beginReplacement(store, fullContainer, container.Name, logger)
+	defer track.finish(ctx)
+
+	// Containers started with --rm vanish as soon as they stop, so the backup-rename and
+	// rollback of the blue-green flow can't work. We already hold their full config from
+	// the inspect above, so replace them without a backup instead.
+	if fullContainer.HostConfig != nil && fullContainer.HostConfig.AutoRemove && strategyFor(fullContainer, cfg.Updates) == config.StrategyBlueGreen {
+		logger.Warn().Msg("Container uses auto-remove (--rm); replacing without a backup, rollback will not be possible")
 	}
 
+	// Replace the old container with one created from the updated image
+	newID, err := swapContainer(ctx, cfg, dockerClient, fullContainer, image, container.Name, track, logger)
+	if err != nil {
+		return "", err
+	}
+
+	runPostUpdateHook(ctx, cfg, dockerClient, fullContainer, newID, logger)
+
 	logger.Info().
 		Str("container_name", container.Name).
 		Str("old_id", shortID(container.ID)).
 		Str("new_id", shortID(newID)).
 		Msg("✅  Container replacement successful")
-	return nil
+	return newID, nil
+}
+
+// replaceContainer swaps the new container in for the old one. Containers started with --rm
+// can't keep a backup, so a failed start or health gate can't be rolled back. Progress is
+// saved to track.
+func replaceContainer(ctx context.Context, cfg config.Config, dockerClient docker.Client, full docker.ContainerInfo, oldID, newID, name string, track *inflight) error {
+	opts := replaceOptions(cfg, full, track)
+	if full.HostConfig != nil && full.HostConfig.AutoRemove {
+		return dockerClient.ReplaceAutoRemoveContainer(ctx, oldID, newID, name, opts)
+	}
+	return dockerClient.ReplaceContainer(ctx, oldID, newID, name, opts)
+}
+
+// replaceOptions configures the replacement of full from the update settings, keeping its run
+// state and saving its progress to track
+func replaceOptions(cfg config.Config, full docker.ContainerInfo, track *inflight) docker.ReplaceOptions {
+	return docker.ReplaceOptions{
+		StopTimeout:   cfg.Updates.StopTimeout,
+		HealthTimeout: cfg.Updates.HealthTimeout,
+		GracePeriod:   cfg.Updates.HealthGracePeriod,
+		LogLines:      cfg.Updates.HealthLogLines,
+		Progress:      track.step,
+		Created:       track.created,
+		RunState:      docker.RunStateOf(full),
+	}
 }
@@ -3,15 +3,25 @@ package updater
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/MikeO7/HarborBuddy/internal/config"
 	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/history"
+	"github.com/MikeO7/HarborBuddy/internal/metrics"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
 	"github.com/MikeO7/HarborBuddy/internal/selfupdate"
 	"github.com/MikeO7/HarborBuddy/pkg/log"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/rs/zerolog"
 )
@@ -368,6 +378,7 @@ func TestUpdateCycleErrorHandling(t *testing.T) {
 		mockClient.PullImageError = fmt.Errorf("network timeout")
 
 		cfg := config.Default()
+		cfg.Updates.PullRetries = 0 // Retries are covered by TestPullImage_Retries
 		ctx := context.Background()
 		testLogger := zerolog.New(zerolog.NewConsoleWriter())
 
@@ -564,7 +575,7 @@ func TestUpdateContainer_Errors(t *testing.T) {
 		mockClient.Containers = []docker.ContainerInfo{container}
 		mockClient.CreateContainerError = fmt.Errorf("name conflict")
 
-		err := updateContainer(ctx, cfg, mockClient, container, logger)
+		_, err := updateContainer(ctx, cfg, mockClient, nil, container, container.Image, logger)
 		if err == nil {
 			t.Error("Expected error when CreateContainerLike fails")
 		} else if !strings.Contains(err.Error(), "failed to create new container") {
@@ -577,7 +588,7 @@ func TestUpdateContainer_Errors(t *testing.T) {
 		mockClient.Containers = []docker.ContainerInfo{container}
 		mockClient.ReplaceContainerError = fmt.Errorf("network error")
 
-		err := updateContainer(ctx, cfg, mockClient, container, logger)
+		_, err := updateContainer(ctx, cfg, mockClient, nil, container, container.Image, logger)
 		if err == nil {
 			t.Error("Expected error when ReplaceContainer fails")
 		} else if !strings.Contains(err.Error(), "failed to replace container") {
@@ -592,7 +603,7 @@ func TestUpdateContainer_Errors(t *testing.T) {
 		// This simulates the behavior documented in internal/updater/updater.go:306
 		mockClient.ReplaceContainerError = fmt.Errorf("warning: could not remove old container")
 
-		err := updateContainer(ctx, cfg, mockClient, container, logger)
+		_, err := updateContainer(ctx, cfg, mockClient, nil, container, container.Image, logger)
 		if err != nil {
 			t.Errorf("Expected nil error for warning, got: %v", err)
 		}
@@ -735,6 +746,29 @@ func TestSafePullCache(t *testing.T) {
 			t.Errorf("Expected cached pullErr, got %v", err)
 		}
 	})
+
+	t.Run("pulled bytes add up successful pulls", func(t *testing.T) {
+		cache := NewSafePullCache()
+		ctx := context.Background()
+
+		_, _, _ = cache.GetOrPull(ctx, "a:latest", func() (docker.ImageInfo, error) {
+			return docker.ImageInfo{ID: "sha256:a", PulledBytes: 1000}, nil
+		})
+		_, _, _ = cache.GetOrPull(ctx, "b:latest", func() (docker.ImageInfo, error) {
+			return docker.ImageInfo{ID: "sha256:b", PulledBytes: 500}, nil
+		})
+		_, _, _ = cache.GetOrPull(ctx, "a:latest", func() (docker.ImageInfo, error) {
+			t.Error("cached image pulled again")
+			return docker.ImageInfo{}, nil
+		})
+		_, _, _ = cache.GetOrPull(ctx, "c:latest", func() (docker.ImageInfo, error) {
+			return docker.ImageInfo{PulledBytes: 200}, fmt.Errorf("network error")
+		})
+
+		if got := cache.PulledBytes(); got != 1500 {
+			t.Errorf("PulledBytes() = %d, want 1500", got)
+		}
+	})
 }
 
 func TestShortID(t *testing.T) {
@@ -904,6 +938,9 @@ func TestRunUpdateCycle_SelfUpdate(t *testing.T) {
 	// The panic happened because Config was nil.
 
 	// Let's enable the update.
+	mockClient.RunResults = map[string]docker.ExecResult{
+		"ghcr.io/mikeo7/harborbuddy:latest": {Output: "HarborBuddy version 0.2.0 (commit: abc1234, linux/amd64)"},
+	}
 	mockClient.PullImageReturns = map[string]docker.ImageInfo{
 		"ghcr.io/mikeo7/harborbuddy:latest": {
 			ID: "sha256:new-self",
@@ -1126,3 +1163,313 @@ func TestRunUpdateCycle_DryRunWithCandidates(t *testing.T) {
 		t.Errorf("Expected 0 replacements in dry run, got %d", len(mockClient.ReplacedContainers))
 	}
 }
+
+func TestRunUpdateCycle_AutoRemoveContainer(t *testing.T) {
+	t.Log("Testing that --rm containers skip the blue-green backup flow")
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{
+			ID:         "container1",
+			Name:       "worker",
+			Image:      "worker:latest",
+			ImageID:    "sha256:old-worker",
+			Config:     &container.Config{Image: "worker:latest"},
+			HostConfig: &container.HostConfig{AutoRemove: true},
+			State:      &types.ContainerState{Running: true, Paused: true},
+		},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"worker:latest": {ID: "sha256:new-worker"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.HealthTimeout = time.Minute
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.ReplacedContainers) != 0 {
+		t.Errorf("blue-green replace should not be used for --rm containers, got %v", mockClient.ReplacedContainers)
+	}
+	if len(mockClient.AutoRemoveReplaced) != 1 {
+		t.Fatalf("expected 1 auto-remove replacement, got %d", len(mockClient.AutoRemoveReplaced))
+	}
+	if mockClient.AutoRemoveReplaced[0].Name != "worker" {
+		t.Errorf("replacement name = %s, want worker", mockClient.AutoRemoveReplaced[0].Name)
+	}
+	// A paused --rm container comes back paused, after the same health gate
+	if opts := mockClient.AutoRemoveReplaced[0].Options; opts.RunState != docker.RunStatePaused || opts.HealthTimeout != time.Minute {
+		t.Errorf("replacement options = %+v, want the paused state and the health timeout", opts)
+	}
+}
+
+func TestRunUpdateCycle_LabelFilter(t *testing.T) {
+	t.Log("Testing that --label-filter scopes a one-shot run")
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{
+			ID:      "container1",
+			Name:    "plex",
+			Image:   "plex:latest",
+			ImageID: "sha256:old-plex",
+			Labels:  map[string]string{"project": "media"},
+			Config:  &container.Config{Image: "plex:latest"},
+		},
+		{
+			ID:      "container2",
+			Name:    "grafana",
+			Image:   "grafana:latest",
+			ImageID: "sha256:old-grafana",
+			Labels:  map[string]string{"project": "monitoring"},
+			Config:  &container.Config{Image: "grafana:latest"},
+		},
+	}
+
+	cfg := config.Default()
+	cfg.RunOnce = true
+	cfg.LabelFilter = map[string]string{"project": "media"}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.PulledImages) != 1 || mockClient.PulledImages[0] != "plex:latest" {
+		t.Errorf("expected only plex:latest to be checked, got %v", mockClient.PulledImages)
+	}
+	if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].Name != "plex" {
+		t.Errorf("expected only plex to be replaced, got %v", mockClient.ReplacedContainers)
+	}
+}
+
+func TestRunUpdateCycle_Notifications(t *testing.T) {
+	t.Log("Testing that update and failure events are sent to the webhook")
+
+	var mu sync.Mutex
+	var events []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{
+			ID:      "container1",
+			Name:    "web",
+			Image:   "nginx:latest",
+			ImageID: "sha256:old-nginx",
+			Config:  &container.Config{Image: "nginx:latest"},
+		},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+
+	cfg := config.Default()
+	cfg.Notifications.WebhookURL = server.URL
+
+	ctx := notify.WithCycleID(context.Background(), "cycle123")
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(ctx, cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	// Second cycle fails to create the replacement
+	mockClient.CreateContainerError = fmt.Errorf("no space left on device")
+	if err := RunUpdateCycle(ctx, cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %+v", len(events), events)
+	}
+
+	update := events[0]
+	if update.Type != notify.EventUpdate || update.Outcome != notify.OutcomeSuccess {
+		t.Errorf("first event = %s/%s, want update/success", update.Type, update.Outcome)
+	}
+	if update.Container != "web" || update.OldImageID != "sha256:old-nginx" || update.NewImageID != "sha256:new-nginx" || update.CycleID != "cycle123" {
+		t.Errorf("unexpected update payload: %+v", update)
+	}
+
+	failure := events[1]
+	if failure.Type != notify.EventFailure || !strings.Contains(failure.Error, "no space left on device") {
+		t.Errorf("unexpected failure payload: %+v", failure)
+	}
+}
+
+func TestRunUpdateCycle_Metrics(t *testing.T) {
+	t.Log("Testing that the update cycle feeds the metrics counters")
+
+	original := metrics.Default
+	metrics.Default = metrics.NewRegistry()
+	defer func() { metrics.Default = original }()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web1", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "web2", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := metrics.Default.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"harborbuddy_containers_checked_total 2",
+		"harborbuddy_updates_applied_total 2",
+		"harborbuddy_image_pulls_total 1",
+		"harborbuddy_pull_cache_hits_total 1",
+		`harborbuddy_last_cycle_timestamp_seconds{kind="update"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+func TestRunUpdateCycle_MonitorOnly(t *testing.T) {
+	t.Log("Testing that monitor-only pulls and reports but never replaces")
+
+	var mu sync.Mutex
+	var events []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.MonitorOnly = true
+	cfg.Notifications.WebhookURL = server.URL
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.PulledImages) != 1 {
+		t.Errorf("monitor-only must still pull, got %v", mockClient.PulledImages)
+	}
+	if len(mockClient.CreatedContainers) != 0 || len(mockClient.ReplacedContainers) != 0 {
+		t.Errorf("monitor-only must not touch containers: created=%d replaced=%d", len(mockClient.CreatedContainers), len(mockClient.ReplacedContainers))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0].Type != notify.EventUpdateAvailable || events[0].NewImageID != "sha256:new-nginx" {
+		t.Errorf("expected one update_available event, got %+v", events)
+	}
+}
+
+func TestRunUpdateCycle_HealthGateOptions(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+
+	cfg := config.Default()
+	cfg.Updates.HealthTimeout = 2 * time.Minute
+	cfg.Updates.HealthGracePeriod = 15 * time.Second
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	if err := RunUpdateCycle(context.Background(), cfg, mockClient, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.ReplacedContainers) != 1 {
+		t.Fatalf("expected one replacement, got %d", len(mockClient.ReplacedContainers))
+	}
+	want := docker.ReplaceOptions{StopTimeout: cfg.Updates.StopTimeout, HealthTimeout: 2 * time.Minute, GracePeriod: 15 * time.Second}
+	got := mockClient.ReplacedContainers[0].Options
+	if got.StopTimeout != want.StopTimeout || got.HealthTimeout != want.HealthTimeout || got.GracePeriod != want.GracePeriod {
+		t.Errorf("replace options = %+v, want %+v", got, want)
+	}
+}
+
+func TestRunUpdateCycle_WritesJournal(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "broken", Image: "missing:latest", ImageID: "sha256:old-missing", Config: &container.Config{Image: "missing:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+
+	cfg := config.Default()
+	cfg.State.HistoryFile = filepath.Join(t.TempDir(), "history.jsonl")
+
+	// Only the nginx pull succeeds
+	client := &failingPullClient{MockDockerClient: mockClient, fail: "missing:latest"}
+
+	testLogger := zerolog.New(zerolog.NewConsoleWriter())
+	ctx := notify.WithCycleID(context.Background(), "cycle42")
+	if err := RunUpdateCycle(ctx, cfg, client, &testLogger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	cycles, err := history.Read(cfg.State.HistoryFile, 0)
+	if err != nil || len(cycles) != 1 {
+		t.Fatalf("history.Read() = %+v, %v; want one cycle", cycles, err)
+	}
+	c := cycles[0]
+	if c.ID != "cycle42" || c.Checked != 2 {
+		t.Errorf("cycle = %+v", c)
+	}
+	if len(c.Pulled) != 1 || c.Pulled[0] != "nginx:latest" {
+		t.Errorf("pulled = %v, want [nginx:latest]", c.Pulled)
+	}
+	if len(c.Replaced) != 1 || c.Replaced[0].Container != "web" || c.Replaced[0].NewImageID != "sha256:new-nginx" {
+		t.Errorf("replaced = %+v", c.Replaced)
+	}
+	if len(c.Failures) != 1 || c.Failures[0].Container != "broken" || c.Failures[0].Category != "pull" {
+		t.Errorf("failures = %+v", c.Failures)
+	}
+}
+
+// failingPullClient fails pulls of one image
+type failingPullClient struct {
+	*docker.MockDockerClient
+	fail string
+}
+
+func (f *failingPullClient) PullImage(ctx context.Context, image string) (docker.ImageInfo, error) {
+	if image == f.fail {
+		return docker.ImageInfo{}, errors.New("manifest unknown")
+	}
+	return f.MockDockerClient.PullImage(ctx, image)
+}
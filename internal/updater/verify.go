@@ -0,0 +1,60 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/registry"
+	"github.com/MikeO7/HarborBuddy/internal/verify"
+	"github.com/distribution/reference"
+)
+
+// ImageVerifier checks that a pulled image was signed by a trusted key
+type ImageVerifier interface {
+	Applies(image string) bool
+	Verify(ctx context.Context, imageRef, digest string) error
+}
+
+// newVerifier is a variable to allow stubbing verification in tests. It returns nil when
+// verification is off.
+var newVerifier = func(cfg config.Config) (ImageVerifier, error) {
+	if !cfg.Verify.Enabled {
+		return nil, nil
+	}
+	v, err := verify.New(cfg.Verify, registry.NewClient(registry.KeychainFromConfig(cfg.Registries)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up signature verification: %w", err)
+	}
+	return v, nil
+}
+
+// verifyImage checks the signature of the manifest image was pulled from for target
+func verifyImage(ctx context.Context, verifier ImageVerifier, target string, image docker.ImageInfo) error {
+	digest, err := pulledDigest(target, image)
+	if err != nil {
+		return err
+	}
+	return verifier.Verify(ctx, target, digest)
+}
+
+// pulledDigest returns the manifest digest recorded for target's repository when image was
+// pulled, the digest a signature has to cover
+func pulledDigest(target string, image docker.ImageInfo) (string, error) {
+	named, err := reference.ParseNormalizedNamed(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %s: %w", target, err)
+	}
+	for _, repoDigest := range image.RepoDigests {
+		repo, digest, ok := strings.Cut(repoDigest, "@")
+		if !ok {
+			continue
+		}
+		if pulled, err := reference.ParseNormalizedNamed(repo); err == nil && pulled.Name() == named.Name() {
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("image %s has no repo digest for %s", shortID(image.ID), named.Name())
+}
@@ -0,0 +1,122 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/docker"
+	"github.com/MikeO7/HarborBuddy/internal/notify"
+	"github.com/MikeO7/HarborBuddy/internal/verify"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rs/zerolog"
+)
+
+// stubVerifier trusts the digests in signed and requires signatures on every image
+type stubVerifier struct {
+	signed map[string]bool
+}
+
+func (s stubVerifier) Applies(image string) bool { return true }
+
+func (s stubVerifier) Verify(ctx context.Context, imageRef, digest string) error {
+	if s.signed[digest] {
+		return nil
+	}
+	return verify.ErrNotSigned
+}
+
+func withVerifier(t *testing.T, v ImageVerifier, err error) {
+	t.Helper()
+	original := newVerifier
+	newVerifier = func(cfg config.Config) (ImageVerifier, error) { return v, err }
+	t.Cleanup(func() { newVerifier = original })
+}
+
+// eventRecorder collects the notifications of a cycle
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (r *eventRecorder) Notify(ctx context.Context, event notify.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestPulledDigest(t *testing.T) {
+	image := docker.ImageInfo{ID: "sha256:new", RepoDigests: []string{
+		"ghcr.io/acme/api@sha256:other",
+		"nginx@sha256:abc",
+	}}
+
+	if digest, err := pulledDigest("nginx:latest", image); err != nil || digest != "sha256:abc" {
+		t.Errorf("pulledDigest(nginx:latest) = %q, %v; want sha256:abc", digest, err)
+	}
+	if digest, err := pulledDigest("docker.io/library/nginx:1.27", image); err != nil || digest != "sha256:abc" {
+		t.Errorf("pulledDigest(docker.io/library/nginx:1.27) = %q, %v; want sha256:abc", digest, err)
+	}
+	if _, err := pulledDigest("redis:7", image); err == nil {
+		t.Error("pulledDigest(redis:7) succeeded, want an error for a missing repo digest")
+	}
+}
+
+func TestRunUpdateCycle_Verification(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+		{ID: "container2", Name: "api", Image: "httpd:latest", ImageID: "sha256:old-httpd", Config: &container.Config{Image: "httpd:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx", RepoDigests: []string{"nginx@sha256:signed"}},
+		"httpd:latest": {ID: "sha256:new-httpd", RepoDigests: []string{"httpd@sha256:unsigned"}},
+	}
+	withVerifier(t, stubVerifier{signed: map[string]bool{"sha256:signed": true}}, nil)
+
+	recorder := &eventRecorder{}
+	ctx := notify.WithNotifier(context.Background(), recorder)
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(ctx, config.Default(), mockClient, &logger); err != nil {
+		t.Fatalf("RunUpdateCycle() error = %v", err)
+	}
+
+	if len(mockClient.ReplacedContainers) != 1 || mockClient.ReplacedContainers[0].Name != "web" {
+		t.Errorf("replaced = %+v, want only web (signed)", mockClient.ReplacedContainers)
+	}
+
+	var unverified []notify.Event
+	for _, event := range recorder.events {
+		if event.Outcome == notify.OutcomeUnverified {
+			unverified = append(unverified, event)
+		}
+	}
+	if len(unverified) != 1 || unverified[0].Container != "api" || unverified[0].NewImageID != "sha256:new-httpd" {
+		t.Fatalf("unverified events = %+v, want one for api", unverified)
+	}
+	if unverified[0].Type != notify.EventFailure || unverified[0].Error != "signature verification failed: image is not signed" {
+		t.Errorf("unverified event = %+v", unverified[0])
+	}
+}
+
+func TestRunUpdateCycle_VerifierSetupFails(t *testing.T) {
+	mockClient := docker.NewMockDockerClient()
+	mockClient.Containers = []docker.ContainerInfo{
+		{ID: "container1", Name: "web", Image: "nginx:latest", ImageID: "sha256:old-nginx", Config: &container.Config{Image: "nginx:latest"}},
+	}
+	mockClient.PullImageReturns = map[string]docker.ImageInfo{
+		"nginx:latest": {ID: "sha256:new-nginx"},
+	}
+	withVerifier(t, nil, errors.New("no PEM public key found in cosign.pub"))
+
+	logger := zerolog.Nop()
+	if err := RunUpdateCycle(context.Background(), config.Default(), mockClient, &logger); err == nil {
+		t.Fatal("RunUpdateCycle() succeeded, want the verifier error")
+	}
+	if len(mockClient.ReplacedContainers) != 0 || len(mockClient.PulledImages) != 0 {
+		t.Errorf("cycle went ahead without a verifier: replaced %d, pulled %v", len(mockClient.ReplacedContainers), mockClient.PulledImages)
+	}
+}
@@ -0,0 +1,195 @@
+package verify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/registry"
+	"github.com/MikeO7/HarborBuddy/pkg/util"
+	"github.com/distribution/reference"
+)
+
+// signatureAnnotation holds the base64 signature on each layer of a cosign signature manifest
+const signatureAnnotation = "dev.cosignproject.cosign/signature"
+
+var (
+	// ErrNotSigned means the registry holds no cosign signature for the image digest
+	ErrNotSigned = errors.New("image is not signed")
+	// ErrUntrusted means signatures exist but none was made by a trusted key for this digest
+	ErrUntrusted = errors.New("no signature by a trusted key")
+)
+
+// Fetcher downloads the signature artifacts cosign stores next to an image
+type Fetcher interface {
+	Manifest(ctx context.Context, imageRef string) ([]byte, error)
+	Blob(ctx context.Context, imageRef, digest string) ([]byte, error)
+}
+
+// Verifier checks cosign signatures of image digests against trusted public keys
+type Verifier struct {
+	keys    []crypto.PublicKey
+	images  []string
+	fetcher Fetcher
+}
+
+// New loads the trusted keys of cfg. Signatures are fetched through fetcher.
+func New(cfg config.VerifyConfig, fetcher Fetcher) (*Verifier, error) {
+	v := &Verifier{images: cfg.Images, fetcher: fetcher}
+	for _, path := range cfg.PublicKeys {
+		keys, err := loadKeys(path)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, keys...)
+	}
+	if len(v.keys) == 0 {
+		return nil, fmt.Errorf("no public keys to verify signatures with")
+	}
+	return v, nil
+}
+
+// Applies reports whether image has to be signed, per verify.images
+func (v *Verifier) Applies(image string) bool {
+	if len(v.images) == 0 {
+		return true
+	}
+	for _, pattern := range v.images {
+		if util.MatchPattern(image, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks that the manifest digest of imageRef's repository carries a cosign signature
+// made by one of the trusted keys. Signatures are looked up where cosign stores them by
+// default, the "sha256-<hex>.sig" tag of the same repository.
+func (v *Verifier) Verify(ctx context.Context, imageRef, digest string) error {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return fmt.Errorf("invalid image reference %s: %w", imageRef, err)
+	}
+	sigRef := named.Name() + ":" + strings.Replace(digest, ":", "-", 1) + ".sig"
+
+	body, err := v.fetcher.Manifest(ctx, sigRef)
+	if errors.Is(err, registry.ErrNotFound) {
+		return ErrNotSigned
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch signatures: %w", err)
+	}
+
+	var manifest struct {
+		Layers []struct {
+			Digest      string            `json:"digest"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("invalid signature manifest %s: %w", sigRef, err)
+	}
+
+	signatures := 0
+	for _, layer := range manifest.Layers {
+		encoded, ok := layer.Annotations[signatureAnnotation]
+		if !ok {
+			continue
+		}
+		signatures++
+
+		signature, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		payload, err := v.fetcher.Blob(ctx, sigRef, layer.Digest)
+		if err != nil {
+			return fmt.Errorf("failed to fetch signature payload: %w", err)
+		}
+		if signedDigest(payload) != digest {
+			continue
+		}
+		for _, key := range v.keys {
+			if verifySignature(key, payload, signature) {
+				return nil
+			}
+		}
+	}
+
+	if signatures == 0 {
+		return ErrNotSigned
+	}
+	return fmt.Errorf("%w (%d signatures checked)", ErrUntrusted, signatures)
+}
+
+// signedDigest returns the image digest a cosign "simple signing" payload vouches for
+func signedDigest(payload []byte) string {
+	var simple struct {
+		Critical struct {
+			Image struct {
+				Digest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &simple); err != nil {
+		return ""
+	}
+	return simple.Critical.Image.Digest
+}
+
+// verifySignature checks signature over payload the way cosign signs: ECDSA and RSA keys sign
+// its SHA-256 hash, Ed25519 keys the payload itself
+func verifySignature(key crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, hash[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, signature)
+	}
+	return false
+}
+
+// loadKeys reads every PEM public key in a file, such as the cosign.pub written by
+// "cosign generate-key-pair"
+func loadKeys(path string) ([]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key in %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PEM public key found in %s", path)
+	}
+	return keys, nil
+}
@@ -0,0 +1,210 @@
+package verify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikeO7/HarborBuddy/internal/config"
+	"github.com/MikeO7/HarborBuddy/internal/registry"
+)
+
+const imageDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+// fakeFetcher serves signature manifests by reference and payloads by digest
+type fakeFetcher struct {
+	manifests map[string][]byte
+	blobs     map[string][]byte
+}
+
+func (f *fakeFetcher) Manifest(ctx context.Context, imageRef string) ([]byte, error) {
+	if body, ok := f.manifests[imageRef]; ok {
+		return body, nil
+	}
+	return nil, fmt.Errorf("registry returned 404 for %s: %w", imageRef, registry.ErrNotFound)
+}
+
+func (f *fakeFetcher) Blob(ctx context.Context, imageRef, digest string) ([]byte, error) {
+	if body, ok := f.blobs[digest]; ok {
+		return body, nil
+	}
+	return nil, errors.New("blob not found")
+}
+
+// sign adds a cosign signature of digest, made with signer, to the image's signature manifest
+func (f *fakeFetcher) sign(t *testing.T, repo, digest string, signer crypto.Signer) {
+	t.Helper()
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, repo, digest))
+
+	var signature []byte
+	var err error
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		signature, err = signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	} else {
+		hash := sha256.Sum256(payload)
+		signature, err = signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	payloadDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(payload))
+	f.blobs[payloadDigest] = payload
+
+	layer := map[string]interface{}{
+		"mediaType":   "application/vnd.dev.cosign.simplesigning.v1+json",
+		"digest":      payloadDigest,
+		"annotations": map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString(signature)},
+	}
+	sigRef := repo + ":sha256-" + digest[len("sha256:"):] + ".sig"
+	var manifest struct {
+		Layers []interface{} `json:"layers"`
+	}
+	if existing, ok := f.manifests[sigRef]; ok {
+		_ = json.Unmarshal(existing, &manifest)
+	}
+	manifest.Layers = append(manifest.Layers, layer)
+	f.manifests[sigRef], _ = json.Marshal(manifest)
+}
+
+func newFetcher() *fakeFetcher {
+	return &fakeFetcher{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+}
+
+// writeKey writes the public key of signer as PEM and returns the file path
+func writeKey(t *testing.T, signer crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cosign.pub")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+func TestVerify(t *testing.T) {
+	trusted, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherDigest := "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+
+	tests := []struct {
+		name    string
+		sign    func(f *fakeFetcher)
+		keys    []crypto.Signer
+		wantErr error
+	}{
+		{
+			name: "signed by the trusted key",
+			sign: func(f *fakeFetcher) {
+				f.sign(t, "docker.io/library/nginx", imageDigest, trusted)
+			},
+			keys: []crypto.Signer{trusted},
+		},
+		{
+			name: "one of several signatures is trusted",
+			sign: func(f *fakeFetcher) {
+				f.sign(t, "docker.io/library/nginx", imageDigest, other)
+				f.sign(t, "docker.io/library/nginx", imageDigest, trusted)
+			},
+			keys: []crypto.Signer{trusted},
+		},
+		{
+			name: "ed25519 key",
+			sign: func(f *fakeFetcher) {
+				f.sign(t, "docker.io/library/nginx", imageDigest, edKey)
+			},
+			keys: []crypto.Signer{other, edKey},
+		},
+		{
+			name:    "not signed",
+			sign:    func(f *fakeFetcher) {},
+			keys:    []crypto.Signer{trusted},
+			wantErr: ErrNotSigned,
+		},
+		{
+			name: "signed by another key",
+			sign: func(f *fakeFetcher) {
+				f.sign(t, "docker.io/library/nginx", imageDigest, other)
+			},
+			keys:    []crypto.Signer{trusted},
+			wantErr: ErrUntrusted,
+		},
+		{
+			name: "signature of another digest",
+			sign: func(f *fakeFetcher) {
+				// A valid signature copied under this digest's tag must not vouch for it
+				f.sign(t, "docker.io/library/nginx", otherDigest, trusted)
+				sigRef := "docker.io/library/nginx:sha256-" + otherDigest[len("sha256:"):] + ".sig"
+				f.manifests["docker.io/library/nginx:sha256-"+imageDigest[len("sha256:"):]+".sig"] = f.manifests[sigRef]
+			},
+			keys:    []crypto.Signer{trusted},
+			wantErr: ErrUntrusted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := newFetcher()
+			tt.sign(fetcher)
+
+			var keyFiles []string
+			for _, key := range tt.keys {
+				keyFiles = append(keyFiles, writeKey(t, key))
+			}
+			v, err := New(config.VerifyConfig{Enabled: true, PublicKeys: keyFiles}, fetcher)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			err = v.Verify(context.Background(), "nginx:latest", imageDigest)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Verify() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidKeys(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "key.txt")
+	_ = os.WriteFile(notPEM, []byte("not a key"), 0o644)
+
+	for _, path := range []string{filepath.Join(dir, "missing.pub"), notPEM} {
+		if _, err := New(config.VerifyConfig{Enabled: true, PublicKeys: []string{path}}, newFetcher()); err == nil {
+			t.Errorf("New() with %s succeeded, want an error", filepath.Base(path))
+		}
+	}
+}
+
+func TestApplies(t *testing.T) {
+	v := &Verifier{images: []string{"ghcr.io/acme/*"}}
+	if !v.Applies("ghcr.io/acme/api:1.2") {
+		t.Error("Applies(ghcr.io/acme/api:1.2) = false, want true")
+	}
+	if v.Applies("nginx:latest") {
+		t.Error("Applies(nginx:latest) = true, want false")
+	}
+	if !(&Verifier{}).Applies("nginx:latest") {
+		t.Error("Applies() without patterns = false, want true")
+	}
+}
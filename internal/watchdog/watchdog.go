@@ -0,0 +1,67 @@
+package watchdog
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Step is something a cycle is busy with, e.g. "pulling nginx:latest"
+type Step struct {
+	Name    string
+	Started time.Time
+	Ended   time.Time // Zero while the step is running
+}
+
+// Tracker records the steps of a cycle, so a cycle that runs past its deadline can say where
+// it got stuck. Update checks run in parallel, so several steps can be running at once.
+type Tracker struct {
+	mu    sync.Mutex
+	steps []Step
+}
+
+type trackerKey struct{}
+
+// With returns a context carrying a new tracker, and the tracker
+func With(ctx context.Context) (context.Context, *Tracker) {
+	t := &Tracker{}
+	return context.WithValue(ctx, trackerKey{}, t), t
+}
+
+// Begin records that the cycle in ctx started step and returns the function that ends it.
+// Without a tracker in ctx it does nothing.
+func Begin(ctx context.Context, step string) func() {
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	if t == nil {
+		return func() {}
+	}
+
+	t.mu.Lock()
+	i := len(t.steps)
+	t.steps = append(t.steps, Step{Name: step, Started: time.Now()})
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.steps[i].Ended = time.Now()
+	}
+}
+
+// RunningAt returns the steps that were running at the given time, longest-running first.
+// Steps cut short by a deadline end right after it, so asking for the steps running at the
+// deadline still finds them once the cycle has unwound.
+func (t *Tracker) RunningAt(at time.Time) []Step {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var running []Step
+	for _, step := range t.steps {
+		if step.Started.Before(at) && (step.Ended.IsZero() || !step.Ended.Before(at)) {
+			running = append(running, step)
+		}
+	}
+	sort.SliceStable(running, func(i, j int) bool { return running[i].Started.Before(running[j].Started) })
+	return running
+}
@@ -0,0 +1,39 @@
+package watchdog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTracker_RunningAt(t *testing.T) {
+	ctx, tracker := With(context.Background())
+
+	endList := Begin(ctx, "listing containers")
+	time.Sleep(time.Millisecond)
+	endPull := Begin(ctx, "pulling nginx:latest")
+	time.Sleep(time.Millisecond)
+	bothRunning := time.Now()
+
+	steps := tracker.RunningAt(bothRunning)
+	if len(steps) != 2 || steps[0].Name != "listing containers" || steps[1].Name != "pulling nginx:latest" {
+		t.Fatalf("RunningAt() = %+v, want both steps, oldest first", steps)
+	}
+
+	endList()
+	time.Sleep(time.Millisecond)
+	if steps := tracker.RunningAt(time.Now()); len(steps) != 1 || steps[0].Name != "pulling nginx:latest" {
+		t.Errorf("RunningAt(now) = %+v, want only the pull", steps)
+	}
+
+	// Steps that ended afterwards still count as running then
+	endPull()
+	if steps := tracker.RunningAt(bothRunning); len(steps) != 2 {
+		t.Errorf("RunningAt() after the steps ended = %+v, want both", steps)
+	}
+}
+
+func TestBegin_WithoutTracker(t *testing.T) {
+	// Phases run outside a watched cycle too, e.g. check and cleanup-only
+	Begin(context.Background(), "pulling nginx:latest")()
+}
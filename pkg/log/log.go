@@ -23,7 +23,10 @@ type Config struct {
 	File       string
 	MaxSize    int // megabytes
 	MaxBackups int
+	MaxAge     int       // days to keep rotated files, 0 = no limit
+	Compress   bool      // gzip rotated files
 	Output     io.Writer // Optional: override output (default stdout)
+	Secrets    []string  // Values hidden from the output, e.g. passwords and tokens
 }
 
 // Initialize sets up the logger with the given configuration
@@ -55,8 +58,8 @@ func Initialize(cfg Config) {
 				Filename:   cfg.File,
 				MaxSize:    cfg.MaxSize,
 				MaxBackups: cfg.MaxBackups,
-				MaxAge:     0,
-				Compress:   false,
+				MaxAge:     cfg.MaxAge,
+				Compress:   cfg.Compress,
 			}
 			writers = append(writers, fileLogger)
 		} else {
@@ -65,8 +68,12 @@ func Initialize(cfg Config) {
 		}
 	}
 
-	// Create multi-writer
-	output := io.MultiWriter(writers...)
+	// Keep the latest lines for the API's /logs
+	writers = append(writers, recentWriter)
+
+	// Create multi-writer, hiding secrets from all of them
+	setSecrets(cfg.Secrets)
+	output := redactWriter{out: io.MultiWriter(writers...)}
 
 	// Parse log level
 	logLevel := zerolog.InfoLevel
@@ -3,7 +3,9 @@ package log
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"testing"
@@ -285,3 +287,50 @@ func TestInitialize_FileError(t *testing.T) {
 	Initialize(cfg)
 	Info("test file error handling")
 }
+
+func TestInitialize_Secrets(t *testing.T) {
+	var buf bytes.Buffer
+	Initialize(Config{Level: "info", Output: &buf, Secrets: []string{"hunter2", `pa"ss`, "ab"}})
+	defer Initialize(Config{Level: "info", Output: io.Discard})
+
+	ErrorErr("Login failed", errors.New(`registry rejected password hunter2 and pa"ss for ab`))
+
+	out := buf.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, `pa\"ss`) {
+		t.Errorf("log output = %s, want the secrets hidden", out)
+	}
+	if !strings.Contains(out, "for ab") {
+		t.Errorf("log output = %s, want values too short to be secrets kept", out)
+	}
+	if got := Redact("token hunter2"); got != "token [REDACTED]" {
+		t.Errorf("Redact() = %q, want the secret hidden", got)
+	}
+}
+
+func TestRecent(t *testing.T) {
+	Initialize(Config{Level: "info", Output: io.Discard, Secrets: []string{"hunter22"}})
+	start := LastSeq()
+
+	Info("first")
+	Warnf("second with %s", "hunter22")
+	lines := Recent(start)
+	if len(lines) != 2 || lines[0].Seq != start+1 || lines[1].Seq != start+2 {
+		t.Fatalf("Recent() = %+v, want the two new lines numbered in order", lines)
+	}
+	if !strings.Contains(lines[0].Text, "INF first") {
+		t.Errorf("line = %q, want it formatted like the console", lines[0].Text)
+	}
+	if strings.Contains(lines[1].Text, "hunter22") || !strings.Contains(lines[1].Text, redacted) {
+		t.Errorf("line = %q, want the secret redacted", lines[1].Text)
+	}
+	if got := Recent(start + 1); len(got) != 1 || got[0].Seq != start+2 {
+		t.Errorf("Recent(after the first) = %+v, want only the second", got)
+	}
+
+	for i := 0; i < maxRecentLines+10; i++ {
+		Info("filler")
+	}
+	if got := Recent(0); len(got) != maxRecentLines || got[len(got)-1].Seq != LastSeq() {
+		t.Errorf("Recent(0) kept %d lines, want the last %d", len(got), maxRecentLines)
+	}
+}
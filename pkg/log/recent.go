@@ -0,0 +1,63 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// maxRecentLines is how many log lines Recent keeps
+const maxRecentLines = 1000
+
+// Line is a log line kept for the API, numbered so a reader can ask for what it hasn't seen
+type Line struct {
+	Seq  uint64 `json:"seq"`
+	Text string `json:"text"`
+}
+
+// recentLines keeps the last log lines in memory, as the console shows them
+type recentLines struct {
+	mu    sync.Mutex
+	lines []Line
+	last  uint64
+}
+
+var recent = &recentLines{}
+
+// recentWriter formats log events for recent; Initialize adds it to the outputs
+var recentWriter = zerolog.ConsoleWriter{Out: recent, NoColor: true, TimeFormat: time.TimeOnly}
+
+func (r *recentLines) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, text := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		r.last++
+		r.lines = append(r.lines, Line{Seq: r.last, Text: text})
+	}
+	if over := len(r.lines) - maxRecentLines; over > 0 {
+		r.lines = append(r.lines[:0], r.lines[over:]...)
+	}
+	return len(p), nil
+}
+
+// Recent returns the kept log lines numbered after seq, oldest first
+func Recent(after uint64) []Line {
+	recent.mu.Lock()
+	defer recent.mu.Unlock()
+	lines := []Line{}
+	for _, line := range recent.lines {
+		if line.Seq > after {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// LastSeq returns the number of the latest log line, 0 before the first
+func LastSeq() uint64 {
+	recent.mu.Lock()
+	defer recent.mu.Unlock()
+	return recent.last
+}
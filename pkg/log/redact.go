@@ -0,0 +1,71 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secret values in log output and API responses
+const redacted = "[REDACTED]"
+
+// minSecretLength keeps very short values from being scrubbed out of unrelated text
+const minSecretLength = 4
+
+var (
+	redactor   *strings.Replacer
+	redactorMu sync.RWMutex
+)
+
+// setSecrets makes Redact and the log output hide these values, as written and as escaped
+// in a JSON string
+func setSecrets(secrets []string) {
+	var pairs []string
+	for _, s := range secrets {
+		if len(s) < minSecretLength {
+			continue
+		}
+		pairs = append(pairs, s, redacted)
+		if quoted, err := json.Marshal(s); err == nil {
+			if escaped := string(quoted[1 : len(quoted)-1]); escaped != s {
+				pairs = append(pairs, escaped, redacted)
+			}
+		}
+	}
+
+	redactorMu.Lock()
+	defer redactorMu.Unlock()
+	redactor = nil
+	if len(pairs) > 0 {
+		redactor = strings.NewReplacer(pairs...)
+	}
+}
+
+// Redact hides the secrets given in Config.Secrets in s, e.g. an error reported by the API
+func Redact(s string) string {
+	redactorMu.RLock()
+	defer redactorMu.RUnlock()
+	if redactor == nil {
+		return s
+	}
+	return redactor.Replace(s)
+}
+
+// redactWriter hides secrets in everything written through it
+type redactWriter struct {
+	out io.Writer
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	redactorMu.RLock()
+	r := redactor
+	redactorMu.RUnlock()
+	if r == nil {
+		return w.out.Write(p)
+	}
+	if _, err := w.out.Write([]byte(r.Replace(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
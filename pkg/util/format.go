@@ -1,6 +1,11 @@
 package util
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
 const (
 	B  = 1
@@ -25,3 +30,33 @@ func FormatBytes(bytes int64) string {
 		return fmt.Sprintf("%d B", bytes)
 	}
 }
+
+// ParseBytes parses a size such as "512m", "5g" or "1.5GB" into bytes. Units are binary
+// (1k = 1024) and a number without a unit is bytes.
+func ParseBytes(s string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "ib"), "b")
+
+	multiplier := int64(B)
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'k':
+			multiplier = KB
+		case 'm':
+			multiplier = MB
+		case 'g':
+			multiplier = GB
+		case 't':
+			multiplier = TB
+		}
+		if multiplier != B {
+			value = value[:n-1]
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number < 0 || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("invalid size %q (e.g. 512m, 5g)", s)
+	}
+	return int64(number * float64(multiplier)), nil
+}
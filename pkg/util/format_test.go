@@ -56,3 +56,37 @@ func TestFormatBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{input: "1024", expected: 1024},
+		{input: "512m", expected: 512 * MB},
+		{input: "5g", expected: 5 * GB},
+		{input: "5G", expected: 5 * GB},
+		{input: "5GB", expected: 5 * GB},
+		{input: "1.5GiB", expected: 1536 * MB},
+		{input: " 2 t ", expected: 2 * TB},
+		{input: "0", expected: 0},
+		{input: "", wantErr: true},
+		{input: "g", wantErr: true},
+		{input: "-1g", wantErr: true},
+		{input: "lots", wantErr: true},
+		{input: "inf", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseBytes(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBytes(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseBytes(%q) = %d; want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package util
+
+// MatchLabels reports whether labels contain every key=value pair in filter.
+// An empty filter matches everything.
+func MatchLabels(labels, filter map[string]string) bool {
+	for key, want := range filter {
+		if got, ok := labels[key]; !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
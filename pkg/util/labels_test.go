@@ -0,0 +1,31 @@
+package util
+
+import "testing"
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"project": "media", "tier": "web"}
+
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		filter   map[string]string
+		expected bool
+	}{
+		{"empty filter", labels, nil, true},
+		{"single match", labels, map[string]string{"project": "media"}, true},
+		{"all match", labels, map[string]string{"project": "media", "tier": "web"}, true},
+		{"value mismatch", labels, map[string]string{"project": "infra"}, false},
+		{"missing key", labels, map[string]string{"owner": "me"}, false},
+		{"partial match", labels, map[string]string{"project": "media", "tier": "db"}, false},
+		{"nil labels", nil, map[string]string{"project": "media"}, false},
+		{"empty value must exist", map[string]string{"flag": ""}, map[string]string{"flag": ""}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchLabels(tt.labels, tt.filter); got != tt.expected {
+				t.Errorf("MatchLabels(%v, %v) = %v, want %v", tt.labels, tt.filter, got, tt.expected)
+			}
+		})
+	}
+}
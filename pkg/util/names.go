@@ -1,24 +1,81 @@
 package util
 
-// GetImageFriendlyName tries to find a human-readable name from image labels
-func GetImageFriendlyName(labels map[string]string) string {
+import (
+	"maps"
+	"sync"
+)
+
+// ComposeNameKey is a pseudo-label in the resolution order that composes the
+// compose project and service labels into "project/service" (e.g., "media/plex").
+// Compose sets those labels on containers, not images, so it only resolves when
+// the container's labels are passed along.
+const ComposeNameKey = "compose"
+
+// DefaultFriendlyNameLabels is the label priority used when none is configured
+var DefaultFriendlyNameLabels = []string{
+	"org.opencontainers.image.title",
+	"org.label-schema.name",
+	ComposeNameKey,
+	"com.docker.compose.service",
+	"io.portainer.access.control", // Sometimes used for stack/service names
+	"name",
+}
+
+var (
+	friendlyNameMu     sync.RWMutex
+	friendlyNameLabels = DefaultFriendlyNameLabels
+)
+
+// SetFriendlyNameLabels overrides the label priority used by GetImageFriendlyName.
+// An empty list restores the defaults.
+func SetFriendlyNameLabels(labels []string) {
+	friendlyNameMu.Lock()
+	defer friendlyNameMu.Unlock()
+
+	if len(labels) == 0 {
+		friendlyNameLabels = DefaultFriendlyNameLabels
+		return
+	}
+	friendlyNameLabels = append([]string(nil), labels...)
+}
+
+// GetImageFriendlyName tries to find a human-readable name from the labels of an
+// image and of the container running it (nil if none); image labels win where both
+// set the same key
+func GetImageFriendlyName(imageLabels, containerLabels map[string]string) string {
+	friendlyNameMu.RLock()
+	order := friendlyNameLabels
+	friendlyNameMu.RUnlock()
+
+	if len(containerLabels) == 0 {
+		return ResolveFriendlyName(imageLabels, order)
+	}
+	labels := make(map[string]string, len(imageLabels)+len(containerLabels))
+	maps.Copy(labels, containerLabels)
+	maps.Copy(labels, imageLabels)
+	return ResolveFriendlyName(labels, order)
+}
+
+// ResolveFriendlyName returns the first non-empty name found by walking the given label order
+func ResolveFriendlyName(labels map[string]string, order []string) string {
 	if labels == nil {
 		return ""
 	}
 
-	// Priority list of labels to check
-	keys := []string{
-		"org.opencontainers.image.title",
-		"org.label-schema.name",
-		"com.docker.compose.service",
-		"io.portainer.access.control", // Sometimes used for stack/service names
-		"name",
-	}
+	for _, key := range order {
+		if key == ComposeNameKey {
+			project := labels["com.docker.compose.project"]
+			service := labels["com.docker.compose.service"]
+			if project != "" && service != "" {
+				return project + "/" + service
+			}
+			continue
+		}
 
-	for _, key := range keys {
 		if val, ok := labels[key]; ok && val != "" {
 			return val
 		}
 	}
+
 	return ""
 }
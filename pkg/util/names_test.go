@@ -44,9 +44,82 @@ func TestGetImageFriendlyName(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := GetImageFriendlyName(tt.labels); got != tt.expected {
+			if got := GetImageFriendlyName(tt.labels, nil); got != tt.expected {
 				t.Errorf("GetImageFriendlyName() = %v, want %v", got, tt.expected)
 			}
 		})
 	}
 }
+
+func TestGetImageFriendlyName_ComposeComposition(t *testing.T) {
+	labels := map[string]string{
+		"com.docker.compose.project": "media",
+		"com.docker.compose.service": "plex",
+	}
+
+	if got := GetImageFriendlyName(labels, nil); got != "media/plex" {
+		t.Errorf("GetImageFriendlyName() = %v, want media/plex", got)
+	}
+}
+
+func TestGetImageFriendlyName_ContainerLabels(t *testing.T) {
+	container := map[string]string{
+		"com.docker.compose.project": "media",
+		"com.docker.compose.service": "plex",
+	}
+
+	// Compose labels live on the container, the image has none
+	if got := GetImageFriendlyName(map[string]string{"maintainer": "someone"}, container); got != "media/plex" {
+		t.Errorf("GetImageFriendlyName() = %v, want media/plex from the container labels", got)
+	}
+	if got := GetImageFriendlyName(map[string]string{"org.opencontainers.image.title": "plex"}, container); got != "plex" {
+		t.Errorf("GetImageFriendlyName() = %v, want the image title first", got)
+	}
+}
+
+func TestSetFriendlyNameLabels(t *testing.T) {
+	defer SetFriendlyNameLabels(nil)
+
+	labels := map[string]string{
+		"org.opencontainers.image.title": "title",
+		"com.example.name":               "custom",
+	}
+
+	SetFriendlyNameLabels([]string{"com.example.name", "org.opencontainers.image.title"})
+	if got := GetImageFriendlyName(labels, nil); got != "custom" {
+		t.Errorf("GetImageFriendlyName() with custom order = %v, want custom", got)
+	}
+
+	SetFriendlyNameLabels(nil)
+	if got := GetImageFriendlyName(labels, nil); got != "title" {
+		t.Errorf("GetImageFriendlyName() after reset = %v, want title", got)
+	}
+}
+
+func TestResolveFriendlyName(t *testing.T) {
+	labels := map[string]string{
+		"com.docker.compose.project": "media",
+		"com.docker.compose.service": "plex",
+		"name":                       "plain",
+	}
+
+	tests := []struct {
+		name     string
+		order    []string
+		expected string
+	}{
+		{"compose first", []string{ComposeNameKey, "name"}, "media/plex"},
+		{"name first", []string{"name", ComposeNameKey}, "plain"},
+		{"service only", []string{"com.docker.compose.service"}, "plex"},
+		{"no match", []string{"missing"}, ""},
+		{"empty order", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveFriendlyName(labels, tt.order); got != tt.expected {
+				t.Errorf("ResolveFriendlyName() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
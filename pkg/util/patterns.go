@@ -0,0 +1,161 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RegexPrefix marks a pattern as a regular expression, e.g. `re:^ghcr\.io/acme/.+:v\d+$`
+const RegexPrefix = "re:"
+
+// NegationPrefix excludes what a pattern matches from an allow list, e.g. "!*:latest"
+const NegationPrefix = "!"
+
+// compiled caches the regular expressions of regex and glob patterns
+var compiled sync.Map // pattern -> *regexp.Regexp
+
+// MatchPattern checks if a value (an image reference or container name) matches a pattern
+// Supports:
+//   - "*" matches everything
+//   - "repo:tag" exact match
+//   - "repo:*" matches any tag for repo
+//   - "registry.io/org/*" matches any repo under registry.io/org/
+//   - globs with "*" (any characters, "/" included), "?" (one character) and "[abc]" / "[!abc]"
+//     anywhere, e.g. "ghcr.io/*/api:v1.*"
+//   - "re:" followed by a regular expression that must match the whole value
+func MatchPattern(value, pattern string) bool {
+	// Universal wildcard
+	if pattern == "*" {
+		return true
+	}
+
+	// Exact match
+	if value == pattern {
+		return true
+	}
+
+	if strings.HasPrefix(pattern, RegexPrefix) || strings.ContainsAny(pattern, "?[") || strings.Count(pattern, "*") > 1 {
+		re, err := compile(pattern)
+		return err == nil && re.MatchString(value)
+	}
+
+	// Pattern with wildcards
+	// Check for wildcards directly to avoid full string search if possible
+	// Optimization: Avoid strings.Contains, strings.HasSuffix, and strings.TrimSuffix
+	// for common wildcard patterns to reduce allocations and CPU cycles.
+	pLen := len(pattern)
+	if pLen > 0 {
+		if pattern[pLen-1] == '*' {
+			// e.g., "postgres:*" or "registry.io/org/*"
+			// Check if value starts with pattern[:pLen-1]
+			// This avoids allocating a new string for the prefix
+			return strings.HasPrefix(value, pattern[:pLen-1])
+		}
+		if pattern[0] == '*' {
+			// e.g., "*:latest"
+			// Check if value ends with pattern[1:]
+			return strings.HasSuffix(value, pattern[1:])
+		}
+		if strings.IndexByte(pattern, '*') >= 0 {
+			// e.g., "ghcr.io/*:latest"
+			re, err := compile(pattern)
+			return err == nil && re.MatchString(value)
+		}
+	}
+
+	return false
+}
+
+// MatchAllowList reports whether an allow list lets value through: it has to match one of
+// the list's patterns, or the list has only "!" patterns, and none of its "!" patterns. An
+// empty list allows everything.
+func MatchAllowList(value string, patterns []string) bool {
+	allowed, positives := false, false
+	for _, pattern := range patterns {
+		if negated, ok := strings.CutPrefix(pattern, NegationPrefix); ok {
+			if MatchPattern(value, negated) {
+				return false
+			}
+			continue
+		}
+		positives = true
+		if !allowed && MatchPattern(value, pattern) {
+			allowed = true
+		}
+	}
+	return allowed || !positives
+}
+
+// ValidatePattern checks that a regex or glob pattern compiles. "!" negation is checked by
+// the caller, as only allow lists take it.
+func ValidatePattern(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("pattern cannot be empty")
+	}
+	if !strings.HasPrefix(pattern, RegexPrefix) && !strings.ContainsAny(pattern, "*?[") {
+		return nil
+	}
+	if _, err := compile(pattern); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// compile turns a regex or glob pattern into an anchored regular expression, once
+func compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiled.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	var expr string
+	if rest, ok := strings.CutPrefix(pattern, RegexPrefix); ok {
+		expr = "^(?:" + rest + ")$"
+	} else {
+		translated, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		expr = translated
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	compiled.Store(pattern, re)
+	return re, nil
+}
+
+// globToRegexp translates a glob into an anchored regular expression
+func globToRegexp(glob string) (string, error) {
+	var b strings.Builder
+	b.WriteByte('^')
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteByte('.')
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed '[' in glob")
+			}
+			class := glob[i+1 : i+1+end]
+			if negated, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + negated
+			}
+			if class == "" || class == "^" {
+				return "", fmt.Errorf("empty '[]' in glob")
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteByte('$')
+	return b.String(), nil
+}
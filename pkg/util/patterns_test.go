@@ -0,0 +1,64 @@
+package util
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		value   string
+		pattern string
+		want    bool
+	}{
+		{"anything", "*", true},
+		{"nginx:latest", "nginx:latest", true},
+		{"nginx:1.27", "nginx:*", true},
+		{"redis:7", "*:7", true},
+		{"ci-runner-42", "ci-runner-*", true},
+		{"web", "ci-runner-*", false},
+		{"web", "", false},
+		{"ghcr.io/acme/api:latest", "ghcr.io/*:latest", true},
+		{"redis:7", "redis:?", true},
+		{"redis:6.2", "redis:[67].*", true},
+		{"mariadb:11", "re:(mysql|mariadb):.*", true},
+		{"my-mysql:8", "re:mysql:.*", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPattern(tt.value, tt.pattern); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.value, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAllowList(t *testing.T) {
+	tests := []struct {
+		value    string
+		patterns []string
+		want     bool
+	}{
+		{"nginx:latest", nil, true},
+		{"nginx:latest", []string{"nginx:*"}, true},
+		{"redis:7", []string{"nginx:*"}, false},
+		{"nginx:latest", []string{"!*:latest"}, false},
+		{"nginx:1.27", []string{"!*:latest"}, true},
+		{"nginx:latest", []string{"nginx:*", "!*:latest"}, false},
+		{"redis:7", []string{"nginx:*", "!*:latest"}, false},
+	}
+	for _, tt := range tests {
+		if got := MatchAllowList(tt.value, tt.patterns); got != tt.want {
+			t.Errorf("MatchAllowList(%q, %v) = %v, want %v", tt.value, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	for _, valid := range []string{"nginx", "nginx:*", "*postgres*", "redis:[67].*", `re:^mysql:8\.\d+$`} {
+		if err := ValidatePattern(valid); err != nil {
+			t.Errorf("ValidatePattern(%q) error = %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", " ", "redis:[6", "redis:[]", "re:mysql:(8"} {
+		if err := ValidatePattern(invalid); err == nil {
+			t.Errorf("ValidatePattern(%q) expected error, got nil", invalid)
+		}
+	}
+}
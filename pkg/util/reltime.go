@@ -0,0 +1,59 @@
+package util
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// HumanizeDuration converts a duration to a short human readable string
+// e.g., 45s, 5m, 2h, 3 days, 2 weeks
+func HumanizeDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < day:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	case d < week:
+		return pluralize(int(d/day), "day")
+	case d < 8*week:
+		return pluralize(int(d/week), "week")
+	default:
+		// Months are irregular; 30 days is close enough for log output
+		return pluralize(int(d/(30*day)), "month")
+	}
+}
+
+// FormatRelative describes t relative to now, e.g., "3 days ago" or "in 2h"
+func FormatRelative(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	d := t.Sub(now)
+	if d > -time.Second && d < time.Second {
+		return "just now"
+	}
+
+	if d > 0 {
+		return "in " + HumanizeDuration(d)
+	}
+	return HumanizeDuration(d) + " ago"
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    time.Duration
+		expected string
+	}{
+		{"seconds", 45 * time.Second, "45s"},
+		{"minutes", 5*time.Minute + 30*time.Second, "5m"},
+		{"hours", 2*time.Hour + 59*time.Minute, "2h"},
+		{"one day", 25 * time.Hour, "1 day"},
+		{"days", 3 * 24 * time.Hour, "3 days"},
+		{"weeks", 15 * 24 * time.Hour, "2 weeks"},
+		{"months", 95 * 24 * time.Hour, "3 months"},
+		{"negative", -2 * time.Hour, "2h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HumanizeDuration(tt.input); got != tt.expected {
+				t.Errorf("HumanizeDuration(%v) = %s; want %s", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatRelative(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    time.Time
+		expected string
+	}{
+		{"past days", now.Add(-3 * 24 * time.Hour), "3 days ago"},
+		{"future hours", now.Add(2 * time.Hour), "in 2h"},
+		{"past minutes", now.Add(-10 * time.Minute), "10m ago"},
+		{"now", now, "just now"},
+		{"zero time", time.Time{}, "never"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatRelative(tt.input, now); got != tt.expected {
+				t.Errorf("FormatRelative() = %s; want %s", got, tt.expected)
+			}
+		})
+	}
+}